@@ -0,0 +1,234 @@
+// Command ddbcrypt-proxy exposes item encryption and decryption over HTTP so that services
+// written in other languages can share this library's envelope format and key management.
+//
+// Items are exchanged as DynamoDB JSON:
+//
+//	POST /v1/encrypt  {"TableName": "my-table", "Item": {"ID": {"S": "123"}, ...}}
+//	POST /v1/decrypt  {"TableName": "my-table", "Item": {...}}
+//	GET  /healthz
+//
+// When the DDBCRYPT_PROXY_TOKEN environment variable is set, the encrypt and decrypt endpoints require it as a bearer
+// token. Without a token the proxy only listens on loopback addresses, unless -allow-unauthenticated is given.
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/ddbjson"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/encrypted"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider/store"
+)
+
+// maxRequestBytes bounds the size of a request body; DynamoDB items are at most 400KB.
+const maxRequestBytes = 1 << 20
+
+// tokenEnv names the environment variable holding the bearer token clients must present.
+const tokenEnv = "DDBCRYPT_PROXY_TOKEN"
+
+// itemRequest is the body accepted by the encrypt and decrypt endpoints.
+type itemRequest struct {
+	TableName string                 `json:"TableName"`
+	Item      map[string]interface{} `json:"Item"`
+}
+
+// itemResponse is the body returned by the encrypt and decrypt endpoints.
+type itemResponse struct {
+	Item map[string]interface{} `json:"Item,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"Error"`
+}
+
+// itemCrypter encrypts and decrypts single items, as EncryptedClient does.
+type itemCrypter interface {
+	EncryptItem(ctx context.Context, tableName string, item map[string]types.AttributeValue) (map[string]types.AttributeValue, error)
+	DecryptItem(ctx context.Context, tableName string, item map[string]types.AttributeValue) (map[string]types.AttributeValue, error)
+}
+
+type server struct {
+	client itemCrypter
+	token  string // required bearer token; empty disables authentication
+}
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8080", "address to listen on")
+	keyURI := flag.String("key-uri", "", "AWS KMS key ARN used to wrap data keys")
+	metaTable := flag.String("meta-table", "meta", "DynamoDB table holding cryptographic materials")
+	readOnly := flag.Bool("read-only", false, "only allow decryption")
+	legacyDescriptions := flag.Bool("legacy-descriptions", false, "read materials stored before material descriptions were signed")
+	policyFile := flag.String("policy", "", "JSON encryption policy document; defaults to encrypting every attribute")
+	allowUnauthenticated := flag.Bool("allow-unauthenticated", false, "listen on non-loopback addresses without "+tokenEnv)
+	flag.Parse()
+
+	if *keyURI == "" {
+		log.Fatal("-key-uri is required")
+	}
+	token := os.Getenv(tokenEnv)
+	if err := checkListenAddr(*addr, token != "", *allowUnauthenticated); err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		log.Fatalf("unable to load SDK config, %v", err)
+	}
+	dynamoDBClient := dynamodb.NewFromConfig(cfg)
+
+	materialStore, err := store.NewMetaStore(dynamoDBClient, *metaTable)
+	if err != nil {
+		log.Fatalf("Failed to create key material store: %v", err)
+	}
+
+	var providerOpts []provider.ProviderOption
+	if *readOnly {
+		providerOpts = append(providerOpts, provider.WithReadOnly())
+	}
+	if *legacyDescriptions {
+		providerOpts = append(providerOpts, provider.WithLegacyDescriptions())
+	}
+	cmp, err := provider.NewAwsKmsCryptographicMaterialsProvider(*keyURI, nil, materialStore, providerOpts...)
+	if err != nil {
+		log.Fatalf("Failed to create cryptographic materials provider: %v", err)
+	}
+
+	clientConfig := encrypted.NewClientConfig(
+		encrypted.WithDefaultEncryption(encrypted.EncryptStandard),
+	)
+	if *policyFile != "" {
+		policy, err := encrypted.LoadPolicyFile(*policyFile)
+		if err != nil {
+			log.Fatalf("Failed to load encryption policy: %v", err)
+		}
+		if err := policy.Apply(clientConfig); err != nil {
+			log.Fatalf("Failed to apply encryption policy: %v", err)
+		}
+	}
+	s := &server{
+		client: encrypted.NewEncryptedClient(dynamoDBClient, cmp, encrypted.WithClientConfig(clientConfig)),
+		token:  token,
+	}
+
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	log.Printf("ddbcrypt-proxy listening on %s", *addr)
+	if err := httpServer.ListenAndServe(); err != nil {
+		log.Fatalf("server stopped: %v", err)
+	}
+}
+
+// checkListenAddr refuses to serve the unauthenticated endpoints on addresses reachable from other hosts, unless
+// allowed explicitly.
+func checkListenAddr(addr string, authenticated, allowUnauthenticated bool) error {
+	if authenticated || allowUnauthenticated {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid listen address %q: %v", addr, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("refusing to listen on %s without authentication: set %s or pass -allow-unauthenticated", addr, tokenEnv)
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/encrypt", s.authenticate(s.handleItem(s.client.EncryptItem)))
+	mux.HandleFunc("/v1/decrypt", s.authenticate(s.handleItem(s.client.DecryptItem)))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return mux
+}
+
+// authenticate rejects requests without the server's bearer token, if it has one.
+func (s *server) authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// handleItem adapts an item transformation (encrypt or decrypt) to an HTTP handler.
+func (s *server) handleItem(transform func(context.Context, string, map[string]types.AttributeValue) (map[string]types.AttributeValue, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+
+		var req itemRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+			return
+		}
+		if req.TableName == "" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("TableName is required"))
+			return
+		}
+
+		item, err := ddbjson.ToItem(req.Item)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		result, err := transform(r.Context(), req.TableName, item)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		raw, err := ddbjson.FromItem(result)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, itemResponse{Item: raw})
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
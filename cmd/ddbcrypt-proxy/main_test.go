@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// fakeCrypter marks encrypted items with an Encrypted attribute and removes it on decryption. Items of the table
+// "fail" can not be transformed.
+type fakeCrypter struct{}
+
+func (fakeCrypter) EncryptItem(ctx context.Context, tableName string, item map[string]types.AttributeValue) (map[string]types.AttributeValue, error) {
+	if tableName == "fail" {
+		return nil, errors.New("encryption failed")
+	}
+	item["Encrypted"] = &types.AttributeValueMemberBOOL{Value: true}
+	return item, nil
+}
+
+func (fakeCrypter) DecryptItem(ctx context.Context, tableName string, item map[string]types.AttributeValue) (map[string]types.AttributeValue, error) {
+	if tableName == "fail" {
+		return nil, errors.New("decryption failed")
+	}
+	delete(item, "Encrypted")
+	return item, nil
+}
+
+func TestHandlers(t *testing.T) {
+	const item = `{"TableName": "users", "Item": {"ID": {"S": "1"}, "Secret": {"S": "value"}}}`
+
+	testCases := []struct {
+		name   string
+		token  string
+		method string
+		path   string
+		auth   string
+		body   string
+		status int
+		want   string // attribute names of the returned item
+	}{
+		{name: "Encrypt", method: http.MethodPost, path: "/v1/encrypt", body: item, status: http.StatusOK, want: "Encrypted,ID,Secret"},
+		{name: "Decrypt", method: http.MethodPost, path: "/v1/decrypt", body: `{"TableName": "users", "Item": {"ID": {"S": "1"}, "Encrypted": {"BOOL": true}}}`, status: http.StatusOK, want: "ID"},
+		{name: "Health", method: http.MethodGet, path: "/healthz", status: http.StatusOK},
+		{name: "Method not allowed", method: http.MethodGet, path: "/v1/encrypt", status: http.StatusMethodNotAllowed},
+		{name: "Invalid JSON", method: http.MethodPost, path: "/v1/encrypt", body: `{"TableName":`, status: http.StatusBadRequest},
+		{name: "Missing table", method: http.MethodPost, path: "/v1/encrypt", body: `{"Item": {"ID": {"S": "1"}}}`, status: http.StatusBadRequest},
+		{name: "Invalid item", method: http.MethodPost, path: "/v1/encrypt", body: `{"TableName": "users", "Item": {"ID": {"X": "1"}}}`, status: http.StatusBadRequest},
+		{name: "Too large", method: http.MethodPost, path: "/v1/encrypt", body: `{"TableName": "users", "Item": {"ID": {"S": "` + strings.Repeat("a", maxRequestBytes) + `"}}}`, status: http.StatusBadRequest},
+		{name: "Transform error", method: http.MethodPost, path: "/v1/decrypt", body: `{"TableName": "fail", "Item": {"ID": {"S": "1"}}}`, status: http.StatusUnprocessableEntity},
+		{name: "Token", token: "s3cret", method: http.MethodPost, path: "/v1/encrypt", auth: "Bearer s3cret", body: item, status: http.StatusOK, want: "Encrypted,ID,Secret"},
+		{name: "Missing token", token: "s3cret", method: http.MethodPost, path: "/v1/encrypt", body: item, status: http.StatusUnauthorized},
+		{name: "Wrong token", token: "s3cret", method: http.MethodPost, path: "/v1/decrypt", auth: "Bearer other", body: item, status: http.StatusUnauthorized},
+		{name: "Health without token", token: "s3cret", method: http.MethodGet, path: "/healthz", status: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &server{client: fakeCrypter{}, token: tc.token}
+			request := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			if tc.auth != "" {
+				request.Header.Set("Authorization", tc.auth)
+			}
+			recorder := httptest.NewRecorder()
+			s.routes().ServeHTTP(recorder, request)
+
+			if recorder.Code != tc.status {
+				t.Fatalf("expected status %d, got %d: %s", tc.status, recorder.Code, recorder.Body.String())
+			}
+			if tc.path == "/healthz" {
+				return
+			}
+			if tc.status != http.StatusOK {
+				var response errorResponse
+				if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil || response.Error == "" {
+					t.Errorf("expected an error body, got %q", recorder.Body.String())
+				}
+				return
+			}
+
+			var response itemResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			var names []string
+			for _, name := range []string{"Encrypted", "ID", "Secret"} {
+				if _, ok := response.Item[name]; ok {
+					names = append(names, name)
+				}
+			}
+			if got := strings.Join(names, ","); got != tc.want {
+				t.Errorf("expected attributes %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCheckListenAddr(t *testing.T) {
+	testCases := []struct {
+		addr                 string
+		authenticated        bool
+		allowUnauthenticated bool
+		ok                   bool
+	}{
+		{addr: "127.0.0.1:8080", ok: true},
+		{addr: "[::1]:8080", ok: true},
+		{addr: "localhost:8080", ok: true},
+		{addr: "0.0.0.0:8080"},
+		{addr: ":8080"},
+		{addr: "10.0.0.5:8080"},
+		{addr: "0.0.0.0:8080", authenticated: true, ok: true},
+		{addr: ":8080", allowUnauthenticated: true, ok: true},
+		{addr: "8080"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.addr, func(t *testing.T) {
+			err := checkListenAddr(tc.addr, tc.authenticated, tc.allowUnauthenticated)
+			if (err == nil) != tc.ok {
+				t.Errorf("expected ok %v, got %v", tc.ok, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	mathrand "math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/encrypted"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider/store"
+)
+
+// Benchmark operation names, as used in the -mix flag and the report.
+const (
+	opPut  = "put"
+	opGet  = "get"
+	opScan = "scan"
+)
+
+var benchOps = []string{opPut, opGet, opScan}
+
+// benchConfig holds the settings of a bench run.
+type benchConfig struct {
+	table              string
+	partitionKey       string
+	keyURI             string
+	metaTable          string
+	concurrency        int
+	duration           time.Duration
+	itemSize           int
+	mix                map[string]int
+	cacheTTL           time.Duration
+	legacyDescriptions bool
+	seed               int64
+}
+
+// benchResult accumulates the latencies and errors of one operation type.
+type benchResult struct {
+	mu        sync.Mutex
+	latencies []time.Duration
+	errors    int64
+}
+
+func (r *benchResult) record(latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil {
+		r.errors++
+		return
+	}
+	r.latencies = append(r.latencies, latency)
+}
+
+// countingHTTPClient counts the HTTP requests, including retries, made by a DynamoDB client.
+type countingHTTPClient struct {
+	next     dynamodb.HTTPClient
+	requests atomic.Int64
+}
+
+func (c *countingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	c.requests.Add(1)
+	return c.next.Do(req)
+}
+
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	table := fs.String("table", "", "test table to write to; it must have a string partition key and no sort key")
+	partitionKey := fs.String("partition-key", "PK", "partition key attribute of the test table")
+	keyURI := fs.String("key-uri", "", "AWS KMS key ARN used to wrap data keys")
+	metaTable := fs.String("meta-table", "meta", "DynamoDB table holding cryptographic materials")
+	concurrency := fs.Int("concurrency", 8, "number of concurrent workers")
+	duration := fs.Duration("duration", 30*time.Second, "how long to drive traffic")
+	itemSize := fs.Int("item-size", 256, "size in bytes of the encrypted payload attribute")
+	mix := fs.String("mix", "put=40,get=50,scan=10", "relative weights of put, get and scan operations")
+	cacheTTL := fs.Duration("cache-ttl", 0, "enable the provider's decryption materials cache with this TTL")
+	legacyDescriptions := fs.Bool("legacy-descriptions", false, "read materials stored before material descriptions were signed")
+	seed := fs.Int64("seed", 1, "seed for the operation mix, so runs are reproducible")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *table == "" || *keyURI == "" {
+		return fmt.Errorf("-table and -key-uri are required")
+	}
+	if *concurrency < 1 {
+		return fmt.Errorf("-concurrency must be at least 1")
+	}
+	weights, err := parseMix(*mix)
+	if err != nil {
+		return err
+	}
+
+	return bench(context.Background(), benchConfig{
+		table:              *table,
+		partitionKey:       *partitionKey,
+		keyURI:             *keyURI,
+		metaTable:          *metaTable,
+		concurrency:        *concurrency,
+		duration:           *duration,
+		itemSize:           *itemSize,
+		mix:                weights,
+		cacheTTL:           *cacheTTL,
+		legacyDescriptions: *legacyDescriptions,
+		seed:               *seed,
+	})
+}
+
+// parseMix parses operation weights such as "put=40,get=50,scan=10". Omitted operations have weight 0.
+func parseMix(mix string) (map[string]int, error) {
+	weights := make(map[string]int)
+	total := 0
+	for _, part := range strings.Split(mix, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid mix entry %q: expected op=weight", part)
+		}
+		if name != opPut && name != opGet && name != opScan {
+			return nil, fmt.Errorf("invalid mix entry %q: unknown operation %q", part, name)
+		}
+		weight, err := strconv.Atoi(value)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid mix entry %q: weight must be a non-negative integer", part)
+		}
+		weights[name] = weight
+		total += weight
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("invalid mix %q: weights must not all be zero", mix)
+	}
+	return weights, nil
+}
+
+func bench(ctx context.Context, cfg benchConfig) error {
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to load SDK config: %v", err)
+	}
+
+	dataHTTP := &countingHTTPClient{}
+	metaHTTP := &countingHTTPClient{}
+	dataClient := dynamodb.NewFromConfig(awsCfg, withHTTPClient(dataHTTP))
+	metaClient := dynamodb.NewFromConfig(awsCfg, withHTTPClient(metaHTTP))
+
+	materialStore, err := store.NewMetaStore(metaClient, cfg.metaTable)
+	if err != nil {
+		return fmt.Errorf("failed to create key material store: %v", err)
+	}
+
+	var providerOpts []provider.ProviderOption
+	if cfg.cacheTTL > 0 {
+		providerOpts = append(providerOpts, provider.WithCache(cfg.cacheTTL))
+	}
+	if cfg.legacyDescriptions {
+		providerOpts = append(providerOpts, provider.WithLegacyDescriptions())
+	}
+	cmp, err := provider.NewAwsKmsCryptographicMaterialsProvider(cfg.keyURI, nil, materialStore, providerOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to create cryptographic materials provider: %v", err)
+	}
+
+	ec := encrypted.NewEncryptedClient(dataClient, cmp,
+		encrypted.WithOptions(encrypted.WithDefaultEncryption(encrypted.EncryptStandard)),
+		encrypted.WithPrimaryKeyInfo(cfg.table, encrypted.PrimaryKeyInfo{PartitionKey: cfg.partitionKey}),
+	)
+
+	results := make(map[string]*benchResult, len(benchOps))
+	for _, op := range benchOps {
+		results[op] = &benchResult{}
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, cfg.duration)
+	defer cancel()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for worker := 0; worker < cfg.concurrency; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			w := &benchWorker{
+				id:      worker,
+				cfg:     cfg,
+				client:  ec,
+				rng:     mathrand.New(mathrand.NewSource(cfg.seed + int64(worker))),
+				results: results,
+			}
+			w.run(runCtx)
+		}(worker)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	report(os.Stdout, cfg, elapsed, results, cmp, dataHTTP.requests.Load(), metaHTTP.requests.Load())
+	return nil
+}
+
+func withHTTPClient(client *countingHTTPClient) func(*dynamodb.Options) {
+	return func(o *dynamodb.Options) {
+		client.next = o.HTTPClient
+		if client.next == nil {
+			client.next = http.DefaultClient
+		}
+		o.HTTPClient = client
+	}
+}
+
+// benchWorker issues operations in the configured mix until its context is done.
+type benchWorker struct {
+	id      int
+	cfg     benchConfig
+	client  *encrypted.EncryptedClient
+	rng     *mathrand.Rand
+	results map[string]*benchResult
+	written []string
+}
+
+func (w *benchWorker) run(ctx context.Context) {
+	for ctx.Err() == nil {
+		op := w.nextOp()
+		start := time.Now()
+		err := w.do(ctx, op)
+		if ctx.Err() != nil {
+			// Operations interrupted by the end of the run are not representative.
+			return
+		}
+		w.results[op].record(time.Since(start), err)
+	}
+}
+
+// nextOp picks an operation according to the mix. Gets fall back to puts until the worker has written an item.
+func (w *benchWorker) nextOp() string {
+	total := 0
+	for _, op := range benchOps {
+		total += w.cfg.mix[op]
+	}
+
+	n := w.rng.Intn(total)
+	for _, op := range benchOps {
+		if n < w.cfg.mix[op] {
+			if op == opGet && len(w.written) == 0 {
+				return opPut
+			}
+			return op
+		}
+		n -= w.cfg.mix[op]
+	}
+	return opPut
+}
+
+func (w *benchWorker) do(ctx context.Context, op string) error {
+	switch op {
+	case opPut:
+		key := fmt.Sprintf("bench-%d-%d", w.id, len(w.written))
+		payload := make([]byte, w.cfg.itemSize/2+1)
+		if _, err := rand.Read(payload); err != nil {
+			return err
+		}
+		_, err := w.client.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(w.cfg.table),
+			Item: map[string]types.AttributeValue{
+				w.cfg.partitionKey: &types.AttributeValueMemberS{Value: key},
+				"Payload":          &types.AttributeValueMemberS{Value: hex.EncodeToString(payload)[:w.cfg.itemSize]},
+			},
+		})
+		if err == nil {
+			w.written = append(w.written, key)
+		}
+		return err
+	case opGet:
+		key := w.written[w.rng.Intn(len(w.written))]
+		_, err := w.client.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName: aws.String(w.cfg.table),
+			Key: map[string]types.AttributeValue{
+				w.cfg.partitionKey: &types.AttributeValueMemberS{Value: key},
+			},
+		})
+		return err
+	case opScan:
+		_, err := w.client.Scan(ctx, &dynamodb.ScanInput{
+			TableName: aws.String(w.cfg.table),
+			Limit:     aws.Int32(25),
+		})
+		return err
+	}
+	return fmt.Errorf("unknown operation %q", op)
+}
+
+// report prints throughput and latency percentiles per operation, followed by KMS and MetaStore call counts.
+func report(out io.Writer, cfg benchConfig, elapsed time.Duration, results map[string]*benchResult, cmp provider.CryptographicMaterialsProvider, dataRequests, metaRequests int64) {
+	fmt.Fprintf(out, "table=%s concurrency=%d duration=%s item-size=%d\n\n", cfg.table, cfg.concurrency, elapsed.Round(time.Millisecond), cfg.itemSize)
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "op\tops\terrors\tops/s\tp50\tp90\tp99\tmax\t")
+	for _, op := range benchOps {
+		r := results[op]
+		sort.Slice(r.latencies, func(i, j int) bool { return r.latencies[i] < r.latencies[j] })
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t\n", op, len(r.latencies), r.errors,
+			float64(len(r.latencies))/elapsed.Seconds(),
+			percentile(r.latencies, 50), percentile(r.latencies, 90), percentile(r.latencies, 99), percentile(r.latencies, 100))
+	}
+	tw.Flush()
+
+	fmt.Fprintf(out, "\nDynamoDB requests: %d\nMetaStore requests: %d\n", dataRequests, metaRequests)
+	if sp, ok := cmp.(provider.StatsProvider); ok {
+		stats := sp.Stats()
+		fmt.Fprintf(out, "KMS calls: %d\nMaterials created: %d\nDecryption materials fetched: %d (cache hits %d, misses %d)\nMetaStore throttles: %d\n",
+			stats.KMSCalls, stats.MaterialsCreated, stats.DecryptionMaterialsFetched, stats.CacheHits, stats.CacheMisses, stats.MetaStoreThrottles)
+	}
+}
+
+// percentile returns the p-th percentile of sorted latencies using the nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1].Round(time.Microsecond)
+}
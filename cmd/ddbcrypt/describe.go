@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/ddbjson"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/encrypted"
+)
+
+func runDescribe(args []string) error {
+	fs := flag.NewFlagSet("describe", flag.ContinueOnError)
+	table := fs.String("table", "", "table the item is stored in, to report its material name")
+	partitionKey := fs.String("partition-key", "", "partition key attribute of the table, to report the item's material name")
+	sortKey := fs.String("sort-key", "", "sort key attribute of the table, if any")
+	policyFile := fs.String("policy", "", "encryption policy document of the table, so plaintext binary attributes are not reported as ciphertext")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("expected a single item file, got %d arguments", fs.NArg())
+	}
+
+	var in io.Reader = os.Stdin
+	if fs.NArg() == 1 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	item, err := ddbjson.UnmarshalItem(data)
+	if err != nil {
+		return err
+	}
+
+	var opts []encrypted.InspectOption
+	if *partitionKey != "" {
+		opts = append(opts, encrypted.InspectWithKeys(&encrypted.PrimaryKeyInfo{Table: *table, PartitionKey: *partitionKey, SortKey: *sortKey}))
+	}
+	if *policyFile != "" {
+		policy, err := encrypted.LoadPolicyFile(*policyFile)
+		if err != nil {
+			return err
+		}
+		clientConfig := encrypted.NewClientConfig(encrypted.WithDefaultEncryption(encrypted.EncryptStandard))
+		if err := policy.Apply(clientConfig); err != nil {
+			return err
+		}
+		opts = append(opts, encrypted.InspectWithConfig(clientConfig))
+	}
+
+	inspection, err := encrypted.InspectItem(item, opts...)
+	if err != nil {
+		return err
+	}
+	printInspection(os.Stdout, inspection)
+	return nil
+}
+
+// printInspection writes the item's metadata followed by one line per attribute.
+func printInspection(w io.Writer, inspection *encrypted.ItemInspection) {
+	if inspection.MaterialName != "" {
+		fmt.Fprintf(w, "material:            %s\n", inspection.MaterialName)
+	}
+	fmt.Fprintf(w, "material scope:      %s\n", inspection.MaterialScope)
+	if inspection.MaterialVersion != 0 {
+		fmt.Fprintf(w, "material version:    %d\n", inspection.MaterialVersion)
+	}
+	fmt.Fprintf(w, "algorithm:           %s\n", inspection.Algorithm)
+	if inspection.KeyDerivation != "" {
+		fmt.Fprintf(w, "key derivation:      %s\n", inspection.KeyDerivation)
+	}
+	fmt.Fprintf(w, "deterministic scope: %s\n", inspection.DeterministicScope)
+	if inspection.PolicyFingerprint != "" {
+		fmt.Fprintf(w, "policy fingerprint:  %s\n", inspection.PolicyFingerprint)
+	}
+	fmt.Fprintf(w, "signed:              %t\n", inspection.Signed)
+
+	fmt.Fprintln(w, "attributes:")
+	for _, attribute := range inspection.Attributes {
+		switch {
+		case attribute.Ciphertext && attribute.Format == encrypted.CiphertextTink:
+			fmt.Fprintf(w, "  %-20s %-4s ciphertext (%s, key %d, %d bytes)\n", attribute.Name, attribute.Type, attribute.Format, attribute.KeyID, attribute.Size)
+		case attribute.Ciphertext:
+			fmt.Fprintf(w, "  %-20s %-4s ciphertext (%s, %d bytes)\n", attribute.Name, attribute.Type, attribute.Format, attribute.Size)
+		default:
+			fmt.Fprintf(w, "  %-20s %-4s plaintext\n", attribute.Name, attribute.Type)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Command ddbcrypt provides operational tooling for tables encrypted with this library.
+//
+// Usage:
+//
+//	ddbcrypt bench -table bench -key-uri arn:aws:kms:... [flags]
+//	ddbcrypt verify -table my-table -key-uri arn:aws:kms:... [flags]
+//	ddbcrypt describe [-table my-table -partition-key ID] [flags] [item.json]
+//	ddbcrypt reap -tables my-table,other-table [-meta-table meta] [-grace 24h] [-dry-run]
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// command is a ddbcrypt subcommand; run receives the arguments following the subcommand name.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{name: "bench", summary: "drive encrypted Put/Get/Scan traffic against a test table and report latencies", run: runBench},
+	{name: "verify", summary: "verify the signatures and authentication tags of every item in an encrypted table", run: runVerify},
+	{name: "describe", summary: "describe how a stored item in DynamoDB JSON is encrypted, without calling AWS", run: runDescribe},
+	{name: "reap", summary: "delete material versions no item references anymore, after a grace period", run: runReap},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "ddbcrypt %s: %v\n", cmd.name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "ddbcrypt: unknown command %q\n", os.Args[1])
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: ddbcrypt <command> [flags]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+}
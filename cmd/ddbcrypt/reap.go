@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/encrypted"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider/store"
+)
+
+func runReap(args []string) error {
+	fs := flag.NewFlagSet("reap", flag.ContinueOnError)
+	tables := fs.String("tables", "", "comma-separated list of every encrypted table whose materials are kept in the meta table")
+	metaTable := fs.String("meta-table", "meta", "DynamoDB table holding cryptographic materials")
+	grace := fs.Duration("grace", 24*time.Hour, "minimum age of the material versions to delete")
+	dryRun := fs.Bool("dry-run", false, "report orphaned material versions without deleting them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *tables == "" {
+		return fmt.Errorf("-tables is required")
+	}
+
+	ctx := context.Background()
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to load SDK config: %v", err)
+	}
+	client := dynamodb.NewFromConfig(awsCfg)
+
+	materialStore, err := store.NewMetaStore(client, *metaTable)
+	if err != nil {
+		return fmt.Errorf("failed to create key material store: %v", err)
+	}
+	// Reaping only reads item metadata, so no materials provider is needed
+	ec := encrypted.NewEncryptedClient(client, nil)
+	defer ec.Close()
+
+	var opts []encrypted.ReapOption
+	if *dryRun {
+		opts = append(opts, encrypted.ReapDryRun())
+	}
+	report, err := ec.ReapMaterials(ctx, materialStore, strings.Split(*tables, ","), *grace, opts...)
+	if err != nil {
+		return err
+	}
+	printReapReport(os.Stdout, report, *dryRun)
+	return nil
+}
+
+// printReapReport writes one line per orphaned material version and a summary.
+func printReapReport(w io.Writer, report *encrypted.ReapReport, dryRun bool) {
+	action := "DELETED"
+	if dryRun {
+		action = "ORPHANED"
+	}
+	for _, material := range report.Orphaned {
+		fmt.Fprintf(w, "%s %s version %d created %s\n", action, material.MaterialName, material.Version, material.CreatedAt.UTC().Format(time.RFC3339))
+	}
+	fmt.Fprintf(w, "%s: scanned %d items, %d of %d material versions orphaned\n", strings.Join(report.Tables, ", "), report.Items, len(report.Orphaned), report.Versions)
+}
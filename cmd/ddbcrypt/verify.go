@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/ddbjson"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/encrypted"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider/store"
+)
+
+// errIntegrityFailures is returned by the verify command when any item failed verification, so scripts can rely on
+// the exit status.
+var errIntegrityFailures = errors.New("items failed verification")
+
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	table := fs.String("table", "", "encrypted table to verify")
+	keyURI := fs.String("key-uri", "", "AWS KMS key ARN used to wrap data keys")
+	metaTable := fs.String("meta-table", "meta", "DynamoDB table holding cryptographic materials")
+	policyFile := fs.String("policy", "", "encryption policy document of the table; by default every attribute is encrypted")
+	signaturesOnly := fs.Bool("signatures-only", false, "only verify signatures, without decrypting or calling kms:Decrypt; signed items need verification keys published with provider.WithVerificationKeys")
+	legacyDescriptions := fs.Bool("legacy-descriptions", false, "read materials stored before material descriptions were signed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *table == "" || (*keyURI == "" && !*signaturesOnly) {
+		return fmt.Errorf("-table and -key-uri are required")
+	}
+
+	ctx := context.Background()
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to load SDK config: %v", err)
+	}
+	client := dynamodb.NewFromConfig(awsCfg)
+
+	materialStore, err := store.NewMetaStore(client, *metaTable)
+	if err != nil {
+		return fmt.Errorf("failed to create key material store: %v", err)
+	}
+	providerOpts := []provider.ProviderOption{provider.WithReadOnly()}
+	if *legacyDescriptions {
+		providerOpts = append(providerOpts, provider.WithLegacyDescriptions())
+	}
+	cmp, err := provider.NewAwsKmsCryptographicMaterialsProvider(*keyURI, nil, materialStore, providerOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to create cryptographic materials provider: %v", err)
+	}
+	defer cmp.(*provider.AwsKmsCryptographicMaterialsProvider).Close()
+
+	clientConfig := encrypted.NewClientConfig(encrypted.WithDefaultEncryption(encrypted.EncryptStandard))
+	if *policyFile != "" {
+		policy, err := encrypted.LoadPolicyFile(*policyFile)
+		if err != nil {
+			return err
+		}
+		if err := policy.Apply(clientConfig); err != nil {
+			return err
+		}
+	}
+	opts := []encrypted.EncryptedClientOption{encrypted.WithClientConfig(clientConfig)}
+	if *signaturesOnly {
+		opts = append(opts, encrypted.WithVerificationOnly(encrypted.MaskEncrypted))
+	}
+	ec := encrypted.NewEncryptedClient(client, cmp, opts...)
+	defer ec.Close()
+
+	report, err := ec.VerifyTable(ctx, *table)
+	if err != nil {
+		return err
+	}
+	if err := printIntegrityReport(os.Stdout, report); err != nil {
+		return err
+	}
+	if len(report.Failures) > 0 {
+		return errIntegrityFailures
+	}
+	return nil
+}
+
+// printIntegrityReport writes one line per failed item, keyed by its primary key in DynamoDB JSON, and a summary.
+func printIntegrityReport(w io.Writer, report *encrypted.IntegrityReport) error {
+	for _, failure := range report.Failures {
+		key, err := ddbjson.MarshalItem(failure.Key)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "FAILED %s: %v\n", key, failure.Err)
+	}
+	fmt.Fprintf(w, "%s: verified %d items, %d failed\n", report.Table, report.Items, len(report.Failures))
+	return nil
+}
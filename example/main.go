@@ -49,6 +49,7 @@ func main() {
 
 	clientConfig := encrypted.NewClientConfig(
 		encrypted.WithDefaultEncryption(encrypted.EncryptStandard),
+		encrypted.WithMaterialCleanup(true),
 	)
 
 	// Initialize EncryptedClient
@@ -2,10 +2,9 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -26,54 +25,39 @@ type Secret struct {
 	Metadata    map[string]string `dynamodbav:"Metadata"`
 	CreatedAt   int64             `dynamodbav:"CreatedAt"`
 	UpdatedAt   int64             `dynamodbav:"UpdatedAt"`
-	Enabled     bool              `dynamodbav:"Enabled"`
+	Enabled     bool              `dynamodbav:"VersionEnabled"`
 	ExpiresAt   int64             `dynamodbav:"ExpiresAt"`
 }
 
 // SecretManager manages operations on secrets
 type SecretManager struct {
 	encryptedTable *encrypted.EncryptedTable
-	db             *dynamodb.Client
+	versions       *encrypted.VersionedItemStore
 	tableName      string
 }
 
 // NewSecretManager creates a new instance of SecretManager
-func NewSecretManager(et *encrypted.EncryptedTable, db *dynamodb.Client, tableName string) *SecretManager {
+func NewSecretManager(et *encrypted.EncryptedTable, tableName string) *SecretManager {
 	return &SecretManager{
 		encryptedTable: et,
-		db:             db,
+		versions:       encrypted.NewVersionedItemStore(et, tableName),
 		tableName:      tableName,
 	}
 }
 
-// ReadLatestSecretVersion queries DynamoDB to find the latest version of a secret.
+// ReadLatestSecretVersion reads the latest enabled version of a secret, or nil if the secret has none.
 func (sm *SecretManager) ReadLatestSecretVersion(ctx context.Context, tenantID, secretID string) (*Secret, error) {
-	// Construct the query input
-	input := &dynamodb.QueryInput{
-		TableName:              aws.String(sm.tableName),
-		KeyConditionExpression: aws.String("TenantID = :tenantID AND begins_with(NameVersion, :name)"),
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":tenantID": &types.AttributeValueMemberS{Value: tenantID},
-			":name":     &types.AttributeValueMemberS{Value: secretID + "#"},
-		},
-		ScanIndexForward: aws.Bool(false),
-		Limit:            aws.Int32(1),
+	latest, err := sm.versions.Latest(ctx, &types.AttributeValueMemberS{Value: tenantID}, secretID)
+	if errors.Is(err, encrypted.ErrVersionNotFound) {
+		return nil, nil
 	}
-
-	// Execute the query
-	resp, err := sm.encryptedTable.Query(ctx, sm.tableName, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query latest secret version: %w", err)
-	}
-
-	// Check if a secret was returned
-	if len(resp.Items) == 0 {
-		return nil, nil // No versions found, return nil to indicate a new secret should start with version 1
+		return nil, fmt.Errorf("failed to read latest secret version: %w", err)
 	}
 
 	// Unmarshal the result into a Secret struct
 	var secret Secret
-	err = attributevalue.UnmarshalMap(resp.Items[0], &secret)
+	err = attributevalue.UnmarshalMap(latest.Item, &secret)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal secret: %w", err)
 	}
@@ -81,51 +65,15 @@ func (sm *SecretManager) ReadLatestSecretVersion(ctx context.Context, tenantID,
 	return &secret, nil
 }
 
-// incrementVersion queries for the latest version of a secret and increments it
-func (sm *SecretManager) incrementVersion(ctx context.Context, tenantID, secretID string) (int, error) {
-	latestSecret, err := sm.ReadLatestSecretVersion(ctx, tenantID, secretID)
-	if err != nil {
-		return 0, fmt.Errorf("failed to get latest secret version: %w", err)
-	}
-
-	var version int
-	if latestSecret != nil {
-		// Split NameVersion to get the version part
-		parts := strings.Split(latestSecret.NameVersion, "#")
-		if len(parts) == 2 {
-			version, err = strconv.Atoi(parts[1])
-			if err != nil {
-				return 0, fmt.Errorf("failed to parse version number: %w", err)
-			}
-			version++
-		} else {
-			return 0, fmt.Errorf("unexpected NameVersion format: %s", latestSecret.NameVersion)
-		}
-	} else {
-		// If no secrets found, start with version 1
-		version = 1
-	}
-
-	return version, nil
-}
-
 // WriteSecret writes a new version of a secret to the database
 func (sm *SecretManager) WriteSecret(ctx context.Context, tenantID, secretID string, plaintext []byte, metadata map[string]string) error {
-	version, err := sm.incrementVersion(ctx, tenantID, secretID)
-	if err != nil {
-		return fmt.Errorf("failed to increment version: %w", err)
-	}
-
-	// Create a new secret
+	// Create a new secret; the store sets the key and enabled attributes
 	secret := Secret{
-		TenantID:    tenantID,
-		NameVersion: fmt.Sprintf("%s#%d", secretID, version),
-		Data:        plaintext,
-		Metadata:    metadata,
-		CreatedAt:   time.Now().Unix(),
-		UpdatedAt:   time.Now().Unix(),
-		Enabled:     true,
-		ExpiresAt:   time.Now().Add(365 * 24 * time.Hour).Unix(),
+		Data:      plaintext,
+		Metadata:  metadata,
+		CreatedAt: time.Now().Unix(),
+		UpdatedAt: time.Now().Unix(),
+		ExpiresAt: time.Now().Add(365 * 24 * time.Hour).Unix(),
 	}
 
 	// Convert Secret struct to map[string]types.AttributeValue for DynamoDB
@@ -133,9 +81,15 @@ func (sm *SecretManager) WriteSecret(ctx context.Context, tenantID, secretID str
 	if err != nil {
 		return fmt.Errorf("failed to marshal secret: %w", err)
 	}
+	delete(item, "TenantID")
+	delete(item, "NameVersion")
+	delete(item, "VersionEnabled")
 
-	// Write the secret to the database using EncryptedTable
-	return sm.encryptedTable.PutItem(ctx, sm.tableName, item)
+	// Write the secret as the next version, failing if another writer created it first
+	if _, err := sm.versions.Put(ctx, &types.AttributeValueMemberS{Value: tenantID}, secretID, item); err != nil {
+		return fmt.Errorf("failed to write secret: %w", err)
+	}
+	return nil
 }
 
 func main() {
@@ -179,7 +133,7 @@ func main() {
 
 	// Initialize EncryptedTable and SecretManager
 	et := encrypted.NewEncryptedTable(ec)
-	sm := NewSecretManager(et, dynamoDBClient, tableName)
+	sm := NewSecretManager(et, tableName)
 
 	// Attempt to create the UserSecretsTest table
 	err = sm.encryptedTable.CreateTable(ctx, tableName, []types.AttributeDefinition{
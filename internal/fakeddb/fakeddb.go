@@ -0,0 +1,149 @@
+// Package fakeddb provides an in-memory DynamoDB client holding meta tables, for tests of materials providers and
+// clients that need a working material store without DynamoDB.
+package fakeddb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// MetaTables stores the items of meta tables, keyed by their MaterialName and Version attributes. It serves the
+// operations of store.MetaStore: GetItem, PutItem, DeleteItem, BatchWriteItem, the puts of TransactWriteItems, and queries for the
+// versions of one material name. Conditions and projections are ignored.
+type MetaTables struct {
+	mu     sync.Mutex
+	tables map[string]map[string][]map[string]types.AttributeValue
+}
+
+// New returns empty meta tables.
+func New() *MetaTables {
+	return &MetaTables{tables: make(map[string]map[string][]map[string]types.AttributeValue)}
+}
+
+// Client returns a DynamoDB client whose requests are served by the tables instead of being sent.
+func (m *MetaTables) Client() *dynamodb.Client {
+	return dynamodb.New(dynamodb.Options{
+		Region: "us-east-1",
+		APIOptions: []func(*middleware.Stack) error{func(stack *middleware.Stack) error {
+			return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("fakeddb", func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				result, err := m.handle(in.Parameters)
+				return middleware.InitializeOutput{Result: result}, middleware.Metadata{}, err
+			}), middleware.Before)
+		}},
+	})
+}
+
+func (m *MetaTables) handle(input interface{}) (interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch input := input.(type) {
+	case *dynamodb.GetItemInput:
+		_, i, ok := m.find(aws.ToString(input.TableName), input.Key)
+		if !ok {
+			return &dynamodb.GetItemOutput{}, nil
+		}
+		return &dynamodb.GetItemOutput{Item: m.tables[aws.ToString(input.TableName)][materialName(input.Key)][i]}, nil
+	case *dynamodb.PutItemInput:
+		m.put(aws.ToString(input.TableName), input.Item)
+		return &dynamodb.PutItemOutput{}, nil
+	case *dynamodb.DeleteItemInput:
+		m.delete(aws.ToString(input.TableName), input.Key)
+		return &dynamodb.DeleteItemOutput{}, nil
+	case *dynamodb.BatchWriteItemInput:
+		for tableName, writeRequests := range input.RequestItems {
+			for _, writeRequest := range writeRequests {
+				switch {
+				case writeRequest.PutRequest != nil:
+					m.put(tableName, writeRequest.PutRequest.Item)
+				case writeRequest.DeleteRequest != nil:
+					m.delete(tableName, writeRequest.DeleteRequest.Key)
+				}
+			}
+		}
+		return &dynamodb.BatchWriteItemOutput{}, nil
+	case *dynamodb.TransactWriteItemsInput:
+		for _, item := range input.TransactItems {
+			if item.Put == nil {
+				return nil, fmt.Errorf("fakeddb: unsupported transaction action")
+			}
+			m.put(aws.ToString(item.Put.TableName), item.Put.Item)
+		}
+		return &dynamodb.TransactWriteItemsOutput{}, nil
+	case *dynamodb.QueryInput:
+		items := append([]map[string]types.AttributeValue(nil), m.tables[aws.ToString(input.TableName)][stringValue(input.ExpressionAttributeValues[":materialName"])]...)
+		if input.ScanIndexForward != nil && !*input.ScanIndexForward {
+			for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+				items[i], items[j] = items[j], items[i]
+			}
+		}
+		if input.Limit != nil && int(*input.Limit) < len(items) {
+			items = items[:*input.Limit]
+		}
+		return &dynamodb.QueryOutput{Items: items, Count: int32(len(items))}, nil
+	}
+	return nil, fmt.Errorf("fakeddb: unsupported operation %T", input)
+}
+
+// put stores item, replacing the item with the same key. The caller holds mu.
+func (m *MetaTables) put(tableName string, item map[string]types.AttributeValue) {
+	if m.tables[tableName] == nil {
+		m.tables[tableName] = make(map[string][]map[string]types.AttributeValue)
+	}
+	items, i, ok := m.find(tableName, item)
+	if ok {
+		items[i] = item
+		return
+	}
+	items = append(items, item)
+	sort.Slice(items, func(i, j int) bool { return version(items[i]) < version(items[j]) })
+	m.tables[tableName][materialName(item)] = items
+}
+
+// delete removes the item with the given key, if any. The caller holds mu.
+func (m *MetaTables) delete(tableName string, key map[string]types.AttributeValue) {
+	if items, i, ok := m.find(tableName, key); ok {
+		m.tables[tableName][materialName(key)] = append(items[:i:i], items[i+1:]...)
+	}
+}
+
+// find returns the items under the material name of key, and the index of the item with its version. The caller
+// holds mu.
+func (m *MetaTables) find(tableName string, key map[string]types.AttributeValue) ([]map[string]types.AttributeValue, int, bool) {
+	items := m.tables[tableName][materialName(key)]
+	for i, item := range items {
+		if version(item) == version(key) {
+			return items, i, true
+		}
+	}
+	return items, 0, false
+}
+
+func materialName(item map[string]types.AttributeValue) string {
+	return stringValue(item["MaterialName"])
+}
+
+func version(item map[string]types.AttributeValue) int64 {
+	n, _ := item["Version"].(*types.AttributeValueMemberN)
+	if n == nil {
+		return 0
+	}
+	v, _ := strconv.ParseInt(n.Value, 10, 64)
+	return v
+}
+
+func stringValue(value types.AttributeValue) string {
+	s, _ := value.(*types.AttributeValueMemberS)
+	if s == nil {
+		return ""
+	}
+	return s.Value
+}
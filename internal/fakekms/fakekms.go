@@ -33,6 +33,32 @@ type fakeAWSKMS struct {
 	kmsiface.KMSAPI
 	aeads  map[string]tink.AEAD
 	keyIDs []string
+	faults *Faults
+}
+
+// Option configures the fake AWS KMS API.
+type Option func(*fakeAWSKMS)
+
+// WithFaults injects the failures configured in faults into every Encrypt and Decrypt call.
+func WithFaults(faults *Faults) Option {
+	return func(f *fakeAWSKMS) {
+		f.faults = faults
+	}
+}
+
+// WithKeyset serves the key ID with the AEAD of a fixed keyset instead of a newly generated one, so ciphertexts
+// written by an earlier run can be decrypted.
+func WithKeyset(keyID string, handle *keyset.Handle) Option {
+	return func(f *fakeAWSKMS) {
+		a, err := aead.New(handle)
+		if err != nil {
+			panic(fmt.Sprintf("fakeawskms: invalid keyset for %q: %v", keyID, err))
+		}
+		if _, ok := f.aeads[keyID]; !ok {
+			f.keyIDs = append(f.keyIDs, keyID)
+		}
+		f.aeads[keyID] = a
+	}
 }
 
 // serializeContext serializes the context map in a canonical way into a byte array.
@@ -55,7 +81,7 @@ func serializeContext(context map[string]*string) []byte {
 }
 
 // New returns a new fake AWS KMS API.
-func New(validKeyIDs []string) (kmsiface.KMSAPI, error) {
+func New(validKeyIDs []string, opts ...Option) (kmsiface.KMSAPI, error) {
 	aeads := make(map[string]tink.AEAD)
 	for _, keyID := range validKeyIDs {
 		handle, err := keyset.NewHandle(aead.AES256GCMKeyTemplate())
@@ -68,13 +94,27 @@ func New(validKeyIDs []string) (kmsiface.KMSAPI, error) {
 		}
 		aeads[keyID] = a
 	}
-	return &fakeAWSKMS{
+	f := &fakeAWSKMS{
 		aeads:  aeads,
 		keyIDs: validKeyIDs,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f, nil
+}
+
+func (f *fakeAWSKMS) injectFault(keyID string) error {
+	if f.faults == nil {
+		return nil
+	}
+	return f.faults.inject(keyID)
 }
 
 func (f *fakeAWSKMS) Encrypt(request *kms.EncryptInput) (*kms.EncryptOutput, error) {
+	if err := f.injectFault(*request.KeyId); err != nil {
+		return nil, err
+	}
 	a, ok := f.aeads[*request.KeyId]
 	if !ok {
 		return nil, fmt.Errorf("unknown keyID: %q not in %q", *request.KeyId, f.keyIDs)
@@ -93,6 +133,9 @@ func (f *fakeAWSKMS) Encrypt(request *kms.EncryptInput) (*kms.EncryptOutput, err
 func (f *fakeAWSKMS) Decrypt(request *kms.DecryptInput) (*kms.DecryptOutput, error) {
 	serializedContext := serializeContext(request.EncryptionContext)
 	if request.KeyId != nil {
+		if err := f.injectFault(*request.KeyId); err != nil {
+			return nil, err
+		}
 		a, ok := f.aeads[*request.KeyId]
 		if !ok {
 			return nil, fmt.Errorf("unknown keyID: %q not in %q", *request.KeyId, f.keyIDs)
@@ -110,6 +153,9 @@ func (f *fakeAWSKMS) Decrypt(request *kms.DecryptInput) (*kms.DecryptOutput, err
 	for keyID, a := range f.aeads {
 		plaintext, err := a.Decrypt(request.CiphertextBlob, serializedContext)
 		if err == nil {
+			if err := f.injectFault(keyID); err != nil {
+				return nil, err
+			}
 			return &kms.DecryptOutput{
 				Plaintext: plaintext,
 				KeyId:     &keyID,
@@ -0,0 +1,108 @@
+package fakeawskms
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// ErrCodeThrottling is the error code returned for injected random failures, matching KMS request throttling.
+const ErrCodeThrottling = "ThrottlingException"
+
+// Faults configures failures injected by the fake KMS. Its settings may be changed between calls,
+// so a test can, for example, disable a key halfway through.
+type Faults struct {
+	mu                 sync.Mutex
+	rand               *rand.Rand
+	errorRate          float64
+	latency            time.Duration
+	disabledKeys       map[string]bool
+	unavailableRegions map[string]bool
+}
+
+// NewFaults returns a fault configuration whose random failures are derived from seed,
+// so a given sequence of calls fails identically on every run.
+func NewFaults(seed int64) *Faults {
+	return &Faults{
+		rand:               rand.New(rand.NewSource(seed)),
+		disabledKeys:       make(map[string]bool),
+		unavailableRegions: make(map[string]bool),
+	}
+}
+
+// SetErrorRate makes the given fraction (0 to 1) of calls fail with a throttling error.
+func (f *Faults) SetErrorRate(rate float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errorRate = rate
+}
+
+// SetLatency delays every call by the given duration.
+func (f *Faults) SetLatency(latency time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.latency = latency
+}
+
+// DisableKey makes every call using the key fail with a DisabledException.
+func (f *Faults) DisableKey(keyID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.disabledKeys[keyID] = true
+}
+
+// EnableKey reverts DisableKey.
+func (f *Faults) EnableKey(keyID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.disabledKeys, keyID)
+}
+
+// SetRegionUnavailable makes every call using a key ARN in the region fail with a KMSInternalException.
+func (f *Faults) SetRegionUnavailable(region string, unavailable bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if unavailable {
+		f.unavailableRegions[region] = true
+	} else {
+		delete(f.unavailableRegions, region)
+	}
+}
+
+// inject applies the configured latency and returns the injected error for a call with the key, if any.
+func (f *Faults) inject(keyID string) error {
+	f.mu.Lock()
+	latency := f.latency
+	disabled := f.disabledKeys[keyID]
+	unavailable := f.unavailableRegions[regionFromARN(keyID)]
+	throttled := f.errorRate > 0 && f.rand.Float64() < f.errorRate
+	f.mu.Unlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+
+	switch {
+	case disabled:
+		return awserr.New(kms.ErrCodeDisabledException, fmt.Sprintf("%s is disabled", keyID), nil)
+	case unavailable:
+		return awserr.New(kms.ErrCodeInternalException, fmt.Sprintf("region of %s is unavailable", keyID), nil)
+	case throttled:
+		return awserr.New(ErrCodeThrottling, "rate exceeded", nil)
+	}
+	return nil
+}
+
+// regionFromARN extracts the region from a key ARN such as arn:aws:kms:eu-west-2:111122223333:key/...
+func regionFromARN(keyID string) string {
+	parts := strings.Split(keyID, ":")
+	if len(parts) < 4 || parts[0] != "arn" {
+		return ""
+	}
+	return parts[3]
+}
@@ -0,0 +1,73 @@
+package fakeawskms
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+const keyARN = "arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b"
+
+func encrypt(t *testing.T, faults *Faults) error {
+	t.Helper()
+	fake, err := New([]string{keyARN}, WithFaults(faults))
+	if err != nil {
+		t.Fatalf("failed to create fake KMS: %v", err)
+	}
+	_, err = fake.Encrypt(&kms.EncryptInput{KeyId: aws.String(keyARN), Plaintext: []byte("plaintext")})
+	return err
+}
+
+func errorCode(err error) string {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code()
+	}
+	return ""
+}
+
+func TestFaults_DisabledKey(t *testing.T) {
+	faults := NewFaults(1)
+	faults.DisableKey(keyARN)
+	if code := errorCode(encrypt(t, faults)); code != kms.ErrCodeDisabledException {
+		t.Errorf("expected %s, got %q", kms.ErrCodeDisabledException, code)
+	}
+
+	faults.EnableKey(keyARN)
+	if err := encrypt(t, faults); err != nil {
+		t.Errorf("expected success after enabling key, got %v", err)
+	}
+}
+
+func TestFaults_UnavailableRegion(t *testing.T) {
+	faults := NewFaults(1)
+	faults.SetRegionUnavailable("eu-west-2", true)
+	if code := errorCode(encrypt(t, faults)); code != kms.ErrCodeInternalException {
+		t.Errorf("expected %s, got %q", kms.ErrCodeInternalException, code)
+	}
+}
+
+func TestFaults_ErrorRateIsDeterministic(t *testing.T) {
+	run := func() []bool {
+		faults := NewFaults(42)
+		faults.SetErrorRate(0.5)
+		fake, err := New([]string{keyARN}, WithFaults(faults))
+		if err != nil {
+			t.Fatalf("failed to create fake KMS: %v", err)
+		}
+		var failures []bool
+		for i := 0; i < 20; i++ {
+			_, err := fake.Encrypt(&kms.EncryptInput{KeyId: aws.String(keyARN), Plaintext: []byte("plaintext")})
+			failures = append(failures, errorCode(err) == ErrCodeThrottling)
+		}
+		return failures
+	}
+
+	first, second := run(), run()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("expected identical failure sequences for the same seed")
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package compression
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ID is the stable identifier stored alongside compressed payloads. Once data has been written with an ID,
+// the same algorithm must stay registered under it for that data to remain readable.
+type ID byte
+
+const (
+	// None marks uncompressed data and can not be registered.
+	None ID = 0
+	// Gzip is the built-in gzip compressor.
+	Gzip ID = 1
+	// Zstd is the built-in zstd compressor, which compresses faster and smaller than gzip.
+	Zstd ID = 2
+)
+
+// ErrUnknownAlgorithm is returned when no compressor is registered for an ID.
+var ErrUnknownAlgorithm = errors.New("unknown compression algorithm")
+
+// ErrAlreadyRegistered is returned when registering a compressor under an ID that is already taken.
+var ErrAlreadyRegistered = errors.New("compression algorithm already registered")
+
+// Compressor creates compressing writers and decompressing readers for one algorithm.
+type Compressor interface {
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+var (
+	mu          sync.RWMutex
+	compressors = map[ID]Compressor{
+		Gzip: gzipCompressor{},
+		Zstd: &ZstdCompressor{},
+	}
+)
+
+// Register makes a compressor available under the given ID for both writing and reading.
+func Register(id ID, c Compressor) error {
+	if id == None {
+		return fmt.Errorf("compression ID %d is reserved", id)
+	}
+	if c == nil {
+		return fmt.Errorf("compressor for ID %d is nil", id)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := compressors[id]; ok {
+		return fmt.Errorf("%w: ID %d", ErrAlreadyRegistered, id)
+	}
+	compressors[id] = c
+	return nil
+}
+
+// Lookup returns the compressor registered under the given ID.
+func Lookup(id ID) (Compressor, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := compressors[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: ID %d", ErrUnknownAlgorithm, id)
+	}
+	return c, nil
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
@@ -0,0 +1,94 @@
+package compression
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+type flateCompressor struct{}
+
+func (flateCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return flate.NewWriter(w, flate.BestSpeed)
+}
+
+func (flateCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return flate.NewReader(r), nil
+}
+
+func TestRegistry(t *testing.T) {
+	for _, id := range []ID{Gzip, Zstd} {
+		if _, err := Lookup(id); err != nil {
+			t.Fatalf("Expected ID %d to be registered: %v", id, err)
+		}
+	}
+
+	const custom ID = 200
+	if _, err := Lookup(custom); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("Expected error '%v', got '%v'", ErrUnknownAlgorithm, err)
+	}
+	if err := Register(custom, flateCompressor{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := Lookup(custom); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := Register(custom, flateCompressor{}); !errors.Is(err, ErrAlreadyRegistered) {
+		t.Errorf("Expected error '%v', got '%v'", ErrAlreadyRegistered, err)
+	}
+	if err := Register(None, flateCompressor{}); err == nil {
+		t.Errorf("Expected error registering the reserved ID")
+	}
+}
+
+func TestBuiltinCompressors(t *testing.T) {
+	data := []byte(strings.Repeat(`{"WrappedKeyset":"CiQAxQ3JtI2ZFmN0VdqvbkS"}`, 50))
+
+	testCases := []struct {
+		name string
+		id   ID
+	}{
+		{"gzip", Gzip},
+		{"zstd", Zstd},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := Lookup(tc.id)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var buf bytes.Buffer
+			w, err := c.NewWriter(&buf)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if _, err := w.Write(data); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if buf.Len() >= len(data) {
+				t.Errorf("Expected compressed data to be smaller than %d bytes, got %d", len(data), buf.Len())
+			}
+
+			r, err := c.NewReader(&buf)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer r.Close()
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("Expected the decompressed data to match the original")
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package compression
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// DefaultDictionarySize is the content size of dictionaries trained with TrainZstdDictionary unless another size
+// is given.
+const DefaultDictionarySize = 32 << 10
+
+// ErrUnknownDictionary is returned when reading data compressed with a dictionary the compressor does not hold.
+var ErrUnknownDictionary = errors.New("unknown zstd dictionary")
+
+// ZstdCompressor is a zstd Compressor that can compress with a dictionary. Small attributes of similar content,
+// such as JSON documents of one shape, compress several times better with a dictionary trained on samples of them.
+// Every compressed frame records the ID of its dictionary, so data compressed with earlier dictionaries stays readable
+// as long as they are passed to WithZstdDecoderDictionaries. The built-in Zstd compressor is a ZstdCompressor without
+// dictionaries; register one with dictionaries under an ID of its own:
+//
+//	dict, err := compression.TrainZstdDictionary(1, samples, 0)
+//	c, err := compression.NewZstdCompressor(compression.WithZstdDictionary(dict))
+//	err = compression.Register(10, c)
+type ZstdCompressor struct {
+	dictionary []byte
+	decoders   map[uint32][]byte
+}
+
+// ZstdOption configures a ZstdCompressor.
+type ZstdOption func(*ZstdCompressor) error
+
+// WithZstdDictionary compresses with the given dictionary, in the zstd dictionary format produced by
+// TrainZstdDictionary or "zstd --train". Data compressed with it can be read too.
+func WithZstdDictionary(dictionary []byte) ZstdOption {
+	return func(c *ZstdCompressor) error {
+		if err := c.addDecoderDictionary(dictionary); err != nil {
+			return err
+		}
+		c.dictionary = dictionary
+		return nil
+	}
+}
+
+// WithZstdDecoderDictionaries makes data compressed with the given dictionaries readable, for example after
+// compression moved to a newly trained dictionary.
+func WithZstdDecoderDictionaries(dictionaries ...[]byte) ZstdOption {
+	return func(c *ZstdCompressor) error {
+		for _, dictionary := range dictionaries {
+			if err := c.addDecoderDictionary(dictionary); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// NewZstdCompressor creates a ZstdCompressor. Dictionaries must have distinct, non-zero IDs.
+func NewZstdCompressor(opts ...ZstdOption) (*ZstdCompressor, error) {
+	c := &ZstdCompressor{decoders: make(map[uint32][]byte)}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *ZstdCompressor) addDecoderDictionary(dictionary []byte) error {
+	d, err := zstd.InspectDictionary(dictionary)
+	if err != nil {
+		return fmt.Errorf("invalid zstd dictionary: %w", err)
+	}
+	if d.ID() == 0 {
+		return errors.New("zstd dictionary ID must not be 0")
+	}
+	if existing, ok := c.decoders[d.ID()]; ok && string(existing) != string(dictionary) {
+		return fmt.Errorf("two zstd dictionaries with ID %d", d.ID())
+	}
+	c.decoders[d.ID()] = dictionary
+	return nil
+}
+
+// DictionaryID returns the ID of the dictionary the compressor writes with, or 0 without one.
+func (c *ZstdCompressor) DictionaryID() uint32 {
+	if c.dictionary == nil {
+		return 0
+	}
+	d, _ := zstd.InspectDictionary(c.dictionary)
+	return d.ID()
+}
+
+func (c *ZstdCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	opts := []zstd.EOption{zstd.WithEncoderConcurrency(1)}
+	if c.dictionary != nil {
+		opts = append(opts, zstd.WithEncoderDict(c.dictionary))
+	}
+	return zstd.NewWriter(w, opts...)
+}
+
+func (c *ZstdCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	opts := []zstd.DOption{zstd.WithDecoderConcurrency(1)}
+	if len(c.decoders) > 0 {
+		dictionaries := make([][]byte, 0, len(c.decoders))
+		for _, dictionary := range c.decoders {
+			dictionaries = append(dictionaries, dictionary)
+		}
+		opts = append(opts, zstd.WithDecoderDicts(dictionaries...))
+	}
+	d, err := zstd.NewReader(r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &zstdReadCloser{ReadCloser: d.IOReadCloser()}, nil
+}
+
+// zstdReadCloser reports data compressed with a missing dictionary as ErrUnknownDictionary.
+type zstdReadCloser struct {
+	io.ReadCloser
+}
+
+func (r *zstdReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if errors.Is(err, zstd.ErrUnknownDictionary) {
+		err = fmt.Errorf("%w: %v", ErrUnknownDictionary, err)
+	}
+	return n, err
+}
+
+// TrainZstdDictionary builds a zstd dictionary with the given ID from samples of the data it will compress, such as
+// attribute values read from a table. The dictionary content is taken from the end of the later half of the samples,
+// up to size bytes, or DefaultDictionarySize when size is 0, and its entropy tables are tuned on the earlier half.
+// Larger dictionaries help more varied data, at the cost of memory in every reader and writer. The ID is recorded in
+// every frame compressed with the dictionary, so each dictionary in use must have a different one.
+func TrainZstdDictionary(id uint32, samples [][]byte, size int) (dictionary []byte, err error) {
+	if id == 0 {
+		return nil, errors.New("zstd dictionary ID must not be 0")
+	}
+	if len(samples) < 2 {
+		return nil, errors.New("at least 2 samples are required to train a zstd dictionary")
+	}
+	if size <= 0 {
+		size = DefaultDictionarySize
+	}
+
+	contents, historySamples := samples[:len(samples)/2], samples[len(samples)/2:]
+	var history []byte
+	for i := len(historySamples) - 1; i >= 0 && len(history) < size; i-- {
+		history = append(historySamples[i][:len(historySamples[i]):len(historySamples[i])], history...)
+	}
+	if len(history) > size {
+		history = history[len(history)-size:]
+	}
+
+	// BuildDict panics on samples that leave no literals to tune the entropy tables on
+	defer func() {
+		if r := recover(); r != nil {
+			dictionary, err = nil, fmt.Errorf("failed to train zstd dictionary: samples too uniform: %v", r)
+		}
+	}()
+	dictionary, err = zstd.BuildDict(zstd.BuildDictOptions{
+		ID:       id,
+		Contents: contents,
+		History:  history,
+		Offsets:  [3]int{1, 4, 8},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to train zstd dictionary: %w", err)
+	}
+	return dictionary, nil
+}
+
+// ZstdDictionaryID returns the ID of the dictionary zstd data was compressed with, or 0 if none was used.
+func ZstdDictionaryID(data []byte) (uint32, error) {
+	var header zstd.Header
+	if err := header.Decode(data); err != nil {
+		return 0, fmt.Errorf("invalid zstd frame: %w", err)
+	}
+	return header.DictionaryID, nil
+}
@@ -0,0 +1,141 @@
+package compression
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// profileSamples returns small JSON documents of one shape, like the attributes dictionaries are meant for.
+func profileSamples(n, offset int) [][]byte {
+	samples := make([][]byte, n)
+	for i := range samples {
+		samples[i] = []byte(fmt.Sprintf(`{"customerId":"cust-%06d","status":"active","preferences":{"newsletter":true,"language":"en-GB","currency":"GBP"},"createdAt":"2024-03-%02dT10:15:00Z"}`, offset+i, i%28+1))
+	}
+	return samples
+}
+
+func compress(t *testing.T, c Compressor, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := c.NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func decompress(c Compressor, data []byte) ([]byte, error) {
+	r, err := c.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func TestZstdDictionary(t *testing.T) {
+	dictionary, err := TrainZstdDictionary(7, profileSamples(200, 0), 0)
+	if err != nil {
+		t.Fatalf("failed to train dictionary: %v", err)
+	}
+	withDictionary, err := NewZstdCompressor(WithZstdDictionary(dictionary))
+	if err != nil {
+		t.Fatalf("failed to create compressor: %v", err)
+	}
+	if withDictionary.DictionaryID() != 7 {
+		t.Errorf("expected dictionary ID 7, got %d", withDictionary.DictionaryID())
+	}
+	data := profileSamples(1, 5000)[0]
+
+	compressed := compress(t, withDictionary, data)
+	plain := compress(t, &ZstdCompressor{}, data)
+	if 2*len(compressed) > len(plain) {
+		t.Errorf("expected the dictionary to at least halve the compressed size, got %d bytes against %d", len(compressed), len(plain))
+	}
+	if id, err := ZstdDictionaryID(compressed); err != nil || id != 7 {
+		t.Errorf("expected the frame to record dictionary 7, got %d, %v", id, err)
+	}
+	if id, err := ZstdDictionaryID(plain); err != nil || id != 0 {
+		t.Errorf("expected no dictionary, got %d, %v", id, err)
+	}
+
+	next, err := TrainZstdDictionary(8, profileSamples(200, 1000), 4096)
+	if err != nil {
+		t.Fatalf("failed to train dictionary: %v", err)
+	}
+	rotated, err := NewZstdCompressor(WithZstdDictionary(next), WithZstdDecoderDictionaries(dictionary))
+	if err != nil {
+		t.Fatalf("failed to create compressor: %v", err)
+	}
+
+	testCases := []struct {
+		name       string
+		compressor Compressor
+		data       []byte
+		err        error
+	}{
+		{name: "Dictionary", compressor: withDictionary, data: compressed},
+		{name: "Without dictionary", compressor: withDictionary, data: plain},
+		{name: "Earlier dictionary", compressor: rotated, data: compressed},
+		{name: "Current dictionary", compressor: rotated, data: compress(t, rotated, data)},
+		{name: "Missing dictionary", compressor: &ZstdCompressor{}, data: compressed, err: ErrUnknownDictionary},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := decompress(tc.compressor, tc.data)
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Errorf("expected %v, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("expected the data back, got %q", got)
+			}
+		})
+	}
+}
+
+func TestZstdDictionary_Invalid(t *testing.T) {
+	dictionary, err := TrainZstdDictionary(1, profileSamples(50, 0), 0)
+	if err != nil {
+		t.Fatalf("failed to train dictionary: %v", err)
+	}
+	other, err := TrainZstdDictionary(1, profileSamples(50, 500), 0)
+	if err != nil {
+		t.Fatalf("failed to train dictionary: %v", err)
+	}
+
+	if _, err := TrainZstdDictionary(0, profileSamples(50, 0), 0); err == nil {
+		t.Errorf("expected an error for dictionary ID 0")
+	}
+	if _, err := TrainZstdDictionary(1, nil, 0); err == nil {
+		t.Errorf("expected an error without samples")
+	}
+	uniform := [][]byte{[]byte(`{"status":"active"}`), []byte(`{"status":"active"}`)}
+	if _, err := TrainZstdDictionary(1, uniform, 0); err == nil {
+		t.Errorf("expected an error for samples without literals")
+	}
+	if _, err := NewZstdCompressor(WithZstdDictionary([]byte("not a dictionary"))); err == nil {
+		t.Errorf("expected an error for an invalid dictionary")
+	}
+	if _, err := NewZstdCompressor(WithZstdDictionary(dictionary), WithZstdDecoderDictionaries(other)); err == nil {
+		t.Errorf("expected an error for two dictionaries with one ID")
+	}
+	if _, err := ZstdDictionaryID([]byte("not zstd")); err == nil {
+		t.Errorf("expected an error for data that is not zstd")
+	}
+}
@@ -0,0 +1,339 @@
+// Package conformance ships fixed keysets and golden encrypted items, one file of vectors per serialization format
+// version, so that changes to serialization, encryption or materials handling can prove that data stored by earlier
+// releases still decrypts. Vectors are only ever added: once published, a vector's encrypted item must decrypt to its
+// plaintext, byte for byte, in every later release.
+//
+// The keysets are public and must never protect real data.
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/compression"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/ddbjson"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/encrypted"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/serde"
+	"github.com/tink-crypto/tink-go/v2/insecurecleartextkeyset"
+	"github.com/tink-crypto/tink-go/v2/keyset"
+)
+
+//go:embed keys/*.json vectors/*.json materials/*.json
+var files embed.FS
+
+// VectorsDir is the directory of the vector files, relative to this package.
+const VectorsDir = "vectors"
+
+// MaterialVersion is the material version the conformance provider pins on every item.
+const MaterialVersion = "1"
+
+// ErrMismatch is returned by Verify when a golden item does not decrypt to the vector's plaintext, or was not
+// stored as the vector describes.
+var ErrMismatch = errors.New("golden item does not match its vector")
+
+// Vector is a plaintext item together with the item stored for it by the release that added the vector. Items are
+// in DynamoDB JSON. The policy, attribute keys and item signatures are the settings the item was encrypted with.
+type Vector struct {
+	Name           string                 `json:"name"`
+	FormatVersion  byte                   `json:"formatVersion"`
+	Table          string                 `json:"table"`
+	PartitionKey   string                 `json:"partitionKey"`
+	SortKey        string                 `json:"sortKey,omitempty"`
+	Policy         encrypted.Policy       `json:"policy"`
+	AttributeKeys  bool                   `json:"attributeKeys,omitempty"`
+	ItemSignatures bool                   `json:"itemSignatures,omitempty"`
+	Plaintext      map[string]interface{} `json:"plaintext"`
+	Encrypted      map[string]interface{} `json:"encrypted,omitempty"`
+}
+
+// vectorFile is the layout of a vector file.
+type vectorFile struct {
+	Vectors []Vector `json:"vectors"`
+}
+
+// Vectors returns the vectors shipped with this package, ordered by format version.
+func Vectors() ([]Vector, error) {
+	names, err := fs.Glob(files, path.Join(VectorsDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	var vectors []Vector
+	for _, name := range names {
+		f, err := files.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		loaded, err := LoadVectors(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		vectors = append(vectors, loaded...)
+	}
+	return vectors, nil
+}
+
+// LoadVectors decodes a vector file.
+func LoadVectors(r io.Reader) ([]Vector, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	var file vectorFile
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to decode vectors: %v", err)
+	}
+	return file.Vectors, nil
+}
+
+// WriteVectors encodes a vector file.
+func WriteVectors(w io.Writer, vectors []Vector) error {
+	data, err := json.MarshalIndent(vectorFile{Vectors: vectors}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode vectors: %v", err)
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Keys returns the fixed data key and signing key every vector is encrypted with, and the serialized public key
+// verifying the signatures.
+func Keys() (*delegatedkeys.TinkDelegatedKey, *delegatedkeys.TinkDelegatedKey, []byte, error) {
+	dataKey, err := readKeyset("keys/data_keyset.json")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	signingKey, err := readKeyset("keys/signing_keyset.json")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	public, err := signingKey.Public()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to extract public key: %v", err)
+	}
+	var publicKey bytes.Buffer
+	if err := public.WriteWithNoSecrets(keyset.NewBinaryWriter(&publicKey)); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to serialize public key: %v", err)
+	}
+	return delegatedkeys.NewTinkDelegatedKey(dataKey, nil), delegatedkeys.NewTinkDelegatedKey(signingKey, nil), publicKey.Bytes(), nil
+}
+
+func readKeyset(name string) (*keyset.Handle, error) {
+	data, err := files.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	handle, err := insecurecleartextkeyset.Read(keyset.NewJSONReader(bytes.NewReader(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyset %s: %v", name, err)
+	}
+	return handle, nil
+}
+
+// staticProvider serves materials made of the fixed keys for every material name and version.
+type staticProvider struct {
+	description map[string]string
+	dataKey     delegatedkeys.DelegatedKey
+	signingKey  delegatedkeys.DelegatedKey
+}
+
+// Provider returns a materials provider serving the fixed keys for every material name, without KMS or a material
+// store.
+func Provider() (provider.CryptographicMaterialsProvider, error) {
+	dataKey, signingKey, publicKey, err := Keys()
+	if err != nil {
+		return nil, err
+	}
+	return &staticProvider{
+		description: map[string]string{
+			"ContentEncryptionAlgorithm": dataKey.Algorithm(),
+			"PublicKey":                  base64.StdEncoding.EncodeToString(publicKey),
+			provider.MaterialVersionKey:  MaterialVersion,
+		},
+		dataKey:    dataKey,
+		signingKey: signingKey,
+	}, nil
+}
+
+func (p *staticProvider) EncryptionMaterials(ctx context.Context, materialName string) (materials.CryptographicMaterials, error) {
+	return materials.NewEncryptionMaterials(p.description, p.dataKey, p.signingKey), nil
+}
+
+func (p *staticProvider) DecryptionMaterials(ctx context.Context, materialName string, version int64) (materials.CryptographicMaterials, error) {
+	return materials.NewDecryptionMaterials(p.description, p.dataKey), nil
+}
+
+func (p *staticProvider) TableName() string {
+	return ""
+}
+
+// NewClient returns a client configured with the vector's settings and the conformance provider. It never calls
+// DynamoDB.
+func NewClient(v Vector) (*encrypted.EncryptedClient, error) {
+	cmp, err := Provider()
+	if err != nil {
+		return nil, err
+	}
+	config := encrypted.NewClientConfig()
+	if err := v.Policy.Apply(config); err != nil {
+		return nil, err
+	}
+	config = config.With(encrypted.WithAttributeKeys(v.AttributeKeys), encrypted.WithItemSignatures(v.ItemSignatures))
+	return encrypted.NewEncryptedClient(nil, cmp,
+		encrypted.WithPrimaryKeyInfo(v.Table, encrypted.PrimaryKeyInfo{PartitionKey: v.PartitionKey, SortKey: v.SortKey}),
+		encrypted.WithClientConfig(config),
+	), nil
+}
+
+// Verify decrypts the vector's golden item and checks that it matches the plaintext, and that it was stored with the
+// format version and signature the vector describes.
+func Verify(ctx context.Context, v Vector) error {
+	if v.Encrypted == nil {
+		return fmt.Errorf("%w: vector %q has no golden item", ErrMismatch, v.Name)
+	}
+	ec, err := NewClient(v)
+	if err != nil {
+		return err
+	}
+	stored, err := ddbjson.ToItem(v.Encrypted)
+	if err != nil {
+		return err
+	}
+
+	var report encrypted.EncryptionReport
+	item, err := ec.DecryptItem(encrypted.ContextWithEncryptionReport(ctx, &report), v.Table, stored)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMismatch, err)
+	}
+	got, err := ddbjson.MarshalItem(item)
+	if err != nil {
+		return err
+	}
+	plaintext, err := ddbjson.ToItem(v.Plaintext)
+	if err != nil {
+		return err
+	}
+	want, err := ddbjson.MarshalItem(plaintext)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("%w: decrypted %s, want %s", ErrMismatch, got, want)
+	}
+
+	if len(report.Encrypted) == 0 {
+		return fmt.Errorf("%w: no attribute is encrypted", ErrMismatch)
+	}
+	compressed := v.FormatVersion == serde.FormatVersion2
+	if (len(report.Compressed) == len(report.Encrypted)) != compressed || (len(report.Compressed) == 0) == compressed {
+		return fmt.Errorf("%w: %d of %d encrypted attributes are compressed", ErrMismatch, len(report.Compressed), len(report.Encrypted))
+	}
+	if report.Signed != v.ItemSignatures {
+		return fmt.Errorf("%w: expected signed to be %v", ErrMismatch, v.ItemSignatures)
+	}
+	return nil
+}
+
+// Generate encrypts the vector's plaintext in its format version and returns the vector with the resulting golden
+// item. Version 0 items are written the way releases before the version header wrote them: with the data key
+// itself, the attribute name as associated data, no compression and no signature.
+func Generate(ctx context.Context, v Vector) (Vector, error) {
+	compressed := v.Policy.Compression != compression.None
+	switch v.FormatVersion {
+	case serde.FormatVersion0:
+		if compressed || v.AttributeKeys || v.ItemSignatures || (v.Policy.DeterministicScope != "" && v.Policy.DeterministicScope != "global") {
+			return v, fmt.Errorf("version 0 items can not use compression, attribute keys, signatures or deterministic scopes")
+		}
+	case serde.FormatVersion1:
+		if compressed {
+			return v, fmt.Errorf("version 1 items can not be compressed")
+		}
+	case serde.FormatVersion2:
+		if !compressed {
+			return v, fmt.Errorf("version 2 items must be compressed")
+		}
+	default:
+		return v, fmt.Errorf("%w: %d", serde.ErrUnsupportedVersion, v.FormatVersion)
+	}
+
+	ec, err := NewClient(v)
+	if err != nil {
+		return v, err
+	}
+	plaintext, err := ddbjson.ToItem(v.Plaintext)
+	if err != nil {
+		return v, err
+	}
+	stored, err := ec.EncryptItem(ctx, v.Table, plaintext)
+	if err != nil {
+		return v, err
+	}
+	if v.FormatVersion == serde.FormatVersion0 {
+		if err := downgrade(ec.Config(), v, stored); err != nil {
+			return v, err
+		}
+	}
+
+	v.Encrypted, err = ddbjson.FromItem(stored)
+	return v, err
+}
+
+// downgrade re-encrypts the encrypted attributes of a stored item as version 0 payloads, which lack the version
+// header of later versions.
+func downgrade(config *encrypted.ClientConfig, v Vector, stored map[string]types.AttributeValue) error {
+	dataKey, _, _, err := Keys()
+	if err != nil {
+		return err
+	}
+	keyInfo := &encrypted.PrimaryKeyInfo{PartitionKey: v.PartitionKey, SortKey: v.SortKey}
+	for name, value := range stored {
+		ciphertext, ok := value.(*types.AttributeValueMemberB)
+		if !ok || keyInfo.IsKeyAttribute(name) || config.Encryption.ActionFor(name) == encrypted.EncryptNone {
+			continue
+		}
+		if _, ok := v.Plaintext[name]; !ok {
+			continue
+		}
+
+		data, err := dataKey.Decrypt(ciphertext.Value, []byte(name))
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s: %v", name, err)
+		}
+		if version, err := serde.FormatVersion(data); err != nil || version != serde.FormatVersion1 {
+			return fmt.Errorf("unexpected format version of %s: %d (%v)", name, version, err)
+		}
+		if ciphertext.Value, err = dataKey.Encrypt(data[1:], []byte(name)); err != nil {
+			return fmt.Errorf("failed to encrypt %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// Run verifies every vector in a subtest named after it.
+func Run(t *testing.T, vectors []Vector) {
+	t.Helper()
+	for _, v := range vectors {
+		v := v
+		t.Run(v.Name, func(t *testing.T) {
+			if err := Verify(context.Background(), v); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/ddbjson"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+)
+
+var generate = flag.Bool("generate", false, "add golden items to the vectors that have none; existing golden items are kept")
+
+// TestGenerate fills in the golden items of newly added vectors when run with -generate. Vectors are only ever
+// appended, so existing golden items are never rewritten.
+func TestGenerate(t *testing.T) {
+	if !*generate {
+		t.Skip("run with -generate to add golden items to new vectors")
+	}
+	names, err := filepath.Glob(filepath.Join(VectorsDir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vectors, err := LoadVectors(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		for i, v := range vectors {
+			if v.Encrypted != nil {
+				continue
+			}
+			if vectors[i], err = Generate(context.Background(), v); err != nil {
+				t.Fatalf("%s: failed to generate %s: %v", name, v.Name, err)
+			}
+		}
+
+		f, err = os.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteVectors(f, vectors); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names, err = filepath.Glob(filepath.Join(MaterialsDir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stored, err := LoadMaterials(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		for i, m := range stored {
+			if m.Description != nil {
+				continue
+			}
+			if stored[i], err = GenerateLegacyMaterial(m); err != nil {
+				t.Fatalf("%s: failed to generate %s: %v", name, m.Name, err)
+			}
+		}
+
+		f, err = os.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteMaterials(f, stored); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestStoredMaterials(t *testing.T) {
+	stored, err := StoredMaterials()
+	if err != nil {
+		t.Fatalf("failed to load stored materials: %v", err)
+	}
+	if len(stored) == 0 {
+		t.Fatal("expected stored materials")
+	}
+
+	testCases := []struct {
+		name string
+		opts []provider.ProviderOption
+		err  error
+	}{
+		{name: "Legacy descriptions allowed", opts: []provider.ProviderOption{provider.WithLegacyDescriptions()}},
+		{name: "Legacy descriptions rejected", err: provider.ErrLegacyDescription},
+	}
+
+	for _, tc := range testCases {
+		for _, m := range stored {
+			t.Run(tc.name+"/"+m.Name, func(t *testing.T) {
+				err := VerifyMaterial(context.Background(), m, tc.opts...)
+				if tc.err == nil && err != nil {
+					t.Fatalf("failed to read stored material: %v", err)
+				}
+				if tc.err != nil && !errors.Is(err, tc.err) {
+					t.Fatalf("expected %v, got %v", tc.err, err)
+				}
+			})
+		}
+	}
+}
+
+func TestVectors(t *testing.T) {
+	vectors, err := Vectors()
+	if err != nil {
+		t.Fatalf("failed to load vectors: %v", err)
+	}
+	versions := make(map[byte]bool)
+	for _, v := range vectors {
+		versions[v.FormatVersion] = true
+	}
+	for _, version := range []byte{0, 1, 2} {
+		if !versions[version] {
+			t.Errorf("expected vectors for format version %d", version)
+		}
+	}
+	Run(t, vectors)
+}
+
+func TestVerify_Tampered(t *testing.T) {
+	vectors, err := Vectors()
+	if err != nil {
+		t.Fatalf("failed to load vectors: %v", err)
+	}
+	byName := make(map[string]Vector)
+	for _, v := range vectors {
+		byName[v.Name] = v
+	}
+
+	testCases := []struct {
+		name   string
+		vector string
+		tamper func(item map[string]types.AttributeValue)
+	}{
+		{
+			name:   "Flipped ciphertext bit",
+			vector: "Standard",
+			tamper: func(item map[string]types.AttributeValue) {
+				item["Email"].(*types.AttributeValueMemberB).Value[20] ^= 1
+			},
+		},
+		{
+			name:   "Swapped attributes",
+			vector: "AttributeKeys",
+			tamper: func(item map[string]types.AttributeValue) {
+				item["Email"], item["Profile"] = item["Profile"], item["Email"]
+			},
+		},
+		{
+			name:   "Modified plaintext attribute",
+			vector: "ItemSignatures",
+			tamper: func(item map[string]types.AttributeValue) {
+				item["Role"] = &types.AttributeValueMemberS{Value: "admin"}
+			},
+		},
+		{
+			name:   "Changed partition key",
+			vector: "PartitionScope",
+			tamper: func(item map[string]types.AttributeValue) {
+				item["ID"] = &types.AttributeValueMemberS{Value: "user-2"}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, ok := byName[tc.vector]
+			if !ok {
+				t.Fatalf("vector %s not found", tc.vector)
+			}
+			item, err := ddbjson.ToItem(v.Encrypted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tc.tamper(item)
+			if v.Encrypted, err = ddbjson.FromItem(item); err != nil {
+				t.Fatal(err)
+			}
+			if err := Verify(context.Background(), v); !errors.Is(err, ErrMismatch) {
+				t.Errorf("expected ErrMismatch, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/cloudopsy/dynamodb-encryption-go/internal/fakeddb"
+	fakeawskms "github.com/cloudopsy/dynamodb-encryption-go/internal/fakekms"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider/store"
+)
+
+// MaterialsDir is the directory of the stored material files, relative to this package.
+const MaterialsDir = "materials"
+
+// MaterialKeyURI is the KMS key the stored materials are wrapped with. Its key material is the fixed, public keyset
+// keys/kms_keyset.json, served by a fake KMS.
+const MaterialKeyURI = "arn:aws:kms:eu-west-2:123456789123:key/c0f0a7e2-3b1d-4c5e-8f9a-0d1e2f3a4b5c"
+
+// StoredMaterial is a material description as stored in a meta table by the release that added it, together with
+// a ciphertext encrypted under its data key. Descriptions written before descriptions were signed have no
+// DescriptionFormat entry and are read with provider.WithLegacyDescriptions.
+type StoredMaterial struct {
+	Name              string            `json:"name"`
+	MaterialName      string            `json:"materialName"`
+	EncryptionContext map[string]string `json:"encryptionContext,omitempty"`
+	Description       map[string]string `json:"description,omitempty"`
+	Plaintext         string            `json:"plaintext"`
+	AssociatedData    string            `json:"associatedData"`
+	Ciphertext        string            `json:"ciphertext,omitempty"` // base64
+}
+
+// materialFile is the layout of a stored material file.
+type materialFile struct {
+	Materials []StoredMaterial `json:"materials"`
+}
+
+// WriteMaterials encodes a stored material file.
+func WriteMaterials(w io.Writer, stored []StoredMaterial) error {
+	data, err := json.MarshalIndent(materialFile{Materials: stored}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode materials: %v", err)
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// StoredMaterials returns the stored materials shipped with this package.
+func StoredMaterials() ([]StoredMaterial, error) {
+	names, err := fs.Glob(files, path.Join(MaterialsDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	var stored []StoredMaterial
+	for _, name := range names {
+		f, err := files.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		loaded, err := LoadMaterials(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		stored = append(stored, loaded...)
+	}
+	return stored, nil
+}
+
+// LoadMaterials decodes a stored material file.
+func LoadMaterials(r io.Reader) ([]StoredMaterial, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	var file materialFile
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to decode materials: %v", err)
+	}
+	return file.Materials, nil
+}
+
+// MaterialKMS returns a fake KMS serving MaterialKeyURI with its fixed keyset.
+func MaterialKMS() (kmsiface.KMSAPI, error) {
+	handle, err := readKeyset("keys/kms_keyset.json")
+	if err != nil {
+		return nil, err
+	}
+	return fakeawskms.New(nil, fakeawskms.WithKeyset(MaterialKeyURI, handle))
+}
+
+// VerifyMaterial stores the material's description in an in-memory meta table, reads it back through a provider
+// created with opts, and checks that its data key decrypts the material's ciphertext.
+func VerifyMaterial(ctx context.Context, m StoredMaterial, opts ...provider.ProviderOption) error {
+	if m.Description == nil || m.Ciphertext == "" {
+		return fmt.Errorf("%w: material %q has no stored description", ErrMismatch, m.Name)
+	}
+	kms, err := MaterialKMS()
+	if err != nil {
+		return err
+	}
+	materialStore, err := store.NewMetaStore(fakeddb.New().Client(), "meta")
+	if err != nil {
+		return err
+	}
+	version, err := materialStore.StoreNewMaterialVersion(ctx, m.MaterialName, materials.NewDecryptionMaterials(m.Description, nil))
+	if err != nil {
+		return err
+	}
+
+	opts = append([]provider.ProviderOption{provider.WithKMSClient(kms), provider.WithReadOnly()}, opts...)
+	cmp, err := provider.New(MaterialKeyURI, m.EncryptionContext, materialStore, opts...)
+	if err != nil {
+		return err
+	}
+	decryptionMaterials, err := cmp.DecryptionMaterials(ctx, m.MaterialName, version)
+	if err != nil {
+		return err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(m.Ciphertext)
+	if err != nil {
+		return err
+	}
+	plaintext, err := decryptionMaterials.DecryptionKey().Decrypt(ciphertext, []byte(m.AssociatedData))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMismatch, err)
+	}
+	if !bytes.Equal(plaintext, []byte(m.Plaintext)) {
+		return fmt.Errorf("%w: decrypted %q, want %q", ErrMismatch, plaintext, m.Plaintext)
+	}
+	return nil
+}
+
+// GenerateLegacyMaterial stores the material the way the provider did before descriptions were signed: a data keyset
+// wrapped without encryption context, a signature over it, and no DescriptionFormat, DescriptionSignature or
+// deterministic keyset.
+func GenerateLegacyMaterial(m StoredMaterial) (StoredMaterial, error) {
+	kms, err := MaterialKMS()
+	if err != nil {
+		return m, err
+	}
+	kek, err := delegatedkeys.GetKEKWithKMS(MaterialKeyURI, kms)
+	if err != nil {
+		return m, err
+	}
+	dataKey, wrappedKeyset, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		return m, err
+	}
+	signingKey, _, publicKey, err := delegatedkeys.GenerateSigningKey(kek)
+	if err != nil {
+		return m, err
+	}
+	signature, err := signingKey.Sign(wrappedKeyset)
+	if err != nil {
+		return m, err
+	}
+	ciphertext, err := dataKey.Encrypt([]byte(m.Plaintext), []byte(m.AssociatedData))
+	if err != nil {
+		return m, err
+	}
+
+	m.Description = make(map[string]string)
+	for key, value := range m.EncryptionContext {
+		m.Description[key] = value
+	}
+	m.Description["ContentEncryptionAlgorithm"] = dataKey.Algorithm()
+	m.Description["WrappedKeyset"] = base64.StdEncoding.EncodeToString(wrappedKeyset)
+	m.Description["Signature"] = base64.StdEncoding.EncodeToString(signature)
+	m.Description["PublicKey"] = base64.StdEncoding.EncodeToString(publicKey)
+	m.Ciphertext = base64.StdEncoding.EncodeToString(ciphertext)
+	return m, nil
+}
@@ -0,0 +1,232 @@
+// Package ddbjson converts DynamoDB items to and from the DynamoDB JSON wire format,
+// in which every value is wrapped in an object keyed by its type descriptor (e.g. {"S": "text"}).
+package ddbjson
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// MarshalItem encodes an item as DynamoDB JSON.
+func MarshalItem(item map[string]types.AttributeValue) ([]byte, error) {
+	raw, err := FromItem(item)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(raw)
+}
+
+// UnmarshalItem decodes DynamoDB JSON into an item.
+func UnmarshalItem(data []byte) (map[string]types.AttributeValue, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to decode DynamoDB JSON: %v", err)
+	}
+	return ToItem(raw)
+}
+
+// FromItem converts an item into its generic DynamoDB JSON representation.
+func FromItem(item map[string]types.AttributeValue) (map[string]interface{}, error) {
+	raw := make(map[string]interface{}, len(item))
+	for name, value := range item {
+		encoded, err := FromAttributeValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %q: %w", name, err)
+		}
+		raw[name] = encoded
+	}
+	return raw, nil
+}
+
+// ToItem converts a generic DynamoDB JSON representation, as produced by encoding/json, into an item.
+func ToItem(raw map[string]interface{}) (map[string]types.AttributeValue, error) {
+	item := make(map[string]types.AttributeValue, len(raw))
+	for name, value := range raw {
+		decoded, err := ToAttributeValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %q: %w", name, err)
+		}
+		item[name] = decoded
+	}
+	return item, nil
+}
+
+// FromAttributeValue converts a single attribute value into its DynamoDB JSON representation.
+func FromAttributeValue(value types.AttributeValue) (map[string]interface{}, error) {
+	switch v := value.(type) {
+	case *types.AttributeValueMemberB:
+		return map[string]interface{}{"B": base64.StdEncoding.EncodeToString(v.Value)}, nil
+	case *types.AttributeValueMemberN:
+		return map[string]interface{}{"N": v.Value}, nil
+	case *types.AttributeValueMemberS:
+		return map[string]interface{}{"S": v.Value}, nil
+	case *types.AttributeValueMemberBOOL:
+		return map[string]interface{}{"BOOL": v.Value}, nil
+	case *types.AttributeValueMemberNULL:
+		return map[string]interface{}{"NULL": v.Value}, nil
+	case *types.AttributeValueMemberL:
+		list := make([]interface{}, len(v.Value))
+		for i, member := range v.Value {
+			encoded, err := FromAttributeValue(member)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = encoded
+		}
+		return map[string]interface{}{"L": list}, nil
+	case *types.AttributeValueMemberM:
+		m, err := FromItem(v.Value)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"M": m}, nil
+	case *types.AttributeValueMemberBS:
+		set := make([]interface{}, len(v.Value))
+		for i, member := range v.Value {
+			set[i] = base64.StdEncoding.EncodeToString(member)
+		}
+		return map[string]interface{}{"BS": set}, nil
+	case *types.AttributeValueMemberNS:
+		return map[string]interface{}{"NS": stringsToInterfaces(v.Value)}, nil
+	case *types.AttributeValueMemberSS:
+		return map[string]interface{}{"SS": stringsToInterfaces(v.Value)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported DynamoDB data type: %T", value)
+	}
+}
+
+// ToAttributeValue converts a single DynamoDB JSON value into an attribute value.
+func ToAttributeValue(raw interface{}) (types.AttributeValue, error) {
+	wrapper, ok := raw.(map[string]interface{})
+	if !ok || len(wrapper) != 1 {
+		return nil, fmt.Errorf("malformed DynamoDB JSON value: %v", raw)
+	}
+
+	for descriptor, value := range wrapper {
+		switch descriptor {
+		case "B":
+			b, err := decodeBinary(value)
+			if err != nil {
+				return nil, err
+			}
+			return &types.AttributeValueMemberB{Value: b}, nil
+		case "N":
+			n, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("malformed N value: %v", value)
+			}
+			return &types.AttributeValueMemberN{Value: n}, nil
+		case "S":
+			str, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("malformed S value: %v", value)
+			}
+			return &types.AttributeValueMemberS{Value: str}, nil
+		case "BOOL":
+			b, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("malformed BOOL value: %v", value)
+			}
+			return &types.AttributeValueMemberBOOL{Value: b}, nil
+		case "NULL":
+			b, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("malformed NULL value: %v", value)
+			}
+			return &types.AttributeValueMemberNULL{Value: b}, nil
+		case "L":
+			list, ok := value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("malformed L value: %v", value)
+			}
+			members := make([]types.AttributeValue, len(list))
+			for i, member := range list {
+				decoded, err := ToAttributeValue(member)
+				if err != nil {
+					return nil, err
+				}
+				members[i] = decoded
+			}
+			return &types.AttributeValueMemberL{Value: members}, nil
+		case "M":
+			m, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("malformed M value: %v", value)
+			}
+			members, err := ToItem(m)
+			if err != nil {
+				return nil, err
+			}
+			return &types.AttributeValueMemberM{Value: members}, nil
+		case "BS":
+			list, ok := value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("malformed BS value: %v", value)
+			}
+			members := make([][]byte, len(list))
+			for i, member := range list {
+				b, err := decodeBinary(member)
+				if err != nil {
+					return nil, err
+				}
+				members[i] = b
+			}
+			return &types.AttributeValueMemberBS{Value: members}, nil
+		case "NS":
+			members, err := interfacesToStrings(value)
+			if err != nil {
+				return nil, fmt.Errorf("malformed NS value: %w", err)
+			}
+			return &types.AttributeValueMemberNS{Value: members}, nil
+		case "SS":
+			members, err := interfacesToStrings(value)
+			if err != nil {
+				return nil, fmt.Errorf("malformed SS value: %w", err)
+			}
+			return &types.AttributeValueMemberSS{Value: members}, nil
+		default:
+			return nil, fmt.Errorf("unsupported DynamoDB type descriptor: %q", descriptor)
+		}
+	}
+
+	return nil, fmt.Errorf("malformed DynamoDB JSON value: %v", raw)
+}
+
+func decodeBinary(value interface{}) ([]byte, error) {
+	str, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("malformed binary value: %v", value)
+	}
+	b, err := base64.StdEncoding.DecodeString(str)
+	if err != nil {
+		return nil, fmt.Errorf("malformed binary value: %v", err)
+	}
+	return b, nil
+}
+
+func stringsToInterfaces(values []string) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+func interfacesToStrings(value interface{}) ([]string, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a list, got %T", value)
+	}
+	out := make([]string, len(list))
+	for i, member := range list {
+		str, ok := member.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string, got %T", member)
+		}
+		out[i] = str
+	}
+	return out, nil
+}
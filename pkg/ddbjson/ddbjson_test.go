@@ -0,0 +1,64 @@
+package ddbjson
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestMarshalUnmarshalItem(t *testing.T) {
+	item := map[string]types.AttributeValue{
+		"B":    &types.AttributeValueMemberB{Value: []byte{1, 2, 3}},
+		"N":    &types.AttributeValueMemberN{Value: "1.5"},
+		"S":    &types.AttributeValueMemberS{Value: "hello"},
+		"BOOL": &types.AttributeValueMemberBOOL{Value: true},
+		"NULL": &types.AttributeValueMemberNULL{Value: true},
+		"L": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+			&types.AttributeValueMemberS{Value: "a"},
+			&types.AttributeValueMemberN{Value: "1"},
+		}},
+		"M": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"nested": &types.AttributeValueMemberS{Value: "value"},
+		}},
+		"BS": &types.AttributeValueMemberBS{Value: [][]byte{{1}, {2}}},
+		"NS": &types.AttributeValueMemberNS{Value: []string{"1", "2"}},
+		"SS": &types.AttributeValueMemberSS{Value: []string{"a", "b"}},
+	}
+
+	data, err := MarshalItem(item)
+	if err != nil {
+		t.Fatalf("MarshalItem failed: %v", err)
+	}
+
+	decoded, err := UnmarshalItem(data)
+	if err != nil {
+		t.Fatalf("UnmarshalItem failed: %v", err)
+	}
+
+	if diff := cmp.Diff(item, decoded, cmpopts.IgnoreUnexported(
+		types.AttributeValueMemberB{}, types.AttributeValueMemberN{}, types.AttributeValueMemberS{},
+		types.AttributeValueMemberBOOL{}, types.AttributeValueMemberNULL{}, types.AttributeValueMemberL{},
+		types.AttributeValueMemberM{}, types.AttributeValueMemberBS{}, types.AttributeValueMemberNS{},
+		types.AttributeValueMemberSS{},
+	)); diff != "" {
+		t.Errorf("round trip mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnmarshalItem_Malformed(t *testing.T) {
+	testCases := []string{
+		`{"a": "plain"}`,
+		`{"a": {"S": 1}}`,
+		`{"a": {"X": "1"}}`,
+		`{"a": {"S": "1", "N": "1"}}`,
+		`{"a": {"B": "not base64!"}}`,
+	}
+
+	for _, tc := range testCases {
+		if _, err := UnmarshalItem([]byte(tc)); err == nil {
+			t.Errorf("expected error for %s", tc)
+		}
+	}
+}
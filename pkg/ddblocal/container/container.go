@@ -0,0 +1,115 @@
+// Package container starts DynamoDB Local with testcontainers-go and returns a ready encryption environment from
+// package ddblocal. It is a separate module so that the testcontainers-go dependency tree stays out of the main
+// module; integration tests need a Docker daemon:
+//
+//	func TestOrders(t *testing.T) {
+//		env := container.Setup(t)
+//		_, err := env.Client.PutItem(ctx, input)
+//		...
+//	}
+package container
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/ddblocal"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const (
+	// DefaultImage is the DynamoDB Local image Run starts unless WithImage is given.
+	DefaultImage = "amazon/dynamodb-local:2.5.2"
+	port         = "8000/tcp"
+)
+
+// Environment is a running DynamoDB Local container and the encryption environment connected to it.
+type Environment struct {
+	*ddblocal.Env
+	// Endpoint is the URL DynamoDB Local is reachable at from the host.
+	Endpoint  string
+	container testcontainers.Container
+}
+
+type settings struct {
+	image   string
+	options []ddblocal.Option
+}
+
+// Option configures Run.
+type Option func(*settings)
+
+// WithImage starts the given DynamoDB Local image instead of DefaultImage.
+func WithImage(image string) Option {
+	return func(s *settings) {
+		s.image = image
+	}
+}
+
+// WithEnvOptions passes options to ddblocal.New, for example the meta table name or EncryptedClient options.
+func WithEnvOptions(opts ...ddblocal.Option) Option {
+	return func(s *settings) {
+		s.options = append(s.options, opts...)
+	}
+}
+
+// Run starts an in-memory DynamoDB Local container, provisions the meta table and returns an EncryptedClient backed by
+// a fake KMS. Terminate stops the container.
+func Run(ctx context.Context, opts ...Option) (*Environment, error) {
+	s := settings{image: DefaultImage}
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	c, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        s.image,
+			ExposedPorts: []string{port},
+			Cmd:          []string{"-jar", "DynamoDBLocal.jar", "-inMemory", "-sharedDb"},
+			WaitingFor:   wait.ForListeningPort(port),
+		},
+		Started: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start DynamoDB Local: %w", err)
+	}
+
+	endpoint, err := c.PortEndpoint(ctx, port, "http")
+	if err != nil {
+		c.Terminate(ctx)
+		return nil, fmt.Errorf("failed to get DynamoDB Local endpoint: %w", err)
+	}
+	env, err := ddblocal.New(ctx, endpoint, s.options...)
+	if err != nil {
+		c.Terminate(ctx)
+		return nil, err
+	}
+	return &Environment{Env: env, Endpoint: endpoint, container: c}, nil
+}
+
+// Terminate closes the encryption environment and stops the container.
+func (e *Environment) Terminate(ctx context.Context) error {
+	if err := e.Env.Close(); err != nil {
+		e.container.Terminate(ctx)
+		return err
+	}
+	return e.container.Terminate(ctx)
+}
+
+// Setup runs an environment for the test and terminates it when the test and its subtests complete. It fails the
+// test if the container can not be started.
+func Setup(t testing.TB, opts ...Option) *Environment {
+	t.Helper()
+	env, err := Run(context.Background(), opts...)
+	if err != nil {
+		t.Fatalf("failed to set up DynamoDB Local: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := env.Terminate(context.Background()); err != nil {
+			t.Errorf("failed to terminate DynamoDB Local: %v", err)
+		}
+	})
+	return env
+}
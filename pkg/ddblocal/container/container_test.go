@@ -0,0 +1,51 @@
+package container
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/ddblocal"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/encrypted"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func TestSetup(t *testing.T) {
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+	ctx := context.Background()
+
+	env := Setup(t, WithEnvOptions(ddblocal.WithClientOptions(encrypted.WithPrimaryKeyInfo("orders", encrypted.PrimaryKeyInfo{PartitionKey: "ID"}))))
+	err := env.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName:            aws.String("orders"),
+		AttributeDefinitions: []types.AttributeDefinition{{AttributeName: aws.String("ID"), AttributeType: types.ScalarAttributeTypeS}},
+		KeySchema:            []types.KeySchemaElement{{AttributeName: aws.String("ID"), KeyType: types.KeyTypeHash}},
+		BillingMode:          types.BillingModePayPerRequest,
+	})
+	if err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+
+	key := map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: "1"}}
+	item := map[string]types.AttributeValue{"ID": key["ID"], "Email": &types.AttributeValueMemberS{Value: "alice@example.com"}}
+	if _, err := env.Client.PutItem(ctx, &dynamodb.PutItemInput{TableName: aws.String("orders"), Item: item}); err != nil {
+		t.Fatalf("failed to put item: %v", err)
+	}
+
+	stored, err := env.DynamoDB.GetItem(ctx, &dynamodb.GetItemInput{TableName: aws.String("orders"), Key: key})
+	if err != nil {
+		t.Fatalf("failed to get stored item: %v", err)
+	}
+	if _, ok := stored.Item["Email"].(*types.AttributeValueMemberB); !ok {
+		t.Errorf("expected the email to be stored encrypted, got %T", stored.Item["Email"])
+	}
+
+	got, err := env.Client.GetItem(ctx, &dynamodb.GetItemInput{TableName: aws.String("orders"), Key: key})
+	if err != nil {
+		t.Fatalf("failed to get item: %v", err)
+	}
+	if email, ok := got.Item["Email"].(*types.AttributeValueMemberS); !ok || email.Value != "alice@example.com" {
+		t.Errorf("expected the email back, got %#v", got.Item["Email"])
+	}
+}
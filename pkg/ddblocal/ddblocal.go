@@ -0,0 +1,125 @@
+// Package ddblocal wires an EncryptedClient to DynamoDB Local and an in-memory fake KMS, for integration tests that
+// exercise the full encryption path without AWS credentials. The container module in pkg/ddblocal/container starts
+// DynamoDB Local with testcontainers-go and calls New; New can also be pointed at an instance started otherwise, for
+// example a CI service container.
+package ddblocal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	fakeawskms "github.com/cloudopsy/dynamodb-encryption-go/internal/fakekms"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/encrypted"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider/store"
+)
+
+const (
+	// KeyURI is the KMS key the fake KMS holds and the provider wraps materials with.
+	KeyURI = "arn:aws:kms:us-east-1:111122223333:key/8b2c7a4e-5f1d-4c3b-9a6e-0d1f2e3c4b5a"
+	// Region is the region the DynamoDB client signs requests for. DynamoDB Local accepts any region.
+	Region = "us-east-1"
+	// DefaultMetaTable is the meta table New provisions unless WithMetaTable is given.
+	DefaultMetaTable = "materials"
+)
+
+// Env is an encryption setup backed by DynamoDB Local and a fake KMS. Every Env has its own fake KMS keys, so
+// materials stored by one Env can not be unwrapped by another.
+type Env struct {
+	DynamoDB  *dynamodb.Client
+	MetaStore *store.MetaStore
+	Provider  *provider.AwsKmsCryptographicMaterialsProvider
+	Client    *encrypted.EncryptedClient
+}
+
+type settings struct {
+	metaTable       string
+	clientOptions   []encrypted.EncryptedClientOption
+	providerOptions []provider.ProviderOption
+}
+
+// Option configures New.
+type Option func(*settings)
+
+// WithMetaTable provisions and uses the named meta table instead of DefaultMetaTable.
+func WithMetaTable(tableName string) Option {
+	return func(s *settings) {
+		s.metaTable = tableName
+	}
+}
+
+// WithClientOptions passes options to the EncryptedClient, for example WithPrimaryKeyInfo or WithClientConfig.
+func WithClientOptions(opts ...encrypted.EncryptedClientOption) Option {
+	return func(s *settings) {
+		s.clientOptions = append(s.clientOptions, opts...)
+	}
+}
+
+// WithProviderOptions passes options to the KMS provider, for example WithCache. The fake KMS client is always set.
+func WithProviderOptions(opts ...provider.ProviderOption) Option {
+	return func(s *settings) {
+		s.providerOptions = append(s.providerOptions, opts...)
+	}
+}
+
+// New connects to DynamoDB Local at endpoint, such as "http://localhost:8000", provisions the meta table, and returns
+// an EncryptedClient whose provider wraps materials with a fake KMS key.
+func New(ctx context.Context, endpoint string, opts ...Option) (*Env, error) {
+	s := settings{metaTable: DefaultMetaTable}
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	ddb := dynamodb.New(dynamodb.Options{
+		Region:       Region,
+		BaseEndpoint: aws.String(endpoint),
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "local", SecretAccessKey: "local", Source: "ddblocal"}, nil
+		}),
+	})
+
+	metaStore, err := store.NewMetaStore(ddb, s.metaTable)
+	if err != nil {
+		return nil, err
+	}
+	if err := metaStore.CreateTableIfNotExists(ctx); err != nil {
+		return nil, fmt.Errorf("failed to provision meta table: %w", err)
+	}
+
+	kms, err := fakeawskms.New([]string{KeyURI})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fake KMS: %w", err)
+	}
+	providerOptions := append([]provider.ProviderOption{provider.WithKMSClient(kms)}, s.providerOptions...)
+	cmProvider, err := provider.New(KeyURI, nil, metaStore, providerOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Env{
+		DynamoDB:  ddb,
+		MetaStore: metaStore,
+		Provider:  cmProvider,
+		Client:    encrypted.NewEncryptedClient(ddb, cmProvider, s.clientOptions...),
+	}, nil
+}
+
+// CreateTable creates a table in DynamoDB Local and waits until it is ACTIVE.
+func (e *Env) CreateTable(ctx context.Context, input *dynamodb.CreateTableInput) error {
+	return store.CreateTable(ctx, e.DynamoDB, input)
+}
+
+// Table returns an EncryptedTable over the environment's client.
+func (e *Env) Table() *encrypted.EncryptedTable {
+	return encrypted.NewEncryptedTable(e.Client)
+}
+
+// Close closes the client and the provider.
+func (e *Env) Close() error {
+	if err := e.Client.Close(); err != nil {
+		return err
+	}
+	return e.Provider.Close()
+}
@@ -0,0 +1,70 @@
+package ddblocal
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/encrypted"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// endpointEnv names the DynamoDB Local endpoint TestNew runs against, for example a CI service container. The
+// container module runs the same round trip in a container of its own.
+const endpointEnv = "DDBLOCAL_ENDPOINT"
+
+func TestNew(t *testing.T) {
+	endpoint := os.Getenv(endpointEnv)
+	if endpoint == "" {
+		t.Skipf("set %s to a DynamoDB Local endpoint to run", endpointEnv)
+	}
+	ctx := context.Background()
+
+	env, err := New(ctx, endpoint, WithClientOptions(encrypted.WithPrimaryKeyInfo("orders", encrypted.PrimaryKeyInfo{PartitionKey: "ID"})))
+	if err != nil {
+		t.Fatalf("failed to create environment: %v", err)
+	}
+	defer env.Close()
+	err = env.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName:            aws.String("orders"),
+		AttributeDefinitions: []types.AttributeDefinition{{AttributeName: aws.String("ID"), AttributeType: types.ScalarAttributeTypeS}},
+		KeySchema:            []types.KeySchemaElement{{AttributeName: aws.String("ID"), KeyType: types.KeyTypeHash}},
+		BillingMode:          types.BillingModePayPerRequest,
+	})
+	if err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+
+	// The instance may outlive the test, so every run writes an item of its own
+	key := map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: strconv.FormatInt(time.Now().UnixNano(), 10)}}
+	item := map[string]types.AttributeValue{
+		"ID":    key["ID"],
+		"Total": &types.AttributeValueMemberN{Value: "42"},
+		"Email": &types.AttributeValueMemberS{Value: "alice@example.com"},
+	}
+	if _, err := env.Client.PutItem(ctx, &dynamodb.PutItemInput{TableName: aws.String("orders"), Item: item}); err != nil {
+		t.Fatalf("failed to put item: %v", err)
+	}
+
+	stored, err := env.DynamoDB.GetItem(ctx, &dynamodb.GetItemInput{TableName: aws.String("orders"), Key: key})
+	if err != nil {
+		t.Fatalf("failed to get stored item: %v", err)
+	}
+	if _, ok := stored.Item["Email"].(*types.AttributeValueMemberB); !ok {
+		t.Errorf("expected the email to be stored encrypted, got %T", stored.Item["Email"])
+	}
+
+	got, err := env.Client.GetItem(ctx, &dynamodb.GetItemInput{TableName: aws.String("orders"), Key: key})
+	if err != nil {
+		t.Fatalf("failed to get item: %v", err)
+	}
+	if diff := cmp.Diff(item, got.Item, cmpopts.IgnoreUnexported(types.AttributeValueMemberS{}, types.AttributeValueMemberN{})); diff != "" {
+		t.Errorf("unexpected item (-want +got):\n%s", diff)
+	}
+}
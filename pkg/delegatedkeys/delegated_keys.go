@@ -2,16 +2,26 @@ package delegatedkeys
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
 
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
 	fakeawskms "github.com/cloudopsy/dynamodb-encryption-go/internal/fakekms"
 	"github.com/tink-crypto/tink-go-awskms/integration/awskms"
 	"github.com/tink-crypto/tink-go/v2/aead"
+	aeadsubtle "github.com/tink-crypto/tink-go/v2/aead/subtle"
+	"github.com/tink-crypto/tink-go/v2/daead"
+	daeadsubtle "github.com/tink-crypto/tink-go/v2/daead/subtle"
+	"github.com/tink-crypto/tink-go/v2/insecurecleartextkeyset"
 	"github.com/tink-crypto/tink-go/v2/keyset"
+	gcmpb "github.com/tink-crypto/tink-go/v2/proto/aes_gcm_go_proto"
+	sivpb "github.com/tink-crypto/tink-go/v2/proto/aes_siv_go_proto"
 	"github.com/tink-crypto/tink-go/v2/signature"
+	"github.com/tink-crypto/tink-go/v2/subtle"
 	"github.com/tink-crypto/tink-go/v2/tink"
+	"google.golang.org/protobuf/proto"
 )
 
 // DelegatedKey is an interface for keys that support encryption, decryption, signing,
@@ -36,13 +46,42 @@ type DelegatedKey interface {
 	WrapKeyset() (wrappedKeyset []byte, err error)
 }
 
+// DeterministicKey is implemented by delegated keys that can also encrypt deterministically, producing the same
+// ciphertext for the same plaintext and associated data. Attributes configured for deterministic encryption are
+// encrypted with it, so they can be compared in condition expressions. Decrypt must accept ciphertexts produced by
+// either method.
+type DeterministicKey interface {
+	DelegatedKey
+
+	// EncryptDeterministically encrypts the given plaintext so that equal inputs give equal ciphertexts.
+	EncryptDeterministically(plaintext []byte, associatedData []byte) (ciphertext []byte, err error)
+}
+
+// KeyDeriver is implemented by delegated keys that can derive independent subkeys from their key material, for
+// example one per attribute. Equal info always derives the same subkey.
+type KeyDeriver interface {
+	DeriveKey(info []byte) (DelegatedKey, error)
+}
+
+// ErrNoDeterministicKeyset is returned by EncryptDeterministically for data keys without a deterministic keyset,
+// such as keys stored before deterministic keysets were generated.
+var ErrNoDeterministicKeyset = errors.New("data key has no deterministic keyset")
+
+// deterministicKeysetAssociatedData binds a wrapped deterministic keyset to its purpose.
+var deterministicKeysetAssociatedData = []byte("DDBENC-DETERMINISTIC-KEYSET-V1")
+
+// TinkDelegatedKey is a delegated key backed by Tink keysets. A data key holds an AES-GCM keyset and, when it was
+// generated by GenerateDataKey, an AES-SIV keyset for deterministic encryption; a signing key holds an ECDSA keyset.
 type TinkDelegatedKey struct {
-	keysetHandle    *keyset.Handle
-	kek             tink.AEAD
-	aeadPrimitive   tink.AEAD
-	signerPrimitive tink.Signer
-	aeadOnce        sync.Once
-	signerOnce      sync.Once
+	keysetHandle        *keyset.Handle
+	deterministicHandle *keyset.Handle
+	kek                 tink.AEAD
+	aeadPrimitive       tink.AEAD
+	daeadPrimitive      tink.DeterministicAEAD
+	signerPrimitive     tink.Signer
+	aeadOnce            sync.Once
+	daeadOnce           sync.Once
+	signerOnce          sync.Once
 }
 
 func NewTinkDelegatedKey(kh *keyset.Handle, kek tink.AEAD) *TinkDelegatedKey {
@@ -73,12 +112,75 @@ func (dk *TinkDelegatedKey) Encrypt(plaintext []byte, associatedData []byte) ([]
 	return aead.Encrypt(plaintext, associatedData)
 }
 
+// Decrypt decrypts ciphertexts of both Encrypt and EncryptDeterministically.
 func (dk *TinkDelegatedKey) Decrypt(ciphertext []byte, associatedData []byte) ([]byte, error) {
 	aead, err := dk.getAEADPrimitive()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get AEAD primitive: %v", err)
 	}
-	return aead.Decrypt(ciphertext, associatedData)
+	plaintext, err := aead.Decrypt(ciphertext, associatedData)
+	if err != nil && dk.deterministicHandle != nil {
+		if daead, daeadErr := dk.getDeterministicPrimitive(); daeadErr == nil {
+			if plaintext, daeadErr := daead.DecryptDeterministically(ciphertext, associatedData); daeadErr == nil {
+				return plaintext, nil
+			}
+		}
+	}
+	return plaintext, err
+}
+
+// EncryptDeterministically encrypts with the key's AES-SIV keyset, so that equal plaintexts and associated data give
+// equal ciphertexts. It returns ErrNoDeterministicKeyset for keys without one.
+func (dk *TinkDelegatedKey) EncryptDeterministically(plaintext []byte, associatedData []byte) ([]byte, error) {
+	if dk.deterministicHandle == nil {
+		return nil, ErrNoDeterministicKeyset
+	}
+	daead, err := dk.getDeterministicPrimitive()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deterministic AEAD primitive: %v", err)
+	}
+	return daead.EncryptDeterministically(plaintext, associatedData)
+}
+
+// HasDeterministicKeyset reports whether the key can encrypt deterministically.
+func (dk *TinkDelegatedKey) HasDeterministicKeyset() bool {
+	return dk.deterministicHandle != nil
+}
+
+// WrapDeterministicKeyset wraps the key's AES-SIV keyset with its AES-GCM keyset, so it can be stored next to the
+// wrapped data keyset and unwrapped without another call to KMS. It returns ErrNoDeterministicKeyset for keys without
+// one.
+func (dk *TinkDelegatedKey) WrapDeterministicKeyset() ([]byte, error) {
+	if dk.deterministicHandle == nil {
+		return nil, ErrNoDeterministicKeyset
+	}
+	aead, err := dk.getAEADPrimitive()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AEAD primitive: %v", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := dk.deterministicHandle.WriteWithAssociatedData(keyset.NewBinaryWriter(buf), aead, deterministicKeysetAssociatedData); err != nil {
+		return nil, fmt.Errorf("failed to wrap deterministic keyset: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// UnwrapDeterministicKeyset unwraps a keyset wrapped by WrapDeterministicKeyset of the same data key and lets the key
+// encrypt deterministically with it.
+func (dk *TinkDelegatedKey) UnwrapDeterministicKeyset(wrapped []byte) error {
+	aead, err := dk.getAEADPrimitive()
+	if err != nil {
+		return fmt.Errorf("failed to get AEAD primitive: %v", err)
+	}
+	handle, err := keyset.ReadWithAssociatedData(keyset.NewBinaryReader(bytes.NewReader(wrapped)), aead, deterministicKeysetAssociatedData)
+	if err != nil {
+		return fmt.Errorf("failed to unwrap deterministic keyset: %v", err)
+	}
+	if typeURL := handle.KeysetInfo().GetKeyInfo()[0].GetTypeUrl(); typeURL != aesSIVTypeURL {
+		return fmt.Errorf("unexpected deterministic key type %s", typeURL)
+	}
+	dk.deterministicHandle = handle
+	return nil
 }
 
 func (dk *TinkDelegatedKey) Sign(data []byte) ([]byte, error) {
@@ -102,6 +204,191 @@ func (dk *TinkDelegatedKey) WrapKeyset() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// WrapKeysetWithAssociatedData wraps the keyset with kek instead of the key's own key encryption key, binding it to
+// associatedData, for example to store a copy of an unwrapped keyset in a cache.
+func (dk *TinkDelegatedKey) WrapKeysetWithAssociatedData(kek tink.AEAD, associatedData []byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	writer := keyset.NewBinaryWriter(buf)
+	if err := dk.keysetHandle.WriteWithAssociatedData(writer, kek, associatedData); err != nil {
+		return nil, fmt.Errorf("failed to wrap keyset: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// DeriveKey derives an AES-256-GCM subkey from the keyset's primary AES-GCM key with HKDF-SHA256, using info to
+// separate subkeys. Ciphertexts of a subkey can only be decrypted by the same subkey. Keys with a deterministic
+// keyset also derive an AES-SIV subkey from its primary key, so the subkey can encrypt deterministically. The copies
+// of the keys decoded to derive the subkeys are zeroed afterwards; the keyset material itself belongs to the keyset
+// handles.
+func (dk *TinkDelegatedKey) DeriveKey(info []byte) (DelegatedKey, error) {
+	aesGCMKey := new(gcmpb.AesGcmKey)
+	if err := primaryKeyValue(dk.keysetHandle, aesGCMTypeURL, aesGCMKey); err != nil {
+		return nil, err
+	}
+	defer zero(aesGCMKey.GetKeyValue())
+	derived, err := NewDerivedKey(aesGCMKey.GetKeyValue(), info)
+	if err != nil || dk.deterministicHandle == nil {
+		return derived, err
+	}
+
+	aesSIVKey := new(sivpb.AesSivKey)
+	if err := primaryKeyValue(dk.deterministicHandle, aesSIVTypeURL, aesSIVKey); err != nil {
+		return nil, err
+	}
+	defer zero(aesSIVKey.GetKeyValue())
+	if err := derived.deriveDeterministicKey(aesSIVKey.GetKeyValue(), info); err != nil {
+		return nil, err
+	}
+	return derived, nil
+}
+
+// primaryKeyValue decodes the primary key of a keyset into key, which must be of the given type.
+func primaryKeyValue(handle *keyset.Handle, typeURL string, key proto.Message) error {
+	material := insecurecleartextkeyset.KeysetMaterial(handle)
+	for _, k := range material.GetKey() {
+		if k.GetKeyId() != material.GetPrimaryKeyId() {
+			continue
+		}
+		if k.GetKeyData().GetTypeUrl() != typeURL || proto.Unmarshal(k.GetKeyData().GetValue(), key) != nil {
+			return fmt.Errorf("subkeys can only be derived from %s keys", typeURL[strings.LastIndex(typeURL, ".")+1:])
+		}
+		return nil
+	}
+	return fmt.Errorf("keyset has no primary key")
+}
+
+const (
+	aesGCMTypeURL = "type.googleapis.com/google.crypto.tink.AesGcmKey"
+	aesSIVTypeURL = "type.googleapis.com/google.crypto.tink.AesSivKey"
+)
+
+// errKeyDestroyed is returned by derived keys used after Destroy.
+var errKeyDestroyed = errors.New("derived key has been destroyed")
+
+// DerivedKeySize is the size of the AES-256 keys derived by DeriveKey.
+const DerivedKeySize = 32
+
+// DerivedKey is an AES-256-GCM subkey derived from a data key, together with an AES-SIV subkey when the data key
+// has a deterministic keyset. It encrypts and decrypts, but does not sign, and has no keyset to wrap.
+type DerivedKey struct {
+	key    []byte
+	aead   *aeadsubtle.AESGCM
+	sivKey []byte
+	siv    *daeadsubtle.AESSIV
+}
+
+// NewDerivedKey derives a subkey from key material with HKDF-SHA256 and the given info.
+func NewDerivedKey(keyMaterial, info []byte) (*DerivedKey, error) {
+	key, err := subtle.ComputeHKDF("SHA256", keyMaterial, nil, info, DerivedKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive subkey: %v", err)
+	}
+	return newDerivedKeyFromBytes(key)
+}
+
+// newDerivedKeyFromBytes creates a DerivedKey from the bytes of a previously derived subkey.
+func newDerivedKeyFromBytes(key []byte) (*DerivedKey, error) {
+	primitive, err := aeadsubtle.NewAESGCM(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES-GCM primitive: %v", err)
+	}
+	return &DerivedKey{key: key, aead: primitive}, nil
+}
+
+// deriveDeterministicKey derives the AES-SIV subkey from the key material of a deterministic keyset.
+func (k *DerivedKey) deriveDeterministicKey(keyMaterial, info []byte) error {
+	key, err := subtle.ComputeHKDF("SHA256", keyMaterial, nil, info, daeadsubtle.AESSIVKeySize)
+	if err != nil {
+		return fmt.Errorf("failed to derive deterministic subkey: %v", err)
+	}
+	primitive, err := daeadsubtle.NewAESSIV(key)
+	if err != nil {
+		return fmt.Errorf("failed to create AES-SIV primitive: %v", err)
+	}
+	k.sivKey, k.siv = key, primitive
+	return nil
+}
+
+func (k *DerivedKey) Algorithm() string {
+	return "HkdfAesGcmKey"
+}
+
+func (k *DerivedKey) AllowedForRawMaterials() bool {
+	return true
+}
+
+func (k *DerivedKey) Encrypt(plaintext []byte, associatedData []byte) ([]byte, error) {
+	if k.aead == nil {
+		return nil, errKeyDestroyed
+	}
+	return k.aead.Encrypt(plaintext, associatedData)
+}
+
+// Decrypt decrypts ciphertexts of both Encrypt and EncryptDeterministically.
+func (k *DerivedKey) Decrypt(ciphertext []byte, associatedData []byte) ([]byte, error) {
+	if k.aead == nil {
+		return nil, errKeyDestroyed
+	}
+	plaintext, err := k.aead.Decrypt(ciphertext, associatedData)
+	if err != nil && k.siv != nil {
+		if plaintext, sivErr := k.siv.DecryptDeterministically(ciphertext, associatedData); sivErr == nil {
+			return plaintext, nil
+		}
+	}
+	return plaintext, err
+}
+
+// EncryptDeterministically encrypts with the AES-SIV subkey. It returns ErrNoDeterministicKeyset for subkeys of data
+// keys without a deterministic keyset.
+func (k *DerivedKey) EncryptDeterministically(plaintext []byte, associatedData []byte) ([]byte, error) {
+	if k.aead == nil {
+		return nil, errKeyDestroyed
+	}
+	if k.siv == nil {
+		return nil, ErrNoDeterministicKeyset
+	}
+	return k.siv.EncryptDeterministically(plaintext, associatedData)
+}
+
+// Wrap encrypts the AES-GCM subkey with kek, so it can be handed to a holder of kek without exposing the data key it was
+// derived from.
+func (k *DerivedKey) Wrap(kek tink.AEAD, associatedData []byte) ([]byte, error) {
+	if k.aead == nil {
+		return nil, errKeyDestroyed
+	}
+	wrapped, err := kek.Encrypt(k.key, associatedData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap subkey: %v", err)
+	}
+	return wrapped, nil
+}
+
+// Destroy zeroes the subkeys' bytes once they are no longer needed. Encrypt, Decrypt and Wrap fail afterwards.
+func (k *DerivedKey) Destroy() {
+	zero(k.key)
+	zero(k.sivKey)
+	k.aead, k.siv = nil, nil
+}
+
+// UnwrapDerivedKey decrypts a subkey wrapped with DerivedKey.Wrap.
+func UnwrapDerivedKey(wrapped []byte, kek tink.AEAD, associatedData []byte) (*DerivedKey, error) {
+	key, err := kek.Decrypt(wrapped, associatedData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap subkey: %v", err)
+	}
+	return newDerivedKeyFromBytes(key)
+}
+
+// Sign fails, since derived keys are only used for encryption.
+func (k *DerivedKey) Sign(data []byte) ([]byte, error) {
+	return nil, fmt.Errorf("derived keys can not sign")
+}
+
+// WrapKeyset fails, since derived keys are not backed by a keyset.
+func (k *DerivedKey) WrapKeyset() ([]byte, error) {
+	return nil, fmt.Errorf("derived keys have no keyset to wrap")
+}
+
 func UnwrapKeyset(encryptedKeyset []byte, kek tink.AEAD) (*TinkDelegatedKey, error) {
 	reader := keyset.NewBinaryReader(bytes.NewReader(encryptedKeyset))
 	handle, err := keyset.Read(reader, kek)
@@ -111,13 +398,38 @@ func UnwrapKeyset(encryptedKeyset []byte, kek tink.AEAD) (*TinkDelegatedKey, err
 	return NewTinkDelegatedKey(handle, kek), nil
 }
 
+// UnwrapKeysetWithAssociatedData unwraps a keyset wrapped by WrapKeysetWithAssociatedData.
+func UnwrapKeysetWithAssociatedData(encryptedKeyset []byte, kek tink.AEAD, associatedData []byte) (*TinkDelegatedKey, error) {
+	reader := keyset.NewBinaryReader(bytes.NewReader(encryptedKeyset))
+	handle, err := keyset.ReadWithAssociatedData(reader, kek, associatedData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap keyset: %v", err)
+	}
+	return NewTinkDelegatedKey(handle, kek), nil
+}
+
+// GenerateDataKey generates an AES-256-GCM data keyset and an AES-SIV keyset for deterministic encryption, and
+// returns the data keyset wrapped with kek. The deterministic keyset is not part of the returned wrapped keyset; store
+// the result of WrapDeterministicKeyset next to it.
 func GenerateDataKey(kek tink.AEAD) (*TinkDelegatedKey, []byte, error) {
+	return GenerateDataKeyWithAssociatedData(kek, nil)
+}
+
+// GenerateDataKeyWithAssociatedData is like GenerateDataKey, but binds the wrapped data keyset to associatedData, which
+// a KMS key encryption key passes to KMS as encryption context. Unwrap it with UnwrapKeysetWithAssociatedData and the
+// same associated data.
+func GenerateDataKeyWithAssociatedData(kek tink.AEAD, associatedData []byte) (*TinkDelegatedKey, []byte, error) {
 	kh, err := keyset.NewHandle(aead.AES256GCMKeyTemplate())
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to generate new keyset handle: %v", err)
 	}
+	deterministicHandle, err := keyset.NewHandle(daead.AESSIVKeyTemplate())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate new deterministic keyset handle: %v", err)
+	}
 	delegatedKey := NewTinkDelegatedKey(kh, kek)
-	wrappedKeyset, err := delegatedKey.WrapKeyset()
+	delegatedKey.deterministicHandle = deterministicHandle
+	wrappedKeyset, err := delegatedKey.WrapKeysetWithAssociatedData(kek, associatedData)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to wrap keyset: %v", err)
 	}
@@ -185,6 +497,16 @@ func GetKEK(kmsKeyARN string, isTesting bool) (tink.AEAD, error) {
 	}
 }
 
+// GetKEKWithKMS returns the KEK for the key ARN, making KMS calls through the given client. This allows KMS to be
+// called with credentials other than the default ones, for example a role assumed in a separate security account.
+func GetKEKWithKMS(kmsKeyARN string, kms kmsiface.KMSAPI) (tink.AEAD, error) {
+	client, err := awskms.NewClientWithOptions("aws-kms://", awskms.WithKMS(kms))
+	if err != nil {
+		return nil, err
+	}
+	return client.GetAEAD("aws-kms://" + kmsKeyARN)
+}
+
 func (dk *TinkDelegatedKey) getAEADPrimitive() (tink.AEAD, error) {
 	var err error
 	dk.aeadOnce.Do(func() {
@@ -193,6 +515,14 @@ func (dk *TinkDelegatedKey) getAEADPrimitive() (tink.AEAD, error) {
 	return dk.aeadPrimitive, err
 }
 
+func (dk *TinkDelegatedKey) getDeterministicPrimitive() (tink.DeterministicAEAD, error) {
+	var err error
+	dk.daeadOnce.Do(func() {
+		dk.daeadPrimitive, err = daead.New(dk.deterministicHandle)
+	})
+	return dk.daeadPrimitive, err
+}
+
 func (dk *TinkDelegatedKey) getSignerPrimitive() (tink.Signer, error) {
 	var err error
 	dk.signerOnce.Do(func() {
@@ -200,3 +530,10 @@ func (dk *TinkDelegatedKey) getSignerPrimitive() (tink.Signer, error) {
 	})
 	return dk.signerPrimitive, err
 }
+
+// zero overwrites b with zeros, like utils.Zero, which would make this package depend on the DynamoDB types.
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -2,6 +2,7 @@ package delegatedkeys
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -192,3 +193,152 @@ func TestVerifySignature(t *testing.T) {
 // 		t.Error("unwrapped keyset doesn't match the original keyset")
 // 	}
 // }
+
+func TestTinkDelegatedKey_DeriveKey(t *testing.T) {
+	kh, err := keyset.NewHandle(aead.AES256GCMKeyTemplate())
+	if err != nil {
+		t.Fatalf("failed to create keyset handle: %v", err)
+	}
+	dk := NewTinkDelegatedKey(kh, nil)
+
+	email, err := dk.DeriveKey([]byte("attribute:Email"))
+	if err != nil {
+		t.Fatalf("failed to derive key: %v", err)
+	}
+	again, err := dk.DeriveKey([]byte("attribute:Email"))
+	if err != nil {
+		t.Fatalf("failed to derive key: %v", err)
+	}
+	phone, err := dk.DeriveKey([]byte("attribute:Phone"))
+	if err != nil {
+		t.Fatalf("failed to derive key: %v", err)
+	}
+
+	plaintext := []byte("a@example.com")
+	ciphertext, err := email.Encrypt(plaintext, []byte("Email"))
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+	decrypted, err := again.Decrypt(ciphertext, []byte("Email"))
+	if err != nil {
+		t.Fatalf("decryption with the same derived key failed: %v", err)
+	}
+	if !bytes.Equal(plaintext, decrypted) {
+		t.Errorf("expected %q, got %q", plaintext, decrypted)
+	}
+
+	if _, err := phone.Decrypt(ciphertext, []byte("Email")); err == nil {
+		t.Errorf("expected a subkey derived with other info to fail decryption")
+	}
+	if _, err := dk.Decrypt(ciphertext, []byte("Email")); err == nil {
+		t.Errorf("expected the data key to fail decryption")
+	}
+
+	// Destroying a subkey must not affect the data key it was derived from
+	email.(*DerivedKey).Destroy()
+	if _, err := email.Encrypt(plaintext, []byte("Email")); err == nil {
+		t.Errorf("expected a destroyed subkey to fail encryption")
+	}
+	again, err = dk.DeriveKey([]byte("attribute:Email"))
+	if err != nil {
+		t.Fatalf("failed to derive key: %v", err)
+	}
+	if _, err := again.Decrypt(ciphertext, []byte("Email")); err != nil {
+		t.Errorf("expected a subkey derived after another was destroyed to decrypt, got %v", err)
+	}
+
+	signingKey, err := keyset.NewHandle(signature.ECDSAP256KeyTemplate())
+	if err != nil {
+		t.Fatalf("failed to create keyset handle: %v", err)
+	}
+	if _, err := NewTinkDelegatedKey(signingKey, nil).DeriveKey([]byte("attribute:Email")); err == nil {
+		t.Errorf("expected derivation from a signing key to fail")
+	}
+}
+
+func TestTinkDelegatedKey_EncryptDeterministically(t *testing.T) {
+	kek, err := GetKEK(keyURI, true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dk, wrappedKeyset, err := GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	wrappedDeterministicKeyset, err := dk.WrapDeterministicKeyset()
+	if err != nil {
+		t.Fatalf("failed to wrap deterministic keyset: %v", err)
+	}
+	unwrapped, err := UnwrapKeyset(wrappedKeyset, kek)
+	if err != nil {
+		t.Fatalf("failed to unwrap keyset: %v", err)
+	}
+	if _, err := unwrapped.EncryptDeterministically([]byte("a@example.com"), nil); !errors.Is(err, ErrNoDeterministicKeyset) {
+		t.Errorf("expected ErrNoDeterministicKeyset before the deterministic keyset is unwrapped, got %v", err)
+	}
+	if err := unwrapped.UnwrapDeterministicKeyset(wrappedDeterministicKeyset); err != nil {
+		t.Fatalf("failed to unwrap deterministic keyset: %v", err)
+	}
+	other, _, err := GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	if err := other.UnwrapDeterministicKeyset(wrappedDeterministicKeyset); err == nil {
+		t.Errorf("expected another data key to fail unwrapping the deterministic keyset")
+	}
+
+	emailKey, err := dk.DeriveKey([]byte("attribute:Email"))
+	if err != nil {
+		t.Fatalf("failed to derive key: %v", err)
+	}
+	unwrappedEmailKey, err := unwrapped.DeriveKey([]byte("attribute:Email"))
+	if err != nil {
+		t.Fatalf("failed to derive key: %v", err)
+	}
+
+	testCases := []struct {
+		name      string
+		encrypter DeterministicKey
+		decrypter DelegatedKey
+	}{
+		{name: "Data key", encrypter: dk, decrypter: unwrapped},
+		{name: "Derived key", encrypter: emailKey.(DeterministicKey), decrypter: unwrappedEmailKey},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			plaintext := []byte("a@example.com")
+			ciphertext, err := tc.encrypter.EncryptDeterministically(plaintext, []byte("Email"))
+			if err != nil {
+				t.Fatalf("encryption failed: %v", err)
+			}
+			again, err := tc.decrypter.(DeterministicKey).EncryptDeterministically(plaintext, []byte("Email"))
+			if err != nil {
+				t.Fatalf("encryption failed: %v", err)
+			}
+			if !bytes.Equal(ciphertext, again) {
+				t.Errorf("expected equal plaintexts to encrypt equally")
+			}
+			if otherData, _ := tc.encrypter.EncryptDeterministically(plaintext, []byte("Phone")); bytes.Equal(ciphertext, otherData) {
+				t.Errorf("expected other associated data to change the ciphertext")
+			}
+			randomized, err := tc.encrypter.Encrypt(plaintext, []byte("Email"))
+			if err != nil {
+				t.Fatalf("encryption failed: %v", err)
+			}
+
+			for _, c := range [][]byte{ciphertext, randomized} {
+				decrypted, err := tc.decrypter.Decrypt(c, []byte("Email"))
+				if err != nil {
+					t.Fatalf("decryption failed: %v", err)
+				}
+				if !bytes.Equal(plaintext, decrypted) {
+					t.Errorf("expected %q, got %q", plaintext, decrypted)
+				}
+				if _, err := tc.decrypter.Decrypt(c, []byte("Phone")); err == nil {
+					t.Errorf("expected decryption with other associated data to fail")
+				}
+			}
+		})
+	}
+}
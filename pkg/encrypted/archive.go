@@ -0,0 +1,212 @@
+package encrypted
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/ddbjson"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/utils"
+	"github.com/tink-crypto/tink-go/v2/tink"
+)
+
+// TTLPrincipal is the principal DynamoDB Streams records for items deleted because their time to live expired.
+const TTLPrincipal = "dynamodb.amazonaws.com"
+
+// ErrArchiveKeyRequired is returned when an archive encrypted under an archive key is read without one.
+var ErrArchiveKeyRequired = errors.New("archive is encrypted and needs the archive key")
+
+// StreamRecord is the part of a DynamoDB Streams record archival and event consumers need. Lambda events carry
+// images as DynamoDB JSON, which ddbjson.ToItem converts; DecryptDynamoDBEvent decrypts Lambda events without
+// converting them to records, and DecryptEventBridgeEvent parses records delivered through EventBridge.
+type StreamRecord struct {
+	TableName   string
+	EventName   string // INSERT, MODIFY or REMOVE
+	PrincipalID string // The userIdentity principal, TTLPrincipal for time to live deletions.
+	Keys        map[string]types.AttributeValue
+	NewImage    map[string]types.AttributeValue
+	OldImage    map[string]types.AttributeValue
+}
+
+// IsTTLExpiry reports whether the record is the removal of an item whose time to live expired.
+func (r StreamRecord) IsTTLExpiry() bool {
+	return r.EventName == "REMOVE" && r.PrincipalID == TTLPrincipal
+}
+
+// ArchiveWriter stores archived items, for example as S3 objects with a Glacier storage class.
+type ArchiveWriter interface {
+	WriteArchive(ctx context.Context, key string, body []byte) error
+}
+
+// ArchivedItem is an item read back from an archive.
+type ArchivedItem struct {
+	Table      string
+	Key        map[string]types.AttributeValue
+	Item       map[string]types.AttributeValue
+	ArchivedAt time.Time
+}
+
+// archiveObject is the JSON document an item is archived as. The item is either stored as DynamoDB JSON or, with an
+// archive key, encrypted under a fresh data key wrapped with it.
+type archiveObject struct {
+	Table         string          `json:"table"`
+	Key           json.RawMessage `json:"key"`
+	ArchivedAt    time.Time       `json:"archivedAt"`
+	Item          json.RawMessage `json:"item,omitempty"`
+	WrappedKeyset []byte          `json:"wrappedKeyset,omitempty"`
+	Ciphertext    []byte          `json:"ciphertext,omitempty"`
+}
+
+// ArchiverOption configures an Archiver.
+type ArchiverOption func(*Archiver)
+
+// WithArchiveKey encrypts archived items under a data key wrapped with kek, typically an archive KMS key with its
+// own access policy, so archives stay readable after the table's materials are gone. Without it, items are written
+// as plaintext DynamoDB JSON and should be protected by the archive store, for example with S3 SSE-KMS.
+func WithArchiveKey(kek tink.AEAD) ArchiverOption {
+	return func(a *Archiver) {
+		a.kek = kek
+	}
+}
+
+// WithArchivePrefix sets the prefix of every archive key.
+func WithArchivePrefix(prefix string) ArchiverOption {
+	return func(a *Archiver) {
+		a.prefix = prefix
+	}
+}
+
+// Archiver archives items that expire through DynamoDB's time to live. It reads their last image from the table's
+// stream, decrypts it with the client, optionally encrypts it again under an archive key, and writes it with an
+// ArchiveWriter, so expired records remain accessible. Once an item is archived, its materials are deleted when the
+// client has material cleanup enabled, as for items deleted through the client.
+type Archiver struct {
+	client *EncryptedClient
+	writer ArchiveWriter
+	kek    tink.AEAD
+	prefix string
+	now    func() time.Time
+}
+
+// NewArchiver creates an Archiver decrypting expired items with client and writing them with writer. The table's
+// stream must include old images.
+func NewArchiver(client *EncryptedClient, writer ArchiveWriter, opts ...ArchiverOption) *Archiver {
+	a := &Archiver{client: client, writer: writer, now: time.Now}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// HandleRecords archives every time to live expiry among records, for example the records of a Lambda invocation,
+// and skips all other records. It stops at the first record that fails, so the batch can be retried.
+func (a *Archiver) HandleRecords(ctx context.Context, records []StreamRecord) error {
+	for _, record := range records {
+		if !record.IsTTLExpiry() {
+			continue
+		}
+		if _, err := a.Archive(ctx, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Archive archives the old image of a record and returns the key it was written under. Archive keys are derived
+// from the table, the day of archival and the item's primary key, so retried records overwrite their own archive.
+func (a *Archiver) Archive(ctx context.Context, record StreamRecord) (string, error) {
+	if record.OldImage == nil {
+		return "", fmt.Errorf("stream record of table %s has no old image", record.TableName)
+	}
+	ctx = operationContext(ctx, "Archive")
+	pkInfo, err := a.client.getPrimaryKeyInfo(ctx, record.TableName)
+	if err != nil {
+		return "", err
+	}
+	item, err := a.client.decryptItem(ctx, record.TableName, record.OldImage)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt expired item: %w", err)
+	}
+
+	key := primaryKey(record.OldImage, pkInfo)
+	object := archiveObject{Table: record.TableName, ArchivedAt: a.now().UTC().Truncate(time.Second)}
+	if object.Key, err = ddbjson.MarshalItem(key); err != nil {
+		return "", err
+	}
+	data, err := ddbjson.MarshalItem(item)
+	if err != nil {
+		return "", err
+	}
+	if a.kek == nil {
+		object.Item = data
+	} else {
+		dataKey, wrappedKeyset, err := delegatedkeys.GenerateDataKey(a.kek)
+		if err != nil {
+			return "", err
+		}
+		object.Ciphertext, err = dataKey.Encrypt(data, object.associatedData())
+		utils.Zero(data)
+		if err != nil {
+			return "", fmt.Errorf("failed to encrypt archived item: %v", err)
+		}
+		object.WrappedKeyset = wrappedKeyset
+	}
+	body, err := json.Marshal(object)
+	if err != nil {
+		return "", err
+	}
+
+	fingerprint, err := keyFingerprint(key)
+	if err != nil {
+		return "", err
+	}
+	archiveKey := fmt.Sprintf("%s%s/%s/%s.json", a.prefix, record.TableName, object.ArchivedAt.Format("2006/01/02"), utils.HashString(fingerprint))
+	if err := a.writer.WriteArchive(ctx, archiveKey, body); err != nil {
+		return "", fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := a.client.deleteMaterials(ctx, record.TableName, key); err != nil {
+		return "", err
+	}
+	return archiveKey, nil
+}
+
+// ReadArchive reads an item archived by an Archiver. Archives written with WithArchiveKey need the same key.
+func ReadArchive(body []byte, kek tink.AEAD) (*ArchivedItem, error) {
+	var object archiveObject
+	if err := json.Unmarshal(body, &object); err != nil {
+		return nil, fmt.Errorf("failed to parse archive: %v", err)
+	}
+	key, err := ddbjson.UnmarshalItem(object.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	data := []byte(object.Item)
+	if object.WrappedKeyset != nil {
+		if kek == nil {
+			return nil, ErrArchiveKeyRequired
+		}
+		dataKey, err := delegatedkeys.UnwrapKeyset(object.WrappedKeyset, kek)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = dataKey.Decrypt(object.Ciphertext, object.associatedData()); err != nil {
+			return nil, fmt.Errorf("failed to decrypt archived item: %v", err)
+		}
+		defer utils.Zero(data)
+	}
+	item, err := ddbjson.UnmarshalItem(data)
+	if err != nil {
+		return nil, err
+	}
+	return &ArchivedItem{Table: object.Table, Key: key, Item: item, ArchivedAt: object.ArchivedAt}, nil
+}
+
+// associatedData binds an encrypted archive to its table, key and time of archival.
+func (o *archiveObject) associatedData() []byte {
+	return []byte(o.Table + "\x00" + string(o.Key) + "\x00" + o.ArchivedAt.Format(time.RFC3339))
+}
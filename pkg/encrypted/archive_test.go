@@ -0,0 +1,94 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/tink-crypto/tink-go/v2/tink"
+)
+
+// memoryArchive is an ArchiveWriter keeping archives in a map.
+type memoryArchive map[string][]byte
+
+func (m memoryArchive) WriteArchive(ctx context.Context, key string, body []byte) error {
+	m[key] = body
+	return nil
+}
+
+func TestArchiver(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	archiveKEK, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/8c6a5d1e-2f5a-4b8e-9d1a-5b8f4e2c7a90", true)
+	if err != nil {
+		t.Fatalf("failed to get archive KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+
+	ec := NewEncryptedClient(nil, &staticProvider{dataKey: dataKey}, WithPrimaryKeyInfo("sessions", PrimaryKeyInfo{PartitionKey: "ID"}))
+	item := map[string]types.AttributeValue{
+		"ID":      &types.AttributeValueMemberS{Value: "1"},
+		"Token":   &types.AttributeValueMemberS{Value: "secret"},
+		"Expires": &types.AttributeValueMemberN{Value: "1700000000"},
+	}
+	encryptedItem, err := ec.encryptItem(context.Background(), "sessions", item)
+	if err != nil {
+		t.Fatalf("failed to encrypt item: %v", err)
+	}
+	records := []StreamRecord{
+		{TableName: "sessions", EventName: "REMOVE", PrincipalID: "user", OldImage: encryptedItem},
+		{TableName: "sessions", EventName: "REMOVE", PrincipalID: TTLPrincipal, OldImage: encryptedItem},
+	}
+	ignore := cmpopts.IgnoreUnexported(types.AttributeValueMemberS{}, types.AttributeValueMemberN{})
+
+	testCases := []struct {
+		name string
+		kek  tink.AEAD
+	}{
+		{name: "Plaintext"},
+		{name: "ArchiveKey", kek: archiveKEK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			archive := memoryArchive{}
+			var opts []ArchiverOption
+			if tc.kek != nil {
+				opts = append(opts, WithArchiveKey(tc.kek))
+			}
+			if err := NewArchiver(ec, archive, opts...).HandleRecords(context.Background(), records); err != nil {
+				t.Fatalf("failed to archive records: %v", err)
+			}
+			if len(archive) != 1 {
+				t.Fatalf("expected only the expired item to be archived, got %d archives", len(archive))
+			}
+
+			for _, body := range archive {
+				if tc.kek != nil {
+					if _, err := ReadArchive(body, nil); !errors.Is(err, ErrArchiveKeyRequired) {
+						t.Errorf("expected ErrArchiveKeyRequired, got %v", err)
+					}
+				}
+				archived, err := ReadArchive(body, tc.kek)
+				if err != nil {
+					t.Fatalf("failed to read archive: %v", err)
+				}
+				if archived.Table != "sessions" {
+					t.Errorf("expected table sessions, got %q", archived.Table)
+				}
+				if diff := cmp.Diff(item, archived.Item, ignore); diff != "" {
+					t.Errorf("unexpected item (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package encrypted
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Encoder wraps an attributevalue.Encoder and encrypts the marshaled item using the client's encryption configuration.
+type Encoder struct {
+	client    *EncryptedClient
+	tableName string
+	encoder   *attributevalue.Encoder
+}
+
+// NewEncoder creates an Encoder that produces encrypted items for the given table.
+func NewEncoder(client *EncryptedClient, tableName string, optFns ...func(*attributevalue.EncoderOptions)) *Encoder {
+	return &Encoder{
+		client:    client,
+		tableName: tableName,
+		encoder:   attributevalue.NewEncoder(optFns...),
+	}
+}
+
+// MarshalMap marshals a Go value into an item and encrypts its attributes.
+func (e *Encoder) MarshalMap(ctx context.Context, in interface{}) (map[string]types.AttributeValue, error) {
+	av, err := e.encoder.Encode(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal item: %w", err)
+	}
+
+	m, ok := av.(*types.AttributeValueMemberM)
+	if !ok {
+		return nil, fmt.Errorf("failed to marshal item: %T is not a map or struct", in)
+	}
+
+	encryptedItem, err := e.client.encryptItem(ctx, e.tableName, m.Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt item: %w", err)
+	}
+
+	return encryptedItem, nil
+}
+
+// Decoder wraps an attributevalue.Decoder and decrypts items using the client's encryption configuration before unmarshaling.
+type Decoder struct {
+	client    *EncryptedClient
+	tableName string
+	decoder   *attributevalue.Decoder
+}
+
+// NewDecoder creates a Decoder that reads encrypted items from the given table.
+func NewDecoder(client *EncryptedClient, tableName string, optFns ...func(*attributevalue.DecoderOptions)) *Decoder {
+	return &Decoder{
+		client:    client,
+		tableName: tableName,
+		decoder:   attributevalue.NewDecoder(optFns...),
+	}
+}
+
+// UnmarshalMap decrypts an item and unmarshals it into the Go value pointed to by out.
+func (d *Decoder) UnmarshalMap(ctx context.Context, item map[string]types.AttributeValue, out interface{}) error {
+	decryptedItem, err := d.client.decryptItem(ctx, d.tableName, item)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt item: %w", err)
+	}
+
+	if err := d.decoder.Decode(&types.AttributeValueMemberM{Value: decryptedItem}, out); err != nil {
+		return fmt.Errorf("failed to unmarshal item: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/google/go-cmp/cmp"
+)
+
+type codecProfile struct {
+	ID       string            `dynamodbav:"ID"`
+	Email    string            `dynamodbav:"email"`
+	Note     string            `dynamodbav:"note"`
+	Nickname string            `dynamodbav:"nickname,omitempty"`
+	Scores   []int             `dynamodbav:"scores"`
+	Address  map[string]string `dynamodbav:"address"`
+	Password string            `dynamodbav:"-"`
+	Version  int
+}
+
+// failingMarshaler fails to marshal itself.
+type failingMarshaler struct{}
+
+func (failingMarshaler) MarshalDynamoDBAttributeValue() (types.AttributeValue, error) {
+	return nil, errors.New("marshal failed")
+}
+
+func TestEncoderDecoder(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	client := NewEncryptedClient(&memoryClient{}, &staticProvider{description: map[string]string{}, dataKey: dataKey},
+		WithPrimaryKeyInfo("profiles", PrimaryKeyInfo{PartitionKey: "ID"}),
+		WithClientConfig(NewClientConfig(WithDefaultEncryption(EncryptStandard), WithEncryption("note", EncryptNone))))
+	ctx := context.Background()
+
+	t.Run("Round trip", func(t *testing.T) {
+		profile := codecProfile{
+			ID:       "1",
+			Email:    "alice@example.com",
+			Note:     "plain",
+			Scores:   []int{3, 1},
+			Address:  map[string]string{"city": "Leeds"},
+			Password: "secret",
+			Version:  7,
+		}
+		item, err := NewEncoder(client, "profiles").MarshalMap(ctx, profile)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if _, ok := item["ID"].(*types.AttributeValueMemberS); !ok {
+			t.Errorf("expected the key to stay plaintext, got %T", item["ID"])
+		}
+		if note, ok := item["note"].(*types.AttributeValueMemberS); !ok || note.Value != "plain" {
+			t.Errorf("expected the note to stay plaintext, got %#v", item["note"])
+		}
+		for _, name := range []string{"email", "scores", "address", "Version"} {
+			if _, ok := item[name].(*types.AttributeValueMemberB); !ok {
+				t.Errorf("expected %s to be encrypted, got %T", name, item[name])
+			}
+		}
+		for _, name := range []string{"nickname", "Password", "-"} {
+			if _, ok := item[name]; ok {
+				t.Errorf("expected %s to be left out", name)
+			}
+		}
+
+		var got codecProfile
+		if err := NewDecoder(client, "profiles").UnmarshalMap(ctx, item, &got); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		profile.Password = ""
+		if diff := cmp.Diff(profile, got); diff != "" {
+			t.Errorf("unexpected profile (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		type tagged struct {
+			ID   string `json:"ID"`
+			Name string `json:"full_name"`
+		}
+		item, err := NewEncoder(client, "profiles", func(o *attributevalue.EncoderOptions) { o.TagKey = "json" }).MarshalMap(ctx, tagged{ID: "2", Name: "Bob"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := item["full_name"].(*types.AttributeValueMemberB); !ok {
+			t.Errorf("expected the json tag to name the encrypted attribute, got %v", item)
+		}
+
+		var got tagged
+		if err := NewDecoder(client, "profiles", func(o *attributevalue.DecoderOptions) { o.TagKey = "json" }).UnmarshalMap(ctx, item, &got); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.Name != "Bob" {
+			t.Errorf("expected Bob, got %q", got.Name)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		item, err := NewEncoder(client, "profiles").MarshalMap(ctx, codecProfile{ID: "3", Email: "carol@example.com"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		tampered := make(map[string]types.AttributeValue, len(item))
+		for name, value := range item {
+			tampered[name] = value
+		}
+		ciphertext := append([]byte(nil), item["email"].(*types.AttributeValueMemberB).Value...)
+		ciphertext[len(ciphertext)-1] ^= 1
+		tampered["email"] = &types.AttributeValueMemberB{Value: ciphertext}
+
+		testCases := []struct {
+			name string
+			run  func() error
+		}{
+			{name: "Not a map or struct", run: func() error {
+				_, err := NewEncoder(client, "profiles").MarshalMap(ctx, "profile")
+				return err
+			}},
+			{name: "Missing key attribute", run: func() error {
+				_, err := NewEncoder(client, "profiles").MarshalMap(ctx, map[string]string{"email": "dave@example.com"})
+				return err
+			}},
+			{name: "Marshaler error", run: func() error {
+				_, err := NewEncoder(client, "profiles").MarshalMap(ctx, map[string]interface{}{"ID": "4", "value": failingMarshaler{}})
+				return err
+			}},
+			{name: "Tampered ciphertext", run: func() error {
+				var got codecProfile
+				return NewDecoder(client, "profiles").UnmarshalMap(ctx, tampered, &got)
+			}},
+			{name: "Non-pointer output", run: func() error {
+				var got codecProfile
+				return NewDecoder(client, "profiles").UnmarshalMap(ctx, item, got)
+			}},
+			{name: "Mismatched type", run: func() error {
+				var got struct {
+					Email int `dynamodbav:"email"`
+				}
+				return NewDecoder(client, "profiles").UnmarshalMap(ctx, item, &got)
+			}},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				if err := tc.run(); err == nil {
+					t.Errorf("expected an error")
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package encrypted
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// AuditHook receives the decryptions chosen for auditing. Events of failed decryptions carry the error in Err and no
+// item. Hooks must not modify the event.
+type AuditHook func(ctx context.Context, event *DecryptEvent)
+
+// Sampler decides whether a successful decryption is audited. Failed decryptions are always audited.
+type Sampler func(ctx context.Context, event *DecryptEvent) bool
+
+// auditor is an audit hook with its sampler.
+type auditor struct {
+	hook    AuditHook
+	sampler Sampler
+}
+
+// WithAuditHook registers a hook auditing decryptions, for example by writing them to an audit log. Successful
+// decryptions are audited when sampler returns true, so auditing can stay on in production on hot read paths; a nil
+// sampler audits every decryption. Failed decryptions, which may indicate tampering or missing permissions, are
+// always audited. Unlike post-decrypt hooks, audit hooks can not change or fail reads.
+func WithAuditHook(hook AuditHook, sampler Sampler) EncryptedClientOption {
+	return func(ec *EncryptedClient) {
+		ec.auditors = append(ec.auditors, auditor{hook: hook, sampler: sampler})
+	}
+}
+
+// runAuditHooks invokes the audit hooks whose samplers select the decryption. A non-nil err marks a failure.
+func (ec *EncryptedClient) runAuditHooks(ctx context.Context, pkInfo *PrimaryKeyInfo, item map[string]types.AttributeValue, decrypted []string, err error) {
+	if len(ec.auditors) == 0 {
+		return
+	}
+
+	event := &DecryptEvent{
+		Table:               pkInfo.Table,
+		Key:                 primaryKey(item, pkInfo),
+		DecryptedAttributes: decrypted,
+		Err:                 err,
+	}
+	if err == nil {
+		event.Item = item
+	}
+	for _, a := range ec.auditors {
+		if err != nil || a.sampler == nil || a.sampler(ctx, event) {
+			a.hook(ctx, event)
+		}
+	}
+}
+
+// SampleRate returns a Sampler selecting each decryption with the given probability, between 0 and 1.
+func SampleRate(rate float64) Sampler {
+	return func(ctx context.Context, event *DecryptEvent) bool {
+		return rand.Float64() < rate
+	}
+}
+
+// SamplePerPrincipal returns a Sampler with exponential decay per principal, as named by the principal function,
+// for example from request metadata attached with provider.ContextWithMaterialsRequest. Each principal's recent
+// decryptions are counted with a count that halves every halfLife, and a decryption is selected with probability
+// one over that count, but at least minRate. Principals that read rarely are always audited, while chatty ones are
+// sampled down towards minRate and recover once they quiet down.
+func SamplePerPrincipal(principal func(ctx context.Context, event *DecryptEvent) string, halfLife time.Duration, minRate float64) Sampler {
+	return samplePerPrincipal(principal, halfLife, minRate, rand.Float64, time.Now)
+}
+
+// maxPrincipals bounds the principals a decay sampler tracks. Beyond it, principals whose count decayed below one
+// are forgotten.
+const maxPrincipals = 10000
+
+// principalCount is the decayed count of a principal's decryptions at a point in time.
+type principalCount struct {
+	count float64
+	at    time.Time
+}
+
+func samplePerPrincipal(principal func(ctx context.Context, event *DecryptEvent) string, halfLife time.Duration, minRate float64, random func() float64, now func() time.Time) Sampler {
+	var mu sync.Mutex
+	counts := make(map[string]principalCount)
+	decay := func(c principalCount, t time.Time) float64 {
+		return c.count * math.Exp2(-float64(t.Sub(c.at))/float64(halfLife))
+	}
+
+	return func(ctx context.Context, event *DecryptEvent) bool {
+		name := principal(ctx, event)
+		t := now()
+
+		mu.Lock()
+		if len(counts) >= maxPrincipals {
+			for other, c := range counts {
+				if decay(c, t) < 1 {
+					delete(counts, other)
+				}
+			}
+		}
+		count := decay(counts[name], t) + 1
+		counts[name] = principalCount{count: count, at: t}
+		mu.Unlock()
+
+		return random() < math.Max(1/count, minRate)
+	}
+}
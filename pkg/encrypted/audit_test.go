@@ -0,0 +1,72 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestAuditHooks(t *testing.T) {
+	pkInfo := &PrimaryKeyInfo{Table: "users", PartitionKey: "ID"}
+	item := map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: "1"}}
+	failure := errors.New("failed to decrypt")
+
+	testCases := []struct {
+		name    string
+		sampler Sampler
+		err     error
+		audited bool
+	}{
+		{name: "Unsampled", audited: true},
+		{name: "Sampled", sampler: SampleRate(1), audited: true},
+		{name: "SampledOut", sampler: SampleRate(0)},
+		{name: "FailureSampledOut", sampler: SampleRate(0), err: failure, audited: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var events []*DecryptEvent
+			ec := NewEncryptedClient(nil, nil, WithAuditHook(func(ctx context.Context, event *DecryptEvent) {
+				events = append(events, event)
+			}, tc.sampler))
+
+			ec.runAuditHooks(context.Background(), pkInfo, item, []string{"Email"}, tc.err)
+			if (len(events) == 1) != tc.audited {
+				t.Fatalf("expected the decryption to be audited: %v, got %d events", tc.audited, len(events))
+			}
+			if tc.audited && (!errors.Is(events[0].Err, tc.err) || (events[0].Item == nil) != (tc.err != nil)) {
+				t.Errorf("unexpected event %+v", events[0])
+			}
+		})
+	}
+}
+
+func TestSamplePerPrincipal(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	principal := func(ctx context.Context, event *DecryptEvent) string { return event.Table }
+	sampler := samplePerPrincipal(principal, time.Minute, 0.1, func() float64 { return 0.4 }, func() time.Time { return now })
+
+	sample := func(table string) bool {
+		return sampler(context.Background(), &DecryptEvent{Table: table})
+	}
+
+	// A chatty principal decays towards the minimum rate, others are unaffected
+	expected := []bool{true, true, false, false}
+	for i, want := range expected {
+		if got := sample("hot"); got != want {
+			t.Errorf("decryption %d of a chatty principal: expected sampled %v, got %v", i+1, want, got)
+		}
+	}
+	if !sample("quiet") {
+		t.Errorf("expected the first decryption of another principal to be sampled")
+	}
+
+	// Once the principal quiets down its count decays and it is audited again
+	now = now.Add(10 * time.Minute)
+	if !sample("hot") {
+		t.Errorf("expected a principal to be sampled again after its count decayed")
+	}
+}
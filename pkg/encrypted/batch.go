@@ -0,0 +1,209 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// maxBatchWriteItems is the largest number of requests DynamoDB accepts in one BatchWriteItem request.
+const maxBatchWriteItems = 25
+
+// DefaultBatchWriteMaxAttempts is the number of BatchWriteItem requests BatchPutItems makes for a chunk of items,
+// including the first, unless WithBatchWriteMaxAttempts is given.
+const DefaultBatchWriteMaxAttempts = 8
+
+// ErrUnprocessedItems is the error of BatchPutItems failures whose items were still unprocessed when the attempts ran
+// out.
+var ErrUnprocessedItems = errors.New("items left unprocessed")
+
+// WithBatchWriteMaxAttempts sets the number of BatchWriteItem requests EncryptedTable.BatchPutItems makes for a chunk
+// of items, including the first, before giving up on items DynamoDB leaves unprocessed. Values below 2 disable
+// follow-up requests.
+func WithBatchWriteMaxAttempts(attempts int) EncryptedClientOption {
+	return func(ec *EncryptedClient) {
+		ec.batchPutAttempts = attempts
+	}
+}
+
+// BatchFailure identifies an item a batch helper failed to write or read, by its index in the slice passed to the
+// helper and its primary key.
+type BatchFailure struct {
+	Index int
+	Key   map[string]types.AttributeValue
+	Err   error
+}
+
+// BatchError is returned by BatchPutItems and BatchGetItems when some items failed. The other items were written, or
+// returned. errors.Is and errors.As match the errors of every failure.
+type BatchError struct {
+	Failures []BatchFailure // Ordered by index
+}
+
+func (e *BatchError) Error() string {
+	first := e.Failures[0]
+	if len(e.Failures) == 1 {
+		return fmt.Sprintf("item %d: %v", first.Index, first.Err)
+	}
+	return fmt.Sprintf("%d items failed, first item %d: %v", len(e.Failures), first.Index, first.Err)
+}
+
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, failure := range e.Failures {
+		errs[i] = failure.Err
+	}
+	return errs
+}
+
+// batchResult returns a *BatchError for the failures, or nil when there are none.
+func batchResult(failures []BatchFailure) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i].Index < failures[j].Index
+	})
+	return &BatchError{Failures: failures}
+}
+
+// BatchPutItems encrypts and writes any number of items to the DynamoDB table, in BatchWriteItem requests of up to 25
+// items. Each chunk is encrypted like a BatchWriteItem request, and items DynamoDB leaves unprocessed are requested
+// again with a growing delay. A chunk that fails to encrypt or write does not stop the others; the items not written
+// are reported in a *BatchError, with ErrUnprocessedItems for those still unprocessed when the attempts ran out.
+// A chunk must not hold two items with the same key, which DynamoDB rejects.
+func (et *EncryptedTable) BatchPutItems(ctx context.Context, tableName string, items []map[string]types.AttributeValue) error {
+	ctx = operationContext(ctx, "BatchPutItems")
+	ec := et.client
+	pkInfo, err := ec.getPrimaryKeyInfo(ctx, tableName)
+	if err != nil {
+		return err
+	}
+
+	var failures []BatchFailure
+	for start := 0; start < len(items); start += maxBatchWriteItems {
+		chunk := items[start:min(start+maxBatchWriteItems, len(items))]
+		fail := func(i int, err error) {
+			failures = append(failures, BatchFailure{Index: start + i, Key: primaryKey(chunk[i], pkInfo), Err: err})
+		}
+
+		// Unprocessed items are matched to the chunk by key, so a key that can not be fingerprinted fails the chunk
+		positions, err := keyPositions(chunk, pkInfo)
+		if err != nil {
+			for i := range chunk {
+				fail(i, err)
+			}
+			continue
+		}
+
+		writeRequests := make([]types.WriteRequest, len(chunk))
+		for i, item := range chunk {
+			writeRequests[i] = types.WriteRequest{PutRequest: &types.PutRequest{Item: item}}
+		}
+		requestItems := map[string][]types.WriteRequest{tableName: writeRequests}
+		if err := ec.encryptWriteRequests(ctx, requestItems); err != nil {
+			for i := range chunk {
+				fail(i, err)
+			}
+			continue
+		}
+
+		unprocessed, err := ec.batchWrite(ctx, requestItems)
+		if len(unprocessed[tableName]) == 0 {
+			continue
+		}
+		if err == nil {
+			err = fmt.Errorf("%w after %d attempts", ErrUnprocessedItems, ec.batchPutAttempts)
+		}
+		failed := make([]bool, len(chunk))
+		for _, writeRequest := range unprocessed[tableName] {
+			if writeRequest.PutRequest == nil {
+				continue
+			}
+			fingerprint, fingerprintErr := keyFingerprint(primaryKey(writeRequest.PutRequest.Item, pkInfo))
+			if fingerprintErr != nil {
+				// The unprocessed item can not be matched, so no item of the chunk is known to be written
+				err = fingerprintErr
+				for i := range failed {
+					failed[i] = true
+				}
+				break
+			}
+			if i, ok := positions[fingerprint]; ok {
+				failed[i] = true
+			}
+		}
+		for i := range chunk {
+			if failed[i] {
+				fail(i, err)
+			}
+		}
+	}
+	return batchResult(failures)
+}
+
+// keyPositions returns the position of every item in items by the fingerprint of its primary key.
+func keyPositions(items []map[string]types.AttributeValue, pkInfo *PrimaryKeyInfo) (map[string]int, error) {
+	positions := make(map[string]int, len(items))
+	for i, item := range items {
+		fingerprint, err := keyFingerprint(primaryKey(item, pkInfo))
+		if err != nil {
+			return nil, err
+		}
+		positions[fingerprint] = i
+	}
+	return positions, nil
+}
+
+// batchWrite sends a BatchWriteItem request of encrypted items and requests unprocessed items again, with a growing
+// delay, for up to the client's max attempts. It returns the requests left unprocessed, which are all of them when the
+// first request fails, with the error that stopped it, if any.
+func (ec *EncryptedClient) batchWrite(ctx context.Context, requestItems map[string][]types.WriteRequest) (map[string][]types.WriteRequest, error) {
+	delay := batchGetBaseDelay
+	for attempt := 1; ; attempt++ {
+		output, err := ec.Client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: requestItems})
+		if err != nil {
+			return requestItems, fmt.Errorf("error batch writing encrypted items: %w", err)
+		}
+		requestItems = output.UnprocessedItems
+		if len(requestItems) == 0 || attempt >= ec.batchPutAttempts {
+			return requestItems, nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return requestItems, ctx.Err()
+		case <-timer.C:
+		}
+		delay = min(2*delay, batchGetMaxDelay)
+	}
+}
+
+// BatchGetItems reads and decrypts any number of items from the DynamoDB table like GetItems, but returns only the
+// items found, in the order of keys. Keys that could not be read or decrypted are reported in a *BatchError, which
+// is returned together with the items that were read.
+func (et *EncryptedTable) BatchGetItems(ctx context.Context, tableName string, keys []map[string]types.AttributeValue) ([]map[string]types.AttributeValue, error) {
+	results, err := et.GetItems(ctx, tableName, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []map[string]types.AttributeValue
+	var failures []BatchFailure
+	for i, result := range results {
+		switch {
+		case result.Err != nil:
+			failures = append(failures, BatchFailure{Index: i, Key: result.Key, Err: result.Err})
+		case result.Item != nil:
+			items = append(items, result.Item)
+		}
+	}
+	return items, batchResult(failures)
+}
@@ -0,0 +1,139 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/go-cmp/cmp"
+)
+
+// batchWriteClient stores the items of BatchWriteItem by ID. It leaves the last item of every request with more than
+// one item unprocessed, fails requests holding an item with the ID "fail", and records the size of every request.
+type batchWriteClient struct {
+	DynamoDBClientInterface
+	items    map[string]map[string]types.AttributeValue
+	requests []int
+}
+
+func (c *batchWriteClient) BatchWriteItem(ctx context.Context, input *dynamodb.BatchWriteItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	output := &dynamodb.BatchWriteItemOutput{UnprocessedItems: make(map[string][]types.WriteRequest)}
+	for tableName, writeRequests := range input.RequestItems {
+		c.requests = append(c.requests, len(writeRequests))
+		for _, writeRequest := range writeRequests {
+			if writeRequest.PutRequest.Item["ID"].(*types.AttributeValueMemberS).Value == "fail" {
+				return nil, errors.New("request failed")
+			}
+		}
+		if len(writeRequests) > 1 {
+			output.UnprocessedItems[tableName] = writeRequests[len(writeRequests)-1:]
+			writeRequests = writeRequests[:len(writeRequests)-1]
+		}
+		for _, writeRequest := range writeRequests {
+			c.items[writeRequest.PutRequest.Item["ID"].(*types.AttributeValueMemberS).Value] = writeRequest.PutRequest.Item
+		}
+	}
+	return output, nil
+}
+
+func TestEncryptedTable_BatchPutItems(t *testing.T) {
+	_, cmProvider := newBatchGetClient(t, 0)
+	item := func(id string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"ID":     &types.AttributeValueMemberS{Value: id},
+			"Secret": &types.AttributeValueMemberS{Value: "secret " + id},
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		ids      []string
+		attempts int
+		failed   []int
+		err      error
+	}{
+		{name: "Several chunks", ids: func() []string {
+			var ids []string
+			for i := 0; i < 60; i++ {
+				ids = append(ids, strconv.Itoa(i))
+			}
+			return ids
+		}(), attempts: DefaultBatchWriteMaxAttempts},
+		{name: "Failed chunk", ids: []string{"0", "1", "fail"}, attempts: DefaultBatchWriteMaxAttempts, failed: []int{0, 1, 2}},
+		{name: "Attempts run out", ids: []string{"0", "1", "2"}, attempts: 1, failed: []int{2}, err: ErrUnprocessedItems},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &batchWriteClient{items: make(map[string]map[string]types.AttributeValue)}
+			ec := NewEncryptedClient(client, cmProvider, WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}), WithBatchWriteMaxAttempts(tc.attempts))
+			table := NewEncryptedTable(ec)
+
+			var items []map[string]types.AttributeValue
+			for _, id := range tc.ids {
+				items = append(items, item(id))
+			}
+			err := table.BatchPutItems(context.Background(), "users", items)
+
+			var failed []int
+			var batchErr *BatchError
+			if errors.As(err, &batchErr) {
+				for _, failure := range batchErr.Failures {
+					failed = append(failed, failure.Index)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.failed, failed); diff != "" {
+				t.Errorf("unexpected failed items (-want +got):\n%s", diff)
+			}
+			if tc.err != nil && !errors.Is(err, tc.err) {
+				t.Errorf("expected %v, got %v", tc.err, err)
+			}
+			if len(client.items) != len(tc.ids)-len(tc.failed) {
+				t.Errorf("expected %d items written, got %d", len(tc.ids)-len(tc.failed), len(client.items))
+			}
+			for id, stored := range client.items {
+				if _, ok := stored["Secret"].(*types.AttributeValueMemberB); !ok {
+					t.Errorf("expected item %s to be stored encrypted", id)
+				}
+			}
+			for _, size := range client.requests {
+				if size > maxBatchWriteItems {
+					t.Errorf("expected requests of at most %d items, got %d", maxBatchWriteItems, size)
+				}
+			}
+		})
+	}
+}
+
+func TestEncryptedTable_BatchGetItems(t *testing.T) {
+	client, cmProvider := newBatchGetClient(t, 120)
+	// Item 5 has a corrupted ciphertext
+	secret := client.items["5"]["Secret"].(*types.AttributeValueMemberB)
+	secret.Value[len(secret.Value)-1] ^= 1
+	table := NewEncryptedTable(NewEncryptedClient(client, cmProvider, WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"})))
+
+	var keys []map[string]types.AttributeValue
+	for id := 0; id < 130; id++ {
+		keys = append(keys, batchGetKey(id))
+	}
+	items, err := table.BatchGetItems(context.Background(), "users", keys)
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected a *BatchError, got %v", err)
+	}
+	if len(batchErr.Failures) != 1 || batchErr.Failures[0].Index != 5 {
+		t.Errorf("expected item 5 to fail, got %v", batchErr.Failures)
+	}
+	if len(items) != 119 {
+		t.Fatalf("expected 119 items, got %d", len(items))
+	}
+	if got := items[5]["Secret"].(*types.AttributeValueMemberS).Value; got != "secret 6" {
+		t.Errorf("expected items in the order of the keys, got %q after the failed item", got)
+	}
+}
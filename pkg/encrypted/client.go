@@ -2,18 +2,41 @@ package encrypted
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
 	"sync"
+	"sync/atomic"
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/compression"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
 	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
 	"github.com/cloudopsy/dynamodb-encryption-go/pkg/serde"
 	"github.com/cloudopsy/dynamodb-encryption-go/pkg/utils"
 )
 
+// ErrPayloadTooLarge is returned when a decrypted attribute exceeds the configured size or nesting limits.
+var ErrPayloadTooLarge = serde.ErrPayloadTooLarge
+
+// ErrMissingKeyAttribute is returned when an item lacks one of its table's primary key attributes,
+// for example when a ProjectionExpression omits the sort key.
+var ErrMissingKeyAttribute = errors.New("item is missing a primary key attribute")
+
+// ErrIndexProjection is returned when the attributes read from an index are not enough to decrypt its items.
+var ErrIndexProjection = errors.New("index projection can not be decrypted")
+
+// ErrClientClosed is returned by operations on an EncryptedClient after Close.
+var ErrClientClosed = errors.New("encrypted client is closed")
+
+// MaterialVersionAttribute is the item attribute recording the material version an item was encrypted with, so it
+// keeps decrypting after newer versions are created. Items without it are decrypted with the latest version.
+const MaterialVersionAttribute = "__MaterialVersion"
+
 type DynamoDBClientInterface interface {
 	CreateTable(ctx context.Context, input *dynamodb.CreateTableInput, opts ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error)
 	PutItem(ctx context.Context, input *dynamodb.PutItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
@@ -26,11 +49,51 @@ type DynamoDBClientInterface interface {
 	DescribeTable(ctx context.Context, input *dynamodb.DescribeTableInput, opts ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
 }
 
+// EncryptedClient has every method of *dynamodb.Client and satisfies the SDK's API client interfaces, so it can be
+// used behind an interface, and with SDK paginators and waiters, in place of a *dynamodb.Client.
+var (
+	_ dynamodb.QueryAPIClient         = (*EncryptedClient)(nil)
+	_ dynamodb.ScanAPIClient          = (*EncryptedClient)(nil)
+	_ dynamodb.BatchGetItemAPIClient  = (*EncryptedClient)(nil)
+	_ dynamodb.DescribeTableAPIClient = (*EncryptedClient)(nil)
+	_ dynamodb.ListTablesAPIClient    = (*EncryptedClient)(nil)
+
+	_ dynamodb.ListContributorInsightsAPIClient = (*EncryptedClient)(nil)
+	_ dynamodb.ListExportsAPIClient             = (*EncryptedClient)(nil)
+	_ dynamodb.ListImportsAPIClient             = (*EncryptedClient)(nil)
+)
+
 // PrimaryKeyInfo holds information about the primary key of a DynamoDB table.
 type PrimaryKeyInfo struct {
 	Table        string
 	PartitionKey string
 	SortKey      string
+	Indexes      map[string]*IndexKeyInfo
+}
+
+// IndexKeyInfo holds the key attributes of a global or local secondary index, and the attributes it projects.
+// Items read through an index are decrypted with the materials named by their base table key, so INCLUDE projections
+// of encrypted attributes must also project MaterialVersionAttribute. An empty Projection is not checked.
+type IndexKeyInfo struct {
+	Name             string
+	PartitionKey     string
+	SortKey          string
+	Projection       types.ProjectionType
+	NonKeyAttributes []string
+}
+
+// IsKeyAttribute reports whether the attribute is part of the table's primary key or of any index key.
+// Key attributes are never encrypted so that the table and its indexes remain queryable.
+func (pk *PrimaryKeyInfo) IsKeyAttribute(attributeName string) bool {
+	if attributeName == pk.PartitionKey || attributeName == pk.SortKey {
+		return true
+	}
+	for _, index := range pk.Indexes {
+		if attributeName == index.PartitionKey || attributeName == index.SortKey {
+			return true
+		}
+	}
+	return false
 }
 
 // EncryptedClient facilitates encrypted operations on DynamoDB items.
@@ -38,11 +101,37 @@ type EncryptedClient struct {
 	Client            DynamoDBClientInterface
 	MaterialsProvider provider.CryptographicMaterialsProvider
 	PrimaryKeyCache   map[string]*PrimaryKeyInfo
-	ClientConfig      *ClientConfig
 	lock              sync.RWMutex
+	keySchemaCache    KeySchemaCache
+
+	// ClientConfig is the configuration the client was created with.
+	//
+	// Deprecated: use Config, which reflects configuration changes made at runtime.
+	ClientConfig *ClientConfig
+	config       atomic.Pointer[ClientConfig]
+	configMu     sync.Mutex // serializes configuration updates
+
+	preEncryptHooks   []PreEncryptHook
+	postDecryptHooks  []PostDecryptHook
+	auditors          []auditor
+	resultSteps       []ResultStep
+	configChangeHooks []ConfigChangeHook
+	verifyOnly        *VerifiedOutput
+	itemCache         *decryptedItemCache
+	noPlaintextCache  bool
+	hashKey           []byte
+	batchGetAttempts  int
+	batchPutAttempts  int
+	parent            *EncryptedClient // the client a scoped client was created from
+	scope             *clientScope
+
+	closed  bool // guarded by configMu
+	done    chan struct{}
+	workers sync.WaitGroup
 }
 
-// NewEncryptedClient creates a new instance of EncryptedClient.
+// NewEncryptedClient creates a new instance of EncryptedClient. Configuration problems surface on the first data
+// operation; use NewClient to detect them up front.
 func NewEncryptedClient(client DynamoDBClientInterface, materialsProvider provider.CryptographicMaterialsProvider, opts ...EncryptedClientOption) *EncryptedClient {
 
 	ec := &EncryptedClient{
@@ -51,46 +140,201 @@ func NewEncryptedClient(client DynamoDBClientInterface, materialsProvider provid
 		PrimaryKeyCache:   make(map[string]*PrimaryKeyInfo),
 		ClientConfig:      NewClientConfig(WithDefaultEncryption(EncryptStandard)),
 		lock:              sync.RWMutex{},
+		batchGetAttempts:  DefaultBatchGetMaxAttempts,
+		batchPutAttempts:  DefaultBatchWriteMaxAttempts,
+		done:              make(chan struct{}),
 	}
 
 	// Apply each option to the instance
 	for _, opt := range opts {
 		opt(ec)
 	}
+	if ec.noPlaintextCache {
+		ec.itemCache = nil
+	}
+	ec.config.Store(ec.ClientConfig.Freeze())
 
 	return ec
 
 }
 
+// NewClient creates an EncryptedClient like NewEncryptedClient, but validates its configuration eagerly and reports
+// problems as errors instead of failing on the first data operation. The configuration must pass
+// ClientConfig.Validate, and the key schema of every table configured with WithPrimaryKeyInfo must be compatible
+// with it.
+func NewClient(ctx context.Context, client DynamoDBClientInterface, materialsProvider provider.CryptographicMaterialsProvider, opts ...EncryptedClientOption) (*EncryptedClient, error) {
+	if client == nil {
+		return nil, errors.New("a DynamoDB client is required")
+	}
+	if materialsProvider == nil {
+		return nil, errors.New("a materials provider is required")
+	}
+
+	ec := NewEncryptedClient(client, materialsProvider, opts...)
+	if err := ec.Config().Validate(); err != nil {
+		return nil, err
+	}
+	for tableName := range ec.PrimaryKeyCache {
+		if err := ec.ValidateConfig(ctx, tableName); err != nil {
+			return nil, err
+		}
+	}
+	return ec, nil
+}
+
+// Config returns the client's current configuration. The returned configuration is frozen.
+func (ec *EncryptedClient) Config() *ClientConfig {
+	if ec.parent != nil {
+		return ec.parent.Config()
+	}
+	return ec.config.Load()
+}
+
+// setConfig atomically replaces the client's configuration. Operations already in progress finish with the
+// configuration they started with.
+func (ec *EncryptedClient) setConfig(config *ClientConfig) {
+	ec.config.Store(config.Freeze())
+	if ec.itemCache != nil {
+		ec.itemCache.clear()
+	}
+}
+
+// UpdateConfig validates config and atomically swaps it in as the client's configuration. Operations already in
+// progress finish with the previous configuration. Besides Validate, the encryption settings are checked against the
+// key schema of every table the client has already used. Registered ConfigChangeHooks are notified when the
+// configuration changes. The client keeps its own frozen copy of config.
+func (ec *EncryptedClient) UpdateConfig(ctx context.Context, config *ClientConfig) error {
+	ec.configMu.Lock()
+	defer ec.configMu.Unlock()
+
+	return ec.updateConfig(ctx, config.Clone())
+}
+
+// updateConfig validates and swaps in config. The caller must hold configMu.
+func (ec *EncryptedClient) updateConfig(ctx context.Context, config *ClientConfig) error {
+	if ec.closed {
+		return ErrClientClosed
+	}
+	if ec.parent != nil {
+		return fmt.Errorf("%w: configuration updates", ErrOutOfScope)
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	ec.lock.RLock()
+	for _, pkInfo := range ec.PrimaryKeyCache {
+		if err := config.Encryption.ValidateKeys(pkInfo); err != nil {
+			ec.lock.RUnlock()
+			return err
+		}
+	}
+	ec.lock.RUnlock()
+
+	previous := ec.Config()
+	ec.setConfig(config)
+	ec.runConfigChangeHooks(ctx, previous, config)
+	return nil
+}
+
+// Close stops the client's background workers, such as policy watchers started with WatchPolicy, waits for them to
+// exit and releases the primary key cache. Operations that encrypt or decrypt items return ErrClientClosed afterwards.
+// The materials provider and DynamoDB client are not closed, since they may be shared. Close is safe to call more
+// than once. Closing a scoped client has no effect; it is closed with the client it was created from.
+func (ec *EncryptedClient) Close() error {
+	if ec.parent != nil {
+		return nil
+	}
+	ec.configMu.Lock()
+	if ec.closed {
+		ec.configMu.Unlock()
+		return nil
+	}
+	ec.closed = true
+	close(ec.done)
+	ec.configMu.Unlock()
+
+	ec.workers.Wait()
+
+	ec.lock.Lock()
+	ec.PrimaryKeyCache = make(map[string]*PrimaryKeyInfo)
+	ec.lock.Unlock()
+	if ec.itemCache != nil {
+		ec.itemCache.clear()
+	}
+	return nil
+}
+
+// startWorker runs fn in a background goroutine that Close stops, by closing the done channel passed to fn, and waits
+// for.
+func (ec *EncryptedClient) startWorker(fn func(done <-chan struct{})) error {
+	ec.configMu.Lock()
+	defer ec.configMu.Unlock()
+
+	if ec.closed {
+		return ErrClientClosed
+	}
+	if ec.parent != nil {
+		return fmt.Errorf("%w: background workers", ErrOutOfScope)
+	}
+	ec.workers.Add(1)
+	go func() {
+		defer ec.workers.Done()
+		fn(ec.done)
+	}()
+	return nil
+}
+
 // CreateTable creates a new DynamoDB table with the specified name, attribute definitions, and key schema.
-func (ec *EncryptedClient) CreateTable(ctx context.Context, input *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
-	return ec.Client.CreateTable(ctx, input)
+func (ec *EncryptedClient) CreateTable(ctx context.Context, input *dynamodb.CreateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+	return ec.Client.CreateTable(ctx, input, optFns...)
 }
 
-// PutItem encrypts an item and puts it into a DynamoDB table.
-func (ec *EncryptedClient) PutItem(ctx context.Context, input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
-	// Encrypt the item, excluding primary keys
-	encryptedItem, err := ec.encryptItem(ctx, aws.StringValue(input.TableName), input.Item)
+// PutItem encrypts an item and puts it into a DynamoDB table. The other fields of the input, such as conditions,
+// ReturnValues and ReturnConsumedCapacity, are passed on unchanged, except that values compared with deterministic
+// attributes in the condition are encrypted like the attributes. Conditions on other encrypted attributes are limited
+// to existence checks and fail with a *ConditionError otherwise. With ReturnValues set to ALL_OLD, the returned
+// attributes of the replaced item are decrypted.
+func (ec *EncryptedClient) PutItem(ctx context.Context, input *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	ctx = operationContext(ctx, "PutItem")
+	tableName := aws.StringValue(input.TableName)
+
+	// Conditions compare against the stored item, so resolve them before new materials are created
+	values, err := ec.encryptConditionValues(ctx, tableName, input.Item, input.ConditionExpression, nil, input.ExpressionAttributeNames, input.ExpressionAttributeValues)
 	if err != nil {
-		return nil, fmt.Errorf("failed to encrypt item: %v", err)
+		return nil, err
 	}
 
-	// Create a new PutItemInput with the encrypted item
-	encryptedInput := &dynamodb.PutItemInput{
-		TableName: input.TableName,
-		Item:      encryptedItem,
+	// Encrypt the item, excluding primary keys
+	encryptedItem, err := ec.encryptItem(ctx, tableName, input.Item)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt item: %w", err)
 	}
 
+	// Copy the input, so the caller's input keeps the plaintext item
+	encryptedInput := *input
+	encryptedInput.Item = encryptedItem
+	encryptedInput.ExpressionAttributeValues = values
+
 	// Put the encrypted item into the DynamoDB table
-	return ec.Client.PutItem(ctx, encryptedInput)
+	output, err := ec.Client.PutItem(ctx, &encryptedInput, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	if output.Attributes, err = ec.decryptReturnedItem(ctx, tableName, output.Attributes); err != nil {
+		return nil, err
+	}
+	return output, nil
 }
 
-// GetItem retrieves an item from a DynamoDB table and decrypts it.
-func (ec *EncryptedClient) GetItem(ctx context.Context, input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+// GetItem retrieves an item from a DynamoDB table and decrypts it. The other fields of the output, such as
+// ConsumedCapacity, are returned unchanged.
+func (ec *EncryptedClient) GetItem(ctx context.Context, input *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	ctx = operationContext(ctx, "GetItem")
 	// First, retrieve the encrypted item from DynamoDB
-	encryptedOutput, err := ec.Client.GetItem(ctx, input)
+	encryptedOutput, err := ec.Client.GetItem(ctx, input, optFns...)
 	if err != nil {
-		return nil, fmt.Errorf("error retrieving encrypted item: %v", err)
+		return nil, fmt.Errorf("error retrieving encrypted item: %w", err)
 	}
 
 	// Check if item is found
@@ -101,93 +345,201 @@ func (ec *EncryptedClient) GetItem(ctx context.Context, input *dynamodb.GetItemI
 	// Decrypt the item, excluding primary keys
 	decryptedItem, err := ec.decryptItem(ctx, aws.StringValue(input.TableName), encryptedOutput.Item)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decrypt item: %v", err)
+		return nil, fmt.Errorf("failed to decrypt item: %w", err)
 	}
 
-	// Create a new GetItemOutput with the decrypted item
-	decryptedOutput := &dynamodb.GetItemOutput{
-		Item: decryptedItem,
-	}
+	// Copy the output, keeping its consumed capacity and metadata, with the decrypted item
+	decryptedOutput := *encryptedOutput
+	decryptedOutput.Item = decryptedItem
 
-	return decryptedOutput, nil
+	return &decryptedOutput, nil
 }
 
-// Query executes a Query operation on DynamoDB and decrypts the returned items.
+// Query executes a Query operation on DynamoDB and decrypts the returned items. Like the underlying client, it
+// returns a single page; follow LastEvaluatedKey, or use QueryItems or dynamodb.NewQueryPaginator, to read the rest.
 func (ec *EncryptedClient) Query(ctx context.Context, input *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
-	paginator := dynamodb.NewQueryPaginator(ec.Client, input)
-
-	var decryptedItems []map[string]types.AttributeValue
-	var lastEvaluatedKey map[string]types.AttributeValue
-
-	for paginator.HasMorePages() {
-		output, err := paginator.NextPage(ctx, optFns...)
-		if err != nil {
-			return nil, fmt.Errorf("error querying encrypted items: %v", err)
-		}
+	ctx = operationContext(ctx, "Query")
+	if err := ec.checkIndex(ctx, aws.StringValue(input.TableName), input.IndexName, input.ProjectionExpression, input.ExpressionAttributeNames); err != nil {
+		return nil, err
+	}
 
-		// Decrypt the items in the response
-		for _, item := range output.Items {
-			decryptedItem, decryptErr := ec.decryptItem(ctx, aws.StringValue(input.TableName), item)
-			if decryptErr != nil {
-				return nil, decryptErr
-			}
-			decryptedItems = append(decryptedItems, decryptedItem)
-		}
+	encryptedOutput, err := ec.Client.Query(ctx, input, optFns...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying encrypted items: %w", err)
+	}
 
-		lastEvaluatedKey = output.LastEvaluatedKey
+	// Decrypt the items in the response and run the result pipeline
+	decryptedItems, err := ec.decryptResults(ctx, aws.StringValue(input.TableName), encryptedOutput.Items)
+	if err != nil {
+		return nil, err
 	}
+	encryptedOutput.Items = decryptedItems
+	encryptedOutput.Count = int32(len(decryptedItems))
 
-	return &dynamodb.QueryOutput{
-		Items:            decryptedItems,
-		Count:            int32(len(decryptedItems)),
-		ScannedCount:     int32(len(decryptedItems)),
-		LastEvaluatedKey: lastEvaluatedKey,
-	}, nil
+	return encryptedOutput, nil
 }
 
 // Scan executes a Scan operation on DynamoDB and decrypts the returned items.
-func (ec *EncryptedClient) Scan(ctx context.Context, input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
-	encryptedOutput, err := ec.Client.Scan(ctx, input)
+func (ec *EncryptedClient) Scan(ctx context.Context, input *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	ctx = operationContext(ctx, "Scan")
+	if err := ec.checkIndex(ctx, aws.StringValue(input.TableName), input.IndexName, input.ProjectionExpression, input.ExpressionAttributeNames); err != nil {
+		return nil, err
+	}
+
+	encryptedOutput, err := ec.Client.Scan(ctx, input, optFns...)
 	if err != nil {
-		return nil, fmt.Errorf("error scanning encrypted items: %v", err)
+		return nil, fmt.Errorf("error scanning encrypted items: %w", err)
 	}
 
-	// Decrypt the items in the response
-	for i, item := range encryptedOutput.Items {
-		decryptedItem, decryptErr := ec.decryptItem(ctx, aws.StringValue(input.TableName), item)
-		if decryptErr != nil {
-			return nil, decryptErr
-		}
-		encryptedOutput.Items[i] = decryptedItem
+	// Decrypt the items in the response and run the result pipeline
+	decryptedItems, err := ec.decryptResults(ctx, aws.StringValue(input.TableName), encryptedOutput.Items)
+	if err != nil {
+		return nil, err
 	}
+	encryptedOutput.Items = decryptedItems
+	encryptedOutput.Count = int32(len(decryptedItems))
 
 	return encryptedOutput, nil
 }
 
 // BatchWriteItem performs batch write operations, encrypting any items to be put.
-func (ec *EncryptedClient) BatchWriteItem(ctx context.Context, input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
-	// Iterate over each table's write requests
-	for tableName, writeRequests := range input.RequestItems {
-		for i, writeRequest := range writeRequests {
-			if writeRequest.PutRequest != nil {
-				// Encrypt the item for PutRequest
-				encryptedItem, err := ec.encryptItem(ctx, tableName, writeRequest.PutRequest.Item)
-				if err != nil {
-					return nil, err
-				}
-				input.RequestItems[tableName][i].PutRequest.Item = encryptedItem
+// When material cleanup is enabled, the materials of deleted items are removed once the deletes are processed.
+func (ec *EncryptedClient) BatchWriteItem(ctx context.Context, input *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	ctx = operationContext(ctx, "BatchWriteItem")
+	if err := ec.encryptWriteRequests(ctx, input.RequestItems); err != nil {
+		return nil, err
+	}
+
+	output, err := ec.Client.BatchWriteItem(ctx, input, optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ec.deleteBatchMaterials(ctx, input.RequestItems, output.UnprocessedItems); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// encryptWriteRequests encrypts the items of every PutRequest in place. Items sharing a material name, such as the
+// items of a table with WithTableMaterials, are encrypted under materials fetched once per call. When the materials
+// provider implements provider.BatchMaterialsProvider, the materials for all items of a table are requested with a
+// single call, so they are stored together instead of with one material-store write per item. Each call names the
+// table to the provider, so it can choose keys per table.
+func (ec *EncryptedClient) encryptWriteRequests(ctx context.Context, requestItems map[string][]types.WriteRequest) error {
+	type tableWrites struct {
+		tableName     string
+		pending       []*pendingEncryption
+		puts          []*types.PutRequest
+		materialNames []string
+	}
+	var tables []*tableWrites
+	for tableName, writeRequests := range requestItems {
+		writes := &tableWrites{tableName: tableName}
+		for _, writeRequest := range writeRequests {
+			if writeRequest.PutRequest == nil {
+				continue
+			}
+			p, err := ec.prepareEncryption(ctx, tableName, writeRequest.PutRequest.Item)
+			if err != nil {
+				return err
+			}
+			writes.pending = append(writes.pending, p)
+			writes.puts = append(writes.puts, writeRequest.PutRequest)
+			writes.materialNames = append(writes.materialNames, p.materialName)
+		}
+		if len(writes.pending) > 0 {
+			tables = append(tables, writes)
+		}
+	}
+
+	for _, writes := range tables {
+		tableCtx := provider.ContextWithMaterialsRequest(ctx, provider.MaterialsRequest{TableName: writes.tableName})
+		encryptionMaterials, err := ec.batchEncryptionMaterials(tableCtx, writes.materialNames)
+		if err != nil {
+			return fmt.Errorf("failed to fetch encryption materials: %v", err)
+		}
+		for i, p := range writes.pending {
+			encryptedItem, err := p.encrypt(encryptionMaterials[i])
+			if err != nil {
+				return err
+			}
+			writes.puts[i].Item = encryptedItem
+		}
+	}
+	return nil
+}
+
+// batchEncryptionMaterials returns encryption materials for each material name, in order. Batch providers receive
+// repeated names as they are and share materials between them; other providers are called once per distinct name.
+func (ec *EncryptedClient) batchEncryptionMaterials(ctx context.Context, materialNames []string) ([]materials.CryptographicMaterials, error) {
+	if batchProvider, ok := ec.MaterialsProvider.(provider.BatchMaterialsProvider); ok {
+		return batchProvider.BatchEncryptionMaterials(ctx, materialNames)
+	}
+
+	fetched := make(map[string]materials.CryptographicMaterials)
+	results := make([]materials.CryptographicMaterials, len(materialNames))
+	for i, materialName := range materialNames {
+		if _, ok := fetched[materialName]; !ok {
+			encryptionMaterials, err := ec.MaterialsProvider.EncryptionMaterials(ctx, materialName)
+			if err != nil {
+				return nil, err
+			}
+			fetched[materialName] = encryptionMaterials
+		}
+		results[i] = fetched[materialName]
+	}
+	return results, nil
+}
+
+// deleteBatchMaterials removes the materials of every processed DeleteRequest in a batch.
+func (ec *EncryptedClient) deleteBatchMaterials(ctx context.Context, requestItems, unprocessedItems map[string][]types.WriteRequest) error {
+	if !ec.Config().MaterialCleanup {
+		return nil
+	}
+
+	for tableName, writeRequests := range requestItems {
+		pending := make(map[string]bool)
+		for _, unprocessed := range unprocessedItems[tableName] {
+			if unprocessed.DeleteRequest == nil {
+				continue
+			}
+			fingerprint, err := keyFingerprint(unprocessed.DeleteRequest.Key)
+			if err != nil {
+				return err
+			}
+			pending[fingerprint] = true
+		}
+
+		for _, writeRequest := range writeRequests {
+			if writeRequest.DeleteRequest == nil {
+				continue
+			}
+			fingerprint, err := keyFingerprint(writeRequest.DeleteRequest.Key)
+			if err != nil {
+				return err
+			}
+			if pending[fingerprint] {
+				continue
+			}
+			if err := ec.deleteMaterials(ctx, tableName, writeRequest.DeleteRequest.Key); err != nil {
+				return err
 			}
 		}
 	}
 
-	return ec.Client.BatchWriteItem(ctx, input)
+	return nil
 }
 
-// BatchGetItem retrieves a batch of items from DynamoDB and decrypts them.
-func (ec *EncryptedClient) BatchGetItem(ctx context.Context, input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
-	encryptedOutput, err := ec.Client.BatchGetItem(ctx, input)
+// BatchGetItem retrieves a batch of items from DynamoDB and decrypts them. Unprocessed keys are requested again, with
+// a growing delay, until every item has been read or the attempts set with WithBatchGetMaxAttempts run out, and the
+// responses of all requests are merged. Keys still unprocessed then are returned in UnprocessedKeys.
+func (ec *EncryptedClient) BatchGetItem(ctx context.Context, input *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	ctx = operationContext(ctx, "BatchGetItem")
+	encryptedOutput, err := ec.batchGet(ctx, input, optFns...)
 	if err != nil {
-		return nil, fmt.Errorf("error batch getting encrypted items: %v", err)
+		return nil, err
 	}
 
 	// Decrypt the items in the response for each table
@@ -204,67 +556,113 @@ func (ec *EncryptedClient) BatchGetItem(ctx context.Context, input *dynamodb.Bat
 	return encryptedOutput, nil
 }
 
-// DeleteItem deletes an item and its associated metadata from a DynamoDB table.
-func (ec *EncryptedClient) DeleteItem(ctx context.Context, input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
-	// First, delete the item from DynamoDB
-	deleteOutput, err := ec.Client.DeleteItem(ctx, input)
+// DeleteItem deletes an item from a DynamoDB table. When material cleanup is enabled,
+// the item's materials are removed from the material store as well. Values compared with deterministic attributes in
+// the condition are encrypted with the item's latest materials, like in PutItem. With ReturnValues set to ALL_OLD, the
+// returned attributes of the deleted item are decrypted.
+func (ec *EncryptedClient) DeleteItem(ctx context.Context, input *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	ctx = operationContext(ctx, "DeleteItem")
+	tableName := aws.StringValue(input.TableName)
+	values, err := ec.encryptConditionValues(ctx, tableName, input.Key, input.ConditionExpression, nil, input.ExpressionAttributeNames, input.ExpressionAttributeValues)
 	if err != nil {
-		return nil, fmt.Errorf("error deleting encrypted item: %v", err)
+		return nil, err
 	}
+	encryptedInput := *input
+	encryptedInput.ExpressionAttributeValues = values
 
-	// Determine the material name or metadata identifier
-	pkInfo, err := ec.getPrimaryKeyInfo(ctx, aws.StringValue(input.TableName))
+	// First, delete the item from DynamoDB
+	deleteOutput, err := ec.Client.DeleteItem(ctx, &encryptedInput, optFns...)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching primary key info: %v", err)
+		return nil, fmt.Errorf("error deleting encrypted item: %w", err)
 	}
 
-	// Construct material name based on the primary key of the item being deleted
-	materialName, err := ConstructMaterialName(input.Key, pkInfo)
+	// Decrypt the deleted item before its materials are removed
+	if deleteOutput.Attributes, err = ec.decryptReturnedItem(ctx, tableName, deleteOutput.Attributes); err != nil {
+		return nil, err
+	}
+
+	if err := ec.deleteMaterials(ctx, tableName, input.Key); err != nil {
+		return nil, err
+	}
+
+	return deleteOutput, nil
+}
+
+// decryptReturnedItem decrypts the attributes of a whole item returned by a write, which DynamoDB returns for
+// ReturnValues ALL_OLD and, for updates, ALL_NEW. The write has already happened when decryption fails.
+func (ec *EncryptedClient) decryptReturnedItem(ctx context.Context, tableName string, attributes map[string]types.AttributeValue) (map[string]types.AttributeValue, error) {
+	if len(attributes) == 0 {
+		return attributes, nil
+	}
+	decryptedItem, err := ec.decryptItem(ctx, tableName, attributes)
 	if err != nil {
-		return nil, fmt.Errorf("error constructing material name: %v", err)
+		return nil, fmt.Errorf("item written, but failed to decrypt the returned item: %w", err)
 	}
+	return decryptedItem, nil
+}
 
-	// Delete the associated metadata
-	tableName := ec.MaterialsProvider.TableName()
-	queryInput := &dynamodb.QueryInput{
-		TableName:              aws.String(tableName),
-		KeyConditionExpression: aws.String("MaterialName = :materialName"),
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":materialName": &types.AttributeValueMemberS{Value: materialName},
-		},
+// deleteMaterials removes the materials belonging to the item with the given key, if cleanup is enabled
+// and the provider supports it.
+func (ec *EncryptedClient) deleteMaterials(ctx context.Context, tableName string, key map[string]types.AttributeValue) error {
+	if !ec.Config().MaterialCleanup {
+		return nil
 	}
 
-	queryOutput, err := ec.Client.Query(ctx, queryInput)
+	deleter, ok := ec.MaterialsProvider.(provider.MaterialDeleter)
+	if !ok {
+		return nil
+	}
+
+	pkInfo, err := ec.getPrimaryKeyInfo(ctx, tableName)
 	if err != nil {
-		return nil, fmt.Errorf("error querying for versions: %v", err)
+		return fmt.Errorf("error fetching primary key info: %v", err)
 	}
 
-	for _, item := range queryOutput.Items {
-		deleteRequest := map[string][]types.WriteRequest{
-			tableName: {
-				{
-					DeleteRequest: &types.DeleteRequest{
-						Key: map[string]types.AttributeValue{
-							"MaterialName": item["MaterialName"],
-							"Version":      item["Version"],
-						},
-					},
-				},
-			},
-		}
+	// Construct material name based on the primary key of the item being deleted
+	materialName, err := ConstructMaterialName(key, pkInfo)
+	if err != nil {
+		return fmt.Errorf("error constructing material name: %w", err)
+	}
 
-		batchWriteInput := &dynamodb.BatchWriteItemInput{RequestItems: deleteRequest}
-		_, err = ec.Client.BatchWriteItem(ctx, batchWriteInput)
-		if err != nil {
-			return nil, fmt.Errorf("error deleting a version: %v", err)
-		}
+	if err := deleter.DeleteMaterials(itemContext(ctx, pkInfo, key), materialName); err != nil {
+		return fmt.Errorf("error deleting materials: %v", err)
 	}
 
-	return deleteOutput, nil
+	return nil
+}
+
+// EncryptItem encrypts an item for the given table without writing it to DynamoDB.
+func (ec *EncryptedClient) EncryptItem(ctx context.Context, tableName string, item map[string]types.AttributeValue) (map[string]types.AttributeValue, error) {
+	ctx = operationContext(ctx, "EncryptItem")
+	return ec.encryptItem(ctx, tableName, item)
+}
+
+// DecryptItem decrypts an item read from the given table without calling DynamoDB for the item itself.
+func (ec *EncryptedClient) DecryptItem(ctx context.Context, tableName string, item map[string]types.AttributeValue) (map[string]types.AttributeValue, error) {
+	ctx = operationContext(ctx, "DecryptItem")
+	return ec.decryptItem(ctx, tableName, item)
+}
+
+// keyFingerprint returns a canonical string form of an item key, suitable for comparing keys by value.
+func keyFingerprint(key map[string]types.AttributeValue) (string, error) {
+	serialized, err := serde.NewSerializer().SerializeAttribute(&types.AttributeValueMemberM{Value: key})
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize key: %w", err)
+	}
+	return string(serialized), nil
 }
 
 // getPrimaryKeyInfo lazily loads and caches primary key information in a thread-safe manner.
 func (ec *EncryptedClient) getPrimaryKeyInfo(ctx context.Context, tableName string) (*PrimaryKeyInfo, error) {
+	select {
+	case <-ec.done:
+		return nil, ErrClientClosed
+	default:
+	}
+	if err := ec.scope.checkTable(tableName); err != nil {
+		return nil, err
+	}
+
 	ec.lock.RLock()
 	pkInfo, exists := ec.PrimaryKeyCache[tableName]
 	ec.lock.RUnlock()
@@ -281,7 +679,7 @@ func (ec *EncryptedClient) getPrimaryKeyInfo(ctx context.Context, tableName stri
 		return pkInfo, nil
 	}
 
-	pkInfo, err := TableInfo(ctx, ec.Client, tableName)
+	pkInfo, err := ec.describeTable(ctx, tableName)
 	if err != nil {
 		return nil, err
 	}
@@ -291,59 +689,263 @@ func (ec *EncryptedClient) getPrimaryKeyInfo(ctx context.Context, tableName stri
 	return pkInfo, nil
 }
 
+// ValidateConfig checks the client's configuration against a table's key schema, for example at startup,
+// so that misconfigured key attributes fail before the first write.
+func (ec *EncryptedClient) ValidateConfig(ctx context.Context, tableName string) error {
+	pkInfo, err := ec.getPrimaryKeyInfo(ctx, tableName)
+	if err != nil {
+		return err
+	}
+	return ec.Config().Encryption.ValidateKeys(pkInfo)
+}
+
+// checkIndex verifies that a Query or Scan index is known for the table, so its key attributes
+// are excluded from decryption, and that the attributes read from it are enough to decrypt its items.
+func (ec *EncryptedClient) checkIndex(ctx context.Context, tableName string, indexName, projectionExpression *string, names map[string]string) error {
+	if indexName == nil {
+		return nil
+	}
+
+	pkInfo, err := ec.getPrimaryKeyInfo(ctx, tableName)
+	if err != nil {
+		return err
+	}
+
+	index, ok := pkInfo.Indexes[*indexName]
+	if !ok {
+		return fmt.Errorf("index %s not found for table: %s", *indexName, tableName)
+	}
+
+	config := ec.Config()
+	encryption := applyActionOverrides(ctx, config.Encryption)
+	if index.Projection == types.ProjectionTypeInclude {
+		// DynamoDB always projects the table and index keys
+		projected := append([]string{pkInfo.PartitionKey, index.PartitionKey}, index.NonKeyAttributes...)
+		if pkInfo.SortKey != "" {
+			projected = append(projected, pkInfo.SortKey)
+		}
+		if err := checkProjection(pkInfo, encryption, config.TableMaterials, projected, fmt.Sprintf("projection of index %s", index.Name)); err != nil {
+			return err
+		}
+	}
+	if projectionExpression != nil {
+		tokens, err := tokenizeExpression(*projectionExpression, names)
+		if err != nil {
+			return err
+		}
+		var projected []string
+		for _, token := range tokens {
+			if token.kind == tokenPath {
+				projected = append(projected, token.attribute)
+			}
+		}
+		if err := checkProjection(pkInfo, encryption, config.TableMaterials, projected, "ProjectionExpression"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkProjection checks that items holding only the projected attributes can be decrypted. They must include the
+// base table's key attributes, which name the item's materials, and the material version attribute when any
+// encrypted attribute is projected, along with the material scope attribute when items use their table's materials.
+// Signatures cover whole items, so they can not be verified on projections.
+func checkProjection(pkInfo *PrimaryKeyInfo, encryption EncryptionConfig, tableMaterials bool, attributes []string, source string) error {
+	projected := make(map[string]bool, len(attributes))
+	encrypted := false
+	for _, name := range attributes {
+		projected[name] = true
+		if !isReservedAttribute(name) && !pkInfo.IsKeyAttribute(name) && encryption.ActionFor(name) != EncryptNone {
+			encrypted = true
+		}
+	}
+
+	for _, key := range []string{pkInfo.PartitionKey, pkInfo.SortKey} {
+		if key != "" && !projected[key] {
+			return fmt.Errorf("%w: %s does not include base table key attribute %q", ErrIndexProjection, source, key)
+		}
+	}
+	if encrypted && !projected[MaterialVersionAttribute] {
+		return fmt.Errorf("%w: %s includes encrypted attributes but not %s", ErrIndexProjection, source, MaterialVersionAttribute)
+	}
+	if encrypted && tableMaterials && !projected[MaterialScopeAttribute] {
+		return fmt.Errorf("%w: %s includes encrypted attributes but not %s", ErrIndexProjection, source, MaterialScopeAttribute)
+	}
+	if projected[SignatureAttribute] {
+		return fmt.Errorf("%w: %s includes %s, which can only be verified on whole items", ErrIndexProjection, source, SignatureAttribute)
+	}
+	return nil
+}
+
 // encryptItem encrypts a DynamoDB item's attributes, excluding primary keys.
 func (ec *EncryptedClient) encryptItem(ctx context.Context, tableName string, item map[string]types.AttributeValue) (map[string]types.AttributeValue, error) {
-	// Fetch primary key info to exclude these attributes from encryption
-	pkInfo, err := ec.getPrimaryKeyInfo(ctx, tableName)
+	pending, err := ec.prepareEncryption(ctx, tableName, item)
 	if err != nil {
 		return nil, err
 	}
 
 	// Generate and fetch encryption materials
-	materialName, err := ConstructMaterialName(item, pkInfo)
+	encryptionMaterials, err := ec.MaterialsProvider.EncryptionMaterials(itemContext(ctx, pending.pkInfo, pending.item), pending.materialName)
 	if err != nil {
-		return nil, fmt.Errorf("error constructing material name: %v", err)
+		return nil, fmt.Errorf("failed to fetch encryption materials: %v", err)
 	}
-	encryptionMaterials, err := ec.MaterialsProvider.EncryptionMaterials(ctx, materialName)
+	return pending.encrypt(encryptionMaterials)
+}
+
+// pendingEncryption is an item that passed validation and pre-encrypt hooks and waits for its encryption materials.
+type pendingEncryption struct {
+	pkInfo       *PrimaryKeyInfo
+	config       *ClientConfig
+	encryption   EncryptionConfig
+	item         map[string]types.AttributeValue
+	materialName string
+	hashKey      []byte
+	report       *EncryptionReport
+}
+
+// prepareEncryption validates an item against the client's configuration, runs the pre-encrypt hooks, and resolves
+// the name of the materials to encrypt it with.
+func (ec *EncryptedClient) prepareEncryption(ctx context.Context, tableName string, item map[string]types.AttributeValue) (*pendingEncryption, error) {
+	if ec.verifyOnly != nil {
+		return nil, ErrVerificationOnly
+	}
+	if err := ec.scope.checkEncrypt(); err != nil {
+		return nil, err
+	}
+
+	// Fetch primary key info to exclude these attributes from encryption
+	pkInfo, err := ec.getPrimaryKeyInfo(ctx, tableName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch encryption materials: %v", err)
+		return nil, err
+	}
+	config := ec.Config()
+	encryption := applyActionOverrides(ctx, config.Encryption)
+	if err := encryption.ValidateKeys(pkInfo); err != nil {
+		return nil, err
 	}
+	if item, err = config.renameAttributes(pkInfo, item); err != nil {
+		return nil, err
+	}
+	if err := config.checkClassified(pkInfo, encryption, item); err != nil {
+		return nil, err
+	}
+	if err := ec.runPreEncryptHooks(ctx, pkInfo, config, encryption, item); err != nil {
+		return nil, err
+	}
+
+	materialName, err := config.materialName(item, pkInfo)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing material name: %w", err)
+	}
+	return &pendingEncryption{
+		pkInfo:       pkInfo,
+		config:       config,
+		encryption:   encryption,
+		item:         item,
+		materialName: materialName,
+		hashKey:      ec.hashKey,
+		report:       reportFromContext(ctx),
+	}, nil
+}
+
+// encrypt encrypts the item's attributes with the materials.
+func (p *pendingEncryption) encrypt(encryptionMaterials materials.CryptographicMaterials) (map[string]types.AttributeValue, error) {
+	pkInfo, config, encryption, item := p.pkInfo, p.config, p.encryption, p.item
 
 	encryptedItem := make(map[string]types.AttributeValue)
+	if version, ok := encryptionMaterials.MaterialDescription()[provider.MaterialVersionKey]; ok {
+		encryptedItem[MaterialVersionAttribute] = &types.AttributeValueMemberN{Value: version}
+	}
+	if config.AttributeKeys {
+		encryptedItem[KeyDerivationAttribute] = &types.AttributeValueMemberS{Value: KeyDerivationHKDF}
+	}
+	if config.DeterministicScope != ScopeGlobal {
+		encryptedItem[DeterministicScopeAttribute] = &types.AttributeValueMemberS{Value: config.DeterministicScope.String()}
+	}
+	if config.TableMaterials {
+		encryptedItem[MaterialScopeAttribute] = &types.AttributeValueMemberS{Value: MaterialScopeTable}
+	}
+	if config.PolicyFingerprints {
+		encryptedItem[PolicyFingerprintAttribute] = &types.AttributeValueMemberS{Value: encryption.Fingerprint()}
+	}
+
 	serializer := serde.NewSerializer()
 	for key, value := range item {
-		// Exclude primary keys from encryption
-		if key == pkInfo.PartitionKey || key == pkInfo.SortKey {
-			encryptedItem[key] = value
+		if isReservedAttribute(key) {
 			continue
 		}
 
-		encryptionAction := ec.ClientConfig.Encryption.DefaultAction
-		if specificAction, ok := ec.ClientConfig.Encryption.SpecificActions[key]; ok {
-			encryptionAction = specificAction
+		// Exclude primary and index keys, and attributes maintained by DynamoDB, from encryption
+		if pkInfo.IsKeyAttribute(key) || isSystemAttribute(config.systemAttributes(), key) {
+			encryptedItem[key] = value
+			continue
 		}
 
-		switch encryptionAction {
+		switch encryption.ActionFor(key) {
 		case EncryptStandard, EncryptDeterministic:
-			rawData, err := serializer.SerializeAttribute(value)
+			rawData, err := serializer.SerializeCompressed(value, config.Compression)
 			if err != nil {
 				return nil, fmt.Errorf("error serializing attribute value: %v", err)
 			}
 
 			// Encrypt the encoded data
-			encryptedData, err := encryptionMaterials.EncryptionKey().Encrypt(rawData, []byte(key))
+			dataKey, err := attributeKey(encryptionMaterials.EncryptionKey(), key, config.AttributeKeys)
+			if err != nil {
+				return nil, err
+			}
+			associatedData, err := config.DeterministicScope.associatedData(pkInfo.Table, pkInfo.PartitionKey, item, key)
+			if err != nil {
+				return nil, err
+			}
+			encryptedData, err := encryptValue(dataKey, encryption.ActionFor(key), rawData, associatedData)
+			utils.Zero(rawData)
+			releaseAttributeKey(dataKey, config.AttributeKeys)
 			if err != nil {
 				return nil, fmt.Errorf("error encrypting attribute value: %v", err)
 			}
 			encryptedItem[key] = &types.AttributeValueMemberB{Value: encryptedData}
+		case EncryptHash:
+			digest, err := HashValue(p.hashKey, key, value)
+			if err != nil {
+				return nil, fmt.Errorf("error hashing attribute %q: %w", key, err)
+			}
+			encryptedItem[key] = digest
 		case EncryptNone:
 			encryptedItem[key] = value
 		}
 	}
 
+	if config.SignItems {
+		if err := signItem(pkInfo.Table, encryptedItem, encryptionMaterials, config.systemAttributes()); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.report != nil {
+		var compressed []string
+		if config.Compression != compression.None {
+			for key := range encryptedItem {
+				if !isReservedAttribute(key) && !pkInfo.IsKeyAttribute(key) && !isSystemAttribute(config.systemAttributes(), key) && encryption.ActionFor(key) != EncryptNone && encryption.ActionFor(key) != EncryptHash {
+					compressed = append(compressed, key)
+				}
+			}
+		}
+		p.report.fill(pkInfo, encryption, p.materialName, encryptedItem, encryptionMaterials.MaterialDescription(), compressed)
+	}
+
 	return encryptedItem, nil
 }
 
+// encryptValue encrypts a serialized attribute value. Deterministic attributes are encrypted deterministically when
+// the key supports it, so their ciphertexts can be compared in condition expressions.
+func encryptValue(key delegatedkeys.DelegatedKey, action EncryptionAction, plaintext, associatedData []byte) ([]byte, error) {
+	if deterministic, ok := key.(delegatedkeys.DeterministicKey); ok && action == EncryptDeterministic {
+		return deterministic.EncryptDeterministically(plaintext, associatedData)
+	}
+	return key.Encrypt(plaintext, associatedData)
+}
+
 // decryptItem decrypts a DynamoDB item's attributes, excluding primary keys.
 func (ec *EncryptedClient) decryptItem(ctx context.Context, tableName string, item map[string]types.AttributeValue) (map[string]types.AttributeValue, error) {
 	pkInfo, err := ec.getPrimaryKeyInfo(ctx, tableName)
@@ -351,57 +953,177 @@ func (ec *EncryptedClient) decryptItem(ctx context.Context, tableName string, it
 		return nil, err
 	}
 
-	// Construct the material name based on primary keys
-	materialName, err := ConstructMaterialName(item, pkInfo)
+	// Construct the material name based on primary keys, unless the item uses its table's materials
+	materialName, err := itemMaterialName(item, pkInfo)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing material name: %w", err)
+	}
+	version, err := materialVersion(item)
+	if err != nil {
+		return nil, err
+	}
+	if ec.verifyOnly != nil {
+		return ec.verifyOnlyItem(ctx, pkInfo, item, materialName, version)
+	}
+
+	config := ec.Config()
+	encryption := applyActionOverrides(ctx, config.Encryption)
+	decryptedItem, decrypted, err := ec.decryptAttributes(ctx, pkInfo, config, encryption, item, materialName, version)
 	if err != nil {
-		return nil, fmt.Errorf("error constructing material name: %v", err)
+		ec.runAuditHooks(ctx, pkInfo, item, nil, err)
+		return nil, err
+	}
+	ec.runAuditHooks(ctx, pkInfo, decryptedItem, decrypted, nil)
+	return ec.runPostDecryptHooks(ctx, pkInfo, decryptedItem, decrypted)
+}
+
+// decryptAttributes verifies an item and decrypts the attributes the encryption settings mark as encrypted, with the
+// named materials. It returns the decrypted item and the names of the attributes that were decrypted.
+// With a decrypted item cache, items decrypted before are assembled from their cached plaintext.
+func (ec *EncryptedClient) decryptAttributes(ctx context.Context, pkInfo *PrimaryKeyInfo, config *ClientConfig, encryption EncryptionConfig, item map[string]types.AttributeValue, materialName string, version int64) (map[string]types.AttributeValue, []string, error) {
+	var cacheKey string
+	if ec.itemCache != nil {
+		var err error
+		if cacheKey, err = ec.itemCache.cacheKey(pkInfo.Table, encryption, item); err != nil {
+			return nil, nil, err
+		}
+		if plaintexts, description, ok := ec.itemCache.get(cacheKey, config); ok {
+			open := func(name string, _ []byte) ([]byte, error) {
+				return plaintexts[name], nil
+			}
+			return assembleDecryptedItem(ctx, pkInfo, config, encryption, item, materialName, description, open)
+		}
+	}
+
+	decryptionMaterials, err := ec.MaterialsProvider.DecryptionMaterials(itemContext(ctx, pkInfo, item), materialName, version)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch decryption materials: %v", err)
+	}
+	if err := verifyItem(pkInfo.Table, item, decryptionMaterials.MaterialDescription(), config.systemAttributes()); err != nil {
+		return nil, nil, err
+	}
+	item = ec.scope.filterDecryption(pkInfo, config, encryption, item)
+	derive, err := usesAttributeKeys(item)
+	if err != nil {
+		return nil, nil, err
+	}
+	scope, err := itemScope(item)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var plaintexts map[string][]byte
+	if ec.itemCache != nil {
+		plaintexts = make(map[string][]byte)
+	}
+	open := func(name string, ciphertext []byte) ([]byte, error) {
+		dataKey, err := attributeKey(decryptionMaterials.DecryptionKey(), name, derive)
+		if err != nil {
+			return nil, err
+		}
+		associatedData, err := scope.associatedData(pkInfo.Table, pkInfo.PartitionKey, item, name)
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err := dataKey.Decrypt(ciphertext, associatedData)
+		releaseAttributeKey(dataKey, derive)
+		if err != nil {
+			return nil, fmt.Errorf("error decrypting attribute value: %v", err)
+		}
+		if plaintexts != nil {
+			plaintexts[name] = append([]byte(nil), plaintext...)
+		}
+		return plaintext, nil
 	}
-	decryptionMaterials, err := ec.MaterialsProvider.DecryptionMaterials(ctx, materialName, 0)
+	decryptedItem, decrypted, err := assembleDecryptedItem(ctx, pkInfo, config, encryption, item, materialName, decryptionMaterials.MaterialDescription(), open)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch decryption materials: %v", err)
+		return nil, nil, err
+	}
+	if plaintexts != nil {
+		ec.itemCache.put(cacheKey, config, plaintexts, decryptionMaterials.MaterialDescription())
 	}
+	return decryptedItem, decrypted, nil
+}
 
+// assembleDecryptedItem builds the decrypted item from a stored item, obtaining the serialized plaintext of each
+// encrypted attribute from open, by its stored name and ciphertext. The plaintexts are zeroed once decoded.
+func assembleDecryptedItem(ctx context.Context, pkInfo *PrimaryKeyInfo, config *ClientConfig, encryption EncryptionConfig, item map[string]types.AttributeValue, materialName string, description map[string]string, open func(name string, ciphertext []byte) ([]byte, error)) (map[string]types.AttributeValue, []string, error) {
 	decryptedItem := make(map[string]types.AttributeValue)
-	deserializer := serde.NewDeserializer()
+	var decrypted, compressed []string
+	deserializer := serde.NewDeserializer(serde.WithLimits(config.Limits))
 	for key, value := range item {
-		// Copy primary key attributes as is
-		if key == pkInfo.PartitionKey || key == pkInfo.SortKey {
+		if isReservedAttribute(key) {
+			continue
+		}
+
+		// Copy primary and index key attributes, and attributes maintained by DynamoDB, as is
+		if pkInfo.IsKeyAttribute(key) || isSystemAttribute(config.systemAttributes(), key) {
 			decryptedItem[key] = value
 			continue
 		}
 
-		encryptionAction := ec.ClientConfig.Encryption.DefaultAction
-		if specificAction, ok := ec.ClientConfig.Encryption.SpecificActions[key]; ok {
-			encryptionAction = specificAction
+		// Legacy attributes are returned under their canonical name, unless the item also holds that name
+		name := config.canonicalName(key)
+		if _, ok := item[name]; ok && name != key {
+			continue
 		}
 
-		switch encryptionAction {
+		switch encryption.ActionFor(name) {
 		case EncryptStandard, EncryptDeterministic:
 			encryptedData, ok := value.(*types.AttributeValueMemberB)
 			if !ok {
 				// If the attribute is not encrypted, copy it as is
-				decryptedItem[key] = value
+				decryptedItem[name] = value
 				continue
 			}
 
 			// Decrypt the encrypted data
-			decryptedData, err := decryptionMaterials.DecryptionKey().Decrypt(encryptedData.Value, []byte(key))
+			decryptedData, err := open(key, encryptedData.Value)
 			if err != nil {
-				return nil, fmt.Errorf("error decrypting attribute value: %v", err)
+				return nil, nil, err
 			}
 
-			// Decode the decrypted data
+			if version, _ := serde.FormatVersion(decryptedData); version == serde.FormatVersion2 {
+				compressed = append(compressed, name)
+			}
+
+			// Decode the decrypted data, then clear the serialized plaintext
 			decryptedValue, err := deserializer.DeserializeAttribute(decryptedData)
+			utils.Zero(decryptedData)
 			if err != nil {
-				return nil, fmt.Errorf("error decoding attribute value with gob: %v", err)
+				return nil, nil, fmt.Errorf("error decoding attribute value: %w", err)
 			}
-			decryptedItem[key] = decryptedValue
-		case EncryptNone:
-			decryptedItem[key] = value
+			decryptedItem[name] = decryptedValue
+			decrypted = append(decrypted, name)
+		case EncryptHash, EncryptNone:
+			// Digests can not be reversed, so they are returned as stored
+			decryptedItem[name] = value
 		}
 	}
 
-	return decryptedItem, nil
+	if report := reportFromContext(ctx); report != nil {
+		report.fill(pkInfo, encryption, materialName, item, description, compressed)
+	}
+
+	return decryptedItem, decrypted, nil
+}
+
+// materialVersion returns the material version pinned on an item, or 0 for the latest version when none is recorded.
+func materialVersion(item map[string]types.AttributeValue) (int64, error) {
+	value, ok := item[MaterialVersionAttribute]
+	if !ok {
+		return 0, nil
+	}
+
+	number, ok := value.(*types.AttributeValueMemberN)
+	if !ok {
+		return 0, fmt.Errorf("unexpected type for %s attribute", MaterialVersionAttribute)
+	}
+	version, err := strconv.ParseInt(number.Value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s attribute: %v", MaterialVersionAttribute, err)
+	}
+	return version, nil
 }
 
 // TableInfo fetches the primary key names of a DynamoDB table.
@@ -415,24 +1137,55 @@ func TableInfo(ctx context.Context, client DynamoDBClientInterface, tableName st
 
 	pkInfo := &PrimaryKeyInfo{}
 	pkInfo.Table = tableName
-
-	for _, keySchema := range resp.Table.KeySchema {
-		if keySchema.KeyType == "HASH" {
-			pkInfo.PartitionKey = *keySchema.AttributeName
-		} else if keySchema.KeyType == "RANGE" {
-			pkInfo.SortKey = *keySchema.AttributeName
-		}
-	}
+	pkInfo.PartitionKey, pkInfo.SortKey = keyAttributes(resp.Table.KeySchema)
 
 	if pkInfo.PartitionKey == "" {
 		return nil, fmt.Errorf("partition key not found for table: %s", tableName)
 	}
 
+	pkInfo.Indexes = make(map[string]*IndexKeyInfo)
+	for _, index := range resp.Table.GlobalSecondaryIndexes {
+		indexInfo := &IndexKeyInfo{Name: aws.StringValue(index.IndexName)}
+		indexInfo.PartitionKey, indexInfo.SortKey = keyAttributes(index.KeySchema)
+		if index.Projection != nil {
+			indexInfo.Projection, indexInfo.NonKeyAttributes = index.Projection.ProjectionType, index.Projection.NonKeyAttributes
+		}
+		pkInfo.Indexes[indexInfo.Name] = indexInfo
+	}
+	for _, index := range resp.Table.LocalSecondaryIndexes {
+		indexInfo := &IndexKeyInfo{Name: aws.StringValue(index.IndexName)}
+		indexInfo.PartitionKey, indexInfo.SortKey = keyAttributes(index.KeySchema)
+		if index.Projection != nil {
+			indexInfo.Projection, indexInfo.NonKeyAttributes = index.Projection.ProjectionType, index.Projection.NonKeyAttributes
+		}
+		pkInfo.Indexes[indexInfo.Name] = indexInfo
+	}
+
 	return pkInfo, nil
 }
 
+// keyAttributes returns the partition and sort key attribute names of a key schema.
+func keyAttributes(keySchema []types.KeySchemaElement) (string, string) {
+	var partitionKey, sortKey string
+	for _, element := range keySchema {
+		if element.KeyType == types.KeyTypeHash {
+			partitionKey = aws.StringValue(element.AttributeName)
+		} else if element.KeyType == types.KeyTypeRange {
+			sortKey = aws.StringValue(element.AttributeName)
+		}
+	}
+	return partitionKey, sortKey
+}
+
 // ConstructMaterialName constructs a material name based on an item's primary key.
 func ConstructMaterialName(item map[string]types.AttributeValue, pkInfo *PrimaryKeyInfo) (string, error) {
+	if _, ok := item[pkInfo.PartitionKey]; !ok {
+		return "", fmt.Errorf("%w: partition key %q", ErrMissingKeyAttribute, pkInfo.PartitionKey)
+	}
+	if _, ok := item[pkInfo.SortKey]; pkInfo.SortKey != "" && !ok {
+		return "", fmt.Errorf("%w: sort key %q", ErrMissingKeyAttribute, pkInfo.SortKey)
+	}
+
 	// TODO: replace with attributevalue.UnmarshalMap
 	var partitionKeyValue string
 	err := attributevalue.Unmarshal(item[pkInfo.PartitionKey], &partitionKeyValue)
@@ -0,0 +1,769 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/cloudopsy/dynamodb-encryption-go/internal/fakeddb"
+	fakeawskms "github.com/cloudopsy/dynamodb-encryption-go/internal/fakekms"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider/store"
+)
+
+func TestConstructMaterialName(t *testing.T) {
+	pkInfo := &PrimaryKeyInfo{Table: "table", PartitionKey: "PK", SortKey: "SK"}
+
+	item := map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: "pk"},
+		"SK": &types.AttributeValueMemberS{Value: "sk"},
+	}
+	name, err := ConstructMaterialName(item, pkInfo)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	again, err := ConstructMaterialName(item, pkInfo)
+	if err != nil || again != name {
+		t.Errorf("expected material name to be stable, got %q and %q", name, again)
+	}
+}
+
+func TestConstructMaterialName_MissingKey(t *testing.T) {
+	pkInfo := &PrimaryKeyInfo{Table: "table", PartitionKey: "PK", SortKey: "SK"}
+
+	testCases := []struct {
+		name string
+		item map[string]types.AttributeValue
+	}{
+		{
+			name: "MissingPartitionKey",
+			item: map[string]types.AttributeValue{"SK": &types.AttributeValueMemberS{Value: "sk"}},
+		},
+		{
+			name: "MissingSortKey",
+			item: map[string]types.AttributeValue{"PK": &types.AttributeValueMemberS{Value: "pk"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ConstructMaterialName(tc.item, pkInfo)
+			if !errors.Is(err, ErrMissingKeyAttribute) {
+				t.Errorf("expected ErrMissingKeyAttribute, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPrimaryKeyInfo_IsKeyAttribute(t *testing.T) {
+	pkInfo := &PrimaryKeyInfo{
+		Table:        "table",
+		PartitionKey: "PK",
+		SortKey:      "SK",
+		Indexes: map[string]*IndexKeyInfo{
+			"GSI1": {Name: "GSI1", PartitionKey: "GSI1PK", SortKey: "GSI1SK"},
+			"LSI1": {Name: "LSI1", PartitionKey: "PK", SortKey: "LSI1SK"},
+		},
+	}
+
+	for _, name := range []string{"PK", "SK", "GSI1PK", "GSI1SK", "LSI1SK"} {
+		if !pkInfo.IsKeyAttribute(name) {
+			t.Errorf("expected %q to be a key attribute", name)
+		}
+	}
+	if pkInfo.IsKeyAttribute("Data") {
+		t.Errorf("expected %q not to be a key attribute", "Data")
+	}
+}
+
+func TestMaterialVersion(t *testing.T) {
+	testCases := []struct {
+		name     string
+		item     map[string]types.AttributeValue
+		expected int64
+		wantErr  bool
+	}{
+		{name: "Unpinned", item: map[string]types.AttributeValue{}},
+		{name: "Pinned", item: map[string]types.AttributeValue{MaterialVersionAttribute: &types.AttributeValueMemberN{Value: "3"}}, expected: 3},
+		{name: "WrongType", item: map[string]types.AttributeValue{MaterialVersionAttribute: &types.AttributeValueMemberS{Value: "3"}}, wantErr: true},
+		{name: "Malformed", item: map[string]types.AttributeValue{MaterialVersionAttribute: &types.AttributeValueMemberN{Value: "x"}}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			version, err := materialVersion(tc.item)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if version != tc.expected {
+				t.Errorf("expected version %d, got %d", tc.expected, version)
+			}
+		})
+	}
+}
+
+func TestUsageProjection(t *testing.T) {
+	projection, names := usageProjection(&PrimaryKeyInfo{Table: "table", PartitionKey: "PK", SortKey: "SK"})
+	if *projection != "#pk, #mv, #ms, #sk" {
+		t.Errorf("unexpected projection %q", *projection)
+	}
+	if names["#pk"] != "PK" || names["#sk"] != "SK" || names["#mv"] != MaterialVersionAttribute || names["#ms"] != MaterialScopeAttribute {
+		t.Errorf("unexpected attribute names %v", names)
+	}
+
+	projection, names = usageProjection(&PrimaryKeyInfo{Table: "table", PartitionKey: "PK"})
+	if *projection != "#pk, #mv, #ms" {
+		t.Errorf("unexpected projection %q", *projection)
+	}
+	if _, ok := names["#sk"]; ok {
+		t.Errorf("expected no sort key placeholder, got %v", names)
+	}
+}
+
+// reversingKey "encrypts" by reversing the plaintext.
+type reversingKey struct {
+	delegatedkeys.DelegatedKey
+}
+
+func (reversingKey) Encrypt(plaintext []byte, associatedData []byte) ([]byte, error) {
+	ciphertext := make([]byte, len(plaintext))
+	for i, b := range plaintext {
+		ciphertext[len(plaintext)-1-i] = b
+	}
+	return ciphertext, nil
+}
+
+// batchProvider records BatchEncryptionMaterials calls and the tables they were made for.
+type batchProvider struct {
+	provider.CryptographicMaterialsProvider
+	calls  [][]string
+	tables []string
+}
+
+func (p *batchProvider) BatchEncryptionMaterials(ctx context.Context, materialNames []string) ([]materials.CryptographicMaterials, error) {
+	p.calls = append(p.calls, materialNames)
+	request, _ := provider.MaterialsRequestFromContext(ctx)
+	p.tables = append(p.tables, request.TableName)
+	results := make([]materials.CryptographicMaterials, len(materialNames))
+	for i := range materialNames {
+		description := map[string]string{provider.MaterialVersionKey: strconv.Itoa(i + 1)}
+		results[i] = materials.NewEncryptionMaterials(description, reversingKey{}, nil)
+	}
+	return results, nil
+}
+
+func TestEncryptWriteRequests_Batch(t *testing.T) {
+	bp := &batchProvider{}
+	ec := NewEncryptedClient(nil, bp, WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}))
+
+	requestItems := map[string][]types.WriteRequest{
+		"users": {
+			{PutRequest: &types.PutRequest{Item: map[string]types.AttributeValue{
+				"ID":    &types.AttributeValueMemberS{Value: "1"},
+				"Email": &types.AttributeValueMemberS{Value: "a@example.com"},
+			}}},
+			{DeleteRequest: &types.DeleteRequest{Key: map[string]types.AttributeValue{
+				"ID": &types.AttributeValueMemberS{Value: "3"},
+			}}},
+			{PutRequest: &types.PutRequest{Item: map[string]types.AttributeValue{
+				"ID":    &types.AttributeValueMemberS{Value: "2"},
+				"Email": &types.AttributeValueMemberS{Value: "b@example.com"},
+			}}},
+		},
+	}
+
+	if err := ec.encryptWriteRequests(context.Background(), requestItems); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(bp.calls) != 1 || len(bp.calls[0]) != 2 {
+		t.Fatalf("Expected one batch call for both puts, got %v", bp.calls)
+	}
+
+	for i, want := range map[int]string{0: "1", 2: "2"} {
+		item := requestItems["users"][i].PutRequest.Item
+		if _, ok := item["Email"].(*types.AttributeValueMemberB); !ok {
+			t.Errorf("Expected Email to be encrypted, got %T", item["Email"])
+		}
+		if id := item["ID"].(*types.AttributeValueMemberS).Value; id != want {
+			t.Errorf("Expected ID %s to stay in plaintext, got %s", want, id)
+		}
+		if _, ok := item[MaterialVersionAttribute]; !ok {
+			t.Errorf("Expected item %d to record its material version", i)
+		}
+	}
+}
+
+func TestEncryptWriteRequests_Tables(t *testing.T) {
+	bp := &batchProvider{}
+	ec := NewEncryptedClient(nil, bp,
+		WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}),
+		WithPrimaryKeyInfo("orders", PrimaryKeyInfo{PartitionKey: "ID"}),
+		WithPrimaryKeyInfo("audit", PrimaryKeyInfo{PartitionKey: "ID"}))
+	put := func(id string) types.WriteRequest {
+		return types.WriteRequest{PutRequest: &types.PutRequest{Item: map[string]types.AttributeValue{
+			"ID":    &types.AttributeValueMemberS{Value: id},
+			"Email": &types.AttributeValueMemberS{Value: id + "@example.com"},
+		}}}
+	}
+
+	requestItems := map[string][]types.WriteRequest{
+		"users":  {put("1"), put("2")},
+		"orders": {put("3")},
+		"audit": {{DeleteRequest: &types.DeleteRequest{Key: map[string]types.AttributeValue{
+			"ID": &types.AttributeValueMemberS{Value: "4"},
+		}}}},
+	}
+	if err := ec.encryptWriteRequests(context.Background(), requestItems); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Tables without puts fetch no materials
+	sort.Strings(bp.tables)
+	if want := []string{"orders", "users"}; !reflect.DeepEqual(bp.tables, want) {
+		t.Errorf("expected one call naming each of %v, got %v", want, bp.tables)
+	}
+}
+
+func TestEncryptedClient_CheckIndex(t *testing.T) {
+	ec := NewEncryptedClient(nil, nil,
+		WithPrimaryKeyInfo("orders", PrimaryKeyInfo{
+			PartitionKey: "PK",
+			SortKey:      "SK",
+			Indexes: map[string]*IndexKeyInfo{
+				"ByStatus":    {Name: "ByStatus", PartitionKey: "Status", Projection: types.ProjectionTypeInclude, NonKeyAttributes: []string{"Total", MaterialVersionAttribute}},
+				"Unversioned": {Name: "Unversioned", PartitionKey: "Status", Projection: types.ProjectionTypeInclude, NonKeyAttributes: []string{"Total"}},
+				"Signed":      {Name: "Signed", PartitionKey: "Status", Projection: types.ProjectionTypeInclude, NonKeyAttributes: []string{MaterialVersionAttribute, SignatureAttribute}},
+				"Plain":       {Name: "Plain", PartitionKey: "Status", Projection: types.ProjectionTypeInclude, NonKeyAttributes: []string{"Region"}},
+				"All":         {Name: "All", PartitionKey: "Status", Projection: types.ProjectionTypeAll},
+			},
+		}),
+		WithOptions(WithEncryption("Region", EncryptNone)),
+	)
+
+	testCases := []struct {
+		name       string
+		index      string
+		projection string
+		names      map[string]string
+		err        error
+	}{
+		{name: "VersionProjected", index: "ByStatus"},
+		{name: "VersionMissing", index: "Unversioned", err: ErrIndexProjection},
+		{name: "SignatureProjected", index: "Signed", err: ErrIndexProjection},
+		{name: "PlaintextOnly", index: "Plain"},
+		{name: "ProjectionExpression", index: "All", projection: "PK, SK, #t, #v", names: map[string]string{"#t": "Total", "#v": MaterialVersionAttribute}},
+		{name: "ProjectionWithoutSortKey", index: "All", projection: "PK, Region", err: ErrIndexProjection},
+		{name: "ProjectionWithoutVersion", index: "All", projection: "PK, SK, Total", err: ErrIndexProjection},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var projection *string
+			if tc.projection != "" {
+				projection = &tc.projection
+			}
+			index := tc.index
+			err := ec.checkIndex(context.Background(), "orders", &index, projection, tc.names)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected error %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+// capacityClient serves the last item put with it, and reports consumed capacity.
+type capacityClient struct {
+	putClient
+}
+
+func (c *capacityClient) GetItem(ctx context.Context, input *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{
+		Item:             c.input.Item,
+		ConsumedCapacity: &types.ConsumedCapacity{TableName: input.TableName, CapacityUnits: aws.Float64(0.5)},
+	}, nil
+}
+
+func TestEncryptedClient_PreservesFields(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	client := &capacityClient{}
+	ec := NewEncryptedClient(client, &staticProvider{description: map[string]string{}, dataKey: dataKey},
+		WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}),
+		WithOptions(WithEncryption("Role", EncryptNone)),
+	)
+	email := &types.AttributeValueMemberS{Value: "a@example.com"}
+	role := &types.AttributeValueMemberS{Value: "reader"}
+	item := map[string]types.AttributeValue{
+		"ID":    &types.AttributeValueMemberS{Value: "1"},
+		"Email": email,
+		"Role":  role,
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName:                 aws.String("users"),
+		Item:                      item,
+		ConditionExpression:       aws.String("attribute_not_exists(ID) OR (attribute_exists(#email) AND #role = :role)"),
+		ExpressionAttributeNames:  map[string]string{"#email": "Email", "#role": "Role"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{":role": role},
+		ReturnValues:              types.ReturnValueAllOld,
+		ReturnConsumedCapacity:    types.ReturnConsumedCapacityTotal,
+	}
+	if _, err := ec.PutItem(context.Background(), input); err != nil {
+		t.Fatalf("failed to put item: %v", err)
+	}
+	sent := client.input
+	if aws.StringValue(sent.ConditionExpression) != aws.StringValue(input.ConditionExpression) || sent.ExpressionAttributeNames["#email"] != "Email" {
+		t.Errorf("expected the condition to be sent, got %q with names %v", aws.StringValue(sent.ConditionExpression), sent.ExpressionAttributeNames)
+	}
+	if sent.ExpressionAttributeValues[":role"] != role {
+		t.Errorf("expected :role to be sent in plaintext, got %v", sent.ExpressionAttributeValues[":role"])
+	}
+	if sent.ReturnValues != types.ReturnValueAllOld || sent.ReturnConsumedCapacity != types.ReturnConsumedCapacityTotal {
+		t.Errorf("expected ReturnValues and ReturnConsumedCapacity to be sent, got %q and %q", sent.ReturnValues, sent.ReturnConsumedCapacity)
+	}
+	if _, ok := sent.Item["Email"].(*types.AttributeValueMemberB); !ok || input.Item["Email"] != email {
+		t.Errorf("expected Email to be encrypted without changing the caller's input")
+	}
+
+	output, err := ec.GetItem(context.Background(), &dynamodb.GetItemInput{TableName: aws.String("users"), Key: map[string]types.AttributeValue{"ID": item["ID"]}})
+	if err != nil {
+		t.Fatalf("failed to get item: %v", err)
+	}
+	if got, ok := output.Item["Email"].(*types.AttributeValueMemberS); !ok || got.Value != email.Value {
+		t.Errorf("expected Email to be decrypted, got %v", output.Item["Email"])
+	}
+	if output.ConsumedCapacity == nil || aws.Float64Value(output.ConsumedCapacity.CapacityUnits) != 0.5 {
+		t.Errorf("expected the consumed capacity to be returned, got %v", output.ConsumedCapacity)
+	}
+}
+
+// pageClient serves one item per Query page, with the consumed capacity of the page.
+type pageClient struct {
+	DynamoDBClientInterface
+	ids   []string
+	calls int
+}
+
+func (c *pageClient) Query(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	c.calls++
+	page := 0
+	if start, ok := input.ExclusiveStartKey["ID"].(*types.AttributeValueMemberS); ok {
+		for i, id := range c.ids {
+			if id == start.Value {
+				page = i + 1
+			}
+		}
+	}
+	key := map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: c.ids[page]}}
+	output := &dynamodb.QueryOutput{
+		Items:            []map[string]types.AttributeValue{key},
+		Count:            1,
+		ScannedCount:     2,
+		ConsumedCapacity: &types.ConsumedCapacity{TableName: input.TableName, CapacityUnits: aws.Float64(0.5)},
+	}
+	if page < len(c.ids)-1 {
+		output.LastEvaluatedKey = key
+	}
+	return output, nil
+}
+
+func TestEncryptedClient_QueryPages(t *testing.T) {
+	client := &pageClient{ids: []string{"1", "2", "3"}}
+	ec := NewEncryptedClient(client, &staticProvider{}, WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}))
+	input := &dynamodb.QueryInput{TableName: aws.String("users")}
+
+	output, err := ec.Query(context.Background(), input)
+	if err != nil {
+		t.Fatalf("failed to query: %v", err)
+	}
+	if client.calls != 1 || len(output.Items) != 1 || output.Count != 1 {
+		t.Errorf("expected a single page of 1 item from 1 call, got %d items from %d calls", len(output.Items), client.calls)
+	}
+	if output.ScannedCount != 2 || output.ConsumedCapacity == nil || aws.Float64Value(output.ConsumedCapacity.CapacityUnits) != 0.5 {
+		t.Errorf("expected the page's scanned count and consumed capacity, got %d and %v", output.ScannedCount, output.ConsumedCapacity)
+	}
+	if got, ok := output.LastEvaluatedKey["ID"].(*types.AttributeValueMemberS); !ok || got.Value != "1" {
+		t.Errorf("expected the page's LastEvaluatedKey, got %v", output.LastEvaluatedKey)
+	}
+
+	// SDK paginators built on the client read every page once
+	client.calls = 0
+	var ids []string
+	paginator := dynamodb.NewQueryPaginator(ec, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			t.Fatalf("failed to query: %v", err)
+		}
+		for _, item := range page.Items {
+			ids = append(ids, item["ID"].(*types.AttributeValueMemberS).Value)
+		}
+	}
+	if client.calls != 3 || len(ids) != 3 || ids[0] != "1" || ids[2] != "3" {
+		t.Errorf("expected every page once from 3 calls, got %v from %d calls", ids, client.calls)
+	}
+}
+
+// oldItemClient stores items keyed by their "ID" attribute and returns the replaced or deleted item for ReturnValues
+// ALL_OLD.
+type oldItemClient struct {
+	DynamoDBClientInterface
+	items map[string]map[string]types.AttributeValue
+}
+
+func (c *oldItemClient) PutItem(ctx context.Context, input *dynamodb.PutItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	id := input.Item["ID"].(*types.AttributeValueMemberS).Value
+	output := &dynamodb.PutItemOutput{}
+	if input.ReturnValues == types.ReturnValueAllOld {
+		output.Attributes = c.items[id]
+	}
+	c.items[id] = input.Item
+	return output, nil
+}
+
+func (c *oldItemClient) DeleteItem(ctx context.Context, input *dynamodb.DeleteItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	id := input.Key["ID"].(*types.AttributeValueMemberS).Value
+	output := &dynamodb.DeleteItemOutput{}
+	if input.ReturnValues == types.ReturnValueAllOld {
+		output.Attributes = c.items[id]
+	}
+	delete(c.items, id)
+	return output, nil
+}
+
+func TestEncryptedClient_ReturnValuesAllOld(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	item := func(id, email string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"ID":    &types.AttributeValueMemberS{Value: id},
+			"Email": &types.AttributeValueMemberS{Value: email},
+		}
+	}
+	key := map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: "1"}}
+
+	testCases := []struct {
+		name      string
+		operation func(ec *EncryptedClient) (map[string]types.AttributeValue, error)
+		old       string
+	}{
+		{
+			name: "Put replacing an item",
+			operation: func(ec *EncryptedClient) (map[string]types.AttributeValue, error) {
+				output, err := ec.PutItem(context.Background(), &dynamodb.PutItemInput{TableName: aws.String("users"), Item: item("1", "new@example.com"), ReturnValues: types.ReturnValueAllOld})
+				if err != nil {
+					return nil, err
+				}
+				return output.Attributes, nil
+			},
+			old: "old@example.com",
+		},
+		{
+			name: "Put creating an item",
+			operation: func(ec *EncryptedClient) (map[string]types.AttributeValue, error) {
+				output, err := ec.PutItem(context.Background(), &dynamodb.PutItemInput{TableName: aws.String("users"), Item: item("2", "new@example.com"), ReturnValues: types.ReturnValueAllOld})
+				if err != nil {
+					return nil, err
+				}
+				return output.Attributes, nil
+			},
+		},
+		{
+			name: "Put without return values",
+			operation: func(ec *EncryptedClient) (map[string]types.AttributeValue, error) {
+				output, err := ec.PutItem(context.Background(), &dynamodb.PutItemInput{TableName: aws.String("users"), Item: item("1", "new@example.com")})
+				if err != nil {
+					return nil, err
+				}
+				return output.Attributes, nil
+			},
+		},
+		{
+			name: "Delete",
+			operation: func(ec *EncryptedClient) (map[string]types.AttributeValue, error) {
+				output, err := ec.DeleteItem(context.Background(), &dynamodb.DeleteItemInput{TableName: aws.String("users"), Key: key, ReturnValues: types.ReturnValueAllOld})
+				if err != nil {
+					return nil, err
+				}
+				return output.Attributes, nil
+			},
+			old: "old@example.com",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &oldItemClient{items: make(map[string]map[string]types.AttributeValue)}
+			ec := NewEncryptedClient(client, &staticProvider{description: map[string]string{}, dataKey: dataKey},
+				WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}))
+			if _, err := ec.PutItem(context.Background(), &dynamodb.PutItemInput{TableName: aws.String("users"), Item: item("1", "old@example.com")}); err != nil {
+				t.Fatalf("failed to put item: %v", err)
+			}
+
+			old, err := tc.operation(ec)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.old == "" {
+				if len(old) != 0 {
+					t.Errorf("expected no old item, got %v", old)
+				}
+				return
+			}
+			if got, ok := old["Email"].(*types.AttributeValueMemberS); !ok || got.Value != tc.old {
+				t.Errorf("expected the old Email to decrypt to %s, got %v", tc.old, old["Email"])
+			}
+			for name := range old {
+				if isReservedAttribute(name) {
+					t.Errorf("expected metadata attribute %s to be removed", name)
+				}
+			}
+		})
+	}
+}
+
+func TestEncryptedClient_ClientParity(t *testing.T) {
+	sdkClient := reflect.TypeOf(&dynamodb.Client{})
+	encryptedClient := reflect.TypeOf(&EncryptedClient{})
+	for i := 0; i < sdkClient.NumMethod(); i++ {
+		method := sdkClient.Method(i)
+		ours, ok := encryptedClient.MethodByName(method.Name)
+		if !ok {
+			t.Errorf("EncryptedClient is missing %s", method.Name)
+			continue
+		}
+		// Compare the signatures without their receivers
+		if ours.Type.NumIn() != method.Type.NumIn() || ours.Type.NumOut() != method.Type.NumOut() {
+			t.Errorf("EncryptedClient.%s has signature %v, expected %v", method.Name, ours.Type, method.Type)
+			continue
+		}
+		for j := 1; j < method.Type.NumIn(); j++ {
+			if ours.Type.In(j) != method.Type.In(j) {
+				t.Errorf("EncryptedClient.%s has signature %v, expected %v", method.Name, ours.Type, method.Type)
+			}
+		}
+		for j := 0; j < method.Type.NumOut(); j++ {
+			if ours.Type.Out(j) != method.Type.Out(j) {
+				t.Errorf("EncryptedClient.%s has signature %v, expected %v", method.Name, ours.Type, method.Type)
+			}
+		}
+	}
+
+	ec := NewEncryptedClient(&capacityClient{}, &staticProvider{})
+	if _, err := ec.ExecuteStatement(context.Background(), &dynamodb.ExecuteStatementInput{Statement: aws.String("SELECT * FROM users")}); !errors.Is(err, ErrStatementsNotSupported) {
+		t.Errorf("expected ErrStatementsNotSupported, got %v", err)
+	}
+}
+
+// deletingProvider records the material names deleted through it.
+type deletingProvider struct {
+	staticProvider
+	deleted []string
+}
+
+func (p *deletingProvider) DeleteMaterials(ctx context.Context, materialName string) error {
+	p.deleted = append(p.deleted, materialName)
+	return nil
+}
+
+// unprocessedClient leaves the delete requests of the given IDs unprocessed in BatchWriteItem.
+type unprocessedClient struct {
+	DynamoDBClientInterface
+	unprocessed map[string]bool
+}
+
+func (c *unprocessedClient) BatchWriteItem(ctx context.Context, input *dynamodb.BatchWriteItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	output := &dynamodb.BatchWriteItemOutput{UnprocessedItems: map[string][]types.WriteRequest{}}
+	for tableName, writeRequests := range input.RequestItems {
+		for _, writeRequest := range writeRequests {
+			if c.unprocessed[writeRequest.DeleteRequest.Key["ID"].(*types.AttributeValueMemberS).Value] {
+				output.UnprocessedItems[tableName] = append(output.UnprocessedItems[tableName], writeRequest)
+			}
+		}
+	}
+	return output, nil
+}
+
+func TestBatchWriteItem_MaterialCleanup(t *testing.T) {
+	deletes := func(ids ...string) []types.WriteRequest {
+		var writeRequests []types.WriteRequest
+		for _, id := range ids {
+			writeRequests = append(writeRequests, types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: idItem(id)}})
+		}
+		return writeRequests
+	}
+	manyIDs := make([]string, 30)
+	for i := range manyIDs {
+		manyIDs[i] = strconv.Itoa(i)
+	}
+
+	testCases := []struct {
+		name        string
+		cleanup     bool
+		requests    map[string][]types.WriteRequest
+		unprocessed []string
+		deleted     map[string][]string // IDs per table
+		err         bool
+	}{
+		{
+			name:     "Cleanup disabled",
+			requests: map[string][]types.WriteRequest{"users": deletes("1", "2")},
+		},
+		{
+			name:     "Material names",
+			cleanup:  true,
+			requests: map[string][]types.WriteRequest{"users": deletes("1", "2"), "orders": deletes("1")},
+			deleted:  map[string][]string{"users": {"1", "2"}, "orders": {"1"}},
+		},
+		{
+			name:     "Over 25 keys",
+			cleanup:  true,
+			requests: map[string][]types.WriteRequest{"users": deletes(manyIDs...)},
+			deleted:  map[string][]string{"users": manyIDs},
+		},
+		{
+			name:        "Unprocessed",
+			cleanup:     true,
+			requests:    map[string][]types.WriteRequest{"users": deletes("1", "2", "3")},
+			unprocessed: []string{"2"},
+			deleted:     map[string][]string{"users": {"1", "3"}},
+		},
+		{
+			name:    "Unserializable key",
+			cleanup: true,
+			requests: map[string][]types.WriteRequest{"users": {{DeleteRequest: &types.DeleteRequest{Key: map[string]types.AttributeValue{
+				"ID":  &types.AttributeValueMemberS{Value: "1"},
+				"Bad": nil,
+			}}}}},
+			err: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &unprocessedClient{unprocessed: map[string]bool{}}
+			for _, id := range tc.unprocessed {
+				client.unprocessed[id] = true
+			}
+			p := &deletingProvider{}
+			ec := NewEncryptedClient(client, p,
+				WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}),
+				WithPrimaryKeyInfo("orders", PrimaryKeyInfo{PartitionKey: "ID"}),
+				WithClientConfig(NewClientConfig(WithMaterialCleanup(tc.cleanup))))
+
+			output, err := ec.BatchWriteItem(context.Background(), &dynamodb.BatchWriteItemInput{RequestItems: tc.requests})
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				if len(p.deleted) != 0 {
+					t.Errorf("expected no materials deleted, got %v", p.deleted)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(output.UnprocessedItems["users"]) != len(tc.unprocessed) {
+				t.Errorf("expected %d unprocessed items, got %d", len(tc.unprocessed), len(output.UnprocessedItems["users"]))
+			}
+
+			var expected []string
+			for tableName, ids := range tc.deleted {
+				pkInfo, err := ec.getPrimaryKeyInfo(context.Background(), tableName)
+				if err != nil {
+					t.Fatalf("failed to get primary key info: %v", err)
+				}
+				for _, id := range ids {
+					materialName, err := ConstructMaterialName(idItem(id), pkInfo)
+					if err != nil {
+						t.Fatalf("failed to construct material name: %v", err)
+					}
+					expected = append(expected, materialName)
+				}
+			}
+			sort.Strings(expected)
+			sort.Strings(p.deleted)
+			if !reflect.DeepEqual(p.deleted, expected) {
+				t.Errorf("expected %d materials deleted, got %d: %v", len(expected), len(p.deleted), p.deleted)
+			}
+		})
+	}
+}
+
+// deletingMemoryClient is a memoryClient that also deletes items.
+type deletingMemoryClient struct {
+	*memoryClient
+}
+
+func (c deletingMemoryClient) DeleteItem(ctx context.Context, input *dynamodb.DeleteItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	delete(c.items, input.Key["ID"].(*types.AttributeValueMemberS).Value)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func TestDeleteItem_MaterialCleanupWithReuse(t *testing.T) {
+	const keyURI = "arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b"
+	kms, err := fakeawskms.New([]string{keyURI})
+	if err != nil {
+		t.Fatalf("failed to create fake KMS: %v", err)
+	}
+	materialStore, err := store.NewMetaStore(fakeddb.New().Client(), "meta")
+	if err != nil {
+		t.Fatalf("failed to create meta store: %v", err)
+	}
+	materialsProvider, err := provider.New(keyURI, nil, materialStore, provider.WithKMSClient(kms),
+		provider.WithMaterialReuse(provider.UsageLimits{MaxMessages: 1000}))
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+	ec := NewEncryptedClient(deletingMemoryClient{&memoryClient{items: make(map[string]map[string]types.AttributeValue)}}, materialsProvider,
+		WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}),
+		WithClientConfig(NewClientConfig(WithMaterialCleanup(true))))
+	ctx := context.Background()
+	key := map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: "1"}}
+	put := func(email string) {
+		t.Helper()
+		item := map[string]types.AttributeValue{"ID": key["ID"], "Email": &types.AttributeValueMemberS{Value: email}}
+		if _, err := ec.PutItem(ctx, &dynamodb.PutItemInput{TableName: aws.String("users"), Item: item}); err != nil {
+			t.Fatalf("failed to put item: %v", err)
+		}
+	}
+
+	put("a@example.com")
+	if _, err := ec.GetItem(ctx, &dynamodb.GetItemInput{TableName: aws.String("users"), Key: key}); err != nil {
+		t.Fatalf("failed to get item: %v", err)
+	}
+	if _, err := ec.DeleteItem(ctx, &dynamodb.DeleteItemInput{TableName: aws.String("users"), Key: key}); err != nil {
+		t.Fatalf("failed to delete item: %v", err)
+	}
+	put("b@example.com")
+
+	output, err := ec.GetItem(ctx, &dynamodb.GetItemInput{TableName: aws.String("users"), Key: key})
+	if err != nil {
+		t.Fatalf("expected the item written after the delete to decrypt, got %v", err)
+	}
+	if email, ok := output.Item["Email"].(*types.AttributeValueMemberS); !ok || email.Value != "b@example.com" {
+		t.Errorf("expected b@example.com, got %v", output.Item["Email"])
+	}
+}
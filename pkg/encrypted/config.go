@@ -1,5 +1,14 @@
 package encrypted
 
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/compression"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/serde"
+)
+
 // EncryptionAction represents the encryption-related action to be taken on a specific attribute.
 type EncryptionAction int
 
@@ -7,17 +16,226 @@ const (
 	EncryptNone          EncryptionAction = iota // No encryption should be applied.
 	EncryptStandard                              // The attribute should be encrypted using a standard algorithm.
 	EncryptDeterministic                         // The attribute should be encrypted deterministically for consistent outcomes.
+	EncryptHash                                  // Only a keyed digest of the attribute is stored, see WithHashKey.
 )
 
+func (a EncryptionAction) valid() bool {
+	return a >= EncryptNone && a <= EncryptHash
+}
+
+// ErrKeyAttributeEncrypted is returned when a configuration asks for a primary or index key attribute to be encrypted.
+var ErrKeyAttributeEncrypted = errors.New("key attributes can not be encrypted")
+
+// ErrInvalidConfig is returned when a configuration holds an unknown action, a malformed pattern or an
+// unregistered compression algorithm.
+var ErrInvalidConfig = errors.New("invalid client config")
+
+// ErrConfigFrozen is returned, or panicked with by options, when a frozen ClientConfig is modified.
+var ErrConfigFrozen = errors.New("client config is frozen")
+
 // ClientConfig holds the configuration for client operations, focusing on encryption.
+//
+// An EncryptedClient freezes its configuration, so it can be read by concurrent operations without locking.
+// To change the settings of a frozen configuration, derive a new one with With.
 type ClientConfig struct {
 	Encryption EncryptionConfig
+
+	// MaterialCleanup makes DeleteItem remove the deleted item's materials from the material store.
+	// Only enable it when every item is encrypted under its own material name, and materials are not reused;
+	// Validate reports it together with table materials or a provider reusing materials.
+	MaterialCleanup bool
+
+	// Limits bounds the size and nesting depth of decrypted attributes. Zero fields use serde.DefaultLimits.
+	Limits serde.Limits
+	// Compression selects the algorithm applied to attributes before encryption. compression.None disables it.
+	Compression compression.ID
+
+	// SignItems adds a signature over the whole stored item, written to SignatureAttribute. Signed items are verified
+	// whenever they are decrypted, whether or not SignItems is set.
+	SignItems bool
+
+	// AttributeKeys encrypts every attribute under its own subkey, derived from the item's data key, instead of
+	// under the data key itself. Items record the derivation in KeyDerivationAttribute, so they decrypt whether or
+	// not AttributeKeys is set.
+	AttributeKeys bool
+
+	// DeterministicScope limits where deterministic attributes encrypt equal values equally. Items record a scope
+	// narrower than ScopeGlobal in DeterministicScopeAttribute, so they decrypt whatever the reader's scope is.
+	DeterministicScope DeterministicScope
+
+	// TableMaterials encrypts every item of a table under the table's shared materials, named by TableMaterialName,
+	// instead of under materials of its own. Items record it in MaterialScopeAttribute, so they decrypt whether or
+	// not TableMaterials is set.
+	TableMaterials bool
+
+	// AttributeRenames maps legacy attribute names to canonical ones. Legacy attributes are decrypted under the name
+	// they were stored with and returned under the canonical name, and written under the canonical name.
+	AttributeRenames map[string]string
+
+	// DefaultDenyTables holds the tables whose writes fail with ErrUnclassifiedAttribute when an item holds an
+	// attribute without an explicitly configured action.
+	DefaultDenyTables map[string]bool
+
+	// SystemAttributes holds path.Match patterns of attributes maintained by DynamoDB, which are neither encrypted nor
+	// signed. Nil selects DefaultSystemAttributes.
+	SystemAttributes []string
+
+	// PolicyFingerprints records the fingerprint of the attribute actions every item is encrypted with in
+	// PolicyFingerprintAttribute, so items written under other actions can be found with FindOutdatedItems.
+	PolicyFingerprints bool
+
+	frozen bool
+}
+
+// Clone returns a deep copy of the configuration. The copy is not frozen.
+func (c *ClientConfig) Clone() *ClientConfig {
+	clone := *c
+	clone.Encryption = c.Encryption.Clone()
+	if c.AttributeRenames != nil {
+		clone.AttributeRenames = make(map[string]string, len(c.AttributeRenames))
+		for legacy, canonical := range c.AttributeRenames {
+			clone.AttributeRenames[legacy] = canonical
+		}
+	}
+	if c.DefaultDenyTables != nil {
+		clone.DefaultDenyTables = make(map[string]bool, len(c.DefaultDenyTables))
+		for tableName, deny := range c.DefaultDenyTables {
+			clone.DefaultDenyTables[tableName] = deny
+		}
+	}
+	if c.SystemAttributes != nil {
+		clone.SystemAttributes = append(make([]string, 0, len(c.SystemAttributes)), c.SystemAttributes...)
+	}
+	clone.frozen = false
+	return &clone
+}
+
+// Freeze makes the configuration immutable: options applied to it afterwards panic with ErrConfigFrozen.
+// Fields must not be assigned directly once a configuration is frozen. Freeze returns c for chaining.
+func (c *ClientConfig) Freeze() *ClientConfig {
+	c.frozen = true
+	return c
+}
+
+// Frozen reports whether the configuration has been frozen.
+func (c *ClientConfig) Frozen() bool {
+	return c.frozen
+}
+
+// With returns a frozen copy of the configuration with the options applied, leaving c unchanged.
+func (c *ClientConfig) With(options ...Option) *ClientConfig {
+	clone := c.Clone()
+	for _, option := range options {
+		option(clone)
+	}
+	return clone.Freeze()
+}
+
+// Validate checks the configuration's actions, patterns, compression algorithm, deterministic scope, attribute
+// renames and system attribute patterns.
+func (c *ClientConfig) Validate() error {
+	if !c.Encryption.DefaultAction.valid() {
+		return fmt.Errorf("%w: unknown default action %d", ErrInvalidConfig, c.Encryption.DefaultAction)
+	}
+	for name, action := range c.Encryption.SpecificActions {
+		if !action.valid() {
+			return fmt.Errorf("%w: unknown action %d for attribute %q", ErrInvalidConfig, action, name)
+		}
+	}
+	for _, rule := range c.Encryption.PatternRules {
+		if _, err := path.Match(rule.Pattern, ""); err != nil {
+			return fmt.Errorf("%w: malformed pattern %q", ErrInvalidConfig, rule.Pattern)
+		}
+		if !rule.Action.valid() {
+			return fmt.Errorf("%w: unknown action %d for pattern %q", ErrInvalidConfig, rule.Action, rule.Pattern)
+		}
+	}
+	if c.Compression != compression.None {
+		if _, err := compression.Lookup(c.Compression); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+	}
+	if !c.DeterministicScope.valid() {
+		return fmt.Errorf("%w: unknown deterministic scope %d", ErrInvalidConfig, c.DeterministicScope)
+	}
+	if err := validateRenames(c.AttributeRenames); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	for _, pattern := range c.SystemAttributes {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%w: malformed system attribute pattern %q", ErrInvalidConfig, pattern)
+		}
+	}
+	return nil
+}
+
+// mustBeMutable panics if the configuration is frozen. Options call it before modifying the configuration.
+func (c *ClientConfig) mustBeMutable() {
+	if c.frozen {
+		panic(ErrConfigFrozen)
+	}
 }
 
 // EncryptionConfig holds encryption-specific settings, including a default action and specific actions for named attributes.
 type EncryptionConfig struct {
 	DefaultAction   EncryptionAction            // The default encryption action if no specific action is provided.
 	SpecificActions map[string]EncryptionAction // Map of attribute names to their specific encryption actions.
+	PatternRules    []PatternRule               // Pattern rules evaluated in order when no specific action matches.
+}
+
+// PatternRule applies an encryption action to every attribute whose name matches a path.Match pattern,
+// such as "pii_*".
+type PatternRule struct {
+	Pattern string
+	Action  EncryptionAction
+}
+
+// Clone returns a deep copy of the encryption settings.
+func (c EncryptionConfig) Clone() EncryptionConfig {
+	clone := EncryptionConfig{DefaultAction: c.DefaultAction}
+	if c.SpecificActions != nil {
+		clone.SpecificActions = make(map[string]EncryptionAction, len(c.SpecificActions))
+		for name, action := range c.SpecificActions {
+			clone.SpecificActions[name] = action
+		}
+	}
+	if c.PatternRules != nil {
+		clone.PatternRules = append([]PatternRule(nil), c.PatternRules...)
+	}
+	return clone
+}
+
+// ActionFor resolves the encryption action for an attribute. An exact SpecificActions entry wins, then the first
+// matching pattern rule, then the default action. Malformed patterns never match.
+func (c EncryptionConfig) ActionFor(attributeName string) EncryptionAction {
+	if action, ok := c.SpecificActions[attributeName]; ok {
+		return action
+	}
+	for _, rule := range c.PatternRules {
+		if matched, err := path.Match(rule.Pattern, attributeName); err == nil && matched {
+			return rule.Action
+		}
+	}
+	return c.DefaultAction
+}
+
+// usesAction reports whether any attribute can resolve to the action: the default action, an attribute's action or
+// a pattern rule's.
+func (c EncryptionConfig) usesAction(action EncryptionAction) bool {
+	if c.DefaultAction == action {
+		return true
+	}
+	for _, specific := range c.SpecificActions {
+		if specific == action {
+			return true
+		}
+	}
+	for _, rule := range c.PatternRules {
+		if rule.Action == action {
+			return true
+		}
+	}
+	return false
 }
 
 // NewClientConfig initializes a new ClientConfig, applying any provided functional options.
@@ -43,6 +261,7 @@ type Option func(*ClientConfig)
 // WithDefaultEncryptionAction sets the default encryption action for the client.
 func WithDefaultEncryption(action EncryptionAction) Option {
 	return func(c *ClientConfig) {
+		c.mustBeMutable()
 		c.Encryption.DefaultAction = action
 	}
 }
@@ -50,16 +269,127 @@ func WithDefaultEncryption(action EncryptionAction) Option {
 // WithEncryption sets a specific encryption action for a named attribute.
 func WithEncryption(attributeName string, action EncryptionAction) Option {
 	return func(c *ClientConfig) {
+		c.mustBeMutable()
+		if c.Encryption.SpecificActions == nil {
+			c.Encryption.SpecificActions = make(map[string]EncryptionAction)
+		}
 		c.Encryption.SpecificActions[attributeName] = action
 	}
 }
 
+// ValidateKeys rejects configurations that explicitly mark a primary or index key attribute for encryption.
+// Key attributes are always stored in plaintext so the table stays queryable.
+func (c EncryptionConfig) ValidateKeys(pkInfo *PrimaryKeyInfo) error {
+	for name, action := range c.SpecificActions {
+		if action != EncryptNone && pkInfo.IsKeyAttribute(name) {
+			return fmt.Errorf("%w: %q in table %q", ErrKeyAttributeEncrypted, name, pkInfo.Table)
+		}
+	}
+	return nil
+}
+
+// WithEncryptionPattern adds a pattern rule applying an encryption action to every attribute matching pattern.
+// Rules are evaluated in the order they are added, after exact attribute names.
+func WithEncryptionPattern(pattern string, action EncryptionAction) Option {
+	return func(c *ClientConfig) {
+		c.mustBeMutable()
+		c.Encryption.PatternRules = append(c.Encryption.PatternRules, PatternRule{Pattern: pattern, Action: action})
+	}
+}
+
+// WithMaterialCleanup enables removal of an item's materials when the item is deleted.
+func WithMaterialCleanup(enabled bool) Option {
+	return func(c *ClientConfig) {
+		c.mustBeMutable()
+		c.MaterialCleanup = enabled
+	}
+}
+
+// WithItemSignatures enables signing every encrypted item with its materials' signing key, so tampering with any
+// attribute, including plaintext ones, is detected on read.
+func WithItemSignatures(enabled bool) Option {
+	return func(c *ClientConfig) {
+		c.mustBeMutable()
+		c.SignItems = enabled
+	}
+}
+
+// WithAttributeKeys enables encrypting each attribute under a subkey derived from the item's data key for that
+// attribute, which limits the exposure of a single subkey to one attribute. The materials provider's data keys
+// must implement delegatedkeys.KeyDeriver.
+func WithAttributeKeys(enabled bool) Option {
+	return func(c *ClientConfig) {
+		c.mustBeMutable()
+		c.AttributeKeys = enabled
+	}
+}
+
+// WithDeterministicScope sets where deterministic attributes produce equal ciphertexts for equal values, for example
+// ScopePartition so that equal values of different tenants can not be correlated.
+func WithDeterministicScope(scope DeterministicScope) Option {
+	return func(c *ClientConfig) {
+		c.mustBeMutable()
+		c.DeterministicScope = scope
+	}
+}
+
+// WithTableMaterials enables encrypting all items of a table under one set of materials. Writes then reuse the
+// table's latest materials instead of creating materials per item, and batch writes fetch them once per table. Use
+// it with a provider that reuses materials, such as one created with provider.WithMaterialReuse, since a provider
+// creating new materials on every request would still do so once per call.
+func WithTableMaterials(enabled bool) Option {
+	return func(c *ClientConfig) {
+		c.mustBeMutable()
+		c.TableMaterials = enabled
+	}
+}
+
+// WithLimits caps the size and nesting depth of decrypted attributes. Attributes exceeding them fail
+// decryption with ErrPayloadTooLarge.
+func WithLimits(limits serde.Limits) Option {
+	return func(c *ClientConfig) {
+		c.mustBeMutable()
+		c.Limits = limits
+	}
+}
+
+// WithCompression compresses attributes with the compressor registered under id before encrypting them.
+// Readers must have the same ID registered to decrypt the resulting items.
+func WithCompression(id compression.ID) Option {
+	return func(c *ClientConfig) {
+		c.mustBeMutable()
+		c.Compression = id
+	}
+}
+
 // EncryptedClientOption defines a function signature for options that modify an EncryptedClient.
 type EncryptedClientOption func(*EncryptedClient)
 
-// WithClientConfig sets the EncryptedClient's configuration.
+// WithClientConfig sets the EncryptedClient's configuration. A nil config keeps the default configuration.
+// The client keeps its own frozen copy, so later changes to config do not affect it.
 func WithClientConfig(config *ClientConfig) EncryptedClientOption {
 	return func(ec *EncryptedClient) {
-		ec.ClientConfig = config
+		if config != nil {
+			ec.ClientConfig = config.Clone()
+		}
+	}
+}
+
+// WithOptions applies ClientConfig options to the EncryptedClient's current configuration, so simple setups
+// do not need to build a ClientConfig separately.
+func WithOptions(options ...Option) EncryptedClientOption {
+	return func(ec *EncryptedClient) {
+		for _, option := range options {
+			option(ec.ClientConfig)
+		}
+	}
+}
+
+// WithPrimaryKeyInfo seeds the primary key cache for a table, so the client does not need DescribeTable
+// permissions or a round trip on first use. The key info's Table field is set to tableName.
+func WithPrimaryKeyInfo(tableName string, info PrimaryKeyInfo) EncryptedClientOption {
+	return func(ec *EncryptedClient) {
+		info.Table = tableName
+		ec.PrimaryKeyCache[tableName] = &info
 	}
 }
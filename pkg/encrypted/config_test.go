@@ -0,0 +1,253 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEncryptionConfig_ActionFor(t *testing.T) {
+	config := NewClientConfig(
+		WithDefaultEncryption(EncryptNone),
+		WithEncryption("pii_public", EncryptNone),
+		WithEncryptionPattern("pii_*", EncryptStandard),
+		WithEncryptionPattern("*_id", EncryptDeterministic),
+		WithEncryptionPattern("[", EncryptStandard),
+	)
+
+	testCases := []struct {
+		attribute string
+		expected  EncryptionAction
+	}{
+		{attribute: "pii_public", expected: EncryptNone},
+		{attribute: "pii_email", expected: EncryptStandard},
+		{attribute: "pii_user_id", expected: EncryptStandard},
+		{attribute: "user_id", expected: EncryptDeterministic},
+		{attribute: "status", expected: EncryptNone},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.attribute, func(t *testing.T) {
+			if action := config.Encryption.ActionFor(tc.attribute); action != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, action)
+			}
+		})
+	}
+}
+
+func TestNewEncryptedClient_Options(t *testing.T) {
+	ec := NewEncryptedClient(nil, nil,
+		WithClientConfig(nil),
+		WithOptions(WithEncryption("status", EncryptNone)),
+	)
+	if action := ec.Config().Encryption.ActionFor("secret"); action != EncryptStandard {
+		t.Errorf("Expected default %v, got %v", EncryptStandard, action)
+	}
+	if action := ec.Config().Encryption.ActionFor("status"); action != EncryptNone {
+		t.Errorf("Expected %v, got %v", EncryptNone, action)
+	}
+
+	// Options also work on a zero value config
+	config := &ClientConfig{}
+	WithEncryption("email", EncryptDeterministic)(config)
+	if action := config.Encryption.ActionFor("email"); action != EncryptDeterministic {
+		t.Errorf("Expected %v, got %v", EncryptDeterministic, action)
+	}
+}
+
+func TestWithPrimaryKeyInfo(t *testing.T) {
+	ec := NewEncryptedClient(nil, nil, WithPrimaryKeyInfo("orders", PrimaryKeyInfo{PartitionKey: "ID"}))
+
+	// A nil DynamoDB client proves the cached key info is used instead of DescribeTable
+	pkInfo, err := ec.getPrimaryKeyInfo(context.Background(), "orders")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pkInfo.Table != "orders" || pkInfo.PartitionKey != "ID" {
+		t.Errorf("Unexpected key info %+v", pkInfo)
+	}
+}
+
+func TestValidateConfig_KeyAttributes(t *testing.T) {
+	pkInfo := PrimaryKeyInfo{
+		PartitionKey: "ID",
+		SortKey:      "Created",
+		Indexes: map[string]*IndexKeyInfo{
+			"by-email": {Name: "by-email", PartitionKey: "Email"},
+		},
+	}
+
+	testCases := []struct {
+		name   string
+		option Option
+		err    error
+	}{
+		{name: "PartitionKey", option: WithEncryption("ID", EncryptStandard), err: ErrKeyAttributeEncrypted},
+		{name: "SortKey", option: WithEncryption("Created", EncryptDeterministic), err: ErrKeyAttributeEncrypted},
+		{name: "IndexKey", option: WithEncryption("Email", EncryptStandard), err: ErrKeyAttributeEncrypted},
+		{name: "Plaintext", option: WithEncryption("ID", EncryptNone)},
+		{name: "Attribute", option: WithEncryption("Secret", EncryptStandard)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec := NewEncryptedClient(nil, nil, WithPrimaryKeyInfo("orders", pkInfo), WithOptions(tc.option))
+			if err := ec.ValidateConfig(context.Background(), "orders"); !errors.Is(err, tc.err) {
+				t.Errorf("Expected error '%v', got '%v'", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestClientConfig_Freeze(t *testing.T) {
+	config := NewClientConfig(WithEncryption("email", EncryptStandard))
+	ec := NewEncryptedClient(nil, nil, WithClientConfig(config))
+
+	// The client keeps a frozen copy, so the caller's config stays mutable
+	WithEncryption("email", EncryptNone)(config)
+	if action := ec.Config().Encryption.ActionFor("email"); action != EncryptStandard {
+		t.Errorf("Expected %v, got %v", EncryptStandard, action)
+	}
+	if !ec.Config().Frozen() {
+		t.Fatalf("Expected the client's config to be frozen")
+	}
+
+	defer func() {
+		if r := recover(); r != ErrConfigFrozen {
+			t.Errorf("Expected panic with ErrConfigFrozen, got %v", r)
+		}
+	}()
+	WithEncryption("email", EncryptNone)(ec.Config())
+}
+
+func TestClientConfig_With(t *testing.T) {
+	base := NewClientConfig(WithEncryption("email", EncryptStandard), WithEncryptionPattern("pii_*", EncryptStandard)).Freeze()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if action := base.Encryption.ActionFor("email"); action != EncryptStandard {
+					t.Errorf("Expected %v, got %v", EncryptStandard, action)
+				}
+			}
+		}()
+	}
+	updated := base.With(WithEncryption("email", EncryptDeterministic), WithEncryptionPattern("tmp_*", EncryptNone))
+	wg.Wait()
+
+	if action := updated.Encryption.ActionFor("email"); action != EncryptDeterministic {
+		t.Errorf("Expected %v, got %v", EncryptDeterministic, action)
+	}
+	if len(base.Encryption.PatternRules) != 1 {
+		t.Errorf("Expected base pattern rules to be unchanged, got %v", base.Encryption.PatternRules)
+	}
+	if !updated.Frozen() {
+		t.Errorf("Expected derived config to be frozen")
+	}
+}
+
+func TestEncryptedClient_UpdateConfig(t *testing.T) {
+	pkInfo := PrimaryKeyInfo{PartitionKey: "ID"}
+
+	var events []*ConfigChangeEvent
+	ec := NewEncryptedClient(nil, nil,
+		WithPrimaryKeyInfo("orders", pkInfo),
+		WithOptions(WithEncryption("email", EncryptStandard)),
+		WithConfigChangeHook(func(ctx context.Context, event *ConfigChangeEvent) {
+			events = append(events, event)
+		}),
+	)
+	original := ec.Config()
+
+	testCases := []struct {
+		name   string
+		option Option
+		err    error
+	}{
+		{"key attribute", WithEncryption("ID", EncryptStandard), ErrKeyAttributeEncrypted},
+		{"unknown action", WithEncryption("email", EncryptionAction(7)), ErrInvalidConfig},
+		{"malformed pattern", WithEncryptionPattern("[", EncryptNone), ErrInvalidConfig},
+		{"unregistered compression", WithCompression(200), ErrInvalidConfig},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := ec.UpdateConfig(context.Background(), ec.Config().With(tc.option)); !errors.Is(err, tc.err) {
+				t.Errorf("Expected error '%v', got '%v'", tc.err, err)
+			}
+			if ec.Config() != original {
+				t.Errorf("Expected a rejected config to leave the client unchanged")
+			}
+		})
+	}
+	if len(events) != 0 {
+		t.Fatalf("Expected no change events for rejected configs, got %d", len(events))
+	}
+
+	updated := ec.Config().With(WithEncryption("email", EncryptDeterministic), WithEncryption("phone", EncryptStandard))
+	if err := ec.UpdateConfig(context.Background(), updated); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if action := ec.Config().Encryption.ActionFor("phone"); action != EncryptStandard {
+		t.Errorf("Expected %v, got %v", EncryptStandard, action)
+	}
+	if action := original.Encryption.ActionFor("email"); action != EncryptStandard {
+		t.Errorf("Expected the previous config to be unchanged, got %v", action)
+	}
+
+	// Applying the same configuration again is not a change
+	if err := ec.UpdateConfig(context.Background(), updated); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 change event, got %d", len(events))
+	}
+	if diff := cmp.Diff([]string{"email", "phone"}, events[0].ChangedAttributes); diff != "" {
+		t.Errorf("unexpected changed attributes (-want +got):\n%s", diff)
+	}
+	if events[0].Previous != original {
+		t.Errorf("Expected the event to carry the previous config")
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	testCases := []struct {
+		name     string
+		client   DynamoDBClientInterface
+		provider provider.CryptographicMaterialsProvider
+		option   Option
+		fails    bool
+		err      error
+	}{
+		{name: "Valid", client: deniedClient{}, provider: &staticProvider{}, option: WithEncryption("Secret", EncryptStandard)},
+		{name: "No client", provider: &staticProvider{}, option: WithEncryption("Secret", EncryptStandard), fails: true},
+		{name: "No provider", client: deniedClient{}, option: WithEncryption("Secret", EncryptStandard), fails: true},
+		{name: "Key attribute", client: deniedClient{}, provider: &staticProvider{}, option: WithEncryption("ID", EncryptStandard), fails: true, err: ErrKeyAttributeEncrypted},
+		{name: "Unregistered compression", client: deniedClient{}, provider: &staticProvider{}, option: WithCompression(200), fails: true, err: ErrInvalidConfig},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec, err := NewClient(context.Background(), tc.client, tc.provider,
+				WithPrimaryKeyInfo("orders", PrimaryKeyInfo{PartitionKey: "ID"}), WithOptions(tc.option))
+			if (err != nil) != tc.fails || (!tc.fails && ec == nil) {
+				t.Fatalf("Expected failure %v, got error '%v'", tc.fails, err)
+			}
+			if tc.err != nil && !errors.Is(err, tc.err) {
+				t.Errorf("Expected error '%v', got '%v'", tc.err, err)
+			}
+		})
+	}
+
+	// Tables without a configured key schema are described, which fails with deniedClient
+	ec := NewEncryptedClient(deniedClient{}, &staticProvider{})
+	if _, err := NewTable(context.Background(), ec, "orders"); err == nil {
+		t.Errorf("Expected NewTable to fail when the table can not be described")
+	}
+}
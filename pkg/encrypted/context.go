@@ -0,0 +1,53 @@
+package encrypted
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+)
+
+type actionOverridesKey struct{}
+
+// ContextWithActionOverrides returns a context whose operations use the given encryption actions in place of the
+// client's for the named attributes. Overrides apply to that call only and take precedence over the client's
+// specific actions and pattern rules. Items written with overrides must be read with the same overrides.
+func ContextWithActionOverrides(ctx context.Context, overrides map[string]EncryptionAction) context.Context {
+	merged := make(map[string]EncryptionAction)
+	if existing, ok := ctx.Value(actionOverridesKey{}).(map[string]EncryptionAction); ok {
+		for name, action := range existing {
+			merged[name] = action
+		}
+	}
+	for name, action := range overrides {
+		merged[name] = action
+	}
+	return context.WithValue(ctx, actionOverridesKey{}, merged)
+}
+
+// applyActionOverrides returns the encryption settings with any overrides carried by ctx merged over them.
+func applyActionOverrides(ctx context.Context, encryption EncryptionConfig) EncryptionConfig {
+	overrides, ok := ctx.Value(actionOverridesKey{}).(map[string]EncryptionAction)
+	if !ok || len(overrides) == 0 {
+		return encryption
+	}
+
+	encryption = encryption.Clone()
+	if encryption.SpecificActions == nil {
+		encryption.SpecificActions = make(map[string]EncryptionAction, len(overrides))
+	}
+	for name, action := range overrides {
+		encryption.SpecificActions[name] = action
+	}
+	return encryption
+}
+
+// operationContext returns a context naming the client operation to the materials provider.
+func operationContext(ctx context.Context, operation string) context.Context {
+	return provider.ContextWithMaterialsRequest(ctx, provider.MaterialsRequest{Operation: operation})
+}
+
+// itemContext returns a context describing the item whose materials are fetched to the materials provider.
+func itemContext(ctx context.Context, pkInfo *PrimaryKeyInfo, item map[string]types.AttributeValue) context.Context {
+	return provider.ContextWithMaterialsRequest(ctx, provider.MaterialsRequest{TableName: pkInfo.Table, Key: primaryKey(item, pkInfo)})
+}
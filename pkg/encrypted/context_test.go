@@ -0,0 +1,79 @@
+package encrypted
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestContextWithActionOverrides(t *testing.T) {
+	ec := NewEncryptedClient(nil, nil, WithOptions(
+		WithEncryption("email", EncryptStandard),
+		WithEncryptionPattern("audit_*", EncryptNone),
+	))
+
+	ctx := ContextWithActionOverrides(context.Background(), map[string]EncryptionAction{"audit_user": EncryptStandard})
+	ctx = ContextWithActionOverrides(ctx, map[string]EncryptionAction{"email": EncryptDeterministic})
+
+	encryption := applyActionOverrides(ctx, ec.Config().Encryption)
+	if action := encryption.ActionFor("audit_user"); action != EncryptStandard {
+		t.Errorf("Expected override %v, got %v", EncryptStandard, action)
+	}
+	if action := encryption.ActionFor("email"); action != EncryptDeterministic {
+		t.Errorf("Expected override %v, got %v", EncryptDeterministic, action)
+	}
+
+	// The client's own configuration is unchanged
+	if action := applyActionOverrides(context.Background(), ec.Config().Encryption).ActionFor("email"); action != EncryptStandard {
+		t.Errorf("Expected %v without overrides, got %v", EncryptStandard, action)
+	}
+}
+
+// requestProvider records the materials request of every EncryptionMaterials call.
+type requestProvider struct {
+	staticProvider
+	requests []provider.MaterialsRequest
+}
+
+func (p *requestProvider) EncryptionMaterials(ctx context.Context, materialName string) (materials.CryptographicMaterials, error) {
+	request, _ := provider.MaterialsRequestFromContext(ctx)
+	p.requests = append(p.requests, request)
+	return p.staticProvider.EncryptionMaterials(ctx, materialName)
+}
+
+func TestMaterialsRequest(t *testing.T) {
+	materialsProvider := &requestProvider{staticProvider: staticProvider{dataKey: reversingKey{}}}
+	ec := NewEncryptedClient(&putClient{}, materialsProvider,
+		WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID", SortKey: "Created"}),
+	)
+	item := map[string]types.AttributeValue{
+		"ID":      &types.AttributeValueMemberS{Value: "1"},
+		"Created": &types.AttributeValueMemberN{Value: "1700000000"},
+		"Email":   &types.AttributeValueMemberS{Value: "a@example.com"},
+	}
+
+	ctx := provider.ContextWithMaterialsRequest(context.Background(), provider.MaterialsRequest{Metadata: map[string]string{"tenant": "acme"}})
+	if _, err := ec.PutItem(ctx, &dynamodb.PutItemInput{TableName: aws.String("users"), Item: item}); err != nil {
+		t.Fatalf("failed to put item: %v", err)
+	}
+	if _, err := ec.EncryptItem(context.Background(), "users", item); err != nil {
+		t.Fatalf("failed to encrypt item: %v", err)
+	}
+
+	key := map[string]types.AttributeValue{"ID": item["ID"], "Created": item["Created"]}
+	expected := []provider.MaterialsRequest{
+		{TableName: "users", Operation: "PutItem", Key: key, Metadata: map[string]string{"tenant": "acme"}},
+		{TableName: "users", Operation: "EncryptItem", Key: key},
+	}
+	ignore := cmpopts.IgnoreUnexported(types.AttributeValueMemberS{}, types.AttributeValueMemberN{})
+	if diff := cmp.Diff(expected, materialsProvider.requests, ignore); diff != "" {
+		t.Errorf("unexpected requests (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,66 @@
+package encrypted
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ErrUnclassifiedAttribute is returned when an item written to a default-deny table holds an attribute without an
+// explicitly configured action.
+var ErrUnclassifiedAttribute = errors.New("attribute has no configured encryption action")
+
+// WithDefaultDeny makes writes to the named tables fail with ErrUnclassifiedAttribute when an item holds a non-key
+// attribute that neither has a specific action nor matches a pattern rule, instead of applying the default action
+// to it. New fields then have to be classified before they can be stored, rather than silently landing in
+// plaintext as schemas evolve.
+func WithDefaultDeny(tableNames ...string) Option {
+	return func(c *ClientConfig) {
+		c.mustBeMutable()
+		deny := make(map[string]bool, len(c.DefaultDenyTables)+len(tableNames))
+		for tableName := range c.DefaultDenyTables {
+			deny[tableName] = true
+		}
+		for _, tableName := range tableNames {
+			deny[tableName] = true
+		}
+		c.DefaultDenyTables = deny
+	}
+}
+
+// Classifies reports whether an attribute has an explicitly configured action, from SpecificActions or a pattern
+// rule, rather than falling back to the default action.
+func (c EncryptionConfig) Classifies(attributeName string) bool {
+	if _, ok := c.SpecificActions[attributeName]; ok {
+		return true
+	}
+	for _, rule := range c.PatternRules {
+		if matched, err := path.Match(rule.Pattern, attributeName); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// checkClassified rejects an item for a default-deny table that holds unclassified attributes.
+func (c *ClientConfig) checkClassified(pkInfo *PrimaryKeyInfo, encryption EncryptionConfig, item map[string]types.AttributeValue) error {
+	if !c.DefaultDenyTables[pkInfo.Table] {
+		return nil
+	}
+
+	var unclassified []string
+	for name := range item {
+		if isReservedAttribute(name) || pkInfo.IsKeyAttribute(name) || isSystemAttribute(c.systemAttributes(), name) || encryption.Classifies(name) {
+			continue
+		}
+		unclassified = append(unclassified, name)
+	}
+	if len(unclassified) == 0 {
+		return nil
+	}
+	sort.Strings(unclassified)
+	return fmt.Errorf("%w: %q in table %q", ErrUnclassifiedAttribute, unclassified, pkInfo.Table)
+}
@@ -0,0 +1,66 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestDefaultDeny(t *testing.T) {
+	ec := NewEncryptedClient(nil, nil,
+		WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}),
+		WithPrimaryKeyInfo("events", PrimaryKeyInfo{PartitionKey: "ID"}),
+		WithOptions(
+			WithEncryption("Email", EncryptDeterministic),
+			WithEncryption("Status", EncryptNone),
+			WithEncryptionPattern("pii_*", EncryptStandard),
+			WithDefaultDeny("users"),
+		),
+	)
+
+	testCases := []struct {
+		name  string
+		table string
+		item  map[string]types.AttributeValue
+		err   error
+	}{
+		{
+			name:  "Classified",
+			table: "users",
+			item: map[string]types.AttributeValue{
+				"ID":          &types.AttributeValueMemberS{Value: "1"},
+				"Email":       &types.AttributeValueMemberS{Value: "a@example.com"},
+				"Status":      &types.AttributeValueMemberS{Value: "active"},
+				"pii_address": &types.AttributeValueMemberS{Value: "1 Main Street"},
+			},
+		},
+		{
+			name:  "Unclassified",
+			table: "users",
+			item: map[string]types.AttributeValue{
+				"ID":    &types.AttributeValueMemberS{Value: "1"},
+				"Phone": &types.AttributeValueMemberS{Value: "+447700900000"},
+			},
+			err: ErrUnclassifiedAttribute,
+		},
+		{
+			name:  "OtherTable",
+			table: "events",
+			item: map[string]types.AttributeValue{
+				"ID":    &types.AttributeValueMemberS{Value: "1"},
+				"Phone": &types.AttributeValueMemberS{Value: "+447700900000"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ec.prepareEncryption(context.Background(), tc.table, tc.item)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected error %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package encrypted
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+)
+
+// KeyDerivationAttribute is the item attribute recording that the item's attributes are encrypted under per-attribute
+// subkeys, enabled with WithAttributeKeys. Items without it are encrypted directly under their data key.
+const KeyDerivationAttribute = "__KeyDerivation"
+
+// KeyDerivationHKDF is the value of KeyDerivationAttribute for subkeys derived with HKDF-SHA256.
+const KeyDerivationHKDF = "HKDF-SHA256"
+
+// attributeKeyInfo is the HKDF info separating the subkey of an attribute from those of other attributes.
+func attributeKeyInfo(attributeName string) []byte {
+	return []byte("attribute:" + attributeName)
+}
+
+// attributeKey returns the key to encrypt or decrypt an attribute with: the data key itself, or, when derive is
+// set, the attribute's subkey.
+func attributeKey(dataKey delegatedkeys.DelegatedKey, attributeName string, derive bool) (delegatedkeys.DelegatedKey, error) {
+	if !derive {
+		return dataKey, nil
+	}
+	deriver, ok := dataKey.(delegatedkeys.KeyDeriver)
+	if !ok {
+		return nil, fmt.Errorf("data key of algorithm %s does not support attribute key derivation", dataKey.Algorithm())
+	}
+	subkey, err := deriver.DeriveKey(attributeKeyInfo(attributeName))
+	if err != nil {
+		return nil, fmt.Errorf("error deriving key for attribute %s: %v", attributeName, err)
+	}
+	return subkey, nil
+}
+
+// releaseAttributeKey zeroes an attribute's subkey once it is no longer needed. Data keys used directly, without
+// derivation, are shared by the item's attributes and left alone.
+func releaseAttributeKey(key delegatedkeys.DelegatedKey, derive bool) {
+	if destroyer, ok := key.(interface{ Destroy() }); ok && derive {
+		destroyer.Destroy()
+	}
+}
+
+// usesAttributeKeys reports whether a stored item was encrypted under per-attribute subkeys.
+func usesAttributeKeys(item map[string]types.AttributeValue) (bool, error) {
+	value, ok := item[KeyDerivationAttribute]
+	if !ok {
+		return false, nil
+	}
+	derivation, ok := value.(*types.AttributeValueMemberS)
+	if !ok || derivation.Value != KeyDerivationHKDF {
+		return false, fmt.Errorf("unsupported key derivation in %s attribute", KeyDerivationAttribute)
+	}
+	return true, nil
+}
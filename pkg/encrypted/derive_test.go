@@ -0,0 +1,73 @@
+package encrypted
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestAttributeKeys(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	materialsProvider := &staticProvider{dataKey: dataKey}
+
+	item := map[string]types.AttributeValue{
+		"ID":    &types.AttributeValueMemberS{Value: "1"},
+		"Email": &types.AttributeValueMemberS{Value: "a@example.com"},
+	}
+	ignore := cmpopts.IgnoreUnexported(types.AttributeValueMemberS{})
+
+	testCases := []struct {
+		name          string
+		writerDerives bool
+		readerDerives bool
+	}{
+		{name: "Derived", writerDerives: true, readerDerives: true},
+		{name: "DerivedReadWithoutOption", writerDerives: true},
+		{name: "DataKeyReadWithOption", readerDerives: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			writer := NewEncryptedClient(nil, materialsProvider,
+				WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}),
+				WithOptions(WithAttributeKeys(tc.writerDerives)),
+			)
+			reader := NewEncryptedClient(nil, materialsProvider,
+				WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}),
+				WithOptions(WithAttributeKeys(tc.readerDerives)),
+			)
+
+			encryptedItem, err := writer.encryptItem(context.Background(), "users", item)
+			if err != nil {
+				t.Fatalf("failed to encrypt item: %v", err)
+			}
+			if _, ok := encryptedItem[KeyDerivationAttribute]; ok != tc.writerDerives {
+				t.Errorf("expected %s to be recorded: %v", KeyDerivationAttribute, tc.writerDerives)
+			}
+
+			ciphertext := encryptedItem["Email"].(*types.AttributeValueMemberB).Value
+			if _, err := dataKey.Decrypt(ciphertext, []byte("Email")); (err == nil) == tc.writerDerives {
+				t.Errorf("expected the data key to decrypt the attribute: %v, got error %v", !tc.writerDerives, err)
+			}
+
+			decrypted, err := reader.decryptItem(context.Background(), "users", encryptedItem)
+			if err != nil {
+				t.Fatalf("failed to decrypt item: %v", err)
+			}
+			if diff := cmp.Diff(item, decrypted, ignore); diff != "" {
+				t.Errorf("unexpected item (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/serde"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/utils"
+	"github.com/tink-crypto/tink-go/v2/tink"
+)
+
+// ErrNotDisclosable is returned when a disclosure token is requested for an attribute that is not encrypted under
+// its own subkey. Only items written with WithAttributeKeys can disclose single attributes.
+var ErrNotDisclosable = errors.New("attribute can not be disclosed on its own")
+
+// ErrTokenExpired is returned when a disclosure token is redeemed after it expired.
+var ErrTokenExpired = errors.New("disclosure token has expired")
+
+// DisclosureToken grants the decryption of one attribute of one item. It holds the attribute's subkey, wrapped with
+// a key of the token's recipient, so a support tool that can read the item and unwrap the token can reveal that
+// attribute without decrypt permissions on the item's materials. Tokens marshal to JSON for transport.
+type DisclosureToken struct {
+	Table           string    `json:"table"`
+	PartitionKey    string    `json:"partitionKey"`
+	MaterialName    string    `json:"materialName"`
+	MaterialVersion int64     `json:"materialVersion"`
+	Attribute       string    `json:"attribute"`
+	Expires         time.Time `json:"expires"`
+	WrappedKey      []byte    `json:"wrappedKey"`
+}
+
+// DiscloseAttribute creates a disclosure token for an attribute of the item with the given key, wrapping the
+// attribute's subkey with recipient, for example the KMS key of a support tool. A positive ttl makes the token
+// expire. The item is read from the table to find the materials it was encrypted with.
+func (ec *EncryptedClient) DiscloseAttribute(ctx context.Context, tableName string, key map[string]types.AttributeValue, attribute string, recipient tink.AEAD, ttl time.Duration) (*DisclosureToken, error) {
+	ctx = operationContext(ctx, "DiscloseAttribute")
+	pkInfo, err := ec.getPrimaryKeyInfo(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if err := ec.scope.checkDecrypt(ec.Config().canonicalName(attribute)); err != nil {
+		return nil, err
+	}
+	output, err := ec.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(tableName),
+		Key:            key,
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving encrypted item: %v", err)
+	}
+	if output.Item == nil {
+		return nil, fmt.Errorf("item not found")
+	}
+
+	derived, err := usesAttributeKeys(output.Item)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := output.Item[attribute].(*types.AttributeValueMemberB); !ok || !derived || pkInfo.IsKeyAttribute(attribute) {
+		return nil, fmt.Errorf("%w: %s", ErrNotDisclosable, attribute)
+	}
+
+	materialName, err := itemMaterialName(output.Item, pkInfo)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing material name: %w", err)
+	}
+	version, err := materialVersion(output.Item)
+	if err != nil {
+		return nil, err
+	}
+	decryptionMaterials, err := ec.MaterialsProvider.DecryptionMaterials(itemContext(ctx, pkInfo, output.Item), materialName, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch decryption materials: %v", err)
+	}
+	subkey, err := attributeKey(decryptionMaterials.DecryptionKey(), attribute, true)
+	if err != nil {
+		return nil, err
+	}
+	exportable, ok := subkey.(*delegatedkeys.DerivedKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: subkeys of algorithm %s can not be exported", ErrNotDisclosable, subkey.Algorithm())
+	}
+
+	token := &DisclosureToken{
+		Table:           tableName,
+		PartitionKey:    pkInfo.PartitionKey,
+		MaterialName:    materialName,
+		MaterialVersion: version,
+		Attribute:       attribute,
+	}
+	if ttl > 0 {
+		token.Expires = time.Now().Add(ttl).UTC().Truncate(time.Second)
+	}
+	if token.WrappedKey, err = exportable.Wrap(recipient, token.associatedData()); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// Reveal decrypts the token's attribute of an item read from the token's table, unwrapping the subkey with
+// recipient. The item must be the one the token was issued for; the attribute of any other item fails to decrypt.
+func (t *DisclosureToken) Reveal(recipient tink.AEAD, item map[string]types.AttributeValue) (types.AttributeValue, error) {
+	if !t.Expires.IsZero() && time.Now().After(t.Expires) {
+		return nil, ErrTokenExpired
+	}
+	if derived, err := usesAttributeKeys(item); err != nil || !derived {
+		return nil, fmt.Errorf("%w: item is not encrypted under attribute keys", ErrNotDisclosable)
+	}
+	encryptedData, ok := item[t.Attribute].(*types.AttributeValueMemberB)
+	if !ok {
+		return nil, fmt.Errorf("item has no encrypted attribute %s", t.Attribute)
+	}
+
+	subkey, err := delegatedkeys.UnwrapDerivedKey(t.WrappedKey, recipient, t.associatedData())
+	if err != nil {
+		return nil, err
+	}
+	scope, err := itemScope(item)
+	if err != nil {
+		return nil, err
+	}
+	associatedData, err := scope.associatedData(t.Table, t.PartitionKey, item, t.Attribute)
+	if err != nil {
+		return nil, err
+	}
+	decryptedData, err := subkey.Decrypt(encryptedData.Value, associatedData)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting attribute value: %v", err)
+	}
+	defer utils.Zero(decryptedData)
+	return serde.NewDeserializer().DeserializeAttribute(decryptedData)
+}
+
+// associatedData binds the wrapped subkey to the token's metadata, so none of it can be changed.
+func (t *DisclosureToken) associatedData() []byte {
+	var expires int64
+	if !t.Expires.IsZero() {
+		expires = t.Expires.Unix()
+	}
+	return []byte(strings.Join([]string{
+		t.Table, t.PartitionKey, t.MaterialName, strconv.FormatInt(t.MaterialVersion, 10), t.Attribute, strconv.FormatInt(expires, 10),
+	}, "\x00"))
+}
@@ -0,0 +1,111 @@
+package encrypted
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// itemClient returns a fixed item from GetItem.
+type itemClient struct {
+	DynamoDBClientInterface
+	item map[string]types.AttributeValue
+}
+
+func (c *itemClient) GetItem(ctx context.Context, input *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: c.item}, nil
+}
+
+func TestDiscloseAttribute(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	recipient, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/8c6a5d1e-2f5a-4b8e-9d1a-5b8f4e2c7a90", true)
+	if err != nil {
+		t.Fatalf("failed to get recipient KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+
+	client := &itemClient{}
+	ec := NewEncryptedClient(client, &staticProvider{dataKey: dataKey},
+		WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}),
+		WithOptions(WithAttributeKeys(true)),
+	)
+	key := map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: "1"}}
+	email := &types.AttributeValueMemberS{Value: "a@example.com"}
+	client.item, err = ec.encryptItem(context.Background(), "users", map[string]types.AttributeValue{
+		"ID":    key["ID"],
+		"Email": email,
+		"Phone": &types.AttributeValueMemberS{Value: "+447700900000"},
+	})
+	if err != nil {
+		t.Fatalf("failed to encrypt item: %v", err)
+	}
+
+	token, err := ec.DiscloseAttribute(context.Background(), "users", key, "Email", recipient, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	// The token travels as JSON to the support tool, which reads the item itself
+	data, err := json.Marshal(token)
+	if err != nil {
+		t.Fatalf("failed to marshal token: %v", err)
+	}
+	var redeemed DisclosureToken
+	if err := json.Unmarshal(data, &redeemed); err != nil {
+		t.Fatalf("failed to unmarshal token: %v", err)
+	}
+	revealed, err := redeemed.Reveal(recipient, client.item)
+	if err != nil {
+		t.Fatalf("failed to reveal attribute: %v", err)
+	}
+	if diff := cmp.Diff(types.AttributeValue(email), revealed, cmpopts.IgnoreUnexported(types.AttributeValueMemberS{})); diff != "" {
+		t.Errorf("unexpected value (-want +got):\n%s", diff)
+	}
+
+	testCases := []struct {
+		name   string
+		modify func(token *DisclosureToken)
+		err    error
+	}{
+		{name: "OtherAttribute", modify: func(token *DisclosureToken) { token.Attribute = "Phone" }},
+		{name: "ExtendedExpiry", modify: func(token *DisclosureToken) { token.Expires = token.Expires.Add(time.Hour) }},
+		{name: "Expired", modify: func(token *DisclosureToken) { token.Expires = time.Now().Add(-time.Minute) }, err: ErrTokenExpired},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tampered := redeemed
+			tc.modify(&tampered)
+			_, err := tampered.Reveal(recipient, client.item)
+			if err == nil || (tc.err != nil && !errors.Is(err, tc.err)) {
+				t.Errorf("expected error %v, got %v", tc.err, err)
+			}
+		})
+	}
+
+	if _, err := redeemed.Reveal(kek, client.item); err == nil {
+		t.Errorf("expected a key other than the recipient's to fail unwrapping")
+	}
+
+	plain := NewEncryptedClient(client, &staticProvider{dataKey: dataKey}, WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}))
+	client.item, err = plain.encryptItem(context.Background(), "users", map[string]types.AttributeValue{"ID": key["ID"], "Email": email})
+	if err != nil {
+		t.Fatalf("failed to encrypt item: %v", err)
+	}
+	if _, err := plain.DiscloseAttribute(context.Background(), "users", key, "Email", recipient, 0); !errors.Is(err, ErrNotDisclosable) {
+		t.Errorf("expected ErrNotDisclosable for an item without attribute keys, got %v", err)
+	}
+}
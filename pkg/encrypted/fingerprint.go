@@ -0,0 +1,179 @@
+package encrypted
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/utils"
+)
+
+// PolicyFingerprintAttribute is the item attribute recording the fingerprint of the attribute actions an item was
+// encrypted with, enabled with WithPolicyFingerprints.
+const PolicyFingerprintAttribute = "__PolicyFingerprint"
+
+// Expression placeholders of the outdated item filter, chosen not to collide with those of callers.
+const (
+	fingerprintName  = "#__fingerprint"
+	fingerprintValue = ":__fingerprint"
+)
+
+// WithPolicyFingerprints enables recording the fingerprint of the attribute actions every item is encrypted with,
+// so items written before a policy change can be found with FindOutdatedItems and re-encrypted one by one.
+func WithPolicyFingerprints(enabled bool) Option {
+	return func(c *ClientConfig) {
+		c.mustBeMutable()
+		c.PolicyFingerprints = enabled
+	}
+}
+
+// Fingerprint returns a short hash identifying the attribute actions: the default action, the action of every named
+// attribute and the pattern rules in order. Configurations with equal actions have equal fingerprints, whether they
+// were built with options or loaded from a policy document, so services sharing a policy agree on it.
+func (c EncryptionConfig) Fingerprint() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "default %d\n", c.DefaultAction)
+
+	names := make([]string, 0, len(c.SpecificActions))
+	for name := range c.SpecificActions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "attribute %s %d\n", strconv.Quote(name), c.SpecificActions[name])
+	}
+	for _, rule := range c.PatternRules {
+		fmt.Fprintf(&b, "pattern %s %d\n", strconv.Quote(rule.Pattern), rule.Action)
+	}
+	return utils.HashString(b.String())[:16]
+}
+
+// OutdatedFilter returns a filter expression matching the items not encrypted under the attribute actions with the
+// given fingerprint, including items written without a fingerprint, with its attribute names and values. Callers
+// can combine it with their own filters to look for outdated items with Query instead of FindOutdatedItems.
+func OutdatedFilter(fingerprint string) (string, map[string]string, map[string]types.AttributeValue) {
+	return fmt.Sprintf("attribute_not_exists(%s) OR %s <> %s", fingerprintName, fingerprintName, fingerprintValue),
+		map[string]string{fingerprintName: PolicyFingerprintAttribute},
+		map[string]types.AttributeValue{fingerprintValue: &types.AttributeValueMemberS{Value: fingerprint}}
+}
+
+// OutdatedItem identifies an item encrypted under other attribute actions than the client's, by its primary key.
+// Fingerprint is empty for items written without one.
+type OutdatedItem struct {
+	Key         map[string]types.AttributeValue
+	Fingerprint string
+}
+
+// OutdatedReport lists the items of a table encrypted under other attribute actions than the client's.
+type OutdatedReport struct {
+	Table       string
+	Fingerprint string
+	Items       int64
+	Outdated    []OutdatedItem
+}
+
+// FindOutdatedItems scans a table for the items whose recorded fingerprint differs from that of the client's
+// attribute actions, for example after an attribute was newly marked for encryption. Items are filtered by DynamoDB
+// and only their keys and fingerprints are read, so nothing is decrypted. The outdated items can then be passed to
+// ReencryptItem instead of rewriting the whole table.
+func (ec *EncryptedClient) FindOutdatedItems(ctx context.Context, tableName string) (*OutdatedReport, error) {
+	ctx = operationContext(ctx, "FindOutdatedItems")
+	pkInfo, err := ec.getPrimaryKeyInfo(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprint := ec.Config().Encryption.Fingerprint()
+	filter, names, values := OutdatedFilter(fingerprint)
+	names["#pk"] = pkInfo.PartitionKey
+	projection := []string{"#pk", fingerprintName}
+	if pkInfo.SortKey != "" {
+		names["#sk"] = pkInfo.SortKey
+		projection = append(projection, "#sk")
+	}
+
+	report := &OutdatedReport{Table: tableName, Fingerprint: fingerprint}
+	paginator := dynamodb.NewScanPaginator(ec.Client, &dynamodb.ScanInput{
+		TableName:                 aws.String(tableName),
+		FilterExpression:          aws.String(filter),
+		ProjectionExpression:      aws.String(strings.Join(projection, ", ")),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning items: %v", err)
+		}
+
+		report.Items += int64(output.ScannedCount)
+		for _, item := range output.Items {
+			outdated := OutdatedItem{Key: itemKey(item, pkInfo)}
+			if value, ok := item[PolicyFingerprintAttribute].(*types.AttributeValueMemberS); ok {
+				outdated.Fingerprint = value.Value
+			}
+			report.Outdated = append(report.Outdated, outdated)
+		}
+	}
+	return report, nil
+}
+
+// ReencryptItem reads an item, decrypts it and writes it back encrypted under the client's current attribute
+// actions. The write is conditioned on the item still existing with the fingerprint it was read with, so items
+// deleted or re-encrypted in the meantime are left alone and the SDK's ConditionalCheckFailedException is returned.
+// Items already encrypted under the current attribute actions are not rewritten.
+func (ec *EncryptedClient) ReencryptItem(ctx context.Context, tableName string, key map[string]types.AttributeValue) error {
+	ctx = operationContext(ctx, "ReencryptItem")
+	pkInfo, err := ec.getPrimaryKeyInfo(ctx, tableName)
+	if err != nil {
+		return err
+	}
+
+	output, err := ec.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(tableName),
+		Key:            key,
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return fmt.Errorf("error getting item: %v", err)
+	}
+	if output.Item == nil {
+		return fmt.Errorf("item not found in table %s", tableName)
+	}
+
+	names := map[string]string{"#pk": pkInfo.PartitionKey, fingerprintName: PolicyFingerprintAttribute}
+	condition := "attribute_exists(#pk) AND attribute_not_exists(" + fingerprintName + ")"
+	var values map[string]types.AttributeValue
+	if stored, ok := output.Item[PolicyFingerprintAttribute].(*types.AttributeValueMemberS); ok {
+		if ec.Config().PolicyFingerprints && stored.Value == ec.Config().Encryption.Fingerprint() {
+			return nil
+		}
+		condition = "attribute_exists(#pk) AND " + fingerprintName + " = " + fingerprintValue
+		values = map[string]types.AttributeValue{fingerprintValue: stored}
+	}
+
+	item, err := ec.decryptItem(ctx, tableName, output.Item)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt item: %w", err)
+	}
+	encryptedItem, err := ec.encryptItem(ctx, tableName, item)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt item: %w", err)
+	}
+	_, err = ec.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                 aws.String(tableName),
+		Item:                      encryptedItem,
+		ConditionExpression:       aws.String(condition),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put re-encrypted item: %w", err)
+	}
+	return nil
+}
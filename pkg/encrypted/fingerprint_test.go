@@ -0,0 +1,155 @@
+package encrypted
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+)
+
+// fingerprintClient stores items keyed by their "ID" attribute and applies the outdated item filter to scans.
+type fingerprintClient struct {
+	DynamoDBClientInterface
+	items     map[string]map[string]types.AttributeValue
+	condition string
+}
+
+func (c *fingerprintClient) Scan(ctx context.Context, input *dynamodb.ScanInput, opts ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	current := input.ExpressionAttributeValues[fingerprintValue].(*types.AttributeValueMemberS).Value
+	output := &dynamodb.ScanOutput{ScannedCount: int32(len(c.items))}
+	for _, id := range []string{"1", "2", "3"} {
+		item, ok := c.items[id]
+		if !ok {
+			continue
+		}
+		if stored, ok := item[PolicyFingerprintAttribute].(*types.AttributeValueMemberS); ok && stored.Value == current {
+			continue
+		}
+		output.Items = append(output.Items, item)
+	}
+	return output, nil
+}
+
+func (c *fingerprintClient) GetItem(ctx context.Context, input *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: c.items[input.Key["ID"].(*types.AttributeValueMemberS).Value]}, nil
+}
+
+func (c *fingerprintClient) PutItem(ctx context.Context, input *dynamodb.PutItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	c.condition = aws.StringValue(input.ConditionExpression)
+	c.items[input.Item["ID"].(*types.AttributeValueMemberS).Value] = input.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func TestEncryptionConfig_Fingerprint(t *testing.T) {
+	base := NewClientConfig(WithDefaultEncryption(EncryptNone), WithEncryption("Secret", EncryptStandard), WithEncryption("Email", EncryptDeterministic))
+
+	policy := &Policy{Version: PolicyVersion, DefaultAction: "none", Attributes: map[string]string{"Email": "deterministic", "Secret": "standard"}}
+	loaded := NewClientConfig()
+	if err := policy.Apply(loaded); err != nil {
+		t.Fatalf("failed to apply policy: %v", err)
+	}
+
+	testCases := []struct {
+		name   string
+		config *ClientConfig
+		equal  bool
+	}{
+		{name: "Options in other order", config: NewClientConfig(WithEncryption("Email", EncryptDeterministic), WithEncryption("Secret", EncryptStandard)), equal: true},
+		{name: "Loaded from policy", config: loaded, equal: true},
+		{name: "Other settings", config: base.With(WithItemSignatures(true), WithPolicyFingerprints(true)), equal: true},
+		{name: "Newly encrypted attribute", config: base.With(WithEncryption("Phone", EncryptStandard))},
+		{name: "Changed action", config: base.With(WithEncryption("Email", EncryptStandard))},
+		{name: "Changed default", config: base.With(WithDefaultEncryption(EncryptStandard))},
+		{name: "Pattern rule", config: base.With(WithEncryptionPattern("pii_*", EncryptStandard))},
+	}
+
+	want := base.Encryption.Fingerprint()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.config.Encryption.Fingerprint()
+			if (got == want) != tc.equal {
+				t.Errorf("expected equal fingerprints to be %v, got %s and %s", tc.equal, want, got)
+			}
+		})
+	}
+}
+
+func TestEncryptedClient_ReencryptOutdated(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	cmp := &staticProvider{description: map[string]string{}, dataKey: dataKey}
+	client := &fingerprintClient{items: make(map[string]map[string]types.AttributeValue)}
+	keyInfo := WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"})
+
+	newClient := func(options ...Option) *EncryptedClient {
+		options = append([]Option{WithDefaultEncryption(EncryptNone), WithEncryption("Secret", EncryptStandard)}, options...)
+		return NewEncryptedClient(client, cmp, keyInfo, WithOptions(options...))
+	}
+	legacy := newClient()
+	old := newClient(WithPolicyFingerprints(true))
+	tightened := newClient(WithPolicyFingerprints(true), WithEncryption("Email", EncryptStandard))
+
+	for id, ec := range map[string]*EncryptedClient{"1": old, "2": old, "3": legacy} {
+		item, err := ec.encryptItem(context.Background(), "users", map[string]types.AttributeValue{
+			"ID":     &types.AttributeValueMemberS{Value: id},
+			"Email":  &types.AttributeValueMemberS{Value: id + "@example.com"},
+			"Secret": &types.AttributeValueMemberS{Value: "value " + id},
+		})
+		if err != nil {
+			t.Fatalf("failed to encrypt item: %v", err)
+		}
+		client.items[id] = item
+	}
+
+	report, err := old.FindOutdatedItems(context.Background(), "users")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Items != 3 || len(report.Outdated) != 1 || report.Outdated[0].Fingerprint != "" {
+		t.Fatalf("expected only the item without a fingerprint to be outdated, got %+v", report)
+	}
+
+	report, err = tightened.FindOutdatedItems(context.Background(), "users")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Outdated) != 3 {
+		t.Fatalf("expected all items to be outdated, got %+v", report)
+	}
+	for _, outdated := range report.Outdated {
+		if err := tightened.ReencryptItem(context.Background(), "users", outdated.Key); err != nil {
+			t.Fatalf("failed to re-encrypt item: %v", err)
+		}
+	}
+	if !strings.Contains(client.condition, "attribute_not_exists") {
+		t.Errorf("expected the item without a fingerprint to be written if it still has none, got %q", client.condition)
+	}
+
+	report, err = tightened.FindOutdatedItems(context.Background(), "users")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Outdated) != 0 {
+		t.Errorf("expected no outdated items after re-encryption, got %+v", report.Outdated)
+	}
+	if _, ok := client.items["1"]["Email"].(*types.AttributeValueMemberB); !ok {
+		t.Errorf("expected Email to be encrypted, got %T", client.items["1"]["Email"])
+	}
+	item, err := tightened.decryptItem(context.Background(), "users", client.items["1"])
+	if err != nil {
+		t.Fatalf("failed to decrypt item: %v", err)
+	}
+	if got := item["Email"].(*types.AttributeValueMemberS).Value; got != "1@example.com" {
+		t.Errorf("expected Email to decrypt to 1@example.com, got %s", got)
+	}
+}
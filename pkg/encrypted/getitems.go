@@ -0,0 +1,159 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Bounds of the backoff between batch requests for unprocessed keys and items.
+const (
+	batchGetBaseDelay = 50 * time.Millisecond
+	batchGetMaxDelay  = 5 * time.Second
+)
+
+// DefaultBatchGetMaxAttempts is the number of BatchGetItem requests made for a batch of keys, including the first,
+// unless WithBatchGetMaxAttempts is given. With the backoff between requests, keys are given up on after about six
+// seconds.
+const DefaultBatchGetMaxAttempts = 8
+
+// ErrUnprocessedKeys is the error of GetItems results whose keys were still unprocessed when the attempts ran out.
+var ErrUnprocessedKeys = errors.New("keys left unprocessed")
+
+// WithBatchGetMaxAttempts sets the number of BatchGetItem requests BatchGetItem and EncryptedTable.GetItems make for a
+// batch of keys, including the first, before giving up on keys DynamoDB leaves unprocessed. Values below 2 disable
+// follow-up requests.
+func WithBatchGetMaxAttempts(attempts int) EncryptedClientOption {
+	return func(ec *EncryptedClient) {
+		ec.batchGetAttempts = attempts
+	}
+}
+
+// maxBatchGetKeys is the largest number of keys DynamoDB accepts in one BatchGetItem request.
+const maxBatchGetKeys = 100
+
+// GetItemResult is the result of one of the keys read with GetItems. Item is nil when the table holds no item with
+// the key, and Err is set when the item could not be read or decrypted.
+type GetItemResult struct {
+	Key  map[string]types.AttributeValue
+	Item map[string]types.AttributeValue
+	Err  error
+}
+
+// GetItems reads and decrypts the items with the given keys from the DynamoDB table, and returns one result per key,
+// in the order of keys. Keys are read with BatchGetItem in requests of up to 100 keys, each requested once however
+// often it is given, and unprocessed keys are requested again like EncryptedClient.BatchGetItem does; keys still
+// unprocessed when the attempts run out fail with ErrUnprocessedKeys. Failed requests, items that can not be
+// decrypted and the end of ctx only fail the results of the keys concerned, so an error is returned only when the
+// table's key schema can not be loaded.
+func (et *EncryptedTable) GetItems(ctx context.Context, tableName string, keys []map[string]types.AttributeValue) ([]GetItemResult, error) {
+	ctx = operationContext(ctx, "GetItems")
+	pkInfo, err := et.client.getPrimaryKeyInfo(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]GetItemResult, len(keys))
+	positions := make(map[string][]int, len(keys))
+	var distinct []map[string]types.AttributeValue
+	for i, key := range keys {
+		results[i].Key = key
+		fingerprint, err := keyFingerprint(primaryKey(key, pkInfo))
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		if _, ok := positions[fingerprint]; !ok {
+			distinct = append(distinct, key)
+		}
+		positions[fingerprint] = append(positions[fingerprint], i)
+	}
+	setResult := func(key, item map[string]types.AttributeValue, err error) {
+		fingerprint, fingerprintErr := keyFingerprint(primaryKey(key, pkInfo))
+		if fingerprintErr != nil {
+			// The key can not be matched to the results it answers, so none of the pending results is known
+			for i := range results {
+				if results[i].Item == nil && results[i].Err == nil {
+					results[i].Err = fingerprintErr
+				}
+			}
+			return
+		}
+		for _, i := range positions[fingerprint] {
+			results[i].Item, results[i].Err = item, err
+		}
+	}
+
+	for start := 0; start < len(distinct); start += maxBatchGetKeys {
+		chunk := distinct[start:min(start+maxBatchGetKeys, len(distinct))]
+		et.getChunk(ctx, tableName, chunk, setResult)
+	}
+	return results, nil
+}
+
+// getChunk reads one BatchGetItem request worth of keys and passes the result of every key found or failed to
+// setResult.
+func (et *EncryptedTable) getChunk(ctx context.Context, tableName string, keys []map[string]types.AttributeValue, setResult func(key, item map[string]types.AttributeValue, err error)) {
+	output, err := et.client.batchGet(ctx, &dynamodb.BatchGetItemInput{
+		RequestItems: map[string]types.KeysAndAttributes{tableName: {Keys: keys}},
+	})
+	if output == nil {
+		for _, key := range keys {
+			setResult(key, nil, err)
+		}
+		return
+	}
+	for _, item := range output.Responses[tableName] {
+		decryptedItem, err := et.client.decryptItem(ctx, tableName, item)
+		setResult(item, decryptedItem, err)
+	}
+
+	if err == nil {
+		err = fmt.Errorf("%w after %d attempts", ErrUnprocessedKeys, et.client.batchGetAttempts)
+	}
+	for _, key := range output.UnprocessedKeys[tableName].Keys {
+		setResult(key, nil, err)
+	}
+}
+
+// batchGet sends a BatchGetItem request and requests its unprocessed keys again, with a growing delay, for up to the
+// client's max attempts. It returns the responses of all requests merged, with the keys left unprocessed. When a
+// follow-up request fails or ctx ends, the responses received so far are returned with the error.
+func (ec *EncryptedClient) batchGet(ctx context.Context, input *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	output, err := ec.Client.BatchGetItem(ctx, input, optFns...)
+	if err != nil {
+		return nil, fmt.Errorf("error batch getting encrypted items: %w", err)
+	}
+
+	request := *input
+	delay := batchGetBaseDelay
+	for attempt := 1; attempt < ec.batchGetAttempts && len(output.UnprocessedKeys) > 0; attempt++ {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return output, ctx.Err()
+		case <-timer.C:
+		}
+		delay = min(2*delay, batchGetMaxDelay)
+
+		request.RequestItems = output.UnprocessedKeys
+		next, err := ec.Client.BatchGetItem(ctx, &request, optFns...)
+		if err != nil {
+			return output, fmt.Errorf("error batch getting encrypted items: %w", err)
+		}
+		if output.Responses == nil {
+			output.Responses = make(map[string][]map[string]types.AttributeValue)
+		}
+		for tableName, items := range next.Responses {
+			output.Responses[tableName] = append(output.Responses[tableName], items...)
+		}
+		output.ConsumedCapacity = append(output.ConsumedCapacity, next.ConsumedCapacity...)
+		output.UnprocessedKeys = next.UnprocessedKeys
+	}
+	return output, nil
+}
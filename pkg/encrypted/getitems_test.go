@@ -0,0 +1,218 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+)
+
+// batchGetClient serves BatchGetItem from a map of items by ID. It leaves the last key of every request with more
+// than one key unprocessed, and records the number of keys of every request.
+type batchGetClient struct {
+	DynamoDBClientInterface
+	items    map[string]map[string]types.AttributeValue
+	requests []int
+}
+
+func (c *batchGetClient) BatchGetItem(ctx context.Context, input *dynamodb.BatchGetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	output := &dynamodb.BatchGetItemOutput{
+		Responses:       make(map[string][]map[string]types.AttributeValue),
+		UnprocessedKeys: make(map[string]types.KeysAndAttributes),
+	}
+	for tableName, request := range input.RequestItems {
+		c.requests = append(c.requests, len(request.Keys))
+		keys := request.Keys
+		if len(keys) > 1 {
+			output.UnprocessedKeys[tableName] = types.KeysAndAttributes{Keys: keys[len(keys)-1:]}
+			keys = keys[:len(keys)-1]
+		}
+		for _, key := range keys {
+			if item, ok := c.items[key["ID"].(*types.AttributeValueMemberS).Value]; ok {
+				output.Responses[tableName] = append(output.Responses[tableName], item)
+			}
+		}
+	}
+	return output, nil
+}
+
+// newBatchGetClient returns a batchGetClient serving n encrypted items with the IDs 0 to n-1, and the materials
+// provider they were encrypted with.
+func newBatchGetClient(t *testing.T, n int) (*batchGetClient, *staticProvider) {
+	t.Helper()
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	cmProvider := &staticProvider{description: map[string]string{}, dataKey: dataKey}
+	ec := NewEncryptedClient(nil, cmProvider, WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}))
+
+	client := &batchGetClient{items: make(map[string]map[string]types.AttributeValue)}
+	for id := 0; id < n; id++ {
+		item, err := ec.EncryptItem(context.Background(), "users", map[string]types.AttributeValue{
+			"ID":     &types.AttributeValueMemberS{Value: strconv.Itoa(id)},
+			"Secret": &types.AttributeValueMemberS{Value: "secret " + strconv.Itoa(id)},
+		})
+		if err != nil {
+			t.Fatalf("failed to encrypt item: %v", err)
+		}
+		client.items[strconv.Itoa(id)] = item
+	}
+	return client, cmProvider
+}
+
+// batchGetKey returns the key of the item with the given ID.
+func batchGetKey(id int) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: strconv.Itoa(id)}}
+}
+
+func TestEncryptedClient_BatchGetItem(t *testing.T) {
+	client, cmProvider := newBatchGetClient(t, 5)
+	keys := []map[string]types.AttributeValue{batchGetKey(9), batchGetKey(0), batchGetKey(1), batchGetKey(2)}
+
+	testCases := []struct {
+		name        string
+		attempts    int
+		items       int
+		unprocessed int
+	}{
+		{name: "Default attempts", attempts: DefaultBatchGetMaxAttempts, items: 3},
+		{name: "No follow-up requests", attempts: 1, items: 2, unprocessed: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client.requests = nil
+			ec := NewEncryptedClient(client, cmProvider, WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}), WithBatchGetMaxAttempts(tc.attempts))
+
+			output, err := ec.BatchGetItem(context.Background(), &dynamodb.BatchGetItemInput{
+				RequestItems: map[string]types.KeysAndAttributes{"users": {Keys: keys}},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := len(output.Responses["users"]); got != tc.items {
+				t.Errorf("expected %d items, got %d", tc.items, got)
+			}
+			for _, item := range output.Responses["users"] {
+				if _, ok := item["Secret"].(*types.AttributeValueMemberS); !ok {
+					t.Errorf("expected a decrypted item, got %v", item)
+				}
+			}
+			if got := len(output.UnprocessedKeys["users"].Keys); got != tc.unprocessed {
+				t.Errorf("expected %d unprocessed keys, got %d", tc.unprocessed, got)
+			}
+			if len(client.requests) > tc.attempts {
+				t.Errorf("expected at most %d requests, got %d", tc.attempts, len(client.requests))
+			}
+		})
+	}
+}
+
+func TestEncryptedTable_GetItems(t *testing.T) {
+	client, cmProvider := newBatchGetClient(t, 150)
+	ec := NewEncryptedClient(client, cmProvider, WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}))
+	table := NewEncryptedTable(ec)
+	key := batchGetKey
+
+	// Item 7 has a corrupted ciphertext
+	secret := client.items["7"]["Secret"].(*types.AttributeValueMemberB)
+	secret.Value[len(secret.Value)-1] ^= 1
+
+	// Keys in reverse order, a missing key, a key given twice, and a key without the partition key
+	var keys []map[string]types.AttributeValue
+	for id := 149; id >= 0; id-- {
+		keys = append(keys, key(id))
+	}
+	keys = append(keys, key(1000), key(3), map[string]types.AttributeValue{"Name": &types.AttributeValueMemberS{Value: "alice"}})
+
+	results, err := table.GetItems(context.Background(), "users", keys)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != len(keys) {
+		t.Fatalf("expected %d results, got %d", len(keys), len(results))
+	}
+
+	testCases := []struct {
+		name   string
+		result int
+		secret string
+		err    bool
+	}{
+		{name: "First key", result: 0, secret: "secret 149"},
+		{name: "Unprocessed key", result: 99, secret: "secret 50"},
+		{name: "Second request", result: 100, secret: "secret 49"},
+		{name: "Corrupted item", result: 142, err: true},
+		{name: "Missing item", result: 150},
+		{name: "Repeated key", result: 151, secret: "secret 3"},
+		{name: "Incomplete key", result: 152, err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := results[tc.result]
+			if result.Key == nil || result.Key["ID"] != keys[tc.result]["ID"] {
+				t.Errorf("expected the result to hold its key")
+			}
+			if tc.err {
+				if result.Err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+			if result.Err != nil {
+				t.Fatalf("unexpected error: %v", result.Err)
+			}
+			if tc.secret == "" {
+				if result.Item != nil {
+					t.Errorf("expected no item, got %v", result.Item)
+				}
+				return
+			}
+			if got := result.Item["Secret"].(*types.AttributeValueMemberS).Value; got != tc.secret {
+				t.Errorf("expected %q, got %q", tc.secret, got)
+			}
+		})
+	}
+
+	for _, size := range client.requests {
+		if size > maxBatchGetKeys {
+			t.Errorf("expected requests of at most %d keys, got %d", maxBatchGetKeys, size)
+		}
+	}
+
+	t.Run("Canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		results, err := table.GetItems(ctx, "users", []map[string]types.AttributeValue{key(1), key(2)})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !errors.Is(results[1].Err, context.Canceled) {
+			t.Errorf("expected the unprocessed key to fail with the context's error, got %v", results[1].Err)
+		}
+	})
+
+	t.Run("Attempts run out", func(t *testing.T) {
+		table := NewEncryptedTable(NewEncryptedClient(client, cmProvider, WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}), WithBatchGetMaxAttempts(1)))
+		results, err := table.GetItems(context.Background(), "users", []map[string]types.AttributeValue{key(1), key(2)})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if results[0].Err != nil || results[0].Item == nil {
+			t.Errorf("expected the processed key to be read, got %v", results[0].Err)
+		}
+		if !errors.Is(results[1].Err, ErrUnprocessedKeys) {
+			t.Errorf("expected ErrUnprocessedKeys, got %v", results[1].Err)
+		}
+	})
+}
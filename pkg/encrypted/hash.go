@@ -0,0 +1,59 @@
+package encrypted
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/serde"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/utils"
+)
+
+// ErrNoHashKey is returned when an attribute is hashed, or a digest is computed, without a hash key of at least
+// MinHashKeySize bytes.
+var ErrNoHashKey = errors.New("no hash key configured")
+
+// MinHashKeySize is the minimum size in bytes of the key given to WithHashKey.
+const MinHashKeySize = 32
+
+// hashDomain separates the digests of hashed attributes from other HMACs computed with the same key.
+const hashDomain = "dynamodb-encryption-go/hash/v1"
+
+// WithHashKey sets the secret key of the HMAC stored for EncryptHash attributes. Every client reading or writing the
+// same hashed attributes needs the same key, and changing it makes stored digests unmatchable, so it should be
+// loaded from a secret store rather than generated at startup. The client keeps its own copy of the key.
+func WithHashKey(key []byte) EncryptedClientOption {
+	return func(ec *EncryptedClient) {
+		ec.hashKey = append([]byte(nil), key...)
+	}
+}
+
+// HashValue returns the digest an EncryptHash attribute of the client stores for value, so equality filters and
+// condition expressions on the attribute can be built without the plaintext ever being stored.
+func (ec *EncryptedClient) HashValue(attributeName string, value types.AttributeValue) (*types.AttributeValueMemberB, error) {
+	return HashValue(ec.hashKey, attributeName, value)
+}
+
+// HashValue computes the digest stored for an EncryptHash attribute: an HMAC-SHA256 under hashKey of the serialized
+// value, salted with the attribute name so equal values of different attributes can not be correlated. Numbers are
+// normalized before hashing, so 1.50 and 1.5 have the same digest.
+func HashValue(hashKey []byte, attributeName string, value types.AttributeValue) (*types.AttributeValueMemberB, error) {
+	if len(hashKey) < MinHashKeySize {
+		return nil, ErrNoHashKey
+	}
+	rawData, err := serde.NewSerializer().SerializeAttribute(value)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing attribute value: %v", err)
+	}
+	defer utils.Zero(rawData)
+
+	mac := hmac.New(sha256.New, hashKey)
+	mac.Write([]byte(hashDomain))
+	mac.Write([]byte{0})
+	mac.Write([]byte(attributeName))
+	mac.Write([]byte{0})
+	mac.Write(rawData)
+	return &types.AttributeValueMemberB{Value: mac.Sum(nil)}, nil
+}
@@ -0,0 +1,140 @@
+package encrypted
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+var testHashKey = bytes.Repeat([]byte{0x2a}, MinHashKeySize)
+
+func TestHashValue(t *testing.T) {
+	digest := func(key []byte, attributeName string, value types.AttributeValue) []byte {
+		t.Helper()
+		hashed, err := HashValue(key, attributeName, value)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return hashed.Value
+	}
+	email := &types.AttributeValueMemberS{Value: "alice@example.com"}
+
+	testCases := []struct {
+		name  string
+		a, b  []byte
+		equal bool
+	}{
+		{name: "Equal values", a: digest(testHashKey, "Email", email), b: digest(testHashKey, "Email", &types.AttributeValueMemberS{Value: "alice@example.com"}), equal: true},
+		{name: "Normalized numbers", a: digest(testHashKey, "Amount", &types.AttributeValueMemberN{Value: "1.50"}), b: digest(testHashKey, "Amount", &types.AttributeValueMemberN{Value: "1.5"}), equal: true},
+		{name: "Different values", a: digest(testHashKey, "Email", email), b: digest(testHashKey, "Email", &types.AttributeValueMemberS{Value: "bob@example.com"})},
+		{name: "Different attributes", a: digest(testHashKey, "Email", email), b: digest(testHashKey, "Contact", email)},
+		{name: "Different keys", a: digest(testHashKey, "Email", email), b: digest(bytes.Repeat([]byte{0x2b}, MinHashKeySize), "Email", email)},
+		{name: "Different types", a: digest(testHashKey, "Code", &types.AttributeValueMemberS{Value: "1"}), b: digest(testHashKey, "Code", &types.AttributeValueMemberN{Value: "1"})},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := bytes.Equal(tc.a, tc.b); got != tc.equal {
+				t.Errorf("expected equal digests to be %v, got %v", tc.equal, got)
+			}
+		})
+	}
+
+	t.Run("Short key", func(t *testing.T) {
+		if _, err := HashValue(testHashKey[:MinHashKeySize-1], "Email", email); !errors.Is(err, ErrNoHashKey) {
+			t.Errorf("expected ErrNoHashKey, got %v", err)
+		}
+	})
+}
+
+func TestEncryptHash(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	options := WithOptions(WithEncryption("Email", EncryptHash))
+	item := map[string]types.AttributeValue{
+		"ID":    &types.AttributeValueMemberS{Value: "1"},
+		"Email": &types.AttributeValueMemberS{Value: "alice@example.com"},
+		"Name":  &types.AttributeValueMemberS{Value: "Alice"},
+	}
+
+	t.Run("Stored digest", func(t *testing.T) {
+		ec := NewEncryptedClient(nil, &staticProvider{description: map[string]string{}, dataKey: dataKey},
+			WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}), WithHashKey(testHashKey), options)
+
+		var report EncryptionReport
+		encryptedItem, err := ec.EncryptItem(ContextWithEncryptionReport(context.Background(), &report), "users", item)
+		if err != nil {
+			t.Fatalf("failed to encrypt item: %v", err)
+		}
+		want, err := ec.HashValue("Email", item["Email"])
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if diff := cmp.Diff(want, encryptedItem["Email"], cmpopts.IgnoreUnexported(types.AttributeValueMemberB{})); diff != "" {
+			t.Errorf("unexpected stored value (-want +got):\n%s", diff)
+		}
+		if diff := cmp.Diff([]string{"Email"}, report.Hashed); diff != "" {
+			t.Errorf("unexpected hashed attributes (-want +got):\n%s", diff)
+		}
+
+		decryptedItem, err := ec.DecryptItem(context.Background(), "users", encryptedItem)
+		if err != nil {
+			t.Fatalf("failed to decrypt item: %v", err)
+		}
+		if diff := cmp.Diff(want, decryptedItem["Email"], cmpopts.IgnoreUnexported(types.AttributeValueMemberB{})); diff != "" {
+			t.Errorf("expected the digest to be returned as stored (-want +got):\n%s", diff)
+		}
+		if name, ok := decryptedItem["Name"].(*types.AttributeValueMemberS); !ok || name.Value != "Alice" {
+			t.Errorf("expected Name to be decrypted, got %v", decryptedItem["Name"])
+		}
+	})
+
+	t.Run("No hash key", func(t *testing.T) {
+		ec := NewEncryptedClient(nil, &staticProvider{description: map[string]string{}, dataKey: dataKey},
+			WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}), options)
+
+		if _, err := ec.EncryptItem(context.Background(), "users", item); !errors.Is(err, ErrNoHashKey) {
+			t.Errorf("expected ErrNoHashKey, got %v", err)
+		}
+	})
+
+	t.Run("Condition", func(t *testing.T) {
+		client := &transactClient{}
+		ec := NewEncryptedClient(client, &staticProvider{description: map[string]string{}, dataKey: dataKey},
+			WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}), WithHashKey(testHashKey), options)
+
+		_, err := ec.TransactWriteItems(context.Background(), &dynamodb.TransactWriteItemsInput{TransactItems: []types.TransactWriteItem{{
+			ConditionCheck: &types.ConditionCheck{
+				TableName:                 aws.String("users"),
+				Key:                       map[string]types.AttributeValue{"ID": item["ID"]},
+				ConditionExpression:       aws.String("Email = :email"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{":email": item["Email"]},
+			},
+		}}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want, err := ec.HashValue("Email", item["Email"])
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got := client.input.TransactItems[0].ConditionCheck.ExpressionAttributeValues[":email"]
+		if diff := cmp.Diff(want, got, cmpopts.IgnoreUnexported(types.AttributeValueMemberB{})); diff != "" {
+			t.Errorf("unexpected condition value (-want +got):\n%s", diff)
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ErrUnencryptedAttribute is returned by RequireEncryption when a matching attribute would be written in plaintext.
+var ErrUnencryptedAttribute = errors.New("attribute must be encrypted")
+
+// EncryptEvent describes an item that is about to be encrypted and written.
+type EncryptEvent struct {
+	Table string
+	// Item is the plaintext item. Hooks must not modify it.
+	Item map[string]types.AttributeValue
+	// Actions holds the resolved encryption action of every non-key attribute in the item. Key attributes and
+	// system attributes are always written in plaintext and are not listed.
+	Actions map[string]EncryptionAction
+}
+
+// PreEncryptHook is invoked before an item is encrypted. Returning an error vetoes the write: no materials
+// are created and the error is returned to the caller.
+type PreEncryptHook func(ctx context.Context, event *EncryptEvent) error
+
+// WithPreEncryptHook registers a hook invoked before every item the client encrypts. Hooks run in the order
+// they are registered.
+func WithPreEncryptHook(hook PreEncryptHook) EncryptedClientOption {
+	return func(ec *EncryptedClient) {
+		ec.preEncryptHooks = append(ec.preEncryptHooks, hook)
+	}
+}
+
+// RequireEncryption returns a PreEncryptHook vetoing writes in which an attribute matching any of the path.Match
+// patterns, such as "pii_*", is configured as EncryptNone.
+func RequireEncryption(patterns ...string) PreEncryptHook {
+	return func(ctx context.Context, event *EncryptEvent) error {
+		for name, action := range event.Actions {
+			if action != EncryptNone {
+				continue
+			}
+			for _, pattern := range patterns {
+				if matched, err := path.Match(pattern, name); err == nil && matched {
+					return fmt.Errorf("%w: %q in table %q matches %q", ErrUnencryptedAttribute, name, event.Table, pattern)
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// runPreEncryptHooks invokes the registered pre-encrypt hooks.
+func (ec *EncryptedClient) runPreEncryptHooks(ctx context.Context, pkInfo *PrimaryKeyInfo, config *ClientConfig, encryption EncryptionConfig, item map[string]types.AttributeValue) error {
+	if len(ec.preEncryptHooks) == 0 {
+		return nil
+	}
+
+	event := &EncryptEvent{
+		Table:   pkInfo.Table,
+		Item:    item,
+		Actions: make(map[string]EncryptionAction, len(item)),
+	}
+	for name := range item {
+		if isReservedAttribute(name) || pkInfo.IsKeyAttribute(name) || isSystemAttribute(config.systemAttributes(), name) {
+			continue
+		}
+		event.Actions[name] = encryption.ActionFor(name)
+	}
+
+	for _, hook := range ec.preEncryptHooks {
+		if err := hook(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DecryptEvent describes an item that has just been decrypted.
+type DecryptEvent struct {
+	Table string
+	// Key holds the item's primary key attributes.
+	Key map[string]types.AttributeValue
+	// DecryptedAttributes lists the names of the attributes that were decrypted.
+	DecryptedAttributes []string
+	// Item is the decrypted item. Hooks may remove or replace attributes, for example to enforce field-level
+	// access control, and the caller receives the modified item.
+	Item map[string]types.AttributeValue
+	// Err is the error a decryption failed with. It is only set for events passed to audit hooks.
+	Err error
+}
+
+// PostDecryptHook is invoked after an item is decrypted, for auditing, cache priming or access control.
+// Returning an error fails the read with that error.
+type PostDecryptHook func(ctx context.Context, event *DecryptEvent) error
+
+// WithPostDecryptHook registers a hook invoked after every item the client decrypts. Hooks run in the order
+// they are registered.
+func WithPostDecryptHook(hook PostDecryptHook) EncryptedClientOption {
+	return func(ec *EncryptedClient) {
+		ec.postDecryptHooks = append(ec.postDecryptHooks, hook)
+	}
+}
+
+// runPostDecryptHooks invokes the registered post-decrypt hooks and returns the possibly modified item.
+func (ec *EncryptedClient) runPostDecryptHooks(ctx context.Context, pkInfo *PrimaryKeyInfo, item map[string]types.AttributeValue, decrypted []string) (map[string]types.AttributeValue, error) {
+	if len(ec.postDecryptHooks) == 0 {
+		return item, nil
+	}
+
+	event := &DecryptEvent{
+		Table:               pkInfo.Table,
+		Key:                 primaryKey(item, pkInfo),
+		DecryptedAttributes: decrypted,
+		Item:                item,
+	}
+	for _, hook := range ec.postDecryptHooks {
+		if err := hook(ctx, event); err != nil {
+			return nil, err
+		}
+	}
+	return event.Item, nil
+}
+
+// primaryKey returns the primary key attributes of an item.
+func primaryKey(item map[string]types.AttributeValue, pkInfo *PrimaryKeyInfo) map[string]types.AttributeValue {
+	key := map[string]types.AttributeValue{
+		pkInfo.PartitionKey: item[pkInfo.PartitionKey],
+	}
+	if pkInfo.SortKey != "" {
+		key[pkInfo.SortKey] = item[pkInfo.SortKey]
+	}
+	return key
+}
+
+// ConfigChangeEvent describes a configuration swapped in by UpdateConfig or WatchPolicy.
+type ConfigChangeEvent struct {
+	// Previous and Current are the frozen configurations before and after the change.
+	Previous *ClientConfig
+	Current  *ClientConfig
+	// ChangedAttributes lists, in order, the attributes with an explicit action whose action was added, removed
+	// or changed. Default action, pattern and compression changes are visible by comparing the configurations.
+	ChangedAttributes []string
+}
+
+// ConfigChangeHook is invoked after the client's configuration changes, for example to record an audit event.
+// The new configuration is already in effect when hooks run.
+type ConfigChangeHook func(ctx context.Context, event *ConfigChangeEvent)
+
+// WithConfigChangeHook registers a hook invoked whenever the client's configuration changes at runtime. Hooks run
+// in the order they are registered.
+func WithConfigChangeHook(hook ConfigChangeHook) EncryptedClientOption {
+	return func(ec *EncryptedClient) {
+		ec.configChangeHooks = append(ec.configChangeHooks, hook)
+	}
+}
+
+// runConfigChangeHooks invokes the registered config change hooks, unless the configuration is unchanged.
+func (ec *EncryptedClient) runConfigChangeHooks(ctx context.Context, previous, current *ClientConfig) {
+	if len(ec.configChangeHooks) == 0 || reflect.DeepEqual(previous, current) {
+		return
+	}
+
+	event := &ConfigChangeEvent{
+		Previous: previous,
+		Current:  current,
+	}
+	for name, action := range current.Encryption.SpecificActions {
+		if old, ok := previous.Encryption.SpecificActions[name]; !ok || old != action {
+			event.ChangedAttributes = append(event.ChangedAttributes, name)
+		}
+	}
+	for name := range previous.Encryption.SpecificActions {
+		if _, ok := current.Encryption.SpecificActions[name]; !ok {
+			event.ChangedAttributes = append(event.ChangedAttributes, name)
+		}
+	}
+	sort.Strings(event.ChangedAttributes)
+
+	for _, hook := range ec.configChangeHooks {
+		hook(ctx, event)
+	}
+}
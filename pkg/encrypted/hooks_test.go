@@ -0,0 +1,78 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestPostDecryptHooks(t *testing.T) {
+	pkInfo := &PrimaryKeyInfo{Table: "users", PartitionKey: "ID"}
+	item := map[string]types.AttributeValue{
+		"ID":    &types.AttributeValueMemberS{Value: "1"},
+		"Email": &types.AttributeValueMemberS{Value: "a@example.com"},
+		"SSN":   &types.AttributeValueMemberS{Value: "123-45-6789"},
+	}
+
+	var audited *DecryptEvent
+	ec := NewEncryptedClient(nil, nil,
+		WithPostDecryptHook(func(ctx context.Context, event *DecryptEvent) error {
+			audited = event
+			return nil
+		}),
+		WithPostDecryptHook(func(ctx context.Context, event *DecryptEvent) error {
+			delete(event.Item, "SSN")
+			return nil
+		}),
+	)
+
+	result, err := ec.runPostDecryptHooks(context.Background(), pkInfo, item, []string{"Email", "SSN"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := result["SSN"]; ok {
+		t.Errorf("Expected hook to redact SSN, got %v", result)
+	}
+	if audited == nil || audited.Table != "users" || len(audited.Key) != 1 || len(audited.DecryptedAttributes) != 2 {
+		t.Errorf("Unexpected event %+v", audited)
+	}
+
+	denied := errors.New("access denied")
+	ec = NewEncryptedClient(nil, nil, WithPostDecryptHook(func(ctx context.Context, event *DecryptEvent) error {
+		return denied
+	}))
+	if _, err := ec.runPostDecryptHooks(context.Background(), pkInfo, item, nil); !errors.Is(err, denied) {
+		t.Errorf("Expected hook error, got %v", err)
+	}
+}
+
+func TestRequireEncryption(t *testing.T) {
+	pkInfo := &PrimaryKeyInfo{Table: "users", PartitionKey: "pii_id"}
+	item := map[string]types.AttributeValue{
+		"pii_id":    &types.AttributeValueMemberS{Value: "1"},
+		"pii_email": &types.AttributeValueMemberS{Value: "a@example.com"},
+	}
+
+	testCases := []struct {
+		name    string
+		option  Option
+		wantErr error
+	}{
+		{name: "Encrypted", option: WithEncryption("pii_email", EncryptStandard)},
+		{name: "Plaintext", option: WithEncryption("pii_email", EncryptNone), wantErr: ErrUnencryptedAttribute},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec := NewEncryptedClient(nil, nil, WithOptions(tc.option), WithPreEncryptHook(RequireEncryption("pii_*")))
+
+			// Key attributes are never encrypted, so they are not checked
+			err := ec.runPreEncryptHooks(context.Background(), pkInfo, ec.Config(), ec.Config().Encryption, item)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("Expected error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package encrypted
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Ciphertext formats reported by InspectItem.
+const (
+	// CiphertextTink is a ciphertext of a Tink keyset, prefixed with a version byte and the ID of the key that
+	// encrypted it.
+	CiphertextTink = "tink"
+	// CiphertextRaw is a ciphertext of an attribute key, without a prefix.
+	CiphertextRaw = "raw"
+)
+
+// Sizes of the parts of an AES-GCM ciphertext, used to tell ciphertexts from other binary values.
+const (
+	tinkPrefixSize    = 5
+	tinkStartByte     = 0x01
+	aesGCMNonceSize   = 12
+	aesGCMTagSize     = 16
+	minRawCiphertext  = aesGCMNonceSize + aesGCMTagSize + 1
+	minTinkCiphertext = tinkPrefixSize + minRawCiphertext
+)
+
+// ItemInspection describes how a stored item is protected, as far as its metadata and the shape of its ciphertexts
+// tell. The serialization format version and compression of an encrypted attribute are encrypted together with its
+// value, so they are only known once it is decrypted, for example from an EncryptionReport.
+type ItemInspection struct {
+	MaterialName       string // Empty unless the table's key attributes are known
+	MaterialScope      string // "item", or MaterialScopeTable for items encrypted under their table's materials
+	MaterialVersion    int64  // 0 when the item does not pin a version
+	Algorithm          string // Content encryption algorithm of the attribute ciphertexts
+	KeyDerivation      string // KeyDerivationHKDF when attributes are encrypted with attribute keys
+	DeterministicScope string
+	PolicyFingerprint  string
+	Signed             bool
+
+	Attributes []AttributeInspection // Sorted by name, excluding the attributes written by the client itself
+}
+
+// AttributeInspection describes how an attribute of a stored item is stored.
+type AttributeInspection struct {
+	Name       string
+	Type       string // DynamoDB type descriptor, such as "S" or "B"
+	Ciphertext bool
+	Format     string // CiphertextTink or CiphertextRaw for ciphertexts
+	KeyID      uint32 // ID of the key in the data keyset, for CiphertextTink
+	Size       int    // Size in bytes of binary values
+}
+
+// Encrypted returns the names of the attributes stored as ciphertext.
+func (i *ItemInspection) Encrypted() []string {
+	var names []string
+	for _, attribute := range i.Attributes {
+		if attribute.Ciphertext {
+			names = append(names, attribute.Name)
+		}
+	}
+	return names
+}
+
+// inspection holds the settings of InspectItem.
+type inspection struct {
+	pkInfo *PrimaryKeyInfo
+	config *ClientConfig
+}
+
+// InspectOption provides InspectItem with what is known about the item's table.
+type InspectOption func(*inspection)
+
+// InspectWithKeys names the table's key attributes, which are never encrypted, so the material name can be
+// reported.
+func InspectWithKeys(pkInfo *PrimaryKeyInfo) InspectOption {
+	return func(i *inspection) {
+		i.pkInfo = pkInfo
+	}
+}
+
+// InspectWithConfig classifies attributes by the configuration the table is written with, so binary attributes
+// configured to be stored in plaintext are not mistaken for ciphertexts.
+func InspectWithConfig(config *ClientConfig) InspectOption {
+	return func(i *inspection) {
+		i.config = config
+	}
+}
+
+// InspectItem parses the metadata of a stored item and the shape of its binary attributes, without fetching
+// materials or calling KMS, for support tooling. Without InspectWithConfig, any binary attribute shaped like a
+// ciphertext is reported as one.
+func InspectItem(item map[string]types.AttributeValue, opts ...InspectOption) (*ItemInspection, error) {
+	var settings inspection
+	for _, opt := range opts {
+		opt(&settings)
+	}
+
+	version, err := materialVersion(item)
+	if err != nil {
+		return nil, err
+	}
+	derive, err := usesAttributeKeys(item)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := itemScope(item)
+	if err != nil {
+		return nil, err
+	}
+	result := &ItemInspection{
+		MaterialScope:      "item",
+		MaterialVersion:    version,
+		Algorithm:          "AesGcmKey",
+		DeterministicScope: scope.String(),
+	}
+	if derive {
+		result.Algorithm = "HkdfAesGcmKey"
+		result.KeyDerivation = KeyDerivationHKDF
+	}
+	if value, ok := item[MaterialScopeAttribute].(*types.AttributeValueMemberS); ok {
+		result.MaterialScope = value.Value
+	}
+	if value, ok := item[PolicyFingerprintAttribute].(*types.AttributeValueMemberS); ok {
+		result.PolicyFingerprint = value.Value
+	}
+	_, result.Signed = item[SignatureAttribute]
+	if settings.pkInfo != nil {
+		if result.MaterialName, err = itemMaterialName(item, settings.pkInfo); err != nil {
+			return nil, err
+		}
+	}
+
+	for name, value := range item {
+		if isReservedAttribute(name) {
+			continue
+		}
+		attribute := AttributeInspection{Name: name, Type: typeDescriptor(value)}
+		if binaryValue, ok := value.(*types.AttributeValueMemberB); ok {
+			attribute.Size = len(binaryValue.Value)
+			if settings.mayBeEncrypted(name) {
+				inspectCiphertext(&attribute, binaryValue.Value, derive)
+			}
+		}
+		result.Attributes = append(result.Attributes, attribute)
+	}
+	sort.Slice(result.Attributes, func(i, j int) bool {
+		return result.Attributes[i].Name < result.Attributes[j].Name
+	})
+	return result, nil
+}
+
+// mayBeEncrypted reports whether an attribute can hold a ciphertext under the known table settings.
+func (i *inspection) mayBeEncrypted(name string) bool {
+	if i.pkInfo != nil && i.pkInfo.IsKeyAttribute(name) {
+		return false
+	}
+	if i.config != nil {
+		action := i.config.Encryption.ActionFor(i.config.canonicalName(name))
+		if isSystemAttribute(i.config.systemAttributes(), name) || action == EncryptNone || action == EncryptHash {
+			return false
+		}
+	}
+	return true
+}
+
+// inspectCiphertext marks an attribute as ciphertext when its value is shaped like one: a Tink ciphertext for items
+// encrypted with the data key, or a raw AES-GCM ciphertext for items encrypted with attribute keys.
+func inspectCiphertext(attribute *AttributeInspection, value []byte, derive bool) {
+	switch {
+	case derive && len(value) >= minRawCiphertext:
+		attribute.Ciphertext = true
+		attribute.Format = CiphertextRaw
+	case !derive && len(value) >= minTinkCiphertext && value[0] == tinkStartByte:
+		attribute.Ciphertext = true
+		attribute.Format = CiphertextTink
+		attribute.KeyID = binary.BigEndian.Uint32(value[1:tinkPrefixSize])
+	}
+}
+
+// typeDescriptor returns the DynamoDB type descriptor of a value.
+func typeDescriptor(value types.AttributeValue) string {
+	switch value.(type) {
+	case *types.AttributeValueMemberB:
+		return "B"
+	case *types.AttributeValueMemberBOOL:
+		return "BOOL"
+	case *types.AttributeValueMemberBS:
+		return "BS"
+	case *types.AttributeValueMemberL:
+		return "L"
+	case *types.AttributeValueMemberM:
+		return "M"
+	case *types.AttributeValueMemberN:
+		return "N"
+	case *types.AttributeValueMemberNS:
+		return "NS"
+	case *types.AttributeValueMemberNULL:
+		return "NULL"
+	case *types.AttributeValueMemberS:
+		return "S"
+	case *types.AttributeValueMemberSS:
+		return "SS"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
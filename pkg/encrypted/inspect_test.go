@@ -0,0 +1,149 @@
+package encrypted
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestInspectItem(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	signingKey, _, publicKey, err := delegatedkeys.GenerateSigningKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+	cmProvider := &staticProvider{
+		description: map[string]string{"PublicKey": base64.StdEncoding.EncodeToString(publicKey), provider.MaterialVersionKey: "3"},
+		dataKey:     dataKey,
+		signingKey:  signingKey,
+	}
+	probe, err := dataKey.Encrypt([]byte("probe"), nil)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	keyID := binary.BigEndian.Uint32(probe[1:tinkPrefixSize])
+	pkInfo := &PrimaryKeyInfo{Table: "users", PartitionKey: "ID"}
+	plaintext := map[string]types.AttributeValue{
+		"ID":     &types.AttributeValueMemberS{Value: "1"},
+		"Avatar": &types.AttributeValueMemberB{Value: append([]byte{tinkStartByte}, make([]byte, 64)...)},
+		"Secret": &types.AttributeValueMemberS{Value: "value"},
+	}
+	itemName, err := ConstructMaterialName(plaintext, pkInfo)
+	if err != nil {
+		t.Fatalf("failed to construct material name: %v", err)
+	}
+	plaintextAvatar := AttributeInspection{Name: "Avatar", Type: "B", Size: 65}
+
+	testCases := []struct {
+		name       string
+		options    []Option
+		inspect    func(config *ClientConfig) []InspectOption
+		want       ItemInspection
+		attributes []AttributeInspection
+	}{
+		{
+			name:    "Data key",
+			options: []Option{WithEncryption("Avatar", EncryptNone)},
+			inspect: func(config *ClientConfig) []InspectOption {
+				return []InspectOption{InspectWithKeys(pkInfo), InspectWithConfig(config)}
+			},
+			want: ItemInspection{MaterialName: itemName, MaterialScope: "item", MaterialVersion: 3, Algorithm: "AesGcmKey", DeterministicScope: "global"},
+			attributes: []AttributeInspection{
+				plaintextAvatar,
+				{Name: "ID", Type: "S"},
+				{Name: "Secret", Type: "B", Ciphertext: true, Format: CiphertextTink, KeyID: keyID},
+			},
+		},
+		{
+			name:    "Plaintext binary without configuration",
+			options: []Option{WithEncryption("Avatar", EncryptNone)},
+			inspect: func(config *ClientConfig) []InspectOption {
+				return nil
+			},
+			want: ItemInspection{MaterialScope: "item", MaterialVersion: 3, Algorithm: "AesGcmKey", DeterministicScope: "global"},
+			attributes: []AttributeInspection{
+				{Name: "Avatar", Type: "B", Ciphertext: true, Format: CiphertextTink, Size: 65},
+				{Name: "ID", Type: "S"},
+				{Name: "Secret", Type: "B", Ciphertext: true, Format: CiphertextTink, KeyID: keyID},
+			},
+		},
+		{
+			name:    "Attribute keys, table materials and signatures",
+			options: []Option{WithEncryption("Avatar", EncryptNone), WithAttributeKeys(true), WithTableMaterials(true), WithItemSignatures(true), WithDeterministicScope(ScopeTable), WithPolicyFingerprints(true)},
+			inspect: func(config *ClientConfig) []InspectOption {
+				return []InspectOption{InspectWithKeys(pkInfo), InspectWithConfig(config)}
+			},
+			want: ItemInspection{
+				MaterialName: TableMaterialName("users"), MaterialScope: MaterialScopeTable, MaterialVersion: 3, Algorithm: "HkdfAesGcmKey",
+				KeyDerivation: KeyDerivationHKDF, DeterministicScope: "table", Signed: true,
+			},
+			attributes: []AttributeInspection{
+				plaintextAvatar,
+				{Name: "ID", Type: "S"},
+				{Name: "Secret", Type: "B", Ciphertext: true, Format: CiphertextRaw},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec := NewEncryptedClient(nil, cmProvider, WithPrimaryKeyInfo("users", *pkInfo), WithOptions(tc.options...))
+			stored, err := ec.EncryptItem(context.Background(), "users", plaintext)
+			if err != nil {
+				t.Fatalf("failed to encrypt item: %v", err)
+			}
+
+			got, err := InspectItem(stored, tc.inspect(ec.Config())...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ec.Config().PolicyFingerprints {
+				tc.want.PolicyFingerprint = ec.Config().Encryption.Fingerprint()
+			}
+			for i := range got.Attributes {
+				if got.Attributes[i].Name == "Secret" {
+					got.Attributes[i].Size = 0
+				}
+			}
+			tc.want.Attributes = tc.attributes
+			if diff := cmp.Diff(&tc.want, got); diff != "" {
+				t.Errorf("unexpected inspection (-want +got):\n%s", diff)
+			}
+			if encrypted := got.Encrypted(); len(encrypted) == 0 || encrypted[len(encrypted)-1] != "Secret" {
+				t.Errorf("expected Secret to be reported as encrypted, got %v", encrypted)
+			}
+		})
+	}
+}
+
+func TestInspectItem_InvalidMetadata(t *testing.T) {
+	testCases := []struct {
+		name string
+		item map[string]types.AttributeValue
+	}{
+		{name: "Material version", item: map[string]types.AttributeValue{MaterialVersionAttribute: &types.AttributeValueMemberS{Value: "1"}}},
+		{name: "Key derivation", item: map[string]types.AttributeValue{KeyDerivationAttribute: &types.AttributeValueMemberS{Value: "unknown"}}},
+		{name: "Deterministic scope", item: map[string]types.AttributeValue{DeterministicScopeAttribute: &types.AttributeValueMemberS{Value: "unknown"}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := InspectItem(tc.item); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
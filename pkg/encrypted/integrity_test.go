@@ -0,0 +1,110 @@
+package encrypted
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+)
+
+// staticProvider serves the same in-memory materials for every material name. Materials are decrypted with
+// decryptionKey, when set, instead of dataKey.
+type staticProvider struct {
+	provider.CryptographicMaterialsProvider
+	description   map[string]string
+	dataKey       delegatedkeys.DelegatedKey
+	decryptionKey delegatedkeys.DelegatedKey
+	signingKey    delegatedkeys.DelegatedKey
+}
+
+func (p *staticProvider) EncryptionMaterials(ctx context.Context, materialName string) (materials.CryptographicMaterials, error) {
+	return materials.NewEncryptionMaterials(p.description, p.dataKey, p.signingKey), nil
+}
+
+func (p *staticProvider) DecryptionMaterials(ctx context.Context, materialName string, version int64) (materials.CryptographicMaterials, error) {
+	if p.decryptionKey != nil {
+		return materials.NewDecryptionMaterials(p.description, p.decryptionKey), nil
+	}
+	return materials.NewDecryptionMaterials(p.description, p.dataKey), nil
+}
+
+// scanClient returns a fixed page of items from Scan.
+type scanClient struct {
+	DynamoDBClientInterface
+	items []map[string]types.AttributeValue
+}
+
+func (c *scanClient) Scan(ctx context.Context, input *dynamodb.ScanInput, opts ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	return &dynamodb.ScanOutput{Items: c.items}, nil
+}
+
+func TestEncryptedClient_VerifyTable(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	signingKey, _, publicKey, err := delegatedkeys.GenerateSigningKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+	cmp := &staticProvider{
+		description: map[string]string{"PublicKey": base64.StdEncoding.EncodeToString(publicKey)},
+		dataKey:     dataKey,
+		signingKey:  signingKey,
+	}
+
+	client := &scanClient{}
+	ec := NewEncryptedClient(client, cmp,
+		WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}),
+		WithOptions(WithEncryption("Role", EncryptNone), WithItemSignatures(true)),
+	)
+
+	for _, id := range []string{"1", "2", "3"} {
+		item, err := ec.encryptItem(context.Background(), "users", map[string]types.AttributeValue{
+			"ID":     &types.AttributeValueMemberS{Value: id},
+			"Role":   &types.AttributeValueMemberS{Value: "reader"},
+			"Secret": &types.AttributeValueMemberS{Value: "value " + id},
+		})
+		if err != nil {
+			t.Fatalf("failed to encrypt item: %v", err)
+		}
+		client.items = append(client.items, item)
+	}
+
+	// Item 2 has a plaintext attribute changed. Item 3 has a corrupted ciphertext and no signature, so only its
+	// authentication tag catches it.
+	client.items[1]["Role"] = &types.AttributeValueMemberS{Value: "admin"}
+	delete(client.items[2], SignatureAttribute)
+	secret := client.items[2]["Secret"].(*types.AttributeValueMemberB)
+	secret.Value[len(secret.Value)-1] ^= 1
+
+	report, err := ec.VerifyTable(context.Background(), "users")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Items != 3 || len(report.Failures) != 2 {
+		t.Fatalf("Expected 2 of 3 items to fail, got %d of %d", len(report.Failures), report.Items)
+	}
+	for i, id := range []string{"2", "3"} {
+		failure := report.Failures[i]
+		if got := failure.Key["ID"].(*types.AttributeValueMemberS).Value; got != id || len(failure.Key) != 1 {
+			t.Errorf("Expected failure %d to be keyed by ID %s, got %v", i, id, failure.Key)
+		}
+	}
+	if !errors.Is(report.Failures[0].Err, ErrInvalidSignature) {
+		t.Errorf("Expected tampered item to fail its signature, got %v", report.Failures[0].Err)
+	}
+	if errors.Is(report.Failures[1].Err, ErrInvalidSignature) {
+		t.Errorf("Expected corrupted item to fail decryption, got %v", report.Failures[1].Err)
+	}
+}
@@ -0,0 +1,163 @@
+package encrypted
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/serde"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/utils"
+)
+
+// WithDecryptedItemCache caches the plaintext of decrypted items for up to ttl, keeping at most maxEntries items, so
+// hot items read again and again skip fetching materials, verifying signatures and decrypting. Items are cached by
+// a hash of their stored form, so an item that changed in any way is decrypted again, and entries only serve reads
+// made with the configuration and action overrides they were decrypted with. Post-decrypt and audit hooks still run
+// on every read.
+//
+// The cache keeps plaintext in process memory beyond the read that needed it, so it is off unless enabled here.
+// Cached plaintext is overwritten with zeros when it expires or is evicted, but values handed to callers are not.
+// Items whose materials are deleted keep being served from the cache until their entries expire.
+func WithDecryptedItemCache(ttl time.Duration, maxEntries int) EncryptedClientOption {
+	return func(ec *EncryptedClient) {
+		ec.itemCache = newDecryptedItemCache(ttl, maxEntries)
+	}
+}
+
+// WithoutPlaintextCaching guarantees that the client keeps no plaintext beyond the read that needed it, for
+// high-assurance deployments. It overrides WithDecryptedItemCache, whatever the order of the options, so a shared
+// set of options can not enable the cache by accident. Pair it with provider.WithoutPlaintextCaching to keep
+// unwrapped keys out of the provider's caches as well.
+func WithoutPlaintextCaching() EncryptedClientOption {
+	return func(ec *EncryptedClient) {
+		ec.noPlaintextCache = true
+	}
+}
+
+// decryptedItemCache holds the serialized plaintext of decrypted attributes, by the hash of the stored item.
+type decryptedItemCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*list.Element
+	lru        *list.List // front is most recently used
+	now        func() time.Time
+}
+
+// decryptedEntry is the plaintext of a decrypted item. The configuration it was decrypted with is kept so entries
+// can only match reads made with that configuration.
+type decryptedEntry struct {
+	key         string
+	config      *ClientConfig
+	plaintexts  map[string][]byte // serialized plaintext by stored attribute name
+	description map[string]string
+	expiresAt   time.Time
+}
+
+func newDecryptedItemCache(ttl time.Duration, maxEntries int) *decryptedItemCache {
+	return &decryptedItemCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		now:        time.Now,
+	}
+}
+
+// cacheKey returns the key of a stored item decrypted with the given attribute actions.
+func (c *decryptedItemCache) cacheKey(tableName string, encryption EncryptionConfig, item map[string]types.AttributeValue) (string, error) {
+	data, err := serde.NewSerializer().Serialize(&types.AttributeValueMemberM{Value: item})
+	if err != nil {
+		return "", fmt.Errorf("error serializing item for the decrypted item cache: %v", err)
+	}
+	key := utils.HashString(tableName + "\x00" + encryption.Fingerprint() + "\x00" + string(data))
+	utils.Zero(data)
+	return key, nil
+}
+
+// get returns copies of the plaintexts cached under key for config, which the caller owns, and the material
+// description they were decrypted with.
+func (c *decryptedItemCache) get(key string, config *ClientConfig) (map[string][]byte, map[string]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, nil, false
+	}
+	entry := element.Value.(*decryptedEntry)
+	if c.now().After(entry.expiresAt) {
+		c.remove(element)
+		return nil, nil, false
+	}
+	if entry.config != config {
+		return nil, nil, false
+	}
+	c.lru.MoveToFront(element)
+
+	plaintexts := make(map[string][]byte, len(entry.plaintexts))
+	for name, plaintext := range entry.plaintexts {
+		plaintexts[name] = append([]byte(nil), plaintext...)
+	}
+	return plaintexts, entry.description, true
+}
+
+// put caches plaintexts under key, taking ownership of them.
+func (c *decryptedItemCache) put(key string, config *ClientConfig, plaintexts map[string][]byte, description map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+	if c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+		c.evict()
+	}
+	entry := &decryptedEntry{
+		key:         key,
+		config:      config,
+		plaintexts:  plaintexts,
+		description: description,
+		expiresAt:   c.now().Add(c.ttl),
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+}
+
+// evict removes the expired entries, or the least recently used one when none has expired. It must be called with
+// c.mu held.
+func (c *decryptedItemCache) evict() {
+	now := c.now()
+	expired := false
+	for element := c.lru.Back(); element != nil; {
+		previous := element.Prev()
+		if now.After(element.Value.(*decryptedEntry).expiresAt) {
+			c.remove(element)
+			expired = true
+		}
+		element = previous
+	}
+	if !expired {
+		c.remove(c.lru.Back())
+	}
+}
+
+// clear removes every entry.
+func (c *decryptedItemCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for c.lru.Len() > 0 {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove drops an entry and zeroes its plaintext. It must be called with c.mu held.
+func (c *decryptedItemCache) remove(element *list.Element) {
+	entry := c.lru.Remove(element).(*decryptedEntry)
+	delete(c.entries, entry.key)
+	for _, plaintext := range entry.plaintexts {
+		utils.Zero(plaintext)
+	}
+}
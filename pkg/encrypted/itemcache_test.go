@@ -0,0 +1,155 @@
+package encrypted
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/utils"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// countingProvider counts the decryption materials fetched from the static provider it wraps.
+type countingProvider struct {
+	*staticProvider
+	decryptions int
+}
+
+func (p *countingProvider) DecryptionMaterials(ctx context.Context, materialName string, version int64) (materials.CryptographicMaterials, error) {
+	p.decryptions++
+	return p.staticProvider.DecryptionMaterials(ctx, materialName, version)
+}
+
+func TestDecryptedItemCache(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	cmProvider := &countingProvider{staticProvider: &staticProvider{description: map[string]string{}, dataKey: dataKey}}
+	ec := NewEncryptedClient(nil, cmProvider,
+		WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}),
+		WithOptions(WithEncryption("Role", EncryptNone)),
+		WithDecryptedItemCache(time.Minute, 10),
+	)
+
+	plaintext := map[string]types.AttributeValue{
+		"ID":     &types.AttributeValueMemberS{Value: "1"},
+		"Role":   &types.AttributeValueMemberS{Value: "reader"},
+		"Secret": &types.AttributeValueMemberS{Value: "value"},
+	}
+	stored, err := ec.encryptItem(context.Background(), "users", plaintext)
+	if err != nil {
+		t.Fatalf("failed to encrypt item: %v", err)
+	}
+	changed := make(map[string]types.AttributeValue, len(stored))
+	for name, value := range stored {
+		changed[name] = value
+	}
+	changed["Role"] = &types.AttributeValueMemberS{Value: "admin"}
+
+	testCases := []struct {
+		name        string
+		item        map[string]types.AttributeValue
+		update      bool
+		decryptions int
+	}{
+		{name: "First read", item: stored, decryptions: 1},
+		{name: "Repeated read", item: stored, decryptions: 1},
+		{name: "Changed item", item: changed, decryptions: 2},
+		{name: "Repeated changed item", item: changed, decryptions: 2},
+		{name: "Configuration update", item: stored, update: true, decryptions: 3},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.update {
+				if err := ec.UpdateConfig(context.Background(), ec.Config().With(WithItemSignatures(true))); err != nil {
+					t.Fatalf("failed to update config: %v", err)
+				}
+			}
+			item, err := ec.decryptItem(context.Background(), "users", tc.item)
+			if err != nil {
+				t.Fatalf("failed to decrypt item: %v", err)
+			}
+			if got := item["Secret"].(*types.AttributeValueMemberS).Value; got != "value" {
+				t.Errorf("expected Secret to decrypt to value, got %s", got)
+			}
+			if got, want := item["Role"], tc.item["Role"]; !cmp.Equal(got, want, cmpopts.IgnoreUnexported(types.AttributeValueMemberS{})) {
+				t.Errorf("expected Role %v, got %v", want, got)
+			}
+			if cmProvider.decryptions != tc.decryptions {
+				t.Errorf("expected %d materials fetches, got %d", tc.decryptions, cmProvider.decryptions)
+			}
+		})
+	}
+}
+
+func TestDecryptedItemCache_Eviction(t *testing.T) {
+	now := time.Now()
+	cache := newDecryptedItemCache(time.Minute, 2)
+	cache.now = func() time.Time { return now }
+	config := NewClientConfig()
+
+	plaintexts := make([][]byte, 3)
+	for i, key := range []string{"a", "b", "c"} {
+		plaintexts[i] = []byte("secret " + key)
+		cache.put(key, config, map[string][]byte{"Secret": plaintexts[i]}, nil)
+		if key == "a" {
+			now = now.Add(30 * time.Second)
+		}
+	}
+
+	if _, _, ok := cache.get("a", config); ok {
+		t.Errorf("expected the least recently used entry to be evicted")
+	}
+	if !bytes.Equal(plaintexts[0], make([]byte, len(plaintexts[0]))) {
+		t.Errorf("expected evicted plaintext to be zeroed, got %q", plaintexts[0])
+	}
+	if _, _, ok := cache.get("b", NewClientConfig()); ok {
+		t.Errorf("expected entries to only match the configuration they were decrypted with")
+	}
+
+	got, _, ok := cache.get("b", config)
+	if !ok || string(got["Secret"]) != "secret b" {
+		t.Fatalf("expected the cached plaintext, got %q", got["Secret"])
+	}
+	utils.Zero(got["Secret"])
+	if string(plaintexts[1]) != "secret b" {
+		t.Errorf("expected get to return a copy of the cached plaintext")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, _, ok := cache.get("c", config); ok {
+		t.Errorf("expected the entry to expire")
+	}
+	if !bytes.Equal(plaintexts[2], make([]byte, len(plaintexts[2]))) {
+		t.Errorf("expected expired plaintext to be zeroed, got %q", plaintexts[2])
+	}
+}
+
+func TestWithoutPlaintextCaching(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts []EncryptedClientOption
+	}{
+		{name: "After the cache", opts: []EncryptedClientOption{WithDecryptedItemCache(time.Minute, 10), WithoutPlaintextCaching()}},
+		{name: "Before the cache", opts: []EncryptedClientOption{WithoutPlaintextCaching(), WithDecryptedItemCache(time.Minute, 10)}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if ec := NewEncryptedClient(nil, nil, tc.opts...); ec.itemCache != nil {
+				t.Errorf("expected the decrypted item cache to be disabled")
+			}
+		})
+	}
+}
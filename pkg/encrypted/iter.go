@@ -0,0 +1,150 @@
+//go:build go1.23
+
+package encrypted
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// BatchItem is an item returned by BatchGetItems, with the table it was read from.
+type BatchItem struct {
+	TableName string
+	Item      map[string]types.AttributeValue
+}
+
+// QueryItems returns an iterator over the decrypted items matching a query, across all result pages:
+//
+//	for item, err := range client.QueryItems(ctx, input) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// Pages are requested as the loop advances, so breaking out of it stops the query. An error ends the iteration
+// after it is yielded. Each loop runs its own pagination and keeps its position to itself, so the same iterator can
+// be ranged over again, or by several goroutines at once, and every loop reads all pages from the start.
+func (ec *EncryptedClient) QueryItems(ctx context.Context, input *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) iter.Seq2[map[string]types.AttributeValue, error] {
+	ctx = operationContext(ctx, "QueryItems")
+	return func(yield func(map[string]types.AttributeValue, error) bool) {
+		tableName := aws.StringValue(input.TableName)
+		if err := ec.checkIndex(ctx, tableName, input.IndexName, input.ProjectionExpression, input.ExpressionAttributeNames); err != nil {
+			yield(nil, err)
+			return
+		}
+
+		paginator := dynamodb.NewQueryPaginator(ec.Client, input)
+		for paginator.HasMorePages() {
+			output, err := paginator.NextPage(ctx, optFns...)
+			if err != nil {
+				yield(nil, fmt.Errorf("error querying encrypted items: %v", err))
+				return
+			}
+			if !ec.yieldDecrypted(ctx, tableName, output.Items, yield) {
+				return
+			}
+		}
+	}
+}
+
+// ScanItems returns an iterator over the decrypted items of a scan, across all result pages. It behaves like
+// QueryItems.
+func (ec *EncryptedClient) ScanItems(ctx context.Context, input *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) iter.Seq2[map[string]types.AttributeValue, error] {
+	ctx = operationContext(ctx, "ScanItems")
+	return func(yield func(map[string]types.AttributeValue, error) bool) {
+		tableName := aws.StringValue(input.TableName)
+		if err := ec.checkIndex(ctx, tableName, input.IndexName, input.ProjectionExpression, input.ExpressionAttributeNames); err != nil {
+			yield(nil, err)
+			return
+		}
+
+		paginator := dynamodb.NewScanPaginator(ec.Client, input)
+		for paginator.HasMorePages() {
+			output, err := paginator.NextPage(ctx, optFns...)
+			if err != nil {
+				yield(nil, fmt.Errorf("error scanning encrypted items: %w", err))
+				return
+			}
+			if !ec.yieldDecrypted(ctx, tableName, output.Items, yield) {
+				return
+			}
+		}
+	}
+}
+
+// BatchGetItems returns an iterator over the decrypted items of a batch get. Unprocessed keys are requested again,
+// with a growing delay, until every item has been read or the attempts set with WithBatchGetMaxAttempts run out, in
+// which case the iteration ends with an ErrUnprocessedKeys error. Items are yielded in the order DynamoDB returns
+// them, which does not follow the order of the requested keys.
+func (ec *EncryptedClient) BatchGetItems(ctx context.Context, input *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) iter.Seq2[BatchItem, error] {
+	ctx = operationContext(ctx, "BatchGetItems")
+	return func(yield func(BatchItem, error) bool) {
+		request := *input
+		delay := batchGetBaseDelay
+		for attempt := 1; len(request.RequestItems) > 0; attempt++ {
+			output, err := ec.Client.BatchGetItem(ctx, &request, optFns...)
+			if err != nil {
+				yield(BatchItem{}, fmt.Errorf("error batch getting encrypted items: %w", err))
+				return
+			}
+
+			for tableName, items := range output.Responses {
+				for _, item := range items {
+					decryptedItem, err := ec.decryptItem(ctx, tableName, item)
+					if err != nil {
+						yield(BatchItem{}, err)
+						return
+					}
+					if !yield(BatchItem{TableName: tableName, Item: decryptedItem}, nil) {
+						return
+					}
+				}
+			}
+
+			request.RequestItems = output.UnprocessedKeys
+			if len(request.RequestItems) == 0 {
+				return
+			}
+			if attempt >= ec.batchGetAttempts {
+				yield(BatchItem{}, fmt.Errorf("%w after %d attempts", ErrUnprocessedKeys, attempt))
+				return
+			}
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				yield(BatchItem{}, ctx.Err())
+				return
+			case <-timer.C:
+			}
+			delay = min(2*delay, batchGetMaxDelay)
+		}
+	}
+}
+
+// yieldDecrypted decrypts a page of items, runs the result pipeline on them and yields the remaining ones. It
+// reports whether the iteration should continue.
+func (ec *EncryptedClient) yieldDecrypted(ctx context.Context, tableName string, items []map[string]types.AttributeValue, yield func(map[string]types.AttributeValue, error) bool) bool {
+	for _, item := range items {
+		decryptedItem, err := ec.decryptResult(ctx, tableName, item)
+		if err != nil {
+			yield(nil, err)
+			return false
+		}
+		if decryptedItem == nil {
+			continue
+		}
+		if !yield(decryptedItem, nil) {
+			return false
+		}
+	}
+	return true
+}
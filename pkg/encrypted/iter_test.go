@@ -0,0 +1,191 @@
+//go:build go1.23
+
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// pagingClient serves one item per Query page and one item per BatchGetItem call, leaving the remaining keys
+// unprocessed.
+type pagingClient struct {
+	DynamoDBClientInterface
+	ids   []string
+	calls int
+	mu    sync.Mutex
+}
+
+func (c *pagingClient) Query(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	c.mu.Lock()
+	c.calls++
+	c.mu.Unlock()
+	page := 0
+	if start, ok := input.ExclusiveStartKey["ID"].(*types.AttributeValueMemberS); ok {
+		for i, id := range c.ids {
+			if id == start.Value {
+				page = i + 1
+			}
+		}
+	}
+
+	output := &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{idItem(c.ids[page])}}
+	if page < len(c.ids)-1 {
+		output.LastEvaluatedKey = idItem(c.ids[page])
+	}
+	return output, nil
+}
+
+func (c *pagingClient) BatchGetItem(ctx context.Context, input *dynamodb.BatchGetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	c.calls++
+	keys := input.RequestItems["users"].Keys
+	output := &dynamodb.BatchGetItemOutput{
+		Responses: map[string][]map[string]types.AttributeValue{"users": keys[:1]},
+	}
+	if len(keys) > 1 {
+		output.UnprocessedKeys = map[string]types.KeysAndAttributes{"users": {Keys: keys[1:]}}
+	}
+	return output, nil
+}
+
+func idItem(id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: id}}
+}
+
+func TestEncryptedClient_QueryItems(t *testing.T) {
+	client := &pagingClient{ids: []string{"1", "2", "3"}}
+	ec := NewEncryptedClient(client, &staticProvider{}, WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}))
+	input := &dynamodb.QueryInput{TableName: aws.String("users")}
+
+	var ids []string
+	for item, err := range ec.QueryItems(context.Background(), input) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids = append(ids, item["ID"].(*types.AttributeValueMemberS).Value)
+	}
+	if len(ids) != 3 || ids[0] != "1" || ids[2] != "3" {
+		t.Errorf("Expected items from every page in order, got %v", ids)
+	}
+
+	// Breaking out of the loop stops requesting pages
+	client.calls = 0
+	for range ec.QueryItems(context.Background(), input) {
+		break
+	}
+	if client.calls != 1 {
+		t.Errorf("Expected 1 Query call after breaking, got %d", client.calls)
+	}
+}
+
+func TestEncryptedClient_QueryItems_Independent(t *testing.T) {
+	client := &pagingClient{ids: []string{"1", "2", "3"}}
+	ec := NewEncryptedClient(client, &staticProvider{}, WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}))
+	items := ec.QueryItems(context.Background(), &dynamodb.QueryInput{TableName: aws.String("users")})
+
+	collect := func(seq iter.Seq2[map[string]types.AttributeValue, error]) ([]string, error) {
+		var ids []string
+		for item, err := range seq {
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, item["ID"].(*types.AttributeValueMemberS).Value)
+		}
+		return ids, nil
+	}
+
+	t.Run("Interleaved", func(t *testing.T) {
+		next, stop := iter.Pull2(items)
+		defer stop()
+		if item, err, ok := next(); !ok || err != nil || item["ID"].(*types.AttributeValueMemberS).Value != "1" {
+			t.Fatalf("expected the first item, got %v, %v", item, err)
+		}
+
+		// A second loop over the same iterator starts from the first page, and leaves the first loop's position alone
+		ids, err := collect(items)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !slices.Equal(ids, client.ids) {
+			t.Errorf("expected items %v, got %v", client.ids, ids)
+		}
+		if item, err, ok := next(); !ok || err != nil || item["ID"].(*types.AttributeValueMemberS).Value != "2" {
+			t.Errorf("expected the first loop to continue with the second item, got %v, %v", item, err)
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		results := make([][]string, 8)
+		errs := make([]error, len(results))
+		for i := range results {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i], errs[i] = collect(items)
+			}()
+		}
+		wg.Wait()
+		for i := range results {
+			if errs[i] != nil {
+				t.Fatalf("unexpected error: %v", errs[i])
+			}
+			if !slices.Equal(results[i], client.ids) {
+				t.Errorf("expected items %v, got %v", client.ids, results[i])
+			}
+		}
+	})
+}
+
+func TestEncryptedClient_BatchGetItems(t *testing.T) {
+	client := &pagingClient{}
+	ec := NewEncryptedClient(client, &staticProvider{}, WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}))
+	input := &dynamodb.BatchGetItemInput{RequestItems: map[string]types.KeysAndAttributes{
+		"users": {Keys: []map[string]types.AttributeValue{idItem("1"), idItem("2")}},
+	}}
+
+	var ids []string
+	for item, err := range ec.BatchGetItems(context.Background(), input) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if item.TableName != "users" {
+			t.Errorf("Expected items from users, got %s", item.TableName)
+		}
+		ids = append(ids, item.Item["ID"].(*types.AttributeValueMemberS).Value)
+	}
+	if len(ids) != 2 || client.calls != 2 {
+		t.Errorf("Expected unprocessed keys to be requested again, got items %v after %d calls", ids, client.calls)
+	}
+	if len(input.RequestItems["users"].Keys) != 2 {
+		t.Errorf("Expected the input to be left unchanged")
+	}
+
+	// Keys still unprocessed when the attempts run out end the iteration with an error
+	client.calls = 0
+	ec = NewEncryptedClient(client, &staticProvider{}, WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}), WithBatchGetMaxAttempts(2))
+	input.RequestItems["users"] = types.KeysAndAttributes{Keys: []map[string]types.AttributeValue{idItem("1"), idItem("2"), idItem("3")}}
+	ids = nil
+	var iterErr error
+	for item, err := range ec.BatchGetItems(context.Background(), input) {
+		if err != nil {
+			iterErr = err
+			continue
+		}
+		ids = append(ids, item.Item["ID"].(*types.AttributeValueMemberS).Value)
+	}
+	if !errors.Is(iterErr, ErrUnprocessedKeys) {
+		t.Errorf("Expected ErrUnprocessedKeys, got %v", iterErr)
+	}
+	if len(ids) != 2 || client.calls != 2 {
+		t.Errorf("Expected 2 items from 2 calls, got items %v after %d calls", ids, client.calls)
+	}
+}
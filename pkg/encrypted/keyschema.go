@@ -0,0 +1,40 @@
+package encrypted
+
+import (
+	"context"
+)
+
+// KeySchemaCache shares the primary key information of tables between clients, for example across a fleet, so that
+// only the first client to use a table calls DescribeTable. Each client keeps its own copy of a table's key schema in
+// PrimaryKeyCache and consults the shared cache on a miss. Cache errors are treated as misses. Implementations must
+// be safe for concurrent use.
+type KeySchemaCache interface {
+	Get(ctx context.Context, tableName string) (*PrimaryKeyInfo, bool, error)
+	Put(ctx context.Context, tableName string, info *PrimaryKeyInfo) error
+}
+
+// WithKeySchemaCache looks up table key schemas missing from the client's own cache in cache before calling
+// DescribeTable, and stores the key schemas it describes there.
+func WithKeySchemaCache(cache KeySchemaCache) EncryptedClientOption {
+	return func(ec *EncryptedClient) {
+		ec.keySchemaCache = cache
+	}
+}
+
+// describeTable returns the primary key information of a table from the shared key schema cache, or describes the
+// table and stores the result there.
+func (ec *EncryptedClient) describeTable(ctx context.Context, tableName string) (*PrimaryKeyInfo, error) {
+	if ec.keySchemaCache == nil {
+		return TableInfo(ctx, ec.Client, tableName)
+	}
+	if pkInfo, ok, err := ec.keySchemaCache.Get(ctx, tableName); err == nil && ok {
+		return pkInfo, nil
+	}
+
+	pkInfo, err := TableInfo(ctx, ec.Client, tableName)
+	if err != nil {
+		return nil, err
+	}
+	_ = ec.keySchemaCache.Put(ctx, tableName, pkInfo)
+	return pkInfo, nil
+}
@@ -0,0 +1,68 @@
+package encrypted
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// describeClient describes every table with an ID partition key and counts the calls.
+type describeClient struct {
+	DynamoDBClientInterface
+	calls int
+}
+
+func (c *describeClient) DescribeTable(ctx context.Context, input *dynamodb.DescribeTableInput, opts ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	c.calls++
+	return &dynamodb.DescribeTableOutput{Table: &types.TableDescription{
+		TableName: input.TableName,
+		KeySchema: []types.KeySchemaElement{{AttributeName: aws.String("ID"), KeyType: types.KeyTypeHash}},
+	}}, nil
+}
+
+// mapKeySchemaCache is a KeySchemaCache backed by a map.
+type mapKeySchemaCache struct {
+	mu      sync.Mutex
+	entries map[string]*PrimaryKeyInfo
+}
+
+func (c *mapKeySchemaCache) Get(ctx context.Context, tableName string) (*PrimaryKeyInfo, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	info, ok := c.entries[tableName]
+	return info, ok, nil
+}
+
+func (c *mapKeySchemaCache) Put(ctx context.Context, tableName string, info *PrimaryKeyInfo) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[tableName] = info
+	return nil
+}
+
+func TestKeySchemaCache(t *testing.T) {
+	client := &describeClient{}
+	shared := &mapKeySchemaCache{entries: make(map[string]*PrimaryKeyInfo)}
+
+	// The first client of the fleet describes the table, every later one reads the shared cache
+	for i := 0; i < 3; i++ {
+		ec := NewEncryptedClient(client, nil, WithKeySchemaCache(shared))
+		pkInfo, err := ec.getPrimaryKeyInfo(context.Background(), "users")
+		if err != nil {
+			t.Fatalf("failed to get key schema: %v", err)
+		}
+		if pkInfo.PartitionKey != "ID" {
+			t.Errorf("expected partition key ID, got %q", pkInfo.PartitionKey)
+		}
+	}
+	if client.calls != 1 {
+		t.Errorf("expected 1 DescribeTable call, got %d", client.calls)
+	}
+	if _, ok := shared.entries["users"]; !ok {
+		t.Errorf("expected the key schema to be shared")
+	}
+}
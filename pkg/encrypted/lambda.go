@@ -0,0 +1,161 @@
+package encrypted
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DecryptDynamoDBEvent decrypts the new and old images of every record of a DynamoDB stream event received by a
+// Lambda function, in place, so handlers read plaintext attributes from the event they were given. The table of each
+// record is taken from its event source ARN. Keys are never encrypted and are left as they are. When a record fails,
+// the error names its event ID and the records before it have already been decrypted.
+func DecryptDynamoDBEvent(ctx context.Context, decryptor ItemDecryptor, event *events.DynamoDBEvent) error {
+	for i := range event.Records {
+		record := &event.Records[i]
+		if err := decryptEventRecord(ctx, decryptor, record); err != nil {
+			return fmt.Errorf("record %s: %w", record.EventID, err)
+		}
+	}
+	return nil
+}
+
+// decryptEventRecord decrypts the images of a single Lambda stream record in place.
+func decryptEventRecord(ctx context.Context, decryptor ItemDecryptor, record *events.DynamoDBEventRecord) error {
+	tableName, err := streamTableName(record.EventSourceArn)
+	if err != nil {
+		return err
+	}
+	if record.Change.NewImage, err = decryptEventImage(ctx, decryptor, tableName, record.Change.NewImage); err != nil {
+		return fmt.Errorf("new image: %w", err)
+	}
+	if record.Change.OldImage, err = decryptEventImage(ctx, decryptor, tableName, record.Change.OldImage); err != nil {
+		return fmt.Errorf("old image: %w", err)
+	}
+	return nil
+}
+
+// decryptEventImage converts an image to SDK attribute values, decrypts it and converts it back. Missing images stay
+// nil.
+func decryptEventImage(ctx context.Context, decryptor ItemDecryptor, tableName string, image map[string]events.DynamoDBAttributeValue) (map[string]events.DynamoDBAttributeValue, error) {
+	if image == nil {
+		return nil, nil
+	}
+	item, err := fromEventItem(image)
+	if err != nil {
+		return nil, err
+	}
+	decrypted, err := decryptor.DecryptItem(ctx, tableName, item)
+	if err != nil {
+		return nil, err
+	}
+	return toEventItem(decrypted)
+}
+
+// fromEventItem converts an item of a Lambda event to SDK attribute values.
+func fromEventItem(image map[string]events.DynamoDBAttributeValue) (map[string]types.AttributeValue, error) {
+	item := make(map[string]types.AttributeValue, len(image))
+	for name, value := range image {
+		converted, err := fromEventValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %w", name, err)
+		}
+		item[name] = converted
+	}
+	return item, nil
+}
+
+// fromEventValue converts an attribute value of a Lambda event to an SDK attribute value.
+func fromEventValue(value events.DynamoDBAttributeValue) (types.AttributeValue, error) {
+	switch value.DataType() {
+	case events.DataTypeBinary:
+		return &types.AttributeValueMemberB{Value: value.Binary()}, nil
+	case events.DataTypeBoolean:
+		return &types.AttributeValueMemberBOOL{Value: value.Boolean()}, nil
+	case events.DataTypeBinarySet:
+		return &types.AttributeValueMemberBS{Value: value.BinarySet()}, nil
+	case events.DataTypeList:
+		list := make([]types.AttributeValue, 0, len(value.List()))
+		for _, element := range value.List() {
+			converted, err := fromEventValue(element)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, converted)
+		}
+		return &types.AttributeValueMemberL{Value: list}, nil
+	case events.DataTypeMap:
+		entries, err := fromEventItem(value.Map())
+		if err != nil {
+			return nil, err
+		}
+		return &types.AttributeValueMemberM{Value: entries}, nil
+	case events.DataTypeNumber:
+		return &types.AttributeValueMemberN{Value: value.Number()}, nil
+	case events.DataTypeNumberSet:
+		return &types.AttributeValueMemberNS{Value: value.NumberSet()}, nil
+	case events.DataTypeNull:
+		return &types.AttributeValueMemberNULL{Value: true}, nil
+	case events.DataTypeString:
+		return &types.AttributeValueMemberS{Value: value.String()}, nil
+	case events.DataTypeStringSet:
+		return &types.AttributeValueMemberSS{Value: value.StringSet()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported attribute data type %d", value.DataType())
+	}
+}
+
+// toEventItem converts an item of SDK attribute values to the attribute values of Lambda events.
+func toEventItem(item map[string]types.AttributeValue) (map[string]events.DynamoDBAttributeValue, error) {
+	image := make(map[string]events.DynamoDBAttributeValue, len(item))
+	for name, value := range item {
+		converted, err := toEventValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %w", name, err)
+		}
+		image[name] = converted
+	}
+	return image, nil
+}
+
+// toEventValue converts an SDK attribute value to an attribute value of Lambda events.
+func toEventValue(value types.AttributeValue) (events.DynamoDBAttributeValue, error) {
+	switch v := value.(type) {
+	case *types.AttributeValueMemberB:
+		return events.NewBinaryAttribute(v.Value), nil
+	case *types.AttributeValueMemberBOOL:
+		return events.NewBooleanAttribute(v.Value), nil
+	case *types.AttributeValueMemberBS:
+		return events.NewBinarySetAttribute(v.Value), nil
+	case *types.AttributeValueMemberL:
+		list := make([]events.DynamoDBAttributeValue, 0, len(v.Value))
+		for _, element := range v.Value {
+			converted, err := toEventValue(element)
+			if err != nil {
+				return events.DynamoDBAttributeValue{}, err
+			}
+			list = append(list, converted)
+		}
+		return events.NewListAttribute(list), nil
+	case *types.AttributeValueMemberM:
+		entries, err := toEventItem(v.Value)
+		if err != nil {
+			return events.DynamoDBAttributeValue{}, err
+		}
+		return events.NewMapAttribute(entries), nil
+	case *types.AttributeValueMemberN:
+		return events.NewNumberAttribute(v.Value), nil
+	case *types.AttributeValueMemberNS:
+		return events.NewNumberSetAttribute(v.Value), nil
+	case *types.AttributeValueMemberNULL:
+		return events.NewNullAttribute(), nil
+	case *types.AttributeValueMemberS:
+		return events.NewStringAttribute(v.Value), nil
+	case *types.AttributeValueMemberSS:
+		return events.NewStringSetAttribute(v.Value), nil
+	default:
+		return events.DynamoDBAttributeValue{}, fmt.Errorf("unsupported attribute value type %T", value)
+	}
+}
@@ -0,0 +1,110 @@
+package encrypted
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestDecryptDynamoDBEvent(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	ec := NewEncryptedClient(nil, &staticProvider{description: map[string]string{}, dataKey: dataKey}, WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}))
+
+	item := map[string]types.AttributeValue{
+		"ID":     &types.AttributeValueMemberS{Value: "1"},
+		"Email":  &types.AttributeValueMemberS{Value: "alice@example.com"},
+		"Active": &types.AttributeValueMemberBOOL{Value: true},
+		"Avatar": &types.AttributeValueMemberB{Value: []byte{1, 2, 3}},
+		"Tags":   &types.AttributeValueMemberSS{Value: []string{"a", "b"}},
+		"Scores": &types.AttributeValueMemberNS{Value: []string{"1", "2.5"}},
+		"Keys":   &types.AttributeValueMemberBS{Value: [][]byte{{4}, {5}}},
+		"Nick":   &types.AttributeValueMemberNULL{Value: true},
+		"Address": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"City":  &types.AttributeValueMemberS{Value: "London"},
+			"Lines": &types.AttributeValueMemberL{Value: []types.AttributeValue{&types.AttributeValueMemberS{Value: "1 Road"}, &types.AttributeValueMemberN{Value: "7"}}},
+		}},
+	}
+	encryptedItem, err := ec.EncryptItem(context.Background(), "users", item)
+	if err != nil {
+		t.Fatalf("failed to encrypt item: %v", err)
+	}
+	encryptedImage, err := toEventItem(encryptedItem)
+	if err != nil {
+		t.Fatalf("failed to convert item: %v", err)
+	}
+	plaintextImage, err := toEventItem(item)
+	if err != nil {
+		t.Fatalf("failed to convert item: %v", err)
+	}
+	keys := map[string]events.DynamoDBAttributeValue{"ID": events.NewStringAttribute("1")}
+	arn := "arn:aws:dynamodb:eu-west-2:123456789012:table/users/stream/2024-01-01T00:00:00.000"
+
+	event := &events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{
+		{EventID: "1", EventName: "INSERT", EventSourceArn: arn, Change: events.DynamoDBStreamRecord{Keys: keys, NewImage: encryptedImage}},
+		{EventID: "2", EventName: "MODIFY", EventSourceArn: arn, Change: events.DynamoDBStreamRecord{Keys: keys, NewImage: encryptedImage, OldImage: encryptedImage}},
+		{EventID: "3", EventName: "REMOVE", EventSourceArn: arn, Change: events.DynamoDBStreamRecord{Keys: keys, OldImage: encryptedImage}},
+	}}
+	if err := DecryptDynamoDBEvent(context.Background(), ec, event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := &events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{
+		{EventID: "1", EventName: "INSERT", EventSourceArn: arn, Change: events.DynamoDBStreamRecord{Keys: keys, NewImage: plaintextImage}},
+		{EventID: "2", EventName: "MODIFY", EventSourceArn: arn, Change: events.DynamoDBStreamRecord{Keys: keys, NewImage: plaintextImage, OldImage: plaintextImage}},
+		{EventID: "3", EventName: "REMOVE", EventSourceArn: arn, Change: events.DynamoDBStreamRecord{Keys: keys, OldImage: plaintextImage}},
+	}}
+	if diff := cmp.Diff(want, event, cmp.AllowUnexported(events.DynamoDBAttributeValue{})); diff != "" {
+		t.Errorf("unexpected event (-want +got):\n%s", diff)
+	}
+
+	other := &events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{
+		{EventID: "4", EventSourceArn: "arn:aws:sqs:eu-west-2:123456789012:queue", Change: events.DynamoDBStreamRecord{NewImage: encryptedImage}},
+	}}
+	if err := DecryptDynamoDBEvent(context.Background(), ec, other); err == nil {
+		t.Errorf("expected an error for a record from another event source")
+	}
+}
+
+func TestEventItemConversion(t *testing.T) {
+	item := map[string]types.AttributeValue{
+		"S":    &types.AttributeValueMemberS{Value: "value"},
+		"N":    &types.AttributeValueMemberN{Value: "1.5"},
+		"B":    &types.AttributeValueMemberB{Value: []byte{1}},
+		"BOOL": &types.AttributeValueMemberBOOL{Value: false},
+		"NULL": &types.AttributeValueMemberNULL{Value: true},
+		"SS":   &types.AttributeValueMemberSS{Value: []string{"a"}},
+		"NS":   &types.AttributeValueMemberNS{Value: []string{"1"}},
+		"BS":   &types.AttributeValueMemberBS{Value: [][]byte{{2}}},
+		"L":    &types.AttributeValueMemberL{Value: []types.AttributeValue{&types.AttributeValueMemberS{Value: "element"}}},
+		"M":    &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"Nested": &types.AttributeValueMemberN{Value: "2"}}},
+	}
+
+	image, err := toEventItem(item)
+	if err != nil {
+		t.Fatalf("failed to convert to event item: %v", err)
+	}
+	got, err := fromEventItem(image)
+	if err != nil {
+		t.Fatalf("failed to convert from event item: %v", err)
+	}
+	ignore := cmpopts.IgnoreUnexported(
+		types.AttributeValueMemberS{}, types.AttributeValueMemberN{}, types.AttributeValueMemberB{}, types.AttributeValueMemberBOOL{},
+		types.AttributeValueMemberNULL{}, types.AttributeValueMemberSS{}, types.AttributeValueMemberNS{}, types.AttributeValueMemberBS{},
+		types.AttributeValueMemberL{}, types.AttributeValueMemberM{},
+	)
+	if diff := cmp.Diff(item, got, ignore); diff != "" {
+		t.Errorf("unexpected item (-want +got):\n%s", diff)
+	}
+}
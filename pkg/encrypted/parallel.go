@@ -0,0 +1,168 @@
+package encrypted
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// DefaultScanSegments is the number of segments ParallelScan divides a table into unless WithScanSegments is given.
+const DefaultScanSegments = 8
+
+// maxScanSegments is the largest TotalSegments DynamoDB accepts.
+const maxScanSegments = 1000000
+
+// parallelScan holds the settings of ParallelScan.
+type parallelScan struct {
+	segments int
+	workers  int
+}
+
+// ParallelScanOption configures a single ParallelScan.
+type ParallelScanOption func(*parallelScan)
+
+// WithScanSegments sets the number of segments the table is scanned in, the TotalSegments of every Scan request.
+// Segments are not equally large, so more segments than workers keep the workers busy until the end of the scan.
+func WithScanSegments(segments int) ParallelScanOption {
+	return func(s *parallelScan) {
+		s.segments = segments
+	}
+}
+
+// WithScanWorkers bounds the number of segments scanned, and decrypted, at once. Zero, the default, scans every
+// segment at once.
+func WithScanWorkers(workers int) ParallelScanOption {
+	return func(s *parallelScan) {
+		s.workers = workers
+	}
+}
+
+// ScanResult is a decrypted item sent by ParallelScanItems, or the error that ended the scan.
+type ScanResult struct {
+	Item map[string]types.AttributeValue
+	Err  error
+}
+
+// ParallelScan scans a table, or an index, in segments read concurrently, and calls fn with every decrypted item that
+// the result pipeline keeps. Each worker pages through one segment at a time, setting the Segment and TotalSegments
+// of a copy of input, and decrypts its items itself, so decryption is spread across workers too. fn is called from
+// the workers' goroutines and must be safe for concurrent use; items are passed in no particular order.
+//
+// The first error, returned by fn or by the scan or decryption of a segment, cancels the context passed to fn and
+// the other segments, and is returned once every worker has stopped.
+func (ec *EncryptedClient) ParallelScan(ctx context.Context, input *dynamodb.ScanInput, fn func(ctx context.Context, item map[string]types.AttributeValue) error, opts ...ParallelScanOption) error {
+	ctx = operationContext(ctx, "ParallelScan")
+	settings := parallelScan{segments: DefaultScanSegments}
+	for _, opt := range opts {
+		opt(&settings)
+	}
+	if settings.segments < 1 || settings.segments > maxScanSegments {
+		return fmt.Errorf("scan segments must be between 1 and %d, got %d", maxScanSegments, settings.segments)
+	}
+	if settings.workers < 0 {
+		return fmt.Errorf("scan workers can not be negative, got %d", settings.workers)
+	}
+	if settings.workers == 0 || settings.workers > settings.segments {
+		settings.workers = settings.segments
+	}
+	tableName := aws.StringValue(input.TableName)
+	if err := ec.checkIndex(ctx, tableName, input.IndexName, input.ProjectionExpression, input.ExpressionAttributeNames); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	segments := make(chan int, settings.segments)
+	for segment := 0; segment < settings.segments; segment++ {
+		segments <- segment
+	}
+	close(segments)
+
+	var (
+		wg       sync.WaitGroup
+		failOnce sync.Once
+		firstErr error
+	)
+	for worker := 0; worker < settings.workers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for segment := range segments {
+				if ctx.Err() != nil {
+					return
+				}
+				if err := ec.scanSegment(ctx, tableName, input, segment, settings.segments, fn); err != nil {
+					failOnce.Do(func() {
+						firstErr = fmt.Errorf("segment %d: %w", segment, err)
+						cancel()
+					})
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// scanSegment pages through one segment of a parallel scan, passing its decrypted items to fn.
+func (ec *EncryptedClient) scanSegment(ctx context.Context, tableName string, input *dynamodb.ScanInput, segment, totalSegments int, fn func(context.Context, map[string]types.AttributeValue) error) error {
+	segmentInput := *input
+	segmentInput.Segment = aws.Int32(int32(segment))
+	segmentInput.TotalSegments = aws.Int32(int32(totalSegments))
+
+	paginator := dynamodb.NewScanPaginator(ec.Client, &segmentInput)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("error scanning encrypted items: %w", err)
+		}
+		for _, item := range output.Items {
+			decryptedItem, err := ec.decryptResult(ctx, tableName, item)
+			if err != nil {
+				return err
+			}
+			if decryptedItem == nil {
+				continue
+			}
+			if err := fn(ctx, decryptedItem); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ParallelScanItems runs ParallelScan in the background and sends the decrypted items on the returned channel, which
+// is closed when the scan ends. A failed scan sends its error as the last result. The caller must receive until the
+// channel is closed, or cancel ctx to stop the scan early; results of a canceled scan, including its error, may not
+// be sent.
+func (ec *EncryptedClient) ParallelScanItems(ctx context.Context, input *dynamodb.ScanInput, opts ...ParallelScanOption) <-chan ScanResult {
+	results := make(chan ScanResult)
+	go func() {
+		defer close(results)
+		err := ec.ParallelScan(ctx, input, func(ctx context.Context, item map[string]types.AttributeValue) error {
+			select {
+			case results <- ScanResult{Item: item}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}, opts...)
+		if err != nil {
+			select {
+			case results <- ScanResult{Err: err}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return results
+}
@@ -0,0 +1,167 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/google/go-cmp/cmp"
+)
+
+// segmentClient serves the items of a parallel scan, one item per page, assigning item i to segment
+// i % TotalSegments. It records the largest number of Scan calls in flight.
+type segmentClient struct {
+	DynamoDBClientInterface
+	items []map[string]types.AttributeValue
+
+	active    atomic.Int32
+	maxActive atomic.Int32
+}
+
+func (c *segmentClient) Scan(ctx context.Context, input *dynamodb.ScanInput, opts ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	active := c.active.Add(1)
+	defer c.active.Add(-1)
+	for {
+		current := c.maxActive.Load()
+		if active <= current || c.maxActive.CompareAndSwap(current, active) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+
+	segment, total := int(aws.Int32Value(input.Segment)), int(aws.Int32Value(input.TotalSegments))
+	start := segment
+	if input.ExclusiveStartKey != nil {
+		last, _ := strconv.Atoi(input.ExclusiveStartKey["ID"].(*types.AttributeValueMemberS).Value)
+		start = last + total
+	}
+	output := &dynamodb.ScanOutput{}
+	if start < len(c.items) {
+		output.Items = []map[string]types.AttributeValue{c.items[start]}
+		if start+total < len(c.items) {
+			output.LastEvaluatedKey = map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: strconv.Itoa(start)}}
+		}
+	}
+	return output, nil
+}
+
+func TestEncryptedClient_ParallelScan(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	client := &segmentClient{}
+	ec := NewEncryptedClient(client, &staticProvider{description: map[string]string{}, dataKey: dataKey},
+		WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}))
+
+	var want []string
+	for i := 0; i < 20; i++ {
+		item, err := ec.EncryptItem(context.Background(), "users", map[string]types.AttributeValue{
+			"ID":     &types.AttributeValueMemberS{Value: strconv.Itoa(i)},
+			"Secret": &types.AttributeValueMemberS{Value: "secret " + strconv.Itoa(i)},
+		})
+		if err != nil {
+			t.Fatalf("failed to encrypt item: %v", err)
+		}
+		client.items = append(client.items, item)
+		want = append(want, "secret "+strconv.Itoa(i))
+	}
+	sort.Strings(want)
+
+	testCases := []struct {
+		name       string
+		opts       []ParallelScanOption
+		maxWorkers int32
+		err        bool
+	}{
+		{name: "Default segments", maxWorkers: DefaultScanSegments},
+		{name: "Bounded workers", opts: []ParallelScanOption{WithScanSegments(6), WithScanWorkers(2)}, maxWorkers: 2},
+		{name: "More segments than items", opts: []ParallelScanOption{WithScanSegments(32)}, maxWorkers: 32},
+		{name: "No segments", opts: []ParallelScanOption{WithScanSegments(0)}, err: true},
+		{name: "Negative workers", opts: []ParallelScanOption{WithScanWorkers(-1)}, err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client.maxActive.Store(0)
+			var (
+				mu  sync.Mutex
+				got []string
+			)
+			err := ec.ParallelScan(context.Background(), &dynamodb.ScanInput{TableName: aws.String("users")}, func(ctx context.Context, item map[string]types.AttributeValue) error {
+				mu.Lock()
+				defer mu.Unlock()
+				got = append(got, item["Secret"].(*types.AttributeValueMemberS).Value)
+				return nil
+			}, tc.opts...)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			sort.Strings(got)
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("unexpected items (-want +got):\n%s", diff)
+			}
+			if maxActive := client.maxActive.Load(); maxActive > tc.maxWorkers {
+				t.Errorf("expected at most %d concurrent scans, got %d", tc.maxWorkers, maxActive)
+			}
+		})
+	}
+
+	t.Run("Callback error", func(t *testing.T) {
+		errStop := errors.New("stop")
+		var calls atomic.Int32
+		err := ec.ParallelScan(context.Background(), &dynamodb.ScanInput{TableName: aws.String("users")}, func(ctx context.Context, item map[string]types.AttributeValue) error {
+			calls.Add(1)
+			return errStop
+		}, WithScanSegments(4), WithScanWorkers(1))
+		if !errors.Is(err, errStop) {
+			t.Errorf("expected the callback's error, got %v", err)
+		}
+		if calls.Load() != 1 {
+			t.Errorf("expected the scan to stop after the first error, got %d calls", calls.Load())
+		}
+	})
+
+	t.Run("Channel", func(t *testing.T) {
+		var got []string
+		for result := range ec.ParallelScanItems(context.Background(), &dynamodb.ScanInput{TableName: aws.String("users")}, WithScanSegments(3)) {
+			if result.Err != nil {
+				t.Fatalf("unexpected error: %v", result.Err)
+			}
+			got = append(got, result.Item["Secret"].(*types.AttributeValueMemberS).Value)
+		}
+		sort.Strings(got)
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("unexpected items (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Channel error", func(t *testing.T) {
+		var results []ScanResult
+		for result := range ec.ParallelScanItems(context.Background(), &dynamodb.ScanInput{TableName: aws.String("users")}, WithScanSegments(0)) {
+			results = append(results, result)
+		}
+		if len(results) != 1 || results[0].Err == nil {
+			t.Errorf("expected a single error result, got %v", results)
+		}
+	})
+}
@@ -0,0 +1,511 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// ErrOperationNotSupported is returned by a passthrough operation when the underlying client does not implement it.
+var ErrOperationNotSupported = errors.New("operation not supported by the underlying DynamoDB client")
+
+// ErrStatementsNotSupported is returned by the PartiQL operations. Statements embed attribute values in their text,
+// which the client can not encrypt or decrypt reliably.
+var ErrStatementsNotSupported = errors.New("PartiQL statements would bypass encryption and are not supported")
+
+// ErrImportNotSupported is returned by ImportTable. Imports write items from S3 straight into a new table, without
+// encryption or signing.
+var ErrImportNotSupported = errors.New("ImportTable would write items without encryption and is not supported")
+
+// The operations below do not read or write item attributes, so they are passed through to the
+// underlying client unchanged. PartiQL statements carry item data and fail with ErrStatementsNotSupported, and
+// ImportTable fails with ErrImportNotSupported, since passing them through would bypass encryption. UpdateItem,
+// TransactGetItems and TransactWriteItems are handled in transact.go.
+
+// Options returns the options of the underlying DynamoDB client, or zero options when it does not expose them.
+func (ec *EncryptedClient) Options() dynamodb.Options {
+	client, ok := ec.Client.(interface{ Options() dynamodb.Options })
+	if !ok {
+		return dynamodb.Options{}
+	}
+	return client.Options()
+}
+
+// BatchExecuteStatement fails with ErrStatementsNotSupported.
+func (ec *EncryptedClient) BatchExecuteStatement(ctx context.Context, params *dynamodb.BatchExecuteStatementInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchExecuteStatementOutput, error) {
+	return nil, fmt.Errorf("%w: BatchExecuteStatement", ErrStatementsNotSupported)
+}
+
+// ExecuteStatement fails with ErrStatementsNotSupported.
+func (ec *EncryptedClient) ExecuteStatement(ctx context.Context, params *dynamodb.ExecuteStatementInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ExecuteStatementOutput, error) {
+	return nil, fmt.Errorf("%w: ExecuteStatement", ErrStatementsNotSupported)
+}
+
+// ExecuteTransaction fails with ErrStatementsNotSupported.
+func (ec *EncryptedClient) ExecuteTransaction(ctx context.Context, params *dynamodb.ExecuteTransactionInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ExecuteTransactionOutput, error) {
+	return nil, fmt.Errorf("%w: ExecuteTransaction", ErrStatementsNotSupported)
+}
+
+// CreateBackup passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) CreateBackup(ctx context.Context, params *dynamodb.CreateBackupInput, optFns ...func(*dynamodb.Options)) (*dynamodb.CreateBackupOutput, error) {
+	client, ok := ec.Client.(interface {
+		CreateBackup(context.Context, *dynamodb.CreateBackupInput, ...func(*dynamodb.Options)) (*dynamodb.CreateBackupOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: CreateBackup", ErrOperationNotSupported)
+	}
+	return client.CreateBackup(ctx, params, optFns...)
+}
+
+// CreateGlobalTable passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) CreateGlobalTable(ctx context.Context, params *dynamodb.CreateGlobalTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.CreateGlobalTableOutput, error) {
+	client, ok := ec.Client.(interface {
+		CreateGlobalTable(context.Context, *dynamodb.CreateGlobalTableInput, ...func(*dynamodb.Options)) (*dynamodb.CreateGlobalTableOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: CreateGlobalTable", ErrOperationNotSupported)
+	}
+	return client.CreateGlobalTable(ctx, params, optFns...)
+}
+
+// DeleteBackup passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) DeleteBackup(ctx context.Context, params *dynamodb.DeleteBackupInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteBackupOutput, error) {
+	client, ok := ec.Client.(interface {
+		DeleteBackup(context.Context, *dynamodb.DeleteBackupInput, ...func(*dynamodb.Options)) (*dynamodb.DeleteBackupOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: DeleteBackup", ErrOperationNotSupported)
+	}
+	return client.DeleteBackup(ctx, params, optFns...)
+}
+
+// DeleteResourcePolicy passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) DeleteResourcePolicy(ctx context.Context, params *dynamodb.DeleteResourcePolicyInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteResourcePolicyOutput, error) {
+	client, ok := ec.Client.(interface {
+		DeleteResourcePolicy(context.Context, *dynamodb.DeleteResourcePolicyInput, ...func(*dynamodb.Options)) (*dynamodb.DeleteResourcePolicyOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: DeleteResourcePolicy", ErrOperationNotSupported)
+	}
+	return client.DeleteResourcePolicy(ctx, params, optFns...)
+}
+
+// DeleteTable passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) DeleteTable(ctx context.Context, params *dynamodb.DeleteTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteTableOutput, error) {
+	client, ok := ec.Client.(interface {
+		DeleteTable(context.Context, *dynamodb.DeleteTableInput, ...func(*dynamodb.Options)) (*dynamodb.DeleteTableOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: DeleteTable", ErrOperationNotSupported)
+	}
+	return client.DeleteTable(ctx, params, optFns...)
+}
+
+// DescribeBackup passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) DescribeBackup(ctx context.Context, params *dynamodb.DescribeBackupInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeBackupOutput, error) {
+	client, ok := ec.Client.(interface {
+		DescribeBackup(context.Context, *dynamodb.DescribeBackupInput, ...func(*dynamodb.Options)) (*dynamodb.DescribeBackupOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: DescribeBackup", ErrOperationNotSupported)
+	}
+	return client.DescribeBackup(ctx, params, optFns...)
+}
+
+// DescribeContinuousBackups passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) DescribeContinuousBackups(ctx context.Context, params *dynamodb.DescribeContinuousBackupsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeContinuousBackupsOutput, error) {
+	client, ok := ec.Client.(interface {
+		DescribeContinuousBackups(context.Context, *dynamodb.DescribeContinuousBackupsInput, ...func(*dynamodb.Options)) (*dynamodb.DescribeContinuousBackupsOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: DescribeContinuousBackups", ErrOperationNotSupported)
+	}
+	return client.DescribeContinuousBackups(ctx, params, optFns...)
+}
+
+// DescribeContributorInsights passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) DescribeContributorInsights(ctx context.Context, params *dynamodb.DescribeContributorInsightsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeContributorInsightsOutput, error) {
+	client, ok := ec.Client.(interface {
+		DescribeContributorInsights(context.Context, *dynamodb.DescribeContributorInsightsInput, ...func(*dynamodb.Options)) (*dynamodb.DescribeContributorInsightsOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: DescribeContributorInsights", ErrOperationNotSupported)
+	}
+	return client.DescribeContributorInsights(ctx, params, optFns...)
+}
+
+// DescribeEndpoints passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) DescribeEndpoints(ctx context.Context, params *dynamodb.DescribeEndpointsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeEndpointsOutput, error) {
+	client, ok := ec.Client.(interface {
+		DescribeEndpoints(context.Context, *dynamodb.DescribeEndpointsInput, ...func(*dynamodb.Options)) (*dynamodb.DescribeEndpointsOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: DescribeEndpoints", ErrOperationNotSupported)
+	}
+	return client.DescribeEndpoints(ctx, params, optFns...)
+}
+
+// DescribeExport passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) DescribeExport(ctx context.Context, params *dynamodb.DescribeExportInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeExportOutput, error) {
+	client, ok := ec.Client.(interface {
+		DescribeExport(context.Context, *dynamodb.DescribeExportInput, ...func(*dynamodb.Options)) (*dynamodb.DescribeExportOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: DescribeExport", ErrOperationNotSupported)
+	}
+	return client.DescribeExport(ctx, params, optFns...)
+}
+
+// DescribeGlobalTable passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) DescribeGlobalTable(ctx context.Context, params *dynamodb.DescribeGlobalTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeGlobalTableOutput, error) {
+	client, ok := ec.Client.(interface {
+		DescribeGlobalTable(context.Context, *dynamodb.DescribeGlobalTableInput, ...func(*dynamodb.Options)) (*dynamodb.DescribeGlobalTableOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: DescribeGlobalTable", ErrOperationNotSupported)
+	}
+	return client.DescribeGlobalTable(ctx, params, optFns...)
+}
+
+// DescribeGlobalTableSettings passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) DescribeGlobalTableSettings(ctx context.Context, params *dynamodb.DescribeGlobalTableSettingsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeGlobalTableSettingsOutput, error) {
+	client, ok := ec.Client.(interface {
+		DescribeGlobalTableSettings(context.Context, *dynamodb.DescribeGlobalTableSettingsInput, ...func(*dynamodb.Options)) (*dynamodb.DescribeGlobalTableSettingsOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: DescribeGlobalTableSettings", ErrOperationNotSupported)
+	}
+	return client.DescribeGlobalTableSettings(ctx, params, optFns...)
+}
+
+// DescribeImport passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) DescribeImport(ctx context.Context, params *dynamodb.DescribeImportInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeImportOutput, error) {
+	client, ok := ec.Client.(interface {
+		DescribeImport(context.Context, *dynamodb.DescribeImportInput, ...func(*dynamodb.Options)) (*dynamodb.DescribeImportOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: DescribeImport", ErrOperationNotSupported)
+	}
+	return client.DescribeImport(ctx, params, optFns...)
+}
+
+// DescribeKinesisStreamingDestination passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) DescribeKinesisStreamingDestination(ctx context.Context, params *dynamodb.DescribeKinesisStreamingDestinationInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeKinesisStreamingDestinationOutput, error) {
+	client, ok := ec.Client.(interface {
+		DescribeKinesisStreamingDestination(context.Context, *dynamodb.DescribeKinesisStreamingDestinationInput, ...func(*dynamodb.Options)) (*dynamodb.DescribeKinesisStreamingDestinationOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: DescribeKinesisStreamingDestination", ErrOperationNotSupported)
+	}
+	return client.DescribeKinesisStreamingDestination(ctx, params, optFns...)
+}
+
+// DescribeLimits passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) DescribeLimits(ctx context.Context, params *dynamodb.DescribeLimitsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeLimitsOutput, error) {
+	client, ok := ec.Client.(interface {
+		DescribeLimits(context.Context, *dynamodb.DescribeLimitsInput, ...func(*dynamodb.Options)) (*dynamodb.DescribeLimitsOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: DescribeLimits", ErrOperationNotSupported)
+	}
+	return client.DescribeLimits(ctx, params, optFns...)
+}
+
+// DescribeTable passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	return ec.Client.DescribeTable(ctx, params, optFns...)
+}
+
+// DescribeTableReplicaAutoScaling passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) DescribeTableReplicaAutoScaling(ctx context.Context, params *dynamodb.DescribeTableReplicaAutoScalingInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableReplicaAutoScalingOutput, error) {
+	client, ok := ec.Client.(interface {
+		DescribeTableReplicaAutoScaling(context.Context, *dynamodb.DescribeTableReplicaAutoScalingInput, ...func(*dynamodb.Options)) (*dynamodb.DescribeTableReplicaAutoScalingOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: DescribeTableReplicaAutoScaling", ErrOperationNotSupported)
+	}
+	return client.DescribeTableReplicaAutoScaling(ctx, params, optFns...)
+}
+
+// DescribeTimeToLive passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) DescribeTimeToLive(ctx context.Context, params *dynamodb.DescribeTimeToLiveInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTimeToLiveOutput, error) {
+	client, ok := ec.Client.(interface {
+		DescribeTimeToLive(context.Context, *dynamodb.DescribeTimeToLiveInput, ...func(*dynamodb.Options)) (*dynamodb.DescribeTimeToLiveOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: DescribeTimeToLive", ErrOperationNotSupported)
+	}
+	return client.DescribeTimeToLive(ctx, params, optFns...)
+}
+
+// DisableKinesisStreamingDestination passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) DisableKinesisStreamingDestination(ctx context.Context, params *dynamodb.DisableKinesisStreamingDestinationInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DisableKinesisStreamingDestinationOutput, error) {
+	client, ok := ec.Client.(interface {
+		DisableKinesisStreamingDestination(context.Context, *dynamodb.DisableKinesisStreamingDestinationInput, ...func(*dynamodb.Options)) (*dynamodb.DisableKinesisStreamingDestinationOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: DisableKinesisStreamingDestination", ErrOperationNotSupported)
+	}
+	return client.DisableKinesisStreamingDestination(ctx, params, optFns...)
+}
+
+// EnableKinesisStreamingDestination passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) EnableKinesisStreamingDestination(ctx context.Context, params *dynamodb.EnableKinesisStreamingDestinationInput, optFns ...func(*dynamodb.Options)) (*dynamodb.EnableKinesisStreamingDestinationOutput, error) {
+	client, ok := ec.Client.(interface {
+		EnableKinesisStreamingDestination(context.Context, *dynamodb.EnableKinesisStreamingDestinationInput, ...func(*dynamodb.Options)) (*dynamodb.EnableKinesisStreamingDestinationOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: EnableKinesisStreamingDestination", ErrOperationNotSupported)
+	}
+	return client.EnableKinesisStreamingDestination(ctx, params, optFns...)
+}
+
+// ExportTableToPointInTime passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) ExportTableToPointInTime(ctx context.Context, params *dynamodb.ExportTableToPointInTimeInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ExportTableToPointInTimeOutput, error) {
+	client, ok := ec.Client.(interface {
+		ExportTableToPointInTime(context.Context, *dynamodb.ExportTableToPointInTimeInput, ...func(*dynamodb.Options)) (*dynamodb.ExportTableToPointInTimeOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: ExportTableToPointInTime", ErrOperationNotSupported)
+	}
+	return client.ExportTableToPointInTime(ctx, params, optFns...)
+}
+
+// GetResourcePolicy passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) GetResourcePolicy(ctx context.Context, params *dynamodb.GetResourcePolicyInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetResourcePolicyOutput, error) {
+	client, ok := ec.Client.(interface {
+		GetResourcePolicy(context.Context, *dynamodb.GetResourcePolicyInput, ...func(*dynamodb.Options)) (*dynamodb.GetResourcePolicyOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: GetResourcePolicy", ErrOperationNotSupported)
+	}
+	return client.GetResourcePolicy(ctx, params, optFns...)
+}
+
+// ImportTable fails with ErrImportNotSupported. Import plaintext data with the underlying client instead, then copy
+// it through the encrypted client, for example with a Scan and BatchWriteItem.
+func (ec *EncryptedClient) ImportTable(ctx context.Context, params *dynamodb.ImportTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ImportTableOutput, error) {
+	return nil, ErrImportNotSupported
+}
+
+// ListBackups passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) ListBackups(ctx context.Context, params *dynamodb.ListBackupsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ListBackupsOutput, error) {
+	client, ok := ec.Client.(interface {
+		ListBackups(context.Context, *dynamodb.ListBackupsInput, ...func(*dynamodb.Options)) (*dynamodb.ListBackupsOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: ListBackups", ErrOperationNotSupported)
+	}
+	return client.ListBackups(ctx, params, optFns...)
+}
+
+// ListContributorInsights passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) ListContributorInsights(ctx context.Context, params *dynamodb.ListContributorInsightsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ListContributorInsightsOutput, error) {
+	client, ok := ec.Client.(interface {
+		ListContributorInsights(context.Context, *dynamodb.ListContributorInsightsInput, ...func(*dynamodb.Options)) (*dynamodb.ListContributorInsightsOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: ListContributorInsights", ErrOperationNotSupported)
+	}
+	return client.ListContributorInsights(ctx, params, optFns...)
+}
+
+// ListExports passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) ListExports(ctx context.Context, params *dynamodb.ListExportsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ListExportsOutput, error) {
+	client, ok := ec.Client.(interface {
+		ListExports(context.Context, *dynamodb.ListExportsInput, ...func(*dynamodb.Options)) (*dynamodb.ListExportsOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: ListExports", ErrOperationNotSupported)
+	}
+	return client.ListExports(ctx, params, optFns...)
+}
+
+// ListGlobalTables passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) ListGlobalTables(ctx context.Context, params *dynamodb.ListGlobalTablesInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ListGlobalTablesOutput, error) {
+	client, ok := ec.Client.(interface {
+		ListGlobalTables(context.Context, *dynamodb.ListGlobalTablesInput, ...func(*dynamodb.Options)) (*dynamodb.ListGlobalTablesOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: ListGlobalTables", ErrOperationNotSupported)
+	}
+	return client.ListGlobalTables(ctx, params, optFns...)
+}
+
+// ListImports passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) ListImports(ctx context.Context, params *dynamodb.ListImportsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ListImportsOutput, error) {
+	client, ok := ec.Client.(interface {
+		ListImports(context.Context, *dynamodb.ListImportsInput, ...func(*dynamodb.Options)) (*dynamodb.ListImportsOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: ListImports", ErrOperationNotSupported)
+	}
+	return client.ListImports(ctx, params, optFns...)
+}
+
+// ListTables passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) ListTables(ctx context.Context, params *dynamodb.ListTablesInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ListTablesOutput, error) {
+	client, ok := ec.Client.(interface {
+		ListTables(context.Context, *dynamodb.ListTablesInput, ...func(*dynamodb.Options)) (*dynamodb.ListTablesOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: ListTables", ErrOperationNotSupported)
+	}
+	return client.ListTables(ctx, params, optFns...)
+}
+
+// ListTagsOfResource passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) ListTagsOfResource(ctx context.Context, params *dynamodb.ListTagsOfResourceInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ListTagsOfResourceOutput, error) {
+	client, ok := ec.Client.(interface {
+		ListTagsOfResource(context.Context, *dynamodb.ListTagsOfResourceInput, ...func(*dynamodb.Options)) (*dynamodb.ListTagsOfResourceOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: ListTagsOfResource", ErrOperationNotSupported)
+	}
+	return client.ListTagsOfResource(ctx, params, optFns...)
+}
+
+// PutResourcePolicy passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) PutResourcePolicy(ctx context.Context, params *dynamodb.PutResourcePolicyInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutResourcePolicyOutput, error) {
+	client, ok := ec.Client.(interface {
+		PutResourcePolicy(context.Context, *dynamodb.PutResourcePolicyInput, ...func(*dynamodb.Options)) (*dynamodb.PutResourcePolicyOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: PutResourcePolicy", ErrOperationNotSupported)
+	}
+	return client.PutResourcePolicy(ctx, params, optFns...)
+}
+
+// RestoreTableFromBackup passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) RestoreTableFromBackup(ctx context.Context, params *dynamodb.RestoreTableFromBackupInput, optFns ...func(*dynamodb.Options)) (*dynamodb.RestoreTableFromBackupOutput, error) {
+	client, ok := ec.Client.(interface {
+		RestoreTableFromBackup(context.Context, *dynamodb.RestoreTableFromBackupInput, ...func(*dynamodb.Options)) (*dynamodb.RestoreTableFromBackupOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: RestoreTableFromBackup", ErrOperationNotSupported)
+	}
+	return client.RestoreTableFromBackup(ctx, params, optFns...)
+}
+
+// RestoreTableToPointInTime passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) RestoreTableToPointInTime(ctx context.Context, params *dynamodb.RestoreTableToPointInTimeInput, optFns ...func(*dynamodb.Options)) (*dynamodb.RestoreTableToPointInTimeOutput, error) {
+	client, ok := ec.Client.(interface {
+		RestoreTableToPointInTime(context.Context, *dynamodb.RestoreTableToPointInTimeInput, ...func(*dynamodb.Options)) (*dynamodb.RestoreTableToPointInTimeOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: RestoreTableToPointInTime", ErrOperationNotSupported)
+	}
+	return client.RestoreTableToPointInTime(ctx, params, optFns...)
+}
+
+// TagResource passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) TagResource(ctx context.Context, params *dynamodb.TagResourceInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TagResourceOutput, error) {
+	client, ok := ec.Client.(interface {
+		TagResource(context.Context, *dynamodb.TagResourceInput, ...func(*dynamodb.Options)) (*dynamodb.TagResourceOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: TagResource", ErrOperationNotSupported)
+	}
+	return client.TagResource(ctx, params, optFns...)
+}
+
+// UntagResource passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) UntagResource(ctx context.Context, params *dynamodb.UntagResourceInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UntagResourceOutput, error) {
+	client, ok := ec.Client.(interface {
+		UntagResource(context.Context, *dynamodb.UntagResourceInput, ...func(*dynamodb.Options)) (*dynamodb.UntagResourceOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: UntagResource", ErrOperationNotSupported)
+	}
+	return client.UntagResource(ctx, params, optFns...)
+}
+
+// UpdateContinuousBackups passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) UpdateContinuousBackups(ctx context.Context, params *dynamodb.UpdateContinuousBackupsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateContinuousBackupsOutput, error) {
+	client, ok := ec.Client.(interface {
+		UpdateContinuousBackups(context.Context, *dynamodb.UpdateContinuousBackupsInput, ...func(*dynamodb.Options)) (*dynamodb.UpdateContinuousBackupsOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: UpdateContinuousBackups", ErrOperationNotSupported)
+	}
+	return client.UpdateContinuousBackups(ctx, params, optFns...)
+}
+
+// UpdateContributorInsights passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) UpdateContributorInsights(ctx context.Context, params *dynamodb.UpdateContributorInsightsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateContributorInsightsOutput, error) {
+	client, ok := ec.Client.(interface {
+		UpdateContributorInsights(context.Context, *dynamodb.UpdateContributorInsightsInput, ...func(*dynamodb.Options)) (*dynamodb.UpdateContributorInsightsOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: UpdateContributorInsights", ErrOperationNotSupported)
+	}
+	return client.UpdateContributorInsights(ctx, params, optFns...)
+}
+
+// UpdateGlobalTable passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) UpdateGlobalTable(ctx context.Context, params *dynamodb.UpdateGlobalTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateGlobalTableOutput, error) {
+	client, ok := ec.Client.(interface {
+		UpdateGlobalTable(context.Context, *dynamodb.UpdateGlobalTableInput, ...func(*dynamodb.Options)) (*dynamodb.UpdateGlobalTableOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: UpdateGlobalTable", ErrOperationNotSupported)
+	}
+	return client.UpdateGlobalTable(ctx, params, optFns...)
+}
+
+// UpdateGlobalTableSettings passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) UpdateGlobalTableSettings(ctx context.Context, params *dynamodb.UpdateGlobalTableSettingsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateGlobalTableSettingsOutput, error) {
+	client, ok := ec.Client.(interface {
+		UpdateGlobalTableSettings(context.Context, *dynamodb.UpdateGlobalTableSettingsInput, ...func(*dynamodb.Options)) (*dynamodb.UpdateGlobalTableSettingsOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: UpdateGlobalTableSettings", ErrOperationNotSupported)
+	}
+	return client.UpdateGlobalTableSettings(ctx, params, optFns...)
+}
+
+// UpdateKinesisStreamingDestination passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) UpdateKinesisStreamingDestination(ctx context.Context, params *dynamodb.UpdateKinesisStreamingDestinationInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateKinesisStreamingDestinationOutput, error) {
+	client, ok := ec.Client.(interface {
+		UpdateKinesisStreamingDestination(context.Context, *dynamodb.UpdateKinesisStreamingDestinationInput, ...func(*dynamodb.Options)) (*dynamodb.UpdateKinesisStreamingDestinationOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: UpdateKinesisStreamingDestination", ErrOperationNotSupported)
+	}
+	return client.UpdateKinesisStreamingDestination(ctx, params, optFns...)
+}
+
+// UpdateTable passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) UpdateTable(ctx context.Context, params *dynamodb.UpdateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error) {
+	client, ok := ec.Client.(interface {
+		UpdateTable(context.Context, *dynamodb.UpdateTableInput, ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: UpdateTable", ErrOperationNotSupported)
+	}
+	return client.UpdateTable(ctx, params, optFns...)
+}
+
+// UpdateTableReplicaAutoScaling passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) UpdateTableReplicaAutoScaling(ctx context.Context, params *dynamodb.UpdateTableReplicaAutoScalingInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableReplicaAutoScalingOutput, error) {
+	client, ok := ec.Client.(interface {
+		UpdateTableReplicaAutoScaling(context.Context, *dynamodb.UpdateTableReplicaAutoScalingInput, ...func(*dynamodb.Options)) (*dynamodb.UpdateTableReplicaAutoScalingOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: UpdateTableReplicaAutoScaling", ErrOperationNotSupported)
+	}
+	return client.UpdateTableReplicaAutoScaling(ctx, params, optFns...)
+}
+
+// UpdateTimeToLive passes the request through to the underlying DynamoDB client.
+func (ec *EncryptedClient) UpdateTimeToLive(ctx context.Context, params *dynamodb.UpdateTimeToLiveInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	client, ok := ec.Client.(interface {
+		UpdateTimeToLive(context.Context, *dynamodb.UpdateTimeToLiveInput, ...func(*dynamodb.Options)) (*dynamodb.UpdateTimeToLiveOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: UpdateTimeToLive", ErrOperationNotSupported)
+	}
+	return client.UpdateTimeToLive(ctx, params, optFns...)
+}
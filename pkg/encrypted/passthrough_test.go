@@ -0,0 +1,109 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// errForwarded is returned by the recording client for every operation that reaches it.
+var errForwarded = errors.New("forwarded")
+
+// recordingDynamoDB returns a DynamoDB client that records the input of every operation instead of sending it, and
+// fails it with errForwarded.
+func recordingDynamoDB(inputs *[]interface{}) *dynamodb.Client {
+	return dynamodb.New(dynamodb.Options{
+		Region: "us-east-1",
+		APIOptions: []func(*middleware.Stack) error{func(stack *middleware.Stack) error {
+			return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("record", func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				*inputs = append(*inputs, in.Parameters)
+				return middleware.InitializeOutput{}, middleware.Metadata{}, errForwarded
+			}), middleware.Before)
+		}},
+	})
+}
+
+// callOperation calls the named operation of ec with a new, empty input and returns the input and the error.
+func callOperation(t *testing.T, ec *EncryptedClient, operation string) (interface{}, error) {
+	t.Helper()
+	method := reflect.ValueOf(ec).MethodByName(operation)
+	if !method.IsValid() {
+		t.Fatalf("EncryptedClient has no method %s", operation)
+	}
+	input := reflect.New(method.Type().In(1).Elem())
+	results := method.Call([]reflect.Value{reflect.ValueOf(context.Background()), input})
+	err, _ := results[1].Interface().(error)
+	return input.Interface(), err
+}
+
+func TestPassthrough(t *testing.T) {
+	testCases := []struct {
+		operation string
+	}{
+		{"CreateBackup"}, {"CreateGlobalTable"}, {"DeleteBackup"}, {"DeleteResourcePolicy"}, {"DeleteTable"},
+		{"DescribeBackup"}, {"DescribeContinuousBackups"}, {"DescribeContributorInsights"}, {"DescribeEndpoints"},
+		{"DescribeExport"}, {"DescribeGlobalTable"}, {"DescribeGlobalTableSettings"}, {"DescribeImport"},
+		{"DescribeKinesisStreamingDestination"}, {"DescribeLimits"}, {"DescribeTable"},
+		{"DescribeTableReplicaAutoScaling"}, {"DescribeTimeToLive"}, {"DisableKinesisStreamingDestination"},
+		{"EnableKinesisStreamingDestination"}, {"ExportTableToPointInTime"}, {"GetResourcePolicy"}, {"ListBackups"},
+		{"ListContributorInsights"}, {"ListExports"}, {"ListGlobalTables"}, {"ListImports"}, {"ListTables"},
+		{"ListTagsOfResource"}, {"PutResourcePolicy"}, {"RestoreTableFromBackup"}, {"RestoreTableToPointInTime"},
+		{"TagResource"}, {"UntagResource"}, {"UpdateContinuousBackups"}, {"UpdateContributorInsights"},
+		{"UpdateGlobalTable"}, {"UpdateGlobalTableSettings"}, {"UpdateKinesisStreamingDestination"}, {"UpdateTable"},
+		{"UpdateTableReplicaAutoScaling"}, {"UpdateTimeToLive"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.operation, func(t *testing.T) {
+			var inputs []interface{}
+			ec := NewEncryptedClient(recordingDynamoDB(&inputs), &staticProvider{})
+
+			input, err := callOperation(t, ec, tc.operation)
+			if !errors.Is(err, errForwarded) {
+				t.Errorf("expected the error of the underlying client, got %v", err)
+			}
+			if len(inputs) != 1 || inputs[0] != input {
+				t.Errorf("expected the input to be forwarded unchanged, got %v", inputs)
+			}
+
+			// Clients without the operation fail instead of panicking
+			if tc.operation == "DescribeTable" {
+				return
+			}
+			ec = NewEncryptedClient(&unprocessedClient{}, &staticProvider{})
+			if _, err := callOperation(t, ec, tc.operation); !errors.Is(err, ErrOperationNotSupported) {
+				t.Errorf("expected ErrOperationNotSupported, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPassthrough_BypassingOperations(t *testing.T) {
+	testCases := []struct {
+		operation string
+		err       error
+	}{
+		{"ExecuteStatement", ErrStatementsNotSupported},
+		{"BatchExecuteStatement", ErrStatementsNotSupported},
+		{"ExecuteTransaction", ErrStatementsNotSupported},
+		{"ImportTable", ErrImportNotSupported},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.operation, func(t *testing.T) {
+			var inputs []interface{}
+			ec := NewEncryptedClient(recordingDynamoDB(&inputs), &staticProvider{})
+
+			if _, err := callOperation(t, ec, tc.operation); !errors.Is(err, tc.err) {
+				t.Errorf("expected %v, got %v", tc.err, err)
+			}
+			if len(inputs) != 0 {
+				t.Errorf("expected the operation not to reach the underlying client")
+			}
+		})
+	}
+}
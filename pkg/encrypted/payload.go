@@ -0,0 +1,116 @@
+package encrypted
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/ddbjson"
+)
+
+// ItemDecryptor decrypts items read from a table without calling DynamoDB for them. EncryptedClient implements it,
+// including scoped clients created with DecryptOnly.
+type ItemDecryptor interface {
+	DecryptItem(ctx context.Context, tableName string, item map[string]types.AttributeValue) (map[string]types.AttributeValue, error)
+}
+
+// DecryptPayloadItem decrypts an item embedded as DynamoDB JSON in an orchestration payload, such as the Item of a
+// Step Functions DynamoDB GetItem task result, so workflows can read protected attributes without calling back into
+// DynamoDB. The item may also be a JSON string holding the DynamoDB JSON, as produced by States.JsonToString.
+// ddbjson.MarshalItem encodes the decrypted item to pass it on in the payload.
+func DecryptPayloadItem(ctx context.Context, decryptor ItemDecryptor, tableName string, data json.RawMessage) (map[string]types.AttributeValue, error) {
+	item, err := payloadItem(data)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, nil
+	}
+	return decryptor.DecryptItem(ctx, tableName, item)
+}
+
+// eventBridgeEvent is an EventBridge event, of which only the detail is read.
+type eventBridgeEvent struct {
+	Detail json.RawMessage `json:"detail"`
+}
+
+// streamRecordJSON is a DynamoDB Streams record as EventBridge Pipes deliver it.
+type streamRecordJSON struct {
+	EventName      string `json:"eventName"`
+	EventSourceARN string `json:"eventSourceARN"`
+	UserIdentity   *struct {
+		PrincipalID string `json:"principalId"`
+	} `json:"userIdentity"`
+	DynamoDB struct {
+		Keys     json.RawMessage `json:"Keys"`
+		NewImage json.RawMessage `json:"NewImage"`
+		OldImage json.RawMessage `json:"OldImage"`
+	} `json:"dynamodb"`
+}
+
+// DecryptEventBridgeEvent parses a DynamoDB Streams record delivered by an EventBridge Pipe from a table's stream,
+// either as the detail of an EventBridge event or as the record itself, and decrypts its images. The table is taken
+// from the record's event source ARN.
+func DecryptEventBridgeEvent(ctx context.Context, decryptor ItemDecryptor, data []byte) (*StreamRecord, error) {
+	var event eventBridgeEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("failed to parse event: %v", err)
+	}
+	if len(event.Detail) > 0 {
+		data = event.Detail
+	}
+	var raw streamRecordJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse stream record: %v", err)
+	}
+
+	tableName, err := streamTableName(raw.EventSourceARN)
+	if err != nil {
+		return nil, err
+	}
+	record := &StreamRecord{TableName: tableName, EventName: raw.EventName}
+	if raw.UserIdentity != nil {
+		record.PrincipalID = raw.UserIdentity.PrincipalID
+	}
+	if record.Keys, err = payloadItem(raw.DynamoDB.Keys); err != nil {
+		return nil, fmt.Errorf("keys: %w", err)
+	}
+	if record.NewImage, err = DecryptPayloadItem(ctx, decryptor, tableName, raw.DynamoDB.NewImage); err != nil {
+		return nil, fmt.Errorf("new image: %w", err)
+	}
+	if record.OldImage, err = DecryptPayloadItem(ctx, decryptor, tableName, raw.DynamoDB.OldImage); err != nil {
+		return nil, fmt.Errorf("old image: %w", err)
+	}
+	return record, nil
+}
+
+// payloadItem decodes an item given as DynamoDB JSON, or as a JSON string holding it. Missing and null items decode
+// to nil.
+func payloadItem(data json.RawMessage) (map[string]types.AttributeValue, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+	if data[0] == '"' {
+		var encoded string
+		if err := json.Unmarshal(data, &encoded); err != nil {
+			return nil, fmt.Errorf("failed to decode DynamoDB JSON: %v", err)
+		}
+		data = []byte(encoded)
+	}
+	return ddbjson.UnmarshalItem(data)
+}
+
+// streamTableName returns the table of a stream from its ARN, such as
+// arn:aws:dynamodb:eu-west-2:123456789012:table/users/stream/2024-01-01T00:00:00.000.
+func streamTableName(arn string) (string, error) {
+	_, resource, ok := strings.Cut(arn, ":table/")
+	if !ok {
+		return "", fmt.Errorf("event source %q is not a DynamoDB table stream", arn)
+	}
+	tableName, _, _ := strings.Cut(resource, "/")
+	return tableName, nil
+}
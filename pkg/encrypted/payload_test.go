@@ -0,0 +1,111 @@
+package encrypted
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/ddbjson"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestDecryptPayloads(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	ec := NewEncryptedClient(nil, &staticProvider{description: map[string]string{}, dataKey: dataKey}, WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}))
+
+	item := map[string]types.AttributeValue{
+		"ID":    &types.AttributeValueMemberS{Value: "1"},
+		"Email": &types.AttributeValueMemberS{Value: "alice@example.com"},
+		"Age":   &types.AttributeValueMemberN{Value: "42"},
+	}
+	encryptedItem, err := ec.EncryptItem(context.Background(), "users", item)
+	if err != nil {
+		t.Fatalf("failed to encrypt item: %v", err)
+	}
+	itemJSON, err := ddbjson.MarshalItem(encryptedItem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	itemString, err := json.Marshal(string(itemJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := fmt.Sprintf(`{
+		"eventName": "MODIFY",
+		"eventSourceARN": "arn:aws:dynamodb:eu-west-2:123456789012:table/users/stream/2024-01-01T00:00:00.000",
+		"dynamodb": {"Keys": {"ID": {"S": "1"}}, "NewImage": %s, "OldImage": %s}
+	}`, itemJSON, itemString)
+	ignore := cmpopts.IgnoreUnexported(types.AttributeValueMemberS{}, types.AttributeValueMemberN{})
+
+	t.Run("Payload items", func(t *testing.T) {
+		testCases := []struct {
+			name string
+			data string
+			want map[string]types.AttributeValue
+		}{
+			{name: "DynamoDB JSON", data: string(itemJSON), want: item},
+			{name: "JSON string", data: string(itemString), want: item},
+			{name: "Null", data: "null"},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				got, err := DecryptPayloadItem(context.Background(), ec, "users", json.RawMessage(tc.data))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if diff := cmp.Diff(tc.want, got, ignore); diff != "" {
+					t.Errorf("unexpected item (-want +got):\n%s", diff)
+				}
+			})
+		}
+	})
+
+	t.Run("EventBridge events", func(t *testing.T) {
+		testCases := []struct {
+			name string
+			data string
+			err  bool
+		}{
+			{name: "Event", data: fmt.Sprintf(`{"source": "pipe", "detail-type": "change", "detail": %s}`, record)},
+			{name: "Pipe record", data: record},
+			{name: "Other event source", data: `{"detail": {"eventSourceARN": "arn:aws:sqs:eu-west-2:123456789012:queue"}}`, err: true},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				got, err := DecryptEventBridgeEvent(context.Background(), ec, []byte(tc.data))
+				if tc.err {
+					if err == nil {
+						t.Errorf("expected an error")
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				want := &StreamRecord{
+					TableName: "users",
+					EventName: "MODIFY",
+					Keys:      map[string]types.AttributeValue{"ID": item["ID"]},
+					NewImage:  item,
+					OldImage:  item,
+				}
+				if diff := cmp.Diff(want, got, ignore); diff != "" {
+					t.Errorf("unexpected record (-want +got):\n%s", diff)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package encrypted
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ResultStep is a step of the pipeline that post-processes Query and Scan results. It receives an item after it was
+// decrypted and decompressed, and after the steps registered before it, and returns the item to pass on. Returning
+// a nil item drops it from the results; returning an error fails the read.
+type ResultStep func(ctx context.Context, tableName string, item map[string]types.AttributeValue) (map[string]types.AttributeValue, error)
+
+// WithResultSteps appends steps to the client's result pipeline, which Query, Scan, QueryItems and ScanItems run
+// on every item they return, so services can for example redact fields without wrapping every call site. Unlike
+// post-decrypt hooks, which run on every decrypted item, steps only see query and scan results and can drop items.
+// QueryInto and ScanInto unmarshal the pipeline's output into Go values.
+func WithResultSteps(steps ...ResultStep) EncryptedClientOption {
+	return func(ec *EncryptedClient) {
+		ec.resultSteps = append(ec.resultSteps, steps...)
+	}
+}
+
+// RedactAttributes returns a ResultStep removing the named attributes from every item.
+func RedactAttributes(names ...string) ResultStep {
+	return func(ctx context.Context, tableName string, item map[string]types.AttributeValue) (map[string]types.AttributeValue, error) {
+		for _, name := range names {
+			delete(item, name)
+		}
+		return item, nil
+	}
+}
+
+// decryptResult decrypts an item read by a query or scan and runs the result pipeline on it. It returns a nil item
+// when a step dropped it.
+func (ec *EncryptedClient) decryptResult(ctx context.Context, tableName string, item map[string]types.AttributeValue) (map[string]types.AttributeValue, error) {
+	decryptedItem, err := ec.decryptItem(ctx, tableName, item)
+	if err != nil {
+		return nil, err
+	}
+	for _, step := range ec.resultSteps {
+		if decryptedItem, err = step(ctx, tableName, decryptedItem); err != nil {
+			return nil, err
+		}
+		if decryptedItem == nil {
+			return nil, nil
+		}
+	}
+	return decryptedItem, nil
+}
+
+// decryptResults decrypts a page of query or scan results and runs the result pipeline on them, leaving out
+// dropped items.
+func (ec *EncryptedClient) decryptResults(ctx context.Context, tableName string, items []map[string]types.AttributeValue) ([]map[string]types.AttributeValue, error) {
+	results := make([]map[string]types.AttributeValue, 0, len(items))
+	for _, item := range items {
+		decryptedItem, err := ec.decryptResult(ctx, tableName, item)
+		if err != nil {
+			return nil, err
+		}
+		if decryptedItem != nil {
+			results = append(results, decryptedItem)
+		}
+	}
+	return results, nil
+}
+
+// QueryInto runs Query, including the result pipeline, and unmarshals the resulting items into out, a pointer to
+// a slice of structs or maps.
+func (ec *EncryptedClient) QueryInto(ctx context.Context, input *dynamodb.QueryInput, out interface{}, optFns ...func(*dynamodb.Options)) error {
+	output, err := ec.Query(ctx, input, optFns...)
+	if err != nil {
+		return err
+	}
+	if err := attributevalue.UnmarshalListOfMaps(output.Items, out); err != nil {
+		return fmt.Errorf("failed to unmarshal items: %w", err)
+	}
+	return nil
+}
+
+// ScanInto runs Scan, including the result pipeline, and unmarshals the resulting items into out, a pointer to a
+// slice of structs or maps.
+func (ec *EncryptedClient) ScanInto(ctx context.Context, input *dynamodb.ScanInput, out interface{}, optFns ...func(*dynamodb.Options)) error {
+	output, err := ec.Scan(ctx, input, optFns...)
+	if err != nil {
+		return err
+	}
+	if err := attributevalue.UnmarshalListOfMaps(output.Items, out); err != nil {
+		return fmt.Errorf("failed to unmarshal items: %w", err)
+	}
+	return nil
+}
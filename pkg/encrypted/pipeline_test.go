@@ -0,0 +1,67 @@
+package encrypted
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResultSteps(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	materialsProvider := &staticProvider{dataKey: dataKey}
+
+	writer := NewEncryptedClient(nil, materialsProvider, WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}))
+	client := &scanClient{}
+	for _, user := range []struct{ id, email, status string }{
+		{"1", "a@example.com", "active"},
+		{"2", "b@example.com", "deleted"},
+		{"3", "c@example.com", "active"},
+	} {
+		item, err := writer.encryptItem(context.Background(), "users", map[string]types.AttributeValue{
+			"ID":     &types.AttributeValueMemberS{Value: user.id},
+			"Email":  &types.AttributeValueMemberS{Value: user.email},
+			"Status": &types.AttributeValueMemberS{Value: user.status},
+		})
+		if err != nil {
+			t.Fatalf("failed to encrypt item: %v", err)
+		}
+		client.items = append(client.items, item)
+	}
+
+	dropDeleted := func(ctx context.Context, tableName string, item map[string]types.AttributeValue) (map[string]types.AttributeValue, error) {
+		if status, ok := item["Status"].(*types.AttributeValueMemberS); ok && status.Value == "deleted" {
+			return nil, nil
+		}
+		return item, nil
+	}
+	reader := NewEncryptedClient(client, materialsProvider,
+		WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}),
+		WithResultSteps(dropDeleted, RedactAttributes("Email")),
+	)
+
+	type user struct {
+		ID     string
+		Email  string
+		Status string
+	}
+	var users []user
+	if err := reader.ScanInto(context.Background(), &dynamodb.ScanInput{TableName: aws.String("users")}, &users); err != nil {
+		t.Fatalf("failed to scan: %v", err)
+	}
+	want := []user{{ID: "1", Status: "active"}, {ID: "3", Status: "active"}}
+	if diff := cmp.Diff(want, users); diff != "" {
+		t.Errorf("unexpected users (-want +got):\n%s", diff)
+	}
+}
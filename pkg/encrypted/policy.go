@@ -0,0 +1,166 @@
+package encrypted
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/compression"
+)
+
+// PolicyVersion is the only policy document version understood by this library.
+const PolicyVersion = 1
+
+// ErrInvalidPolicy is returned when a policy document fails validation.
+var ErrInvalidPolicy = errors.New("invalid encryption policy")
+
+// Policy is a serializable description of how a table's attributes are protected, so that several services can
+// share one authoritative configuration:
+//
+//	{
+//	  "version": 1,
+//	  "defaultAction": "standard",
+//	  "attributes": {"email": "deterministic", "status": "none"},
+//	  "patterns": [{"pattern": "pii_*", "action": "standard"}],
+//	  "compression": 1,
+//	  "deterministicScope": "partition",
+//	  "tableMaterials": true,
+//	  "renames": {"ssn_enc": "ssn"},
+//	  "defaultDeny": ["users"],
+//	  "policyFingerprints": true
+//	}
+type Policy struct {
+	Version            int               `json:"version"`
+	DefaultAction      string            `json:"defaultAction"`
+	Attributes         map[string]string `json:"attributes,omitempty"`
+	Patterns           []PolicyPattern   `json:"patterns,omitempty"`
+	Compression        compression.ID    `json:"compression,omitempty"`
+	MaterialCleanup    bool              `json:"materialCleanup,omitempty"`
+	DeterministicScope string            `json:"deterministicScope,omitempty"`
+	TableMaterials     bool              `json:"tableMaterials,omitempty"`
+	Renames            map[string]string `json:"renames,omitempty"`
+	DefaultDeny        []string          `json:"defaultDeny,omitempty"`
+	PolicyFingerprints bool              `json:"policyFingerprints,omitempty"`
+}
+
+// PolicyPattern is the serialized form of a PatternRule. Patterns are evaluated in document order.
+type PolicyPattern struct {
+	Pattern string `json:"pattern"`
+	Action  string `json:"action"`
+}
+
+var policyActions = map[string]EncryptionAction{
+	"none":          EncryptNone,
+	"standard":      EncryptStandard,
+	"deterministic": EncryptDeterministic,
+	"hash":          EncryptHash,
+}
+
+// LoadPolicy decodes and validates a policy document. Unknown fields are rejected so that typos are not silently
+// ignored.
+func LoadPolicy(r io.Reader) (*Policy, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	var p Policy
+	if err := decoder.Decode(&p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// LoadPolicyFile reads and validates the policy document at the given path.
+func LoadPolicyFile(name string) (*Policy, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open policy: %w", err)
+	}
+	defer f.Close()
+	return LoadPolicy(f)
+}
+
+// Validate checks the policy's version, actions, patterns, compression algorithm, deterministic scope and renames.
+func (p *Policy) Validate() error {
+	if p.Version != PolicyVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidPolicy, p.Version)
+	}
+	if _, ok := policyActions[p.DefaultAction]; !ok {
+		return fmt.Errorf("%w: unknown default action %q", ErrInvalidPolicy, p.DefaultAction)
+	}
+	for name, action := range p.Attributes {
+		if _, ok := policyActions[action]; !ok {
+			return fmt.Errorf("%w: unknown action %q for attribute %q", ErrInvalidPolicy, action, name)
+		}
+	}
+	for _, pattern := range p.Patterns {
+		if _, err := path.Match(pattern.Pattern, ""); err != nil {
+			return fmt.Errorf("%w: malformed pattern %q", ErrInvalidPolicy, pattern.Pattern)
+		}
+		if _, ok := policyActions[pattern.Action]; !ok {
+			return fmt.Errorf("%w: unknown action %q for pattern %q", ErrInvalidPolicy, pattern.Action, pattern.Pattern)
+		}
+	}
+	if p.Compression != compression.None {
+		if _, err := compression.Lookup(p.Compression); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+		}
+	}
+	if _, ok := parseScope(p.DeterministicScope); !ok && p.DeterministicScope != "" {
+		return fmt.Errorf("%w: unknown deterministic scope %q", ErrInvalidPolicy, p.DeterministicScope)
+	}
+	if err := validateRenames(p.Renames); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+	return nil
+}
+
+// Apply validates the policy and replaces the encryption settings of config with it. Frozen configurations
+// are rejected with ErrConfigFrozen.
+func (p *Policy) Apply(config *ClientConfig) error {
+	if config.Frozen() {
+		return ErrConfigFrozen
+	}
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	config.Encryption = EncryptionConfig{
+		DefaultAction:   policyActions[p.DefaultAction],
+		SpecificActions: make(map[string]EncryptionAction, len(p.Attributes)),
+	}
+	for name, action := range p.Attributes {
+		config.Encryption.SpecificActions[name] = policyActions[action]
+	}
+	for _, pattern := range p.Patterns {
+		config.Encryption.PatternRules = append(config.Encryption.PatternRules, PatternRule{
+			Pattern: pattern.Pattern,
+			Action:  policyActions[pattern.Action],
+		})
+	}
+	config.Compression = p.Compression
+	config.MaterialCleanup = p.MaterialCleanup
+	config.DeterministicScope, _ = parseScope(p.DeterministicScope)
+	config.TableMaterials = p.TableMaterials
+	config.PolicyFingerprints = p.PolicyFingerprints
+	config.AttributeRenames = nil
+	if len(p.Renames) > 0 {
+		config.AttributeRenames = make(map[string]string, len(p.Renames))
+		for legacy, canonical := range p.Renames {
+			config.AttributeRenames[legacy] = canonical
+		}
+	}
+	config.DefaultDenyTables = nil
+	if len(p.DefaultDeny) > 0 {
+		config.DefaultDenyTables = make(map[string]bool, len(p.DefaultDeny))
+		for _, tableName := range p.DefaultDeny {
+			config.DefaultDenyTables[tableName] = true
+		}
+	}
+	return nil
+}
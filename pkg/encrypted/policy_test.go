@@ -0,0 +1,65 @@
+package encrypted
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/compression"
+)
+
+func TestLoadPolicy(t *testing.T) {
+	document := `{
+		"version": 1,
+		"defaultAction": "none",
+		"attributes": {"email": "deterministic"},
+		"patterns": [{"pattern": "pii_*", "action": "standard"}],
+		"compression": 1,
+		"deterministicScope": "partition"
+	}`
+
+	policy, err := LoadPolicy(strings.NewReader(document))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	config := NewClientConfig()
+	if err := policy.Apply(config); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if action := config.Encryption.ActionFor("email"); action != EncryptDeterministic {
+		t.Errorf("Expected %v for email, got %v", EncryptDeterministic, action)
+	}
+	if action := config.Encryption.ActionFor("pii_phone"); action != EncryptStandard {
+		t.Errorf("Expected %v for pii_phone, got %v", EncryptStandard, action)
+	}
+	if action := config.Encryption.ActionFor("status"); action != EncryptNone {
+		t.Errorf("Expected %v for status, got %v", EncryptNone, action)
+	}
+	if config.Compression != compression.Gzip {
+		t.Errorf("Expected compression %d, got %d", compression.Gzip, config.Compression)
+	}
+	if config.DeterministicScope != ScopePartition {
+		t.Errorf("Expected scope %v, got %v", ScopePartition, config.DeterministicScope)
+	}
+}
+
+func TestLoadPolicy_Invalid(t *testing.T) {
+	testCases := map[string]string{
+		"Version":         `{"version": 2, "defaultAction": "none"}`,
+		"DefaultAction":   `{"version": 1, "defaultAction": "encrypt"}`,
+		"AttributeAction": `{"version": 1, "defaultAction": "none", "attributes": {"a": "sign"}}`,
+		"Pattern":         `{"version": 1, "defaultAction": "none", "patterns": [{"pattern": "[", "action": "none"}]}`,
+		"Compression":     `{"version": 1, "defaultAction": "none", "compression": 250}`,
+		"UnknownField":    `{"version": 1, "defaultAction": "none", "signing": true}`,
+		"Scope":           `{"version": 1, "defaultAction": "none", "deterministicScope": "tenant"}`,
+	}
+
+	for name, document := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadPolicy(strings.NewReader(document)); !errors.Is(err, ErrInvalidPolicy) {
+				t.Errorf("Expected error '%v', got '%v'", ErrInvalidPolicy, err)
+			}
+		})
+	}
+}
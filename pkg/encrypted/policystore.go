@@ -0,0 +1,189 @@
+package encrypted
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/appconfigdata"
+	"github.com/aws/aws-sdk-go/service/appconfigdata/appconfigdataiface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+// PolicySource loads an encryption policy document from a central store.
+type PolicySource interface {
+	LoadPolicy(ctx context.Context) (*Policy, error)
+}
+
+// PolicySourceFunc adapts a function to a PolicySource.
+type PolicySourceFunc func(ctx context.Context) (*Policy, error)
+
+// LoadPolicy calls f.
+func (f PolicySourceFunc) LoadPolicy(ctx context.Context) (*Policy, error) {
+	return f(ctx)
+}
+
+// SSMPolicySource loads a policy document stored in an SSM Parameter Store parameter. SecureString
+// parameters are decrypted.
+type SSMPolicySource struct {
+	Client ssmiface.SSMAPI
+	Name   string
+}
+
+// LoadPolicy reads and validates the parameter's value.
+func (s *SSMPolicySource) LoadPolicy(ctx context.Context) (*Policy, error) {
+	output, err := s.Client.GetParameterWithContext(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(s.Name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get policy parameter %s: %w", s.Name, err)
+	}
+	if output.Parameter == nil {
+		return nil, fmt.Errorf("policy parameter %s has no value", s.Name)
+	}
+	return LoadPolicy(bytes.NewReader([]byte(aws.StringValue(output.Parameter.Value))))
+}
+
+// AppConfigPolicySource loads a policy document deployed as an AWS AppConfig configuration profile.
+// It keeps an AppConfig session and returns the last deployed document when the configuration is unchanged.
+type AppConfigPolicySource struct {
+	Client      appconfigdataiface.AppConfigDataAPI
+	Application string
+	Environment string
+	Profile     string
+
+	mu     sync.Mutex
+	token  *string
+	policy *Policy
+}
+
+// LoadPolicy polls AppConfig for the latest deployed configuration.
+func (s *AppConfigPolicySource) LoadPolicy(ctx context.Context) (*Policy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == nil {
+		session, err := s.Client.StartConfigurationSessionWithContext(ctx, &appconfigdata.StartConfigurationSessionInput{
+			ApplicationIdentifier:          aws.String(s.Application),
+			EnvironmentIdentifier:          aws.String(s.Environment),
+			ConfigurationProfileIdentifier: aws.String(s.Profile),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to start AppConfig session: %w", err)
+		}
+		s.token = session.InitialConfigurationToken
+	}
+
+	output, err := s.Client.GetLatestConfigurationWithContext(ctx, &appconfigdata.GetLatestConfigurationInput{
+		ConfigurationToken: s.token,
+	})
+	if err != nil {
+		// Tokens expire, so start a new session on the next call
+		s.token = nil
+		return nil, fmt.Errorf("failed to get AppConfig configuration: %w", err)
+	}
+	s.token = output.NextPollConfigurationToken
+
+	// An empty configuration means it has not changed since the previous call
+	if len(output.Configuration) == 0 {
+		if s.policy == nil {
+			return nil, fmt.Errorf("AppConfig returned no configuration")
+		}
+		return s.policy, nil
+	}
+
+	policy, err := LoadPolicy(bytes.NewReader(output.Configuration))
+	if err != nil {
+		return nil, err
+	}
+	s.policy = policy
+	return policy, nil
+}
+
+// DynamoDBPolicySource loads a policy document stored as a string attribute of a DynamoDB item.
+type DynamoDBPolicySource struct {
+	Client interface {
+		GetItem(ctx context.Context, input *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	}
+	TableName string
+	Key       map[string]types.AttributeValue
+	Attribute string
+}
+
+// LoadPolicy reads the item with a strongly consistent read and validates its policy attribute.
+func (s *DynamoDBPolicySource) LoadPolicy(ctx context.Context) (*Policy, error) {
+	output, err := s.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.TableName),
+		Key:            s.Key,
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get policy item: %w", err)
+	}
+
+	document, ok := output.Item[s.Attribute].(*types.AttributeValueMemberS)
+	if !ok {
+		return nil, fmt.Errorf("policy item in table %s has no string attribute %s", s.TableName, s.Attribute)
+	}
+	return LoadPolicy(bytes.NewReader([]byte(document.Value)))
+}
+
+// WatchPolicy applies the policy loaded from source to the client, then reloads it every interval until ctx is
+// done or the client is closed. Changed policies are swapped in atomically; operations in progress finish with the
+// previous policy. An error loading the initial policy is returned; later errors are passed to onError, if set, and
+// the last good policy stays in effect.
+func (ec *EncryptedClient) WatchPolicy(ctx context.Context, source PolicySource, interval time.Duration, onError func(error)) error {
+	current, err := source.LoadPolicy(ctx)
+	if err != nil {
+		return err
+	}
+	if err := ec.applyPolicy(ctx, current); err != nil {
+		return err
+	}
+
+	return ec.startWorker(func(done <-chan struct{}) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			policy, err := source.LoadPolicy(ctx)
+			if err == nil && !reflect.DeepEqual(policy, current) {
+				if err = ec.applyPolicy(ctx, policy); err == nil {
+					current = policy
+				}
+			}
+			if err != nil && onError != nil && ctx.Err() == nil && !errors.Is(err, ErrClientClosed) {
+				onError(err)
+			}
+		}
+	})
+}
+
+// applyPolicy replaces the encryption settings of the client's configuration with the policy, like UpdateConfig.
+func (ec *EncryptedClient) applyPolicy(ctx context.Context, policy *Policy) error {
+	ec.configMu.Lock()
+	defer ec.configMu.Unlock()
+
+	config := ec.Config().Clone()
+	if err := policy.Apply(config); err != nil {
+		return err
+	}
+	return ec.updateConfig(ctx, config)
+}
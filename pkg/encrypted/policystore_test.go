@@ -0,0 +1,97 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWatchPolicy(t *testing.T) {
+	policies := []*Policy{
+		{Version: PolicyVersion, DefaultAction: "standard", Attributes: map[string]string{"email": "deterministic"}},
+		{Version: PolicyVersion, DefaultAction: "none", Attributes: map[string]string{"email": "standard"}},
+	}
+	var loads atomic.Int32
+	source := PolicySourceFunc(func(ctx context.Context) (*Policy, error) {
+		n := loads.Add(1)
+		switch {
+		case n == 2:
+			return nil, errors.New("unavailable")
+		case n >= 3:
+			return policies[1], nil
+		}
+		return policies[0], nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ec := NewEncryptedClient(nil, nil)
+	errs := make(chan error, 10)
+	if err := ec.WatchPolicy(ctx, source, time.Millisecond, func(err error) { errs <- err }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if action := ec.Config().Encryption.ActionFor("email"); action != EncryptDeterministic {
+		t.Errorf("Expected %v after the initial load, got %v", EncryptDeterministic, action)
+	}
+
+	select {
+	case <-errs:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the failed reload to be reported")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for ec.Config().Encryption.ActionFor("email") != EncryptStandard {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the reloaded policy to be applied")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if action := ec.Config().Encryption.ActionFor("other"); action != EncryptNone {
+		t.Errorf("Expected default %v after reload, got %v", EncryptNone, action)
+	}
+	if !ec.Config().Frozen() {
+		t.Errorf("Expected the swapped configuration to be frozen")
+	}
+
+	failing := PolicySourceFunc(func(ctx context.Context) (*Policy, error) { return nil, errors.New("unavailable") })
+	if err := NewEncryptedClient(nil, nil).WatchPolicy(ctx, failing, time.Minute, nil); err == nil {
+		t.Errorf("Expected an error when the initial load fails")
+	}
+}
+
+func TestEncryptedClient_Close(t *testing.T) {
+	var loads atomic.Int32
+	source := PolicySourceFunc(func(ctx context.Context) (*Policy, error) {
+		loads.Add(1)
+		return &Policy{Version: PolicyVersion, DefaultAction: "standard"}, nil
+	})
+
+	ec := NewEncryptedClient(nil, nil, WithPrimaryKeyInfo("orders", PrimaryKeyInfo{PartitionKey: "ID"}))
+	if err := ec.WatchPolicy(context.Background(), source, time.Millisecond, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := ec.Close(); err != nil {
+			t.Fatalf("unexpected error closing client: %v", err)
+		}
+	}
+
+	// Close waits for the watcher to exit, so no more loads happen afterwards
+	stopped := loads.Load()
+	time.Sleep(10 * time.Millisecond)
+	if loads.Load() != stopped {
+		t.Errorf("Expected the policy watcher to stop on Close")
+	}
+
+	if _, err := ec.getPrimaryKeyInfo(context.Background(), "orders"); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed, got %v", err)
+	}
+	if err := ec.WatchPolicy(context.Background(), source, time.Millisecond, nil); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed, got %v", err)
+	}
+}
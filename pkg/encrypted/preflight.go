@@ -0,0 +1,36 @@
+package encrypted
+
+import (
+	"context"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider/store"
+)
+
+// Preflight exercises the operations the client needs before the application takes traffic, and returns the
+// permissions found missing, or none when every operation succeeded. The key schema of each table is loaded with
+// dynamodb:DescribeTable unless it was configured with WithPrimaryKeyInfo. When the materials provider implements
+// provider.PreflightChecker, its meta-table and KMS operations are checked too.
+func (ec *EncryptedClient) Preflight(ctx context.Context, tableNames ...string) ([]store.MissingPermission, error) {
+	select {
+	case <-ec.done:
+		return nil, ErrClientClosed
+	default:
+	}
+
+	var missing []store.MissingPermission
+	for _, tableName := range tableNames {
+		if _, err := ec.getPrimaryKeyInfo(ctx, tableName); err != nil {
+			missing = append(missing, store.MissingPermission{Action: "dynamodb:DescribeTable", Resource: tableName, Err: err})
+		}
+	}
+
+	if checker, ok := ec.MaterialsProvider.(provider.PreflightChecker); ok {
+		providerMissing, err := checker.Preflight(ctx)
+		if err != nil {
+			return nil, err
+		}
+		missing = append(missing, providerMissing...)
+	}
+	return missing, nil
+}
@@ -0,0 +1,56 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider/store"
+)
+
+// deniedClient denies DescribeTable.
+type deniedClient struct {
+	DynamoDBClientInterface
+}
+
+func (deniedClient) DescribeTable(ctx context.Context, input *dynamodb.DescribeTableInput, opts ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	return nil, errors.New("AccessDeniedException")
+}
+
+// preflightProvider reports a fixed list of missing permissions.
+type preflightProvider struct {
+	provider.CryptographicMaterialsProvider
+	missing []store.MissingPermission
+}
+
+func (p *preflightProvider) Preflight(ctx context.Context) ([]store.MissingPermission, error) {
+	return p.missing, nil
+}
+
+func TestEncryptedClient_Preflight(t *testing.T) {
+	kmsDenied := store.MissingPermission{Action: "kms:Decrypt", Resource: "arn:aws:kms:eu-west-2:123456789123:key/1", Err: errors.New("denied")}
+	ec := NewEncryptedClient(deniedClient{}, &preflightProvider{missing: []store.MissingPermission{kmsDenied}},
+		WithPrimaryKeyInfo("configured", PrimaryKeyInfo{PartitionKey: "ID"}))
+
+	missing, err := ec.Preflight(context.Background(), "configured", "described")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"dynamodb:DescribeTable described", "kms:Decrypt " + kmsDenied.Resource}
+	if len(missing) != len(expected) {
+		t.Fatalf("Expected %d missing permissions, got %v", len(expected), missing)
+	}
+	for i, permission := range missing {
+		if got := permission.Action + " " + permission.Resource; got != expected[i] {
+			t.Errorf("Expected missing permission %q, got %q", expected[i], got)
+		}
+	}
+
+	ec.Close()
+	if _, err := ec.Preflight(context.Background()); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed, got %v", err)
+	}
+}
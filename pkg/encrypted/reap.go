@@ -0,0 +1,173 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider/store"
+)
+
+// ReapReport is the result of ReapMaterials.
+type ReapReport struct {
+	Tables   []string
+	Items    int64 // Items scanned across the tables
+	Versions int64 // Material versions stored in the meta table
+
+	// Orphaned lists the material versions no item references that are older than the grace period. They have been
+	// deleted, unless the reaper ran with ReapDryRun.
+	Orphaned []store.StoredMaterial
+}
+
+// reaping holds the settings of ReapMaterials.
+type reaping struct {
+	dryRun bool
+}
+
+// ReapOption configures ReapMaterials.
+type ReapOption func(*reaping)
+
+// ReapDryRun reports orphaned material versions without deleting them.
+func ReapDryRun() ReapOption {
+	return func(r *reaping) {
+		r.dryRun = true
+	}
+}
+
+// ReapMaterials deletes material versions no item references anymore, such as versions left behind when items are
+// re-encrypted under new materials, deleted without material cleanup, or when an item write failed after its
+// materials were stored. It scans the given tables without decrypting them, like MaterialUsage, then lists the
+// versions in the meta store and deletes those that no item pins and that were created more than gracePeriod ago.
+// Items that do not pin a version keep the latest version of their material. Versions stored without a creation
+// time are kept.
+//
+// Every table whose materials are kept in the meta store must be listed, since versions of any other table look
+// unreferenced. The grace period must cover the time between storing materials and writing the item, including
+// retries of failed material writes, and the maximum age of reused materials; versions reused for writes during the
+// run must not be older than it.
+func (ec *EncryptedClient) ReapMaterials(ctx context.Context, materialStore *store.MetaStore, tableNames []string, gracePeriod time.Duration, opts ...ReapOption) (*ReapReport, error) {
+	if len(tableNames) == 0 {
+		return nil, errors.New("no tables to scan for material references")
+	}
+	if gracePeriod <= 0 {
+		return nil, errors.New("grace period must be positive")
+	}
+	var settings reaping
+	for _, opt := range opts {
+		opt(&settings)
+	}
+
+	ctx = operationContext(ctx, "ReapMaterials")
+	cutoff := time.Now().Add(-gracePeriod)
+	report := &ReapReport{Tables: tableNames}
+	references := newMaterialReferences()
+	for _, tableName := range tableNames {
+		items, err := ec.collectReferences(ctx, tableName, references)
+		if err != nil {
+			return nil, err
+		}
+		report.Items += items
+	}
+
+	stored, err := materialStore.ListMaterials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.Versions = int64(len(stored))
+	report.Orphaned = references.orphaned(stored, cutoff)
+	if settings.dryRun || len(report.Orphaned) == 0 {
+		return report, nil
+	}
+	if err := materialStore.DeleteMaterialVersions(ctx, report.Orphaned); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// collectReferences scans a table and records the material versions its items reference, returning the number of
+// items scanned.
+func (ec *EncryptedClient) collectReferences(ctx context.Context, tableName string, references *materialReferences) (int64, error) {
+	pkInfo, err := ec.getPrimaryKeyInfo(ctx, tableName)
+	if err != nil {
+		return 0, err
+	}
+
+	input := &dynamodb.ScanInput{
+		TableName: aws.String(tableName),
+	}
+	input.ProjectionExpression, input.ExpressionAttributeNames = usageProjection(pkInfo)
+
+	var items int64
+	paginator := dynamodb.NewScanPaginator(ec.Client, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("error scanning items: %v", err)
+		}
+
+		for _, item := range output.Items {
+			materialName, err := itemMaterialName(item, pkInfo)
+			if err != nil {
+				return 0, fmt.Errorf("error constructing material name: %w", err)
+			}
+			version, err := materialVersion(item)
+			if err != nil {
+				return 0, err
+			}
+			references.add(materialName, version)
+			items++
+		}
+	}
+	return items, nil
+}
+
+// materialReferences records the material versions referenced by items.
+type materialReferences struct {
+	versions map[store.StoredMaterial]bool // Keyed by material name and version only
+	latest   map[string]bool               // Material names referenced by items that do not pin a version
+}
+
+func newMaterialReferences() *materialReferences {
+	return &materialReferences{
+		versions: make(map[store.StoredMaterial]bool),
+		latest:   make(map[string]bool),
+	}
+}
+
+// add records a reference to a material version, where version 0 references the latest version.
+func (r *materialReferences) add(materialName string, version int64) {
+	if version < 1 {
+		r.latest[materialName] = true
+		return
+	}
+	r.versions[store.StoredMaterial{MaterialName: materialName, Version: version}] = true
+}
+
+// orphaned returns the stored versions created before the cutoff that no item references.
+func (r *materialReferences) orphaned(stored []store.StoredMaterial, cutoff time.Time) []store.StoredMaterial {
+	latest := make(map[string]int64)
+	for _, material := range stored {
+		if material.Version > latest[material.MaterialName] {
+			latest[material.MaterialName] = material.Version
+		}
+	}
+
+	var orphaned []store.StoredMaterial
+	for _, material := range stored {
+		// Versions without a creation time, or within the grace period, may be about to be referenced
+		if material.CreatedAt.IsZero() || !material.CreatedAt.Before(cutoff) {
+			continue
+		}
+		if r.versions[store.StoredMaterial{MaterialName: material.MaterialName, Version: material.Version}] {
+			continue
+		}
+		if r.latest[material.MaterialName] && material.Version == latest[material.MaterialName] {
+			continue
+		}
+		orphaned = append(orphaned, material)
+	}
+	return orphaned
+}
@@ -0,0 +1,95 @@
+package encrypted
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider/store"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEncryptedClient_CollectReferences(t *testing.T) {
+	pinned := map[string]types.AttributeValue{
+		"ID":                     &types.AttributeValueMemberS{Value: "1"},
+		MaterialVersionAttribute: &types.AttributeValueMemberN{Value: "2"},
+	}
+	unpinned := map[string]types.AttributeValue{
+		"ID": &types.AttributeValueMemberS{Value: "2"},
+	}
+	shared := map[string]types.AttributeValue{
+		"ID":                     &types.AttributeValueMemberS{Value: "3"},
+		MaterialScopeAttribute:   &types.AttributeValueMemberS{Value: MaterialScopeTable},
+		MaterialVersionAttribute: &types.AttributeValueMemberN{Value: "5"},
+	}
+	pkInfo := &PrimaryKeyInfo{Table: "users", PartitionKey: "ID"}
+	pinnedName, err := ConstructMaterialName(pinned, pkInfo)
+	if err != nil {
+		t.Fatalf("failed to construct material name: %v", err)
+	}
+	unpinnedName, err := ConstructMaterialName(unpinned, pkInfo)
+	if err != nil {
+		t.Fatalf("failed to construct material name: %v", err)
+	}
+
+	ec := NewEncryptedClient(&scanClient{items: []map[string]types.AttributeValue{pinned, unpinned, shared}}, nil,
+		WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}))
+	references := newMaterialReferences()
+	items, err := ec.collectReferences(context.Background(), "users", references)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if items != 3 {
+		t.Errorf("expected 3 items, got %d", items)
+	}
+
+	want := &materialReferences{
+		versions: map[store.StoredMaterial]bool{
+			{MaterialName: pinnedName, Version: 2}:                 true,
+			{MaterialName: TableMaterialName("users"), Version: 5}: true,
+		},
+		latest: map[string]bool{unpinnedName: true},
+	}
+	if diff := cmp.Diff(want, references, cmp.AllowUnexported(materialReferences{})); diff != "" {
+		t.Errorf("unexpected references (-want +got):\n%s", diff)
+	}
+}
+
+func TestMaterialReferences_Orphaned(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+	references := newMaterialReferences()
+	references.add("pinned", 2)
+	references.add("unpinned", 0)
+
+	stored := []store.StoredMaterial{
+		{MaterialName: "pinned", Version: 1, CreatedAt: old},
+		{MaterialName: "pinned", Version: 2, CreatedAt: old},
+		{MaterialName: "pinned", Version: 3, CreatedAt: now},
+		{MaterialName: "unpinned", Version: 1, CreatedAt: old},
+		{MaterialName: "unpinned", Version: 2, CreatedAt: old},
+		{MaterialName: "deleted", Version: 1, CreatedAt: old},
+		{MaterialName: "legacy", Version: 1},
+	}
+
+	got := references.orphaned(stored, now.Add(-24*time.Hour))
+	want := []store.StoredMaterial{
+		{MaterialName: "pinned", Version: 1, CreatedAt: old},
+		{MaterialName: "unpinned", Version: 1, CreatedAt: old},
+		{MaterialName: "deleted", Version: 1, CreatedAt: old},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected orphaned versions (-want +got):\n%s", diff)
+	}
+}
+
+func TestEncryptedClient_ReapMaterials_InvalidArguments(t *testing.T) {
+	ec := NewEncryptedClient(&scanClient{}, nil)
+	if _, err := ec.ReapMaterials(context.Background(), nil, nil, time.Hour); err == nil {
+		t.Errorf("expected an error without tables")
+	}
+	if _, err := ec.ReapMaterials(context.Background(), nil, []string{"users"}, 0); err == nil {
+		t.Errorf("expected an error without a grace period")
+	}
+}
@@ -0,0 +1,71 @@
+package encrypted
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// WithAttributeRename maps a legacy attribute name to its canonical name, for example "ssn_enc" to "ssn". Items read
+// with the legacy attribute return it under the canonical name, decrypted with the canonical name's action, and
+// items written with it are stored under the canonical name, so tables migrate as their items are rewritten.
+func WithAttributeRename(legacy, canonical string) Option {
+	return func(c *ClientConfig) {
+		c.mustBeMutable()
+		renames := make(map[string]string, len(c.AttributeRenames)+1)
+		for from, to := range c.AttributeRenames {
+			renames[from] = to
+		}
+		renames[legacy] = canonical
+		c.AttributeRenames = renames
+	}
+}
+
+// validateRenames checks that no rename involves a reserved attribute and that canonical names are not renamed
+// themselves.
+func validateRenames(renames map[string]string) error {
+	for legacy, canonical := range renames {
+		if legacy == canonical || legacy == "" || canonical == "" {
+			return fmt.Errorf("invalid rename of %q to %q", legacy, canonical)
+		}
+		if isReservedAttribute(legacy) || isReservedAttribute(canonical) {
+			return fmt.Errorf("reserved attribute in rename of %q to %q", legacy, canonical)
+		}
+		if _, ok := renames[canonical]; ok {
+			return fmt.Errorf("canonical name %q is renamed itself", canonical)
+		}
+	}
+	return nil
+}
+
+// canonicalName returns the name an attribute is read and written under.
+func (c *ClientConfig) canonicalName(name string) string {
+	if canonical, ok := c.AttributeRenames[name]; ok {
+		return canonical
+	}
+	return name
+}
+
+// renameAttributes returns a copy of an item to be written with its legacy attributes renamed to their canonical
+// names. Key attributes are never renamed. An item holding both names of an attribute is rejected, since one of
+// the values would be lost.
+func (c *ClientConfig) renameAttributes(pkInfo *PrimaryKeyInfo, item map[string]types.AttributeValue) (map[string]types.AttributeValue, error) {
+	if len(c.AttributeRenames) == 0 {
+		return item, nil
+	}
+
+	renamed := make(map[string]types.AttributeValue, len(item))
+	for name, value := range item {
+		canonical := name
+		if !pkInfo.IsKeyAttribute(name) {
+			canonical = c.canonicalName(name)
+		}
+		if canonical != name {
+			if _, ok := item[canonical]; ok {
+				return nil, fmt.Errorf("item contains both %q and its legacy name %q", canonical, name)
+			}
+		}
+		renamed[canonical] = value
+	}
+	return renamed, nil
+}
@@ -0,0 +1,98 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestAttributeRenames(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	materialsProvider := &staticProvider{dataKey: dataKey}
+	ignore := cmpopts.IgnoreUnexported(types.AttributeValueMemberS{})
+
+	legacy := NewEncryptedClient(nil, materialsProvider, WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}))
+	current := NewEncryptedClient(nil, materialsProvider,
+		WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}),
+		WithOptions(
+			WithDefaultEncryption(EncryptNone),
+			WithEncryption("ssn", EncryptStandard),
+			WithAttributeRename("ssn_enc", "ssn"),
+		),
+	)
+
+	ssn := &types.AttributeValueMemberS{Value: "078-05-1120"}
+	stored, err := legacy.encryptItem(context.Background(), "users", map[string]types.AttributeValue{
+		"ID":      &types.AttributeValueMemberS{Value: "1"},
+		"ssn_enc": ssn,
+	})
+	if err != nil {
+		t.Fatalf("failed to encrypt legacy item: %v", err)
+	}
+
+	decrypted, err := current.decryptItem(context.Background(), "users", stored)
+	if err != nil {
+		t.Fatalf("failed to decrypt legacy item: %v", err)
+	}
+	want := map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: "1"}, "ssn": ssn}
+	if diff := cmp.Diff(want, decrypted, ignore); diff != "" {
+		t.Errorf("unexpected item (-want +got):\n%s", diff)
+	}
+
+	rewritten, err := current.encryptItem(context.Background(), "users", map[string]types.AttributeValue{
+		"ID":      &types.AttributeValueMemberS{Value: "1"},
+		"ssn_enc": ssn,
+	})
+	if err != nil {
+		t.Fatalf("failed to encrypt item: %v", err)
+	}
+	if _, ok := rewritten["ssn_enc"]; ok {
+		t.Errorf("expected the legacy name not to be written")
+	}
+	if _, ok := rewritten["ssn"].(*types.AttributeValueMemberB); !ok {
+		t.Errorf("expected ssn to be written encrypted, got %T", rewritten["ssn"])
+	}
+
+	_, err = current.encryptItem(context.Background(), "users", map[string]types.AttributeValue{
+		"ID":      &types.AttributeValueMemberS{Value: "1"},
+		"ssn":     ssn,
+		"ssn_enc": ssn,
+	})
+	if err == nil {
+		t.Errorf("expected an item with both names to be rejected")
+	}
+}
+
+func TestValidateRenames(t *testing.T) {
+	testCases := []struct {
+		name    string
+		renames map[string]string
+		valid   bool
+	}{
+		{name: "Valid", renames: map[string]string{"ssn_enc": "ssn", "dob_enc": "dob"}, valid: true},
+		{name: "Identity", renames: map[string]string{"ssn": "ssn"}},
+		{name: "Chain", renames: map[string]string{"ssn_v1": "ssn_enc", "ssn_enc": "ssn"}},
+		{name: "Reserved", renames: map[string]string{"version": MaterialVersionAttribute}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := NewClientConfig(func(c *ClientConfig) { c.AttributeRenames = tc.renames }).Validate()
+			if (err == nil) != tc.valid || (err != nil && !errors.Is(err, ErrInvalidConfig)) {
+				t.Errorf("expected valid %v, got %v", tc.valid, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package encrypted
+
+import (
+	"context"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+)
+
+// EncryptionReport describes how an item is protected: by the write that stored it, or as found by the read that
+// returned it. Attribute lists are sorted and exclude key attributes, which are always stored in plaintext.
+type EncryptionReport struct {
+	Table           string
+	MaterialName    string
+	MaterialVersion int64 // 0 when the item does not pin a version
+	Algorithm       string
+	KMSKeyURI       string
+
+	Encrypted  []string // Attributes stored encrypted
+	Compressed []string // Encrypted attributes compressed before encryption
+	Hashed     []string // Attributes stored as a keyed digest
+	Plaintext  []string // Non-key attributes stored in plaintext
+	Signed     bool
+}
+
+type encryptionReportKey struct{}
+
+// ContextWithEncryptionReport returns a context that makes single-item operations such as PutItem and GetItem fill
+// in report, so applications can log and assert on the protection of individual items without inspecting the stored
+// item. The report is overwritten for every item encrypted or decrypted with the context, so operations on several
+// items leave the report of the last one.
+func ContextWithEncryptionReport(ctx context.Context, report *EncryptionReport) context.Context {
+	return context.WithValue(ctx, encryptionReportKey{}, report)
+}
+
+// reportFromContext returns the report to fill in for the operation, or nil.
+func reportFromContext(ctx context.Context) *EncryptionReport {
+	report, _ := ctx.Value(encryptionReportKey{}).(*EncryptionReport)
+	return report
+}
+
+// fill describes a stored item in the report, replacing its previous contents.
+func (r *EncryptionReport) fill(pkInfo *PrimaryKeyInfo, encryption EncryptionConfig, materialName string, stored map[string]types.AttributeValue, description map[string]string, compressed []string) {
+	version, _ := materialVersion(stored)
+	*r = EncryptionReport{
+		Table:           pkInfo.Table,
+		MaterialName:    materialName,
+		MaterialVersion: version,
+		Algorithm:       description["ContentEncryptionAlgorithm"],
+		KMSKeyURI:       description[provider.KMSKeyURIKey],
+		Compressed:      append([]string(nil), compressed...),
+	}
+
+	for name, value := range stored {
+		switch {
+		case name == SignatureAttribute:
+			r.Signed = true
+		case isReservedAttribute(name) || pkInfo.IsKeyAttribute(name):
+		default:
+			_, binary := value.(*types.AttributeValueMemberB)
+			switch action := encryption.ActionFor(name); {
+			case binary && action == EncryptHash:
+				r.Hashed = append(r.Hashed, name)
+			case binary && action != EncryptNone:
+				r.Encrypted = append(r.Encrypted, name)
+			default:
+				r.Plaintext = append(r.Plaintext, name)
+			}
+		}
+	}
+	sort.Strings(r.Encrypted)
+	sort.Strings(r.Compressed)
+	sort.Strings(r.Hashed)
+	sort.Strings(r.Plaintext)
+}
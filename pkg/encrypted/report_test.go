@@ -0,0 +1,78 @@
+package encrypted
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/compression"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEncryptionReport(t *testing.T) {
+	const kmsKeyURI = "arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b"
+	kek, err := delegatedkeys.GetKEK(kmsKeyURI, true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	signingKey, _, publicKey, err := delegatedkeys.GenerateSigningKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+	materialsProvider := &staticProvider{
+		description: map[string]string{
+			"ContentEncryptionAlgorithm": dataKey.Algorithm(),
+			provider.KMSKeyURIKey:        kmsKeyURI,
+			provider.MaterialVersionKey:  "3",
+			"PublicKey":                  base64.StdEncoding.EncodeToString(publicKey),
+		},
+		dataKey:    dataKey,
+		signingKey: signingKey,
+	}
+
+	ec := NewEncryptedClient(nil, materialsProvider,
+		WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}),
+		WithOptions(WithEncryption("Role", EncryptNone), WithItemSignatures(true), WithCompression(compression.Gzip)),
+	)
+
+	expected := EncryptionReport{
+		Table:           "users",
+		MaterialVersion: 3,
+		Algorithm:       dataKey.Algorithm(),
+		KMSKeyURI:       kmsKeyURI,
+		Encrypted:       []string{"Email", "Secret"},
+		Compressed:      []string{"Email", "Secret"},
+		Plaintext:       []string{"Role"},
+		Signed:          true,
+	}
+
+	var written EncryptionReport
+	stored, err := ec.encryptItem(ContextWithEncryptionReport(context.Background(), &written), "users", map[string]types.AttributeValue{
+		"ID":     &types.AttributeValueMemberS{Value: "1"},
+		"Email":  &types.AttributeValueMemberS{Value: "a@example.com"},
+		"Role":   &types.AttributeValueMemberS{Value: "reader"},
+		"Secret": &types.AttributeValueMemberS{Value: "value"},
+	})
+	if err != nil {
+		t.Fatalf("failed to encrypt item: %v", err)
+	}
+	expected.MaterialName = written.MaterialName
+	if diff := cmp.Diff(expected, written); diff != "" || written.MaterialName == "" {
+		t.Errorf("unexpected write report (-want +got):\n%s", diff)
+	}
+
+	var read EncryptionReport
+	if _, err := ec.decryptItem(ContextWithEncryptionReport(context.Background(), &read), "users", stored); err != nil {
+		t.Fatalf("failed to decrypt item: %v", err)
+	}
+	if diff := cmp.Diff(expected, read); diff != "" {
+		t.Errorf("unexpected read report (-want +got):\n%s", diff)
+	}
+}
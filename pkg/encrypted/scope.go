@@ -0,0 +1,92 @@
+package encrypted
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/serde"
+)
+
+// DeterministicScope controls where deterministic attributes produce equal ciphertexts for equal values. The scope
+// is mixed into the associated data attributes are encrypted with, so values only compare equal within it.
+type DeterministicScope int
+
+const (
+	ScopeGlobal    DeterministicScope = iota // Equal values encrypt equally wherever the same data key is used.
+	ScopeTable                               // Equal values encrypt equally within a table.
+	ScopePartition                           // Equal values encrypt equally within a partition, for example a tenant.
+)
+
+// DeterministicScopeAttribute is the item attribute recording the scope an item's attributes were encrypted with,
+// when it is narrower than ScopeGlobal.
+const DeterministicScopeAttribute = "__DeterministicScope"
+
+var scopeNames = map[DeterministicScope]string{
+	ScopeGlobal:    "global",
+	ScopeTable:     "table",
+	ScopePartition: "partition",
+}
+
+func (s DeterministicScope) String() string {
+	if name, ok := scopeNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("DeterministicScope(%d)", int(s))
+}
+
+func (s DeterministicScope) valid() bool {
+	_, ok := scopeNames[s]
+	return ok
+}
+
+// parseScope returns the scope with the given name.
+func parseScope(name string) (DeterministicScope, bool) {
+	for scope, scopeName := range scopeNames {
+		if scopeName == name {
+			return scope, true
+		}
+	}
+	return 0, false
+}
+
+// itemScope returns the scope a stored item's attributes were encrypted with.
+func itemScope(item map[string]types.AttributeValue) (DeterministicScope, error) {
+	value, ok := item[DeterministicScopeAttribute]
+	if !ok {
+		return ScopeGlobal, nil
+	}
+	name, ok := value.(*types.AttributeValueMemberS)
+	if !ok {
+		return 0, fmt.Errorf("unexpected type for %s attribute", DeterministicScopeAttribute)
+	}
+	scope, ok := parseScope(name.Value)
+	if !ok {
+		return 0, fmt.Errorf("unknown deterministic scope %q in %s attribute", name.Value, DeterministicScopeAttribute)
+	}
+	return scope, nil
+}
+
+// associatedData returns the associated data an attribute of an item is encrypted with: the attribute name,
+// prefixed with the table name for ScopeTable, and with the table name and partition key value for ScopePartition.
+// Every encrypted attribute of an item uses it, but it only changes the outcome for deterministic attributes, since
+// standard ones are randomized anyway.
+func (s DeterministicScope) associatedData(tableName, partitionKey string, item map[string]types.AttributeValue, attributeName string) ([]byte, error) {
+	switch s {
+	case ScopeGlobal:
+		return []byte(attributeName), nil
+	case ScopeTable:
+		return []byte(tableName + "\x00" + attributeName), nil
+	case ScopePartition:
+		value, ok := item[partitionKey]
+		if !ok {
+			return nil, fmt.Errorf("%w: partition key %q", ErrMissingKeyAttribute, partitionKey)
+		}
+		partition, err := serde.NewSerializer().Serialize(value)
+		if err != nil {
+			return nil, fmt.Errorf("error serializing partition key: %v", err)
+		}
+		data := append([]byte(tableName+"\x00"), partition...)
+		return append(data, "\x00"+attributeName...), nil
+	}
+	return nil, fmt.Errorf("%w: unknown deterministic scope %d", ErrInvalidConfig, s)
+}
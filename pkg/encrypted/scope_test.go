@@ -0,0 +1,165 @@
+package encrypted
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/internal/fakeddb"
+	fakeawskms "github.com/cloudopsy/dynamodb-encryption-go/internal/fakekms"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider/store"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestDeterministicScope(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	encryptEmail := func(t *testing.T, scope DeterministicScope, table, tenant string) []byte {
+		ec := NewEncryptedClient(nil, &staticProvider{dataKey: dataKey},
+			WithPrimaryKeyInfo("orders", PrimaryKeyInfo{PartitionKey: "Tenant", SortKey: "ID"}),
+			WithPrimaryKeyInfo("invoices", PrimaryKeyInfo{PartitionKey: "Tenant", SortKey: "ID"}),
+			WithOptions(WithEncryption("Email", EncryptDeterministic), WithDeterministicScope(scope)),
+		)
+		item, err := ec.encryptItem(context.Background(), table, map[string]types.AttributeValue{
+			"Tenant": &types.AttributeValueMemberS{Value: tenant},
+			"ID":     &types.AttributeValueMemberS{Value: "1"},
+			"Email":  &types.AttributeValueMemberS{Value: "a@example.com"},
+		})
+		if err != nil {
+			t.Fatalf("failed to encrypt item: %v", err)
+		}
+		if _, ok := item[DeterministicScopeAttribute]; ok != (scope != ScopeGlobal) {
+			t.Errorf("expected %s to be recorded: %v", DeterministicScopeAttribute, scope != ScopeGlobal)
+		}
+		return item["Email"].(*types.AttributeValueMemberB).Value
+	}
+
+	testCases := []struct {
+		scope              DeterministicScope
+		equalAcrossTenants bool
+		equalAcrossTables  bool
+	}{
+		{scope: ScopeGlobal, equalAcrossTenants: true, equalAcrossTables: true},
+		{scope: ScopeTable, equalAcrossTenants: true},
+		{scope: ScopePartition},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scope.String(), func(t *testing.T) {
+			ciphertext := encryptEmail(t, tc.scope, "orders", "acme")
+			if !bytes.Equal(ciphertext, encryptEmail(t, tc.scope, "orders", "acme")) {
+				t.Errorf("expected equal values to encrypt equally within the partition")
+			}
+			if equal := bytes.Equal(ciphertext, encryptEmail(t, tc.scope, "orders", "globex")); equal != tc.equalAcrossTenants {
+				t.Errorf("expected equal ciphertexts across tenants: %v", tc.equalAcrossTenants)
+			}
+			if equal := bytes.Equal(ciphertext, encryptEmail(t, tc.scope, "invoices", "acme")); equal != tc.equalAcrossTables {
+				t.Errorf("expected equal ciphertexts across tables: %v", tc.equalAcrossTables)
+			}
+		})
+	}
+}
+
+func TestDeterministicScope_Decrypt(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	materialsProvider := &staticProvider{dataKey: dataKey}
+
+	writer := NewEncryptedClient(nil, materialsProvider,
+		WithPrimaryKeyInfo("orders", PrimaryKeyInfo{PartitionKey: "Tenant"}),
+		WithOptions(WithDeterministicScope(ScopePartition)),
+	)
+	reader := NewEncryptedClient(nil, materialsProvider, WithPrimaryKeyInfo("orders", PrimaryKeyInfo{PartitionKey: "Tenant"}))
+
+	item := map[string]types.AttributeValue{
+		"Tenant": &types.AttributeValueMemberS{Value: "acme"},
+		"Email":  &types.AttributeValueMemberS{Value: "a@example.com"},
+	}
+	encryptedItem, err := writer.encryptItem(context.Background(), "orders", item)
+	if err != nil {
+		t.Fatalf("failed to encrypt item: %v", err)
+	}
+	decrypted, err := reader.decryptItem(context.Background(), "orders", encryptedItem)
+	if err != nil {
+		t.Fatalf("failed to decrypt item: %v", err)
+	}
+	if diff := cmp.Diff(item, decrypted, cmpopts.IgnoreUnexported(types.AttributeValueMemberS{})); diff != "" {
+		t.Errorf("unexpected item (-want +got):\n%s", diff)
+	}
+
+	// Moving the ciphertext to another tenant's item breaks its associated data
+	encryptedItem["Tenant"] = &types.AttributeValueMemberS{Value: "globex"}
+	if _, err := reader.decryptItem(context.Background(), "orders", encryptedItem); err == nil {
+		t.Errorf("expected decryption under another partition to fail")
+	}
+}
+
+func TestDeterministicScope_ProviderMaterials(t *testing.T) {
+	const keyURI = "arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b"
+	kms, err := fakeawskms.New([]string{keyURI})
+	if err != nil {
+		t.Fatalf("failed to create fake KMS: %v", err)
+	}
+	materialStore, err := store.NewMetaStore(fakeddb.New().Client(), "meta")
+	if err != nil {
+		t.Fatalf("failed to create meta store: %v", err)
+	}
+	materialsProvider, err := provider.New(keyURI, nil, materialStore, provider.WithKMSClient(kms), provider.WithMaterialReuse(provider.UsageLimits{MaxMessages: 1000}))
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+
+	testCases := []struct {
+		scope              DeterministicScope
+		equalAcrossTenants bool
+	}{
+		{scope: ScopeGlobal, equalAcrossTenants: true},
+		{scope: ScopeTable, equalAcrossTenants: true},
+		{scope: ScopePartition},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scope.String(), func(t *testing.T) {
+			// Items of a table share materials, so only the scope separates their ciphertexts
+			ec := NewEncryptedClient(nil, materialsProvider,
+				WithPrimaryKeyInfo("orders", PrimaryKeyInfo{PartitionKey: "Tenant", SortKey: "ID"}),
+				WithOptions(WithEncryption("Email", EncryptDeterministic), WithDeterministicScope(tc.scope), WithTableMaterials(true)),
+			)
+			encryptEmail := func(tenant, id string) []byte {
+				item, err := ec.encryptItem(context.Background(), "orders", map[string]types.AttributeValue{
+					"Tenant": &types.AttributeValueMemberS{Value: tenant},
+					"ID":     &types.AttributeValueMemberS{Value: id},
+					"Email":  &types.AttributeValueMemberS{Value: "a@example.com"},
+				})
+				if err != nil {
+					t.Fatalf("failed to encrypt item: %v", err)
+				}
+				return item["Email"].(*types.AttributeValueMemberB).Value
+			}
+
+			ciphertext := encryptEmail("acme", "1")
+			if !bytes.Equal(ciphertext, encryptEmail("acme", "2")) {
+				t.Errorf("expected equal values to encrypt equally within the partition")
+			}
+			if equal := bytes.Equal(ciphertext, encryptEmail("globex", "3")); equal != tc.equalAcrossTenants {
+				t.Errorf("expected equal ciphertexts across tenants: %v", tc.equalAcrossTenants)
+			}
+		})
+	}
+}
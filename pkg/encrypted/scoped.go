@@ -0,0 +1,321 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// ErrOutOfScope is returned when a client created with WithScope is used beyond the capabilities it was given.
+var ErrOutOfScope = errors.New("operation is outside the client's scope")
+
+// scopeMode is what a scoped client may do with the tables it can access.
+type scopeMode int
+
+const (
+	scopeReadWrite   scopeMode = iota
+	scopeReadOnly              // Read and decrypt items, but neither write nor encrypt them.
+	scopeDecryptOnly           // Decrypt items handed to the client, without calling DynamoDB for them.
+)
+
+// clientScope narrows what a client may do. A nil scope allows everything.
+type clientScope struct {
+	tables     map[string]bool // nil allows every table
+	attributes map[string]bool // nil allows decrypting every attribute
+	mode       scopeMode
+}
+
+// ScopeOption narrows the capabilities of a client created with WithScope.
+type ScopeOption func(*clientScope)
+
+// AllowTables restricts a scoped client to the named tables. Within a scope that is already restricted, only the
+// tables allowed by both remain.
+func AllowTables(tableNames ...string) ScopeOption {
+	return func(s *clientScope) {
+		s.tables = intersect(s.tables, tableNames)
+	}
+}
+
+// AllowDecryption restricts the encrypted attributes a scoped client decrypts to the named ones. Other encrypted
+// attributes are left out of the items it returns, without being decrypted. Plaintext attributes are unaffected.
+// Within a scope that is already restricted, only the attributes allowed by both remain.
+func AllowDecryption(attributeNames ...string) ScopeOption {
+	return func(s *clientScope) {
+		s.attributes = intersect(s.attributes, attributeNames)
+	}
+}
+
+// ReadOnly makes a scoped client fail writes and encryption with ErrOutOfScope, while it can still read and decrypt
+// items.
+func ReadOnly() ScopeOption {
+	return func(s *clientScope) {
+		if s.mode < scopeReadOnly {
+			s.mode = scopeReadOnly
+		}
+	}
+}
+
+// DecryptOnly makes a scoped client fail every DynamoDB request but DescribeTable, and encryption, with
+// ErrOutOfScope. It can only decrypt items handed to it, for example with DecryptItem from stream records.
+func DecryptOnly() ScopeOption {
+	return func(s *clientScope) {
+		s.mode = scopeDecryptOnly
+	}
+}
+
+// intersect returns the names allowed by both allowed, where nil allows every name, and names.
+func intersect(allowed map[string]bool, names []string) map[string]bool {
+	result := make(map[string]bool, len(names))
+	for _, name := range names {
+		if allowed == nil || allowed[name] {
+			result[name] = true
+		}
+	}
+	return result
+}
+
+// WithScope returns a client restricted to a subset of this client's capabilities, so that a module of the same
+// process can not read or write beyond what it needs by mistake. The scope is not a security boundary: the scoped
+// client's exported MaterialsProvider is this client's provider, which serves the keys of every table and attribute.
+// The scoped client shares this client's DynamoDB client, materials provider, hooks and configuration, including later
+// configuration updates, but can not change the configuration or start policy watchers itself. It has no decrypted item cache, and operations passed through to DynamoDB without touching items,
+// such as backups and table administration, fail with ErrOperationNotSupported. Scoping a scoped client narrows it
+// further. The scoped client is closed together with this one; closing it has no effect.
+func (ec *EncryptedClient) WithScope(options ...ScopeOption) *EncryptedClient {
+	root := ec
+	scope := &clientScope{}
+	if ec.parent != nil {
+		root = ec.parent
+		*scope = *ec.scope
+	}
+	for _, option := range options {
+		option(scope)
+	}
+
+	scoped := &EncryptedClient{
+		Client:            &scopedDynamoDB{client: root.Client, scope: scope},
+		MaterialsProvider: ec.MaterialsProvider,
+		PrimaryKeyCache:   make(map[string]*PrimaryKeyInfo),
+		keySchemaCache:    ec.keySchemaCache,
+		ClientConfig:      ec.ClientConfig,
+		preEncryptHooks:   ec.preEncryptHooks,
+		postDecryptHooks:  ec.postDecryptHooks,
+		auditors:          ec.auditors,
+		resultSteps:       ec.resultSteps,
+		verifyOnly:        ec.verifyOnly,
+		hashKey:           ec.hashKey,
+		batchGetAttempts:  ec.batchGetAttempts,
+		batchPutAttempts:  ec.batchPutAttempts,
+		parent:            root,
+		scope:             scope,
+		done:              root.done,
+	}
+	ec.lock.RLock()
+	for tableName, pkInfo := range ec.PrimaryKeyCache {
+		if scope.allowsTable(tableName) {
+			scoped.PrimaryKeyCache[tableName] = pkInfo
+		}
+	}
+	ec.lock.RUnlock()
+	return scoped
+}
+
+// checkTable fails with ErrOutOfScope unless the table is accessible.
+func (s *clientScope) checkTable(tableName string) error {
+	if s != nil && s.tables != nil && !s.tables[tableName] {
+		return fmt.Errorf("%w: table %q", ErrOutOfScope, tableName)
+	}
+	return nil
+}
+
+func (s *clientScope) allowsTable(tableName string) bool {
+	return s.checkTable(tableName) == nil
+}
+
+// checkEncrypt fails with ErrOutOfScope unless items may be encrypted.
+func (s *clientScope) checkEncrypt() error {
+	if s != nil && s.mode != scopeReadWrite {
+		return fmt.Errorf("%w: encryption", ErrOutOfScope)
+	}
+	return nil
+}
+
+// checkDecrypt fails with ErrOutOfScope unless the attribute may be decrypted.
+func (s *clientScope) checkDecrypt(attributeName string) error {
+	if s != nil && s.attributes != nil && !s.attributes[attributeName] {
+		return fmt.Errorf("%w: attribute %q", ErrOutOfScope, attributeName)
+	}
+	return nil
+}
+
+// filterDecryption returns the stored item without the encrypted attributes the scope does not allow decrypting.
+func (s *clientScope) filterDecryption(pkInfo *PrimaryKeyInfo, config *ClientConfig, encryption EncryptionConfig, item map[string]types.AttributeValue) map[string]types.AttributeValue {
+	if s == nil || s.attributes == nil {
+		return item
+	}
+	filtered := make(map[string]types.AttributeValue, len(item))
+	for name, value := range item {
+		canonical := config.canonicalName(name)
+		if _, encrypted := value.(*types.AttributeValueMemberB); encrypted && !isReservedAttribute(name) && !pkInfo.IsKeyAttribute(name) &&
+			!isSystemAttribute(config.systemAttributes(), name) && encryption.ActionFor(canonical) != EncryptNone && !s.attributes[canonical] {
+			continue
+		}
+		filtered[name] = value
+	}
+	return filtered
+}
+
+// scopedDynamoDB checks the requests of a scoped client against its scope before passing them to the DynamoDB client.
+type scopedDynamoDB struct {
+	client DynamoDBClientInterface
+	scope  *clientScope
+}
+
+// check fails with ErrOutOfScope unless the scope allows the request on the given tables.
+func (c *scopedDynamoDB) check(operation string, write bool, tableNames ...string) error {
+	switch {
+	case c.scope.mode == scopeDecryptOnly && operation != "DescribeTable":
+		return fmt.Errorf("%w: %s on a decrypt-only client", ErrOutOfScope, operation)
+	case c.scope.mode == scopeReadOnly && write:
+		return fmt.Errorf("%w: %s on a read-only client", ErrOutOfScope, operation)
+	}
+	for _, tableName := range tableNames {
+		if err := c.scope.checkTable(tableName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *scopedDynamoDB) CreateTable(ctx context.Context, input *dynamodb.CreateTableInput, opts ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+	if err := c.check("CreateTable", true, aws.StringValue(input.TableName)); err != nil {
+		return nil, err
+	}
+	return c.client.CreateTable(ctx, input, opts...)
+}
+
+func (c *scopedDynamoDB) PutItem(ctx context.Context, input *dynamodb.PutItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	if err := c.check("PutItem", true, aws.StringValue(input.TableName)); err != nil {
+		return nil, err
+	}
+	return c.client.PutItem(ctx, input, opts...)
+}
+
+func (c *scopedDynamoDB) GetItem(ctx context.Context, input *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if err := c.check("GetItem", false, aws.StringValue(input.TableName)); err != nil {
+		return nil, err
+	}
+	return c.client.GetItem(ctx, input, opts...)
+}
+
+func (c *scopedDynamoDB) Query(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	if err := c.check("Query", false, aws.StringValue(input.TableName)); err != nil {
+		return nil, err
+	}
+	return c.client.Query(ctx, input, opts...)
+}
+
+func (c *scopedDynamoDB) Scan(ctx context.Context, input *dynamodb.ScanInput, opts ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	if err := c.check("Scan", false, aws.StringValue(input.TableName)); err != nil {
+		return nil, err
+	}
+	return c.client.Scan(ctx, input, opts...)
+}
+
+func (c *scopedDynamoDB) BatchGetItem(ctx context.Context, input *dynamodb.BatchGetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	tableNames := make([]string, 0, len(input.RequestItems))
+	for tableName := range input.RequestItems {
+		tableNames = append(tableNames, tableName)
+	}
+	if err := c.check("BatchGetItem", false, tableNames...); err != nil {
+		return nil, err
+	}
+	return c.client.BatchGetItem(ctx, input, opts...)
+}
+
+func (c *scopedDynamoDB) BatchWriteItem(ctx context.Context, input *dynamodb.BatchWriteItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	tableNames := make([]string, 0, len(input.RequestItems))
+	for tableName := range input.RequestItems {
+		tableNames = append(tableNames, tableName)
+	}
+	if err := c.check("BatchWriteItem", true, tableNames...); err != nil {
+		return nil, err
+	}
+	return c.client.BatchWriteItem(ctx, input, opts...)
+}
+
+func (c *scopedDynamoDB) DeleteItem(ctx context.Context, input *dynamodb.DeleteItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	if err := c.check("DeleteItem", true, aws.StringValue(input.TableName)); err != nil {
+		return nil, err
+	}
+	return c.client.DeleteItem(ctx, input, opts...)
+}
+
+func (c *scopedDynamoDB) DescribeTable(ctx context.Context, input *dynamodb.DescribeTableInput, opts ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	if err := c.check("DescribeTable", false, aws.StringValue(input.TableName)); err != nil {
+		return nil, err
+	}
+	return c.client.DescribeTable(ctx, input, opts...)
+}
+
+func (c *scopedDynamoDB) UpdateItem(ctx context.Context, input *dynamodb.UpdateItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	client, ok := c.client.(interface {
+		UpdateItem(context.Context, *dynamodb.UpdateItemInput, ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: UpdateItem", ErrOperationNotSupported)
+	}
+	if err := c.check("UpdateItem", true, aws.StringValue(input.TableName)); err != nil {
+		return nil, err
+	}
+	return client.UpdateItem(ctx, input, opts...)
+}
+
+func (c *scopedDynamoDB) TransactGetItems(ctx context.Context, input *dynamodb.TransactGetItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactGetItemsOutput, error) {
+	client, ok := c.client.(interface {
+		TransactGetItems(context.Context, *dynamodb.TransactGetItemsInput, ...func(*dynamodb.Options)) (*dynamodb.TransactGetItemsOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: TransactGetItems", ErrOperationNotSupported)
+	}
+	var tableNames []string
+	for _, item := range input.TransactItems {
+		if item.Get != nil {
+			tableNames = append(tableNames, aws.StringValue(item.Get.TableName))
+		}
+	}
+	if err := c.check("TransactGetItems", false, tableNames...); err != nil {
+		return nil, err
+	}
+	return client.TransactGetItems(ctx, input, opts...)
+}
+
+func (c *scopedDynamoDB) TransactWriteItems(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	client, ok := c.client.(interface {
+		TransactWriteItems(context.Context, *dynamodb.TransactWriteItemsInput, ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: TransactWriteItems", ErrOperationNotSupported)
+	}
+	var tableNames []string
+	for _, item := range input.TransactItems {
+		switch {
+		case item.ConditionCheck != nil:
+			tableNames = append(tableNames, aws.StringValue(item.ConditionCheck.TableName))
+		case item.Put != nil:
+			tableNames = append(tableNames, aws.StringValue(item.Put.TableName))
+		case item.Delete != nil:
+			tableNames = append(tableNames, aws.StringValue(item.Delete.TableName))
+		case item.Update != nil:
+			tableNames = append(tableNames, aws.StringValue(item.Update.TableName))
+		}
+	}
+	if err := c.check("TransactWriteItems", true, tableNames...); err != nil {
+		return nil, err
+	}
+	return client.TransactWriteItems(ctx, input, opts...)
+}
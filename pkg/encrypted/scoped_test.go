@@ -0,0 +1,143 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+)
+
+func TestEncryptedClient_WithScope(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	client := &fingerprintClient{items: make(map[string]map[string]types.AttributeValue)}
+	ec := NewEncryptedClient(client, &staticProvider{description: map[string]string{}, dataKey: dataKey},
+		WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}),
+		WithPrimaryKeyInfo("orders", PrimaryKeyInfo{PartitionKey: "ID"}),
+		WithOptions(WithEncryption("Role", EncryptNone)),
+	)
+
+	item := map[string]types.AttributeValue{
+		"ID":     &types.AttributeValueMemberS{Value: "1"},
+		"Role":   &types.AttributeValueMemberS{Value: "reader"},
+		"Email":  &types.AttributeValueMemberS{Value: "alice@example.com"},
+		"Secret": &types.AttributeValueMemberS{Value: "value"},
+	}
+	if _, err := ec.PutItem(context.Background(), &dynamodb.PutItemInput{TableName: aws.String("users"), Item: item}); err != nil {
+		t.Fatalf("failed to put item: %v", err)
+	}
+	stored := client.items["1"]
+	key := map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: "1"}}
+
+	get := func(scoped *EncryptedClient) error {
+		_, err := scoped.GetItem(context.Background(), &dynamodb.GetItemInput{TableName: aws.String("users"), Key: key})
+		return err
+	}
+	put := func(scoped *EncryptedClient) error {
+		_, err := scoped.PutItem(context.Background(), &dynamodb.PutItemInput{TableName: aws.String("users"), Item: item})
+		return err
+	}
+	encrypt := func(scoped *EncryptedClient) error {
+		_, err := scoped.EncryptItem(context.Background(), "users", item)
+		return err
+	}
+	decrypt := func(scoped *EncryptedClient) error {
+		_, err := scoped.DecryptItem(context.Background(), "users", stored)
+		return err
+	}
+	deleteItem := func(scoped *EncryptedClient) error {
+		_, err := scoped.DeleteItem(context.Background(), &dynamodb.DeleteItemInput{TableName: aws.String("users"), Key: key})
+		return err
+	}
+	getOrder := func(scoped *EncryptedClient) error {
+		_, err := scoped.DecryptItem(context.Background(), "orders", stored)
+		return err
+	}
+	updateConfig := func(scoped *EncryptedClient) error {
+		return scoped.UpdateConfig(context.Background(), scoped.Config().With(WithItemSignatures(true)))
+	}
+
+	testCases := []struct {
+		name      string
+		scopes    [][]ScopeOption
+		operation func(*EncryptedClient) error
+		allowed   bool
+	}{
+		{name: "Allowed table", scopes: [][]ScopeOption{{AllowTables("users")}}, operation: put, allowed: true},
+		{name: "Other table", scopes: [][]ScopeOption{{AllowTables("users")}}, operation: getOrder},
+		{name: "Narrowed tables", scopes: [][]ScopeOption{{AllowTables("users", "orders")}, {AllowTables("orders")}}, operation: get},
+		{name: "Read-only read", scopes: [][]ScopeOption{{ReadOnly()}}, operation: get, allowed: true},
+		{name: "Read-only write", scopes: [][]ScopeOption{{ReadOnly()}}, operation: put},
+		{name: "Read-only delete", scopes: [][]ScopeOption{{ReadOnly()}}, operation: deleteItem},
+		{name: "Read-only encryption", scopes: [][]ScopeOption{{ReadOnly()}}, operation: encrypt},
+		{name: "Decrypt-only decryption", scopes: [][]ScopeOption{{DecryptOnly()}}, operation: decrypt, allowed: true},
+		{name: "Decrypt-only read", scopes: [][]ScopeOption{{DecryptOnly()}}, operation: get},
+		{name: "Decrypt-only stays decrypt-only", scopes: [][]ScopeOption{{DecryptOnly()}, {ReadOnly()}}, operation: get},
+		{name: "Configuration update", scopes: [][]ScopeOption{{}}, operation: updateConfig},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scoped := ec
+			for _, options := range tc.scopes {
+				scoped = scoped.WithScope(options...)
+			}
+			err := tc.operation(scoped)
+			if tc.allowed && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tc.allowed && !errors.Is(err, ErrOutOfScope) {
+				t.Errorf("expected ErrOutOfScope, got %v", err)
+			}
+		})
+	}
+
+	t.Run("Decryptable attributes", func(t *testing.T) {
+		scoped := ec.WithScope(AllowDecryption("Email", "Secret")).WithScope(AllowDecryption("Email"))
+		output, err := scoped.GetItem(context.Background(), &dynamodb.GetItemInput{TableName: aws.String("users"), Key: key})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := output.Item["Email"].(*types.AttributeValueMemberS).Value; got != "alice@example.com" {
+			t.Errorf("expected Email to decrypt to alice@example.com, got %s", got)
+		}
+		if _, ok := output.Item["Role"]; !ok {
+			t.Errorf("expected plaintext attributes to be returned")
+		}
+		if value, ok := output.Item["Secret"]; ok {
+			t.Errorf("expected Secret to be left out, got %v", value)
+		}
+	})
+
+	t.Run("Configuration updates", func(t *testing.T) {
+		scoped := ec.WithScope(ReadOnly())
+		if err := ec.UpdateConfig(context.Background(), ec.Config().With(WithEncryption("Role", EncryptStandard))); err != nil {
+			t.Fatalf("failed to update config: %v", err)
+		}
+		if scoped.Config() != ec.Config() {
+			t.Errorf("expected the scoped client to follow its parent's configuration")
+		}
+		if err := scoped.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := get(ec); err != nil {
+			t.Errorf("expected closing a scoped client to leave its parent open, got %v", err)
+		}
+		if err := ec.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := decrypt(scoped); !errors.Is(err, ErrClientClosed) {
+			t.Errorf("expected ErrClientClosed, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+)
+
+// SelfTestTable is the table name SelfTest encrypts its canary item for. The table is never accessed, but its name
+// determines the canary's material name.
+const SelfTestTable = "__selftest"
+
+// ErrSelfTestFailed is returned by SelfTest when the canary item does not decrypt to its original value.
+var ErrSelfTestFailed = errors.New("self test failed")
+
+// SelfTest encrypts and decrypts a canary item in memory with the live materials provider, checking that KMS, the
+// material store and the client's configuration work together, for example from a health check after a deploy.
+// The canary's materials are created and stored like any other, then deleted again when the provider implements
+// provider.MaterialDeleter. Read-only providers and verification-only clients can not run a self test.
+func (ec *EncryptedClient) SelfTest(ctx context.Context) error {
+	ctx = operationContext(ctx, "SelfTest")
+	select {
+	case <-ec.done:
+		return ErrClientClosed
+	default:
+	}
+	if ec.verifyOnly != nil {
+		return ErrVerificationOnly
+	}
+	if err := ec.scope.checkEncrypt(); err != nil {
+		return err
+	}
+
+	config := ec.Config()
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	pkInfo := &PrimaryKeyInfo{Table: SelfTestTable, PartitionKey: "ID"}
+	encryption := EncryptionConfig{DefaultAction: EncryptStandard}
+	canary := map[string]types.AttributeValue{
+		"ID":     &types.AttributeValueMemberS{Value: "canary"},
+		"Secret": &types.AttributeValueMemberS{Value: "canary value"},
+	}
+	materialName, err := ConstructMaterialName(canary, pkInfo)
+	if err != nil {
+		return err
+	}
+
+	encryptionMaterials, err := ec.MaterialsProvider.EncryptionMaterials(itemContext(ctx, pkInfo, canary), materialName)
+	if err != nil {
+		return fmt.Errorf("%w: failed to fetch encryption materials: %v", ErrSelfTestFailed, err)
+	}
+	pending := &pendingEncryption{pkInfo: pkInfo, config: config, encryption: encryption, item: canary, materialName: materialName}
+	encryptedItem, err := pending.encrypt(encryptionMaterials)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSelfTestFailed, err)
+	}
+
+	version, err := materialVersion(encryptedItem)
+	if err != nil {
+		return err
+	}
+	decryptedItem, _, err := ec.decryptAttributes(ctx, pkInfo, config, encryption, encryptedItem, materialName, version)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSelfTestFailed, err)
+	}
+	if _, ok := encryptedItem["Secret"].(*types.AttributeValueMemberB); !ok || !reflect.DeepEqual(decryptedItem, canary) {
+		return fmt.Errorf("%w: canary item did not round trip", ErrSelfTestFailed)
+	}
+
+	if deleter, ok := ec.MaterialsProvider.(provider.MaterialDeleter); ok {
+		if err := deleter.DeleteMaterials(itemContext(ctx, pkInfo, canary), materialName); err != nil {
+			return fmt.Errorf("failed to delete canary materials: %v", err)
+		}
+	}
+	return nil
+}
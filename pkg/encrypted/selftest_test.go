@@ -0,0 +1,42 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+)
+
+func TestEncryptedClient_SelfTest(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	otherKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		provider *staticProvider
+		err      error
+	}{
+		{name: "Round trip", provider: &staticProvider{dataKey: dataKey}},
+		{name: "Mismatched keys", provider: &staticProvider{dataKey: dataKey, decryptionKey: otherKey}, err: ErrSelfTestFailed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec := NewEncryptedClient(nil, tc.provider)
+			if err := ec.SelfTest(context.Background()); !errors.Is(err, tc.err) {
+				t.Errorf("Expected error %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
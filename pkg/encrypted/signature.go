@@ -0,0 +1,97 @@
+package encrypted
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/serde"
+)
+
+// SignatureAttribute is the item attribute holding the item's signature when item signatures are enabled with
+// WithItemSignatures. The signature covers the table name and every other attribute as stored, so encrypted and
+// plaintext attributes alike can not be modified, added or removed without detection.
+const SignatureAttribute = "__Signature"
+
+// ErrInvalidSignature is returned when an item's signature does not match its contents.
+var ErrInvalidSignature = errors.New("invalid item signature")
+
+// isReservedAttribute reports whether an attribute is written by the client itself rather than by the caller.
+func isReservedAttribute(name string) bool {
+	switch name {
+	case MaterialVersionAttribute, SignatureAttribute, KeyDerivationAttribute, DeterministicScopeAttribute, MaterialScopeAttribute,
+		PolicyFingerprintAttribute:
+		return true
+	}
+	return false
+}
+
+// signingInput returns the canonical bytes an item's signature covers: every attribute except the signature itself
+// and the system attributes DynamoDB may rewrite.
+func signingInput(tableName string, item map[string]types.AttributeValue, systemAttributes []string) ([]byte, error) {
+	signed := make(map[string]types.AttributeValue, len(item))
+	for name, value := range item {
+		if name != SignatureAttribute && !isSystemAttribute(systemAttributes, name) {
+			signed[name] = value
+		}
+	}
+
+	// The serializer orders map entries and set members, so the encoding does not depend on map iteration order
+	data, err := serde.NewSerializer().Serialize(&types.AttributeValueMemberM{Value: signed})
+	if err != nil {
+		return nil, fmt.Errorf("error serializing item for signing: %v", err)
+	}
+	return append([]byte(tableName+"\x00"), data...), nil
+}
+
+// signItem adds a signature over the stored item, made with the materials' signing key.
+func signItem(tableName string, item map[string]types.AttributeValue, encryptionMaterials materials.CryptographicMaterials, systemAttributes []string) error {
+	signingKey := encryptionMaterials.SigningKey()
+	if signingKey == nil {
+		return fmt.Errorf("encryption materials do not provide a signing key")
+	}
+
+	data, err := signingInput(tableName, item, systemAttributes)
+	if err != nil {
+		return err
+	}
+	signature, err := signingKey.Sign(data)
+	if err != nil {
+		return fmt.Errorf("error signing item: %v", err)
+	}
+	item[SignatureAttribute] = &types.AttributeValueMemberB{Value: signature}
+	return nil
+}
+
+// verifyItem checks a stored item's signature against the PublicKey in its material description. Items without a
+// signature are accepted.
+func verifyItem(tableName string, item map[string]types.AttributeValue, materialDescription map[string]string, systemAttributes []string) error {
+	value, ok := item[SignatureAttribute]
+	if !ok {
+		return nil
+	}
+	signature, ok := value.(*types.AttributeValueMemberB)
+	if !ok {
+		return fmt.Errorf("%w: unexpected type for %s attribute", ErrInvalidSignature, SignatureAttribute)
+	}
+
+	publicKey, err := base64.StdEncoding.DecodeString(materialDescription["PublicKey"])
+	if err != nil || len(publicKey) == 0 {
+		return fmt.Errorf("%w: materials have no public key", ErrInvalidSignature)
+	}
+	data, err := signingInput(tableName, item, systemAttributes)
+	if err != nil {
+		return err
+	}
+	valid, err := delegatedkeys.VerifySignature(publicKey, signature.Value, data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if !valid {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package encrypted
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+)
+
+func TestItemSignatures(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	signingKey, _, publicKey, err := delegatedkeys.GenerateSigningKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+	description := map[string]string{"PublicKey": base64.StdEncoding.EncodeToString(publicKey)}
+	encryptionMaterials := materials.NewEncryptionMaterials(description, nil, signingKey)
+
+	newItem := func() map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"ID":     &types.AttributeValueMemberS{Value: "1"},
+			"Role":   &types.AttributeValueMemberS{Value: "reader"},
+			"Secret": &types.AttributeValueMemberB{Value: []byte{1, 2, 3}},
+			"Tags":   &types.AttributeValueMemberSS{Value: []string{"b", "a"}},
+		}
+	}
+
+	testCases := []struct {
+		name   string
+		table  string
+		tamper func(item map[string]types.AttributeValue)
+		err    error
+	}{
+		{name: "Unmodified", table: "users"},
+		{name: "Plaintext attribute changed", table: "users", tamper: func(item map[string]types.AttributeValue) {
+			item["Role"] = &types.AttributeValueMemberS{Value: "admin"}
+		}, err: ErrInvalidSignature},
+		{name: "Attribute removed", table: "users", tamper: func(item map[string]types.AttributeValue) {
+			delete(item, "Secret")
+		}, err: ErrInvalidSignature},
+		{name: "Set reordered", table: "users", tamper: func(item map[string]types.AttributeValue) {
+			item["Tags"] = &types.AttributeValueMemberSS{Value: []string{"a", "b"}}
+		}},
+		{name: "Copied to another table", table: "admins", err: ErrInvalidSignature},
+		{name: "Replication metadata added", table: "users", tamper: func(item map[string]types.AttributeValue) {
+			item["aws:rep:updatetime"] = &types.AttributeValueMemberN{Value: "1700000000.000001"}
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			item := newItem()
+			if err := signItem("users", item, encryptionMaterials, DefaultSystemAttributes); err != nil {
+				t.Fatalf("failed to sign item: %v", err)
+			}
+			if tc.tamper != nil {
+				tc.tamper(item)
+			}
+			if err := verifyItem(tc.table, item, description, DefaultSystemAttributes); !errors.Is(err, tc.err) {
+				t.Errorf("Expected error %v, got %v", tc.err, err)
+			}
+		})
+	}
+
+	// Unsigned items are accepted
+	if err := verifyItem("users", newItem(), description, nil); err != nil {
+		t.Errorf("Expected unsigned item to be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package encrypted
+
+import (
+	"path"
+)
+
+// DefaultSystemAttributes matches the attributes DynamoDB global tables (version 2017.11.29) add to every item to
+// resolve replication conflicts, such as aws:rep:updatetime.
+var DefaultSystemAttributes = []string{"aws:rep:*"}
+
+// WithSystemAttributes replaces DefaultSystemAttributes with the given path.Match patterns. Attributes matching
+// them are maintained by DynamoDB rather than the caller: they are stored and returned as is, never encrypted, and
+// left out of item signatures, since DynamoDB rewrites them after the item was signed. Calling it without patterns
+// treats every attribute as a regular one.
+func WithSystemAttributes(patterns ...string) Option {
+	return func(c *ClientConfig) {
+		c.mustBeMutable()
+		c.SystemAttributes = append(make([]string, 0, len(patterns)), patterns...)
+	}
+}
+
+// systemAttributes returns the configured system attribute patterns, or DefaultSystemAttributes when none are set.
+func (c *ClientConfig) systemAttributes() []string {
+	if c.SystemAttributes == nil {
+		return DefaultSystemAttributes
+	}
+	return c.SystemAttributes
+}
+
+// isSystemAttribute reports whether an attribute matches any of the system attribute patterns.
+func isSystemAttribute(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package encrypted
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestSystemAttributes(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+
+	item := map[string]types.AttributeValue{
+		"ID":                   &types.AttributeValueMemberS{Value: "1"},
+		"Email":                &types.AttributeValueMemberS{Value: "a@example.com"},
+		"aws:rep:updateregion": &types.AttributeValueMemberS{Value: "eu-west-2"},
+	}
+
+	testCases := []struct {
+		name      string
+		options   []Option
+		plaintext bool
+	}{
+		{name: "Default", plaintext: true},
+		{name: "Override", options: []Option{WithSystemAttributes("aws:rep:deleting")}},
+		{name: "Disabled", options: []Option{WithSystemAttributes()}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec := NewEncryptedClient(nil, &staticProvider{dataKey: dataKey},
+				WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}),
+				WithOptions(tc.options...),
+			)
+			encryptedItem, err := ec.encryptItem(context.Background(), "users", item)
+			if err != nil {
+				t.Fatalf("failed to encrypt item: %v", err)
+			}
+			_, plaintext := encryptedItem["aws:rep:updateregion"].(*types.AttributeValueMemberS)
+			if plaintext != tc.plaintext {
+				t.Errorf("expected aws:rep:updateregion stored in plaintext: %v, got %T", tc.plaintext, encryptedItem["aws:rep:updateregion"])
+			}
+
+			decrypted, err := ec.decryptItem(context.Background(), "users", encryptedItem)
+			if err != nil {
+				t.Fatalf("failed to decrypt item: %v", err)
+			}
+			if diff := cmp.Diff(item, decrypted, cmpopts.IgnoreUnexported(types.AttributeValueMemberS{})); diff != "" {
+				t.Errorf("unexpected item (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
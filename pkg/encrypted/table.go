@@ -2,11 +2,13 @@ package encrypted
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider/store"
 )
 
 // EncryptedTable provides a high-level interface to encrypted DynamoDB operations.
@@ -14,21 +16,65 @@ type EncryptedTable struct {
 	client *EncryptedClient
 }
 
-// NewEncryptedTable creates a new EncryptedTable with the given EncryptedClient.
+// NewEncryptedTable creates a new EncryptedTable with the given EncryptedClient. NewTable also validates the tables
+// it will be used with.
 func NewEncryptedTable(client *EncryptedClient) *EncryptedTable {
 	return &EncryptedTable{
 		client: client,
 	}
 }
 
+// NewTable creates an EncryptedTable like NewEncryptedTable, but checks the client and each of the given tables
+// eagerly. The key schema of tables not configured with WithPrimaryKeyInfo is loaded with DescribeTable and
+// validated against the client's configuration.
+func NewTable(ctx context.Context, client *EncryptedClient, tableNames ...string) (*EncryptedTable, error) {
+	if client == nil {
+		return nil, errors.New("an encrypted client is required")
+	}
+	for _, tableName := range tableNames {
+		if err := client.ValidateConfig(ctx, tableName); err != nil {
+			return nil, fmt.Errorf("table %s: %w", tableName, err)
+		}
+	}
+	return NewEncryptedTable(client), nil
+}
+
+// PutItemOptions holds the optional settings of EncryptedTable.PutItem.
+type PutItemOptions struct {
+	ConditionExpression       *string
+	ExpressionAttributeNames  map[string]string
+	ExpressionAttributeValues map[string]types.AttributeValue
+}
+
+// PutItemOption configures a single EncryptedTable.PutItem call.
+type PutItemOption func(*PutItemOptions)
+
+// WithPutCondition makes PutItem write the item only if the stored item satisfies the condition expression, for
+// example "attribute_not_exists(ID)" for idempotent creates. Values compared with deterministic attributes are
+// encrypted like the attributes; conditions on other encrypted attributes are limited to existence checks and fail
+// with a *ConditionError otherwise. A failed condition returns the SDK's ConditionalCheckFailedException.
+func WithPutCondition(expression string, names map[string]string, values map[string]types.AttributeValue) PutItemOption {
+	return func(o *PutItemOptions) {
+		o.ConditionExpression = aws.String(expression)
+		o.ExpressionAttributeNames = names
+		o.ExpressionAttributeValues = values
+	}
+}
+
 // PutItem encrypts and stores an item in the DynamoDB table.
-func (et *EncryptedTable) PutItem(ctx context.Context, tableName string, item map[string]types.AttributeValue) error {
+func (et *EncryptedTable) PutItem(ctx context.Context, tableName string, item map[string]types.AttributeValue, opts ...PutItemOption) error {
+	var options PutItemOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
 	putItemInput := &dynamodb.PutItemInput{
-		TableName: &tableName,
-		Item:      item,
+		TableName:                 &tableName,
+		Item:                      item,
+		ConditionExpression:       options.ConditionExpression,
+		ExpressionAttributeNames:  options.ExpressionAttributeNames,
+		ExpressionAttributeValues: options.ExpressionAttributeValues,
 	}
-	_, err := et.client.PutItem(ctx, putItemInput)
-	if err != nil {
+	if _, err := et.client.PutItem(ctx, putItemInput); err != nil {
 		return fmt.Errorf("failed to put encrypted item: %w", err)
 	}
 	return nil
@@ -71,8 +117,23 @@ func (et *EncryptedTable) Scan(ctx context.Context, tableName string, input *dyn
 	return encryptedOutput, nil
 }
 
-// CreateTable creates a new DynamoDB table with the specified name, attribute definitions, and key schema.
-func (et *EncryptedTable) CreateTable(ctx context.Context, tableName string, attributes []types.AttributeDefinition, keySchema []types.KeySchemaElement) error {
+// ParallelScan scans the DynamoDB table in segments read concurrently and calls fn with every decrypted item, like
+// EncryptedClient.ParallelScan. input may be nil to read every item.
+func (et *EncryptedTable) ParallelScan(ctx context.Context, tableName string, input *dynamodb.ScanInput, fn func(ctx context.Context, item map[string]types.AttributeValue) error, opts ...ParallelScanOption) error {
+	if input == nil {
+		input = &dynamodb.ScanInput{}
+	}
+	input.TableName = &tableName
+
+	if err := et.client.ParallelScan(ctx, input, fn, opts...); err != nil {
+		return fmt.Errorf("error scanning encrypted items: %w", err)
+	}
+	return nil
+}
+
+// CreateTable creates a new DynamoDB table with the specified name, attribute definitions, and key schema,
+// and waits until it is ACTIVE. Tags, server-side encryption and time to live can be set through options.
+func (et *EncryptedTable) CreateTable(ctx context.Context, tableName string, attributes []types.AttributeDefinition, keySchema []types.KeySchemaElement, opts ...store.TableOption) error {
 	input := &dynamodb.CreateTableInput{
 		AttributeDefinitions: attributes,
 		KeySchema:            keySchema,
@@ -80,10 +141,5 @@ func (et *EncryptedTable) CreateTable(ctx context.Context, tableName string, att
 		TableName:            aws.String(tableName),
 	}
 
-	_, err := et.client.CreateTable(ctx, input)
-	if err != nil {
-		return fmt.Errorf("failed to create table: %w", err)
-	}
-
-	return nil
+	return store.CreateTable(ctx, et.client, input, opts...)
 }
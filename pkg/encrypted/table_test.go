@@ -0,0 +1,192 @@
+package encrypted
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/cloudopsy/dynamodb-encryption-go/internal/fakeddb"
+	fakeawskms "github.com/cloudopsy/dynamodb-encryption-go/internal/fakekms"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider/store"
+)
+
+// putClient records PutItem requests.
+type putClient struct {
+	DynamoDBClientInterface
+	input *dynamodb.PutItemInput
+}
+
+func (c *putClient) PutItem(ctx context.Context, input *dynamodb.PutItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	c.input = input
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func TestEncryptedTable_PutItemCondition(t *testing.T) {
+	email := &types.AttributeValueMemberS{Value: "a@example.com"}
+	item := map[string]types.AttributeValue{
+		"ID":    &types.AttributeValueMemberS{Value: "1"},
+		"Email": email,
+	}
+
+	testCases := []struct {
+		name      string
+		opts      []PutItemOption
+		condition string
+		encrypted bool
+		err       bool
+	}{
+		{name: "Unconditional"},
+		{
+			name:      "Idempotent",
+			opts:      []PutItemOption{WithPutCondition("attribute_not_exists(ID)", nil, nil)},
+			condition: "attribute_not_exists(ID)",
+		},
+		{
+			name:      "Deterministic",
+			opts:      []PutItemOption{WithPutCondition("Email = :email", nil, map[string]types.AttributeValue{":email": email})},
+			condition: "Email = :email",
+			encrypted: true,
+		},
+		{
+			name: "Randomized",
+			opts: []PutItemOption{WithPutCondition("Secret = :s", nil, map[string]types.AttributeValue{":s": email})},
+			err:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &putClient{}
+			ec := NewEncryptedClient(client, &staticProvider{dataKey: deterministicKey{}},
+				WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}),
+				WithOptions(WithEncryption("Email", EncryptDeterministic)),
+			)
+
+			err := NewEncryptedTable(ec).PutItem(context.Background(), "users", item, tc.opts...)
+			if tc.err {
+				var conditionErr *ConditionError
+				if !errors.As(err, &conditionErr) {
+					t.Errorf("expected a ConditionError, got %v", err)
+				}
+				if client.input != nil {
+					t.Errorf("expected nothing to be written")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to put item: %v", err)
+			}
+
+			if got := aws.StringValue(client.input.ConditionExpression); got != tc.condition {
+				t.Errorf("expected condition %q, got %q", tc.condition, got)
+			}
+			if _, ok := client.input.Item["Email"].(*types.AttributeValueMemberB); !ok {
+				t.Errorf("expected Email to be encrypted, got %T", client.input.Item["Email"])
+			}
+			if _, ok := client.input.ExpressionAttributeValues[":email"].(*types.AttributeValueMemberB); ok != tc.encrypted {
+				t.Errorf("expected :email to be encrypted: %v", tc.encrypted)
+			}
+		})
+	}
+}
+
+// conditionClient stores items like memoryClient and records the expression attribute values of the last
+// conditional write.
+type conditionClient struct {
+	*memoryClient
+	values map[string]types.AttributeValue
+}
+
+func (c *conditionClient) PutItem(ctx context.Context, input *dynamodb.PutItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	if input.ConditionExpression != nil {
+		c.values = input.ExpressionAttributeValues
+	}
+	return c.memoryClient.PutItem(ctx, input, opts...)
+}
+
+func (c *conditionClient) DeleteItem(ctx context.Context, input *dynamodb.DeleteItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	c.values = input.ExpressionAttributeValues
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func (c *conditionClient) TransactWriteItems(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	c.values = input.TransactItems[0].ConditionCheck.ExpressionAttributeValues
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+func TestConditions_ProviderMaterials(t *testing.T) {
+	const keyURI = "arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b"
+	kms, err := fakeawskms.New([]string{keyURI})
+	if err != nil {
+		t.Fatalf("failed to create fake KMS: %v", err)
+	}
+	materialStore, err := store.NewMetaStore(fakeddb.New().Client(), "meta")
+	if err != nil {
+		t.Fatalf("failed to create meta store: %v", err)
+	}
+	materialsProvider, err := provider.New(keyURI, nil, materialStore, provider.WithKMSClient(kms))
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+	ctx := context.Background()
+	key := map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: "1"}}
+	condition := "Email = :email AND Email <> :other"
+	values := map[string]types.AttributeValue{
+		":email": &types.AttributeValueMemberS{Value: "a@example.com"},
+		":other": &types.AttributeValueMemberS{Value: "b@example.com"},
+	}
+
+	testCases := []struct {
+		name  string
+		write func(ec *EncryptedClient) error
+	}{
+		{name: "PutItem", write: func(ec *EncryptedClient) error {
+			return NewEncryptedTable(ec).PutItem(ctx, "users", map[string]types.AttributeValue{"ID": key["ID"], "Email": values[":other"]}, WithPutCondition(condition, nil, values))
+		}},
+		{name: "DeleteItem", write: func(ec *EncryptedClient) error {
+			_, err := ec.DeleteItem(ctx, &dynamodb.DeleteItemInput{TableName: aws.String("users"), Key: key, ConditionExpression: aws.String(condition), ExpressionAttributeValues: values})
+			return err
+		}},
+		{name: "ConditionCheck", write: func(ec *EncryptedClient) error {
+			_, err := ec.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: []types.TransactWriteItem{{
+				ConditionCheck: &types.ConditionCheck{TableName: aws.String("users"), Key: key, ConditionExpression: aws.String(condition), ExpressionAttributeValues: values},
+			}}})
+			return err
+		}},
+	}
+
+	for _, tc := range testCases {
+		for _, attributeKeys := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/attribute keys %v", tc.name, attributeKeys), func(t *testing.T) {
+				client := &conditionClient{memoryClient: &memoryClient{items: make(map[string]map[string]types.AttributeValue)}}
+				ec := NewEncryptedClient(client, materialsProvider,
+					WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}),
+					WithOptions(WithEncryption("Email", EncryptDeterministic), WithAttributeKeys(attributeKeys)),
+				)
+				if err := NewEncryptedTable(ec).PutItem(ctx, "users", map[string]types.AttributeValue{"ID": key["ID"], "Email": values[":email"]}); err != nil {
+					t.Fatalf("failed to put item: %v", err)
+				}
+				stored := client.items["1"]["Email"].(*types.AttributeValueMemberB).Value
+
+				if err := tc.write(ec); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				// DynamoDB compares the sent values with the stored ciphertext
+				email, ok := client.values[":email"].(*types.AttributeValueMemberB)
+				if !ok || !bytes.Equal(email.Value, stored) {
+					t.Errorf("expected :email to be sent as the stored ciphertext, got %v", client.values[":email"])
+				}
+				other, ok := client.values[":other"].(*types.AttributeValueMemberB)
+				if !ok || bytes.Equal(other.Value, stored) {
+					t.Errorf("expected :other to be sent as another ciphertext, got %v", client.values[":other"])
+				}
+			})
+		}
+	}
+}
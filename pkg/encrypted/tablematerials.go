@@ -0,0 +1,42 @@
+package encrypted
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/utils"
+)
+
+// MaterialScopeAttribute is the item attribute recording that an item is encrypted under the materials shared by its
+// table, enabled with WithTableMaterials. Items without it are encrypted under materials named by their primary key.
+const MaterialScopeAttribute = "__MaterialScope"
+
+// MaterialScopeTable is the value of MaterialScopeAttribute for items encrypted under their table's materials.
+const MaterialScopeTable = "table"
+
+// TableMaterialName returns the name of the materials shared by the items of a table. Table names can not contain
+// '#', so it never equals the material name of a single item.
+func TableMaterialName(tableName string) string {
+	return utils.HashString(tableName + "#" + MaterialScopeTable)
+}
+
+// materialName returns the name of the materials a new item is encrypted under.
+func (c *ClientConfig) materialName(item map[string]types.AttributeValue, pkInfo *PrimaryKeyInfo) (string, error) {
+	if c.TableMaterials {
+		return TableMaterialName(pkInfo.Table), nil
+	}
+	return ConstructMaterialName(item, pkInfo)
+}
+
+// itemMaterialName returns the name of the materials a stored item was encrypted under.
+func itemMaterialName(item map[string]types.AttributeValue, pkInfo *PrimaryKeyInfo) (string, error) {
+	value, ok := item[MaterialScopeAttribute]
+	if !ok {
+		return ConstructMaterialName(item, pkInfo)
+	}
+	scope, ok := value.(*types.AttributeValueMemberS)
+	if !ok || scope.Value != MaterialScopeTable {
+		return "", fmt.Errorf("unsupported material scope in %s attribute", MaterialScopeAttribute)
+	}
+	return TableMaterialName(pkInfo.Table), nil
+}
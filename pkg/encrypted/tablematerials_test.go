@@ -0,0 +1,101 @@
+package encrypted
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// namingProvider records the material names it is asked for.
+type namingProvider struct {
+	provider.CryptographicMaterialsProvider
+	dataKey     delegatedkeys.DelegatedKey
+	encryptions []string
+	decryptions []string
+}
+
+func (p *namingProvider) EncryptionMaterials(ctx context.Context, materialName string) (materials.CryptographicMaterials, error) {
+	p.encryptions = append(p.encryptions, materialName)
+	return materials.NewEncryptionMaterials(map[string]string{provider.MaterialVersionKey: "1"}, p.dataKey, nil), nil
+}
+
+func (p *namingProvider) DecryptionMaterials(ctx context.Context, materialName string, version int64) (materials.CryptographicMaterials, error) {
+	p.decryptions = append(p.decryptions, materialName)
+	return materials.NewDecryptionMaterials(nil, p.dataKey), nil
+}
+
+func TestTableMaterials(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	pkInfo := &PrimaryKeyInfo{Table: "users", PartitionKey: "ID"}
+	items := []map[string]types.AttributeValue{
+		{"ID": &types.AttributeValueMemberS{Value: "1"}, "Email": &types.AttributeValueMemberS{Value: "a@example.com"}},
+		{"ID": &types.AttributeValueMemberS{Value: "2"}, "Email": &types.AttributeValueMemberS{Value: "b@example.com"}},
+	}
+	itemNames := make([]string, len(items))
+	for i, item := range items {
+		name, err := ConstructMaterialName(item, pkInfo)
+		if err != nil {
+			t.Fatalf("failed to construct material name: %v", err)
+		}
+		itemNames[i] = name
+	}
+	tableName := TableMaterialName("users")
+
+	testCases := []struct {
+		name           string
+		tableMaterials bool
+		want           []string
+	}{
+		{name: "ItemMaterials", want: itemNames},
+		{name: "TableMaterials", tableMaterials: true, want: []string{tableName}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &namingProvider{dataKey: dataKey}
+			writer := NewEncryptedClient(nil, p, WithPrimaryKeyInfo("users", *pkInfo), WithOptions(WithTableMaterials(tc.tableMaterials)))
+			reader := NewEncryptedClient(nil, p, WithPrimaryKeyInfo("users", *pkInfo))
+
+			requestItems := map[string][]types.WriteRequest{"users": {
+				{PutRequest: &types.PutRequest{Item: items[0]}},
+				{PutRequest: &types.PutRequest{Item: items[1]}},
+			}}
+			if err := writer.encryptWriteRequests(context.Background(), requestItems); err != nil {
+				t.Fatalf("failed to encrypt write requests: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, p.encryptions, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+				t.Errorf("unexpected materials fetched (-want +got):\n%s", diff)
+			}
+
+			for i, writeRequest := range requestItems["users"] {
+				decrypted, err := reader.decryptItem(context.Background(), "users", writeRequest.PutRequest.Item)
+				if err != nil {
+					t.Fatalf("failed to decrypt item: %v", err)
+				}
+				if diff := cmp.Diff(items[i], decrypted, cmpopts.IgnoreUnexported(types.AttributeValueMemberS{})); diff != "" {
+					t.Errorf("unexpected item (-want +got):\n%s", diff)
+				}
+				want := itemNames[i]
+				if tc.tableMaterials {
+					want = tableName
+				}
+				if got := p.decryptions[len(p.decryptions)-1]; got != want {
+					t.Errorf("expected item %d to decrypt with materials %s, got %s", i, want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,490 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/serde"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/utils"
+)
+
+// ErrSignedItemUpdate is returned by UpdateItem when items are signed, since changing attributes in place would
+// invalidate the item's signature.
+var ErrSignedItemUpdate = errors.New("signed items can not be updated in place")
+
+// ConditionError is returned when an expression references an encrypted attribute in a way DynamoDB can not
+// evaluate against its ciphertext. Standard attributes are encrypted with randomized ciphertexts, so they can only be
+// checked with attribute_exists and attribute_not_exists. Deterministic attributes can additionally be compared for
+// equality with expression attribute values, which are then encrypted before the request is sent.
+type ConditionError struct {
+	TableName string
+	Attribute string
+	Action    EncryptionAction
+	Reason    string
+}
+
+func (e *ConditionError) Error() string {
+	return fmt.Sprintf("condition on encrypted attribute %q of table %s: %s", e.Attribute, e.TableName, e.Reason)
+}
+
+// TransactWriteItems encrypts the items of Put actions and runs the transaction. Values compared with deterministic
+// attributes in condition expressions, including those of ConditionCheck actions, are encrypted with the latest
+// materials of the item the action targets, so they match items written with those materials. Conditions DynamoDB
+// can not evaluate against ciphertexts, and update expressions referencing encrypted attributes, fail with a
+// *ConditionError before anything is written. When items are signed, Update actions fail with ErrSignedItemUpdate, as
+// in UpdateItem. When material cleanup is enabled, the materials of deleted items are removed once the transaction
+// succeeds.
+func (ec *EncryptedClient) TransactWriteItems(ctx context.Context, input *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	client, ok := ec.Client.(interface {
+		TransactWriteItems(context.Context, *dynamodb.TransactWriteItemsInput, ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: TransactWriteItems", ErrOperationNotSupported)
+	}
+	if ec.verifyOnly != nil {
+		return nil, ErrVerificationOnly
+	}
+	ctx = operationContext(ctx, "TransactWriteItems")
+
+	// Build a copy of the request so the caller's input keeps its plaintext items and values
+	encryptedInput := *input
+	encryptedInput.TransactItems = make([]types.TransactWriteItem, len(input.TransactItems))
+	for i, item := range input.TransactItems {
+		var err error
+		switch {
+		case item.ConditionCheck != nil:
+			check := *item.ConditionCheck
+			check.ExpressionAttributeValues, err = ec.encryptConditionValues(ctx, aws.StringValue(check.TableName), check.Key, check.ConditionExpression, nil, check.ExpressionAttributeNames, check.ExpressionAttributeValues)
+			encryptedInput.TransactItems[i].ConditionCheck = &check
+		case item.Put != nil:
+			put := *item.Put
+			// Conditions compare against the stored item, so resolve them before new materials are created
+			put.ExpressionAttributeValues, err = ec.encryptConditionValues(ctx, aws.StringValue(put.TableName), put.Item, put.ConditionExpression, nil, put.ExpressionAttributeNames, put.ExpressionAttributeValues)
+			if err == nil {
+				put.Item, err = ec.encryptItem(ctx, aws.StringValue(put.TableName), put.Item)
+			}
+			encryptedInput.TransactItems[i].Put = &put
+		case item.Delete != nil:
+			del := *item.Delete
+			del.ExpressionAttributeValues, err = ec.encryptConditionValues(ctx, aws.StringValue(del.TableName), del.Key, del.ConditionExpression, nil, del.ExpressionAttributeNames, del.ExpressionAttributeValues)
+			encryptedInput.TransactItems[i].Delete = &del
+		case item.Update != nil:
+			if ec.Config().SignItems {
+				err = ErrSignedItemUpdate
+				break
+			}
+			update := *item.Update
+			update.ExpressionAttributeValues, err = ec.encryptConditionValues(ctx, aws.StringValue(update.TableName), update.Key, update.ConditionExpression, update.UpdateExpression, update.ExpressionAttributeNames, update.ExpressionAttributeValues)
+			encryptedInput.TransactItems[i].Update = &update
+		}
+		if err != nil {
+			return nil, fmt.Errorf("transaction item %d: %w", i, err)
+		}
+	}
+
+	output, err := client.TransactWriteItems(ctx, &encryptedInput, optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range input.TransactItems {
+		if item.Delete == nil {
+			continue
+		}
+		if err := ec.deleteMaterials(ctx, aws.StringValue(item.Delete.TableName), item.Delete.Key); err != nil {
+			return nil, err
+		}
+	}
+
+	return output, nil
+}
+
+// UpdateItem updates plaintext attributes of an item in place. Update expressions can not reference encrypted
+// attributes, since their new values could not be encrypted with the item's materials, and conditions follow the
+// same rules as in TransactWriteItems; violations fail with a *ConditionError before anything is written. When items
+// are signed, updates fail with ErrSignedItemUpdate. With ReturnValues set to ALL_OLD or ALL_NEW, the returned item is
+// decrypted; UPDATED_OLD and UPDATED_NEW only return plaintext attributes.
+func (ec *EncryptedClient) UpdateItem(ctx context.Context, input *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	client, ok := ec.Client.(interface {
+		UpdateItem(context.Context, *dynamodb.UpdateItemInput, ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: UpdateItem", ErrOperationNotSupported)
+	}
+	if ec.verifyOnly != nil {
+		return nil, ErrVerificationOnly
+	}
+	if ec.Config().SignItems {
+		return nil, ErrSignedItemUpdate
+	}
+	ctx = operationContext(ctx, "UpdateItem")
+	tableName := aws.StringValue(input.TableName)
+
+	values, err := ec.encryptConditionValues(ctx, tableName, input.Key, input.ConditionExpression, input.UpdateExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	encryptedInput := *input
+	encryptedInput.ExpressionAttributeValues = values
+
+	output, err := client.UpdateItem(ctx, &encryptedInput, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	if input.ReturnValues == types.ReturnValueAllOld || input.ReturnValues == types.ReturnValueAllNew {
+		if output.Attributes, err = ec.decryptReturnedItem(ctx, tableName, output.Attributes); err != nil {
+			return nil, err
+		}
+	}
+	return output, nil
+}
+
+// TransactGetItems reads items in a transaction and decrypts them. Responses keep the order of the requested items,
+// with nil items for those that do not exist.
+func (ec *EncryptedClient) TransactGetItems(ctx context.Context, input *dynamodb.TransactGetItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactGetItemsOutput, error) {
+	client, ok := ec.Client.(interface {
+		TransactGetItems(context.Context, *dynamodb.TransactGetItemsInput, ...func(*dynamodb.Options)) (*dynamodb.TransactGetItemsOutput, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: TransactGetItems", ErrOperationNotSupported)
+	}
+	ctx = operationContext(ctx, "TransactGetItems")
+
+	output, err := client.TransactGetItems(ctx, input, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range output.Responses {
+		if output.Responses[i].Item == nil || i >= len(input.TransactItems) || input.TransactItems[i].Get == nil {
+			continue
+		}
+		tableName := aws.StringValue(input.TransactItems[i].Get.TableName)
+		if output.Responses[i].Item, err = ec.decryptItem(ctx, tableName, output.Responses[i].Item); err != nil {
+			return nil, fmt.Errorf("transaction item %d: %w", i, err)
+		}
+	}
+	return output, nil
+}
+
+// encryptConditionValues checks a condition expression, and optionally an update expression, against the table's
+// encryption settings. It returns the expression attribute values with every value compared to a deterministic
+// attribute encrypted under the materials of the item identified by key, and every value compared to a hashed
+// attribute replaced by its digest.
+func (ec *EncryptedClient) encryptConditionValues(ctx context.Context, tableName string, key map[string]types.AttributeValue, condition, update *string, names map[string]string, values map[string]types.AttributeValue) (map[string]types.AttributeValue, error) {
+	if condition == nil && update == nil {
+		return values, nil
+	}
+
+	pkInfo, err := ec.getPrimaryKeyInfo(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+	config := ec.Config()
+	encryption := applyActionOverrides(ctx, config.Encryption)
+	actionFor := func(attributeName string) EncryptionAction {
+		if isReservedAttribute(attributeName) || pkInfo.IsKeyAttribute(attributeName) || isSystemAttribute(config.systemAttributes(), attributeName) {
+			return EncryptNone
+		}
+		return encryption.ActionFor(attributeName)
+	}
+
+	conditionTokens, err := tokenizeExpression(aws.StringValue(condition), names)
+	if err != nil {
+		return nil, err
+	}
+	updateTokens, err := tokenizeExpression(aws.StringValue(update), names)
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range updateTokens {
+		if token.kind == tokenPath && actionFor(token.attribute) != EncryptNone {
+			return nil, &ConditionError{TableName: tableName, Attribute: token.attribute, Action: actionFor(token.attribute), Reason: "update expressions can not reference encrypted attributes"}
+		}
+	}
+
+	compared, err := comparedValues(tableName, conditionTokens, actionFor)
+	if err != nil {
+		return nil, err
+	}
+	if len(compared) == 0 {
+		return values, nil
+	}
+
+	// A value compared with an encrypted attribute can not be used anywhere else, since it is replaced by ciphertext
+	uses := make(map[string]int)
+	for _, token := range append(conditionTokens, updateTokens...) {
+		if token.kind == tokenValue {
+			uses[token.text]++
+		}
+	}
+	placeholders := make([]string, 0, len(compared))
+	for placeholder, comparison := range compared {
+		if uses[placeholder] != comparison.uses {
+			return nil, &ConditionError{TableName: tableName, Attribute: comparison.attribute, Action: comparison.action, Reason: fmt.Sprintf("value %s is also used outside comparisons with the attribute", placeholder)}
+		}
+		placeholders = append(placeholders, placeholder)
+	}
+	sort.Strings(placeholders)
+
+	encryptedValues := make(map[string]types.AttributeValue, len(values))
+	for placeholder, value := range values {
+		encryptedValues[placeholder] = value
+	}
+	var decryptionMaterials materials.CryptographicMaterials
+	serializer := serde.NewSerializer()
+	for _, placeholder := range placeholders {
+		value, ok := values[placeholder]
+		if !ok {
+			return nil, fmt.Errorf("expression attribute value %s is not defined", placeholder)
+		}
+		if compared[placeholder].action == EncryptHash {
+			if encryptedValues[placeholder], err = HashValue(ec.hashKey, compared[placeholder].attribute, value); err != nil {
+				return nil, fmt.Errorf("error hashing value %s: %w", placeholder, err)
+			}
+			continue
+		}
+
+		// Deterministic values are encrypted with the item's latest materials, fetched once
+		if decryptionMaterials == nil {
+			materialName, err := config.materialName(key, pkInfo)
+			if err != nil {
+				return nil, fmt.Errorf("error constructing material name: %w", err)
+			}
+			decryptionMaterials, err = ec.MaterialsProvider.DecryptionMaterials(itemContext(ctx, pkInfo, key), materialName, 0)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch decryption materials: %v", err)
+			}
+		}
+		rawData, err := serializer.SerializeCompressed(value, config.Compression)
+		if err != nil {
+			return nil, fmt.Errorf("error serializing attribute value: %v", err)
+		}
+		attribute := compared[placeholder].attribute
+		dataKey, err := attributeKey(decryptionMaterials.DecryptionKey(), attribute, config.AttributeKeys)
+		if err != nil {
+			return nil, err
+		}
+		deterministicKey, ok := dataKey.(delegatedkeys.DeterministicKey)
+		if !ok {
+			return nil, &ConditionError{TableName: tableName, Attribute: attribute, Action: EncryptDeterministic, Reason: "the item's data key does not support deterministic encryption"}
+		}
+		associatedData, err := config.DeterministicScope.associatedData(tableName, pkInfo.PartitionKey, key, attribute)
+		if err != nil {
+			return nil, err
+		}
+		encryptedData, err := deterministicKey.EncryptDeterministically(rawData, associatedData)
+		utils.Zero(rawData)
+		releaseAttributeKey(dataKey, config.AttributeKeys)
+		if errors.Is(err, delegatedkeys.ErrNoDeterministicKeyset) {
+			return nil, &ConditionError{TableName: tableName, Attribute: attribute, Action: EncryptDeterministic, Reason: "the item's data key does not support deterministic encryption"}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error encrypting attribute value: %v", err)
+		}
+		encryptedValues[placeholder] = &types.AttributeValueMemberB{Value: encryptedData}
+	}
+	return encryptedValues, nil
+}
+
+// comparison records the deterministic or hashed attribute an expression attribute value is compared with.
+type comparison struct {
+	attribute string
+	action    EncryptionAction
+	uses      int
+}
+
+// comparedValues finds every reference to an encrypted attribute in a condition expression and returns the values
+// compared with deterministic or hashed attributes. References DynamoDB can not evaluate against ciphertexts are
+// rejected.
+func comparedValues(tableName string, tokens []exprToken, actionFor func(string) EncryptionAction) (map[string]*comparison, error) {
+	compared := make(map[string]*comparison)
+	for i, token := range tokens {
+		if token.kind != tokenPath {
+			continue
+		}
+		action := actionFor(token.attribute)
+		if action == EncryptNone || isExistenceCheck(tokens, i) {
+			continue
+		}
+
+		fail := func(reason string) error {
+			return &ConditionError{TableName: tableName, Attribute: token.attribute, Action: action, Reason: reason}
+		}
+		if action != EncryptDeterministic && action != EncryptHash {
+			return nil, fail("randomized ciphertexts can only be checked with attribute_exists and attribute_not_exists")
+		}
+		if token.nested {
+			return nil, fail("nested paths of encrypted attributes can not be compared")
+		}
+		placeholders := equalityOperands(tokens, i)
+		if placeholders == nil {
+			return nil, fail("deterministic and hashed attributes can only be compared with =, <> or IN against expression attribute values")
+		}
+
+		for _, placeholder := range placeholders {
+			c, ok := compared[placeholder]
+			if !ok {
+				c = &comparison{attribute: token.attribute, action: action}
+				compared[placeholder] = c
+			}
+			if c.attribute != token.attribute {
+				return nil, fail(fmt.Sprintf("value %s is also compared with attribute %q", placeholder, c.attribute))
+			}
+			c.uses++
+		}
+	}
+	return compared, nil
+}
+
+// isExistenceCheck reports whether the path at tokens[i] is the argument of attribute_exists or attribute_not_exists,
+// which work on ciphertexts like on plaintexts.
+func isExistenceCheck(tokens []exprToken, i int) bool {
+	if i < 2 || i+1 >= len(tokens) || tokens[i-1].text != "(" || tokens[i+1].text != ")" {
+		return false
+	}
+	function := tokens[i-2]
+	return function.kind == tokenFunction && (function.text == "attribute_exists" || function.text == "attribute_not_exists")
+}
+
+// equalityOperands returns the expression attribute values the path at tokens[i] is compared with, in the forms
+// "path = :v", ":v = path", "path <> :v", ":v <> path" and "path IN (:a, :b)". It returns nil for any other use.
+func equalityOperands(tokens []exprToken, i int) []string {
+	at := func(j int) exprToken {
+		if j < 0 || j >= len(tokens) {
+			return exprToken{}
+		}
+		return tokens[j]
+	}
+	isEquality := func(j int) bool {
+		return at(j).kind == tokenOperator && (at(j).text == "=" || at(j).text == "<>")
+	}
+
+	switch {
+	case isEquality(i+1) && at(i+2).kind == tokenValue && !isEquality(i-1):
+		return []string{at(i + 2).text}
+	case isEquality(i-1) && at(i-2).kind == tokenValue && !isEquality(i+1):
+		return []string{at(i - 2).text}
+	case at(i+1).kind == tokenKeyword && at(i+1).text == "IN" && at(i+2).text == "(":
+		var placeholders []string
+		for j := i + 3; ; j += 2 {
+			if at(j).kind != tokenValue {
+				return nil
+			}
+			placeholders = append(placeholders, at(j).text)
+			if at(j+1).text == ")" {
+				return placeholders
+			}
+			if at(j+1).text != "," {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+type tokenKind int
+
+const (
+	tokenPath tokenKind = iota + 1
+	tokenValue
+	tokenKeyword
+	tokenFunction
+	tokenOperator
+)
+
+// exprToken is a lexical token of a condition or update expression. Paths record the top-level attribute they
+// refer to, with expression attribute names resolved.
+type exprToken struct {
+	kind      tokenKind
+	text      string
+	attribute string
+	nested    bool
+}
+
+// expressionKeywords are the keywords of condition and update expressions. They are reserved words, so they can not
+// be attribute names.
+var expressionKeywords = map[string]bool{
+	"AND": true, "OR": true, "NOT": true, "BETWEEN": true, "IN": true,
+	"SET": true, "REMOVE": true, "ADD": true, "DELETE": true,
+}
+
+// tokenizeExpression splits a condition or update expression into tokens. It does not validate the grammar, which
+// is left to DynamoDB.
+func tokenizeExpression(expression string, names map[string]string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == ':':
+			j := i + 1
+			for j < len(expression) && isNameChar(expression[j]) {
+				j++
+			}
+			tokens = append(tokens, exprToken{kind: tokenValue, text: expression[i:j]})
+			i = j
+		case c == '#' || isNameChar(c):
+			j := i
+			for j < len(expression) && (isNameChar(expression[j]) || strings.IndexByte("#.[]", expression[j]) >= 0) {
+				j++
+			}
+			token, err := classifyName(expression[i:j], strings.TrimLeft(expression[j:], " \t\n\r"), names)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token)
+			i = j
+		case c == '<' || c == '>':
+			j := i + 1
+			if j < len(expression) && (expression[j] == '=' || (c == '<' && expression[j] == '>')) {
+				j++
+			}
+			tokens = append(tokens, exprToken{kind: tokenOperator, text: expression[i:j]})
+			i = j
+		case strings.IndexByte("=(),+-", c) >= 0:
+			tokens = append(tokens, exprToken{kind: tokenOperator, text: expression[i : i+1]})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected character %q in expression %q", c, expression)
+		}
+	}
+	return tokens, nil
+}
+
+// classifyName classifies a name token as a keyword, a function or a document path, given the rest of the
+// expression that follows it.
+func classifyName(text, rest string, names map[string]string) (exprToken, error) {
+	if upper := strings.ToUpper(text); expressionKeywords[upper] {
+		return exprToken{kind: tokenKeyword, text: upper}, nil
+	}
+	if strings.HasPrefix(rest, "(") && !strings.HasPrefix(text, "#") {
+		return exprToken{kind: tokenFunction, text: text}, nil
+	}
+
+	top := text
+	if end := strings.IndexAny(text, ".["); end >= 0 {
+		top = text[:end]
+	}
+	attribute := top
+	if strings.HasPrefix(top, "#") {
+		name, ok := names[top]
+		if !ok {
+			return exprToken{}, fmt.Errorf("expression attribute name %s is not defined", top)
+		}
+		attribute = name
+	}
+	return exprToken{kind: tokenPath, text: text, attribute: attribute, nested: len(top) < len(text)}, nil
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
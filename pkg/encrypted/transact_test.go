@@ -0,0 +1,357 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// transactClient records the TransactWriteItems request it receives.
+type transactClient struct {
+	DynamoDBClientInterface
+	input *dynamodb.TransactWriteItemsInput
+}
+
+func (c *transactClient) TransactWriteItems(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	c.input = input
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+// deterministicKey "encrypts" deterministically by reversing the plaintext and prefixing it with the associated data.
+type deterministicKey struct {
+	reversingKey
+}
+
+func (k deterministicKey) EncryptDeterministically(plaintext []byte, associatedData []byte) ([]byte, error) {
+	ciphertext, err := k.Encrypt(plaintext, associatedData)
+	return append(append([]byte{}, associatedData...), ciphertext...), err
+}
+
+func TestEncryptedClient_TransactWriteItems_ConditionCheck(t *testing.T) {
+	key := map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: "1"}}
+	email := &types.AttributeValueMemberS{Value: "a@example.com"}
+
+	testCases := []struct {
+		name       string
+		condition  string
+		names      map[string]string
+		values     map[string]types.AttributeValue
+		plainKey   bool
+		encrypted  []string
+		errorField string
+	}{
+		{
+			name:      "deterministic equality",
+			condition: "#e = :email AND #r = :role",
+			names:     map[string]string{"#e": "Email", "#r": "Role"},
+			values:    map[string]types.AttributeValue{":email": email, ":role": &types.AttributeValueMemberS{Value: "admin"}},
+			encrypted: []string{":email"},
+		},
+		{
+			name:      "deterministic IN list",
+			condition: "Email IN (:a, :b)",
+			values:    map[string]types.AttributeValue{":a": email, ":b": &types.AttributeValueMemberS{Value: "b@example.com"}},
+			encrypted: []string{":a", ":b"},
+		},
+		{
+			name:      "value first",
+			condition: ":email <> Email",
+			values:    map[string]types.AttributeValue{":email": email},
+			encrypted: []string{":email"},
+		},
+		{
+			name:      "existence of randomized attribute",
+			condition: "attribute_exists(Secret) AND attribute_not_exists(#e)",
+			names:     map[string]string{"#e": "Email"},
+		},
+		{
+			name:       "comparison on randomized attribute",
+			condition:  "Secret = :s",
+			values:     map[string]types.AttributeValue{":s": &types.AttributeValueMemberS{Value: "x"}},
+			errorField: "Secret",
+		},
+		{
+			name:       "function on deterministic attribute",
+			condition:  "begins_with(Email, :p)",
+			values:     map[string]types.AttributeValue{":p": &types.AttributeValueMemberS{Value: "a@"}},
+			errorField: "Email",
+		},
+		{
+			name:       "value shared with plaintext attribute",
+			condition:  "Email = :v OR #r = :v",
+			names:      map[string]string{"#r": "Role"},
+			values:     map[string]types.AttributeValue{":v": email},
+			errorField: "Email",
+		},
+		{
+			name:       "key without deterministic encryption",
+			condition:  "Email = :email",
+			values:     map[string]types.AttributeValue{":email": email},
+			plainKey:   true,
+			errorField: "Email",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			materialsProvider := &staticProvider{dataKey: deterministicKey{}}
+			if tc.plainKey {
+				materialsProvider.dataKey = reversingKey{}
+			}
+			client := &transactClient{}
+			ec := NewEncryptedClient(client, materialsProvider,
+				WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}),
+				WithOptions(WithEncryption("Email", EncryptDeterministic), WithEncryption("Role", EncryptNone)),
+			)
+
+			input := &dynamodb.TransactWriteItemsInput{TransactItems: []types.TransactWriteItem{{
+				ConditionCheck: &types.ConditionCheck{
+					TableName:                 aws.String("users"),
+					Key:                       key,
+					ConditionExpression:       aws.String(tc.condition),
+					ExpressionAttributeNames:  tc.names,
+					ExpressionAttributeValues: tc.values,
+				},
+			}}}
+			_, err := ec.TransactWriteItems(context.Background(), input)
+
+			if tc.errorField != "" {
+				var conditionErr *ConditionError
+				if !errors.As(err, &conditionErr) {
+					t.Fatalf("expected a ConditionError, got %v", err)
+				}
+				if conditionErr.Attribute != tc.errorField {
+					t.Errorf("expected the error to name %s, got %s", tc.errorField, conditionErr.Attribute)
+				}
+				if client.input != nil {
+					t.Errorf("expected the transaction not to be sent")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// Encrypted values must match the ciphertexts stored for the same plaintexts
+			expected := make(map[string]types.AttributeValue, len(tc.values))
+			for placeholder, value := range tc.values {
+				expected[placeholder] = value
+			}
+			for _, placeholder := range tc.encrypted {
+				stored, err := ec.encryptItem(context.Background(), "users", map[string]types.AttributeValue{"ID": key["ID"], "Email": tc.values[placeholder]})
+				if err != nil {
+					t.Fatalf("failed to encrypt item: %v", err)
+				}
+				expected[placeholder] = stored["Email"]
+			}
+			ignore := cmpopts.IgnoreUnexported(types.AttributeValueMemberS{}, types.AttributeValueMemberB{})
+			if diff := cmp.Diff(expected, client.input.TransactItems[0].ConditionCheck.ExpressionAttributeValues, ignore, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("unexpected values (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.values, input.TransactItems[0].ConditionCheck.ExpressionAttributeValues, ignore); diff != "" {
+				t.Errorf("input values were modified (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEncryptedClient_TransactWriteItems_Update(t *testing.T) {
+	testCases := []struct {
+		name         string
+		update       string
+		signed       bool
+		err          error
+		conditionErr bool
+	}{
+		{name: "Plaintext attribute", update: "SET #r = :r"},
+		{name: "Encrypted attribute", update: "SET Secret = :s, #r = :r", conditionErr: true},
+		{name: "Signed items", update: "SET #r = :r", signed: true, err: ErrSignedItemUpdate},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &transactClient{}
+			ec := NewEncryptedClient(client, &staticProvider{dataKey: deterministicKey{}},
+				WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}),
+				WithOptions(WithEncryption("Role", EncryptNone), WithItemSignatures(tc.signed)),
+			)
+
+			_, err := ec.TransactWriteItems(context.Background(), &dynamodb.TransactWriteItemsInput{TransactItems: []types.TransactWriteItem{{
+				Update: &types.Update{
+					TableName:                 aws.String("users"),
+					Key:                       map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: "1"}},
+					UpdateExpression:          aws.String(tc.update),
+					ExpressionAttributeNames:  map[string]string{"#r": "Role"},
+					ExpressionAttributeValues: map[string]types.AttributeValue{":s": &types.AttributeValueMemberS{Value: "x"}, ":r": &types.AttributeValueMemberS{Value: "admin"}},
+				},
+			}}})
+			var conditionErr *ConditionError
+			switch {
+			case tc.conditionErr:
+				if !errors.As(err, &conditionErr) || conditionErr.Attribute != "Secret" {
+					t.Errorf("expected a ConditionError for Secret, got %v", err)
+				}
+			case tc.err != nil:
+				if !errors.Is(err, tc.err) {
+					t.Errorf("expected %v, got %v", tc.err, err)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil && client.input != nil {
+				t.Errorf("expected nothing to be written")
+			}
+		})
+	}
+}
+
+// storedItemClient serves items keyed by their "ID" attribute to UpdateItem, which records its request and returns
+// the stored item without changing it, and to TransactGetItems.
+type storedItemClient struct {
+	DynamoDBClientInterface
+	items  map[string]map[string]types.AttributeValue
+	update *dynamodb.UpdateItemInput
+}
+
+func (c *storedItemClient) UpdateItem(ctx context.Context, input *dynamodb.UpdateItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	c.update = input
+	output := &dynamodb.UpdateItemOutput{}
+	if input.ReturnValues == types.ReturnValueAllNew || input.ReturnValues == types.ReturnValueAllOld {
+		output.Attributes = c.items[input.Key["ID"].(*types.AttributeValueMemberS).Value]
+	}
+	return output, nil
+}
+
+func (c *storedItemClient) TransactGetItems(ctx context.Context, input *dynamodb.TransactGetItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactGetItemsOutput, error) {
+	output := &dynamodb.TransactGetItemsOutput{Responses: make([]types.ItemResponse, len(input.TransactItems))}
+	for i, item := range input.TransactItems {
+		output.Responses[i].Item = c.items[item.Get.Key["ID"].(*types.AttributeValueMemberS).Value]
+	}
+	return output, nil
+}
+
+func TestEncryptedClient_UpdateItem(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	item := map[string]types.AttributeValue{
+		"ID":    &types.AttributeValueMemberS{Value: "1"},
+		"Email": &types.AttributeValueMemberS{Value: "alice@example.com"},
+		"Count": &types.AttributeValueMemberN{Value: "1"},
+	}
+	key := map[string]types.AttributeValue{"ID": item["ID"]}
+	ignore := cmpopts.IgnoreUnexported(types.AttributeValueMemberS{}, types.AttributeValueMemberN{})
+
+	testCases := []struct {
+		name         string
+		update       string
+		returnValues types.ReturnValue
+		signed       bool
+		err          error
+		conditionErr bool
+	}{
+		{name: "Plaintext attribute", update: "SET #count = #count + :one"},
+		{name: "All new", update: "SET #count = #count + :one", returnValues: types.ReturnValueAllNew},
+		{name: "Encrypted attribute", update: "SET Email = :one", conditionErr: true},
+		{name: "Signed items", update: "SET #count = #count + :one", signed: true, err: ErrSignedItemUpdate},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &storedItemClient{items: make(map[string]map[string]types.AttributeValue)}
+			ec := NewEncryptedClient(client, &staticProvider{description: map[string]string{}, dataKey: dataKey},
+				WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}),
+				WithOptions(WithEncryption("Count", EncryptNone), WithItemSignatures(tc.signed)),
+			)
+			if !tc.signed {
+				if client.items["1"], err = ec.EncryptItem(context.Background(), "users", item); err != nil {
+					t.Fatalf("failed to encrypt item: %v", err)
+				}
+			}
+
+			output, err := ec.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+				TableName:                 aws.String("users"),
+				Key:                       key,
+				UpdateExpression:          aws.String(tc.update),
+				ExpressionAttributeNames:  map[string]string{"#count": "Count"},
+				ExpressionAttributeValues: map[string]types.AttributeValue{":one": &types.AttributeValueMemberN{Value: "1"}},
+				ReturnValues:              tc.returnValues,
+			})
+			var conditionErr *ConditionError
+			switch {
+			case tc.conditionErr:
+				if !errors.As(err, &conditionErr) {
+					t.Errorf("expected a ConditionError, got %v", err)
+				}
+			case tc.err != nil:
+				if !errors.Is(err, tc.err) {
+					t.Errorf("expected %v, got %v", tc.err, err)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil {
+				if client.update != nil {
+					t.Errorf("expected nothing to be written")
+				}
+				return
+			}
+
+			if aws.StringValue(client.update.UpdateExpression) != tc.update {
+				t.Errorf("expected update %q to be sent, got %q", tc.update, aws.StringValue(client.update.UpdateExpression))
+			}
+			if tc.returnValues == types.ReturnValueAllNew {
+				if diff := cmp.Diff(item, output.Attributes, ignore); diff != "" {
+					t.Errorf("unexpected returned item (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestEncryptedClient_TransactGetItems(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	client := &storedItemClient{items: make(map[string]map[string]types.AttributeValue)}
+	ec := NewEncryptedClient(client, &staticProvider{description: map[string]string{}, dataKey: dataKey}, WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}))
+	item := map[string]types.AttributeValue{
+		"ID":    &types.AttributeValueMemberS{Value: "1"},
+		"Email": &types.AttributeValueMemberS{Value: "alice@example.com"},
+	}
+	if client.items["1"], err = ec.EncryptItem(context.Background(), "users", item); err != nil {
+		t.Fatalf("failed to encrypt item: %v", err)
+	}
+
+	get := func(id string) types.TransactGetItem {
+		return types.TransactGetItem{Get: &types.Get{TableName: aws.String("users"), Key: map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: id}}}}
+	}
+	output, err := ec.TransactGetItems(context.Background(), &dynamodb.TransactGetItemsInput{TransactItems: []types.TransactGetItem{get("2"), get("1")}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(output.Responses) != 2 || output.Responses[0].Item != nil {
+		t.Fatalf("expected a missing item followed by a found one, got %v", output.Responses)
+	}
+	ignore := cmpopts.IgnoreUnexported(types.AttributeValueMemberS{})
+	if diff := cmp.Diff(item, output.Responses[1].Item, ignore); diff != "" {
+		t.Errorf("unexpected item (-want +got):\n%s", diff)
+	}
+}
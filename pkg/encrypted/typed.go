@@ -0,0 +1,37 @@
+package encrypted
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Put marshals value, a struct or map, with attributevalue.MarshalMap and stores it encrypted in the table, like
+// EncryptedTable.PutItem:
+//
+//	err := encrypted.Put(ctx, table, "users", User{ID: "1", Email: "alice@example.com"})
+func Put[T any](ctx context.Context, table *EncryptedTable, tableName string, value T, opts ...PutItemOption) error {
+	item, err := attributevalue.MarshalMap(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal item: %w", err)
+	}
+	return table.PutItem(ctx, tableName, item, opts...)
+}
+
+// Get reads and decrypts the item with the given key from the table, like EncryptedTable.GetItem, and unmarshals it
+// into a T with attributevalue.UnmarshalMap:
+//
+//	user, err := encrypted.Get[User](ctx, table, "users", key)
+func Get[T any](ctx context.Context, table *EncryptedTable, tableName string, key map[string]types.AttributeValue) (T, error) {
+	var value T
+	item, err := table.GetItem(ctx, tableName, key)
+	if err != nil {
+		return value, err
+	}
+	if err := attributevalue.UnmarshalMap(item, &value); err != nil {
+		return value, fmt.Errorf("failed to unmarshal item: %w", err)
+	}
+	return value, nil
+}
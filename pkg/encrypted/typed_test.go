@@ -0,0 +1,106 @@
+package encrypted
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/google/go-cmp/cmp"
+)
+
+// memoryClient stores the items of PutItem by their "ID" attribute and serves them to GetItem.
+type memoryClient struct {
+	DynamoDBClientInterface
+	items map[string]map[string]types.AttributeValue
+}
+
+func (c *memoryClient) PutItem(ctx context.Context, input *dynamodb.PutItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	c.items[input.Item["ID"].(*types.AttributeValueMemberS).Value] = input.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (c *memoryClient) GetItem(ctx context.Context, input *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: c.items[input.Key["ID"].(*types.AttributeValueMemberS).Value]}, nil
+}
+
+type typedUser struct {
+	ID     string
+	Email  string `dynamodbav:"email"`
+	Age    int
+	Labels []string `dynamodbav:",stringset,omitempty"`
+}
+
+func TestPutGet(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	client := &memoryClient{items: make(map[string]map[string]types.AttributeValue)}
+	table := NewEncryptedTable(NewEncryptedClient(client, &staticProvider{description: map[string]string{}, dataKey: dataKey},
+		WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"})))
+	key := func(id string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: id}}
+	}
+
+	t.Run("Struct", func(t *testing.T) {
+		user := typedUser{ID: "1", Email: "alice@example.com", Age: 42, Labels: []string{"admin"}}
+		if err := Put(context.Background(), table, "users", user); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := client.items["1"]["email"].(*types.AttributeValueMemberB); !ok {
+			t.Errorf("expected the email to be stored encrypted, got %T", client.items["1"]["email"])
+		}
+
+		got, err := Get[typedUser](context.Background(), table, "users", key("1"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if diff := cmp.Diff(user, got); diff != "" {
+			t.Errorf("unexpected user (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Pointer", func(t *testing.T) {
+		if err := Put(context.Background(), table, "users", &typedUser{ID: "2", Email: "bob@example.com"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, err := Get[*typedUser](context.Background(), table, "users", key("2"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got == nil || got.Email != "bob@example.com" {
+			t.Errorf("unexpected user %+v", got)
+		}
+	})
+
+	testCases := []struct {
+		name string
+		fn   func() error
+	}{
+		{name: "Not a struct", fn: func() error {
+			return Put(context.Background(), table, "users", "alice")
+		}},
+		{name: "Missing item", fn: func() error {
+			_, err := Get[typedUser](context.Background(), table, "users", key("missing"))
+			return err
+		}},
+		{name: "Mismatched type", fn: func() error {
+			_, err := Get[struct{ Age bool }](context.Background(), table, "users", key("1"))
+			return err
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.fn(); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
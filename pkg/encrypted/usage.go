@@ -0,0 +1,98 @@
+package encrypted
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+)
+
+// MaterialUsage reports how the items of a table are spread across material versions and KMS keys, for example to
+// decide whether a KMS key can be retired or old material versions deleted.
+type MaterialUsage struct {
+	Table string
+	Items int64
+
+	// Versions counts items per pinned material version. Version 0 counts items written before versions were
+	// pinned, which decrypt with the latest version of their material.
+	Versions map[int64]int64
+
+	// KMSKeys counts items per KMS key recorded in their material description. The empty key counts items whose
+	// materials do not record a key. KMSKeys is nil when the provider can not describe materials.
+	KMSKeys map[string]int64
+}
+
+// MaterialUsage scans a table without decrypting it and aggregates which material versions and KMS keys its items
+// use. Only key attributes and the material version are read from the table; KMS keys are looked up in the material
+// store when the materials provider implements provider.MaterialDescriber.
+func (ec *EncryptedClient) MaterialUsage(ctx context.Context, tableName string) (*MaterialUsage, error) {
+	ctx = operationContext(ctx, "MaterialUsage")
+	pkInfo, err := ec.getPrimaryKeyInfo(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &MaterialUsage{
+		Table:    tableName,
+		Versions: make(map[int64]int64),
+	}
+	describer, describe := ec.MaterialsProvider.(provider.MaterialDescriber)
+	if describe {
+		usage.KMSKeys = make(map[string]int64)
+	}
+
+	input := &dynamodb.ScanInput{
+		TableName: aws.String(tableName),
+	}
+	input.ProjectionExpression, input.ExpressionAttributeNames = usageProjection(pkInfo)
+
+	paginator := dynamodb.NewScanPaginator(ec.Client, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning items: %v", err)
+		}
+
+		for _, item := range output.Items {
+			version, err := materialVersion(item)
+			if err != nil {
+				return nil, err
+			}
+			usage.Items++
+			usage.Versions[version]++
+
+			if !describe {
+				continue
+			}
+			materialName, err := itemMaterialName(item, pkInfo)
+			if err != nil {
+				return nil, fmt.Errorf("error constructing material name: %w", err)
+			}
+			description, err := describer.DescribeMaterials(itemContext(ctx, pkInfo, item), materialName, version)
+			if err != nil {
+				return nil, fmt.Errorf("failed to describe materials: %v", err)
+			}
+			usage.KMSKeys[description[provider.KMSKeyURIKey]]++
+		}
+	}
+
+	return usage, nil
+}
+
+// usageProjection builds a projection reading only the primary key, material version and material scope attributes.
+func usageProjection(pkInfo *PrimaryKeyInfo) (*string, map[string]string) {
+	names := map[string]string{
+		"#pk": pkInfo.PartitionKey,
+		"#mv": MaterialVersionAttribute,
+		"#ms": MaterialScopeAttribute,
+	}
+	projection := []string{"#pk", "#mv", "#ms"}
+	if pkInfo.SortKey != "" {
+		names["#sk"] = pkInfo.SortKey
+		projection = append(projection, "#sk")
+	}
+	return aws.String(strings.Join(projection, ", ")), names
+}
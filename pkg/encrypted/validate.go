@@ -0,0 +1,231 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+)
+
+// ProblemCode identifies a kind of configuration problem reported by Validate.
+type ProblemCode string
+
+const (
+	ProblemInvalidConfig         ProblemCode = "invalid-config"
+	ProblemNoProvider            ProblemCode = "no-provider"
+	ProblemNoMaterialStore       ProblemCode = "no-material-store"
+	ProblemTableSchema           ProblemCode = "table-schema"
+	ProblemKeyAttributeEncrypted ProblemCode = "key-attribute-encrypted"
+	ProblemIndexKeyEncrypted     ProblemCode = "index-key-encrypted"
+	ProblemIndexProjection       ProblemCode = "index-projection"
+	ProblemSharedMaterialCleanup ProblemCode = "shared-material-cleanup"
+	ProblemDeterministicFloat    ProblemCode = "deterministic-float"
+	ProblemNoHashKey             ProblemCode = "no-hash-key"
+)
+
+// ErrSharedMaterialCleanup is the error of a ProblemSharedMaterialCleanup: material cleanup deletes materials by the
+// deleted item's own material name, which items encrypted under their table's materials do not use.
+var ErrSharedMaterialCleanup = errors.New("material cleanup does not apply to table materials")
+
+// ErrReusedMaterialCleanup is the error of a ProblemSharedMaterialCleanup for providers reusing materials: cleanup
+// deletes stored versions that other writers of the material name may still hold in memory and encrypt under.
+var ErrReusedMaterialCleanup = errors.New("material cleanup does not apply to reused materials")
+
+// ErrDeterministicFloat is the error of a ProblemDeterministicFloat.
+var ErrDeterministicFloat = errors.New("deterministic encryption of a floating point attribute")
+
+// ConfigProblem is a misconfiguration found by Validate. Err wraps the sentinel error the misconfiguration fails
+// with, or would fail with, at runtime, such as ErrKeyAttributeEncrypted, so problems can be matched with errors.Is.
+type ConfigProblem struct {
+	Code      ProblemCode
+	Table     string // Empty for problems of the client itself
+	Attribute string // Empty for problems not tied to an attribute
+	Err       error
+	Hint      string // How to fix the problem
+}
+
+func (p ConfigProblem) Error() string {
+	message := p.Err.Error()
+	if p.Attribute != "" {
+		message = fmt.Sprintf("attribute %q: %s", p.Attribute, message)
+	}
+	if p.Table != "" {
+		message = fmt.Sprintf("table %s: %s", p.Table, message)
+	}
+	return message
+}
+
+func (p ConfigProblem) Unwrap() error {
+	return p.Err
+}
+
+// validation holds the settings of Validate.
+type validation struct {
+	floats map[string]bool
+}
+
+// ValidateOption provides Validate with what DynamoDB does not record about a table.
+type ValidateOption func(*validation)
+
+// ValidateFloatAttributes declares attributes holding floating point numbers. DynamoDB only records the types of key
+// attributes, so Validate can not tell them apart otherwise.
+func ValidateFloatAttributes(names ...string) ValidateOption {
+	return func(v *validation) {
+		if v.floats == nil {
+			v.floats = make(map[string]bool)
+		}
+		for _, name := range names {
+			v.floats[name] = true
+		}
+	}
+}
+
+// Validate checks the assembled client for misconfigurations, for example in a CI test, and returns every problem
+// found rather than the first: the client configuration, its materials provider and material store, and the
+// configuration against the key schemas and index projections of the given tables. The key schema of each table is
+// loaded with dynamodb:DescribeTable unless it was configured with WithPrimaryKeyInfo. Unlike Preflight, Validate
+// calls no other AWS operation.
+func (ec *EncryptedClient) Validate(ctx context.Context, tableNames []string, opts ...ValidateOption) []ConfigProblem {
+	var settings validation
+	for _, opt := range opts {
+		opt(&settings)
+	}
+	config := ec.Config()
+
+	var problems []ConfigProblem
+	if err := config.Validate(); err != nil {
+		problems = append(problems, ConfigProblem{
+			Code: ProblemInvalidConfig,
+			Err:  err,
+			Hint: "Fix the action, pattern or setting named in the error.",
+		})
+	}
+	problems = append(problems, ec.validateProvider()...)
+	if config.Encryption.usesAction(EncryptHash) && len(ec.hashKey) < MinHashKeySize {
+		problems = append(problems, ConfigProblem{
+			Code: ProblemNoHashKey,
+			Err:  ErrNoHashKey,
+			Hint: fmt.Sprintf("Configure a secret of at least %d bytes with WithHashKey, shared by every client of the hashed attributes.", MinHashKeySize),
+		})
+	}
+	if config.MaterialCleanup && config.TableMaterials {
+		problems = append(problems, ConfigProblem{
+			Code: ProblemSharedMaterialCleanup,
+			Err:  ErrSharedMaterialCleanup,
+			Hint: "Disable WithMaterialCleanup, or encrypt items under their own materials; orphaned versions can be deleted with ReapMaterials.",
+		})
+	}
+	if reuser, ok := ec.MaterialsProvider.(provider.MaterialReuser); ok && config.MaterialCleanup && reuser.ReusesMaterials() {
+		problems = append(problems, ConfigProblem{
+			Code: ProblemSharedMaterialCleanup,
+			Err:  ErrReusedMaterialCleanup,
+			Hint: "Disable WithMaterialCleanup or provider.WithMaterialReuse; orphaned versions can be deleted with ReapMaterials.",
+		})
+	}
+	for _, name := range sortedKeys(settings.floats) {
+		if config.Encryption.ActionFor(name) == EncryptDeterministic {
+			problems = append(problems, ConfigProblem{
+				Code:      ProblemDeterministicFloat,
+				Attribute: name,
+				Err:       ErrDeterministicFloat,
+				Hint:      "Floats that differ in their last digits encrypt differently and never match. Store a fixed-precision or string representation, or use EncryptStandard.",
+			})
+		}
+	}
+
+	for _, tableName := range tableNames {
+		pkInfo, err := ec.getPrimaryKeyInfo(ctx, tableName)
+		if err != nil {
+			problems = append(problems, ConfigProblem{
+				Code:  ProblemTableSchema,
+				Table: tableName,
+				Err:   err,
+				Hint:  "Grant dynamodb:DescribeTable on the table, or configure its key schema with WithPrimaryKeyInfo.",
+			})
+			continue
+		}
+		problems = append(problems, validateTable(config, pkInfo)...)
+	}
+	return problems
+}
+
+// validateProvider checks that the client has a materials provider, and that the KMS provider has a material store.
+func (ec *EncryptedClient) validateProvider() []ConfigProblem {
+	switch p := ec.MaterialsProvider.(type) {
+	case nil:
+		return []ConfigProblem{{
+			Code: ProblemNoProvider,
+			Err:  errors.New("no materials provider"),
+			Hint: "Pass a provider.CryptographicMaterialsProvider to NewEncryptedClient.",
+		}}
+	case *provider.AwsKmsCryptographicMaterialsProvider:
+		if p.MaterialStore == nil || p.MaterialStore.DynamoDBClient == nil {
+			return []ConfigProblem{{
+				Code: ProblemNoMaterialStore,
+				Err:  errors.New("KMS provider has no material store"),
+				Hint: "Create the provider with a store.MetaStore backed by a DynamoDB client.",
+			}}
+		}
+	}
+	return nil
+}
+
+// validateTable checks the actions configured for a table's key attributes and the projections of its indexes.
+func validateTable(config *ClientConfig, pkInfo *PrimaryKeyInfo) []ConfigProblem {
+	var problems []ConfigProblem
+	baseKeys := &PrimaryKeyInfo{Table: pkInfo.Table, PartitionKey: pkInfo.PartitionKey, SortKey: pkInfo.SortKey}
+	for _, name := range sortedKeys(config.Encryption.SpecificActions) {
+		action := config.Encryption.SpecificActions[name]
+		if action == EncryptNone || !pkInfo.IsKeyAttribute(name) {
+			continue
+		}
+		problem := ConfigProblem{
+			Code:      ProblemIndexKeyEncrypted,
+			Table:     pkInfo.Table,
+			Attribute: name,
+			Err:       ErrKeyAttributeEncrypted,
+			Hint:      "Index keys are stored in plaintext. To look up items by an encrypted value, index a separate attribute encrypted with EncryptDeterministic.",
+		}
+		if baseKeys.IsKeyAttribute(name) {
+			problem.Code = ProblemKeyAttributeEncrypted
+			problem.Hint = "Primary key attributes are always stored in plaintext. Remove the action, or move the value to a non-key attribute."
+		}
+		problems = append(problems, problem)
+	}
+
+	for _, indexName := range sortedKeys(pkInfo.Indexes) {
+		index := pkInfo.Indexes[indexName]
+		if index.Projection != types.ProjectionTypeInclude {
+			continue
+		}
+		projected := append([]string{pkInfo.PartitionKey, index.PartitionKey}, index.NonKeyAttributes...)
+		if pkInfo.SortKey != "" {
+			projected = append(projected, pkInfo.SortKey)
+		}
+		if index.SortKey != "" {
+			projected = append(projected, index.SortKey)
+		}
+		if err := checkProjection(pkInfo, config.Encryption, config.TableMaterials, projected, fmt.Sprintf("projection of index %s", index.Name)); err != nil {
+			problems = append(problems, ConfigProblem{
+				Code:  ProblemIndexProjection,
+				Table: pkInfo.Table,
+				Err:   err,
+				Hint:  fmt.Sprintf("Project the base table keys, %s and, with table materials, %s, or project ALL attributes.", MaterialVersionAttribute, MaterialScopeAttribute),
+			})
+		}
+	}
+	return problems
+}
+
+// sortedKeys returns the keys of a map in order, so problems are reported deterministically.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
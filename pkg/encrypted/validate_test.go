@@ -0,0 +1,155 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+	"github.com/google/go-cmp/cmp"
+)
+
+// reusingProvider reuses its materials for every write.
+type reusingProvider struct {
+	staticProvider
+}
+
+func (*reusingProvider) ReusesMaterials() bool {
+	return true
+}
+
+func TestEncryptedClient_Validate(t *testing.T) {
+	pkInfo := PrimaryKeyInfo{
+		PartitionKey: "ID",
+		SortKey:      "Created",
+		Indexes: map[string]*IndexKeyInfo{
+			"by-email":  {Name: "by-email", PartitionKey: "Email"},
+			"by-status": {Name: "by-status", PartitionKey: "Status", Projection: types.ProjectionTypeInclude, NonKeyAttributes: []string{"Label"}},
+		},
+	}
+	cmProvider := &staticProvider{}
+
+	type problem struct {
+		Code      ProblemCode
+		Table     string
+		Attribute string
+	}
+	testCases := []struct {
+		name     string
+		provider provider.CryptographicMaterialsProvider
+		options  []Option
+		validate []ValidateOption
+		tables   []string
+		want     []problem
+		err      error
+	}{
+		{
+			name:     "Valid",
+			provider: cmProvider,
+			options:  []Option{WithEncryption("Price", EncryptStandard)},
+			validate: []ValidateOption{ValidateFloatAttributes("Price")},
+			tables:   []string{"orders"},
+		},
+		{
+			name:    "No provider",
+			options: []Option{WithEncryption("Secret", EncryptStandard)},
+			want:    []problem{{Code: ProblemNoProvider}},
+		},
+		{
+			name:     "KMS provider without material store",
+			provider: &provider.AwsKmsCryptographicMaterialsProvider{},
+			want:     []problem{{Code: ProblemNoMaterialStore}},
+		},
+		{
+			name:     "Invalid config",
+			provider: cmProvider,
+			options:  []Option{WithEncryptionPattern("[", EncryptStandard)},
+			want:     []problem{{Code: ProblemInvalidConfig}},
+			err:      ErrInvalidConfig,
+		},
+		{
+			name:     "Key attributes",
+			provider: cmProvider,
+			options:  []Option{WithEncryption("Created", EncryptDeterministic), WithEncryption("Email", EncryptStandard)},
+			tables:   []string{"orders"},
+			want: []problem{
+				{Code: ProblemKeyAttributeEncrypted, Table: "orders", Attribute: "Created"},
+				{Code: ProblemIndexKeyEncrypted, Table: "orders", Attribute: "Email"},
+			},
+			err: ErrKeyAttributeEncrypted,
+		},
+		{
+			name:     "Hashed attribute without hash key",
+			provider: cmProvider,
+			options:  []Option{WithEncryptionPattern("*_digest", EncryptHash)},
+			want:     []problem{{Code: ProblemNoHashKey}},
+			err:      ErrNoHashKey,
+		},
+		{
+			name:     "Index projection",
+			provider: cmProvider,
+			options:  []Option{WithEncryption("Label", EncryptStandard)},
+			tables:   []string{"orders"},
+			want:     []problem{{Code: ProblemIndexProjection, Table: "orders"}},
+			err:      ErrIndexProjection,
+		},
+		{
+			name:     "Shared material cleanup",
+			provider: cmProvider,
+			options:  []Option{WithMaterialCleanup(true), WithTableMaterials(true)},
+			want:     []problem{{Code: ProblemSharedMaterialCleanup}},
+			err:      ErrSharedMaterialCleanup,
+		},
+		{
+			name:     "Reused material cleanup",
+			provider: &reusingProvider{},
+			options:  []Option{WithMaterialCleanup(true)},
+			want:     []problem{{Code: ProblemSharedMaterialCleanup}},
+			err:      ErrReusedMaterialCleanup,
+		},
+		{
+			name:     "Deterministic float",
+			provider: cmProvider,
+			options:  []Option{WithEncryptionPattern("price_*", EncryptDeterministic)},
+			validate: []ValidateOption{ValidateFloatAttributes("price_eur", "count")},
+			want:     []problem{{Code: ProblemDeterministicFloat, Attribute: "price_eur"}},
+			err:      ErrDeterministicFloat,
+		},
+		{
+			name:     "Unknown table schema",
+			provider: cmProvider,
+			tables:   []string{"orders", "described"},
+			want:     []problem{{Code: ProblemTableSchema, Table: "described"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			options := append([]Option{WithEncryption("Label", EncryptNone)}, tc.options...)
+			ec := NewEncryptedClient(deniedClient{}, tc.provider, WithPrimaryKeyInfo("orders", pkInfo), WithOptions(options...))
+			problems := ec.Validate(context.Background(), tc.tables, tc.validate...)
+
+			var got []problem
+			for _, p := range problems {
+				got = append(got, problem{Code: p.Code, Table: p.Table, Attribute: p.Attribute})
+				if p.Hint == "" {
+					t.Errorf("expected a remediation hint for %s", p.Code)
+				}
+				if tc.err != nil && !errors.Is(p, tc.err) {
+					t.Errorf("expected %v to wrap %v", p, tc.err)
+				}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected problems (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConfigProblem_Error(t *testing.T) {
+	problem := ConfigProblem{Code: ProblemKeyAttributeEncrypted, Table: "orders", Attribute: "ID", Err: ErrKeyAttributeEncrypted}
+	if got, want := problem.Error(), `table orders: attribute "ID": key attributes can not be encrypted`; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
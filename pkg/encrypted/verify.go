@@ -0,0 +1,158 @@
+package encrypted
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+)
+
+// ErrVerificationOnly is returned when a client in verification-only mode is asked to encrypt an item.
+var ErrVerificationOnly = errors.New("client is in verification-only mode")
+
+// MaskedValue replaces encrypted attributes returned by a verification-only client using MaskEncrypted.
+const MaskedValue = "********"
+
+// VerifiedOutput selects what a verification-only client returns in place of encrypted attributes.
+type VerifiedOutput int
+
+const (
+	ReturnCiphertext VerifiedOutput = iota // Encrypted attributes are returned as stored, as binary ciphertext.
+	MaskEncrypted                          // Encrypted attributes are replaced with the string MaskedValue.
+)
+
+// WithVerificationOnly puts the client in verification-only mode, for integrity-monitoring jobs that must not have
+// access to plaintext. Reads verify each item's signature, and the signature of its materials' wrapped keyset, but
+// never unwrap keys, so KMS is not called. Encrypted attributes are returned as selected by output. Writes that need
+// encryption fail with ErrVerificationOnly.
+//
+// Public keys are loaded from the provider's published verification keys when it implements
+// provider.VerificationMaterialsProvider, falling back to stored material descriptions through
+// provider.MaterialDescriber. Stored material descriptions are only trusted when their public key matches the
+// published verification key, or when they are legacy descriptions the provider accepts. Items whose integrity can
+// not be checked at all, because they are unsigned and only verification keys are available, fail with
+// ErrInvalidSignature.
+func WithVerificationOnly(output VerifiedOutput) EncryptedClientOption {
+	return func(ec *EncryptedClient) {
+		ec.verifyOnly = &output
+	}
+}
+
+// verifyOnlyItem verifies a stored item without decrypting it.
+func (ec *EncryptedClient) verifyOnlyItem(ctx context.Context, pkInfo *PrimaryKeyInfo, item map[string]types.AttributeValue, materialName string, version int64) (map[string]types.AttributeValue, error) {
+	_, signed := item[SignatureAttribute]
+
+	var description map[string]string
+	var err error
+	verifier, hasVerificationKeys := ec.MaterialsProvider.(provider.VerificationMaterialsProvider)
+	describer, hasDescriptions := ec.MaterialsProvider.(provider.MaterialDescriber)
+	switch {
+	case hasVerificationKeys && (signed || !hasDescriptions):
+		if !signed {
+			return nil, fmt.Errorf("%w: item is not signed", ErrInvalidSignature)
+		}
+		verificationMaterials, err := verifier.VerificationMaterials(itemContext(ctx, pkInfo, item), materialName, version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch verification materials: %v", err)
+		}
+		description = verificationMaterials.MaterialDescription()
+	case hasDescriptions:
+		if description, err = describer.DescribeMaterials(itemContext(ctx, pkInfo, item), materialName, version); err != nil {
+			return nil, fmt.Errorf("failed to fetch material description: %v", err)
+		}
+		if err := ec.verifyMaterialSignature(itemContext(ctx, pkInfo, item), materialName, version, description); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("materials provider %T can not load materials without decrypting them", ec.MaterialsProvider)
+	}
+
+	config := ec.Config()
+	if err := verifyItem(pkInfo.Table, item, description, config.systemAttributes()); err != nil {
+		return nil, err
+	}
+
+	encryption := applyActionOverrides(ctx, config.Encryption)
+	if report := reportFromContext(ctx); report != nil {
+		report.fill(pkInfo, encryption, materialName, item, description, nil)
+	}
+	verifiedItem := make(map[string]types.AttributeValue, len(item))
+	for key, value := range item {
+		if isReservedAttribute(key) {
+			continue
+		}
+		_, isBinary := value.(*types.AttributeValueMemberB)
+		if *ec.verifyOnly == MaskEncrypted && isBinary && !pkInfo.IsKeyAttribute(key) && !isSystemAttribute(config.systemAttributes(), key) && encryption.ActionFor(key) != EncryptNone {
+			value = &types.AttributeValueMemberS{Value: MaskedValue}
+		}
+		verifiedItem[key] = value
+	}
+	return ec.runPostDecryptHooks(ctx, pkInfo, verifiedItem, nil)
+}
+
+// verifyMaterialSignature checks the signatures over the wrapped keyset and over the entries of a stored material
+// description. Without KMS, the description's public key is only trusted when it matches the verification key the
+// provider published for the materials. Legacy descriptions, without a DescriptionFormat entry, are checked with their
+// own public key if the provider accepts them, and may be unsigned.
+func (ec *EncryptedClient) verifyMaterialSignature(ctx context.Context, materialName string, version int64, description map[string]string) error {
+	decode := func(description map[string]string, key string) ([]byte, error) {
+		value, err := base64.StdEncoding.DecodeString(description[key])
+		if err != nil || len(value) == 0 {
+			return nil, fmt.Errorf("%w: material description has no valid %s", ErrInvalidSignature, key)
+		}
+		return value, nil
+	}
+
+	wrappedKeyset, err := decode(description, "WrappedKeyset")
+	if err != nil {
+		return err
+	}
+	publicKey, err := decode(description, "PublicKey")
+	if err != nil {
+		return err
+	}
+	signature, err := decode(description, "Signature")
+	if err != nil {
+		return err
+	}
+
+	legacy := materials.IsLegacyDescription(description)
+	if policy, ok := ec.MaterialsProvider.(provider.LegacyDescriptionPolicy); legacy && (!ok || !policy.AcceptsLegacyDescriptions()) {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, provider.ErrLegacyDescription)
+	}
+	if !legacy {
+		verifier, ok := ec.MaterialsProvider.(provider.VerificationMaterialsProvider)
+		if !ok {
+			return fmt.Errorf("%w: materials provider %T publishes no verification keys to trust the material description with", ErrInvalidSignature, ec.MaterialsProvider)
+		}
+		verificationMaterials, err := verifier.VerificationMaterials(ctx, materialName, version)
+		if err != nil {
+			return fmt.Errorf("failed to fetch verification materials: %v", err)
+		}
+		published, err := decode(verificationMaterials.MaterialDescription(), "PublicKey")
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(published, publicKey) {
+			return fmt.Errorf("%w: material description's public key is not the published verification key", ErrInvalidSignature)
+		}
+	}
+
+	valid, err := delegatedkeys.VerifySignature(publicKey, signature, wrappedKeyset)
+	if err != nil || !valid {
+		return fmt.Errorf("%w: wrapped keyset signature does not match", ErrInvalidSignature)
+	}
+	if _, signed := description[materials.DescriptionSignatureKey]; !signed && legacy {
+		return nil
+	}
+	if err := materials.VerifyDescription(description, publicKey); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package encrypted
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// verificationProvider only serves verification materials; any other call panics on the nil provider.
+type verificationProvider struct {
+	provider.CryptographicMaterialsProvider
+	description map[string]string
+}
+
+func (p *verificationProvider) VerificationMaterials(ctx context.Context, materialName string, version int64) (materials.CryptographicMaterials, error) {
+	return materials.NewVerificationMaterials(p.description), nil
+}
+
+// describingProvider serves published verification materials and accepts legacy descriptions when legacy is set.
+// Its stored descriptions are passed to verifyMaterialSignature directly.
+type describingProvider struct {
+	verificationProvider
+	legacy bool
+}
+
+func (p *describingProvider) DescribeMaterials(ctx context.Context, materialName string, version int64) (map[string]string, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (p *describingProvider) AcceptsLegacyDescriptions() bool {
+	return p.legacy
+}
+
+// unpublishedProvider serves stored material descriptions only, without verification keys.
+type unpublishedProvider struct {
+	provider.CryptographicMaterialsProvider
+}
+
+func (unpublishedProvider) DescribeMaterials(ctx context.Context, materialName string, version int64) (map[string]string, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestEncryptedClient_VerifyMaterialSignature(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	signingKey, _, publicKey, err := delegatedkeys.GenerateSigningKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+	otherKey, _, otherPublicKey, err := delegatedkeys.GenerateSigningKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+	published := map[string]string{"PublicKey": base64.StdEncoding.EncodeToString(publicKey)}
+
+	sign := func(t *testing.T, description map[string]string, signingKey delegatedkeys.DelegatedKey, publicKey []byte) {
+		t.Helper()
+		signature, err := signingKey.Sign([]byte("keyset"))
+		if err != nil {
+			t.Fatalf("failed to sign keyset: %v", err)
+		}
+		description["WrappedKeyset"] = base64.StdEncoding.EncodeToString([]byte("keyset"))
+		description["Signature"] = base64.StdEncoding.EncodeToString(signature)
+		description["PublicKey"] = base64.StdEncoding.EncodeToString(publicKey)
+		if err := materials.SignDescription(description, signingKey); err != nil {
+			t.Fatalf("failed to sign description: %v", err)
+		}
+	}
+	unsign := func(t *testing.T, description map[string]string) {
+		delete(description, materials.DescriptionFormatKey)
+		delete(description, materials.DescriptionSignatureKey)
+	}
+
+	testCases := []struct {
+		name     string
+		provider provider.CryptographicMaterialsProvider
+		tamper   func(t *testing.T, description map[string]string)
+		err      error
+	}{
+		{name: "Signed", provider: &describingProvider{verificationProvider: verificationProvider{description: published}}},
+		{name: "Changed entry", provider: &describingProvider{verificationProvider: verificationProvider{description: published}}, tamper: func(t *testing.T, description map[string]string) {
+			description["tenant"] = "other"
+		}, err: ErrInvalidSignature},
+		{name: "Signature stripped", provider: &describingProvider{verificationProvider: verificationProvider{description: published}}, tamper: func(t *testing.T, description map[string]string) {
+			description["tenant"] = "other"
+			delete(description, materials.DescriptionSignatureKey)
+		}, err: ErrInvalidSignature},
+		{name: "Re-signed with another key", provider: &describingProvider{verificationProvider: verificationProvider{description: published}}, tamper: func(t *testing.T, description map[string]string) {
+			description["tenant"] = "other"
+			sign(t, description, otherKey, otherPublicKey)
+		}, err: ErrInvalidSignature},
+		{name: "Legacy", provider: &describingProvider{verificationProvider: verificationProvider{description: published}, legacy: true}, tamper: unsign},
+		{name: "Legacy not accepted", provider: &describingProvider{verificationProvider: verificationProvider{description: published}}, tamper: unsign, err: ErrInvalidSignature},
+		{name: "No verification keys", provider: unpublishedProvider{}, err: ErrInvalidSignature},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			description := map[string]string{"tenant": "acme"}
+			sign(t, description, signingKey, publicKey)
+			if tc.tamper != nil {
+				tc.tamper(t, description)
+			}
+			ec := NewEncryptedClient(nil, tc.provider, WithVerificationOnly(ReturnCiphertext))
+			err := ec.verifyMaterialSignature(context.Background(), "users", 1, description)
+			if tc.err == nil && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestEncryptedClient_VerificationOnly(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	signingKey, _, publicKey, err := delegatedkeys.GenerateSigningKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+	description := map[string]string{"PublicKey": base64.StdEncoding.EncodeToString(publicKey)}
+	encryptionMaterials := materials.NewEncryptionMaterials(description, nil, signingKey)
+
+	newItem := func() map[string]types.AttributeValue {
+		item := map[string]types.AttributeValue{
+			"ID":     &types.AttributeValueMemberS{Value: "1"},
+			"Role":   &types.AttributeValueMemberS{Value: "reader"},
+			"Secret": &types.AttributeValueMemberB{Value: []byte{1, 2, 3}},
+		}
+		if err := signItem("users", item, encryptionMaterials, nil); err != nil {
+			t.Fatalf("failed to sign item: %v", err)
+		}
+		return item
+	}
+
+	testCases := []struct {
+		name     string
+		output   VerifiedOutput
+		tamper   func(item map[string]types.AttributeValue)
+		expected map[string]types.AttributeValue
+		err      error
+	}{
+		{
+			name:   "Ciphertext",
+			output: ReturnCiphertext,
+			expected: map[string]types.AttributeValue{
+				"ID":     &types.AttributeValueMemberS{Value: "1"},
+				"Role":   &types.AttributeValueMemberS{Value: "reader"},
+				"Secret": &types.AttributeValueMemberB{Value: []byte{1, 2, 3}},
+			},
+		},
+		{
+			name:   "Masked",
+			output: MaskEncrypted,
+			expected: map[string]types.AttributeValue{
+				"ID":     &types.AttributeValueMemberS{Value: "1"},
+				"Role":   &types.AttributeValueMemberS{Value: "reader"},
+				"Secret": &types.AttributeValueMemberS{Value: MaskedValue},
+			},
+		},
+		{
+			name:   "Tampered",
+			output: MaskEncrypted,
+			tamper: func(item map[string]types.AttributeValue) {
+				item["Role"] = &types.AttributeValueMemberS{Value: "admin"}
+			},
+			err: ErrInvalidSignature,
+		},
+		{
+			name:   "Unsigned",
+			output: MaskEncrypted,
+			tamper: func(item map[string]types.AttributeValue) {
+				delete(item, SignatureAttribute)
+			},
+			err: ErrInvalidSignature,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec := NewEncryptedClient(nil, &verificationProvider{description: description},
+				WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}),
+				WithOptions(WithEncryption("Role", EncryptNone)),
+				WithVerificationOnly(tc.output),
+			)
+
+			item := newItem()
+			if tc.tamper != nil {
+				tc.tamper(item)
+			}
+			verified, err := ec.decryptItem(context.Background(), "users", item)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Expected error %v, got %v", tc.err, err)
+			}
+			if diff := cmp.Diff(tc.expected, verified, cmpopts.IgnoreUnexported(
+				types.AttributeValueMemberS{}, types.AttributeValueMemberB{},
+			)); tc.err == nil && diff != "" {
+				t.Errorf("unexpected item (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	ec := NewEncryptedClient(nil, &verificationProvider{description: description},
+		WithPrimaryKeyInfo("users", PrimaryKeyInfo{PartitionKey: "ID"}), WithVerificationOnly(ReturnCiphertext))
+	if _, err := ec.prepareEncryption(context.Background(), "users", newItem()); !errors.Is(err, ErrVerificationOnly) {
+		t.Errorf("Expected ErrVerificationOnly, got %v", err)
+	}
+}
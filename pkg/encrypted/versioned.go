@@ -0,0 +1,293 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// VersionEnabledAttribute is the item attribute in which a VersionedItemStore records whether a version is enabled.
+const VersionEnabledAttribute = "VersionEnabled"
+
+// ErrVersionNotFound is returned when a versioned item, or a requested version of it, does not exist.
+var ErrVersionNotFound = errors.New("item version not found")
+
+// ErrVersionConflict is returned when a new version could not be written because another writer created the same
+// version first. Reading the latest version and retrying resolves it.
+var ErrVersionConflict = errors.New("item version already exists")
+
+// VersionedItemStore keeps numbered, encrypted versions of named items, such as secrets, in a table with a sort key.
+// Version n of the item name under a partition key value is stored with the sort key "name#n", with n zero-padded
+// so versions sort numerically. New versions are written with a condition on their sort key, so concurrent writers
+// never overwrite each other's versions.
+type VersionedItemStore struct {
+	table     *EncryptedTable
+	tableName string
+}
+
+// ItemVersion is a decrypted version of a versioned item.
+type ItemVersion struct {
+	Version int64
+	Enabled bool
+	Item    map[string]types.AttributeValue
+}
+
+// VersionedWrite is a new version of an item to write with WriteVersions.
+type VersionedWrite struct {
+	Store     *VersionedItemStore
+	Partition types.AttributeValue
+	Name      string
+	Item      map[string]types.AttributeValue
+}
+
+// NewVersionedItemStore creates a VersionedItemStore for a table whose sort key is a string attribute.
+func NewVersionedItemStore(table *EncryptedTable, tableName string) *VersionedItemStore {
+	return &VersionedItemStore{table: table, tableName: tableName}
+}
+
+// versionSortKey returns the sort key value of a version of a named item.
+func versionSortKey(name string, version int64) string {
+	return fmt.Sprintf("%s#%010d", name, version)
+}
+
+// parseVersion returns the version number of a sort key value written by versionSortKey.
+func parseVersion(name, sortKey string) (int64, error) {
+	version, err := strconv.ParseInt(strings.TrimPrefix(sortKey, name+"#"), 10, 64)
+	if err != nil || !strings.HasPrefix(sortKey, name+"#") {
+		return 0, fmt.Errorf("unexpected sort key %q for item %q", sortKey, name)
+	}
+	return version, nil
+}
+
+// keyInfo returns the table's key info, requiring a sort key.
+func (s *VersionedItemStore) keyInfo(ctx context.Context) (*PrimaryKeyInfo, error) {
+	pkInfo, err := s.table.client.getPrimaryKeyInfo(ctx, s.tableName)
+	if err != nil {
+		return nil, err
+	}
+	if pkInfo.SortKey == "" {
+		return nil, fmt.Errorf("table %s has no sort key to store versions in", s.tableName)
+	}
+	return pkInfo, nil
+}
+
+// Put writes the item as the next version of the named item and returns its version number, starting at 1.
+func (s *VersionedItemStore) Put(ctx context.Context, partition types.AttributeValue, name string, item map[string]types.AttributeValue) (int64, error) {
+	versions, err := WriteVersions(ctx, VersionedWrite{Store: s, Partition: partition, Name: name, Item: item})
+	if err != nil {
+		return 0, err
+	}
+	return versions[0], nil
+}
+
+// WriteVersions writes the next version of several items, which may belong to different stores and tables of the
+// same client, in a single transaction. Either every version is written or none is; if any of them was created
+// concurrently, ErrVersionConflict is returned. It returns the new version numbers in order.
+func WriteVersions(ctx context.Context, writes ...VersionedWrite) ([]int64, error) {
+	if len(writes) == 0 {
+		return nil, nil
+	}
+	client := writes[0].Store.table.client
+
+	versions := make([]int64, len(writes))
+	transactItems := make([]types.TransactWriteItem, len(writes))
+	for i, write := range writes {
+		if write.Store.table.client != client {
+			return nil, errors.New("versioned writes must share an encrypted client")
+		}
+		if strings.Contains(write.Name, "#") {
+			return nil, fmt.Errorf("item name %q must not contain '#'", write.Name)
+		}
+		pkInfo, err := write.Store.keyInfo(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		latest, err := write.Store.latest(ctx, pkInfo, write.Partition, write.Name, false)
+		switch {
+		case errors.Is(err, ErrVersionNotFound):
+			versions[i] = 1
+		case err != nil:
+			return nil, err
+		default:
+			versions[i] = latest.Version + 1
+		}
+
+		item := make(map[string]types.AttributeValue, len(write.Item)+3)
+		for name, value := range write.Item {
+			item[name] = value
+		}
+		item[pkInfo.PartitionKey] = write.Partition
+		item[pkInfo.SortKey] = &types.AttributeValueMemberS{Value: versionSortKey(write.Name, versions[i])}
+		item[VersionEnabledAttribute] = &types.AttributeValueMemberBOOL{Value: true}
+		transactItems[i] = types.TransactWriteItem{Put: &types.Put{
+			TableName:                aws.String(write.Store.tableName),
+			Item:                     item,
+			ConditionExpression:      aws.String("attribute_not_exists(#sk)"),
+			ExpressionAttributeNames: map[string]string{"#sk": pkInfo.SortKey},
+		}}
+	}
+
+	if _, err := client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: transactItems}); err != nil {
+		var canceled *types.TransactionCanceledException
+		if errors.As(err, &canceled) {
+			for _, reason := range canceled.CancellationReasons {
+				if aws.StringValue(reason.Code) == "ConditionalCheckFailed" {
+					return nil, fmt.Errorf("%w: %v", ErrVersionConflict, err)
+				}
+			}
+		}
+		return nil, fmt.Errorf("failed to write versions: %w", err)
+	}
+	return versions, nil
+}
+
+// Latest returns the latest enabled version of the named item, or ErrVersionNotFound if there is none.
+func (s *VersionedItemStore) Latest(ctx context.Context, partition types.AttributeValue, name string) (*ItemVersion, error) {
+	pkInfo, err := s.keyInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.latest(ctx, pkInfo, partition, name, true)
+}
+
+// latest returns the latest version of the named item, skipping disabled versions if enabledOnly is set.
+func (s *VersionedItemStore) latest(ctx context.Context, pkInfo *PrimaryKeyInfo, partition types.AttributeValue, name string, enabledOnly bool) (*ItemVersion, error) {
+	versions, err := s.versions(ctx, pkInfo, partition, name, false)
+	if err != nil {
+		return nil, err
+	}
+	for i := range versions {
+		if versions[i].Enabled || !enabledOnly {
+			return &versions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrVersionNotFound, name)
+}
+
+// Get returns a version of the named item, whether or not it is enabled.
+func (s *VersionedItemStore) Get(ctx context.Context, partition types.AttributeValue, name string, version int64) (*ItemVersion, error) {
+	pkInfo, err := s.keyInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.get(ctx, pkInfo, partition, name, version)
+}
+
+func (s *VersionedItemStore) get(ctx context.Context, pkInfo *PrimaryKeyInfo, partition types.AttributeValue, name string, version int64) (*ItemVersion, error) {
+	key := map[string]types.AttributeValue{
+		pkInfo.PartitionKey: partition,
+		pkInfo.SortKey:      &types.AttributeValueMemberS{Value: versionSortKey(name, version)},
+	}
+	output, err := s.table.client.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.tableName),
+		Key:            key,
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving item version: %v", err)
+	}
+	if output.Item == nil {
+		return nil, fmt.Errorf("%w: %s version %d", ErrVersionNotFound, name, version)
+	}
+	item, err := s.table.client.decryptItem(ctx, s.tableName, output.Item)
+	if err != nil {
+		return nil, err
+	}
+	return newItemVersion(pkInfo, name, item)
+}
+
+// Versions returns every version of the named item, newest first, including disabled ones.
+func (s *VersionedItemStore) Versions(ctx context.Context, partition types.AttributeValue, name string) ([]ItemVersion, error) {
+	pkInfo, err := s.keyInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.versions(ctx, pkInfo, partition, name, true)
+}
+
+// versions returns every version of the named item, newest first. If required is set, an item without versions
+// is an error.
+func (s *VersionedItemStore) versions(ctx context.Context, pkInfo *PrimaryKeyInfo, partition types.AttributeValue, name string, required bool) ([]ItemVersion, error) {
+	input := &dynamodb.QueryInput{
+		KeyConditionExpression:   aws.String("#pk = :pk AND begins_with(#sk, :prefix)"),
+		ExpressionAttributeNames: map[string]string{"#pk": pkInfo.PartitionKey, "#sk": pkInfo.SortKey},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk":     partition,
+			":prefix": &types.AttributeValueMemberS{Value: name + "#"},
+		},
+		ConsistentRead:   aws.Bool(true),
+		ScanIndexForward: aws.Bool(false),
+	}
+
+	var versions []ItemVersion
+	for {
+		output, err := s.table.Query(ctx, s.tableName, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range output.Items {
+			version, err := newItemVersion(pkInfo, name, item)
+			if err != nil {
+				return nil, err
+			}
+			versions = append(versions, *version)
+		}
+		if len(output.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+	if len(versions) == 0 && required {
+		return nil, fmt.Errorf("%w: %s", ErrVersionNotFound, name)
+	}
+	return versions, nil
+}
+
+// Disable marks a version of the named item as disabled, so Latest skips it. The version is re-encrypted and
+// rewritten with the flag cleared, which keeps item signatures valid.
+func (s *VersionedItemStore) Disable(ctx context.Context, partition types.AttributeValue, name string, version int64) error {
+	pkInfo, err := s.keyInfo(ctx)
+	if err != nil {
+		return err
+	}
+	current, err := s.get(ctx, pkInfo, partition, name, version)
+	if err != nil {
+		return err
+	}
+	if !current.Enabled {
+		return nil
+	}
+
+	current.Item[VersionEnabledAttribute] = &types.AttributeValueMemberBOOL{Value: false}
+	_, err = s.table.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: []types.TransactWriteItem{{Put: &types.Put{
+		TableName:                aws.String(s.tableName),
+		Item:                     current.Item,
+		ConditionExpression:      aws.String("attribute_exists(#sk)"),
+		ExpressionAttributeNames: map[string]string{"#sk": pkInfo.SortKey},
+	}}}})
+	if err != nil {
+		return fmt.Errorf("failed to disable version: %w", err)
+	}
+	return nil
+}
+
+// newItemVersion reads the version number and enabled flag of a decrypted item version.
+func newItemVersion(pkInfo *PrimaryKeyInfo, name string, item map[string]types.AttributeValue) (*ItemVersion, error) {
+	sortKey, ok := item[pkInfo.SortKey].(*types.AttributeValueMemberS)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type for sort key %s", pkInfo.SortKey)
+	}
+	version, err := parseVersion(name, sortKey.Value)
+	if err != nil {
+		return nil, err
+	}
+	enabled, _ := item[VersionEnabledAttribute].(*types.AttributeValueMemberBOOL)
+	return &ItemVersion{Version: version, Enabled: enabled != nil && enabled.Value, Item: item}, nil
+}
@@ -0,0 +1,160 @@
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/google/go-cmp/cmp"
+)
+
+// versionClient stores the items of tables keyed by string "PK" and "SK" attributes in memory.
+type versionClient struct {
+	DynamoDBClientInterface
+	items       map[string]map[string]types.AttributeValue
+	beforeWrite func()
+}
+
+func versionKey(tableName string, item map[string]types.AttributeValue) string {
+	return tableName + "/" + item["PK"].(*types.AttributeValueMemberS).Value + "/" + item["SK"].(*types.AttributeValueMemberS).Value
+}
+
+func (c *versionClient) TransactWriteItems(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	if c.beforeWrite != nil {
+		c.beforeWrite()
+		c.beforeWrite = nil
+	}
+	reasons := make([]types.CancellationReason, len(input.TransactItems))
+	failed := false
+	for i, item := range input.TransactItems {
+		_, exists := c.items[versionKey(aws.StringValue(item.Put.TableName), item.Put.Item)]
+		if exists == strings.HasPrefix(*item.Put.ConditionExpression, "attribute_not_exists") {
+			reasons[i].Code = aws.String("ConditionalCheckFailed")
+			failed = true
+		}
+	}
+	if failed {
+		return nil, &types.TransactionCanceledException{CancellationReasons: reasons}
+	}
+	for _, item := range input.TransactItems {
+		c.items[versionKey(aws.StringValue(item.Put.TableName), item.Put.Item)] = item.Put.Item
+	}
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+func (c *versionClient) GetItem(ctx context.Context, input *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: c.items[versionKey(aws.StringValue(input.TableName), input.Key)]}, nil
+}
+
+func (c *versionClient) Query(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	prefix := aws.StringValue(input.TableName) + "/" + input.ExpressionAttributeValues[":pk"].(*types.AttributeValueMemberS).Value + "/" +
+		input.ExpressionAttributeValues[":prefix"].(*types.AttributeValueMemberS).Value
+	var keys []string
+	for key := range c.items {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+	output := &dynamodb.QueryOutput{}
+	for _, key := range keys {
+		output.Items = append(output.Items, c.items[key])
+	}
+	return output, nil
+}
+
+func TestVersionedItemStore(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+
+	client := &versionClient{items: make(map[string]map[string]types.AttributeValue)}
+	ec := NewEncryptedClient(client, &staticProvider{dataKey: dataKey},
+		WithPrimaryKeyInfo("secrets", PrimaryKeyInfo{PartitionKey: "PK", SortKey: "SK"}),
+		WithPrimaryKeyInfo("audit", PrimaryKeyInfo{PartitionKey: "PK", SortKey: "SK"}),
+	)
+	secrets := NewVersionedItemStore(NewEncryptedTable(ec), "secrets")
+	audit := NewVersionedItemStore(NewEncryptedTable(ec), "audit")
+	tenant := &types.AttributeValueMemberS{Value: "tenant1"}
+	secret := func(value string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{"Data": &types.AttributeValueMemberS{Value: value}}
+	}
+	data := func(version *ItemVersion) string {
+		return version.Item["Data"].(*types.AttributeValueMemberS).Value
+	}
+
+	if _, err := secrets.Latest(context.Background(), tenant, "db"); !errors.Is(err, ErrVersionNotFound) {
+		t.Fatalf("expected ErrVersionNotFound, got %v", err)
+	}
+	for i, value := range []string{"one", "two"} {
+		version, err := secrets.Put(context.Background(), tenant, "db", secret(value))
+		if err != nil {
+			t.Fatalf("failed to put version: %v", err)
+		}
+		if version != int64(i+1) {
+			t.Errorf("expected version %d, got %d", i+1, version)
+		}
+	}
+	for key, item := range client.items {
+		if _, ok := item["Data"].(*types.AttributeValueMemberB); !ok {
+			t.Errorf("expected %s to be stored encrypted", key)
+		}
+	}
+
+	versions, err := WriteVersions(context.Background(),
+		VersionedWrite{Store: secrets, Partition: tenant, Name: "db", Item: secret("three")},
+		VersionedWrite{Store: audit, Partition: tenant, Name: "db", Item: secret("rotated")},
+	)
+	if err != nil {
+		t.Fatalf("failed to write versions: %v", err)
+	}
+	if diff := cmp.Diff([]int64{3, 1}, versions); diff != "" {
+		t.Errorf("unexpected versions (-want +got):\n%s", diff)
+	}
+
+	if err := secrets.Disable(context.Background(), tenant, "db", 3); err != nil {
+		t.Fatalf("failed to disable version: %v", err)
+	}
+	latest, err := secrets.Latest(context.Background(), tenant, "db")
+	if err != nil {
+		t.Fatalf("failed to read latest version: %v", err)
+	}
+	if latest.Version != 2 || data(latest) != "two" {
+		t.Errorf("expected enabled version 2, got %d with %q", latest.Version, data(latest))
+	}
+
+	all, err := secrets.Versions(context.Background(), tenant, "db")
+	if err != nil {
+		t.Fatalf("failed to list versions: %v", err)
+	}
+	var listed []int64
+	for _, version := range all {
+		listed = append(listed, version.Version)
+	}
+	if diff := cmp.Diff([]int64{3, 2, 1}, listed); diff != "" {
+		t.Errorf("unexpected versions (-want +got):\n%s", diff)
+	}
+	if all[0].Enabled {
+		t.Errorf("expected version 3 to be disabled")
+	}
+
+	// A version created between reading the latest version and writing the next one makes the write fail
+	client.beforeWrite = func() {
+		concurrent := map[string]types.AttributeValue{"PK": tenant, "SK": &types.AttributeValueMemberS{Value: versionSortKey("db", 4)}}
+		client.items[versionKey("secrets", concurrent)] = concurrent
+	}
+	if _, err := secrets.Put(context.Background(), tenant, "db", secret("four")); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("expected ErrVersionConflict, got %v", err)
+	}
+}
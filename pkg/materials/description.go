@@ -0,0 +1,114 @@
+package materials
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+)
+
+// DescriptionSignatureKey is the material description entry holding the signature over the canonical description.
+const DescriptionSignatureKey = "DescriptionSignature"
+
+// DescriptionFormatKey is the material description entry recording the format of descriptions that are signed and
+// whose keysets are bound to their encryption context and public key. Descriptions without it are legacy.
+const DescriptionFormatKey = "DescriptionFormat"
+
+// DescriptionFormatVersion is the DescriptionFormatKey value SignDescription records.
+const DescriptionFormatVersion = "1"
+
+// descriptionSigningPrefix starts every canonical description, naming the encoding and its version.
+const descriptionSigningPrefix = "DDBENC-MATERIAL-DESCRIPTION-V1\n"
+
+// unsignedEntries are left out of the canonical description: the signature itself, and the material version, which
+// the material store assigns after the materials were signed.
+var unsignedEntries = map[string]bool{
+	DescriptionSignatureKey: true,
+	"MaterialVersion":       true,
+}
+
+// ErrInvalidDescriptionSignature is returned when a material description's signature does not match its entries.
+var ErrInvalidDescriptionSignature = errors.New("material description signature does not match")
+
+// ErrUnsignedDescription is returned when verifying a material description without a signature.
+var ErrUnsignedDescription = fmt.Errorf("%w: description is not signed", ErrInvalidDescriptionSignature)
+
+// CanonicalDescription returns the payload a material description is signed over. It is defined so implementations
+// in other languages can produce it byte for byte:
+//
+//   - the ASCII prefix "DDBENC-MATERIAL-DESCRIPTION-V1" followed by a line feed, then
+//   - every entry except DescriptionSignature and MaterialVersion, ordered by the bytes of their UTF-8 keys, each
+//     encoded as the key's length as a 4-byte big-endian integer, the key, the value's length as a 4-byte
+//     big-endian integer and the value.
+//
+// The signature is a Tink ECDSA P-256 signature by the materials' signing key, stored base64-encoded under
+// DescriptionSignatureKey, and verified with the Tink public keyset stored base64-encoded under PublicKey once that key
+// is authenticated.
+func CanonicalDescription(description map[string]string) []byte {
+	keys := make([]string, 0, len(description))
+	size := len(descriptionSigningPrefix)
+	for key, value := range description {
+		if unsignedEntries[key] {
+			continue
+		}
+		keys = append(keys, key)
+		size += 8 + len(key) + len(value)
+	}
+	sort.Strings(keys)
+
+	payload := make([]byte, 0, size)
+	payload = append(payload, descriptionSigningPrefix...)
+	for _, key := range keys {
+		payload = binary.BigEndian.AppendUint32(payload, uint32(len(key)))
+		payload = append(payload, key...)
+		payload = binary.BigEndian.AppendUint32(payload, uint32(len(description[key])))
+		payload = append(payload, description[key]...)
+	}
+	return payload
+}
+
+// SignDescription records the description format under DescriptionFormatKey, signs the canonical form of a material
+// description with signingKey and records the signature under DescriptionSignatureKey.
+func SignDescription(description map[string]string, signingKey delegatedkeys.DelegatedKey) error {
+	description[DescriptionFormatKey] = DescriptionFormatVersion
+	signature, err := signingKey.Sign(CanonicalDescription(description))
+	if err != nil {
+		return fmt.Errorf("failed to sign material description: %v", err)
+	}
+	description[DescriptionSignatureKey] = base64.StdEncoding.EncodeToString(signature)
+	return nil
+}
+
+// IsLegacyDescription reports whether a material description has no DescriptionFormatKey entry, having been written
+// before descriptions were signed and bound to their keysets. Whether to accept such descriptions is up to the caller.
+func IsLegacyDescription(description map[string]string) bool {
+	_, ok := description[DescriptionFormatKey]
+	return !ok
+}
+
+// VerifyDescription checks the signature over a material description with publicKey, a Tink public keyset. The key
+// must be trusted independently of the description, for example because it was bound to the wrapped keyset that
+// unwrapped, or published as a verification key; the PublicKey entry of an unauthenticated description only proves
+// that whoever wrote the description also signed it. Descriptions without a signature fail with
+// ErrUnsignedDescription.
+func VerifyDescription(description map[string]string, publicKey []byte) error {
+	encoded, ok := description[DescriptionSignatureKey]
+	if !ok {
+		return ErrUnsignedDescription
+	}
+	signature, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("%w: invalid signature encoding", ErrInvalidDescriptionSignature)
+	}
+	if len(publicKey) == 0 {
+		return fmt.Errorf("%w: no public key", ErrInvalidDescriptionSignature)
+	}
+	valid, err := delegatedkeys.VerifySignature(publicKey, signature, CanonicalDescription(description))
+	if err != nil || !valid {
+		return ErrInvalidDescriptionSignature
+	}
+	return nil
+}
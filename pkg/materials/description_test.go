@@ -0,0 +1,132 @@
+package materials
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+)
+
+// descriptionVector is a conformance vector for material description signing, shared with implementations in other
+// languages. Vectors with a DescriptionSignature also state whether it verifies.
+type descriptionVector struct {
+	Name        string            `json:"name"`
+	Description map[string]string `json:"description"`
+	Canonical   string            `json:"canonical"`
+	Valid       *bool             `json:"valid"`
+}
+
+func loadDescriptionVectors(t *testing.T) []descriptionVector {
+	data, err := os.ReadFile("testdata/description_vectors.json")
+	if err != nil {
+		t.Fatalf("failed to read vectors: %v", err)
+	}
+	var file struct {
+		Vectors []descriptionVector `json:"vectors"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("failed to parse vectors: %v", err)
+	}
+	return file.Vectors
+}
+
+func TestCanonicalDescription_Vectors(t *testing.T) {
+	for _, tc := range loadDescriptionVectors(t) {
+		t.Run(tc.Name, func(t *testing.T) {
+			if got := hex.EncodeToString(CanonicalDescription(tc.Description)); got != tc.Canonical {
+				t.Errorf("unexpected canonical description:\nwant %s\ngot  %s", tc.Canonical, got)
+			}
+			if tc.Valid == nil {
+				return
+			}
+			publicKey, _ := base64.StdEncoding.DecodeString(tc.Description["PublicKey"])
+			err := VerifyDescription(tc.Description, publicKey)
+			if *tc.Valid && err != nil {
+				t.Errorf("expected the signature to verify, got %v", err)
+			}
+			if !*tc.Valid && !errors.Is(err, ErrInvalidDescriptionSignature) {
+				t.Errorf("expected ErrInvalidDescriptionSignature, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSignDescription(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b", true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	signingKey, _, publicKey, err := delegatedkeys.GenerateSigningKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+	otherKey, _, otherPublicKey, err := delegatedkeys.GenerateSigningKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+
+	signed := func(t *testing.T, signingKey delegatedkeys.DelegatedKey, publicKey []byte) map[string]string {
+		description := map[string]string{
+			"tenant":    "acme",
+			"PublicKey": base64.StdEncoding.EncodeToString(publicKey),
+		}
+		if err := SignDescription(description, signingKey); err != nil {
+			t.Fatalf("failed to sign description: %v", err)
+		}
+		return description
+	}
+	if description := signed(t, signingKey, publicKey); description[DescriptionFormatKey] != DescriptionFormatVersion || IsLegacyDescription(description) {
+		t.Errorf("expected the description to record format %s, got %v", DescriptionFormatVersion, description)
+	}
+	if !IsLegacyDescription(map[string]string{"tenant": "acme"}) {
+		t.Errorf("expected a description without format to be legacy")
+	}
+
+	testCases := []struct {
+		name   string
+		tamper func(t *testing.T, description map[string]string)
+		err    error
+	}{
+		{name: "Signed"},
+		{name: "Material version assigned", tamper: func(t *testing.T, description map[string]string) {
+			description["MaterialVersion"] = "1"
+		}},
+		{name: "Changed entry", tamper: func(t *testing.T, description map[string]string) {
+			description["tenant"] = "other"
+		}, err: ErrInvalidDescriptionSignature},
+		{name: "Signature stripped", tamper: func(t *testing.T, description map[string]string) {
+			description["tenant"] = "other"
+			delete(description, DescriptionSignatureKey)
+		}, err: ErrUnsignedDescription},
+		{name: "Format and signature stripped", tamper: func(t *testing.T, description map[string]string) {
+			delete(description, DescriptionFormatKey)
+			delete(description, DescriptionSignatureKey)
+		}, err: ErrUnsignedDescription},
+		{name: "Re-signed with another key", tamper: func(t *testing.T, description map[string]string) {
+			description["tenant"] = "other"
+			for key, value := range signed(t, otherKey, otherPublicKey) {
+				description[key] = value
+			}
+		}, err: ErrInvalidDescriptionSignature},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			description := signed(t, signingKey, publicKey)
+			if tc.tamper != nil {
+				tc.tamper(t, description)
+			}
+			err := VerifyDescription(description, publicKey)
+			if tc.err == nil && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
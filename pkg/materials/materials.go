@@ -79,3 +79,34 @@ func (dm *DecryptionMaterials) DecryptionKey() delegatedkeys.DelegatedKey {
 func (dm *DecryptionMaterials) SigningKey() delegatedkeys.DelegatedKey {
 	panic("Decryption materials do not provide signing keys.")
 }
+
+// VerificationMaterials carry only the public entries of a material description, such as its PublicKey, for
+// consumers that verify signatures without access to the wrapped keysets.
+type VerificationMaterials struct {
+	materialDescription map[string]string
+}
+
+func NewVerificationMaterials(description map[string]string) CryptographicMaterials {
+	return &VerificationMaterials{
+		materialDescription: description,
+	}
+}
+
+func (vm *VerificationMaterials) MaterialDescription() map[string]string {
+	return vm.materialDescription
+}
+
+// EncryptionKey panics because VerificationMaterials does not provide an encryption key.
+func (vm *VerificationMaterials) EncryptionKey() delegatedkeys.DelegatedKey {
+	panic("Verification materials do not provide encryption keys.")
+}
+
+// DecryptionKey panics because VerificationMaterials does not provide a decryption key.
+func (vm *VerificationMaterials) DecryptionKey() delegatedkeys.DelegatedKey {
+	panic("Verification materials do not provide decryption keys.")
+}
+
+// SigningKey panics because VerificationMaterials does not provide a signing key.
+func (vm *VerificationMaterials) SigningKey() delegatedkeys.DelegatedKey {
+	panic("Verification materials do not provide signing keys.")
+}
@@ -0,0 +1,313 @@
+// Package pii suggests encryption policies by detecting attributes that likely hold personal data, such as
+// email addresses, social security numbers and card numbers. Suggestions are heuristic and meant to be reviewed
+// before they are applied.
+package pii
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/encrypted"
+)
+
+// Rule detects one kind of personal data, by attribute name, by value, or both.
+type Rule struct {
+	Name string
+	// NamePattern flags attributes whose name matches it, regardless of their values.
+	NamePattern *regexp.Regexp
+	// ValuePattern flags string and number values that match it.
+	ValuePattern *regexp.Regexp
+	// Validate, when set, must also accept a value matched by ValuePattern, for example a checksum.
+	Validate func(value string) bool
+}
+
+// DefaultRules detect common personal data in US and international formats.
+var DefaultRules = []Rule{
+	{
+		Name:         "email",
+		NamePattern:  regexp.MustCompile(`(?i)e-?mail`),
+		ValuePattern: regexp.MustCompile(`^[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}$`),
+	},
+	{
+		Name:         "ssn",
+		NamePattern:  regexp.MustCompile(`(?i)(^|_|\b)ssn|social_?security`),
+		ValuePattern: regexp.MustCompile(`^\d{3}-\d{2}-\d{4}$`),
+	},
+	{
+		Name:         "card",
+		NamePattern:  regexp.MustCompile(`(?i)card_?(number|num|no)|(^|_)pan$`),
+		ValuePattern: regexp.MustCompile(`^\d(?:[ -]?\d){12,18}$`),
+		Validate:     luhn,
+	},
+	{
+		Name:         "phone",
+		NamePattern:  regexp.MustCompile(`(?i)phone|mobile`),
+		ValuePattern: regexp.MustCompile(`^\+[1-9]\d{7,14}$`),
+	},
+	{
+		Name:        "secret",
+		NamePattern: regexp.MustCompile(`(?i)password|passwd|secret|token`),
+	},
+	{
+		Name:        "birthdate",
+		NamePattern: regexp.MustCompile(`(?i)birth|(^|_)dob$`),
+	},
+}
+
+// defaultThreshold is the fraction of an attribute's sampled values that must match a value rule to flag it.
+const defaultThreshold = 0.5
+
+// Analyzer inspects sampled items or Go types and reports likely personal data.
+type Analyzer struct {
+	rules     []Rule
+	threshold float64
+	action    encrypted.EncryptionAction
+}
+
+// Option defines a function signature for options that modify an Analyzer.
+type Option func(*Analyzer)
+
+// WithRules replaces the detection rules.
+func WithRules(rules ...Rule) Option {
+	return func(a *Analyzer) {
+		a.rules = rules
+	}
+}
+
+// WithThreshold sets the fraction, between 0 and 1, of an attribute's sampled values that must match a value
+// rule for the attribute to be flagged.
+func WithThreshold(threshold float64) Option {
+	return func(a *Analyzer) {
+		a.threshold = threshold
+	}
+}
+
+// WithSuggestedAction sets the action suggested for flagged attributes. The default is EncryptStandard.
+func WithSuggestedAction(action encrypted.EncryptionAction) Option {
+	return func(a *Analyzer) {
+		a.action = action
+	}
+}
+
+// NewAnalyzer creates an Analyzer using DefaultRules unless configured otherwise.
+func NewAnalyzer(opts ...Option) *Analyzer {
+	a := &Analyzer{
+		rules:     DefaultRules,
+		threshold: defaultThreshold,
+		action:    encrypted.EncryptStandard,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Finding reports an attribute flagged by a rule. Matches and Samples count the sampled values matching the rule's
+// value pattern; both are zero for findings based on the attribute name alone.
+type Finding struct {
+	Attribute string
+	Rule      string
+	Matches   int
+	Samples   int
+}
+
+// Report holds the findings of an analysis, ordered by attribute name.
+type Report struct {
+	Findings []Finding
+	action   encrypted.EncryptionAction
+}
+
+// AnalyzeItems flags the top-level attributes of the sampled items whose names or values look like personal data.
+// Values nested in maps, lists and sets are attributed to their top-level attribute, since that is the unit of
+// encryption.
+func (a *Analyzer) AnalyzeItems(items []map[string]types.AttributeValue) *Report {
+	samples := make(map[string][]string)
+	for _, item := range items {
+		for name, value := range item {
+			samples[name] = appendScalars(samples[name], value)
+		}
+	}
+
+	report := &Report{action: a.action}
+	for name, values := range samples {
+		if finding, ok := a.analyze(name, values); ok {
+			report.Findings = append(report.Findings, finding)
+		}
+	}
+	report.sort()
+	return report
+}
+
+// AnalyzeType flags the fields of a struct type whose attribute names look like personal data. Attribute names
+// follow the dynamodbav struct tags used by the attributevalue package. in may be a struct value or pointer.
+func (a *Analyzer) AnalyzeType(in interface{}) (*Report, error) {
+	t := reflect.TypeOf(in)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%T is not a struct", in)
+	}
+
+	report := &Report{action: a.action}
+	for _, name := range attributeNames(t) {
+		if finding, ok := a.analyze(name, nil); ok {
+			report.Findings = append(report.Findings, finding)
+		}
+	}
+	report.sort()
+	return report, nil
+}
+
+// SampleTable reads up to limit items from a plaintext table, for example before migrating it to encryption.
+func SampleTable(ctx context.Context, client dynamodb.ScanAPIClient, tableName string, limit int32) ([]map[string]types.AttributeValue, error) {
+	output, err := client.Scan(ctx, &dynamodb.ScanInput{
+		TableName: aws.String(tableName),
+		Limit:     aws.Int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error sampling table: %w", err)
+	}
+	return output.Items, nil
+}
+
+// Policy converts the report into an encryption policy document for review, applying the suggested action to every
+// flagged attribute and defaultAction ("none", "standard" or "deterministic") to the rest.
+func (r *Report) Policy(defaultAction string) *encrypted.Policy {
+	policy := &encrypted.Policy{
+		Version:       encrypted.PolicyVersion,
+		DefaultAction: defaultAction,
+		Attributes:    make(map[string]string, len(r.Findings)),
+	}
+	for _, finding := range r.Findings {
+		policy.Attributes[finding.Attribute] = actionName(r.action)
+	}
+	return policy
+}
+
+// analyze applies the rules to one attribute. Name rules take precedence over value rules.
+func (a *Analyzer) analyze(name string, values []string) (Finding, bool) {
+	for _, rule := range a.rules {
+		if rule.NamePattern != nil && rule.NamePattern.MatchString(name) {
+			return Finding{Attribute: name, Rule: rule.Name}, true
+		}
+	}
+
+	if len(values) == 0 {
+		return Finding{}, false
+	}
+	for _, rule := range a.rules {
+		if rule.ValuePattern == nil {
+			continue
+		}
+		matches := 0
+		for _, value := range values {
+			if rule.ValuePattern.MatchString(value) && (rule.Validate == nil || rule.Validate(value)) {
+				matches++
+			}
+		}
+		if matches > 0 && float64(matches)/float64(len(values)) >= a.threshold {
+			return Finding{Attribute: name, Rule: rule.Name, Matches: matches, Samples: len(values)}, true
+		}
+	}
+	return Finding{}, false
+}
+
+func (r *Report) sort() {
+	sort.Slice(r.Findings, func(i, j int) bool { return r.Findings[i].Attribute < r.Findings[j].Attribute })
+}
+
+// appendScalars appends the string and number values held by an attribute value, recursing into collections.
+func appendScalars(values []string, av types.AttributeValue) []string {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return append(values, v.Value)
+	case *types.AttributeValueMemberN:
+		return append(values, v.Value)
+	case *types.AttributeValueMemberSS:
+		return append(values, v.Value...)
+	case *types.AttributeValueMemberNS:
+		return append(values, v.Value...)
+	case *types.AttributeValueMemberL:
+		for _, element := range v.Value {
+			values = appendScalars(values, element)
+		}
+	case *types.AttributeValueMemberM:
+		for _, element := range v.Value {
+			values = appendScalars(values, element)
+		}
+	}
+	return values
+}
+
+// attributeNames returns the attribute names of a struct's exported fields, honoring dynamodbav tags and
+// flattening embedded structs like the attributevalue package does.
+func attributeNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("dynamodbav")
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				names = append(names, attributeNames(embedded)...)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// luhn reports whether the digits of value pass the Luhn checksum used by payment card numbers.
+func luhn(value string) bool {
+	sum, digits := 0, 0
+	for i := len(value) - 1; i >= 0; i-- {
+		c := value[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+		d := int(c - '0')
+		if digits%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits > 0 && sum%10 == 0
+}
+
+func actionName(action encrypted.EncryptionAction) string {
+	switch action {
+	case encrypted.EncryptDeterministic:
+		return "deterministic"
+	case encrypted.EncryptNone:
+		return "none"
+	default:
+		return "standard"
+	}
+}
@@ -0,0 +1,92 @@
+package pii
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAnalyzer_AnalyzeItems(t *testing.T) {
+	items := []map[string]types.AttributeValue{
+		{
+			"ID":      &types.AttributeValueMemberS{Value: "1"},
+			"Contact": &types.AttributeValueMemberS{Value: "alice@example.com"},
+			"Payment": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"Number": &types.AttributeValueMemberS{Value: "4111 1111 1111 1111"},
+			}},
+			"Ref":   &types.AttributeValueMemberS{Value: "123-45-6789"},
+			"Notes": &types.AttributeValueMemberS{Value: "called twice"},
+		},
+		{
+			"ID":       &types.AttributeValueMemberS{Value: "2"},
+			"Contact":  &types.AttributeValueMemberS{Value: "bob@example.com"},
+			"Ref":      &types.AttributeValueMemberS{Value: "ticket-7"},
+			"Password": &types.AttributeValueMemberS{Value: "hunter2"},
+			"Invoice":  &types.AttributeValueMemberS{Value: "4111 1111 1111 1112"},
+		},
+	}
+
+	report := NewAnalyzer().AnalyzeItems(items)
+
+	expected := []Finding{
+		{Attribute: "Contact", Rule: "email", Matches: 2, Samples: 2},
+		{Attribute: "Password", Rule: "secret"},
+		{Attribute: "Payment", Rule: "card", Matches: 1, Samples: 1},
+		{Attribute: "Ref", Rule: "ssn", Matches: 1, Samples: 2},
+	}
+	if diff := cmp.Diff(expected, report.Findings); diff != "" {
+		t.Errorf("unexpected findings (-want +got):\n%s", diff)
+	}
+
+	policy := report.Policy("none")
+	if err := policy.Validate(); err != nil {
+		t.Fatalf("suggested policy is invalid: %v", err)
+	}
+	if policy.Attributes["Contact"] != "standard" || len(policy.Attributes) != len(expected) {
+		t.Errorf("unexpected policy attributes %v", policy.Attributes)
+	}
+}
+
+func TestAnalyzer_AnalyzeType(t *testing.T) {
+	type Audit struct {
+		CreatedBy string
+	}
+	type User struct {
+		Audit
+		ID          string
+		Email       string `dynamodbav:"email_address"`
+		DateOfBirth string `dynamodbav:"dob"`
+		Mobile      string `dynamodbav:"-"`
+		ssn         string
+	}
+
+	report, err := NewAnalyzer(WithThreshold(1)).AnalyzeType(&User{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []Finding{
+		{Attribute: "dob", Rule: "birthdate"},
+		{Attribute: "email_address", Rule: "email"},
+	}
+	if diff := cmp.Diff(expected, report.Findings); diff != "" {
+		t.Errorf("unexpected findings (-want +got):\n%s", diff)
+	}
+
+	if _, err := NewAnalyzer().AnalyzeType("not a struct"); err == nil {
+		t.Errorf("expected an error for a non-struct type")
+	}
+}
+
+func TestLuhn(t *testing.T) {
+	for value, valid := range map[string]bool{
+		"4111 1111 1111 1111": true,
+		"4111-1111-1111-1112": false,
+		"79927398713":         true,
+	} {
+		if luhn(value) != valid {
+			t.Errorf("expected luhn(%q) to be %v", value, valid)
+		}
+	}
+}
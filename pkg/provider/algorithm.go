@@ -0,0 +1,38 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+)
+
+// ErrAlgorithmNotAllowed is returned when decryption materials use a content encryption algorithm outside the
+// provider's allow-list.
+var ErrAlgorithmNotAllowed = errors.New("content encryption algorithm not allowed")
+
+// DefaultAllowedAlgorithms holds the content encryption algorithms produced by this library.
+var DefaultAllowedAlgorithms = []string{"AesGcmKey"}
+
+// WithAllowedAlgorithms replaces the content encryption algorithms accepted on decrypt.
+func WithAllowedAlgorithms(algorithms ...string) ProviderOption {
+	return func(p *AwsKmsCryptographicMaterialsProvider) {
+		p.allowedAlgorithms = make(map[string]bool, len(algorithms))
+		for _, algorithm := range algorithms {
+			p.allowedAlgorithms[algorithm] = true
+		}
+	}
+}
+
+// validateAlgorithm checks both the algorithm recorded in the material description and the one of the unwrapped
+// key against the allow-list, so a tampered description can not downgrade decryption.
+func validateAlgorithm(allowed map[string]bool, m materials.CryptographicMaterials) error {
+	described := m.MaterialDescription()["ContentEncryptionAlgorithm"]
+	if !allowed[described] {
+		return fmt.Errorf("%w: %q", ErrAlgorithmNotAllowed, described)
+	}
+	if key := m.DecryptionKey(); key != nil && key.Algorithm() != described {
+		return fmt.Errorf("%w: described as %q but key uses %q", ErrAlgorithmNotAllowed, described, key.Algorithm())
+	}
+	return nil
+}
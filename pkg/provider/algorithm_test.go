@@ -0,0 +1,62 @@
+package provider
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tink-crypto/tink-go/v2/aead"
+	"github.com/tink-crypto/tink-go/v2/keyset"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+)
+
+func TestValidateAlgorithm(t *testing.T) {
+	p := &AwsKmsCryptographicMaterialsProvider{}
+	WithAllowedAlgorithms(DefaultAllowedAlgorithms...)(p)
+
+	testCases := []struct {
+		name      string
+		algorithm string
+		err       error
+	}{
+		{name: "Allowed", algorithm: "AesGcmKey"},
+		{name: "Downgrade", algorithm: "AesCtrHmacAeadKey", err: ErrAlgorithmNotAllowed},
+		{name: "Missing", err: ErrAlgorithmNotAllowed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			description := map[string]string{}
+			if tc.algorithm != "" {
+				description["ContentEncryptionAlgorithm"] = tc.algorithm
+			}
+			m := materials.NewDecryptionMaterials(description, nil)
+			if err := validateAlgorithm(p.allowedAlgorithms, m); !errors.Is(err, tc.err) {
+				t.Errorf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateAlgorithm_KeyMismatch(t *testing.T) {
+	kh, err := keyset.NewHandle(aead.AES256GCMKeyTemplate())
+	if err != nil {
+		t.Fatalf("failed to create keyset: %v", err)
+	}
+	key := delegatedkeys.NewTinkDelegatedKey(kh, nil)
+
+	p := &AwsKmsCryptographicMaterialsProvider{}
+	WithAllowedAlgorithms(DefaultAllowedAlgorithms...)(p)
+
+	m := materials.NewDecryptionMaterials(map[string]string{"ContentEncryptionAlgorithm": key.Algorithm()}, key)
+	if err := validateAlgorithm(p.allowedAlgorithms, m); err != nil {
+		t.Errorf("expected the generated data key to be allowed, got %v", err)
+	}
+
+	WithAllowedAlgorithms("AesGcmKey", "AesCtrHmacAeadKey")(p)
+	m = materials.NewDecryptionMaterials(map[string]string{"ContentEncryptionAlgorithm": "AesCtrHmacAeadKey"}, key)
+	if err := validateAlgorithm(p.allowedAlgorithms, m); !errors.Is(err, ErrAlgorithmNotAllowed) {
+		t.Errorf("expected %v, got %v", ErrAlgorithmNotAllowed, err)
+	}
+}
@@ -3,120 +3,626 @@ package provider
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
 
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
 	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
 	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
 	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider/store"
 )
 
+// MaterialVersionKey is the material description entry recording the version encryption materials were stored
+// under. It is only set on materials returned by EncryptionMaterials and is not persisted.
+const MaterialVersionKey = "MaterialVersion"
+
+// KMSKeyURIKey is the material description entry recording the KMS key that wrapped the materials' keysets.
+const KMSKeyURIKey = "KMSKeyURI"
+
+// DeterministicKeysetKey is the material description entry holding the data key's AES-SIV keyset, wrapped with the
+// data keyset itself. Materials stored without it can not encrypt deterministically.
+const DeterministicKeysetKey = "WrappedDeterministicKeyset"
+
+// ErrReadOnlyProvider is returned by EncryptionMaterials when the provider is in read-only mode.
+var ErrReadOnlyProvider = errors.New("provider is read-only: encryption materials are disabled")
+
+// ErrProviderClosed is returned by a provider's methods after it has been closed.
+var ErrProviderClosed = errors.New("provider is closed")
+
 // AwsKmsCryptographicMaterialsProvider uses AWS KMS for key management and Tink for cryptographic operations.
 type AwsKmsCryptographicMaterialsProvider struct {
 	KMSKeyURI         string
 	EncryptionContext map[string]string
 	DelegatedKey      *delegatedkeys.TinkDelegatedKey
 	MaterialStore     *store.MetaStore
+	ReadOnly          bool
+
+	cache              MaterialsCache
+	keysets            *keysetCache
+	counters           providerCounters
+	allowedAlgorithms  map[string]bool
+	reuse              *reusedMaterials
+	pending            *pendingMaterials
+	usageWarning       usageWarning
+	kmsClient          kmsiface.KMSAPI
+	kmsClientFactory   KMSClientFactory
+	kms                kmsClients
+	discovery          *DiscoveryFilter
+	verificationKeys   bool
+	legacyDescriptions bool
+	keyURIResolver     KeyURIResolver
+	tableKeys          map[string]string
+	noPlaintextCache   bool
+	maxStale           time.Duration
+	revalidation       *revalidation
+	closed             atomic.Bool
+}
+
+// ErrLegacyDescription is returned when reading materials whose description predates signed descriptions bound to
+// their keysets, unless the provider was created with WithLegacyDescriptions.
+var ErrLegacyDescription = errors.New("legacy material description")
+
+// ProviderOption defines a function signature for options that modify an AwsKmsCryptographicMaterialsProvider.
+type ProviderOption func(*AwsKmsCryptographicMaterialsProvider)
+
+// WithReadOnly puts the provider in decrypt-only mode. EncryptionMaterials returns ErrReadOnlyProvider
+// and nothing is written to the material store.
+func WithReadOnly() ProviderOption {
+	return func(p *AwsKmsCryptographicMaterialsProvider) {
+		p.ReadOnly = true
+	}
+}
+
+// WithLegacyDescriptions accepts materials whose description has no DescriptionFormat entry, stored before
+// descriptions were signed and keysets bound to their encryption context and public key. Such descriptions are read
+// unsigned, or verified with their own public key, so their entries are not protected against changes in the
+// material store. Enable it only while legacy materials are still in use, and rotate them.
+func WithLegacyDescriptions() ProviderOption {
+	return func(p *AwsKmsCryptographicMaterialsProvider) {
+		p.legacyDescriptions = true
+	}
+}
+
+// AcceptsLegacyDescriptions reports whether the provider was created with WithLegacyDescriptions.
+func (p *AwsKmsCryptographicMaterialsProvider) AcceptsLegacyDescriptions() bool {
+	return p.legacyDescriptions
+}
+
+// WithCache enables caching of unwrapped decryption materials for the given TTL, in an unbounded in-memory cache.
+// Materials created by this provider invalidate the cached entries for their material name.
+func WithCache(ttl time.Duration) ProviderOption {
+	return WithMaterialsCache(NewMemoryCache(ttl, 0))
+}
+
+// WithMaterialsCache caches unwrapped decryption materials in cache, for example a bounded NewMemoryCache or an
+// adapter over another in-process cache library. Materials created by this provider invalidate the cached entries
+// for their material name.
+func WithMaterialsCache(cache MaterialsCache) ProviderOption {
+	return func(p *AwsKmsCryptographicMaterialsProvider) {
+		p.cache = cache
+	}
+}
+
+// WithoutPlaintextCaching keeps unwrapped keys in memory only for the request that needs them, for high-assurance
+// deployments that want to limit key exposure in core dumps and swapped memory. It overrides WithCache,
+// WithMaterialsCache and WithMaterialReuse, whatever the order of the options, so every request unwraps its keys
+// with KMS. New materials whose write failed are not kept for the next attempt either. Keyset caches set with
+// WithKeysetCache only hold wrapped keysets and stay enabled.
+func WithoutPlaintextCaching() ProviderOption {
+	return func(p *AwsKmsCryptographicMaterialsProvider) {
+		p.noPlaintextCache = true
+	}
+}
+
+// WithMetricsHook registers a hook that receives every counter update made by the provider.
+func WithMetricsHook(hook MetricsHook) ProviderOption {
+	return func(p *AwsKmsCryptographicMaterialsProvider) {
+		p.counters.hook = hook
+	}
 }
 
 // NewAwsKmsCryptographicMaterialsProvider initializes a provider with the specified AWS KMS key ID, encryption context, and material store.
-func NewAwsKmsCryptographicMaterialsProvider(keyURI string, encryptionContext map[string]string, materialStore *store.MetaStore) (CryptographicMaterialsProvider, error) {
-	return &AwsKmsCryptographicMaterialsProvider{
+// The configuration is not validated until materials are requested; use New to validate it up front. A templated key
+// URI is resolved and validated here, see WithKeyURIResolver.
+func NewAwsKmsCryptographicMaterialsProvider(keyURI string, encryptionContext map[string]string, materialStore *store.MetaStore, opts ...ProviderOption) (CryptographicMaterialsProvider, error) {
+	p := &AwsKmsCryptographicMaterialsProvider{
 		KMSKeyURI:         keyURI,
 		EncryptionContext: encryptionContext,
 		MaterialStore:     materialStore,
-	}, nil
+		pending:           newPendingMaterials(),
+	}
+	WithAllowedAlgorithms(DefaultAllowedAlgorithms...)(p)
+
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.noPlaintextCache {
+		p.cache, p.reuse, p.pending = nil, nil, nil
+	}
+	if cache, ok := p.cache.(StaleMaterialsCache); ok && p.maxStale > 0 {
+		p.revalidation = newRevalidation(cache, p.maxStale)
+	}
+
+	resolver := p.keyURIResolver
+	if resolver == nil {
+		resolver = EnvResolver("DDBENC_")
+	}
+	if IsKeyURITemplate(keyURI) {
+		resolved, err := ResolveKeyURI(keyURI, resolver)
+		if err != nil {
+			return nil, err
+		}
+		p.KMSKeyURI = resolved
+	}
+	if err := p.resolveTableKeys(resolver); err != nil {
+		return nil, err
+	}
+	return p, nil
 }
 
-// EncryptionMaterials retrieves and stores encryption materials for the given encryption context.
+// EncryptionMaterials retrieves and stores encryption materials for the given encryption context. New materials are
+// wrapped with the KMS key of the request's table, see WithTableKeys.
+// With WithMaterialReuse, the latest materials are returned again until they reach their usage limits or maximum age.
+// The returned materials record their version under MaterialVersionKey, so items can pin it for decryption.
+// When storing new materials fails, they are kept for a while and stored again by the next call for the material
+// name, so a write that did reach the store is not stored twice; see store.MaterialIDKey.
 func (p *AwsKmsCryptographicMaterialsProvider) EncryptionMaterials(ctx context.Context, materialName string) (materials.CryptographicMaterials, error) {
-	// Get the KEK (Key Encryption Key) from KMS
-	kek, err := delegatedkeys.GetKEK(p.KMSKeyURI, false)
+	if p.closed.Load() {
+		return nil, ErrProviderClosed
+	}
+	if p.ReadOnly {
+		return nil, ErrReadOnlyProvider
+	}
+
+	var reuseKey string
+	if p.reuse != nil || p.pending != nil {
+		var err error
+		if reuseKey, err = p.cacheKey(ctx, materialName); err != nil {
+			return nil, err
+		}
+	}
+	if p.reuse != nil {
+		if reused, ok := p.reuse.get(reuseKey); ok {
+			p.counters.add(MetricMaterialsReused, 1)
+			return reused, nil
+		}
+	}
+
+	encryptionMaterials, ok := p.pending.get(reuseKey)
+	if !ok {
+		var err error
+		if encryptionMaterials, err = p.newEncryptionMaterials(p.encryptionKeyURI(ctx)); err != nil {
+			return nil, err
+		}
+	}
+
+	// Store the new material in the material store
+	version, err := p.MaterialStore.StoreNewMaterialVersion(ctx, materialName, encryptionMaterials)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get KEK: %v", err)
+		p.pending.put(reuseKey, encryptionMaterials)
+		return nil, fmt.Errorf("failed to store encryption material: %v", err)
+	}
+	p.pending.remove(reuseKey)
+	if err := p.publishVerificationKey(ctx, materialName, version, encryptionMaterials); err != nil {
+		return nil, err
 	}
+	return p.storedEncryptionMaterials(ctx, materialName, reuseKey, encryptionMaterials, version), nil
+}
 
-	// Generate a new Tink keyset and wrap it
-	delegatedKey, wrappedKeyset, err := delegatedkeys.GenerateDataKey(kek)
+// newEncryptionMaterials generates a data key with its deterministic keyset and a signing key, wraps them with the KEK of the KMS key, and returns
+// materials describing them. The materials still have to be stored.
+func (p *AwsKmsCryptographicMaterialsProvider) newEncryptionMaterials(keyURI string) (materials.CryptographicMaterials, error) {
+	// Get the KEK (Key Encryption Key) from KMS
+	kek, err := p.kekFor(keyURI)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate and wrap data key: %v", err)
+		return nil, fmt.Errorf("failed to get KEK: %v", err)
 	}
 
 	// Generate a signing key and wrap it
+	p.counters.add(MetricKMSCalls, 1)
 	delegatedSigningKey, _, publicKeyBytes, err := delegatedkeys.GenerateSigningKey(kek)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate and wrap signing key: %v", err)
 	}
 
-	// Sign the wrappedKeyset
-	signature, err := delegatedSigningKey.Sign(wrappedKeyset)
+	// Prepare the material description with encryption context and public key
+	materialID, err := store.NewMaterialID()
 	if err != nil {
-		return nil, fmt.Errorf("failed to sign wrappedKeyset: %v", err)
+		return nil, err
+	}
+	contextKeys := encryptionContextKeys(p.EncryptionContext)
+	encodedContextKeys, err := json.Marshal(contextKeys)
+	if err != nil {
+		return nil, err
 	}
-
-	// Prepare the material description with encryption context and wrapped keyset
 	materialDescription := make(map[string]string)
 	for key, value := range p.EncryptionContext {
 		materialDescription[key] = value
 	}
+	materialDescription[EncryptionContextKeysKey] = string(encodedContextKeys)
+	materialDescription[store.MaterialIDKey] = materialID
+	materialDescription[KMSKeyURIKey] = keyURI
+	materialDescription[publicKeyKey] = base64.StdEncoding.EncodeToString(publicKeyBytes)
+
+	// Generate a new Tink keyset and wrap it bound to the encryption context and public key, so it only unwraps with
+	// the ones stored beside it
+	p.counters.add(MetricKMSCalls, 1)
+	delegatedKey, wrappedKeyset, err := delegatedkeys.GenerateDataKeyWithAssociatedData(kek, canonicalEncryptionContext(contextKeys, materialDescription))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate and wrap data key: %v", err)
+	}
+	wrappedDeterministicKeyset, err := delegatedKey.WrapDeterministicKeyset()
+	if err != nil {
+		return nil, err
+	}
+
+	// Sign the wrappedKeyset
+	signature, err := delegatedSigningKey.Sign(wrappedKeyset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign wrappedKeyset: %v", err)
+	}
+
 	materialDescription["ContentEncryptionAlgorithm"] = delegatedKey.Algorithm()
 	materialDescription["WrappedKeyset"] = base64.StdEncoding.EncodeToString(wrappedKeyset)
+	materialDescription[DeterministicKeysetKey] = base64.StdEncoding.EncodeToString(wrappedDeterministicKeyset)
 	materialDescription["Signature"] = base64.StdEncoding.EncodeToString(signature)
-	materialDescription["PublicKey"] = base64.StdEncoding.EncodeToString(publicKeyBytes)
+	if err := materials.SignDescription(materialDescription, delegatedSigningKey); err != nil {
+		return nil, err
+	}
 
 	// Create encryption materials with the material description and the encryption key
-	encryptionMaterials := materials.NewEncryptionMaterials(materialDescription, delegatedKey, nil)
+	return materials.NewEncryptionMaterials(materialDescription, delegatedKey, delegatedSigningKey), nil
+}
 
-	// Store the new material in the material store
-	if err := p.MaterialStore.StoreNewMaterial(ctx, materialName, encryptionMaterials); err != nil {
-		return nil, fmt.Errorf("failed to store encryption material: %v", err)
+// storedEncryptionMaterials records the version newly stored materials were stored under, invalidates cached
+// decryption materials for the material name, and starts tracking the materials for reuse.
+func (p *AwsKmsCryptographicMaterialsProvider) storedEncryptionMaterials(ctx context.Context, materialName, reuseKey string, encryptionMaterials materials.CryptographicMaterials, version int64) materials.CryptographicMaterials {
+	encryptionMaterials.MaterialDescription()[MaterialVersionKey] = strconv.FormatInt(version, 10)
+	p.counters.add(MetricMaterialsCreated, 1)
+
+	// A new version now exists, so any cached "latest" materials are stale
+	if p.cache != nil {
+		if cacheKey, err := p.cacheKey(ctx, materialName); err == nil {
+			p.cache.Invalidate(cacheKey)
+		}
 	}
 
-	return encryptionMaterials, nil
+	if p.reuse != nil {
+		return p.reuse.put(reuseKey, encryptionMaterials)
+	}
+	return encryptionMaterials
+}
+
+// BatchEncryptionMaterials returns encryption materials for each material name, in order, like EncryptionMaterials.
+// New materials are stored together with MetaStore.StoreNewMaterials instead of one transaction each. Repeated
+// material names share the materials of their first occurrence.
+func (p *AwsKmsCryptographicMaterialsProvider) BatchEncryptionMaterials(ctx context.Context, materialNames []string) ([]materials.CryptographicMaterials, error) {
+	if p.closed.Load() {
+		return nil, ErrProviderClosed
+	}
+	if p.ReadOnly {
+		return nil, ErrReadOnlyProvider
+	}
+
+	positions := make(map[string]int, len(materialNames))
+	var distinct []string
+	for _, materialName := range materialNames {
+		if _, ok := positions[materialName]; !ok {
+			positions[materialName] = len(distinct)
+			distinct = append(distinct, materialName)
+		}
+	}
+	distinctMaterials, err := p.batchEncryptionMaterials(ctx, distinct)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]materials.CryptographicMaterials, len(materialNames))
+	for i, materialName := range materialNames {
+		results[i] = distinctMaterials[positions[materialName]]
+	}
+	return results, nil
 }
 
+// batchEncryptionMaterials returns encryption materials for distinct material names.
+func (p *AwsKmsCryptographicMaterialsProvider) batchEncryptionMaterials(ctx context.Context, materialNames []string) ([]materials.CryptographicMaterials, error) {
+	results := make([]materials.CryptographicMaterials, len(materialNames))
+	reuseKeys := make([]string, len(materialNames))
+	var created []int
+	var writes []store.MaterialWrite
+	for i, materialName := range materialNames {
+		if p.reuse != nil || p.pending != nil {
+			var err error
+			if reuseKeys[i], err = p.cacheKey(ctx, materialName); err != nil {
+				return nil, err
+			}
+		}
+		if p.reuse != nil {
+			if reused, ok := p.reuse.get(reuseKeys[i]); ok {
+				p.counters.add(MetricMaterialsReused, 1)
+				results[i] = reused
+				continue
+			}
+		}
+
+		encryptionMaterials, ok := p.pending.get(reuseKeys[i])
+		if !ok {
+			var err error
+			if encryptionMaterials, err = p.newEncryptionMaterials(p.encryptionKeyURI(ctx)); err != nil {
+				return nil, err
+			}
+		}
+		created = append(created, i)
+		writes = append(writes, store.MaterialWrite{MaterialName: materialName, Material: encryptionMaterials})
+	}
+	if len(writes) == 0 {
+		return results, nil
+	}
+
+	versions, err := p.MaterialStore.StoreNewMaterials(ctx, writes)
+	if err != nil {
+		for j, i := range created {
+			p.pending.put(reuseKeys[i], writes[j].Material)
+		}
+		return nil, fmt.Errorf("failed to store encryption materials: %v", err)
+	}
+	for j, i := range created {
+		p.pending.remove(reuseKeys[i])
+		if err := p.publishVerificationKey(ctx, materialNames[i], versions[j], writes[j].Material); err != nil {
+			return nil, err
+		}
+		results[i] = p.storedEncryptionMaterials(ctx, materialNames[i], reuseKeys[i], writes[j].Material, versions[j])
+	}
+	return results, nil
+}
+
+// DecryptionMaterials retrieves, verifies and unwraps the decryption materials for the given material name and version.
+// A version less than 1 resolves to the latest version. The materials' encryption context must match the provider's
+// encryption context and any entries required through ContextWithRequiredEncryptionContext, and their content encryption
+// algorithm must be allowed by WithAllowedAlgorithms. The data keyset is wrapped with the encryption context as KMS
+// encryption context, so materials whose stored context was altered fail to unwrap.
 func (p *AwsKmsCryptographicMaterialsProvider) DecryptionMaterials(ctx context.Context, materialName string, version int64) (materials.CryptographicMaterials, error) {
-	materialDescMap, wrappedKeysetBase64, err := p.MaterialStore.RetrieveMaterial(ctx, materialName, version)
+	if p.closed.Load() {
+		return nil, ErrProviderClosed
+	}
+	decryptionMaterials, err := p.decryptionMaterials(ctx, materialName, version)
 	if err != nil {
 		return nil, err
 	}
 
-	encryptedKeyset, err := base64.StdEncoding.DecodeString(wrappedKeysetBase64)
+	if err := validateEncryptionContext(ctx, p.EncryptionContext, decryptionMaterials); err != nil {
+		return nil, err
+	}
+	if err := validateAlgorithm(p.allowedAlgorithms, decryptionMaterials); err != nil {
+		return nil, err
+	}
+
+	return decryptionMaterials, nil
+}
+
+func (p *AwsKmsCryptographicMaterialsProvider) decryptionMaterials(ctx context.Context, materialName string, version int64) (materials.CryptographicMaterials, error) {
+	p.counters.add(MetricDecryptionMaterialsFetched, 1)
+
+	if p.cache == nil {
+		return p.fetchDecryptionMaterials(ctx, materialName, version)
+	}
+
+	cacheKey, err := p.cacheKey(ctx, materialName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode encrypted keyset: %v", err)
+		return nil, err
 	}
+	if version < 1 {
+		version = 0
+	}
+	var cached materials.CryptographicMaterials
+	var ok bool
+	if p.revalidation != nil {
+		cached, ok = p.revalidation.get(ctx, &p.counters, cacheKey, version, func(ctx context.Context) (materials.CryptographicMaterials, error) {
+			return p.fetchDecryptionMaterials(ctx, materialName, version)
+		})
+	} else {
+		cached, ok = p.cache.Get(cacheKey, version)
+	}
+	if ok {
+		p.counters.add(MetricCacheHits, 1)
+		return cached, nil
+	}
+	p.counters.add(MetricCacheMisses, 1)
 
-	publicKeyBase64 := materialDescMap["PublicKey"]
-	publicKeyBytes, err := base64.StdEncoding.DecodeString(publicKeyBase64)
+	decryptionMaterials, err := p.fetchDecryptionMaterials(ctx, materialName, version)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode public key: %v", err)
+		return nil, err
 	}
+	p.cache.Put(cacheKey, version, decryptionMaterials)
+
+	return decryptionMaterials, nil
+}
 
-	signatureBase64 := materialDescMap["Signature"]
-	signatureBytes, err := base64.StdEncoding.DecodeString(signatureBase64)
+// cacheKey scopes a material name to the meta table it is stored in, so routed tables never share cache entries.
+func (p *AwsKmsCryptographicMaterialsProvider) cacheKey(ctx context.Context, materialName string) (string, error) {
+	tableName, err := p.MaterialStore.ResolveTableName(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode signature: %v", err)
+		return "", err
 	}
+	return tableName + "/" + materialName, nil
+}
 
-	valid, err := delegatedkeys.VerifySignature(publicKeyBytes, signatureBytes, encryptedKeyset)
-	if err != nil || !valid {
-		return nil, fmt.Errorf("failed to verify the wrapped keyset's signature: %v", err)
+// Stats returns a snapshot of the provider's usage counters.
+func (p *AwsKmsCryptographicMaterialsProvider) Stats() ProviderStats {
+	stats := p.counters.snapshot()
+	if p.cache != nil {
+		stats.CachedMaterials = int64(p.cache.Len())
+	}
+	if p.MaterialStore != nil {
+		stats.MetaStoreThrottles = p.MaterialStore.Throttles()
+	}
+	return stats
+}
+
+func (p *AwsKmsCryptographicMaterialsProvider) fetchDecryptionMaterials(ctx context.Context, materialName string, version int64) (materials.CryptographicMaterials, error) {
+	materialDescMap, wrappedKeysetBase64, err := p.MaterialStore.RetrieveMaterial(ctx, materialName, version)
+	if err != nil {
+		return nil, err
+	}
+	if materials.IsLegacyDescription(materialDescMap) && !p.legacyDescriptions {
+		return nil, fmt.Errorf("%w for %q", ErrLegacyDescription, materialName)
+	}
+
+	encryptedKeyset, err := base64.StdEncoding.DecodeString(wrappedKeysetBase64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode encrypted keyset: %v", err)
+	}
+	_, associatedData, err := boundEncryptionContext(materialDescMap)
+	if err != nil {
+		return nil, err
+	}
+
+	var keysetKey string
+	if p.keysets != nil {
+		cacheKey, err := p.cacheKey(ctx, materialName)
+		if err != nil {
+			return nil, err
+		}
+		keysetKey = keysetCacheKey(cacheKey, wrappedKeysetBase64, associatedData)
+		if delegatedKey, ok := p.keysets.get(ctx, &p.counters, keysetKey); ok {
+			if err := p.verifyMaterials(materialDescMap, encryptedKeyset); err != nil {
+				return nil, err
+			}
+			return decryptionMaterialsFor(materialDescMap, delegatedKey)
+		}
 	}
 
 	// Get the KEK (Key Encryption Key) from KMS
-	kek, err := delegatedkeys.GetKEK(p.KMSKeyURI, false)
+	keyURI, err := p.decryptionKeyURI(ctx, materialDescMap)
+	if err != nil {
+		return nil, err
+	}
+	kek, err := p.kekFor(keyURI)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get KEK: %v", err)
 	}
 
-	delegatedKey, err := delegatedkeys.UnwrapKeyset(encryptedKeyset, kek)
+	p.counters.add(MetricKMSCalls, 1)
+	delegatedKey, err := delegatedkeys.UnwrapKeysetWithAssociatedData(encryptedKeyset, kek, associatedData)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decrypt and unwrap data key: %v", err)
 	}
+	if err := p.verifyMaterials(materialDescMap, encryptedKeyset); err != nil {
+		return nil, err
+	}
+	if p.keysets != nil {
+		p.keysets.put(ctx, &p.counters, keysetKey, delegatedKey)
+	}
 
 	// Construct DecryptionMaterials with the actual delegatedKey
+	return decryptionMaterialsFor(materialDescMap, delegatedKey)
+}
+
+// verifyMaterials checks the signatures over the wrapped keyset and over the material description with the public
+// key of the description. It is called once the keyset unwrapped, or was found in the keyset cache under its bound
+// encryption context: the public key is part of the KMS encryption context of the keyset, so KMS has authenticated it.
+// Unsigned descriptions are only accepted when they are legacy and allowed by WithLegacyDescriptions, whose public
+// key is not authenticated.
+func (p *AwsKmsCryptographicMaterialsProvider) verifyMaterials(materialDescMap map[string]string, encryptedKeyset []byte) error {
+	publicKeyBytes, err := base64.StdEncoding.DecodeString(materialDescMap[publicKeyKey])
+	if err != nil {
+		return fmt.Errorf("failed to decode public key: %v", err)
+	}
+	signatureBytes, err := base64.StdEncoding.DecodeString(materialDescMap["Signature"])
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %v", err)
+	}
+
+	p.counters.add(MetricSignatureVerifications, 1)
+	valid, err := delegatedkeys.VerifySignature(publicKeyBytes, signatureBytes, encryptedKeyset)
+	if err != nil || !valid {
+		return fmt.Errorf("failed to verify the wrapped keyset's signature: %v", err)
+	}
+	if _, signed := materialDescMap[materials.DescriptionSignatureKey]; !signed && materials.IsLegacyDescription(materialDescMap) {
+		return nil
+	}
+	return materials.VerifyDescription(materialDescMap, publicKeyBytes)
+}
+
+// decryptionMaterialsFor returns decryption materials for an unwrapped data key, after unwrapping its deterministic
+// keyset when the description holds one.
+func decryptionMaterialsFor(materialDescMap map[string]string, delegatedKey *delegatedkeys.TinkDelegatedKey) (materials.CryptographicMaterials, error) {
+	if encoded, ok := materialDescMap[DeterministicKeysetKey]; ok {
+		wrapped, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode deterministic keyset: %v", err)
+		}
+		if err := delegatedKey.UnwrapDeterministicKeyset(wrapped); err != nil {
+			return nil, err
+		}
+	}
 	return materials.NewDecryptionMaterials(materialDescMap, delegatedKey), nil
 }
 
+// DescribeMaterials returns the stored material description for the given material name and version, without
+// verifying or unwrapping its keysets. A version less than 1 resolves to the latest version.
+func (p *AwsKmsCryptographicMaterialsProvider) DescribeMaterials(ctx context.Context, materialName string, version int64) (map[string]string, error) {
+	materialDescMap, _, err := p.MaterialStore.RetrieveMaterial(ctx, materialName, version)
+	if err != nil {
+		return nil, err
+	}
+	return materialDescMap, nil
+}
+
+// DeleteMaterials removes every stored version of the named material. Reused, pending and cached materials of the
+// name are dropped first, so later writes never use materials whose stored version is gone.
+func (p *AwsKmsCryptographicMaterialsProvider) DeleteMaterials(ctx context.Context, materialName string) error {
+	if p.closed.Load() {
+		return ErrProviderClosed
+	}
+	if p.ReadOnly {
+		return ErrReadOnlyProvider
+	}
+
+	cacheKey, err := p.cacheKey(ctx, materialName)
+	if err != nil {
+		return err
+	}
+	if p.reuse != nil {
+		p.reuse.remove(cacheKey)
+	}
+	p.pending.remove(cacheKey)
+	if p.cache != nil {
+		p.cache.Invalidate(cacheKey)
+	}
+	return p.MaterialStore.DeleteAllVersions(ctx, materialName)
+}
+
+// ReusesMaterials reports whether the provider was created with WithMaterialReuse.
+func (p *AwsKmsCryptographicMaterialsProvider) ReusesMaterials() bool {
+	return p.reuse != nil
+}
+
+// Close releases the provider's cached materials, reused data keys and KMS clients. Afterwards the provider's methods
+// return ErrProviderClosed. Close is safe to call more than once.
+func (p *AwsKmsCryptographicMaterialsProvider) Close() error {
+	if p.closed.Swap(true) {
+		return nil
+	}
+
+	if p.revalidation != nil {
+		p.revalidation.close()
+	}
+	if p.cache != nil {
+		p.cache.Clear()
+	}
+	if p.reuse != nil {
+		p.reuse.clear()
+	}
+	p.pending.clear()
+	p.kms.clear()
+	return nil
+}
+
 func (p *AwsKmsCryptographicMaterialsProvider) TableName() string {
 	return p.MaterialStore.TableName
 }
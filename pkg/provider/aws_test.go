@@ -0,0 +1,49 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+const (
+	keyURI = "arn:aws:kms:eu-west-2:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b"
+)
+
+func TestAwsKmsCryptographicMaterialsProvider_ReadOnly(t *testing.T) {
+	cmp, err := NewAwsKmsCryptographicMaterialsProvider(keyURI, nil, nil, WithReadOnly())
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+
+	_, err = cmp.EncryptionMaterials(context.Background(), "material")
+	if !errors.Is(err, ErrReadOnlyProvider) {
+		t.Errorf("expected ErrReadOnlyProvider, got %v", err)
+	}
+}
+
+func TestAwsKmsCryptographicMaterialsProvider_Close(t *testing.T) {
+	cmp, err := NewAwsKmsCryptographicMaterialsProvider(keyURI, nil, nil, WithCache(time.Minute))
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+	p := cmp.(*AwsKmsCryptographicMaterialsProvider)
+	p.cache.Put("meta/material", 0, nil)
+
+	for i := 0; i < 2; i++ {
+		if err := p.Close(); err != nil {
+			t.Fatalf("unexpected error closing provider: %v", err)
+		}
+	}
+	if n := p.cache.Len(); n != 0 {
+		t.Errorf("expected the cache to be released, got %d entries", n)
+	}
+
+	if _, err := p.EncryptionMaterials(context.Background(), "material"); !errors.Is(err, ErrProviderClosed) {
+		t.Errorf("expected ErrProviderClosed, got %v", err)
+	}
+	if _, err := p.DecryptionMaterials(context.Background(), "material", 1); !errors.Is(err, ErrProviderClosed) {
+		t.Errorf("expected ErrProviderClosed, got %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package provider
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+)
+
+// MaterialsCache caches unwrapped decryption materials by material name and version. Version 0 holds the most
+// recently resolved "latest" version for a material name. Cached materials hold plaintext keys, so implementations
+// must keep them in process memory; fleets share unwrapped keysets through a KeysetCache instead. Implementations
+// must be safe for concurrent use.
+type MaterialsCache interface {
+	Get(materialName string, version int64) (materials.CryptographicMaterials, bool)
+	Put(materialName string, version int64, m materials.CryptographicMaterials)
+	// Invalidate drops every cached version for the material name.
+	Invalidate(materialName string)
+	// Clear drops every cached entry.
+	Clear()
+	// Len returns the number of cached entries, including expired ones not yet dropped.
+	Len() int
+}
+
+// materialsCache is the in-memory MaterialsCache.
+type materialsCache struct {
+	ttl        time.Duration
+	maxEntries int
+	mu         sync.Mutex
+	entries    map[string]map[int64]cacheEntry
+	size       int
+}
+
+type cacheEntry struct {
+	materials materials.CryptographicMaterials
+	expiresAt time.Time
+}
+
+// NewMemoryCache returns an in-memory MaterialsCache whose entries expire after ttl. A positive maxEntries bounds
+// the cache: when it is full, expired entries are dropped first, then the entry closest to expiring. The cache
+// implements StaleMaterialsCache.
+func NewMemoryCache(ttl time.Duration, maxEntries int) MaterialsCache {
+	return newMaterialsCache(ttl, maxEntries)
+}
+
+func newMaterialsCache(ttl time.Duration, maxEntries int) *materialsCache {
+	return &materialsCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]map[int64]cacheEntry),
+	}
+}
+
+func (c *materialsCache) Get(materialName string, version int64) (materials.CryptographicMaterials, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[materialName][version]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		c.remove(materialName, version)
+		return nil, false
+	}
+	return entry.materials, true
+}
+
+func (c *materialsCache) GetStale(materialName string, version int64, maxStale time.Duration) (materials.CryptographicMaterials, bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[materialName][version]
+	if !ok {
+		return nil, false, false
+	}
+	now := time.Now()
+	if now.After(entry.expiresAt.Add(maxStale)) {
+		c.remove(materialName, version)
+		return nil, false, false
+	}
+	return entry.materials, now.After(entry.expiresAt), true
+}
+
+func (c *materialsCache) Put(materialName string, version int64, m materials.CryptographicMaterials) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	versions, ok := c.entries[materialName]
+	if !ok {
+		versions = make(map[int64]cacheEntry)
+		c.entries[materialName] = versions
+	}
+	if _, exists := versions[version]; !exists {
+		if c.maxEntries > 0 && c.size >= c.maxEntries {
+			c.evict()
+		}
+		c.size++
+	}
+	versions[version] = cacheEntry{
+		materials: m,
+		expiresAt: time.Now().Add(c.ttl),
+	}
+}
+
+// evict makes room for one entry, dropping expired entries or, when there are none, the entry closest to expiring.
+func (c *materialsCache) evict() {
+	now := time.Now()
+	var oldestName string
+	var oldestVersion int64
+	var oldest time.Time
+	for materialName, versions := range c.entries {
+		for version, entry := range versions {
+			if now.After(entry.expiresAt) {
+				c.remove(materialName, version)
+				continue
+			}
+			if oldest.IsZero() || entry.expiresAt.Before(oldest) {
+				oldestName, oldestVersion, oldest = materialName, version, entry.expiresAt
+			}
+		}
+	}
+	if c.size >= c.maxEntries && !oldest.IsZero() {
+		c.remove(oldestName, oldestVersion)
+	}
+}
+
+// remove drops a single entry. The caller must hold the lock.
+func (c *materialsCache) remove(materialName string, version int64) {
+	versions := c.entries[materialName]
+	if _, ok := versions[version]; !ok {
+		return
+	}
+	delete(versions, version)
+	c.size--
+	if len(versions) == 0 {
+		delete(c.entries, materialName)
+	}
+}
+
+func (c *materialsCache) Invalidate(materialName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.size -= len(c.entries[materialName])
+	delete(c.entries, materialName)
+}
+
+func (c *materialsCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]map[int64]cacheEntry)
+	c.size = 0
+}
+
+func (c *materialsCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.size
+}
@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+)
+
+func TestMaterialsCache(t *testing.T) {
+	cache := newMaterialsCache(time.Minute, 0)
+	m := materials.NewDecryptionMaterials(map[string]string{"k": "v"}, nil)
+
+	if _, ok := cache.Get("material", 1); ok {
+		t.Fatalf("expected cache miss on empty cache")
+	}
+
+	cache.Put("material", 1, m)
+	if got, ok := cache.Get("material", 1); !ok || got != m {
+		t.Fatalf("expected cache hit for stored materials")
+	}
+
+	cache.Invalidate("material")
+	if _, ok := cache.Get("material", 1); ok {
+		t.Errorf("expected cache miss after invalidate")
+	}
+}
+
+func TestMaterialsCache_Expiry(t *testing.T) {
+	cache := newMaterialsCache(-time.Second, 0)
+	cache.Put("material", 0, materials.NewDecryptionMaterials(nil, nil))
+
+	if _, ok := cache.Get("material", 0); ok {
+		t.Errorf("expected expired entry to miss")
+	}
+}
+
+func TestMaterialsCache_MaxEntries(t *testing.T) {
+	cache := newMaterialsCache(time.Minute, 2)
+	cache.Put("first", 1, materials.NewDecryptionMaterials(nil, nil))
+	cache.Put("second", 1, materials.NewDecryptionMaterials(nil, nil))
+	cache.Put("second", 1, materials.NewDecryptionMaterials(nil, nil))
+	if n := cache.Len(); n != 2 {
+		t.Fatalf("expected 2 entries, got %d", n)
+	}
+
+	cache.Put("third", 1, materials.NewDecryptionMaterials(nil, nil))
+	if n := cache.Len(); n != 2 {
+		t.Errorf("expected the cache to stay at 2 entries, got %d", n)
+	}
+	if _, ok := cache.Get("first", 1); ok {
+		t.Errorf("expected the entry closest to expiring to be evicted")
+	}
+	if _, ok := cache.Get("third", 1); !ok {
+		t.Errorf("expected the new entry to be cached")
+	}
+
+	cache.Invalidate("third")
+	if n := cache.Len(); n != 1 {
+		t.Errorf("expected 1 entry after invalidate, got %d", n)
+	}
+}
+
+func TestWithoutPlaintextCaching(t *testing.T) {
+	cmp, err := NewAwsKmsCryptographicMaterialsProvider(keyURI, nil, nil,
+		WithoutPlaintextCaching(), WithCache(time.Minute), WithMaterialReuse(DefaultUsageLimits))
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+	p := cmp.(*AwsKmsCryptographicMaterialsProvider)
+	if p.cache != nil || p.reuse != nil || p.pending != nil {
+		t.Errorf("expected materials caching, reuse and pending materials to be disabled")
+	}
+}
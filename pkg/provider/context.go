@@ -0,0 +1,134 @@
+package provider
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+)
+
+// EncryptionContextKeysKey is the material description entry listing, as a JSON array, the entries of the encryption
+// context the wrapped data keyset is bound to. Materials stored without it were wrapped without encryption context.
+const EncryptionContextKeysKey = "EncryptionContextKeys"
+
+// publicKeyKey is the material description entry holding the public keyset verifying the materials' signatures.
+const publicKeyKey = "PublicKey"
+
+// encryptionContextPrefix starts every canonical encryption context, naming the encoding and its version.
+const encryptionContextPrefix = "DDBENC-ENCRYPTION-CONTEXT-V1\n"
+
+// ErrEncryptionContextMismatch is returned when stored materials do not carry the expected encryption context.
+var ErrEncryptionContextMismatch = errors.New("encryption context mismatch")
+
+type requiredContextKey struct{}
+
+// ContextWithRequiredEncryptionContext returns a context requiring the given entries to be present in the
+// material description of any decryption materials fetched with it, in addition to the provider's own context.
+func ContextWithRequiredEncryptionContext(ctx context.Context, required map[string]string) context.Context {
+	merged := make(map[string]string)
+	if existing, ok := ctx.Value(requiredContextKey{}).(map[string]string); ok {
+		for key, value := range existing {
+			merged[key] = value
+		}
+	}
+	for key, value := range required {
+		merged[key] = value
+	}
+	return context.WithValue(ctx, requiredContextKey{}, merged)
+}
+
+// canonicalEncryptionContext returns the associated data a data keyset is wrapped with: the prefix
+// "DDBENC-ENCRYPTION-CONTEXT-V1" and a line feed, followed by the given entries of the description ordered by key and
+// then by the PublicKey entry, each encoded like in materials.CanonicalDescription. KMS key encryption keys pass it
+// to KMS as encryption context, so a keyset only unwraps with the encryption context and signing key it was stored
+// with.
+func canonicalEncryptionContext(keys []string, description map[string]string) []byte {
+	sorted := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		if key != publicKeyKey {
+			sorted = append(sorted, key)
+		}
+	}
+	sort.Strings(sorted)
+	payload := []byte(encryptionContextPrefix)
+	for _, key := range append(sorted, publicKeyKey) {
+		payload = binary.BigEndian.AppendUint32(payload, uint32(len(key)))
+		payload = append(payload, key...)
+		payload = binary.BigEndian.AppendUint32(payload, uint32(len(description[key])))
+		payload = append(payload, description[key]...)
+	}
+	return payload
+}
+
+// encryptionContextKeys returns the sorted keys of an encryption context.
+func encryptionContextKeys(encryptionContext map[string]string) []string {
+	keys := make([]string, 0, len(encryptionContext))
+	for key := range encryptionContext {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// boundEncryptionContext returns the entries of a stored material description its data keyset is bound to, and the
+// associated data to unwrap it with. Both are nil for legacy materials, stored before keysets were bound.
+func boundEncryptionContext(description map[string]string) (map[string]bool, []byte, error) {
+	encoded, ok := description[EncryptionContextKeysKey]
+	if !ok {
+		if !materials.IsLegacyDescription(description) {
+			return nil, nil, fmt.Errorf("%w: missing %s entry", ErrEncryptionContextMismatch, EncryptionContextKeysKey)
+		}
+		return nil, nil, nil
+	}
+	var keys []string
+	if err := json.Unmarshal([]byte(encoded), &keys); err != nil {
+		return nil, nil, fmt.Errorf("invalid %s entry: %v", EncryptionContextKeysKey, err)
+	}
+	bound := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if _, ok := description[key]; !ok {
+			return nil, nil, fmt.Errorf("%w: missing entry %q", ErrEncryptionContextMismatch, key)
+		}
+		bound[key] = true
+	}
+	return bound, canonicalEncryptionContext(keys, description), nil
+}
+
+// validateEncryptionContext checks that every expected entry, from the provider and from the request context,
+// matches the material description of the decryption materials. When the description records the entries its keyset
+// is bound to, expected entries must be among them, since only those were checked by unwrapping the keyset.
+func validateEncryptionContext(ctx context.Context, expected map[string]string, m materials.CryptographicMaterials) error {
+	description := m.MaterialDescription()
+	bound, _, err := boundEncryptionContext(description)
+	if err != nil {
+		return err
+	}
+
+	check := func(entries map[string]string) error {
+		for key, value := range entries {
+			if bound != nil && !bound[key] {
+				return fmt.Errorf("%w: entry %q is not bound to the keyset", ErrEncryptionContextMismatch, key)
+			}
+			actual, ok := description[key]
+			if !ok {
+				return fmt.Errorf("%w: missing entry %q", ErrEncryptionContextMismatch, key)
+			}
+			if actual != value {
+				return fmt.Errorf("%w: unexpected value for entry %q", ErrEncryptionContextMismatch, key)
+			}
+		}
+		return nil
+	}
+
+	if err := check(expected); err != nil {
+		return err
+	}
+	if required, ok := ctx.Value(requiredContextKey{}).(map[string]string); ok {
+		return check(required)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/internal/fakeddb"
+	fakeawskms "github.com/cloudopsy/dynamodb-encryption-go/internal/fakekms"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider/store"
+)
+
+func TestValidateEncryptionContext(t *testing.T) {
+	m := materials.NewDecryptionMaterials(map[string]string{
+		"tenant":        "acme",
+		"WrappedKeyset": "keyset",
+	}, nil)
+	bound := materials.NewDecryptionMaterials(map[string]string{
+		"tenant":                 "acme",
+		"env":                    "prod",
+		EncryptionContextKeysKey: `["tenant"]`,
+		"WrappedKeyset":          "keyset",
+	}, nil)
+
+	testCases := []struct {
+		name      string
+		materials materials.CryptographicMaterials
+		expected  map[string]string
+		required  map[string]string
+		err       error
+	}{
+		{name: "NoExpectations", materials: m},
+		{name: "Match", materials: m, expected: map[string]string{"tenant": "acme"}},
+		{name: "Mismatch", materials: m, expected: map[string]string{"tenant": "other"}, err: ErrEncryptionContextMismatch},
+		{name: "Missing", materials: m, expected: map[string]string{"env": "prod"}, err: ErrEncryptionContextMismatch},
+		{name: "RequiredMatch", materials: m, required: map[string]string{"tenant": "acme"}},
+		{name: "RequiredMismatch", materials: m, required: map[string]string{"tenant": "other"}, err: ErrEncryptionContextMismatch},
+		{name: "BoundMatch", materials: bound, expected: map[string]string{"tenant": "acme"}},
+		{name: "Unbound", materials: bound, expected: map[string]string{"env": "prod"}, err: ErrEncryptionContextMismatch},
+		{name: "RequiredUnbound", materials: bound, required: map[string]string{"env": "prod"}, err: ErrEncryptionContextMismatch},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.required != nil {
+				ctx = ContextWithRequiredEncryptionContext(ctx, tc.required)
+			}
+			err := validateEncryptionContext(ctx, tc.expected, tc.materials)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestEncryptionContextBinding(t *testing.T) {
+	kms, err := fakeawskms.New([]string{keyURI})
+	if err != nil {
+		t.Fatalf("failed to create fake KMS: %v", err)
+	}
+	materialStore, err := store.NewMetaStore(fakeddb.New().Client(), "meta")
+	if err != nil {
+		t.Fatalf("failed to create meta store: %v", err)
+	}
+	writer, err := New(keyURI, map[string]string{"tenant": "acme"}, materialStore, WithKMSClient(kms))
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+	cacheKEK, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/8c6a5d1e-2f5a-4b8e-9d1a-5b8f4e2c7a90", true)
+	if err != nil {
+		t.Fatalf("failed to get cache KEK: %v", err)
+	}
+	ctx := context.Background()
+
+	if _, err := writer.EncryptionMaterials(ctx, "users"); err != nil {
+		t.Fatalf("failed to get encryption materials: %v", err)
+	}
+	stored, err := writer.DescribeMaterials(ctx, "users", 0)
+	if err != nil {
+		t.Fatalf("failed to describe materials: %v", err)
+	}
+
+	// resign replaces the signing key of a description with one of the attacker's, who can not unwrap the data keyset
+	resign := func(t *testing.T, description map[string]string) {
+		t.Helper()
+		signingKey, _, publicKey, err := delegatedkeys.GenerateSigningKey(cacheKEK)
+		if err != nil {
+			t.Fatalf("failed to generate signing key: %v", err)
+		}
+		wrappedKeyset, err := base64.StdEncoding.DecodeString(description["WrappedKeyset"])
+		if err != nil {
+			t.Fatalf("failed to decode keyset: %v", err)
+		}
+		signature, err := signingKey.Sign(wrappedKeyset)
+		if err != nil {
+			t.Fatalf("failed to sign keyset: %v", err)
+		}
+		description["Signature"] = base64.StdEncoding.EncodeToString(signature)
+		description["PublicKey"] = base64.StdEncoding.EncodeToString(publicKey)
+		if err := materials.SignDescription(description, signingKey); err != nil {
+			t.Fatalf("failed to sign description: %v", err)
+		}
+	}
+
+	// legacy builds an unsigned description like those stored before keysets were bound to the encryption context
+	legacy := func(t *testing.T, description map[string]string) {
+		t.Helper()
+		kek, err := writer.kekFor(keyURI)
+		if err != nil {
+			t.Fatalf("failed to get KEK: %v", err)
+		}
+		dataKey, wrappedKeyset, err := delegatedkeys.GenerateDataKey(kek)
+		if err != nil {
+			t.Fatalf("failed to generate data key: %v", err)
+		}
+		delete(description, EncryptionContextKeysKey)
+		delete(description, DeterministicKeysetKey)
+		description["ContentEncryptionAlgorithm"] = dataKey.Algorithm()
+		description["WrappedKeyset"] = base64.StdEncoding.EncodeToString(wrappedKeyset)
+		resign(t, description)
+		delete(description, materials.DescriptionFormatKey)
+		delete(description, materials.DescriptionSignatureKey)
+	}
+
+	testCases := []struct {
+		name     string
+		context  map[string]string
+		opts     []ProviderOption
+		tamper   func(t *testing.T, description map[string]string)
+		rejected bool
+		err      error
+	}{
+		{name: "Bound", context: map[string]string{"tenant": "acme"}},
+		{name: "Re-signed", context: map[string]string{"tenant": "acme"}, tamper: resign, rejected: true},
+		{name: "Changed value re-signed", context: map[string]string{"tenant": "other"}, tamper: func(t *testing.T, description map[string]string) {
+			description["tenant"] = "other"
+			resign(t, description)
+		}, rejected: true},
+		{name: "Binding removed", context: map[string]string{"tenant": "acme"}, tamper: func(t *testing.T, description map[string]string) {
+			delete(description, EncryptionContextKeysKey)
+			resign(t, description)
+		}, rejected: true},
+		{name: "Binding widened", context: map[string]string{"tenant": "acme", "env": "prod"}, tamper: func(t *testing.T, description map[string]string) {
+			description["env"] = "prod"
+			description[EncryptionContextKeysKey] = `["env","tenant"]`
+			resign(t, description)
+		}, rejected: true},
+		{name: "Unbound entry", context: map[string]string{"tenant": "acme", "env": "prod"}, tamper: func(t *testing.T, description map[string]string) {
+			description["env"] = "prod"
+			resign(t, description)
+		}, rejected: true},
+		{name: "Signature stripped", context: map[string]string{"tenant": "acme"}, tamper: func(t *testing.T, description map[string]string) {
+			delete(description, materials.DescriptionSignatureKey)
+		}, rejected: true, err: materials.ErrUnsignedDescription},
+		{name: "Format and signature stripped", context: map[string]string{"tenant": "acme"}, tamper: func(t *testing.T, description map[string]string) {
+			delete(description, materials.DescriptionFormatKey)
+			delete(description, materials.DescriptionSignatureKey)
+		}, rejected: true, err: ErrLegacyDescription},
+		{name: "Legacy changed value", context: map[string]string{"tenant": "other"}, opts: []ProviderOption{WithLegacyDescriptions()}, tamper: func(t *testing.T, description map[string]string) {
+			description["tenant"] = "other"
+			delete(description, materials.DescriptionFormatKey)
+			delete(description, materials.DescriptionSignatureKey)
+		}, rejected: true},
+		{name: "Legacy", context: map[string]string{"tenant": "acme"}, opts: []ProviderOption{WithLegacyDescriptions()}, tamper: legacy},
+		{name: "Legacy not allowed", context: map[string]string{"tenant": "acme"}, tamper: legacy, rejected: true, err: ErrLegacyDescription},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			description := make(map[string]string, len(stored))
+			for key, value := range stored {
+				description[key] = value
+			}
+			version := int64(1)
+			if tc.tamper != nil {
+				materialID, err := store.NewMaterialID()
+				if err != nil {
+					t.Fatalf("failed to create material ID: %v", err)
+				}
+				description[store.MaterialIDKey] = materialID
+				tc.tamper(t, description)
+				version, err = materialStore.StoreNewMaterialVersion(ctx, "users", materials.NewDecryptionMaterials(description, nil))
+				if err != nil {
+					t.Fatalf("failed to store materials: %v", err)
+				}
+			}
+			for _, opts := range [][]ProviderOption{nil, {WithKeysetCache(NewMemoryKeysetCache(), cacheKEK, time.Minute)}} {
+				opts = append(append([]ProviderOption{WithKMSClient(kms), WithReadOnly()}, tc.opts...), opts...)
+				reader, err := New(keyURI, tc.context, materialStore, opts...)
+				if err != nil {
+					t.Fatalf("failed to create provider: %v", err)
+				}
+				// Reading the untampered materials first fills the keyset cache
+				if _, err := reader.fetchDecryptionMaterials(ctx, "users", 1); err != nil {
+					t.Fatalf("failed to read the stored materials: %v", err)
+				}
+				_, err = reader.DecryptionMaterials(ctx, "users", version)
+				switch {
+				case tc.err != nil && !errors.Is(err, tc.err):
+					t.Errorf("expected %v, got %v", tc.err, err)
+				case tc.rejected && err == nil:
+					t.Errorf("expected the tampered materials to be rejected")
+				case tc.rejected && tc.err == nil && errors.Is(err, materials.ErrInvalidDescriptionSignature):
+					t.Errorf("expected the tampered materials to be rejected by unwrapping, got %v", err)
+				case !tc.rejected && err != nil:
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/internal/fakeddb"
+	fakeawskms "github.com/cloudopsy/dynamodb-encryption-go/internal/fakekms"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider/store"
+)
+
+func TestDeterministicMaterials(t *testing.T) {
+	kms, err := fakeawskms.New([]string{keyURI})
+	if err != nil {
+		t.Fatalf("failed to create fake KMS: %v", err)
+	}
+	materialStore, err := store.NewMetaStore(fakeddb.New().Client(), "meta")
+	if err != nil {
+		t.Fatalf("failed to create meta store: %v", err)
+	}
+	writer, err := New(keyURI, nil, materialStore, WithKMSClient(kms))
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+	cacheKEK, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/8c6a5d1e-2f5a-4b8e-9d1a-5b8f4e2c7a90", true)
+	if err != nil {
+		t.Fatalf("failed to get cache KEK: %v", err)
+	}
+	ctx := context.Background()
+
+	encryptionMaterials, err := writer.EncryptionMaterials(ctx, "users")
+	if err != nil {
+		t.Fatalf("failed to get encryption materials: %v", err)
+	}
+	encryptionKey, ok := encryptionMaterials.EncryptionKey().(delegatedkeys.DeterministicKey)
+	if !ok {
+		t.Fatalf("expected the data key to support deterministic encryption, got %T", encryptionMaterials.EncryptionKey())
+	}
+	plaintext := []byte("a@example.com")
+	ciphertext, err := encryptionKey.EncryptDeterministically(plaintext, []byte("Email"))
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+	version, err := strconv.ParseInt(encryptionMaterials.MaterialDescription()[MaterialVersionKey], 10, 64)
+	if err != nil {
+		t.Fatalf("failed to parse material version: %v", err)
+	}
+
+	testCases := []struct {
+		name    string
+		opts    []ProviderOption
+		version int64
+	}{
+		{name: "Version", version: version},
+		{name: "Latest", version: 0},
+		{name: "Keyset cache", opts: []ProviderOption{WithKeysetCache(NewMemoryKeysetCache(), cacheKEK, time.Minute)}, version: version},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reader, err := New(keyURI, nil, materialStore, append([]ProviderOption{WithKMSClient(kms)}, tc.opts...)...)
+			if err != nil {
+				t.Fatalf("failed to create provider: %v", err)
+			}
+			// Twice, so the keyset cache serves the second request
+			for i := 0; i < 2; i++ {
+				decryptionMaterials, err := reader.DecryptionMaterials(ctx, "users", tc.version)
+				if err != nil {
+					t.Fatalf("failed to get decryption materials: %v", err)
+				}
+				decryptionKey := decryptionMaterials.DecryptionKey().(delegatedkeys.DeterministicKey)
+				again, err := decryptionKey.EncryptDeterministically(plaintext, []byte("Email"))
+				if err != nil {
+					t.Fatalf("encryption failed: %v", err)
+				}
+				if !bytes.Equal(ciphertext, again) {
+					t.Errorf("expected the stored materials to encrypt like the new ones")
+				}
+				decrypted, err := decryptionKey.Decrypt(ciphertext, []byte("Email"))
+				if err != nil || !bytes.Equal(decrypted, plaintext) {
+					t.Errorf("expected %q, got %q, %v", plaintext, decrypted, err)
+				}
+			}
+		})
+	}
+}
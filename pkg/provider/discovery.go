@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrKeyNotAllowed is returned in discovery mode when materials are wrapped under a KMS key outside the filter.
+var ErrKeyNotAllowed = errors.New("KMS key not allowed by discovery filter")
+
+// DiscoveryFilter limits the KMS keys a provider in discovery mode unwraps materials with. A key is allowed when
+// its ARN is listed in KeyARNs or it belongs to one of AccountIDs. An empty filter allows no keys.
+type DiscoveryFilter struct {
+	AccountIDs []string
+	KeyARNs    []string
+}
+
+// WithDiscovery makes DecryptionMaterials unwrap keysets with the KMS key recorded in their material description,
+// instead of the provider's own key URI, as long as the key passes the filter. This lets tooling read tables
+// encrypted under many keys. Materials that do not record a key are unwrapped with the provider's key URI.
+func WithDiscovery(filter DiscoveryFilter) ProviderOption {
+	return func(p *AwsKmsCryptographicMaterialsProvider) {
+		p.discovery = &filter
+	}
+}
+
+// allows reports whether the filter allows the KMS key.
+func (f *DiscoveryFilter) allows(keyARN string) bool {
+	for _, allowed := range f.KeyARNs {
+		if allowed == keyARN {
+			return true
+		}
+	}
+
+	account, err := accountFromKeyARN(keyARN)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range f.AccountIDs {
+		if allowed == account {
+			return true
+		}
+	}
+	return false
+}
+
+// decryptionKeyURI returns the KMS key to unwrap materials with: the key recorded in the material description when
+// it is one of the provider's keys or, in discovery mode, passes the filter, and otherwise the key of the request's
+// table.
+func (p *AwsKmsCryptographicMaterialsProvider) decryptionKeyURI(ctx context.Context, materialDescription map[string]string) (string, error) {
+	keyURI, ok := materialDescription[KMSKeyURIKey]
+	if !ok || keyURI == "" || (p.discovery == nil && !p.isProviderKey(keyURI)) {
+		return p.encryptionKeyURI(ctx), nil
+	}
+	if p.discovery == nil {
+		return keyURI, nil
+	}
+	if !p.discovery.allows(keyURI) {
+		return "", fmt.Errorf("%w: %s", ErrKeyNotAllowed, keyURI)
+	}
+	return keyURI, nil
+}
@@ -0,0 +1,46 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAwsKmsCryptographicMaterialsProvider_DecryptionKeyURI(t *testing.T) {
+	const (
+		otherAccountKey = "arn:aws:kms:eu-west-2:999999999999:key/4f1b3c52-1a2b-4c3d-8e9f-0a1b2c3d4e5f"
+		listedKey       = "arn:aws:kms:us-east-1:888888888888:key/7d6c5b4a-3b2a-4c1d-9e8f-7a6b5c4d3e2f"
+	)
+	p := &AwsKmsCryptographicMaterialsProvider{KMSKeyURI: keyURI}
+	WithDiscovery(DiscoveryFilter{AccountIDs: []string{"123456789123"}, KeyARNs: []string{listedKey}})(p)
+
+	testCases := []struct {
+		name     string
+		recorded string
+		expected string
+		wantErr  error
+	}{
+		{name: "NotRecorded", expected: keyURI},
+		{name: "AllowedAccount", recorded: "arn:aws:kms:us-west-2:123456789123:key/other", expected: "arn:aws:kms:us-west-2:123456789123:key/other"},
+		{name: "AllowedKey", recorded: listedKey, expected: listedKey},
+		{name: "OtherAccount", recorded: otherAccountKey, wantErr: ErrKeyNotAllowed},
+		{name: "Malformed", recorded: "not-an-arn", wantErr: ErrKeyNotAllowed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			description := map[string]string{}
+			if tc.recorded != "" {
+				description[KMSKeyURIKey] = tc.recorded
+			}
+
+			got, err := p.decryptionKeyURI(context.Background(), description)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected key %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
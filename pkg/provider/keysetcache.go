@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/utils"
+	"github.com/tink-crypto/tink-go/v2/tink"
+)
+
+// KeysetCache stores keysets after they were unwrapped with KMS, so that processes sharing the cache can skip the KMS
+// call. The provider wraps every keyset with the cache's own key encryption key before storing it, bound to its cache
+// key, so implementations only ever hold ciphertext and can live outside the process, for example in Redis.
+// Implementations must be safe for concurrent use.
+type KeysetCache interface {
+	// Get returns the value stored under key. A missing or expired entry is not an error.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key for ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete drops the entry stored under key.
+	Delete(ctx context.Context, key string) error
+}
+
+// keysetCache wraps and unwraps the keysets held by a KeysetCache.
+type keysetCache struct {
+	cache KeysetCache
+	kek   tink.AEAD
+	ttl   time.Duration
+}
+
+// WithKeysetCache shares unwrapped keysets through cache for the given TTL, wrapped with kek, a key the processes
+// sharing the cache hold, for example a KMS key used once at startup to unwrap a local keyset. Keysets are still
+// read from the material store and their signatures verified before a cached copy is used, so deleted materials
+// are never served from the cache. Cache errors are counted and treated as misses.
+func WithKeysetCache(cache KeysetCache, kek tink.AEAD, ttl time.Duration) ProviderOption {
+	return func(p *AwsKmsCryptographicMaterialsProvider) {
+		p.keysets = &keysetCache{cache: cache, kek: kek, ttl: ttl}
+	}
+}
+
+// keysetCacheKey names a stored keyset within a meta table by a hash of its wrapped form and the encryption context
+// it is bound to, so an entry only ever matches the exact keyset and encryption context read from the material store.
+func keysetCacheKey(cacheKey, wrappedKeyset string, associatedData []byte) string {
+	if associatedData != nil {
+		wrappedKeyset += "\x00" + string(associatedData)
+	}
+	return cacheKey + "/" + utils.HashString(wrappedKeyset)
+}
+
+// get returns the cached keyset stored under key, if any.
+func (c *keysetCache) get(ctx context.Context, counters *providerCounters, key string) (*delegatedkeys.TinkDelegatedKey, bool) {
+	wrapped, ok, err := c.cache.Get(ctx, key)
+	if err != nil {
+		counters.add(MetricKeysetCacheErrors, 1)
+		return nil, false
+	}
+	if !ok {
+		counters.add(MetricKeysetCacheMisses, 1)
+		return nil, false
+	}
+	delegatedKey, err := delegatedkeys.UnwrapKeysetWithAssociatedData(wrapped, c.kek, []byte(key))
+	if err != nil {
+		counters.add(MetricKeysetCacheErrors, 1)
+		return nil, false
+	}
+	counters.add(MetricKeysetCacheHits, 1)
+	return delegatedKey, true
+}
+
+// put stores a copy of an unwrapped keyset under key.
+func (c *keysetCache) put(ctx context.Context, counters *providerCounters, key string, delegatedKey *delegatedkeys.TinkDelegatedKey) {
+	wrapped, err := delegatedKey.WrapKeysetWithAssociatedData(c.kek, []byte(key))
+	if err == nil {
+		err = c.cache.Set(ctx, key, wrapped, c.ttl)
+	}
+	if err != nil {
+		counters.add(MetricKeysetCacheErrors, 1)
+	}
+}
+
+// memoryKeysetCache is the in-memory KeysetCache.
+type memoryKeysetCache struct {
+	mu      sync.Mutex
+	entries map[string]keysetEntry
+}
+
+type keysetEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewMemoryKeysetCache returns a KeysetCache held in process memory, for single processes and tests.
+func NewMemoryKeysetCache() KeysetCache {
+	return &memoryKeysetCache{entries: make(map[string]keysetEntry)}
+}
+
+func (c *memoryKeysetCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+	return entry.value, true, nil
+}
+
+func (c *memoryKeysetCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = keysetEntry{value: value, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (c *memoryKeysetCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+	return nil
+}
@@ -0,0 +1,81 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+)
+
+func TestKeysetCache(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK(keyURI, true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	cacheKEK, err := delegatedkeys.GetKEK("arn:aws:kms:eu-west-2:123456789123:key/8c6a5d1e-2f5a-4b8e-9d1a-5b8f4e2c7a90", true)
+	if err != nil {
+		t.Fatalf("failed to get cache KEK: %v", err)
+	}
+	dataKey, wrappedKeyset, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	ciphertext, err := dataKey.Encrypt([]byte("secret"), nil)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	ctx := context.Background()
+	shared := NewMemoryKeysetCache()
+	cache := &keysetCache{cache: shared, kek: cacheKEK, ttl: time.Minute}
+	var counters providerCounters
+	key := keysetCacheKey("meta/material", string(wrappedKeyset), nil)
+
+	if _, ok := cache.get(ctx, &counters, key); ok {
+		t.Fatalf("expected a miss on an empty cache")
+	}
+	cache.put(ctx, &counters, key, dataKey)
+
+	// Entries are wrapped with the cache's key, never stored as the plaintext or KMS-wrapped keyset
+	stored, ok, err := shared.Get(ctx, key)
+	if err != nil || !ok {
+		t.Fatalf("expected an entry in the shared cache, got %v", err)
+	}
+	if _, err := delegatedkeys.UnwrapKeyset(stored, kek); err == nil {
+		t.Errorf("expected the KMS key not to unwrap the cached keyset")
+	}
+
+	cached, ok := cache.get(ctx, &counters, key)
+	if !ok {
+		t.Fatalf("expected a hit after put")
+	}
+	if plaintext, err := cached.Decrypt(ciphertext, nil); err != nil || string(plaintext) != "secret" {
+		t.Errorf("expected the cached keyset to decrypt, got %q, %v", plaintext, err)
+	}
+
+	// An entry copied under another key fails to unwrap, since it is bound to its own key
+	other := keysetCacheKey("meta/other", "keyset", nil)
+	if err := shared.Set(ctx, other, stored, time.Minute); err != nil {
+		t.Fatalf("failed to copy entry: %v", err)
+	}
+	if _, ok := cache.get(ctx, &counters, other); ok {
+		t.Errorf("expected an entry moved to another key to be rejected")
+	}
+
+	stats := counters.snapshot()
+	if stats.KeysetCacheHits != 1 || stats.KeysetCacheMisses != 1 || stats.KeysetCacheErrors != 1 {
+		t.Errorf("unexpected keyset cache stats: %+v", stats)
+	}
+}
+
+func TestMemoryKeysetCache_Expiry(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryKeysetCache()
+	if err := cache.Set(ctx, "key", []byte("value"), -time.Second); err != nil {
+		t.Fatalf("failed to set entry: %v", err)
+	}
+	if _, ok, err := cache.Get(ctx, "key"); ok || err != nil {
+		t.Errorf("expected an expired entry to miss, got %v, %v", ok, err)
+	}
+}
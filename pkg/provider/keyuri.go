@@ -0,0 +1,126 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidKeyURITemplate is returned when a key URI template is malformed, names a placeholder without a value, or
+// resolves to something other than a KMS key or alias ARN.
+var ErrInvalidKeyURITemplate = errors.New("invalid key URI template")
+
+// KeyURIResolver returns the value of a key URI template placeholder, reporting whether it has one.
+type KeyURIResolver func(name string) (string, bool)
+
+var (
+	placeholderNamePattern  = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+	placeholderValuePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+	accountIDPattern        = regexp.MustCompile(`^[0-9]{12}$`)
+	regionPattern           = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9]+$`)
+)
+
+// WithKeyURIResolver resolves the placeholders of a templated key URI with resolver. A key URI is a template when it
+// contains placeholders in braces, such as "aws-kms://arn:aws:kms:{region}:{account}:alias/app-{env}". Without this
+// option, templates are resolved with EnvResolver("DDBENC_").
+func WithKeyURIResolver(resolver KeyURIResolver) ProviderOption {
+	return func(p *AwsKmsCryptographicMaterialsProvider) {
+		p.keyURIResolver = resolver
+	}
+}
+
+// MapResolver returns a KeyURIResolver looking placeholders up in values.
+func MapResolver(values map[string]string) KeyURIResolver {
+	return func(name string) (string, bool) {
+		value, ok := values[name]
+		return value, ok
+	}
+}
+
+// EnvResolver returns a KeyURIResolver reading placeholders from environment variables named by prefix and the
+// placeholder name in upper case, so with the prefix "DDBENC_" the placeholder {env} is read from DDBENC_ENV. The
+// {region} placeholder falls back to AWS_REGION and AWS_DEFAULT_REGION, as set in Lambda and ECS.
+func EnvResolver(prefix string) KeyURIResolver {
+	return func(name string) (string, bool) {
+		if value, ok := os.LookupEnv(prefix + strings.ToUpper(name)); ok {
+			return value, true
+		}
+		if name == "region" {
+			for _, fallback := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+				if value, ok := os.LookupEnv(fallback); ok {
+					return value, true
+				}
+			}
+		}
+		return "", false
+	}
+}
+
+// IsKeyURITemplate reports whether keyURI contains placeholders.
+func IsKeyURITemplate(keyURI string) bool {
+	return strings.ContainsAny(keyURI, "{}")
+}
+
+// ResolveKeyURI replaces the placeholders of a key URI template with the values resolver returns and validates the
+// result. Values may only contain letters, digits, '.', '_' and '-', so a placeholder can not change the structure
+// of the ARN, and the resolved ARN must name a key or alias with a valid region and account ID. All placeholders
+// without a value are reported together. Key URIs without placeholders are returned unchanged.
+func ResolveKeyURI(template string, resolver KeyURIResolver) (string, error) {
+	if !IsKeyURITemplate(template) {
+		return template, nil
+	}
+
+	var resolved strings.Builder
+	var missing []string
+	rest := template
+	for {
+		open := strings.IndexAny(rest, "{}")
+		if open < 0 {
+			resolved.WriteString(rest)
+			break
+		}
+		if rest[open] == '}' {
+			return "", fmt.Errorf("%w: unmatched '}' in %q", ErrInvalidKeyURITemplate, template)
+		}
+		end := strings.IndexAny(rest[open+1:], "{}")
+		if end < 0 || rest[open+1+end] != '}' {
+			return "", fmt.Errorf("%w: unterminated placeholder in %q", ErrInvalidKeyURITemplate, template)
+		}
+		name := rest[open+1 : open+1+end]
+		if !placeholderNamePattern.MatchString(name) {
+			return "", fmt.Errorf("%w: invalid placeholder name %q", ErrInvalidKeyURITemplate, name)
+		}
+
+		value, ok := resolver(name)
+		switch {
+		case !ok || value == "":
+			missing = append(missing, name)
+		case !placeholderValuePattern.MatchString(value):
+			return "", fmt.Errorf("%w: invalid value %q for placeholder {%s}", ErrInvalidKeyURITemplate, value, name)
+		}
+		resolved.WriteString(rest[:open])
+		resolved.WriteString(value)
+		rest = rest[open+2+end:]
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%w: no value for placeholders {%s}", ErrInvalidKeyURITemplate, strings.Join(missing, "}, {"))
+	}
+
+	keyURI := resolved.String()
+	parts, err := splitKeyARN(keyURI)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidKeyURITemplate, err)
+	}
+	if !regionPattern.MatchString(parts[3]) {
+		return "", fmt.Errorf("%w: invalid region %q in %q", ErrInvalidKeyURITemplate, parts[3], keyURI)
+	}
+	if !accountIDPattern.MatchString(parts[4]) {
+		return "", fmt.Errorf("%w: invalid account ID %q in %q", ErrInvalidKeyURITemplate, parts[4], keyURI)
+	}
+	if !strings.HasPrefix(parts[5], "key/") && !strings.HasPrefix(parts[5], "alias/") {
+		return "", fmt.Errorf("%w: %q names neither a key nor an alias", ErrInvalidKeyURITemplate, keyURI)
+	}
+	return keyURI, nil
+}
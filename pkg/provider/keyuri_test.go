@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResolveKeyURI(t *testing.T) {
+	values := map[string]string{
+		"region":  "eu-west-2",
+		"account": "123456789123",
+		"env":     "prod",
+		"slash":   "a/b",
+		"short":   "12345",
+	}
+
+	testCases := []struct {
+		name     string
+		template string
+		want     string
+		valid    bool
+	}{
+		{name: "Alias", template: "aws-kms://arn:aws:kms:{region}:{account}:alias/app-{env}", want: "aws-kms://arn:aws:kms:eu-west-2:123456789123:alias/app-prod", valid: true},
+		{name: "Key", template: "arn:aws:kms:{region}:{account}:key/02813db0-b23a-420c-94b0-bdceb08e121b", want: keyURI, valid: true},
+		{name: "Not a template", template: "key/02813db0", want: "key/02813db0", valid: true},
+		{name: "Missing value", template: "arn:aws:kms:{region}:{tenant}:alias/app-{env}"},
+		{name: "Value changes structure", template: "arn:aws:kms:{region}:{account}:alias/{slash}"},
+		{name: "Invalid account", template: "arn:aws:kms:{region}:{short}:alias/app"},
+		{name: "Invalid region", template: "arn:aws:kms:{env}:{account}:alias/app"},
+		{name: "Not a key", template: "arn:aws:kms:{region}:{account}:grant/{env}"},
+		{name: "Unterminated", template: "arn:aws:kms:{region:{account}:alias/app"},
+		{name: "Unmatched", template: "arn:aws:kms:region}:{account}:alias/app"},
+		{name: "Invalid name", template: "arn:aws:kms:{}:{account}:alias/app"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ResolveKeyURI(tc.template, MapResolver(values))
+			if !tc.valid {
+				if !errors.Is(err, ErrInvalidKeyURITemplate) {
+					t.Errorf("expected ErrInvalidKeyURITemplate, got %q, %v", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to resolve key URI: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestEnvResolver(t *testing.T) {
+	t.Setenv("DDBENC_ENV", "staging")
+	t.Setenv("DDBENC_ACCOUNT", "123456789123")
+	t.Setenv("AWS_REGION", "eu-west-1")
+
+	cmp, err := NewAwsKmsCryptographicMaterialsProvider("aws-kms://arn:aws:kms:{region}:{account}:alias/app-{env}", nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+	if got, want := cmp.(*AwsKmsCryptographicMaterialsProvider).KMSKeyURI, "aws-kms://arn:aws:kms:eu-west-1:123456789123:alias/app-staging"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	_, err = NewAwsKmsCryptographicMaterialsProvider("arn:aws:kms:{region}:{account}:alias/app-{env}", nil, nil,
+		WithKeyURIResolver(MapResolver(map[string]string{"region": "eu-west-2"})))
+	if !errors.Is(err, ErrInvalidKeyURITemplate) {
+		t.Errorf("expected ErrInvalidKeyURITemplate, got %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/tink-crypto/tink-go/v2/tink"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+)
+
+// KMSClientFactory builds the KMS client used for keys in the given region.
+type KMSClientFactory func(region string) (kmsiface.KMSAPI, error)
+
+// WithKMSClient makes the provider call KMS through the given client instead of one built from the default
+// credentials, so KMS can be accessed with credentials distinct from the DynamoDB client's. The client is used for
+// keys in the region of the provider's key URI; keys in other regions use the KMS client factory, if any.
+func WithKMSClient(client kmsiface.KMSAPI) ProviderOption {
+	return func(p *AwsKmsCryptographicMaterialsProvider) {
+		p.kmsClient = client
+	}
+}
+
+// WithKMSClientFactory makes the provider build one KMS client per region through the factory, for example so
+// that items encrypted under a key in another region, such as global table replicas, can be decrypted.
+// Clients are built on first use and reused afterwards.
+func WithKMSClientFactory(factory KMSClientFactory) ProviderOption {
+	return func(p *AwsKmsCryptographicMaterialsProvider) {
+		p.kmsClientFactory = factory
+	}
+}
+
+// WithAssumeRole makes the provider call KMS with credentials obtained by assuming the given role, for example
+// a role in a separate security account that holds the KMS key. Options such as an external ID can be set on the
+// assume role provider. A client is built for each region a key is used in.
+func WithAssumeRole(roleARN string, opts ...func(*stscreds.AssumeRoleProvider)) ProviderOption {
+	return WithKMSClientFactory(func(region string) (kmsiface.KMSAPI, error) {
+		sess, err := session.NewSession(&aws.Config{Region: aws.String(region)})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create AWS session: %v", err)
+		}
+
+		creds := stscreds.NewCredentials(sess, roleARN, opts...)
+		return kms.New(sess, &aws.Config{Credentials: creds}), nil
+	})
+}
+
+// kmsClients caches the KMS clients built by a KMSClientFactory and the key encryption keys obtained through them.
+type kmsClients struct {
+	mu      sync.Mutex
+	clients map[string]kmsiface.KMSAPI
+	keks    map[string]tink.AEAD
+}
+
+// clear drops the cached KMS clients and key encryption keys.
+func (c *kmsClients) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.clients = nil
+	c.keks = nil
+}
+
+// regionFromKeyARN extracts the region from a KMS key or alias ARN such as
+// "arn:aws:kms:eu-west-2:111122223333:key/...".
+func regionFromKeyARN(keyARN string) (string, error) {
+	parts, err := splitKeyARN(keyARN)
+	if err != nil {
+		return "", err
+	}
+	return parts[3], nil
+}
+
+// accountFromKeyARN extracts the account ID from a KMS key or alias ARN.
+func accountFromKeyARN(keyARN string) (string, error) {
+	parts, err := splitKeyARN(keyARN)
+	if err != nil {
+		return "", err
+	}
+	return parts[4], nil
+}
+
+func splitKeyARN(keyARN string) ([]string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(keyARN, "aws-kms://"), ":", 6)
+	if len(parts) < 6 || parts[0] != "arn" || parts[2] != "kms" || parts[3] == "" || parts[4] == "" {
+		return nil, fmt.Errorf("invalid KMS key ARN %q", keyARN)
+	}
+	return parts, nil
+}
+
+// kek returns the provider's key encryption key.
+func (p *AwsKmsCryptographicMaterialsProvider) kek() (tink.AEAD, error) {
+	return p.kekFor(p.KMSKeyURI)
+}
+
+// kekFor returns the key encryption key for a KMS key. The KMS client is chosen by the key's region: the configured
+// client for the provider key's region, a client built by the factory for other regions, and otherwise a client
+// built from the default credentials in the key's region. Key encryption keys are cached by key URI.
+func (p *AwsKmsCryptographicMaterialsProvider) kekFor(keyURI string) (tink.AEAD, error) {
+	p.kms.mu.Lock()
+	defer p.kms.mu.Unlock()
+
+	if kek, ok := p.kms.keks[keyURI]; ok {
+		return kek, nil
+	}
+
+	client, err := p.kmsClientFor(keyURI)
+	if err != nil {
+		return nil, err
+	}
+
+	var kek tink.AEAD
+	if client != nil {
+		kek, err = delegatedkeys.GetKEKWithKMS(keyURI, client)
+	} else {
+		kek, err = delegatedkeys.GetKEK(keyURI, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if p.kms.keks == nil {
+		p.kms.keks = make(map[string]tink.AEAD)
+	}
+	p.kms.keks[keyURI] = kek
+	return kek, nil
+}
+
+// kmsClientFor returns the KMS client for the key's region, or nil to use the default credentials.
+// It must be called with p.kms.mu held.
+func (p *AwsKmsCryptographicMaterialsProvider) kmsClientFor(keyURI string) (kmsiface.KMSAPI, error) {
+	region, err := regionFromKeyARN(keyURI)
+	if err != nil {
+		// Key IDs and aliases without an ARN can only be used in the configured client's region.
+		return p.kmsClient, nil
+	}
+
+	if p.kmsClient != nil {
+		if providerRegion, err := regionFromKeyARN(p.KMSKeyURI); err != nil || providerRegion == region {
+			return p.kmsClient, nil
+		}
+	}
+	if p.kmsClientFactory == nil {
+		return nil, nil
+	}
+
+	if client, ok := p.kms.clients[region]; ok {
+		return client, nil
+	}
+	client, err := p.kmsClientFactory(region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create KMS client for region %s: %w", region, err)
+	}
+	if p.kms.clients == nil {
+		p.kms.clients = make(map[string]kmsiface.KMSAPI)
+	}
+	p.kms.clients[region] = client
+	return client, nil
+}
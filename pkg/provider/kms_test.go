@@ -0,0 +1,114 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	fakeawskms "github.com/cloudopsy/dynamodb-encryption-go/internal/fakekms"
+)
+
+func TestRegionFromKeyARN(t *testing.T) {
+	testCases := []struct {
+		keyARN   string
+		expected string
+		wantErr  bool
+	}{
+		{keyARN: keyURI, expected: "eu-west-2"},
+		{keyARN: "aws-kms://" + keyURI, expected: "eu-west-2"},
+		{keyARN: "arn:aws:kms:us-east-1:123456789123:alias/data", expected: "us-east-1"},
+		{keyARN: "02813db0-b23a-420c-94b0-bdceb08e121b", wantErr: true},
+		{keyARN: "arn:aws:s3:::bucket", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.keyARN, func(t *testing.T) {
+			region, err := regionFromKeyARN(tc.keyARN)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if region != tc.expected {
+				t.Errorf("expected region %q, got %q", tc.expected, region)
+			}
+		})
+	}
+}
+
+func TestAwsKmsCryptographicMaterialsProvider_KMSClient(t *testing.T) {
+	kms, err := fakeawskms.New([]string{keyURI})
+	if err != nil {
+		t.Fatalf("failed to create fake KMS: %v", err)
+	}
+
+	cmp, err := NewAwsKmsCryptographicMaterialsProvider(keyURI, nil, nil, WithKMSClient(kms))
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+
+	kek, err := cmp.(*AwsKmsCryptographicMaterialsProvider).kek()
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	ciphertext, err := kek.Encrypt([]byte("keyset"), nil)
+	if err != nil {
+		t.Fatalf("failed to encrypt with KEK: %v", err)
+	}
+	if plaintext, err := kek.Decrypt(ciphertext, nil); err != nil || string(plaintext) != "keyset" {
+		t.Errorf("expected KEK round trip through the configured client, got %q, %v", plaintext, err)
+	}
+}
+
+func TestAwsKmsCryptographicMaterialsProvider_KMSClientFactory(t *testing.T) {
+	const replicaKeyURI = "arn:aws:kms:us-east-1:123456789123:key/02813db0-b23a-420c-94b0-bdceb08e121b"
+	kms, err := fakeawskms.New([]string{keyURI, replicaKeyURI})
+	if err != nil {
+		t.Fatalf("failed to create fake KMS: %v", err)
+	}
+
+	var regions []string
+	factory := func(region string) (kmsiface.KMSAPI, error) {
+		regions = append(regions, region)
+		return kms, nil
+	}
+	cmp, err := NewAwsKmsCryptographicMaterialsProvider(keyURI, nil, nil, WithKMSClient(kms), WithKMSClientFactory(factory))
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+	p := cmp.(*AwsKmsCryptographicMaterialsProvider)
+
+	for _, uri := range []string{keyURI, replicaKeyURI, replicaKeyURI} {
+		if _, err := p.kekFor(uri); err != nil {
+			t.Fatalf("failed to get KEK for %s: %v", uri, err)
+		}
+	}
+
+	if len(regions) != 1 || regions[0] != "us-east-1" {
+		t.Errorf("expected one client built for us-east-1, got %v", regions)
+	}
+}
+
+func TestAwsKmsCryptographicMaterialsProvider_Preflight(t *testing.T) {
+	faults := fakeawskms.NewFaults(1)
+	kms, err := fakeawskms.New([]string{keyURI}, fakeawskms.WithFaults(faults))
+	if err != nil {
+		t.Fatalf("failed to create fake KMS: %v", err)
+	}
+	cmp, err := NewAwsKmsCryptographicMaterialsProvider(keyURI, nil, nil, WithKMSClient(kms))
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+	p := cmp.(*AwsKmsCryptographicMaterialsProvider)
+
+	if missing, err := p.Preflight(context.Background()); err != nil || len(missing) != 0 {
+		t.Fatalf("expected no missing permissions, got %v, %v", missing, err)
+	}
+
+	faults.DisableKey(keyURI)
+	missing, err := p.Preflight(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(missing) != 1 || missing[0].Action != "kms:Encrypt" || missing[0].Resource != keyURI {
+		t.Errorf("expected kms:Encrypt to be reported, got %v", missing)
+	}
+}
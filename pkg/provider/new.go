@@ -0,0 +1,50 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider/store"
+)
+
+// New creates an AwsKmsCryptographicMaterialsProvider like NewAwsKmsCryptographicMaterialsProvider, but checks its
+// configuration eagerly instead of on the first materials request, and returns the concrete provider type. A
+// material store is required, as is a valid KMS key ARN unless the provider is a read-only provider in discovery
+// mode. The key encryption keys for the key ARN and any table key ARNs are set up before New returns.
+func New(keyURI string, encryptionContext map[string]string, materialStore *store.MetaStore, opts ...ProviderOption) (*AwsKmsCryptographicMaterialsProvider, error) {
+	if materialStore == nil {
+		return nil, errors.New("a material store is required")
+	}
+
+	cmp, err := NewAwsKmsCryptographicMaterialsProvider(keyURI, encryptionContext, materialStore, opts...)
+	if err != nil {
+		return nil, err
+	}
+	p := cmp.(*AwsKmsCryptographicMaterialsProvider)
+
+	if p.discovery != nil && len(p.discovery.AccountIDs) == 0 && len(p.discovery.KeyARNs) == 0 {
+		return nil, errors.New("discovery filter allows no KMS keys")
+	}
+	if len(p.allowedAlgorithms) == 0 {
+		return nil, errors.New("no algorithms are allowed")
+	}
+	if p.maxStale < 0 {
+		return nil, errors.New("maximum staleness must not be negative")
+	}
+	if _, ok := p.cache.(StaleMaterialsCache); p.maxStale > 0 && p.cache != nil && !ok {
+		return nil, errors.New("stale-while-revalidate requires a materials cache implementing StaleMaterialsCache")
+	}
+	if p.KMSKeyURI == "" && p.ReadOnly && p.discovery != nil {
+		return p, nil
+	}
+
+	for _, keyURI := range p.keyURIs() {
+		if _, err := splitKeyARN(keyURI); err != nil {
+			return nil, err
+		}
+		if _, err := p.kekFor(keyURI); err != nil {
+			return nil, fmt.Errorf("failed to get KEK: %v", err)
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	fakeawskms "github.com/cloudopsy/dynamodb-encryption-go/internal/fakekms"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider/store"
+)
+
+func TestNew(t *testing.T) {
+	kms, err := fakeawskms.New([]string{keyURI})
+	if err != nil {
+		t.Fatalf("failed to create fake KMS: %v", err)
+	}
+	materialStore := &store.MetaStore{TableName: "meta"}
+
+	testCases := []struct {
+		name          string
+		keyURI        string
+		materialStore *store.MetaStore
+		opts          []ProviderOption
+		valid         bool
+	}{
+		{name: "Valid", keyURI: keyURI, materialStore: materialStore, opts: []ProviderOption{WithKMSClient(kms)}, valid: true},
+		{name: "No material store", keyURI: keyURI, opts: []ProviderOption{WithKMSClient(kms)}},
+		{name: "Invalid key ARN", keyURI: "key/02813db0", materialStore: materialStore},
+		{name: "No key ARN", materialStore: materialStore},
+		{name: "Discovery without key ARN", materialStore: materialStore, opts: []ProviderOption{
+			WithReadOnly(), WithDiscovery(DiscoveryFilter{AccountIDs: []string{"123456789123"}}),
+		}, valid: true},
+		{name: "Empty discovery filter", materialStore: materialStore, opts: []ProviderOption{
+			WithReadOnly(), WithDiscovery(DiscoveryFilter{}),
+		}},
+		{name: "Stale while revalidate", keyURI: keyURI, materialStore: materialStore, opts: []ProviderOption{
+			WithKMSClient(kms), WithCache(time.Minute), WithStaleWhileRevalidate(time.Minute),
+		}, valid: true},
+		{name: "Negative staleness", keyURI: keyURI, materialStore: materialStore, opts: []ProviderOption{
+			WithKMSClient(kms), WithCache(time.Minute), WithStaleWhileRevalidate(-time.Minute),
+		}},
+		{name: "Cache without stale entries", keyURI: keyURI, materialStore: materialStore, opts: []ProviderOption{
+			WithKMSClient(kms), WithMaterialsCache(struct{ MaterialsCache }{NewMemoryCache(time.Minute, 0)}), WithStaleWhileRevalidate(time.Minute),
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := New(tc.keyURI, nil, tc.materialStore, tc.opts...)
+			if tc.valid && (err != nil || p == nil) {
+				t.Errorf("expected a provider, got %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+)
+
+const (
+	// pendingMaterialsTTL is how long materials whose write failed are kept for the next attempt. A failed write may
+	// still have been stored, so retrying with the same materials lets the store return the stored version instead of
+	// creating another.
+	pendingMaterialsTTL = 10 * time.Minute
+
+	// maxPendingMaterials bounds the number of material names with pending materials.
+	maxPendingMaterials = 1024
+)
+
+// pendingMaterials holds new encryption materials whose write to the material store failed, by cache key, until they
+// are stored or expire.
+type pendingMaterials struct {
+	mu      sync.Mutex
+	entries map[string]pendingEntry
+}
+
+type pendingEntry struct {
+	materials materials.CryptographicMaterials
+	expiresAt time.Time
+}
+
+func newPendingMaterials() *pendingMaterials {
+	return &pendingMaterials{entries: make(map[string]pendingEntry)}
+}
+
+// get returns the pending materials for the cache key, or false if there are none or they expired. A nil
+// pendingMaterials holds nothing.
+func (p *pendingMaterials) get(cacheKey string) (materials.CryptographicMaterials, bool) {
+	if p == nil {
+		return nil, false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, ok := p.entries[cacheKey]
+	if !ok {
+		return nil, false
+	}
+	if !time.Now().Before(entry.expiresAt) {
+		delete(p.entries, cacheKey)
+		return nil, false
+	}
+	return entry.materials, true
+}
+
+// put keeps materials whose write failed. Expired entries are dropped first; when the limit is still reached, the
+// materials are not kept, and the next attempt creates new ones.
+func (p *pendingMaterials) put(cacheKey string, m materials.CryptographicMaterials) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.entries[cacheKey]; !ok && len(p.entries) >= maxPendingMaterials {
+		now := time.Now()
+		for key, entry := range p.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(p.entries, key)
+			}
+		}
+		if len(p.entries) >= maxPendingMaterials {
+			return
+		}
+	}
+	p.entries[cacheKey] = pendingEntry{materials: m, expiresAt: time.Now().Add(pendingMaterialsTTL)}
+}
+
+// remove forgets the pending materials for the cache key once they are stored.
+func (p *pendingMaterials) remove(cacheKey string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.entries, cacheKey)
+}
+
+// clear forgets every pending material.
+func (p *pendingMaterials) clear() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.entries = make(map[string]pendingEntry)
+}
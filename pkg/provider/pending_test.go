@@ -0,0 +1,62 @@
+package provider
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+)
+
+func TestPendingMaterials(t *testing.T) {
+	m := materials.NewEncryptionMaterials(map[string]string{"MaterialID": "1"}, nil, nil)
+	pending := newPendingMaterials()
+
+	if _, ok := pending.get("meta/item"); ok {
+		t.Fatalf("expected no pending materials")
+	}
+	pending.put("meta/item", m)
+	got, ok := pending.get("meta/item")
+	if !ok || got != m {
+		t.Fatalf("expected the pending materials to be returned for the next attempt")
+	}
+	if _, ok := pending.get("meta/other"); ok {
+		t.Errorf("expected pending materials to be kept per material name")
+	}
+
+	pending.remove("meta/item")
+	if _, ok := pending.get("meta/item"); ok {
+		t.Errorf("expected stored materials to be forgotten")
+	}
+
+	pending.put("meta/item", m)
+	pending.entries["meta/item"] = pendingEntry{materials: m, expiresAt: time.Now().Add(-time.Second)}
+	if _, ok := pending.get("meta/item"); ok {
+		t.Errorf("expected expired materials to be dropped")
+	}
+
+	var disabled *pendingMaterials
+	disabled.put("meta/item", m)
+	if _, ok := disabled.get("meta/item"); ok {
+		t.Errorf("expected no pending materials without plaintext caching")
+	}
+}
+
+func TestPendingMaterials_MaxEntries(t *testing.T) {
+	m := materials.NewEncryptionMaterials(nil, nil, nil)
+	pending := newPendingMaterials()
+	for i := 0; i < maxPendingMaterials; i++ {
+		pending.put(fmt.Sprintf("meta/%d", i), m)
+	}
+
+	pending.put("meta/full", m)
+	if _, ok := pending.get("meta/full"); ok {
+		t.Errorf("expected materials beyond the limit not to be kept")
+	}
+
+	pending.entries["meta/0"] = pendingEntry{materials: m, expiresAt: time.Now().Add(-time.Second)}
+	pending.put("meta/full", m)
+	if _, ok := pending.get("meta/full"); !ok {
+		t.Errorf("expected expired entries to make room")
+	}
+}
@@ -0,0 +1,81 @@
+package provider
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider/store"
+)
+
+// PreflightChecker is implemented by providers that can check, before taking traffic, that their credentials allow
+// every operation they perform.
+type PreflightChecker interface {
+	Preflight(ctx context.Context) ([]store.MissingPermission, error)
+}
+
+// preflightPlaintext is wrapped and unwrapped with the KEK to check KMS access.
+var preflightPlaintext = []byte("preflight")
+
+// Preflight exercises the meta-table and KMS operations the provider relies on and reports those that failed.
+// Materials are wrapped with kms:Encrypt and unwrapped with kms:Decrypt under the provider's key URI and every table
+// key URI. Read-only providers only need to unwrap, so kms:Decrypt is checked with a ciphertext KMS rejects as
+// invalid, which it only does for callers allowed to decrypt.
+func (p *AwsKmsCryptographicMaterialsProvider) Preflight(ctx context.Context) ([]store.MissingPermission, error) {
+	if p.closed.Load() {
+		return nil, ErrProviderClosed
+	}
+
+	var missing []store.MissingPermission
+	if p.MaterialStore != nil {
+		var err error
+		if missing, err = p.MaterialStore.Preflight(ctx, !p.ReadOnly); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, keyURI := range p.keyURIs() {
+		kekMissing, err := p.preflightKEK(keyURI)
+		if err != nil {
+			return nil, err
+		}
+		missing = append(missing, kekMissing...)
+	}
+	return missing, nil
+}
+
+// preflightKEK checks the KMS operations the provider performs with one KMS key.
+func (p *AwsKmsCryptographicMaterialsProvider) preflightKEK(keyURI string) ([]store.MissingPermission, error) {
+	kek, err := p.kekFor(keyURI)
+	if err != nil {
+		return nil, err
+	}
+	var missing []store.MissingPermission
+	check := func(action string, err error) bool {
+		if err != nil {
+			missing = append(missing, store.MissingPermission{Action: action, Resource: keyURI, Err: err})
+		}
+		return err == nil
+	}
+
+	if p.ReadOnly {
+		if _, err := kek.Decrypt(preflightPlaintext, []byte{}); !isInvalidCiphertext(err) {
+			check("kms:Decrypt", err)
+		}
+		return missing, nil
+	}
+
+	// Keysets are wrapped without associated data
+	ciphertext, err := kek.Encrypt(preflightPlaintext, []byte{})
+	if check("kms:Encrypt", err) {
+		_, err = kek.Decrypt(ciphertext, []byte{})
+		check("kms:Decrypt", err)
+	}
+	return missing, nil
+}
+
+// isInvalidCiphertext reports whether KMS rejected a ciphertext, after authorizing the request.
+func isInvalidCiphertext(err error) bool {
+	var coded interface{ Code() string }
+	return errors.As(err, &coded) && coded.Code() == kms.ErrCodeInvalidCiphertextException
+}
@@ -6,8 +6,47 @@ import (
 	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
 )
 
+// CryptographicMaterialsProvider creates and loads the materials items are encrypted with. The encrypted client
+// passes a context carrying a MaterialsRequest, describing the table, operation and item the materials are for.
 type CryptographicMaterialsProvider interface {
 	EncryptionMaterials(ctx context.Context, materialName string) (materials.CryptographicMaterials, error)
 	DecryptionMaterials(ctx context.Context, materialName string, version int64) (materials.CryptographicMaterials, error)
 	TableName() string
 }
+
+// MaterialDeleter is implemented by providers whose materials can be removed from the material store.
+type MaterialDeleter interface {
+	DeleteMaterials(ctx context.Context, materialName string) error
+}
+
+// MaterialDescriber is implemented by providers that can look up a stored material description without unwrapping
+// its keys, for example to report which KMS key protects it.
+type MaterialDescriber interface {
+	DescribeMaterials(ctx context.Context, materialName string, version int64) (map[string]string, error)
+}
+
+// BatchMaterialsProvider is implemented by providers that can create encryption materials for many material names
+// at once, with fewer material store requests than one EncryptionMaterials call per name. Material names may repeat,
+// for example when a batch writes many items under their table's materials; every occurrence of a name receives the
+// same materials, which are fetched or created once.
+type BatchMaterialsProvider interface {
+	BatchEncryptionMaterials(ctx context.Context, materialNames []string) ([]materials.CryptographicMaterials, error)
+}
+
+// VerificationMaterialsProvider is implemented by providers that can load the public material description, including
+// the PublicKey verifying item signatures, without access to the wrapped keysets.
+type VerificationMaterialsProvider interface {
+	VerificationMaterials(ctx context.Context, materialName string, version int64) (materials.CryptographicMaterials, error)
+}
+
+// MaterialReuser is implemented by providers that can hand out the same encryption materials for several writes,
+// such as with WithMaterialReuse.
+type MaterialReuser interface {
+	ReusesMaterials() bool
+}
+
+// LegacyDescriptionPolicy is implemented by providers that decide whether material descriptions without a
+// DescriptionFormat entry, stored before descriptions were signed, are accepted.
+type LegacyDescriptionPolicy interface {
+	AcceptsLegacyDescriptions() bool
+}
@@ -0,0 +1,132 @@
+package provider
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// RedisClient is the subset of a Redis client the Redis keyset cache needs. Adapters over go-redis or an ElastiCache
+// client implement it in a few lines, so the module does not depend on a particular client library.
+type RedisClient interface {
+	// Get returns the value stored under key. A missing key is not an error.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key with the given expiry.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// SetNX stores value under key with the given expiry only if key does not exist, reporting whether it did.
+	SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	// Del removes the keys.
+	Del(ctx context.Context, keys ...string) error
+}
+
+// RedisCacheOption configures a Redis keyset cache.
+type RedisCacheOption func(*redisKeysetCache)
+
+// WithRedisPrefix sets the prefix of every key the cache writes, so several caches can share a Redis database.
+// The default is "ddbenc:keyset:".
+func WithRedisPrefix(prefix string) RedisCacheOption {
+	return func(c *redisKeysetCache) {
+		c.prefix = prefix
+	}
+}
+
+// WithRedisTTL bounds the expiry of cached keysets to maxTTL, whatever TTL the provider asks for, and shortens each
+// expiry by a random fraction of up to jitter, so keysets cached together do not all expire at once. A maxTTL of
+// zero keeps the provider's TTL.
+func WithRedisTTL(maxTTL time.Duration, jitter float64) RedisCacheOption {
+	return func(c *redisKeysetCache) {
+		c.maxTTL, c.jitter = maxTTL, jitter
+	}
+}
+
+// WithRedisStampedeProtection sets how misses are coordinated. The first process to miss a keyset takes a lock for
+// up to lockTTL while it unwraps the keyset with KMS; the others poll the cache for up to wait before unwrapping it
+// themselves. A zero wait disables the protection. The defaults are a 10 second lock and a 2 second wait.
+func WithRedisStampedeProtection(lockTTL, wait time.Duration) RedisCacheOption {
+	return func(c *redisKeysetCache) {
+		c.lockTTL, c.wait = lockTTL, wait
+	}
+}
+
+// redisKeysetCache is a KeysetCache stored in Redis.
+type redisKeysetCache struct {
+	client  RedisClient
+	prefix  string
+	maxTTL  time.Duration
+	jitter  float64
+	lockTTL time.Duration
+	wait    time.Duration
+	poll    time.Duration
+}
+
+// NewRedisKeysetCache returns a KeysetCache stored in Redis or ElastiCache, for fleets whose per-process caches
+// still call KMS too often. The provider wraps keysets with the cache's key encryption key before they are stored,
+// so Redis never holds plaintext keys. Concurrent misses of the same keyset are coordinated with a lock in Redis, so
+// a cold or expired entry is unwrapped with KMS by one process rather than by the whole fleet.
+func NewRedisKeysetCache(client RedisClient, opts ...RedisCacheOption) KeysetCache {
+	c := &redisKeysetCache{
+		client:  client,
+		prefix:  "ddbenc:keyset:",
+		lockTTL: 10 * time.Second,
+		wait:    2 * time.Second,
+		poll:    50 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Get returns the cached value. On a miss it takes the key's lock, so the caller fetches the value and stores it
+// with Set, or waits for the process holding the lock to store it.
+func (c *redisKeysetCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, ok, err := c.client.Get(ctx, c.prefix+key)
+	if err != nil || ok || c.wait <= 0 {
+		return value, ok, err
+	}
+
+	locked, err := c.client.SetNX(ctx, c.lockKey(key), []byte{1}, c.lockTTL)
+	if err != nil || locked {
+		return nil, false, err
+	}
+
+	timer := time.NewTimer(c.wait)
+	defer timer.Stop()
+	ticker := time.NewTicker(c.poll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		case <-timer.C:
+			return nil, false, nil
+		case <-ticker.C:
+			if value, ok, err := c.client.Get(ctx, c.prefix+key); err != nil || ok {
+				return value, ok, err
+			}
+		}
+	}
+}
+
+// Set stores the value and releases the key's lock.
+func (c *redisKeysetCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if c.maxTTL > 0 && ttl > c.maxTTL {
+		ttl = c.maxTTL
+	}
+	if c.jitter > 0 {
+		ttl -= time.Duration(rand.Float64() * c.jitter * float64(ttl))
+	}
+	if err := c.client.Set(ctx, c.prefix+key, value, ttl); err != nil {
+		return err
+	}
+	return c.client.Del(ctx, c.lockKey(key))
+}
+
+func (c *redisKeysetCache) Delete(ctx context.Context, key string) error {
+	return c.client.Del(ctx, c.prefix+key, c.lockKey(key))
+}
+
+// lockKey names the lock coordinating misses of a key.
+func (c *redisKeysetCache) lockKey(key string) string {
+	return c.prefix + "lock:" + key
+}
@@ -0,0 +1,110 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is an in-memory RedisClient that records the expiry of every key.
+type fakeRedis struct {
+	mu      sync.Mutex
+	values  map[string][]byte
+	expires map[string]time.Duration
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{values: make(map[string][]byte), expires: make(map[string]time.Duration)}
+}
+
+func (r *fakeRedis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	value, ok := r.values[key]
+	return value, ok, nil
+}
+
+func (r *fakeRedis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.values[key], r.expires[key] = value, ttl
+	return nil
+}
+
+func (r *fakeRedis) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.values[key]; ok {
+		return false, nil
+	}
+	r.values[key], r.expires[key] = value, ttl
+	return true, nil
+}
+
+func (r *fakeRedis) Del(ctx context.Context, keys ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range keys {
+		delete(r.values, key)
+	}
+	return nil
+}
+
+func TestRedisKeysetCache(t *testing.T) {
+	ctx := context.Background()
+	redis := newFakeRedis()
+	cache := NewRedisKeysetCache(redis, WithRedisPrefix("test:"), WithRedisTTL(time.Minute, 0.1), WithRedisStampedeProtection(time.Second, 500*time.Millisecond))
+
+	// The first miss takes the lock and returns at once, so the caller unwraps the keyset
+	if _, ok, err := cache.Get(ctx, "meta/material"); ok || err != nil {
+		t.Fatalf("expected a miss, got %v, %v", ok, err)
+	}
+	if _, locked := redis.values["test:lock:meta/material"]; !locked {
+		t.Fatalf("expected the miss to take the lock")
+	}
+
+	// A concurrent miss waits for the lock holder to store the keyset
+	result := make(chan []byte)
+	go func() {
+		value, _, _ := cache.Get(ctx, "meta/material")
+		result <- value
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if err := cache.Set(ctx, "meta/material", []byte("wrapped"), time.Hour); err != nil {
+		t.Fatalf("failed to set entry: %v", err)
+	}
+	if value := <-result; string(value) != "wrapped" {
+		t.Errorf("expected the waiting miss to receive the stored keyset, got %q", value)
+	}
+
+	if _, locked := redis.values["test:lock:meta/material"]; locked {
+		t.Errorf("expected Set to release the lock")
+	}
+	if ttl := redis.expires["test:meta/material"]; ttl > time.Minute || ttl < 54*time.Second {
+		t.Errorf("expected the TTL to be capped at a minute less up to 10%% jitter, got %v", ttl)
+	}
+
+	if err := cache.Delete(ctx, "meta/material"); err != nil {
+		t.Fatalf("failed to delete entry: %v", err)
+	}
+	if len(redis.values) != 0 {
+		t.Errorf("expected no keys after delete, got %v", redis.values)
+	}
+}
+
+func TestRedisKeysetCache_WaitTimeout(t *testing.T) {
+	ctx := context.Background()
+	redis := newFakeRedis()
+	redis.values["ddbenc:keyset:lock:meta/material"] = []byte{1}
+	cache := NewRedisKeysetCache(redis, WithRedisStampedeProtection(time.Second, 100*time.Millisecond))
+
+	// The lock holder never stores the keyset, so the miss gives up waiting and unwraps it itself
+	start := time.Now()
+	if _, ok, err := cache.Get(ctx, "meta/material"); ok || err != nil {
+		t.Fatalf("expected a miss, got %v, %v", ok, err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected the miss to wait for the lock holder, returned after %v", elapsed)
+	}
+}
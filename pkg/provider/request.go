@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// MaterialsRequest describes the request materials are fetched for. The encrypted client attaches it to the context
+// passed to every provider method, so providers can base decisions such as key choice or caching scope on the table,
+// operation and item rather than parsing material names. Applications can attach their own metadata, for example a
+// tenant or caller identity, with ContextWithMaterialsRequest before calling the client.
+type MaterialsRequest struct {
+	// TableName is the table of the item materials are fetched for.
+	TableName string
+	// Operation names the client operation, such as PutItem, Query or TransactWriteItems.
+	Operation string
+	// Key holds the primary key attributes of the item. It is unset when materials for several items are fetched
+	// with a single call, such as BatchEncryptionMaterials.
+	Key map[string]types.AttributeValue
+	// Metadata holds application entries attached to the request.
+	Metadata map[string]string
+}
+
+type materialsRequestKey struct{}
+
+// ContextWithMaterialsRequest returns a context carrying the request merged over any request ctx already carries.
+// Fields left unset keep their existing values, and metadata entries are added to the existing ones.
+func ContextWithMaterialsRequest(ctx context.Context, request MaterialsRequest) context.Context {
+	merged, _ := MaterialsRequestFromContext(ctx)
+	if request.TableName != "" {
+		merged.TableName = request.TableName
+	}
+	if request.Operation != "" {
+		merged.Operation = request.Operation
+	}
+	if request.Key != nil {
+		merged.Key = request.Key
+	}
+	if len(request.Metadata) > 0 {
+		metadata := make(map[string]string, len(merged.Metadata)+len(request.Metadata))
+		for key, value := range merged.Metadata {
+			metadata[key] = value
+		}
+		for key, value := range request.Metadata {
+			metadata[key] = value
+		}
+		merged.Metadata = metadata
+	}
+	return context.WithValue(ctx, materialsRequestKey{}, merged)
+}
+
+// MaterialsRequestFromContext returns the request carried by ctx, if any.
+func MaterialsRequestFromContext(ctx context.Context) (MaterialsRequest, bool) {
+	request, ok := ctx.Value(materialsRequestKey{}).(MaterialsRequest)
+	return request, ok
+}
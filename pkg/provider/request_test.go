@@ -0,0 +1,42 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestContextWithMaterialsRequest(t *testing.T) {
+	key := map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: "1"}}
+
+	ctx := context.Background()
+	if _, ok := MaterialsRequestFromContext(ctx); ok {
+		t.Errorf("expected no request on an empty context")
+	}
+
+	ctx = ContextWithMaterialsRequest(ctx, MaterialsRequest{Operation: "Query", Metadata: map[string]string{"tenant": "acme"}})
+	outer := ctx
+	ctx = ContextWithMaterialsRequest(ctx, MaterialsRequest{TableName: "users", Key: key, Metadata: map[string]string{"caller": "api"}})
+
+	request, ok := MaterialsRequestFromContext(ctx)
+	if !ok {
+		t.Fatalf("expected a request")
+	}
+	expected := MaterialsRequest{
+		TableName: "users",
+		Operation: "Query",
+		Key:       key,
+		Metadata:  map[string]string{"tenant": "acme", "caller": "api"},
+	}
+	if diff := cmp.Diff(expected, request, cmpopts.IgnoreUnexported(types.AttributeValueMemberS{})); diff != "" {
+		t.Errorf("unexpected request (-want +got):\n%s", diff)
+	}
+
+	// Merging never modifies the request of the parent context
+	if parent, _ := MaterialsRequestFromContext(outer); len(parent.Metadata) != 1 || parent.TableName != "" {
+		t.Errorf("expected the parent request to be unchanged, got %+v", parent)
+	}
+}
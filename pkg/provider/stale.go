@@ -0,0 +1,103 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+)
+
+// staleRefreshTimeout bounds a background refresh of stale decryption materials.
+const staleRefreshTimeout = time.Minute
+
+// StaleMaterialsCache is a MaterialsCache that can also return entries that expired recently, which
+// WithStaleWhileRevalidate requires. The caches returned by NewMemoryCache implement it.
+type StaleMaterialsCache interface {
+	MaterialsCache
+	// GetStale returns the cached materials unless they expired more than maxStale ago, and whether they expired.
+	GetStale(materialName string, version int64, maxStale time.Duration) (m materials.CryptographicMaterials, expired bool, ok bool)
+}
+
+// WithStaleWhileRevalidate serves cached decryption materials for up to maxStale after they expire, while they are
+// fetched again in the background, so meta-table and KMS latency spikes stay off the reads that find them expired.
+// Materials are never served more than the cache TTL plus maxStale after they were fetched; older entries are
+// fetched on the read as usual. The "latest" version of a material name can therefore lag behind new versions
+// created by other processes for as long. A failed refresh is counted and retried by the next read.
+//
+// The materials cache must implement StaleMaterialsCache, which New checks. The option has no effect together with
+// WithoutPlaintextCaching.
+func WithStaleWhileRevalidate(maxStale time.Duration) ProviderOption {
+	return func(p *AwsKmsCryptographicMaterialsProvider) {
+		p.maxStale = maxStale
+	}
+}
+
+// revalidation serves stale cache entries and refreshes them in the background, at most once at a time per entry.
+type revalidation struct {
+	cache    StaleMaterialsCache
+	maxStale time.Duration
+
+	mu       sync.Mutex
+	inflight map[revalidationKey]bool
+	closed   bool
+}
+
+type revalidationKey struct {
+	cacheKey string
+	version  int64
+}
+
+func newRevalidation(cache StaleMaterialsCache, maxStale time.Duration) *revalidation {
+	return &revalidation{
+		cache:    cache,
+		maxStale: maxStale,
+		inflight: make(map[revalidationKey]bool),
+	}
+}
+
+// get returns the materials cached under cacheKey and version, even if they expired less than maxStale ago. Expired
+// materials are refreshed with fetch in the background, detached from the cancellation of ctx.
+func (r *revalidation) get(ctx context.Context, counters *providerCounters, cacheKey string, version int64, fetch func(context.Context) (materials.CryptographicMaterials, error)) (materials.CryptographicMaterials, bool) {
+	cached, expired, ok := r.cache.GetStale(cacheKey, version, r.maxStale)
+	if !ok {
+		return nil, false
+	}
+	if !expired {
+		return cached, true
+	}
+	counters.add(MetricStaleMaterialsServed, 1)
+
+	key := revalidationKey{cacheKey: cacheKey, version: version}
+	r.mu.Lock()
+	if r.inflight[key] {
+		r.mu.Unlock()
+		return cached, true
+	}
+	r.inflight[key] = true
+	r.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), staleRefreshTimeout)
+		defer cancel()
+		refreshed, err := fetch(ctx)
+		if err != nil {
+			counters.add(MetricStaleRefreshErrors, 1)
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.inflight, key)
+		if err == nil && !r.closed {
+			r.cache.Put(cacheKey, version, refreshed)
+		}
+	}()
+	return cached, true
+}
+
+// close stops refreshes still in flight from caching their materials, so the cache can be cleared for good.
+func (r *revalidation) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+}
@@ -0,0 +1,129 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+)
+
+// waitFor polls condition until it holds or a second has passed.
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRevalidation(t *testing.T) {
+	old := materials.NewDecryptionMaterials(map[string]string{"v": "old"}, nil)
+	refreshed := materials.NewDecryptionMaterials(map[string]string{"v": "new"}, nil)
+
+	testCases := []struct {
+		name     string
+		ttl      time.Duration
+		maxStale time.Duration
+		fetchErr error
+		hit      bool
+		stale    bool
+		want     materials.CryptographicMaterials
+	}{
+		{name: "Fresh", ttl: time.Minute, maxStale: time.Minute, hit: true, want: old},
+		{name: "Stale", ttl: -time.Second, maxStale: time.Minute, hit: true, stale: true, want: refreshed},
+		{name: "Refresh error", ttl: -time.Second, maxStale: time.Minute, fetchErr: errors.New("throttled"), hit: true, stale: true, want: old},
+		{name: "Too stale", ttl: -time.Second, maxStale: time.Millisecond},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cache := newMaterialsCache(tc.ttl, 0)
+			cache.Put("material", 0, old)
+			r := newRevalidation(cache, tc.maxStale)
+			var counters providerCounters
+
+			release := make(chan struct{})
+			var fetches atomic.Int32
+			fetch := func(ctx context.Context) (materials.CryptographicMaterials, error) {
+				fetches.Add(1)
+				<-release
+				return refreshed, tc.fetchErr
+			}
+
+			// A canceled request must not cancel the refresh it started
+			ctx, cancel := context.WithCancel(context.Background())
+			got, ok := r.get(ctx, &counters, "material", 0, fetch)
+			cancel()
+			if ok != tc.hit {
+				t.Fatalf("expected hit %v, got %v", tc.hit, ok)
+			}
+			if !tc.hit {
+				if cache.Len() != 0 {
+					t.Errorf("expected the entry to be dropped")
+				}
+				return
+			}
+			if got != old {
+				t.Errorf("expected the cached materials")
+			}
+			if _, ok := r.get(context.Background(), &counters, "material", 0, fetch); !ok {
+				t.Errorf("expected a second hit while refreshing")
+			}
+			close(release)
+
+			if !tc.stale {
+				if fetches.Load() != 0 || counters.staleMaterialsServed.Load() != 0 {
+					t.Errorf("expected fresh materials to be served without a refresh")
+				}
+				return
+			}
+			waitFor(t, func() bool {
+				r.mu.Lock()
+				defer r.mu.Unlock()
+				return len(r.inflight) == 0
+			})
+			if fetches.Load() != 1 {
+				t.Errorf("expected 1 refresh, got %d", fetches.Load())
+			}
+			if served := counters.staleMaterialsServed.Load(); served != 2 {
+				t.Errorf("expected 2 stale materials served, got %d", served)
+			}
+			if tc.fetchErr != nil && counters.staleRefreshErrors.Load() != 1 {
+				t.Errorf("expected the failed refresh to be counted")
+			}
+			if cached, _, _ := cache.GetStale("material", 0, tc.maxStale); cached != tc.want {
+				t.Errorf("expected the %s materials to be cached after the refresh", tc.want.MaterialDescription()["v"])
+			}
+		})
+	}
+
+	t.Run("Closed", func(t *testing.T) {
+		cache := newMaterialsCache(-time.Second, 0)
+		cache.Put("material", 0, old)
+		r := newRevalidation(cache, time.Minute)
+
+		release := make(chan struct{})
+		r.get(context.Background(), &providerCounters{}, "material", 0, func(ctx context.Context) (materials.CryptographicMaterials, error) {
+			<-release
+			return refreshed, nil
+		})
+		r.close()
+		cache.Clear()
+		close(release)
+
+		waitFor(t, func() bool {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			return len(r.inflight) == 0
+		})
+		if cache.Len() != 0 {
+			t.Errorf("expected a refresh finishing after close not to be cached")
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package provider
+
+import (
+	"sync/atomic"
+)
+
+// Metric names reported to a MetricsHook.
+const (
+	MetricMaterialsCreated           = "MaterialsCreated"
+	MetricMaterialsReused            = "MaterialsReused"
+	MetricDecryptionMaterialsFetched = "DecryptionMaterialsFetched"
+	MetricCacheHits                  = "CacheHits"
+	MetricCacheMisses                = "CacheMisses"
+	MetricKMSCalls                   = "KMSCalls"
+	MetricSignatureVerifications     = "SignatureVerifications"
+	MetricDataKeyEncryptions         = "DataKeyEncryptions"
+	MetricKeyUsageRejections         = "KeyUsageRejections"
+	MetricKeysetCacheHits            = "KeysetCacheHits"
+	MetricKeysetCacheMisses          = "KeysetCacheMisses"
+	MetricKeysetCacheErrors          = "KeysetCacheErrors"
+	MetricStaleMaterialsServed       = "StaleMaterialsServed"
+	MetricStaleRefreshErrors         = "StaleRefreshErrors"
+)
+
+// MetricsHook is invoked every time a provider counter changes.
+type MetricsHook func(metric string, delta int64)
+
+// ProviderStats is a point-in-time snapshot of a provider's usage counters.
+type ProviderStats struct {
+	MaterialsCreated           int64
+	MaterialsReused            int64
+	DecryptionMaterialsFetched int64
+	CacheHits                  int64
+	CacheMisses                int64
+	KMSCalls                   int64
+	SignatureVerifications     int64
+	DataKeyEncryptions         int64 // Encryptions under reused data keys.
+	KeyUsageRejections         int64 // Encryptions refused because a reused data key reached its hard limit.
+	KeysetCacheHits            int64 // Keysets read from a KeysetCache instead of being unwrapped with KMS.
+	KeysetCacheMisses          int64
+	KeysetCacheErrors          int64 // Keyset cache requests that failed and were treated as misses.
+	StaleMaterialsServed       int64 // Expired materials served while they were refreshed in the background.
+	StaleRefreshErrors         int64 // Background refreshes of expired materials that failed.
+	CachedMaterials            int64 // Entries held by the materials cache when the snapshot was taken.
+	MetaStoreThrottles         int64 // Meta-table requests throttled, including ones that succeeded on retry.
+}
+
+// StatsProvider is implemented by providers that track usage statistics.
+type StatsProvider interface {
+	Stats() ProviderStats
+}
+
+// providerCounters holds the live counters behind ProviderStats.
+type providerCounters struct {
+	materialsCreated           atomic.Int64
+	materialsReused            atomic.Int64
+	decryptionMaterialsFetched atomic.Int64
+	cacheHits                  atomic.Int64
+	cacheMisses                atomic.Int64
+	kmsCalls                   atomic.Int64
+	signatureVerifications     atomic.Int64
+	dataKeyEncryptions         atomic.Int64
+	keyUsageRejections         atomic.Int64
+	keysetCacheHits            atomic.Int64
+	keysetCacheMisses          atomic.Int64
+	keysetCacheErrors          atomic.Int64
+	staleMaterialsServed       atomic.Int64
+	staleRefreshErrors         atomic.Int64
+	hook                       MetricsHook
+}
+
+func (c *providerCounters) add(metric string, delta int64) {
+	switch metric {
+	case MetricMaterialsCreated:
+		c.materialsCreated.Add(delta)
+	case MetricMaterialsReused:
+		c.materialsReused.Add(delta)
+	case MetricDecryptionMaterialsFetched:
+		c.decryptionMaterialsFetched.Add(delta)
+	case MetricCacheHits:
+		c.cacheHits.Add(delta)
+	case MetricCacheMisses:
+		c.cacheMisses.Add(delta)
+	case MetricKMSCalls:
+		c.kmsCalls.Add(delta)
+	case MetricSignatureVerifications:
+		c.signatureVerifications.Add(delta)
+	case MetricDataKeyEncryptions:
+		c.dataKeyEncryptions.Add(delta)
+	case MetricKeyUsageRejections:
+		c.keyUsageRejections.Add(delta)
+	case MetricKeysetCacheHits:
+		c.keysetCacheHits.Add(delta)
+	case MetricKeysetCacheMisses:
+		c.keysetCacheMisses.Add(delta)
+	case MetricKeysetCacheErrors:
+		c.keysetCacheErrors.Add(delta)
+	case MetricStaleMaterialsServed:
+		c.staleMaterialsServed.Add(delta)
+	case MetricStaleRefreshErrors:
+		c.staleRefreshErrors.Add(delta)
+	}
+
+	if c.hook != nil {
+		c.hook(metric, delta)
+	}
+}
+
+func (c *providerCounters) snapshot() ProviderStats {
+	return ProviderStats{
+		MaterialsCreated:           c.materialsCreated.Load(),
+		MaterialsReused:            c.materialsReused.Load(),
+		DecryptionMaterialsFetched: c.decryptionMaterialsFetched.Load(),
+		CacheHits:                  c.cacheHits.Load(),
+		CacheMisses:                c.cacheMisses.Load(),
+		KMSCalls:                   c.kmsCalls.Load(),
+		SignatureVerifications:     c.signatureVerifications.Load(),
+		DataKeyEncryptions:         c.dataKeyEncryptions.Load(),
+		KeyUsageRejections:         c.keyUsageRejections.Load(),
+		KeysetCacheHits:            c.keysetCacheHits.Load(),
+		KeysetCacheMisses:          c.keysetCacheMisses.Load(),
+		KeysetCacheErrors:          c.keysetCacheErrors.Load(),
+		StaleMaterialsServed:       c.staleMaterialsServed.Load(),
+		StaleRefreshErrors:         c.staleRefreshErrors.Load(),
+	}
+}
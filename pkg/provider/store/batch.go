@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+)
+
+// maxTransactItems is the maximum number of actions DynamoDB accepts in a single TransactWriteItems call.
+const maxTransactItems = 100
+
+// MaterialWrite is a material to store with StoreNewMaterials.
+type MaterialWrite struct {
+	MaterialName string
+	Material     materials.CryptographicMaterials
+}
+
+// StoreNewMaterials stores several materials and returns the version each was stored under, in order. Instead of
+// one transaction per material, materials are written in transactions of up to 100 conditional puts, which cuts
+// the number of meta-table write requests for bulk imports. A material name may appear more than once; its writes
+// get consecutive versions. When a transaction conflicts with a concurrent writer, its materials are stored one at
+// a time with StoreNewMaterialVersion instead.
+func (s *MetaStore) StoreNewMaterials(ctx context.Context, writes []MaterialWrite) ([]int64, error) {
+	tableName, err := s.ResolveTableName(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptions := make([]types.AttributeValue, len(writes))
+	for i, write := range writes {
+		descriptionJSON, err := json.Marshal(write.Material.MaterialDescription())
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize material description: %v", err)
+		}
+		if descriptions[i], err = s.encodeDescription(descriptionJSON); err != nil {
+			return nil, err
+		}
+	}
+
+	versions := make([]int64, len(writes))
+	for start := 0; start < len(writes); start += maxTransactItems {
+		end := min(start+maxTransactItems, len(writes))
+
+		err := s.storeMaterialChunk(ctx, tableName, writes[start:end], descriptions[start:end], versions[start:end])
+		if isConflict(err) {
+			for i := start; i < end; i++ {
+				if versions[i], err = s.StoreNewMaterialVersion(ctx, writes[i].MaterialName, writes[i].Material); err != nil {
+					return nil, err
+				}
+			}
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	return versions, nil
+}
+
+// storeMaterialChunk writes up to maxTransactItems materials in one transaction, filling in their versions.
+func (s *MetaStore) storeMaterialChunk(ctx context.Context, tableName string, writes []MaterialWrite, descriptions []types.AttributeValue, versions []int64) error {
+	release, err := s.acquireWrite(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return s.retry.do(ctx, "StoreNewMaterials", func() error {
+		next := make(map[string]int64)
+		transactItems := make([]types.TransactWriteItem, 0, len(writes))
+		for i, write := range writes {
+			materialID := write.Material.MaterialDescription()[MaterialIDKey]
+			version, ok := next[write.MaterialName]
+			if !ok {
+				latest, latestID, err := s.getLastVersion(ctx, tableName, write.MaterialName)
+				if err != nil {
+					return err
+				}
+				// The materials were stored by an earlier attempt
+				if materialID != "" && latestID == materialID {
+					versions[i] = latest
+					next[write.MaterialName] = latest + 1
+					continue
+				}
+				version = latest + 1
+			}
+			next[write.MaterialName] = version + 1
+
+			versions[i] = version
+			transactItems = append(transactItems, materialPut(tableName, s.partitionKey(write.MaterialName, version), version, materialID, descriptions[i]))
+		}
+		if len(transactItems) == 0 {
+			return nil
+		}
+
+		_, err := s.DynamoDBClient.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: transactItems,
+		})
+		if err != nil {
+			return fmt.Errorf("transaction failed: %w", err)
+		}
+		return nil
+	})
+}
+
+// isConflict reports whether a transaction was canceled because another writer stored the same material version
+// or was writing the same items concurrently.
+func isConflict(err error) bool {
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) {
+		return false
+	}
+	for _, reason := range canceled.CancellationReasons {
+		if reason.Code != nil && (*reason.Code == "ConditionalCheckFailed" || *reason.Code == "TransactionConflict") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestIsConflict(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		conflict bool
+	}{
+		{"nil", nil, false},
+		{"condition failed", fmt.Errorf("transaction failed: %w", &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{{Code: aws.String("None")}, {Code: aws.String("ConditionalCheckFailed")}},
+		}), true},
+		{"throttled", &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{{Code: aws.String("ThrottlingError")}},
+		}, false},
+		{"other", errors.New("access denied"), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isConflict(tc.err); got != tc.conflict {
+				t.Errorf("expected %v, got %v", tc.conflict, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/compression"
+)
+
+// maxDescriptionSize bounds the size of a decompressed material description. Descriptions stored uncompressed are
+// limited to DynamoDB's 400 KB item size, so anything larger was not written by a MetaStore.
+const maxDescriptionSize = 1 << 20
+
+// WithDescriptionCompression compresses the material descriptions and verification keys the store writes with the
+// compressor registered under id, typically compression.Zstd. Their wrapped keysets and public keys make up most of a
+// meta-table item, so compression cuts both the storage and the read capacity used to retrieve materials.
+//
+// Compressed descriptions are stored as a binary MaterialDescription attribute holding the compression ID in its first
+// byte, followed by the compressed JSON. Descriptions that would not get smaller are stored as JSON strings, as are
+// all descriptions without this option. Both forms are read regardless of the option, but versions of this package
+// from before compression support can not read compressed descriptions.
+func WithDescriptionCompression(id compression.ID) MetaStoreOption {
+	return func(s *MetaStore) error {
+		if id != compression.None {
+			if _, err := compression.Lookup(id); err != nil {
+				return err
+			}
+		}
+		s.compression = id
+		return nil
+	}
+}
+
+// encodeDescription returns the MaterialDescription attribute storing a serialized description, compressed when the
+// store is configured to compress descriptions and that makes it smaller.
+func (s *MetaStore) encodeDescription(descriptionJSON []byte) (types.AttributeValue, error) {
+	if s.compression == compression.None {
+		return &types.AttributeValueMemberS{Value: string(descriptionJSON)}, nil
+	}
+	compressor, err := compression.Lookup(s.compression)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(byte(s.compression))
+	w, err := compressor.NewWriter(&buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compressor: %w", err)
+	}
+	if _, err := w.Write(descriptionJSON); err != nil {
+		return nil, fmt.Errorf("failed to compress material description: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress material description: %w", err)
+	}
+
+	if buf.Len() >= len(descriptionJSON) {
+		return &types.AttributeValueMemberS{Value: string(descriptionJSON)}, nil
+	}
+	return &types.AttributeValueMemberB{Value: buf.Bytes()}, nil
+}
+
+// decodeDescription returns the serialized description stored in a MaterialDescription attribute, decompressing it
+// when it was stored compressed.
+func decodeDescription(attr types.AttributeValue) ([]byte, error) {
+	switch v := attr.(type) {
+	case *types.AttributeValueMemberS:
+		return []byte(v.Value), nil
+	case *types.AttributeValueMemberB:
+		if len(v.Value) == 0 {
+			return nil, errors.New("empty MaterialDescription attribute")
+		}
+		id := compression.ID(v.Value[0])
+		if id == compression.None {
+			return v.Value[1:], nil
+		}
+		compressor, err := compression.Lookup(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress material description: %w", err)
+		}
+		r, err := compressor.NewReader(bytes.NewReader(v.Value[1:]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress material description: %w", err)
+		}
+		defer r.Close()
+
+		descriptionJSON, err := io.ReadAll(io.LimitReader(r, maxDescriptionSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress material description: %w", err)
+		}
+		if len(descriptionJSON) > maxDescriptionSize {
+			return nil, fmt.Errorf("decompressed material description exceeds %d bytes", maxDescriptionSize)
+		}
+		return descriptionJSON, nil
+	default:
+		return nil, fmt.Errorf("unexpected type for MaterialDescription attribute")
+	}
+}
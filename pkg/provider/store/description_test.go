@@ -0,0 +1,94 @@
+package store
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/compression"
+)
+
+func TestDescriptionCompression(t *testing.T) {
+	if _, err := NewMetaStore(nil, "meta", WithDescriptionCompression(200)); err == nil {
+		t.Errorf("expected error for an unregistered compression ID")
+	}
+
+	// A wrapped keyset's JSON is repetitive enough to compress, a short description is not
+	keyset := base64.StdEncoding.EncodeToString([]byte(strings.Repeat(`{"primaryKeyId":1,"key":[{"keyData":{"typeUrl":"type.googleapis.com/google.crypto.tink.AesGcmKey"}}]}`, 8)))
+	large, err := json.Marshal(map[string]string{"WrappedKeyset": keyset, "Algorithm": "AES256_GCM"})
+	if err != nil {
+		t.Fatalf("failed to serialize description: %v", err)
+	}
+	small := []byte(`{"A":"B"}`)
+
+	testCases := []struct {
+		name        string
+		compression compression.ID
+		description []byte
+		compressed  bool
+	}{
+		{"uncompressed", compression.None, large, false},
+		{"zstd", compression.Zstd, large, true},
+		{"gzip", compression.Gzip, large, true},
+		{"not smaller", compression.Zstd, small, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := NewMetaStore(nil, "meta", WithDescriptionCompression(tc.compression))
+			if err != nil {
+				t.Fatalf("failed to create store: %v", err)
+			}
+			attr, err := s.encodeDescription(tc.description)
+			if err != nil {
+				t.Fatalf("failed to encode description: %v", err)
+			}
+
+			switch v := attr.(type) {
+			case *types.AttributeValueMemberB:
+				if !tc.compressed {
+					t.Fatalf("expected an uncompressed description")
+				}
+				if compression.ID(v.Value[0]) != tc.compression {
+					t.Errorf("expected flag byte %d, got %d", tc.compression, v.Value[0])
+				}
+				if len(v.Value) >= len(tc.description) {
+					t.Errorf("expected fewer than %d bytes, got %d", len(tc.description), len(v.Value))
+				}
+			case *types.AttributeValueMemberS:
+				if tc.compressed {
+					t.Fatalf("expected a compressed description")
+				}
+			}
+
+			got, err := decodeDescription(attr)
+			if err != nil {
+				t.Fatalf("failed to decode description: %v", err)
+			}
+			if !bytes.Equal(got, tc.description) {
+				t.Errorf("expected %s, got %s", tc.description, got)
+			}
+		})
+	}
+
+	invalid := []struct {
+		name string
+		attr types.AttributeValue
+	}{
+		{"empty", &types.AttributeValueMemberB{}},
+		{"unknown compression", &types.AttributeValueMemberB{Value: []byte{200, 1, 2}}},
+		{"corrupted", &types.AttributeValueMemberB{Value: []byte{byte(compression.Zstd), 1, 2}}},
+		{"wrong type", &types.AttributeValueMemberN{Value: "1"}},
+	}
+
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := decodeDescription(tc.attr); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
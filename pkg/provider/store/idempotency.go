@@ -0,0 +1,23 @@
+package store
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// MaterialIDKey is the material description entry holding the ID the client generated for new materials. The store
+// also writes it as the MaterialID attribute of the material's item, so a write retried after an ambiguous failure
+// finds the version it already stored, as the latest version of the material name, and returns it instead of storing
+// the materials again under a second version.
+const MaterialIDKey = "MaterialID"
+
+// NewMaterialID returns a random UUID to identify new materials with under MaterialIDKey.
+func NewMaterialID() (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", fmt.Errorf("failed to generate material ID: %v", err)
+	}
+	id[6] = id[6]&0x0f | 0x40 // Version 4
+	id[8] = id[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16]), nil
+}
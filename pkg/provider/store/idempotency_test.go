@@ -0,0 +1,52 @@
+package store
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestNewMaterialID(t *testing.T) {
+	format := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id, err := NewMaterialID()
+		if err != nil {
+			t.Fatalf("failed to generate material ID: %v", err)
+		}
+		if !format.MatchString(id) {
+			t.Errorf("expected a version 4 UUID, got %s", id)
+		}
+		if seen[id] {
+			t.Fatalf("duplicate material ID %s", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestMaterialPut_MaterialID(t *testing.T) {
+	testCases := []struct {
+		name       string
+		materialID string
+	}{
+		{name: "With material ID", materialID: "c0ffee00-0000-4000-8000-000000000000"},
+		{name: "Without material ID"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			put := materialPut("meta", "material", 2, tc.materialID, &types.AttributeValueMemberS{Value: "{}"})
+			value, ok := put.Put.Item["MaterialID"]
+			if tc.materialID == "" {
+				if ok {
+					t.Errorf("expected no MaterialID attribute, got %v", value)
+				}
+				return
+			}
+			if id, ok := value.(*types.AttributeValueMemberS); !ok || id.Value != tc.materialID {
+				t.Errorf("expected MaterialID %s, got %v", tc.materialID, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ErrInvalidMetaTable is returned by New when the meta table is not usable as a material store.
+var ErrInvalidMetaTable = errors.New("invalid meta table")
+
+// New creates a MetaStore like NewMetaStore, but checks its configuration eagerly instead of on the first material
+// operation. The meta table, and the verification table if one is configured, must exist, be usable, and have the
+// key schema created by CreateTableIfNotExists. Tables selected per request by a table name resolver can not be
+// checked in advance.
+func New(ctx context.Context, dynamoDBClient *dynamodb.Client, tableName string, opts ...MetaStoreOption) (*MetaStore, error) {
+	if dynamoDBClient == nil {
+		return nil, errors.New("a DynamoDB client is required")
+	}
+
+	s, err := NewMetaStore(dynamoDBClient, tableName, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.TableNameResolver == nil {
+		if s.TableName == "" {
+			return nil, fmt.Errorf("%w: a table name or table name resolver is required", ErrInvalidMetaTable)
+		}
+		if err := checkMetaTable(ctx, s.DynamoDBClient, s.TableName); err != nil {
+			return nil, err
+		}
+	}
+	if s.VerificationTableName != "" {
+		if err := checkMetaTable(ctx, s.DynamoDBClient, s.VerificationTableName); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// checkMetaTable verifies that a table exists, is not being deleted, and is keyed by MaterialName and Version.
+func checkMetaTable(ctx context.Context, client TableAPI, tableName string) error {
+	output, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe meta table %s: %w", tableName, err)
+	}
+
+	table := output.Table
+	if table.TableStatus == types.TableStatusDeleting || table.TableStatus == types.TableStatusArchived {
+		return fmt.Errorf("%w: table %s is %s", ErrInvalidMetaTable, tableName, table.TableStatus)
+	}
+
+	expected := map[string]types.KeyType{"MaterialName": types.KeyTypeHash, "Version": types.KeyTypeRange}
+	if len(table.KeySchema) != len(expected) {
+		return fmt.Errorf("%w: table %s must be keyed by MaterialName and Version", ErrInvalidMetaTable, tableName)
+	}
+	for _, element := range table.KeySchema {
+		if keyType, ok := expected[aws.ToString(element.AttributeName)]; !ok || keyType != element.KeyType {
+			return fmt.Errorf("%w: table %s must be keyed by MaterialName and Version", ErrInvalidMetaTable, tableName)
+		}
+	}
+	return nil
+}
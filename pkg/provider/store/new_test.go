@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// describeTableAPI describes a fixed table.
+type describeTableAPI struct {
+	TableAPI
+	table *types.TableDescription
+}
+
+func (c *describeTableAPI) DescribeTable(ctx context.Context, input *dynamodb.DescribeTableInput, opts ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	if c.table == nil {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("table not found")}
+	}
+	return &dynamodb.DescribeTableOutput{Table: c.table}, nil
+}
+
+func TestCheckMetaTable(t *testing.T) {
+	metaKeySchema := []types.KeySchemaElement{
+		{AttributeName: aws.String("MaterialName"), KeyType: types.KeyTypeHash},
+		{AttributeName: aws.String("Version"), KeyType: types.KeyTypeRange},
+	}
+
+	testCases := []struct {
+		name  string
+		table *types.TableDescription
+		err   error
+	}{
+		{name: "Valid", table: &types.TableDescription{TableStatus: types.TableStatusActive, KeySchema: metaKeySchema}},
+		{name: "Missing", err: &types.ResourceNotFoundException{}},
+		{name: "Deleting", table: &types.TableDescription{TableStatus: types.TableStatusDeleting, KeySchema: metaKeySchema}, err: ErrInvalidMetaTable},
+		{name: "Wrong key schema", table: &types.TableDescription{
+			TableStatus: types.TableStatusActive,
+			KeySchema:   []types.KeySchemaElement{{AttributeName: aws.String("ID"), KeyType: types.KeyTypeHash}},
+		}, err: ErrInvalidMetaTable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkMetaTable(context.Background(), &describeTableAPI{table: tc.table}, "meta")
+			switch expected := tc.err.(type) {
+			case nil:
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			case *types.ResourceNotFoundException:
+				if !errors.As(err, &expected) {
+					t.Errorf("Expected a ResourceNotFoundException, got %v", err)
+				}
+			default:
+				if !errors.Is(err, tc.err) {
+					t.Errorf("Expected error %v, got %v", tc.err, err)
+				}
+			}
+		})
+	}
+}
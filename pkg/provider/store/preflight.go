@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// PreflightMaterialName is the material name Preflight stores and deletes to check write access.
+const PreflightMaterialName = "__preflight"
+
+// MissingPermission reports an operation a preflight check could not perform. Err holds the error the operation
+// failed with, which is usually an access denial but can also be, for example, a missing table or a disabled key.
+type MissingPermission struct {
+	Action   string // IAM action, such as "dynamodb:Query" or "kms:Decrypt"
+	Resource string // Table name or KMS key ARN the action was performed on
+	Err      error
+}
+
+// String describes the missing permission.
+func (m MissingPermission) String() string {
+	return fmt.Sprintf("%s on %s: %v", m.Action, m.Resource, m.Err)
+}
+
+// Preflight exercises the meta-table operations used to read materials and, if write is set, to store and delete
+// them, and reports those that failed. Write access is checked by storing a material under PreflightMaterialName and
+// deleting it again. An error is returned only when the meta table can not be resolved.
+func (s *MetaStore) Preflight(ctx context.Context, write bool) ([]MissingPermission, error) {
+	tableName, err := s.ResolveTableName(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []MissingPermission
+	check := func(action string, err error) bool {
+		if err != nil {
+			missing = append(missing, MissingPermission{Action: action, Resource: tableName, Err: err})
+		}
+		return err == nil
+	}
+
+	_, _, err = s.getLastVersion(ctx, tableName, PreflightMaterialName)
+	check("dynamodb:Query", err)
+	_, err = s.DynamoDBClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(tableName),
+		Key: map[string]types.AttributeValue{
+			"MaterialName": &types.AttributeValueMemberS{Value: PreflightMaterialName},
+			"Version":      &types.AttributeValueMemberN{Value: "0"},
+		},
+	})
+	check("dynamodb:GetItem", err)
+
+	if !write {
+		return missing, nil
+	}
+
+	version, err := s.storeNewMaterial(ctx, tableName, PreflightMaterialName, "", &types.AttributeValueMemberS{Value: "{}"})
+	if !check("dynamodb:PutItem", err) {
+		return missing, nil
+	}
+	err = s.deleteKeys(ctx, tableName, []map[string]types.AttributeValue{{
+		"MaterialName": &types.AttributeValueMemberS{Value: s.partitionKey(PreflightMaterialName, version)},
+		"Version":      &types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)},
+	}})
+	check("dynamodb:BatchWriteItem", err)
+
+	return missing, nil
+}
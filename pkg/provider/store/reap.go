@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// StoredMaterial identifies a material version stored in the meta table.
+type StoredMaterial struct {
+	MaterialName string
+	Version      int64
+	MaterialID   string    // Empty for versions stored without a material ID
+	CreatedAt    time.Time // Zero for versions stored without a creation time
+}
+
+// ListMaterials scans the meta table and returns every stored material version, by material name rather than shard.
+// Verification keys and the preflight material are left out.
+func (s *MetaStore) ListMaterials(ctx context.Context) ([]StoredMaterial, error) {
+	tableName, err := s.ResolveTableName(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	paginator := dynamodb.NewScanPaginator(s.DynamoDBClient, &dynamodb.ScanInput{
+		TableName:            aws.String(tableName),
+		ProjectionExpression: aws.String("MaterialName, Version, MaterialID, CreatedAt"),
+	})
+	var stored []StoredMaterial
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning materials: %v", err)
+		}
+		for _, item := range output.Items {
+			material, ok := s.storedMaterial(item)
+			if ok {
+				stored = append(stored, material)
+			}
+		}
+	}
+	return stored, nil
+}
+
+// storedMaterial parses a meta-table item, reporting false for items that are not material versions.
+func (s *MetaStore) storedMaterial(item map[string]types.AttributeValue) (StoredMaterial, bool) {
+	partitionKey, ok := item["MaterialName"].(*types.AttributeValueMemberS)
+	if !ok || strings.HasPrefix(partitionKey.Value, VerificationKeyPrefix) {
+		return StoredMaterial{}, false
+	}
+	material := StoredMaterial{
+		MaterialName: s.shardMaterialName(partitionKey.Value),
+		Version:      itemVersion(item),
+	}
+	if material.MaterialName == PreflightMaterialName || material.Version < 1 {
+		return StoredMaterial{}, false
+	}
+	if id, ok := item["MaterialID"].(*types.AttributeValueMemberS); ok {
+		material.MaterialID = id.Value
+	}
+	if createdAttr, ok := item["CreatedAt"].(*types.AttributeValueMemberN); ok {
+		if createdAt, err := strconv.ParseInt(createdAttr.Value, 10, 64); err == nil {
+			material.CreatedAt = time.Unix(createdAt, 0)
+		}
+	}
+	return material, true
+}
+
+// DeleteMaterialVersions deletes the given material versions, for example orphaned versions found by a reaper.
+func (s *MetaStore) DeleteMaterialVersions(ctx context.Context, versions []StoredMaterial) error {
+	tableName, err := s.ResolveTableName(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]map[string]types.AttributeValue, 0, len(versions))
+	for _, version := range versions {
+		keys = append(keys, map[string]types.AttributeValue{
+			"MaterialName": &types.AttributeValueMemberS{Value: s.partitionKey(version.MaterialName, version.Version)},
+			"Version":      &types.AttributeValueMemberN{Value: strconv.FormatInt(version.Version, 10)},
+		})
+	}
+	return s.deleteKeys(ctx, tableName, keys)
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMetaStore_StoredMaterial(t *testing.T) {
+	testCases := []struct {
+		name   string
+		shards int
+		item   map[string]types.AttributeValue
+		want   StoredMaterial
+		ok     bool
+	}{
+		{
+			name: "Material version",
+			item: map[string]types.AttributeValue{
+				"MaterialName": &types.AttributeValueMemberS{Value: "material"},
+				"Version":      &types.AttributeValueMemberN{Value: "2"},
+				"MaterialID":   &types.AttributeValueMemberS{Value: "id"},
+				"CreatedAt":    &types.AttributeValueMemberN{Value: "1700000000"},
+			},
+			want: StoredMaterial{MaterialName: "material", Version: 2, MaterialID: "id", CreatedAt: time.Unix(1700000000, 0)},
+			ok:   true,
+		},
+		{
+			name:   "Sharded version",
+			shards: 4,
+			item: map[string]types.AttributeValue{
+				"MaterialName": &types.AttributeValueMemberS{Value: "material#3"},
+				"Version":      &types.AttributeValueMemberN{Value: "7"},
+			},
+			want: StoredMaterial{MaterialName: "material", Version: 7},
+			ok:   true,
+		},
+		{
+			name: "Verification key",
+			item: map[string]types.AttributeValue{
+				"MaterialName": &types.AttributeValueMemberS{Value: VerificationKeyPrefix + "material"},
+				"Version":      &types.AttributeValueMemberN{Value: "1"},
+			},
+		},
+		{
+			name: "Preflight material",
+			item: map[string]types.AttributeValue{
+				"MaterialName": &types.AttributeValueMemberS{Value: PreflightMaterialName},
+				"Version":      &types.AttributeValueMemberN{Value: "1"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := NewMetaStore(nil, "meta", WithShards(max(tc.shards, 1)))
+			if err != nil {
+				t.Fatalf("failed to create store: %v", err)
+			}
+			got, ok := s.storedMaterial(tc.item)
+			if ok != tc.ok {
+				t.Fatalf("expected %v, got %v", tc.ok, ok)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected material (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// RetryPolicy controls how MetaStore retries requests throttled by the meta table. Retries wait a random delay of up
+// to BaseDelay doubled for every attempt, capped at MaxDelay. While the table keeps throttling, the delay adapts
+// upwards across requests, and it decays again as requests succeed.
+type RetryPolicy struct {
+	MaxAttempts int // Total attempts per request, including the first. Values below 2 disable retries.
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy is used by stores created with NewMetaStore. It retries on top of the retries made by the
+// DynamoDB client, so a throttled meta table slows material operations down instead of failing them.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   25 * time.Millisecond,
+	MaxDelay:    time.Second,
+}
+
+// ThrottleHook is invoked every time a meta-table request is throttled, with the store operation that was throttled.
+type ThrottleHook func(operation string)
+
+// WithRetryPolicy replaces DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) MetaStoreOption {
+	return func(s *MetaStore) error {
+		s.retry.policy = policy
+		return nil
+	}
+}
+
+// WithThrottleHook registers a hook invoked for every throttled meta-table request, for example to emit a metric.
+func WithThrottleHook(hook ThrottleHook) MetaStoreOption {
+	return func(s *MetaStore) error {
+		s.retry.hook = hook
+		return nil
+	}
+}
+
+// WithWriteBuffer limits the number of materials written to the meta table concurrently. Further
+// StoreNewMaterialVersion calls wait in a buffer until a write completes, which smooths out bursts of new materials
+// instead of letting them all hit the table, and its throttling, at once.
+func WithWriteBuffer(size int) MetaStoreOption {
+	return func(s *MetaStore) error {
+		if size < 1 {
+			return errors.New("write buffer size must be at least 1")
+		}
+		s.writeSlots = make(chan struct{}, size)
+		return nil
+	}
+}
+
+// Throttles returns the number of meta-table requests that have been throttled.
+func (s *MetaStore) Throttles() int64 {
+	return s.retry.throttles.Load()
+}
+
+// retryer retries throttled requests with an adaptive, jittered backoff.
+type retryer struct {
+	policy    RetryPolicy
+	hook      ThrottleHook
+	throttles atomic.Int64
+
+	mu    sync.Mutex
+	delay time.Duration // adaptive lower bound for the next backoff
+}
+
+// do calls fn until it succeeds, fails with an error other than throttling, or runs out of attempts.
+func (r *retryer) do(ctx context.Context, operation string, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if !isThrottle(err) {
+			if err == nil {
+				r.succeeded()
+			}
+			return err
+		}
+
+		r.throttles.Add(1)
+		if r.hook != nil {
+			r.hook(operation)
+		}
+		if attempt >= r.policy.MaxAttempts {
+			return err
+		}
+
+		timer := time.NewTimer(r.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff records a throttle and returns how long to wait before the next attempt.
+func (r *retryer) backoff(attempt int) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.delay = min(max(2*r.delay, r.policy.BaseDelay), r.policy.MaxDelay)
+
+	delay := r.policy.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > r.policy.MaxDelay {
+		delay = r.policy.MaxDelay
+	}
+	delay = max(delay, r.delay)
+	if delay <= 0 {
+		return 0
+	}
+	// Full jitter spreads retries from concurrent callers apart
+	return time.Duration(rand.Int63n(int64(delay)) + 1)
+}
+
+// succeeded decays the adaptive delay after a request goes through.
+func (r *retryer) succeeded() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.delay /= 2
+	if r.delay < r.policy.BaseDelay {
+		r.delay = 0
+	}
+}
+
+// isThrottle reports whether err means the request was rejected for exceeding the table's or account's capacity.
+func isThrottle(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		for _, reason := range canceled.CancellationReasons {
+			if reason.Code != nil && (*reason.Code == "ThrottlingError" || *reason.Code == "ProvisionedThroughputExceeded") {
+				return true
+			}
+		}
+		return false
+	}
+
+	var coded interface{ ErrorCode() string }
+	if errors.As(err, &coded) {
+		switch coded.ErrorCode() {
+		case "ProvisionedThroughputExceededException", "RequestLimitExceeded", "ThrottlingException":
+			return true
+		}
+	}
+	return false
+}
+
+// acquireWrite waits for a write slot when a write buffer is configured. The returned function releases the slot.
+func (s *MetaStore) acquireWrite(ctx context.Context) (func(), error) {
+	if s.writeSlots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case s.writeSlots <- struct{}{}:
+		return func() { <-s.writeSlots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestIsThrottle(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		throttle bool
+	}{
+		{"nil", nil, false},
+		{"provisioned throughput", &types.ProvisionedThroughputExceededException{}, true},
+		{"wrapped request limit", fmt.Errorf("query: %w", &types.RequestLimitExceeded{}), true},
+		{"throttled transaction", &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{{Code: aws.String("ThrottlingError")}},
+		}, true},
+		{"conflicting transaction", &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{{Code: aws.String("ConditionalCheckFailed")}},
+		}, false},
+		{"other", errors.New("access denied"), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isThrottle(tc.err); got != tc.throttle {
+				t.Errorf("expected %v, got %v", tc.throttle, got)
+			}
+		})
+	}
+}
+
+func TestRetryer(t *testing.T) {
+	var operations []string
+	s, err := NewMetaStore(nil, "meta",
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}),
+		WithThrottleHook(func(operation string) { operations = append(operations, operation) }),
+	)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	calls := 0
+	err = s.retry.do(context.Background(), "StoreNewMaterial", func() error {
+		calls++
+		if calls < 3 {
+			return &types.ProvisionedThroughputExceededException{}
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("expected success after 3 calls, got %d calls and error %v", calls, err)
+	}
+
+	calls = 0
+	err = s.retry.do(context.Background(), "RetrieveMaterial", func() error {
+		calls++
+		return &types.ProvisionedThroughputExceededException{}
+	})
+	if !isThrottle(err) || calls != 3 {
+		t.Errorf("expected throttling error after 3 calls, got %d calls and error %v", calls, err)
+	}
+
+	calls = 0
+	denied := errors.New("access denied")
+	if err := s.retry.do(context.Background(), "DeleteMaterial", func() error { calls++; return denied }); err != denied || calls != 1 {
+		t.Errorf("expected other errors not to be retried, got %d calls and error %v", calls, err)
+	}
+
+	if s.Throttles() != 5 || len(operations) != 5 {
+		t.Errorf("expected 5 throttles, got %d and %d hook calls", s.Throttles(), len(operations))
+	}
+}
+
+func TestWithWriteBuffer(t *testing.T) {
+	if _, err := NewMetaStore(nil, "meta", WithWriteBuffer(0)); err == nil {
+		t.Errorf("expected error for an empty write buffer")
+	}
+
+	s, err := NewMetaStore(nil, "meta", WithWriteBuffer(1))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	release, err := s.acquireWrite(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := s.acquireWrite(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the second write to wait for a slot, got %v", err)
+	}
+
+	release()
+	if _, err := s.acquireWrite(context.Background()); err != nil {
+		t.Errorf("expected a slot after release, got %v", err)
+	}
+}
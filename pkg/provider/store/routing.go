@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+// TableNameResolver selects the meta table to use for a request.
+type TableNameResolver func(ctx context.Context) (string, error)
+
+// MetaStoreOption defines a function signature for options that modify a MetaStore.
+type MetaStoreOption func(*MetaStore) error
+
+type tableParamsKey struct{}
+
+// placeholderPattern matches "{name}" placeholders in a table name template.
+var placeholderPattern = regexp.MustCompile(`\{([A-Za-z0-9_]+)\}`)
+
+// ContextWithTableParams returns a context carrying the values used to resolve a table name template,
+// for example {"tenant": "acme"} for the template "meta-{tenant}".
+func ContextWithTableParams(ctx context.Context, params map[string]string) context.Context {
+	merged := make(map[string]string)
+	if existing, ok := ctx.Value(tableParamsKey{}).(map[string]string); ok {
+		for key, value := range existing {
+			merged[key] = value
+		}
+	}
+	for key, value := range params {
+		merged[key] = value
+	}
+	return context.WithValue(ctx, tableParamsKey{}, merged)
+}
+
+// WithTableNameResolver routes every request to the meta table returned by the resolver.
+func WithTableNameResolver(resolver TableNameResolver) MetaStoreOption {
+	return func(s *MetaStore) error {
+		s.TableNameResolver = resolver
+		return nil
+	}
+}
+
+// WithTableNameTemplate routes every request to the meta table obtained by filling the template's
+// placeholders from the values attached with ContextWithTableParams. Requests whose context lacks
+// a placeholder value fail rather than falling back to a shared table.
+func WithTableNameTemplate(template string) MetaStoreOption {
+	return func(s *MetaStore) error {
+		if !placeholderPattern.MatchString(template) {
+			return fmt.Errorf("table name template %q has no placeholders", template)
+		}
+
+		s.TableNameResolver = func(ctx context.Context) (string, error) {
+			params, _ := ctx.Value(tableParamsKey{}).(map[string]string)
+
+			var missing string
+			tableName := placeholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+				name := placeholderPattern.FindStringSubmatch(placeholder)[1]
+				value, ok := params[name]
+				if !ok || value == "" {
+					missing = name
+				}
+				return value
+			})
+			if missing != "" {
+				return "", fmt.Errorf("no value for placeholder %q in table name template %q", missing, template)
+			}
+
+			return tableName, nil
+		}
+		return nil
+	}
+}
+
+// ResolveTableName returns the meta table to use for the request.
+func (s *MetaStore) ResolveTableName(ctx context.Context) (string, error) {
+	if s.TableNameResolver == nil {
+		return s.TableName, nil
+	}
+
+	tableName, err := s.TableNameResolver(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve meta table name: %w", err)
+	}
+	return tableName, nil
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWithTableNameTemplate(t *testing.T) {
+	s, err := NewMetaStore(nil, "meta", WithTableNameTemplate("meta-{env}-{tenant}"))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	ctx := ContextWithTableParams(context.Background(), map[string]string{"env": "prod"})
+	ctx = ContextWithTableParams(ctx, map[string]string{"tenant": "acme"})
+
+	tableName, err := s.ResolveTableName(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tableName != "meta-prod-acme" {
+		t.Errorf("expected meta-prod-acme, got %s", tableName)
+	}
+
+	if _, err := s.ResolveTableName(context.Background()); err == nil {
+		t.Errorf("expected error for missing placeholder values")
+	}
+}
+
+func TestResolveTableName_Default(t *testing.T) {
+	s, err := NewMetaStore(nil, "meta")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	tableName, err := s.ResolveTableName(context.Background())
+	if err != nil || tableName != "meta" {
+		t.Errorf("expected meta, got %s (%v)", tableName, err)
+	}
+}
+
+func TestWithTableNameTemplate_NoPlaceholders(t *testing.T) {
+	if _, err := NewMetaStore(nil, "meta", WithTableNameTemplate("meta")); err == nil {
+		t.Errorf("expected error for template without placeholders")
+	}
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// WithShards spreads the versions of every material over n partition keys, so materials that get new versions
+// frequently, such as materials shared by a whole table, do not concentrate their writes on one meta-table
+// partition. Version v is stored under the material name suffixed with "#" and v mod n, except that shard 0 uses the
+// plain material name. Reading a known version goes straight to its shard; resolving the latest version, and
+// deleting versions, queries every shard.
+//
+// Versions stored before sharding was enabled stay readable. n must not be changed once sharded versions exist.
+func WithShards(n int) MetaStoreOption {
+	return func(s *MetaStore) error {
+		if n < 1 {
+			return errors.New("number of shards must be at least 1")
+		}
+		s.shards = int64(n)
+		return nil
+	}
+}
+
+// partitionKey returns the meta-table partition key a material version is stored under.
+func (s *MetaStore) partitionKey(materialName string, version int64) string {
+	if s.shards <= 1 {
+		return materialName
+	}
+	shard := version % s.shards
+	if shard <= 0 {
+		return materialName
+	}
+	return materialName + "#" + strconv.FormatInt(shard, 10)
+}
+
+// partitionKeys returns every partition key the versions of a material may be stored under.
+func (s *MetaStore) partitionKeys(materialName string) []string {
+	if s.shards <= 1 {
+		return []string{materialName}
+	}
+	keys := make([]string, 0, s.shards)
+	for shard := int64(0); shard < s.shards; shard++ {
+		keys = append(keys, s.partitionKey(materialName, shard))
+	}
+	return keys
+}
+
+// itemVersion returns the version of a meta-table item, or 0 if it has none.
+func itemVersion(item map[string]types.AttributeValue) int64 {
+	versionAttr, ok := item["Version"].(*types.AttributeValueMemberN)
+	if !ok {
+		return 0
+	}
+	version, _ := strconv.ParseInt(versionAttr.Value, 10, 64)
+	return version
+}
+
+// shardMaterialName returns the material name a partition key belongs to, removing the shard suffix of sharded
+// stores.
+func (s *MetaStore) shardMaterialName(partitionKey string) string {
+	if s.shards <= 1 {
+		return partitionKey
+	}
+	i := strings.LastIndexByte(partitionKey, '#')
+	if i < 0 {
+		return partitionKey
+	}
+	if shard, err := strconv.ParseInt(partitionKey[i+1:], 10, 64); err != nil || shard < 1 || shard >= s.shards {
+		return partitionKey
+	}
+	return partitionKey[:i]
+}
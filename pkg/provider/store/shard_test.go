@@ -0,0 +1,44 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWithShards(t *testing.T) {
+	if _, err := NewMetaStore(nil, "meta", WithShards(0)); err == nil {
+		t.Errorf("expected error for zero shards")
+	}
+
+	testCases := []struct {
+		name    string
+		shards  int
+		version int64
+		key     string
+	}{
+		{"unsharded", 1, 7, "material"},
+		{"first shard", 4, 8, "material"},
+		{"other shard", 4, 7, "material#3"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := NewMetaStore(nil, "meta", WithShards(tc.shards))
+			if err != nil {
+				t.Fatalf("failed to create store: %v", err)
+			}
+			if key := s.partitionKey("material", tc.version); key != tc.key {
+				t.Errorf("expected %s, got %s", tc.key, key)
+			}
+		})
+	}
+
+	s, err := NewMetaStore(nil, "meta", WithShards(3))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	if diff := cmp.Diff([]string{"material", "material#1", "material#2"}, s.partitionKeys("material")); diff != "" {
+		t.Errorf("unexpected partition keys (-want +got):\n%s", diff)
+	}
+}
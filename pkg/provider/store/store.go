@@ -4,35 +4,97 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/compression"
 	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
 )
 
+// maxBatchWriteItems is the maximum number of write requests DynamoDB accepts in a single BatchWriteItem call.
+const maxBatchWriteItems = 25
+
 type MetaStore struct {
 	DynamoDBClient *dynamodb.Client
 	TableName      string
+
+	// TableNameResolver, when set, selects the meta table for each request instead of TableName.
+	TableNameResolver TableNameResolver
+
+	// VerificationTableName, when set, is the table verification keys are stored in instead of the meta table.
+	VerificationTableName string
+
+	retry       retryer
+	writeSlots  chan struct{}
+	shards      int64
+	compression compression.ID
 }
 
-// NewMetaStore creates a new instance of MetaStore.
-func NewMetaStore(dynamoDBClient *dynamodb.Client, tableName string) (*MetaStore, error) {
-	return &MetaStore{
+// NewMetaStore creates a new instance of MetaStore. The meta table is not accessed until the first material
+// operation; use New to check it up front.
+func NewMetaStore(dynamoDBClient *dynamodb.Client, tableName string, opts ...MetaStoreOption) (*MetaStore, error) {
+	s := &MetaStore{
 		DynamoDBClient: dynamoDBClient,
 		TableName:      tableName,
-	}, nil
+		retry:          retryer{policy: DefaultRetryPolicy},
+	}
+
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
 }
 
 // StoreNewMaterial stores a new material along with its encryption context serialized as JSON.
+// Use StoreNewMaterialVersion to learn the version it was stored under.
 func (s *MetaStore) StoreNewMaterial(ctx context.Context, materialName string, material materials.CryptographicMaterials) error {
+	_, err := s.StoreNewMaterialVersion(ctx, materialName, material)
+	return err
+}
+
+// StoreNewMaterialVersion stores a new material along with its encryption context serialized as JSON,
+// and returns the version it was stored under. Throttled writes are retried following the store's RetryPolicy.
+func (s *MetaStore) StoreNewMaterialVersion(ctx context.Context, materialName string, material materials.CryptographicMaterials) (int64, error) {
+	tableName, err := s.ResolveTableName(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	release, err := s.acquireWrite(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
 	// Serialize the material description to a JSON string.
 	materialDescriptionJSON, err := json.Marshal(material.MaterialDescription())
 	if err != nil {
-		return fmt.Errorf("failed to serialize material description: %v", err)
+		return 0, fmt.Errorf("failed to serialize material description: %v", err)
+	}
+	description, err := s.encodeDescription(materialDescriptionJSON)
+	if err != nil {
+		return 0, err
 	}
 
+	materialID := material.MaterialDescription()[MaterialIDKey]
+	var newVersion int64
+	err = s.retry.do(ctx, "StoreNewMaterial", func() error {
+		newVersion, err = s.storeNewMaterial(ctx, tableName, materialName, materialID, description)
+		return err
+	})
+	return newVersion, err
+}
+
+// storeNewMaterial writes the encoded material description under the version following the latest stored version. When the
+// latest version was stored with the same material ID, it is returned instead.
+func (s *MetaStore) storeNewMaterial(ctx context.Context, tableName, materialName, materialID string, description types.AttributeValue) (int64, error) {
 	// Start a transaction to ensure atomic increment of version
 	transactItems := []types.TransactWriteItem{}
 
@@ -40,69 +102,96 @@ func (s *MetaStore) StoreNewMaterial(ctx context.Context, materialName string, m
 	var newVersion int64 = 1 // default to 1 if no existing versions
 
 	// Attempt to fetch the latest version of the material
-	currentVersion, err := s.getLastVersion(ctx, materialName)
+	currentVersion, currentID, err := s.getLastVersion(ctx, tableName, materialName)
 	if err != nil {
-		return err
+		return 0, err
+	}
+	if materialID != "" && currentID == materialID {
+		return currentVersion, nil
 	}
 	if currentVersion != 0 {
 		newVersion = currentVersion + 1
 	}
 
+	transactItems = append(transactItems, materialPut(tableName, s.partitionKey(materialName, newVersion), newVersion, materialID, description))
+
+	// Execute the transaction
+	_, err = s.DynamoDBClient.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("transaction failed: %w", err)
+	}
+
+	return newVersion, nil
+}
+
+// materialPut builds the conditional put storing a material version. The partition key is the material name, or the
+// name of its shard when the store is sharded. The material ID is left out when empty.
+func materialPut(tableName, partitionKey string, version int64, materialID string, description types.AttributeValue) types.TransactWriteItem {
 	// Conditional check to ensure the version has not been updated since it was last fetched
 	conditionExpression := "attribute_not_exists(Version) OR Version < :newVersion"
 	expressionAttributeValues := map[string]types.AttributeValue{
-		":newVersion": &types.AttributeValueMemberN{Value: strconv.FormatInt(newVersion, 10)},
+		":newVersion": &types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)},
 	}
 
 	// Prepare the new material item with the incremented version
 	item := map[string]types.AttributeValue{
-		"MaterialName":        &types.AttributeValueMemberS{Value: materialName},
-		"Version":             &types.AttributeValueMemberN{Value: strconv.FormatInt(newVersion, 10)},
-		"MaterialDescription": &types.AttributeValueMemberS{Value: string(materialDescriptionJSON)},
+		"MaterialName":        &types.AttributeValueMemberS{Value: partitionKey},
+		"Version":             &types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)},
+		"MaterialDescription": description,
+		"CreatedAt":           &types.AttributeValueMemberN{Value: strconv.FormatInt(time.Now().Unix(), 10)},
+	}
+	if materialID != "" {
+		item["MaterialID"] = &types.AttributeValueMemberS{Value: materialID}
 	}
 
-	putItem := types.TransactWriteItem{
+	return types.TransactWriteItem{
 		Put: &types.Put{
-			TableName:                 aws.String(s.TableName),
+			TableName:                 aws.String(tableName),
 			Item:                      item,
 			ConditionExpression:       aws.String(conditionExpression),
 			ExpressionAttributeValues: expressionAttributeValues,
 		},
 	}
-	transactItems = append(transactItems, putItem)
+}
 
-	// Execute the transaction
-	_, err = s.DynamoDBClient.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
-		TransactItems: transactItems,
-	})
+// RetrieveMaterial retrieves a material and its encryption context by materialName and version.
+func (s *MetaStore) RetrieveMaterial(ctx context.Context, materialName string, version int64) (map[string]string, string, error) {
+	tableName, err := s.ResolveTableName(ctx)
 	if err != nil {
-		return fmt.Errorf("transaction failed: %v", err)
+		return nil, "", err
 	}
 
-	return nil
-}
+	var result *dynamodb.GetItemOutput
+	err = s.retry.do(ctx, "RetrieveMaterial", func() error {
+		// If version is less than 1, retrieve the latest version
+		resolved := version
+		if resolved < 1 {
+			if resolved, _, err = s.getLastVersion(ctx, tableName, materialName); err != nil {
+				return err
+			}
+		}
 
-// RetrieveMaterial retrieves a material and its encryption context by materialName and version.
-func (s *MetaStore) RetrieveMaterial(ctx context.Context, materialName string, version int64) (map[string]string, string, error) {
-	// If version is less than 1, retrieve the latest version
-	if version < 1 {
-		var err error
-		version, err = s.getLastVersion(ctx, materialName)
-		if err != nil {
-			return nil, "", err
+		input := &dynamodb.GetItemInput{
+			TableName: aws.String(tableName),
+			Key: map[string]types.AttributeValue{
+				"MaterialName": &types.AttributeValueMemberS{Value: s.partitionKey(materialName, resolved)},
+				"Version":      &types.AttributeValueMemberN{Value: strconv.FormatInt(resolved, 10)},
+			},
 		}
-	}
 
-	input := &dynamodb.GetItemInput{
-		TableName: &s.TableName,
-		Key: map[string]types.AttributeValue{
-			"MaterialName": &types.AttributeValueMemberS{Value: materialName},
-			"Version":      &types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)},
-		},
-	}
+		// Execute the get item request.
+		result, err = s.DynamoDBClient.GetItem(ctx, input)
+		if err != nil || result.Item != nil || s.partitionKey(materialName, resolved) == materialName {
+			return err
+		}
 
-	// Execute the get item request.
-	result, err := s.DynamoDBClient.GetItem(ctx, input)
+		// Versions stored before sharding was enabled live under the unsharded material name
+		input.Key["MaterialName"] = &types.AttributeValueMemberS{Value: materialName}
+		result, err = s.DynamoDBClient.GetItem(ctx, input)
+		return err
+	})
 	if err != nil {
 		return nil, "", err
 	}
@@ -112,16 +201,15 @@ func (s *MetaStore) RetrieveMaterial(ctx context.Context, materialName string, v
 		return nil, "", fmt.Errorf("material not found")
 	}
 
-	// Directly use the MaterialDescription attribute as a JSON string.
-	materialDescriptionAttr, ok := result.Item["MaterialDescription"].(*types.AttributeValueMemberS)
-	if !ok {
-		return nil, "", fmt.Errorf("unexpected type for MaterialDescription attribute")
+	// The MaterialDescription attribute holds the JSON, compressed when stored with description compression.
+	materialDescriptionJSON, err := decodeDescription(result.Item["MaterialDescription"])
+	if err != nil {
+		return nil, "", err
 	}
-	materialDescriptionJSON := materialDescriptionAttr.Value
 
 	// Deserialize the JSON string back into a map[string]string.
 	var materialDescMap map[string]string
-	err = json.Unmarshal([]byte(materialDescriptionJSON), &materialDescMap)
+	err = json.Unmarshal(materialDescriptionJSON, &materialDescMap)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to deserialize material description: %v", err)
 	}
@@ -135,56 +223,259 @@ func (s *MetaStore) RetrieveMaterial(ctx context.Context, materialName string, v
 	return materialDescMap, wrappedKeysetBase64, nil
 }
 
-func (s *MetaStore) getLastVersion(ctx context.Context, materialName string) (int64, error) {
-	input := &dynamodb.QueryInput{
-		TableName:              aws.String(s.TableName),
-		KeyConditionExpression: aws.String("MaterialName = :materialName"),
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":materialName": &types.AttributeValueMemberS{Value: materialName},
-		},
-		ScanIndexForward: aws.Bool(false),
-		Limit:            aws.Int32(1),
+// DeleteMaterial deletes a single version of a material.
+func (s *MetaStore) DeleteMaterial(ctx context.Context, materialName string, version int64) error {
+	tableName, err := s.ResolveTableName(ctx)
+	if err != nil {
+		return err
 	}
 
-	result, err := s.DynamoDBClient.Query(ctx, input)
+	err = s.retry.do(ctx, "DeleteMaterial", func() error {
+		_, err := s.DynamoDBClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(tableName),
+			Key: map[string]types.AttributeValue{
+				"MaterialName": &types.AttributeValueMemberS{Value: s.partitionKey(materialName, version)},
+				"Version":      &types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)},
+			},
+		})
+		return err
+	})
 	if err != nil {
-		return 0, err
+		return fmt.Errorf("error deleting material version: %v", err)
 	}
+	return nil
+}
 
-	// If no items are returned, this is the first version for the material name
-	if len(result.Items) == 0 {
-		return 0, nil
+// DeleteVersionsOlderThan deletes the versions of a material created more than maxAge ago.
+// The latest version is always kept, as are versions stored without a creation time.
+func (s *MetaStore) DeleteVersionsOlderThan(ctx context.Context, materialName string, maxAge time.Duration) error {
+	tableName, err := s.ResolveTableName(ctx)
+	if err != nil {
+		return err
 	}
 
-	// Extract the version number from the result
-	versionAttr, ok := result.Items[0]["Version"].(*types.AttributeValueMemberN)
-	if !ok {
-		return 0, fmt.Errorf("unexpected type for Version attribute")
+	cutoff := time.Now().Add(-maxAge).Unix()
+	items, err := s.queryVersions(ctx, tableName, materialName, "MaterialName, Version, CreatedAt")
+	if err != nil {
+		return err
+	}
+
+	// Skip the latest version, which queryVersions returns first
+	var keys []map[string]types.AttributeValue
+	for i, item := range items {
+		if i == 0 {
+			continue
+		}
+		createdAttr, ok := item["CreatedAt"].(*types.AttributeValueMemberN)
+		if !ok {
+			continue
+		}
+		createdAt, err := strconv.ParseInt(createdAttr.Value, 10, 64)
+		if err != nil || createdAt >= cutoff {
+			continue
+		}
+		keys = append(keys, map[string]types.AttributeValue{
+			"MaterialName": item["MaterialName"],
+			"Version":      item["Version"],
+		})
 	}
 
-	highestVersion, err := strconv.ParseInt(versionAttr.Value, 10, 64)
+	return s.deleteKeys(ctx, tableName, keys)
+}
+
+// DeleteAllVersions deletes every stored version of a material.
+func (s *MetaStore) DeleteAllVersions(ctx context.Context, materialName string) error {
+	tableName, err := s.ResolveTableName(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse version number: %v", err)
+		return err
 	}
 
-	return highestVersion, nil
+	items, err := s.queryVersions(ctx, tableName, materialName, "MaterialName, Version")
+	if err != nil {
+		return err
+	}
+
+	keys := make([]map[string]types.AttributeValue, 0, len(items))
+	for _, item := range items {
+		keys = append(keys, map[string]types.AttributeValue{
+			"MaterialName": item["MaterialName"],
+			"Version":      item["Version"],
+		})
+	}
 
+	return s.deleteKeys(ctx, tableName, keys)
 }
 
-// CreateTableIfNotExists checks if a DynamoDB table exists, and if not, creates it.
-func (s *MetaStore) CreateTableIfNotExists(ctx context.Context) error {
-	_, err := s.DynamoDBClient.DescribeTable(ctx, &dynamodb.DescribeTableInput{
-		TableName: aws.String(s.TableName),
-	})
+// queryVersions returns the projected attributes of every stored version of a material, from every shard, latest
+// version first.
+func (s *MetaStore) queryVersions(ctx context.Context, tableName, materialName, projection string) ([]map[string]types.AttributeValue, error) {
+	var items []map[string]types.AttributeValue
+	for _, partitionKey := range s.partitionKeys(materialName) {
+		input := &dynamodb.QueryInput{
+			TableName:              aws.String(tableName),
+			KeyConditionExpression: aws.String("MaterialName = :materialName"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":materialName": &types.AttributeValueMemberS{Value: partitionKey},
+			},
+			ProjectionExpression: aws.String(projection),
+			ScanIndexForward:     aws.Bool(false),
+		}
 
-	// If no error, table exists, return.
-	if err == nil {
-		fmt.Println("Table already exists:", s.TableName)
-		return nil
+		paginator := dynamodb.NewQueryPaginator(s.DynamoDBClient, input)
+		for paginator.HasMorePages() {
+			output, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("error querying for versions: %v", err)
+			}
+			items = append(items, output.Items...)
+		}
 	}
 
-	_, err = s.DynamoDBClient.CreateTable(ctx, &dynamodb.CreateTableInput{
-		TableName: aws.String(s.TableName),
+	if len(s.partitionKeys(materialName)) > 1 {
+		sort.SliceStable(items, func(i, j int) bool { return itemVersion(items[i]) > itemVersion(items[j]) })
+	}
+	return items, nil
+}
+
+// UnprocessedKeysError is returned when meta-table keys were still left unprocessed by BatchWriteItem after the
+// store's retry attempts ran out. Keys holds the keys that were not deleted, including those of batches not yet sent.
+type UnprocessedKeysError struct {
+	Keys []map[string]types.AttributeValue
+}
+
+func (e *UnprocessedKeysError) Error() string {
+	return fmt.Sprintf("%d material versions left unprocessed", len(e.Keys))
+}
+
+// deleteKeys removes the given meta-table keys in batches. Unprocessed items are requested again with the store's
+// backoff, for up to the attempts of its RetryPolicy.
+func (s *MetaStore) deleteKeys(ctx context.Context, tableName string, keys []map[string]types.AttributeValue) error {
+	for start := 0; start < len(keys); start += maxBatchWriteItems {
+		end := start + maxBatchWriteItems
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		writeRequests := make([]types.WriteRequest, 0, end-start)
+		for _, key := range keys[start:end] {
+			writeRequests = append(writeRequests, types.WriteRequest{
+				DeleteRequest: &types.DeleteRequest{Key: key},
+			})
+		}
+
+		requestItems := map[string][]types.WriteRequest{tableName: writeRequests}
+		for attempt := 1; ; attempt++ {
+			var output *dynamodb.BatchWriteItemOutput
+			err := s.retry.do(ctx, "DeleteMaterials", func() (err error) {
+				output, err = s.DynamoDBClient.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: requestItems})
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("error deleting material versions: %v", err)
+			}
+			requestItems = output.UnprocessedItems
+			if len(requestItems) == 0 {
+				break
+			}
+
+			if attempt >= s.retry.policy.MaxAttempts {
+				return unprocessedKeysError(requestItems, keys[end:])
+			}
+			timer := time.NewTimer(s.retry.backoff(attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return fmt.Errorf("%w: %w", unprocessedKeysError(requestItems, keys[end:]), ctx.Err())
+			case <-timer.C:
+			}
+		}
+	}
+
+	return nil
+}
+
+// unprocessedKeysError collects the keys of unprocessed delete requests, followed by the keys not sent yet.
+func unprocessedKeysError(requestItems map[string][]types.WriteRequest, pending []map[string]types.AttributeValue) *UnprocessedKeysError {
+	var keys []map[string]types.AttributeValue
+	for _, writeRequests := range requestItems {
+		for _, writeRequest := range writeRequests {
+			if writeRequest.DeleteRequest != nil {
+				keys = append(keys, writeRequest.DeleteRequest.Key)
+			}
+		}
+	}
+	return &UnprocessedKeysError{Keys: append(keys, pending...)}
+}
+
+// getLastVersion returns the latest stored version of a material and the material ID it was stored with, or 0 if
+// none is stored. Sharded stores query every shard.
+func (s *MetaStore) getLastVersion(ctx context.Context, tableName, materialName string) (int64, string, error) {
+	var highestVersion int64
+	var materialID string
+	for _, partitionKey := range s.partitionKeys(materialName) {
+		input := &dynamodb.QueryInput{
+			TableName:              aws.String(tableName),
+			KeyConditionExpression: aws.String("MaterialName = :materialName"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":materialName": &types.AttributeValueMemberS{Value: partitionKey},
+			},
+			ScanIndexForward: aws.Bool(false),
+			Limit:            aws.Int32(1),
+		}
+
+		result, err := s.DynamoDBClient.Query(ctx, input)
+		if err != nil {
+			return 0, "", err
+		}
+
+		// If no items are returned, no version is stored in this shard
+		if len(result.Items) == 0 {
+			continue
+		}
+
+		// Extract the version number from the result
+		versionAttr, ok := result.Items[0]["Version"].(*types.AttributeValueMemberN)
+		if !ok {
+			return 0, "", fmt.Errorf("unexpected type for Version attribute")
+		}
+
+		version, err := strconv.ParseInt(versionAttr.Value, 10, 64)
+		if err != nil {
+			return 0, "", fmt.Errorf("failed to parse version number: %v", err)
+		}
+		if version > highestVersion {
+			highestVersion = version
+			materialID = ""
+			if id, ok := result.Items[0]["MaterialID"].(*types.AttributeValueMemberS); ok {
+				materialID = id.Value
+			}
+		}
+	}
+
+	return highestVersion, materialID, nil
+}
+
+// CreateTableIfNotExists checks if the meta table exists, and if not, creates it. Either way, it waits until the
+// table is ACTIVE. When a table name resolver is configured, the table resolved for ctx is created. The tag, SSE and
+// TTL options only apply to a table it creates: an existing table is left as it is, and only WithWaitTimeout applies.
+func (s *MetaStore) CreateTableIfNotExists(ctx context.Context, opts ...TableOption) error {
+	tableName, err := s.ResolveTableName(ctx)
+	if err != nil {
+		return err
+	}
+
+	exists, err := TableExists(ctx, s.DynamoDBClient, tableName)
+	if err != nil {
+		return err
+	}
+
+	// If the table exists, it may still be CREATING.
+	if exists {
+		return waitForActive(ctx, s.DynamoDBClient, tableName, newTableOptions(opts).WaitTimeout)
+	}
+
+	return CreateTable(ctx, s.DynamoDBClient, &dynamodb.CreateTableInput{
+		TableName: aws.String(tableName),
 		AttributeDefinitions: []types.AttributeDefinition{
 			{
 				AttributeName: aws.String("MaterialName"),
@@ -209,12 +500,5 @@ func (s *MetaStore) CreateTableIfNotExists(ctx context.Context) error {
 			ReadCapacityUnits:  aws.Int64(5),
 			WriteCapacityUnits: aws.Int64(5),
 		},
-	})
-
-	if err != nil {
-		return fmt.Errorf("failed to create table: %w", err)
-	}
-
-	fmt.Println("Table created successfully:", s.TableName)
-	return nil
+	}, opts...)
 }
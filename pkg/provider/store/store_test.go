@@ -0,0 +1,309 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/cloudopsy/dynamodb-encryption-go/internal/fakeddb"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+)
+
+// fakeDynamoDB returns a DynamoDB client whose operations are answered by handler instead of being sent. handler
+// receives the operation input, such as a *dynamodb.BatchWriteItemInput, and returns the matching output.
+func fakeDynamoDB(handler func(input interface{}) (interface{}, error)) *dynamodb.Client {
+	return dynamodb.New(dynamodb.Options{
+		Region: "us-east-1",
+		APIOptions: []func(*middleware.Stack) error{func(stack *middleware.Stack) error {
+			return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("fake", func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				result, err := handler(in.Parameters)
+				return middleware.InitializeOutput{Result: result}, middleware.Metadata{}, err
+			}), middleware.Before)
+		}},
+	})
+}
+
+// versionKey returns the meta-table key of a material version.
+func versionKey(materialName string, version int64) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"MaterialName": &types.AttributeValueMemberS{Value: materialName},
+		"Version":      &types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)},
+	}
+}
+
+func TestDeleteKeys_Unprocessed(t *testing.T) {
+	keys := make([]map[string]types.AttributeValue, 30)
+	for i := range keys {
+		keys[i] = versionKey("material", int64(i+1))
+	}
+
+	testCases := []struct {
+		name        string
+		unprocessed int // calls answered with every item unprocessed
+		calls       int
+		remaining   int
+	}{
+		{name: "Processed", unprocessed: 0, calls: 2},
+		{name: "Processed after retries", unprocessed: 2, calls: 4},
+		{name: "Attempts exhausted", unprocessed: -1, calls: 3, remaining: 30},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int
+			client := fakeDynamoDB(func(input interface{}) (interface{}, error) {
+				calls++
+				batch := input.(*dynamodb.BatchWriteItemInput)
+				if tc.unprocessed < 0 || calls <= tc.unprocessed {
+					return &dynamodb.BatchWriteItemOutput{UnprocessedItems: batch.RequestItems}, nil
+				}
+				return &dynamodb.BatchWriteItemOutput{}, nil
+			})
+			s, err := NewMetaStore(client, "meta", WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}))
+			if err != nil {
+				t.Fatalf("failed to create store: %v", err)
+			}
+
+			err = s.deleteKeys(context.Background(), "meta", keys)
+			if calls != tc.calls {
+				t.Errorf("expected %d BatchWriteItem calls, got %d", tc.calls, calls)
+			}
+			if tc.remaining == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var unprocessed *UnprocessedKeysError
+			if !errors.As(err, &unprocessed) {
+				t.Fatalf("expected an UnprocessedKeysError, got %v", err)
+			}
+			if len(unprocessed.Keys) != tc.remaining {
+				t.Errorf("expected %d remaining keys, got %d", tc.remaining, len(unprocessed.Keys))
+			}
+		})
+	}
+
+	t.Run("Canceled", func(t *testing.T) {
+		client := fakeDynamoDB(func(input interface{}) (interface{}, error) {
+			return &dynamodb.BatchWriteItemOutput{UnprocessedItems: input.(*dynamodb.BatchWriteItemInput).RequestItems}, nil
+		})
+		s, err := NewMetaStore(client, "meta", WithRetryPolicy(RetryPolicy{MaxAttempts: 100, BaseDelay: time.Hour, MaxDelay: time.Hour}))
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err = s.deleteKeys(ctx, "meta", keys[:1])
+		var unprocessed *UnprocessedKeysError
+		if !errors.As(err, &unprocessed) || !errors.Is(err, context.Canceled) {
+			t.Errorf("expected an UnprocessedKeysError and the context error, got %v", err)
+		}
+	})
+}
+
+// versionsTable answers Query with the stored versions of a material, latest first and pageSize per page, and
+// records the versions deleted with BatchWriteItem and DeleteItem.
+type versionsTable struct {
+	items    []map[string]types.AttributeValue
+	pageSize int
+	queries  int
+	deleted  []int64
+}
+
+func (v *versionsTable) handle(input interface{}) (interface{}, error) {
+	switch input := input.(type) {
+	case *dynamodb.QueryInput:
+		v.queries++
+		start := 0
+		if input.ExclusiveStartKey != nil {
+			start = v.itemIndex(itemVersion(input.ExclusiveStartKey)) + 1
+		}
+		end := min(start+v.pageSize, len(v.items))
+		output := &dynamodb.QueryOutput{Items: v.items[start:end]}
+		if end < len(v.items) {
+			output.LastEvaluatedKey = v.items[end-1]
+		}
+		return output, nil
+	case *dynamodb.BatchWriteItemInput:
+		for _, writeRequests := range input.RequestItems {
+			for _, writeRequest := range writeRequests {
+				v.deleted = append(v.deleted, itemVersion(writeRequest.DeleteRequest.Key))
+			}
+		}
+		return &dynamodb.BatchWriteItemOutput{}, nil
+	case *dynamodb.DeleteItemInput:
+		v.deleted = append(v.deleted, itemVersion(input.Key))
+		return &dynamodb.DeleteItemOutput{}, nil
+	}
+	return nil, errors.New("unexpected operation")
+}
+
+func (v *versionsTable) itemIndex(version int64) int {
+	for i, item := range v.items {
+		if itemVersion(item) == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// storedVersion returns a meta-table item of a material version created age ago, or without a creation time when
+// age is negative.
+func storedVersion(version int64, age time.Duration) map[string]types.AttributeValue {
+	item := versionKey("material", version)
+	if age >= 0 {
+		item["CreatedAt"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(time.Now().Add(-age).Unix(), 10)}
+	}
+	return item
+}
+
+func TestDeleteVersionsOlderThan(t *testing.T) {
+	testCases := []struct {
+		name     string
+		items    []map[string]types.AttributeValue
+		pageSize int
+		queries  int
+		deleted  []int64
+	}{
+		{
+			name:     "Old versions",
+			items:    []map[string]types.AttributeValue{storedVersion(4, 2*time.Hour), storedVersion(3, time.Minute), storedVersion(2, 2*time.Hour), storedVersion(1, 3*time.Hour)},
+			pageSize: 10,
+			queries:  1,
+			deleted:  []int64{2, 1},
+		},
+		{
+			name:     "Latest version kept",
+			items:    []map[string]types.AttributeValue{storedVersion(1, 2*time.Hour)},
+			pageSize: 10,
+			queries:  1,
+		},
+		{
+			name:     "Versions without creation time kept",
+			items:    []map[string]types.AttributeValue{storedVersion(3, 0), storedVersion(2, -1), storedVersion(1, 2*time.Hour)},
+			pageSize: 10,
+			queries:  1,
+			deleted:  []int64{1},
+		},
+		{
+			name:     "Paginated",
+			items:    []map[string]types.AttributeValue{storedVersion(5, 0), storedVersion(4, 2*time.Hour), storedVersion(3, 2*time.Hour), storedVersion(2, 2*time.Hour), storedVersion(1, 2*time.Hour)},
+			pageSize: 2,
+			queries:  3,
+			deleted:  []int64{4, 3, 2, 1},
+		},
+		{
+			name:     "No versions",
+			pageSize: 10,
+			queries:  1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			table := &versionsTable{items: tc.items, pageSize: tc.pageSize}
+			s, err := NewMetaStore(fakeDynamoDB(table.handle), "meta")
+			if err != nil {
+				t.Fatalf("failed to create store: %v", err)
+			}
+
+			if err := s.DeleteVersionsOlderThan(context.Background(), "material", time.Hour); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if table.queries != tc.queries {
+				t.Errorf("expected %d queries, got %d", tc.queries, table.queries)
+			}
+			if !slices.Equal(table.deleted, tc.deleted) {
+				t.Errorf("expected versions %v to be deleted, got %v", tc.deleted, table.deleted)
+			}
+		})
+	}
+}
+
+func TestDeleteMaterial(t *testing.T) {
+	testCases := []struct {
+		name         string
+		shards       int
+		version      int64
+		partitionKey string
+	}{
+		{name: "Unsharded", shards: 1, version: 3, partitionKey: "material"},
+		{name: "Sharded", shards: 2, version: 3, partitionKey: "material#1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var key map[string]types.AttributeValue
+			client := fakeDynamoDB(func(input interface{}) (interface{}, error) {
+				key = input.(*dynamodb.DeleteItemInput).Key
+				return &dynamodb.DeleteItemOutput{}, nil
+			})
+			s, err := NewMetaStore(client, "meta", WithShards(tc.shards))
+			if err != nil {
+				t.Fatalf("failed to create store: %v", err)
+			}
+
+			if err := s.DeleteMaterial(context.Background(), "material", tc.version); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := key["MaterialName"].(*types.AttributeValueMemberS).Value; got != tc.partitionKey {
+				t.Errorf("expected partition key %q, got %q", tc.partitionKey, got)
+			}
+			if got := itemVersion(key); got != tc.version {
+				t.Errorf("expected version %d, got %d", tc.version, got)
+			}
+		})
+	}
+
+	t.Run("Error", func(t *testing.T) {
+		s, err := NewMetaStore(fakeDynamoDB(func(input interface{}) (interface{}, error) {
+			return nil, errors.New("access denied")
+		}), "meta")
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+		if err := s.DeleteMaterial(context.Background(), "material", 1); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
+
+func TestStoreNewMaterial(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewMetaStore(fakeddb.New().Client(), "meta")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	material := func(wrappedKeyset string) materials.CryptographicMaterials {
+		return materials.NewDecryptionMaterials(map[string]string{"WrappedKeyset": wrappedKeyset}, nil)
+	}
+
+	if err := s.StoreNewMaterial(ctx, "material", material("first")); err != nil {
+		t.Fatalf("failed to store material: %v", err)
+	}
+	version, err := s.StoreNewMaterialVersion(ctx, "material", material("second"))
+	if err != nil {
+		t.Fatalf("failed to store material: %v", err)
+	}
+	if version != 2 {
+		t.Errorf("expected version 2, got %d", version)
+	}
+
+	for version, want := range map[int64]string{1: "first", 2: "second"} {
+		_, wrappedKeyset, err := s.RetrieveMaterial(ctx, "material", version)
+		if err != nil {
+			t.Fatalf("failed to retrieve version %d: %v", version, err)
+		}
+		if wrappedKeyset != want {
+			t.Errorf("expected version %d to hold %q, got %q", version, want, wrappedKeyset)
+		}
+	}
+}
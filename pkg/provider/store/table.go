@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// defaultTableWaitTimeout bounds how long table creation waits for the table to become ACTIVE.
+const defaultTableWaitTimeout = 5 * time.Minute
+
+// TableAPI is the subset of the DynamoDB API used to create and configure tables.
+type TableAPI interface {
+	CreateTable(ctx context.Context, input *dynamodb.CreateTableInput, opts ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error)
+	DescribeTable(ctx context.Context, input *dynamodb.DescribeTableInput, opts ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
+	UpdateTimeToLive(ctx context.Context, input *dynamodb.UpdateTimeToLiveInput, opts ...func(*dynamodb.Options)) (*dynamodb.UpdateTimeToLiveOutput, error)
+}
+
+// TableOptions holds the settings applied when creating a table.
+type TableOptions struct {
+	Tags         map[string]string
+	KMSKeyID     string
+	TTLAttribute string
+	WaitTimeout  time.Duration
+}
+
+// TableOption defines a function signature for options that modify TableOptions.
+type TableOption func(*TableOptions)
+
+// WithTags applies the given tags to the created table.
+func WithTags(tags map[string]string) TableOption {
+	return func(o *TableOptions) {
+		o.Tags = tags
+	}
+}
+
+// WithSSE enables server-side encryption with the given KMS key. An empty key ID uses the AWS managed key.
+func WithSSE(kmsKeyID string) TableOption {
+	return func(o *TableOptions) {
+		o.KMSKeyID = kmsKeyID
+		if o.KMSKeyID == "" {
+			o.KMSKeyID = "alias/aws/dynamodb"
+		}
+	}
+}
+
+// WithTTLAttribute enables time to live on the named attribute once the table is ACTIVE.
+func WithTTLAttribute(attributeName string) TableOption {
+	return func(o *TableOptions) {
+		o.TTLAttribute = attributeName
+	}
+}
+
+// WithWaitTimeout sets how long to wait for the table to become ACTIVE.
+func WithWaitTimeout(timeout time.Duration) TableOption {
+	return func(o *TableOptions) {
+		o.WaitTimeout = timeout
+	}
+}
+
+// tableWaitMinDelay is the shortest delay between the DescribeTable calls made while waiting for a table to become
+// ACTIVE. Tables are usually ACTIVE within seconds, well before the waiter's default of 20 seconds.
+var tableWaitMinDelay = 2 * time.Second
+
+// newTableOptions applies opts to the default table options.
+func newTableOptions(opts []TableOption) TableOptions {
+	options := TableOptions{WaitTimeout: defaultTableWaitTimeout}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// CreateTable creates a table, applies the given options, and waits until the table is ACTIVE. When the table is
+// being created or already exists, for example because another process created it concurrently, CreateTable only
+// waits for it to become ACTIVE; the options are those of the process that created it.
+func CreateTable(ctx context.Context, client TableAPI, input *dynamodb.CreateTableInput, opts ...TableOption) error {
+	options := newTableOptions(opts)
+
+	for key, value := range options.Tags {
+		input.Tags = append(input.Tags, types.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	if options.KMSKeyID != "" {
+		input.SSESpecification = &types.SSESpecification{
+			Enabled:        aws.Bool(true),
+			SSEType:        types.SSETypeKms,
+			KMSMasterKeyId: aws.String(options.KMSKeyID),
+		}
+	}
+
+	if _, err := client.CreateTable(ctx, input); err != nil {
+		var inUse *types.ResourceInUseException
+		if !errors.As(err, &inUse) {
+			return fmt.Errorf("failed to create table: %w", err)
+		}
+		return waitForActive(ctx, client, aws.ToString(input.TableName), options.WaitTimeout)
+	}
+
+	if err := waitForActive(ctx, client, aws.ToString(input.TableName), options.WaitTimeout); err != nil {
+		return err
+	}
+
+	if options.TTLAttribute != "" {
+		_, err := client.UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+			TableName: input.TableName,
+			TimeToLiveSpecification: &types.TimeToLiveSpecification{
+				AttributeName: aws.String(options.TTLAttribute),
+				Enabled:       aws.Bool(true),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to enable time to live: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// waitForActive waits for up to timeout until the table is ACTIVE.
+func waitForActive(ctx context.Context, client TableAPI, tableName string, timeout time.Duration) error {
+	waiter := dynamodb.NewTableExistsWaiter(client, func(o *dynamodb.TableExistsWaiterOptions) {
+		o.MinDelay = tableWaitMinDelay
+	})
+	if err := waiter.Wait(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)}, timeout); err != nil {
+		return fmt.Errorf("failed waiting for table to become active: %w", err)
+	}
+	return nil
+}
+
+// TableExists reports whether the table exists. Errors other than the table not being found are returned.
+func TableExists(ctx context.Context, client TableAPI, tableName string) (bool, error) {
+	_, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+	if err == nil {
+		return true, nil
+	}
+
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return false, nil
+	}
+
+	return false, fmt.Errorf("failed to describe table: %w", err)
+}
@@ -0,0 +1,209 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// fakeTableAPI creates tables and reports the given statuses from DescribeTable, one per call, repeating the last.
+type fakeTableAPI struct {
+	createErr   error
+	describeErr error
+	statuses    []types.TableStatus
+
+	created   *dynamodb.CreateTableInput
+	describes int
+	ttl       *dynamodb.UpdateTimeToLiveInput
+}
+
+func (f *fakeTableAPI) CreateTable(ctx context.Context, input *dynamodb.CreateTableInput, opts ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+	if f.createErr != nil {
+		return nil, f.createErr
+	}
+	f.created = input
+	return &dynamodb.CreateTableOutput{}, nil
+}
+
+func (f *fakeTableAPI) DescribeTable(ctx context.Context, input *dynamodb.DescribeTableInput, opts ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	if f.describeErr != nil {
+		return nil, f.describeErr
+	}
+	status := f.statuses[min(f.describes, len(f.statuses)-1)]
+	f.describes++
+	if status == "" {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("not found")}
+	}
+	return &dynamodb.DescribeTableOutput{Table: &types.TableDescription{TableName: input.TableName, TableStatus: status}}, nil
+}
+
+func (f *fakeTableAPI) UpdateTimeToLive(ctx context.Context, input *dynamodb.UpdateTimeToLiveInput, opts ...func(*dynamodb.Options)) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	f.ttl = input
+	return &dynamodb.UpdateTimeToLiveOutput{}, nil
+}
+
+// fastTableWait shortens the delay between DescribeTable calls while waiting for tables for the rest of the test.
+func fastTableWait(t *testing.T) {
+	delay := tableWaitMinDelay
+	tableWaitMinDelay = time.Millisecond
+	t.Cleanup(func() { tableWaitMinDelay = delay })
+}
+
+func TestCreateTable(t *testing.T) {
+	fastTableWait(t)
+
+	testCases := []struct {
+		name      string
+		opts      []TableOption
+		createErr error
+		statuses  []types.TableStatus
+		created   bool
+		kmsKeyID  string
+		tags      int
+		ttl       string
+		describes int
+		err       bool
+	}{
+		{name: "Plain", statuses: []types.TableStatus{types.TableStatusActive}, created: true, describes: 1},
+		{name: "SSE", opts: []TableOption{WithSSE("arn:aws:kms:eu-west-2:123456789123:key/1")}, statuses: []types.TableStatus{types.TableStatusActive}, created: true, kmsKeyID: "arn:aws:kms:eu-west-2:123456789123:key/1", describes: 1},
+		{name: "SSE with the AWS managed key", opts: []TableOption{WithSSE("")}, statuses: []types.TableStatus{types.TableStatusActive}, created: true, kmsKeyID: "alias/aws/dynamodb", describes: 1},
+		{name: "Tags", opts: []TableOption{WithTags(map[string]string{"team": "payments", "env": "test"})}, statuses: []types.TableStatus{types.TableStatusActive}, created: true, tags: 2, describes: 1},
+		{name: "TTL after ACTIVE", opts: []TableOption{WithTTLAttribute("ExpiresAt")}, statuses: []types.TableStatus{types.TableStatusCreating, types.TableStatusActive}, created: true, ttl: "ExpiresAt", describes: 2},
+		{name: "Concurrent create", opts: []TableOption{WithTTLAttribute("ExpiresAt")}, createErr: &types.ResourceInUseException{Message: aws.String("in use")}, statuses: []types.TableStatus{types.TableStatusCreating, types.TableStatusCreating, types.TableStatusActive}, describes: 3},
+		{name: "Create error", createErr: errors.New("access denied"), statuses: []types.TableStatus{types.TableStatusActive}, err: true},
+		{name: "Wait timeout", opts: []TableOption{WithWaitTimeout(20 * time.Millisecond)}, statuses: []types.TableStatus{types.TableStatusCreating}, created: true, err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &fakeTableAPI{createErr: tc.createErr, statuses: tc.statuses}
+			err := CreateTable(context.Background(), client, &dynamodb.CreateTableInput{TableName: aws.String("table")}, tc.opts...)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if (client.created != nil) != tc.created {
+				t.Fatalf("expected created %v", tc.created)
+			}
+			if client.describes != tc.describes {
+				t.Errorf("expected %d DescribeTable calls, got %d", tc.describes, client.describes)
+			}
+			if tc.created {
+				sse := client.created.SSESpecification
+				if tc.kmsKeyID == "" && sse != nil {
+					t.Errorf("expected no SSE specification")
+				}
+				if tc.kmsKeyID != "" && (sse == nil || !aws.ToBool(sse.Enabled) || sse.SSEType != types.SSETypeKms || aws.ToString(sse.KMSMasterKeyId) != tc.kmsKeyID) {
+					t.Errorf("expected SSE with key %q, got %+v", tc.kmsKeyID, sse)
+				}
+				if len(client.created.Tags) != tc.tags {
+					t.Errorf("expected %d tags, got %d", tc.tags, len(client.created.Tags))
+				}
+			}
+			if tc.ttl == "" && client.ttl != nil {
+				t.Errorf("expected time to live to be left alone")
+			}
+			if tc.ttl != "" && (client.ttl == nil || aws.ToString(client.ttl.TimeToLiveSpecification.AttributeName) != tc.ttl || !aws.ToBool(client.ttl.TimeToLiveSpecification.Enabled)) {
+				t.Errorf("expected time to live on %q, got %+v", tc.ttl, client.ttl)
+			}
+		})
+	}
+}
+
+func TestTableExists(t *testing.T) {
+	testCases := []struct {
+		name   string
+		status types.TableStatus
+		err    error
+		exists bool
+	}{
+		{name: "Exists", status: types.TableStatusActive, exists: true},
+		{name: "Creating", status: types.TableStatusCreating, exists: true},
+		{name: "Not found", status: ""},
+		{name: "Error", err: errors.New("access denied")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			exists, err := TableExists(context.Background(), &fakeTableAPI{statuses: []types.TableStatus{tc.status}, describeErr: tc.err}, "table")
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Errorf("expected the DescribeTable error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if exists != tc.exists {
+				t.Errorf("expected %v, got %v", tc.exists, exists)
+			}
+		})
+	}
+}
+
+func TestCreateTableIfNotExists(t *testing.T) {
+	fastTableWait(t)
+
+	testCases := []struct {
+		name      string
+		statuses  []types.TableStatus
+		createErr error
+		creates   int
+		describes int
+		ttl       bool
+	}{
+		{name: "Active", statuses: []types.TableStatus{types.TableStatusActive, types.TableStatusActive}, describes: 2},
+		{name: "Creating", statuses: []types.TableStatus{types.TableStatusCreating, types.TableStatusCreating, types.TableStatusActive}, describes: 3},
+		{name: "Missing", statuses: []types.TableStatus{"", types.TableStatusCreating, types.TableStatusActive}, creates: 1, describes: 3, ttl: true},
+		{name: "Created concurrently", statuses: []types.TableStatus{"", types.TableStatusCreating, types.TableStatusActive}, createErr: &types.ResourceInUseException{Message: aws.String("in use")}, creates: 1, describes: 3},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			table := &fakeTableAPI{statuses: tc.statuses, createErr: tc.createErr}
+			var creates int
+			client := fakeDynamoDB(func(input interface{}) (interface{}, error) {
+				switch input := input.(type) {
+				case *dynamodb.DescribeTableInput:
+					return table.DescribeTable(context.Background(), input)
+				case *dynamodb.CreateTableInput:
+					creates++
+					return table.CreateTable(context.Background(), input)
+				case *dynamodb.UpdateTimeToLiveInput:
+					return table.UpdateTimeToLive(context.Background(), input)
+				}
+				return nil, errors.New("unexpected operation")
+			})
+			s, err := NewMetaStore(client, "meta")
+			if err != nil {
+				t.Fatalf("failed to create store: %v", err)
+			}
+
+			// Options other than the wait timeout only apply to a table that is created
+			if err := s.CreateTableIfNotExists(context.Background(), WithTTLAttribute("ExpiresAt")); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if creates != tc.creates {
+				t.Errorf("expected %d CreateTable calls, got %d", tc.creates, creates)
+			}
+			if table.describes != tc.describes {
+				t.Errorf("expected %d DescribeTable calls, got %d", tc.describes, table.describes)
+			}
+			if (table.ttl != nil) != tc.ttl {
+				t.Errorf("expected time to live enabled to be %v, got %v", tc.ttl, table.ttl != nil)
+			}
+		})
+	}
+}
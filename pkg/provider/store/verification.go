@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// VerificationKeyPrefix prefixes the partition key of verification key items. When they share the meta table with
+// the materials, IAM policies can grant read access to the verification keys alone with a dynamodb:LeadingKeys
+// condition such as "verify#*".
+const VerificationKeyPrefix = "verify#"
+
+// WithVerificationTable stores verification keys in a dedicated table, instead of next to the materials, so it can be
+// made readable to consumers that only verify signatures. The table uses the same key schema as the meta table.
+func WithVerificationTable(tableName string) MetaStoreOption {
+	return func(s *MetaStore) error {
+		s.VerificationTableName = tableName
+		return nil
+	}
+}
+
+// StoreVerificationKey stores the public entries of a material version's description, such as its PublicKey, in the
+// verification key location. The description must not contain secrets; WrappedKeyset entries are dropped.
+func (s *MetaStore) StoreVerificationKey(ctx context.Context, materialName string, version int64, description map[string]string) error {
+	tableName, err := s.verificationTableName(ctx)
+	if err != nil {
+		return err
+	}
+
+	public := make(map[string]string, len(description))
+	for key, value := range description {
+		if key != "WrappedKeyset" {
+			public[key] = value
+		}
+	}
+	descriptionJSON, err := json.Marshal(public)
+	if err != nil {
+		return fmt.Errorf("failed to serialize verification key: %v", err)
+	}
+	encoded, err := s.encodeDescription(descriptionJSON)
+	if err != nil {
+		return err
+	}
+
+	item := map[string]types.AttributeValue{
+		"MaterialName":        &types.AttributeValueMemberS{Value: VerificationKeyPrefix + materialName},
+		"Version":             &types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)},
+		"MaterialDescription": encoded,
+		"CreatedAt":           &types.AttributeValueMemberN{Value: strconv.FormatInt(time.Now().Unix(), 10)},
+	}
+	err = s.retry.do(ctx, "StoreVerificationKey", func() error {
+		_, err := s.DynamoDBClient.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName:           aws.String(tableName),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(Version)"),
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store verification key: %w", err)
+	}
+	return nil
+}
+
+// RetrieveVerificationKey retrieves the public material description stored by StoreVerificationKey. A version less
+// than 1 resolves to the latest version.
+func (s *MetaStore) RetrieveVerificationKey(ctx context.Context, materialName string, version int64) (map[string]string, error) {
+	tableName, err := s.verificationTableName(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(tableName),
+		KeyConditionExpression: aws.String("MaterialName = :materialName"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":materialName": &types.AttributeValueMemberS{Value: VerificationKeyPrefix + materialName},
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int32(1),
+	}
+	if version >= 1 {
+		input.KeyConditionExpression = aws.String("MaterialName = :materialName AND Version = :version")
+		input.ExpressionAttributeValues[":version"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)}
+	}
+
+	var result *dynamodb.QueryOutput
+	err = s.retry.do(ctx, "RetrieveVerificationKey", func() (err error) {
+		result, err = s.DynamoDBClient.Query(ctx, input)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Items) == 0 {
+		return nil, fmt.Errorf("verification key not found")
+	}
+
+	descriptionJSON, err := decodeDescription(result.Items[0]["MaterialDescription"])
+	if err != nil {
+		return nil, err
+	}
+	var description map[string]string
+	if err := json.Unmarshal(descriptionJSON, &description); err != nil {
+		return nil, fmt.Errorf("failed to deserialize verification key: %v", err)
+	}
+	return description, nil
+}
+
+// verificationTableName returns the table verification keys are stored in.
+func (s *MetaStore) verificationTableName(ctx context.Context) (string, error) {
+	if s.VerificationTableName != "" {
+		return s.VerificationTableName, nil
+	}
+	return s.ResolveTableName(ctx)
+}
@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// WithTableKeys wraps the materials of the tables named in keys with the KMS key URI mapped to the table, instead of
+// the provider's key URI, which remains the key of every other table. One provider, and so one EncryptedClient, can
+// then serve tables that must be encrypted under separate KMS keys, for example for organizational or billing
+// separation. The table is read from the MaterialsRequest the encrypted client attaches to the context. Key URIs may
+// be templates, resolved like the provider's key URI.
+//
+// Materials record the key that wrapped them, and are unwrapped with it as long as it is one of the provider's keys,
+// so moving a table to another key keeps its existing items readable while both keys are configured.
+func WithTableKeys(keys map[string]string) ProviderOption {
+	return func(p *AwsKmsCryptographicMaterialsProvider) {
+		p.tableKeys = make(map[string]string, len(keys))
+		for tableName, keyURI := range keys {
+			p.tableKeys[tableName] = keyURI
+		}
+	}
+}
+
+// resolveTableKeys resolves templated table key URIs.
+func (p *AwsKmsCryptographicMaterialsProvider) resolveTableKeys(resolver KeyURIResolver) error {
+	for tableName, keyURI := range p.tableKeys {
+		resolved, err := ResolveKeyURI(keyURI, resolver)
+		if err != nil {
+			return fmt.Errorf("key URI of table %s: %w", tableName, err)
+		}
+		p.tableKeys[tableName] = resolved
+	}
+	return nil
+}
+
+// encryptionKeyURI returns the KMS key that wraps new materials for the table of the request carried by ctx.
+func (p *AwsKmsCryptographicMaterialsProvider) encryptionKeyURI(ctx context.Context) string {
+	if request, ok := MaterialsRequestFromContext(ctx); ok {
+		if keyURI, ok := p.tableKeys[request.TableName]; ok {
+			return keyURI
+		}
+	}
+	return p.KMSKeyURI
+}
+
+// keyURIs returns the provider's key URI followed by the other table key URIs, sorted.
+func (p *AwsKmsCryptographicMaterialsProvider) keyURIs() []string {
+	var tableKeyURIs []string
+	seen := map[string]bool{p.KMSKeyURI: true}
+	for _, keyURI := range p.tableKeys {
+		if !seen[keyURI] {
+			seen[keyURI] = true
+			tableKeyURIs = append(tableKeyURIs, keyURI)
+		}
+	}
+	sort.Strings(tableKeyURIs)
+	return append([]string{p.KMSKeyURI}, tableKeyURIs...)
+}
+
+// isProviderKey reports whether keyURI is the provider's key URI or one of its table key URIs.
+func (p *AwsKmsCryptographicMaterialsProvider) isProviderKey(keyURI string) bool {
+	if keyURI == p.KMSKeyURI {
+		return true
+	}
+	for _, tableKeyURI := range p.tableKeys {
+		if keyURI == tableKeyURI {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	fakeawskms "github.com/cloudopsy/dynamodb-encryption-go/internal/fakekms"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/provider/store"
+)
+
+func TestWithTableKeys(t *testing.T) {
+	const ordersKey = "arn:aws:kms:eu-west-2:123456789123:key/5a4e2c1b-7d3f-4b6a-9c8e-1f2a3b4c5d6e"
+	kms, err := fakeawskms.New([]string{keyURI, ordersKey})
+	if err != nil {
+		t.Fatalf("failed to create fake KMS: %v", err)
+	}
+	p, err := New(keyURI, nil, &store.MetaStore{TableName: "meta"}, WithKMSClient(kms), WithTableKeys(map[string]string{"orders": ordersKey}))
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+	tableContext := func(tableName string) context.Context {
+		return ContextWithMaterialsRequest(context.Background(), MaterialsRequest{TableName: tableName})
+	}
+
+	testCases := []struct {
+		name     string
+		ctx      context.Context
+		recorded string
+		expected string
+	}{
+		{name: "No request", ctx: context.Background(), expected: keyURI},
+		{name: "Mapped table", ctx: tableContext("orders"), expected: ordersKey},
+		{name: "Other table", ctx: tableContext("users"), expected: keyURI},
+		{name: "Recorded table key", ctx: tableContext("users"), recorded: ordersKey, expected: ordersKey},
+		{name: "Recorded default key", ctx: tableContext("orders"), recorded: keyURI, expected: keyURI},
+		{name: "Recorded unknown key", ctx: tableContext("orders"), recorded: "arn:aws:kms:eu-west-2:999999999999:key/other", expected: ordersKey},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.recorded == "" {
+				if got := p.encryptionKeyURI(tc.ctx); got != tc.expected {
+					t.Errorf("expected encryption key %q, got %q", tc.expected, got)
+				}
+			}
+			description := map[string]string{}
+			if tc.recorded != "" {
+				description[KMSKeyURIKey] = tc.recorded
+			}
+			got, err := p.decryptionKeyURI(tc.ctx, description)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected decryption key %q, got %q", tc.expected, got)
+			}
+		})
+	}
+
+	t.Run("New materials", func(t *testing.T) {
+		encryptionMaterials, err := p.newEncryptionMaterials(p.encryptionKeyURI(tableContext("orders")))
+		if err != nil {
+			t.Fatalf("failed to create materials: %v", err)
+		}
+		description := encryptionMaterials.MaterialDescription()
+		if description[KMSKeyURIKey] != ordersKey {
+			t.Errorf("expected the materials to record %q, got %q", ordersKey, description[KMSKeyURIKey])
+		}
+		wrappedKeyset, err := base64.StdEncoding.DecodeString(description["WrappedKeyset"])
+		if err != nil {
+			t.Fatalf("failed to decode keyset: %v", err)
+		}
+		kek, err := p.kekFor(ordersKey)
+		if err != nil {
+			t.Fatalf("failed to get KEK: %v", err)
+		}
+		_, associatedData, err := boundEncryptionContext(description)
+		if err != nil {
+			t.Fatalf("failed to read the bound encryption context: %v", err)
+		}
+		if _, err := delegatedkeys.UnwrapKeysetWithAssociatedData(wrappedKeyset, kek, associatedData); err != nil {
+			t.Errorf("expected the keyset to be wrapped with the table key: %v", err)
+		}
+	})
+
+	t.Run("Templates", func(t *testing.T) {
+		cmp, err := NewAwsKmsCryptographicMaterialsProvider(keyURI, nil, nil,
+			WithKeyURIResolver(MapResolver(map[string]string{"region": "eu-west-2"})),
+			WithTableKeys(map[string]string{"orders": "arn:aws:kms:{region}:123456789123:alias/orders"}))
+		if err != nil {
+			t.Fatalf("failed to create provider: %v", err)
+		}
+		if got := cmp.(*AwsKmsCryptographicMaterialsProvider).tableKeys["orders"]; got != "arn:aws:kms:eu-west-2:123456789123:alias/orders" {
+			t.Errorf("expected the table key to be resolved, got %q", got)
+		}
+
+		if _, err := NewAwsKmsCryptographicMaterialsProvider(keyURI, nil, nil,
+			WithKeyURIResolver(MapResolver(nil)),
+			WithTableKeys(map[string]string{"orders": "arn:aws:kms:{region}:123456789123:alias/orders"})); err == nil {
+			t.Errorf("expected an error for an unresolved placeholder")
+		}
+	})
+
+	t.Run("Invalid table key", func(t *testing.T) {
+		if _, err := New(keyURI, nil, &store.MetaStore{TableName: "meta"}, WithKMSClient(kms), WithTableKeys(map[string]string{"orders": "key/5a4e2c1b"})); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
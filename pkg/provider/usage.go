@@ -0,0 +1,243 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+)
+
+// ErrKeyUsageExceeded is returned when encrypting under a reused data key that has reached
+// UsageLimits.HardMaxMessages.
+var ErrKeyUsageExceeded = errors.New("data key usage limit exceeded")
+
+// UsageLimits bounds how much data a reused data key may protect, and for how long, before a new material version
+// is created. Zero fields are unlimited.
+type UsageLimits struct {
+	MaxMessages int64         // Maximum number of encryptions, i.e. AES-GCM invocations, under one data key.
+	MaxBytes    int64         // Maximum number of plaintext bytes encrypted under one data key.
+	MaxAge      time.Duration // Maximum time a data key is used for writes after it was created.
+
+	// HardMaxMessages is the number of encryptions after which a reused data key refuses to encrypt, failing with
+	// ErrKeyUsageExceeded. MaxMessages is only checked before materials are reused, so concurrent writers sharing
+	// the materials may overshoot it; HardMaxMessages is checked on every encryption and should be set somewhat
+	// above MaxMessages as a safeguard against nonce reuse.
+	HardMaxMessages int64
+}
+
+// DefaultUsageLimits follows NIST SP 800-38D, which bounds AES-GCM with random nonces to 2^32 invocations per key.
+var DefaultUsageLimits = UsageLimits{
+	MaxMessages: 1 << 32,
+}
+
+// WithMaterialReuse makes EncryptionMaterials reuse the latest materials created by this provider for a material
+// name until they reach the given usage limits, instead of creating a new version on every call. Setting MaxAge
+// rotates the materials on a schedule; items written under older versions remain readable because the client
+// pins each item's material version.
+func WithMaterialReuse(limits UsageLimits) ProviderOption {
+	return func(p *AwsKmsCryptographicMaterialsProvider) {
+		p.reuse = newReusedMaterials(limits, &p.counters, &p.usageWarning)
+	}
+}
+
+// UsageWarningHook is invoked when a reused data key approaches its message limit, for example to log a warning.
+// It receives the material name, the encryptions made under the key so far, and the limit being approached.
+type UsageWarningHook func(materialName string, messages, limit int64)
+
+// usageWarning is the threshold and hook set with WithUsageWarning.
+type usageWarning struct {
+	fraction float64
+	hook     UsageWarningHook
+}
+
+// WithUsageWarning registers a hook invoked once per reused data key when its encryptions reach the given fraction,
+// between 0 and 1, of UsageLimits.HardMaxMessages, or of MaxMessages when no hard limit is set. It has no effect
+// without WithMaterialReuse.
+func WithUsageWarning(fraction float64, hook UsageWarningHook) ProviderOption {
+	return func(p *AwsKmsCryptographicMaterialsProvider) {
+		p.usageWarning = usageWarning{fraction: fraction, hook: hook}
+	}
+}
+
+// reusedMaterials holds the encryption materials currently reused for each material name.
+type reusedMaterials struct {
+	limits   UsageLimits
+	mu       sync.Mutex
+	entries  map[string]*trackedMaterials
+	counters *providerCounters
+	warning  *usageWarning
+}
+
+// trackedMaterials are encryption materials whose encryption key counts its own usage.
+type trackedMaterials struct {
+	materials.CryptographicMaterials
+	key       *countingKey
+	createdAt time.Time
+}
+
+func (m *trackedMaterials) EncryptionKey() delegatedkeys.DelegatedKey {
+	return m.key
+}
+
+// get returns the reusable materials for the material name, or false if there are none or they are exhausted.
+func (r *reusedMaterials) get(materialName string) (materials.CryptographicMaterials, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[materialName]
+	if !ok {
+		return nil, false
+	}
+	if entry.key.exhausted(r.limits) || entry.expired(r.limits) {
+		delete(r.entries, materialName)
+		return nil, false
+	}
+	return entry, true
+}
+
+func newReusedMaterials(limits UsageLimits, counters *providerCounters, warning *usageWarning) *reusedMaterials {
+	return &reusedMaterials{
+		limits:   limits,
+		entries:  make(map[string]*trackedMaterials),
+		counters: counters,
+		warning:  warning,
+	}
+}
+
+// put starts tracking newly created materials and returns the tracked version to hand out.
+func (r *reusedMaterials) put(materialName string, m materials.CryptographicMaterials) materials.CryptographicMaterials {
+	entry := &trackedMaterials{
+		CryptographicMaterials: m,
+		key:                    &countingKey{DelegatedKey: m.EncryptionKey(), materialName: materialName, reuse: r},
+		createdAt:              time.Now(),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[materialName] = entry
+	return entry
+}
+
+// remove stops reusing the materials of the material name.
+func (r *reusedMaterials) remove(materialName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.entries, materialName)
+}
+
+// clear stops reusing every tracked material.
+func (r *reusedMaterials) clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = make(map[string]*trackedMaterials)
+}
+
+// expired reports whether the materials are older than the maximum age.
+func (m *trackedMaterials) expired(limits UsageLimits) bool {
+	return limits.MaxAge > 0 && time.Since(m.createdAt) >= limits.MaxAge
+}
+
+// countingKey counts the encryptions made with a delegated key, and enforces the hard usage limit.
+type countingKey struct {
+	delegatedkeys.DelegatedKey
+	materialName string
+	reuse        *reusedMaterials
+	messages     atomic.Int64
+	bytes        atomic.Int64
+	warned       atomic.Bool
+}
+
+func (k *countingKey) Encrypt(plaintext []byte, associatedData []byte) ([]byte, error) {
+	if err := k.count(len(plaintext)); err != nil {
+		return nil, err
+	}
+	return k.DelegatedKey.Encrypt(plaintext, associatedData)
+}
+
+// EncryptDeterministically encrypts with the underlying key when it supports deterministic encryption. Deterministic
+// encryptions use no nonces, so they do not count towards the key's usage.
+func (k *countingKey) EncryptDeterministically(plaintext []byte, associatedData []byte) ([]byte, error) {
+	return encryptDeterministically(k.DelegatedKey, plaintext, associatedData)
+}
+
+// DeriveKey derives a subkey from the underlying key when it supports derivation. Encryptions under the subkey
+// count towards the data key's usage, which is conservative, since every subkey has its own nonce space.
+func (k *countingKey) DeriveKey(info []byte) (delegatedkeys.DelegatedKey, error) {
+	deriver, ok := k.DelegatedKey.(delegatedkeys.KeyDeriver)
+	if !ok {
+		return nil, fmt.Errorf("data key of algorithm %s does not support key derivation", k.Algorithm())
+	}
+	subkey, err := deriver.DeriveKey(info)
+	if err != nil {
+		return nil, err
+	}
+	return &countedSubkey{DelegatedKey: subkey, parent: k}, nil
+}
+
+// count records an encryption of size bytes, refusing it once the hard limit is reached, and warns when the key
+// approaches its limit.
+func (k *countingKey) count(size int) error {
+	limits := k.reuse.limits
+	messages := k.messages.Add(1)
+	if limits.HardMaxMessages > 0 && messages > limits.HardMaxMessages {
+		k.messages.Add(-1)
+		k.reuse.counters.add(MetricKeyUsageRejections, 1)
+		return fmt.Errorf("%w: %d encryptions under the data key for %s", ErrKeyUsageExceeded, limits.HardMaxMessages, k.materialName)
+	}
+	k.bytes.Add(int64(size))
+	k.reuse.counters.add(MetricDataKeyEncryptions, 1)
+
+	limit := limits.HardMaxMessages
+	if limit == 0 {
+		limit = limits.MaxMessages
+	}
+	warning := k.reuse.warning
+	if warning.hook != nil && limit > 0 && float64(messages) >= warning.fraction*float64(limit) && k.warned.CompareAndSwap(false, true) {
+		warning.hook(k.materialName, messages, limit)
+	}
+	return nil
+}
+
+// exhausted reports whether the key has reached any of the limits. Limits are checked before each reuse, so a
+// single item's encryptions may overshoot them slightly, up to HardMaxMessages.
+func (k *countingKey) exhausted(limits UsageLimits) bool {
+	if limits.MaxMessages > 0 && k.messages.Load() >= limits.MaxMessages {
+		return true
+	}
+	if limits.HardMaxMessages > 0 && k.messages.Load() >= limits.HardMaxMessages {
+		return true
+	}
+	return limits.MaxBytes > 0 && k.bytes.Load() >= limits.MaxBytes
+}
+
+// countedSubkey is a subkey derived from a counting key, whose encryptions count towards the parent's usage.
+type countedSubkey struct {
+	delegatedkeys.DelegatedKey
+	parent *countingKey
+}
+
+func (k *countedSubkey) Encrypt(plaintext []byte, associatedData []byte) ([]byte, error) {
+	if err := k.parent.count(len(plaintext)); err != nil {
+		return nil, err
+	}
+	return k.DelegatedKey.Encrypt(plaintext, associatedData)
+}
+
+func (k *countedSubkey) EncryptDeterministically(plaintext []byte, associatedData []byte) ([]byte, error) {
+	return encryptDeterministically(k.DelegatedKey, plaintext, associatedData)
+}
+
+// encryptDeterministically encrypts with key when it supports deterministic encryption.
+func encryptDeterministically(key delegatedkeys.DelegatedKey, plaintext, associatedData []byte) ([]byte, error) {
+	deterministic, ok := key.(delegatedkeys.DeterministicKey)
+	if !ok {
+		return nil, delegatedkeys.ErrNoDeterministicKeyset
+	}
+	return deterministic.EncryptDeterministically(plaintext, associatedData)
+}
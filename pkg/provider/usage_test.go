@@ -0,0 +1,162 @@
+package provider
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tink-crypto/tink-go/v2/aead"
+	"github.com/tink-crypto/tink-go/v2/keyset"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/delegatedkeys"
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+)
+
+func TestReusedMaterials_Limits(t *testing.T) {
+	kh, err := keyset.NewHandle(aead.AES256GCMKeyTemplate())
+	if err != nil {
+		t.Fatalf("failed to create keyset: %v", err)
+	}
+	m := materials.NewEncryptionMaterials(nil, delegatedkeys.NewTinkDelegatedKey(kh, nil), nil)
+
+	testCases := []struct {
+		name     string
+		limits   UsageLimits
+		encrypts int
+		reusable bool
+	}{
+		{name: "Unused", limits: UsageLimits{MaxMessages: 2}, reusable: true},
+		{name: "BelowMessages", limits: UsageLimits{MaxMessages: 2}, encrypts: 1, reusable: true},
+		{name: "MaxMessages", limits: UsageLimits{MaxMessages: 2}, encrypts: 2},
+		{name: "MaxBytes", limits: UsageLimits{MaxBytes: 8}, encrypts: 2},
+		{name: "Unlimited", encrypts: 3, reusable: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reuse := newReusedMaterials(tc.limits, &providerCounters{}, &usageWarning{})
+			tracked := reuse.put("meta/item", m)
+			for i := 0; i < tc.encrypts; i++ {
+				if _, err := tracked.EncryptionKey().Encrypt([]byte("abcd"), nil); err != nil {
+					t.Fatalf("failed to encrypt: %v", err)
+				}
+			}
+
+			_, ok := reuse.get("meta/item")
+			if ok != tc.reusable {
+				t.Errorf("expected reusable %v, got %v", tc.reusable, ok)
+			}
+		})
+	}
+}
+
+func TestReusedMaterials_MaxAge(t *testing.T) {
+	m := materials.NewEncryptionMaterials(nil, nil, nil)
+	reuse := newReusedMaterials(UsageLimits{MaxAge: time.Hour}, &providerCounters{}, &usageWarning{})
+
+	tracked := reuse.put("meta/item", m).(*trackedMaterials)
+	if _, ok := reuse.get("meta/item"); !ok {
+		t.Fatalf("expected fresh materials to be reusable")
+	}
+
+	tracked.createdAt = time.Now().Add(-2 * time.Hour)
+	if _, ok := reuse.get("meta/item"); ok {
+		t.Errorf("expected materials older than MaxAge to be rotated")
+	}
+}
+
+func TestReusedMaterials_HardLimit(t *testing.T) {
+	kh, err := keyset.NewHandle(aead.AES256GCMKeyTemplate())
+	if err != nil {
+		t.Fatalf("failed to create keyset: %v", err)
+	}
+	m := materials.NewEncryptionMaterials(nil, delegatedkeys.NewTinkDelegatedKey(kh, nil), nil)
+
+	var warnings []int64
+	counters := &providerCounters{}
+	warning := &usageWarning{fraction: 0.5, hook: func(materialName string, messages, limit int64) {
+		if materialName != "meta/item" || limit != 4 {
+			t.Errorf("unexpected warning for %s with limit %d", materialName, limit)
+		}
+		warnings = append(warnings, messages)
+	}}
+	reuse := newReusedMaterials(UsageLimits{HardMaxMessages: 4}, counters, warning)
+	key := reuse.put("meta/item", m).EncryptionKey()
+
+	for i := 0; i < 4; i++ {
+		if _, err := key.Encrypt([]byte("abcd"), nil); err != nil {
+			t.Fatalf("failed to encrypt: %v", err)
+		}
+	}
+	if _, err := key.Encrypt([]byte("abcd"), nil); !errors.Is(err, ErrKeyUsageExceeded) {
+		t.Errorf("expected ErrKeyUsageExceeded, got %v", err)
+	}
+
+	subkey, err := key.(delegatedkeys.KeyDeriver).DeriveKey([]byte("attribute:Email"))
+	if err != nil {
+		t.Fatalf("failed to derive subkey: %v", err)
+	}
+	if _, err := subkey.Encrypt([]byte("abcd"), nil); !errors.Is(err, ErrKeyUsageExceeded) {
+		t.Errorf("expected subkey encryptions to count towards the limit, got %v", err)
+	}
+
+	if diff := cmp.Diff([]int64{2}, warnings); diff != "" {
+		t.Errorf("unexpected warnings (-want +got):\n%s", diff)
+	}
+	stats := counters.snapshot()
+	if stats.DataKeyEncryptions != 4 || stats.KeyUsageRejections != 2 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if _, ok := reuse.get("meta/item"); ok {
+		t.Errorf("expected materials at the hard limit to be rotated")
+	}
+}
+
+func TestReusedMaterials_Deterministic(t *testing.T) {
+	kek, err := delegatedkeys.GetKEK(keyURI, true)
+	if err != nil {
+		t.Fatalf("failed to get KEK: %v", err)
+	}
+	dataKey, _, err := delegatedkeys.GenerateDataKey(kek)
+	if err != nil {
+		t.Fatalf("failed to generate data key: %v", err)
+	}
+	reuse := newReusedMaterials(UsageLimits{MaxMessages: 1}, &providerCounters{}, &usageWarning{})
+	tracked := reuse.put("meta/item", materials.NewEncryptionMaterials(nil, dataKey, nil))
+	subkey, err := tracked.EncryptionKey().(delegatedkeys.KeyDeriver).DeriveKey([]byte("attribute:Email"))
+	if err != nil {
+		t.Fatalf("failed to derive key: %v", err)
+	}
+
+	testCases := []struct {
+		name string
+		key  delegatedkeys.DelegatedKey
+	}{
+		{name: "Data key", key: tracked.EncryptionKey()},
+		{name: "Subkey", key: subkey},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			deterministic, ok := tc.key.(delegatedkeys.DeterministicKey)
+			if !ok {
+				t.Fatalf("expected %T to support deterministic encryption", tc.key)
+			}
+			first, err := deterministic.EncryptDeterministically([]byte("a@example.com"), []byte("Email"))
+			if err != nil {
+				t.Fatalf("failed to encrypt: %v", err)
+			}
+			second, err := deterministic.EncryptDeterministically([]byte("a@example.com"), []byte("Email"))
+			if err != nil {
+				t.Fatalf("failed to encrypt: %v", err)
+			}
+			if !cmp.Equal(first, second) {
+				t.Errorf("expected equal ciphertexts")
+			}
+		})
+	}
+	if _, ok := reuse.get("meta/item"); !ok {
+		t.Errorf("expected deterministic encryptions not to count towards the usage limits")
+	}
+}
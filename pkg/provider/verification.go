@@ -0,0 +1,49 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/materials"
+)
+
+// WithVerificationKeys publishes the public parts of every material the provider creates, including the PublicKey
+// that verifies item signatures, with MetaStore.StoreVerificationKey. Consumers that only verify signatures can then
+// load them with VerificationMaterials, without read access to the wrapped keysets.
+func WithVerificationKeys() ProviderOption {
+	return func(p *AwsKmsCryptographicMaterialsProvider) {
+		p.verificationKeys = true
+	}
+}
+
+// publishVerificationKey stores the verification key of newly stored materials, if enabled.
+func (p *AwsKmsCryptographicMaterialsProvider) publishVerificationKey(ctx context.Context, materialName string, version int64, encryptionMaterials materials.CryptographicMaterials) error {
+	if !p.verificationKeys {
+		return nil
+	}
+	return p.MaterialStore.StoreVerificationKey(ctx, materialName, version, encryptionMaterials.MaterialDescription())
+}
+
+// VerificationMaterials loads the public material description published for a material version by a provider
+// created with WithVerificationKeys. A version less than 1 resolves to the latest version. The returned materials
+// only provide their description; neither the wrapped keysets nor KMS are accessed.
+func (p *AwsKmsCryptographicMaterialsProvider) VerificationMaterials(ctx context.Context, materialName string, version int64) (materials.CryptographicMaterials, error) {
+	if p.closed.Load() {
+		return nil, ErrProviderClosed
+	}
+
+	description, err := p.MaterialStore.RetrieveVerificationKey(ctx, materialName, version)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := base64.StdEncoding.DecodeString(description["PublicKey"]); err != nil || description["PublicKey"] == "" {
+		return nil, fmt.Errorf("verification key has no valid public key")
+	}
+
+	verificationMaterials := materials.NewVerificationMaterials(description)
+	if err := validateEncryptionContext(ctx, p.EncryptionContext, verificationMaterials); err != nil {
+		return nil, err
+	}
+	return verificationMaterials, nil
+}
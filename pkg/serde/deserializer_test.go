@@ -2,9 +2,14 @@ package serde
 
 import (
 	"bytes"
+	"errors"
+	"io"
+	"runtime"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/compression"
 )
 
 func TestDeserializer_DeserializeAttribute(t *testing.T) {
@@ -96,6 +101,86 @@ func TestDeserializer_DeserializeAttribute(t *testing.T) {
 	}
 }
 
+func TestDeserializer_Limits(t *testing.T) {
+	testCases := []struct {
+		name   string
+		data   []byte
+		limits Limits
+		err    error
+	}{
+		{
+			name:   "ValueTooLong",
+			data:   []byte{0, byte(tagString), 0, 0, 0, 5, 104, 101, 108, 108, 111},
+			limits: Limits{MaxValueLength: 4},
+			err:    ErrPayloadTooLarge,
+		},
+		{
+			name:   "TooManyElements",
+			data:   []byte{0, byte(tagList), 0, 0, 0, 2, 0, byte(tagNull), 0, byte(tagNull)},
+			limits: Limits{MaxElements: 1},
+			err:    ErrPayloadTooLarge,
+		},
+		{
+			name:   "TooDeep",
+			data:   []byte{0, byte(tagList), 0, 0, 0, 1, 0, byte(tagList), 0, 0, 0, 1, 0, byte(tagNull)},
+			limits: Limits{MaxDepth: 1},
+			err:    ErrPayloadTooLarge,
+		},
+		{
+			name: "HugeDeclaredLength",
+			data: []byte{0, byte(tagBinary), 0xFF, 0xFF, 0xFF, 0xF0, 1},
+			err:  ErrPayloadTooLarge,
+		},
+		{
+			name: "LengthBeyondInput",
+			data: []byte{0, byte(tagBinary), 0, 0, 0x10, 0, 1},
+			err:  ErrMalformedPayload,
+		},
+		{
+			name: "CountBeyondInput",
+			data: []byte{0, byte(tagList), 0, 0, 0x10, 0, 0, byte(tagNull)},
+			err:  ErrMalformedPayload,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			deserializer := NewDeserializer(WithLimits(tc.limits))
+			_, err := deserializer.DeserializeAttribute(tc.data)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Expected error '%v', got '%v'", tc.err, err)
+			}
+		})
+	}
+}
+
+func FuzzDeserializeAttribute(f *testing.F) {
+	serializer := NewSerializer()
+	seeds := []types.AttributeValue{
+		&types.AttributeValueMemberS{Value: "hello"},
+		&types.AttributeValueMemberN{Value: "1.5"},
+		&types.AttributeValueMemberBS{Value: [][]byte{{1}, {2}}},
+		&types.AttributeValueMemberL{Value: []types.AttributeValue{
+			&types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"key": &types.AttributeValueMemberBOOL{Value: true},
+			}},
+		}},
+	}
+	for _, seed := range seeds {
+		data, err := serializer.SerializeAttribute(seed)
+		if err != nil {
+			f.Fatalf("failed to serialize seed: %v", err)
+		}
+		f.Add(data)
+	}
+
+	deserializer := NewDeserializer(WithLimits(Limits{MaxElements: 1024, MaxValueLength: 1024, MaxDepth: 8}))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Arbitrary input must be rejected or decoded, never panic or exhaust memory
+		_, _ = deserializer.DeserializeAttribute(data)
+	})
+}
+
 func attributeEqual(a, b types.AttributeValue) bool {
 	if a == nil && b == nil {
 		return true
@@ -183,3 +268,148 @@ func attributeValueEqual(a, b types.AttributeValue) bool {
 		return false
 	}
 }
+
+func TestDeserializer_FormatVersion(t *testing.T) {
+	attribute := &types.AttributeValueMemberS{Value: "hello"}
+
+	versioned, err := NewSerializer().Serialize(attribute)
+	if err != nil {
+		t.Fatalf("failed to serialize: %v", err)
+	}
+	if version, err := FormatVersion(versioned); err != nil || version != CurrentFormatVersion {
+		t.Errorf("Expected version %d, got %d (%v)", CurrentFormatVersion, version, err)
+	}
+
+	legacy, err := NewSerializer().SerializeAttribute(attribute)
+	if err != nil {
+		t.Fatalf("failed to serialize: %v", err)
+	}
+	if version, err := FormatVersion(legacy); err != nil || version != FormatVersion0 {
+		t.Errorf("Expected version %d, got %d (%v)", FormatVersion0, version, err)
+	}
+
+	deserializer := NewDeserializer()
+	for _, data := range [][]byte{versioned, legacy} {
+		result, err := deserializer.DeserializeAttribute(data)
+		if err != nil {
+			t.Fatalf("failed to deserialize: %v", err)
+		}
+		if !attributeEqual(result, attribute) {
+			t.Errorf("Expected %v, got %v", attribute, result)
+		}
+	}
+
+	if _, err := deserializer.DeserializeAttribute([]byte{0x7F, 0, byte(tagNull)}); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Expected error '%v', got '%v'", ErrUnsupportedVersion, err)
+	}
+}
+
+func TestDeserializer_DeserializeFrom(t *testing.T) {
+	attribute := &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+		"blob": &types.AttributeValueMemberB{Value: bytes.Repeat([]byte{7}, 1<<20)},
+		"tags": &types.AttributeValueMemberSS{Value: []string{"a", "b"}},
+		"list": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+			&types.AttributeValueMemberN{Value: "1.5"},
+			&types.AttributeValueMemberNULL{Value: true},
+		}},
+	}}
+
+	serializer := NewSerializer()
+	var buf bytes.Buffer
+	if err := serializer.SerializeTo(&buf, attribute); err != nil {
+		t.Fatalf("failed to serialize: %v", err)
+	}
+
+	serialized, err := serializer.Serialize(attribute)
+	if err != nil {
+		t.Fatalf("failed to serialize: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), serialized) {
+		t.Errorf("SerializeTo and Serialize produced different payloads")
+	}
+
+	legacy, err := serializer.SerializeAttribute(attribute)
+	if err != nil {
+		t.Fatalf("failed to serialize: %v", err)
+	}
+	if !bytes.Equal(serialized[1:], legacy) {
+		t.Errorf("Versioned payload body differs from the legacy encoding")
+	}
+
+	deserializer := NewDeserializer(WithLimits(Limits{MaxValueLength: 2 << 20}))
+	for _, data := range [][]byte{serialized, legacy} {
+		// Hide Len so the deserializer sees a plain stream
+		result, err := deserializer.DeserializeFrom(io.MultiReader(bytes.NewReader(data)))
+		if err != nil {
+			t.Fatalf("failed to deserialize: %v", err)
+		}
+		if !attributeEqual(result, attribute) {
+			t.Errorf("Round trip mismatch")
+		}
+	}
+
+	if _, err := deserializer.DeserializeFrom(bytes.NewReader(nil)); err == nil {
+		t.Errorf("Expected error for empty stream")
+	}
+}
+
+func TestDeserializer_Compressed(t *testing.T) {
+	attribute := &types.AttributeValueMemberS{Value: string(bytes.Repeat([]byte(`{"status":"active"}`), 100))}
+
+	data, err := NewSerializer().SerializeCompressed(attribute, compression.Gzip)
+	if err != nil {
+		t.Fatalf("failed to serialize: %v", err)
+	}
+	if version, err := FormatVersion(data); err != nil || version != FormatVersion2 {
+		t.Errorf("Expected version %d, got %d (%v)", FormatVersion2, version, err)
+	}
+	if len(data) >= len(attribute.Value) {
+		t.Errorf("Expected compressed payload smaller than %d bytes, got %d", len(attribute.Value), len(data))
+	}
+
+	deserializer := NewDeserializer()
+	result, err := deserializer.DeserializeAttribute(data)
+	if err != nil {
+		t.Fatalf("failed to deserialize: %v", err)
+	}
+	if !attributeEqual(result, attribute) {
+		t.Errorf("Round trip mismatch")
+	}
+	if result, err = deserializer.DeserializeFrom(bytes.NewReader(data)); err != nil || !attributeEqual(result, attribute) {
+		t.Errorf("Streaming round trip failed: %v", err)
+	}
+
+	unknown := append([]byte{FormatVersion2, 250}, data[2:]...)
+	if _, err := deserializer.DeserializeAttribute(unknown); !errors.Is(err, compression.ErrUnknownAlgorithm) {
+		t.Errorf("Expected error '%v', got '%v'", compression.ErrUnknownAlgorithm, err)
+	}
+
+	limited := NewDeserializer(WithLimits(Limits{MaxDecompressedSize: len(attribute.Value)}))
+	if _, err := limited.DeserializeAttribute(data); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Errorf("Expected error '%v', got '%v'", ErrPayloadTooLarge, err)
+	}
+	if _, err := limited.DeserializeFrom(bytes.NewReader(data)); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Errorf("Expected error '%v', got '%v'", ErrPayloadTooLarge, err)
+	}
+}
+
+func TestDeserializer_StreamPreallocation(t *testing.T) {
+	// Every nesting level declares the maximum member count, which a stream can not be checked against
+	var data bytes.Buffer
+	data.WriteByte(FormatVersion1)
+	for i := 0; i < DefaultLimits.MaxDepth; i++ {
+		data.Write([]byte{0, byte(tagList)})
+		data.Write(encodeLength(DefaultLimits.MaxElements))
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := NewDeserializer().DeserializeFrom(io.MultiReader(&data))
+	runtime.ReadMemStats(&after)
+	if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected a truncated payload, got '%v'", err)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 4<<20 {
+		t.Errorf("Expected at most 4MB allocated for a truncated stream, got %d bytes", allocated)
+	}
+}
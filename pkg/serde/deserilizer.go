@@ -2,6 +2,7 @@ package serde
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"sort"
@@ -9,45 +10,161 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
-type Deserializer struct{}
+// ErrPayloadTooLarge is returned when a serialized payload exceeds the deserializer's limits.
+var ErrPayloadTooLarge = errors.New("serialized payload exceeds limits")
 
-func NewDeserializer() *Deserializer {
-	return &Deserializer{}
+// ErrMalformedPayload is returned when a serialized payload declares more data than it contains.
+var ErrMalformedPayload = errors.New("malformed serialized payload")
+
+// Limits bounds the resources a Deserializer may use for a single payload.
+type Limits struct {
+	MaxElements         int // Maximum number of members in a list, map or set.
+	MaxValueLength      int // Maximum length in bytes of a single binary, number or string value.
+	MaxDepth            int // Maximum nesting depth of lists and maps.
+	MaxDecompressedSize int // Maximum length in bytes of the decompressed content of a compressed payload.
+}
+
+// DefaultLimits mirrors DynamoDB's own constraints: items of at most 400KB nested at most 32 levels deep. Compressed
+// payloads may expand beyond the item size, up to 1MB.
+var DefaultLimits = Limits{
+	MaxElements:         400 * 1024,
+	MaxValueLength:      400 * 1024,
+	MaxDepth:            32,
+	MaxDecompressedSize: 1 << 20,
+}
+
+// maxStreamPrealloc caps the members allocated up front when the reader does not report its remaining length, so a
+// declared count can not be checked against the input. Longer lists, maps and sets grow as their members are read.
+const maxStreamPrealloc = 1024
+
+type Deserializer struct {
+	limits Limits
+}
+
+// DeserializerOption defines a function signature for options that modify a Deserializer.
+type DeserializerOption func(*Deserializer)
+
+// WithLimits replaces the deserializer's limits. Zero fields keep their default value.
+func WithLimits(limits Limits) DeserializerOption {
+	return func(d *Deserializer) {
+		if limits.MaxElements > 0 {
+			d.limits.MaxElements = limits.MaxElements
+		}
+		if limits.MaxValueLength > 0 {
+			d.limits.MaxValueLength = limits.MaxValueLength
+		}
+		if limits.MaxDepth > 0 {
+			d.limits.MaxDepth = limits.MaxDepth
+		}
+		if limits.MaxDecompressedSize > 0 {
+			d.limits.MaxDecompressedSize = limits.MaxDecompressedSize
+		}
+	}
+}
+
+func NewDeserializer(opts ...DeserializerOption) *Deserializer {
+	d := &Deserializer{limits: DefaultLimits}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
 }
 
+// DeserializeAttribute decodes a payload written by Serialize, SerializeCompressed or SerializeAttribute, negotiating the
+// format version from its first byte.
 func (d *Deserializer) DeserializeAttribute(data []byte) (types.AttributeValue, error) {
-	if len(data) == 0 {
-		return nil, fmt.Errorf("empty serialized data")
+	version, err := FormatVersion(data)
+	if err != nil {
+		return nil, err
+	}
+	switch version {
+	case FormatVersion1:
+		data = data[1:]
+	case FormatVersion2:
+		return d.deserializeCompressed(bytes.NewReader(data[1:]))
 	}
 	r := bytes.NewReader(data)
-	return d.deserialize(r)
+	return d.deserialize(r, 0)
 }
 
-func (d *Deserializer) deserializeBinary(r io.Reader) (types.AttributeValue, error) {
-	value, err := decodeValue(r)
+// readValue decodes a length-prefixed value, rejecting lengths above the limit or beyond the remaining input
+// before allocating.
+func (d *Deserializer) readValue(r io.Reader) ([]byte, error) {
+	length, err := decodeLength(r)
+	if err != nil {
+		return nil, err
+	}
+	if length > d.limits.MaxValueLength {
+		return nil, fmt.Errorf("%w: value length %d exceeds %d", ErrPayloadTooLarge, length, d.limits.MaxValueLength)
+	}
+	if err := checkRemaining(r, length); err != nil {
+		return nil, err
+	}
+	value := make([]byte, length)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// readCount decodes a member count, rejecting counts above the limit or that could not fit in the remaining
+// input given each member's minimum encoded size.
+func (d *Deserializer) readCount(r io.Reader, minMemberSize int) (int, error) {
+	count, err := decodeLength(r)
+	if err != nil {
+		return 0, err
+	}
+	if count > d.limits.MaxElements {
+		return 0, fmt.Errorf("%w: member count %d exceeds %d", ErrPayloadTooLarge, count, d.limits.MaxElements)
+	}
+	if err := checkRemaining(r, count*minMemberSize); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// checkRemaining fails if the reader reports fewer than n unread bytes.
+func checkRemaining(r io.Reader, n int) error {
+	if lr, ok := r.(interface{ Len() int }); ok && n > lr.Len() {
+		return fmt.Errorf("%w: %d bytes declared but %d remain", ErrMalformedPayload, n, lr.Len())
+	}
+	return nil
+}
+
+// preallocation returns how many of count members to allocate up front: all of them when checkRemaining has bounded
+// count by the remaining input, and at most maxStreamPrealloc otherwise.
+func preallocation(r io.Reader, count int) int {
+	if _, ok := r.(interface{ Len() int }); !ok && count > maxStreamPrealloc {
+		return maxStreamPrealloc
+	}
+	return count
+}
+
+func (d *Deserializer) deserializeBinary(r io.Reader, _ int) (types.AttributeValue, error) {
+	value, err := d.readValue(r)
 	if err != nil {
 		return nil, err
 	}
 	return &types.AttributeValueMemberB{Value: value}, nil
 }
 
-func (d *Deserializer) deserializeNumber(r io.Reader) (types.AttributeValue, error) {
-	value, err := decodeValue(r)
+func (d *Deserializer) deserializeNumber(r io.Reader, _ int) (types.AttributeValue, error) {
+	value, err := d.readValue(r)
 	if err != nil {
 		return nil, err
 	}
 	return &types.AttributeValueMemberN{Value: string(value)}, nil
 }
 
-func (d *Deserializer) deserializeString(r io.Reader) (types.AttributeValue, error) {
-	value, err := decodeValue(r)
+func (d *Deserializer) deserializeString(r io.Reader, _ int) (types.AttributeValue, error) {
+	value, err := d.readValue(r)
 	if err != nil {
 		return nil, err
 	}
 	return &types.AttributeValueMemberS{Value: string(value)}, nil
 }
 
-func (d *Deserializer) deserializeBoolean(r io.Reader) (types.AttributeValue, error) {
+func (d *Deserializer) deserializeBoolean(r io.Reader, _ int) (types.AttributeValue, error) {
 	value, err := decodeByte(r)
 	if err != nil {
 		return nil, err
@@ -55,38 +172,40 @@ func (d *Deserializer) deserializeBoolean(r io.Reader) (types.AttributeValue, er
 	return &types.AttributeValueMemberBOOL{Value: value != 0}, nil
 }
 
-func (d *Deserializer) deserializeNull(r io.Reader) (types.AttributeValue, error) {
+func (d *Deserializer) deserializeNull(r io.Reader, _ int) (types.AttributeValue, error) {
 	return &types.AttributeValueMemberNULL{Value: true}, nil
 }
 
-func (d *Deserializer) deserializeList(r io.Reader) (types.AttributeValue, error) {
-	memberCount, err := decodeLength(r)
+func (d *Deserializer) deserializeList(r io.Reader, depth int) (types.AttributeValue, error) {
+	// Every member is at least a two byte tag
+	memberCount, err := d.readCount(r, 2)
 	if err != nil {
 		return nil, err
 	}
-	members := make([]types.AttributeValue, memberCount)
+	members := make([]types.AttributeValue, 0, preallocation(r, memberCount))
 	for i := 0; i < memberCount; i++ {
-		member, err := d.deserialize(r)
+		member, err := d.deserialize(r, depth+1)
 		if err != nil {
 			return nil, err
 		}
-		members[i] = member
+		members = append(members, member)
 	}
 	return &types.AttributeValueMemberL{Value: members}, nil
 }
 
-func (d *Deserializer) deserializeMap(r io.Reader) (types.AttributeValue, error) {
-	memberCount, err := decodeLength(r)
+func (d *Deserializer) deserializeMap(r io.Reader, depth int) (types.AttributeValue, error) {
+	// Every entry is at least a six byte string key and a two byte tag
+	memberCount, err := d.readCount(r, 8)
 	if err != nil {
 		return nil, err
 	}
-	members := make(map[string]types.AttributeValue, memberCount)
+	members := make(map[string]types.AttributeValue, preallocation(r, memberCount))
 	for i := 0; i < memberCount; i++ {
-		key, err := d.deserialize(r)
+		key, err := d.deserialize(r, depth+1)
 		if err != nil {
 			return nil, err
 		}
-		value, err := d.deserialize(r)
+		value, err := d.deserialize(r, depth+1)
 		if err != nil {
 			return nil, err
 		}
@@ -99,60 +218,60 @@ func (d *Deserializer) deserializeMap(r io.Reader) (types.AttributeValue, error)
 	return &types.AttributeValueMemberM{Value: members}, nil
 }
 
-func (d *Deserializer) deserializeBinarySet(r io.Reader) (types.AttributeValue, error) {
-	memberCount, err := decodeLength(r)
+func (d *Deserializer) deserializeBinarySet(r io.Reader, _ int) (types.AttributeValue, error) {
+	members, err := d.readSetMembers(r)
 	if err != nil {
 		return nil, err
 	}
-	members := make([][]byte, memberCount)
-	for i := 0; i < memberCount; i++ {
-		member, err := decodeValue(r)
-		if err != nil {
-			return nil, err
-		}
-		members[i] = member
-	}
 	sort.Slice(members, func(i, j int) bool {
 		return bytes.Compare(members[i], members[j]) < 0
 	})
 	return &types.AttributeValueMemberBS{Value: members}, nil
 }
 
-func (d *Deserializer) deserializeNumberSet(r io.Reader) (types.AttributeValue, error) {
-	memberCount, err := decodeLength(r)
+func (d *Deserializer) deserializeNumberSet(r io.Reader, _ int) (types.AttributeValue, error) {
+	members, err := d.readSetMembers(r)
 	if err != nil {
 		return nil, err
 	}
-	members := make([]string, memberCount)
-	for i := 0; i < memberCount; i++ {
-		member, err := decodeValue(r)
-		if err != nil {
-			return nil, err
-		}
-		members[i] = string(member)
+	return &types.AttributeValueMemberNS{Value: sortedStrings(members)}, nil
+}
+
+func (d *Deserializer) deserializeStringSet(r io.Reader, _ int) (types.AttributeValue, error) {
+	members, err := d.readSetMembers(r)
+	if err != nil {
+		return nil, err
 	}
-	sort.Strings(members)
-	return &types.AttributeValueMemberNS{Value: members}, nil
+	return &types.AttributeValueMemberSS{Value: sortedStrings(members)}, nil
 }
 
-func (d *Deserializer) deserializeStringSet(r io.Reader) (types.AttributeValue, error) {
-	memberCount, err := decodeLength(r)
+func (d *Deserializer) readSetMembers(r io.Reader) ([][]byte, error) {
+	// Every member is at least a four byte length
+	memberCount, err := d.readCount(r, 4)
 	if err != nil {
 		return nil, err
 	}
-	members := make([]string, memberCount)
+	members := make([][]byte, 0, preallocation(r, memberCount))
 	for i := 0; i < memberCount; i++ {
-		member, err := decodeValue(r)
+		member, err := d.readValue(r)
 		if err != nil {
 			return nil, err
 		}
-		members[i] = string(member)
+		members = append(members, member)
 	}
-	sort.Strings(members)
-	return &types.AttributeValueMemberSS{Value: members}, nil
+	return members, nil
 }
 
-func (d *Deserializer) deserializeFunction(tag Tag) (func(io.Reader) (types.AttributeValue, error), error) {
+func sortedStrings(members [][]byte) []string {
+	strs := make([]string, len(members))
+	for i, member := range members {
+		strs[i] = string(member)
+	}
+	sort.Strings(strs)
+	return strs
+}
+
+func (d *Deserializer) deserializeFunction(tag Tag) (func(io.Reader, int) (types.AttributeValue, error), error) {
 	switch tag {
 	case tagBinary:
 		return d.deserializeBinary, nil
@@ -179,7 +298,10 @@ func (d *Deserializer) deserializeFunction(tag Tag) (func(io.Reader) (types.Attr
 	}
 }
 
-func (d *Deserializer) deserialize(r io.Reader) (types.AttributeValue, error) {
+func (d *Deserializer) deserialize(r io.Reader, depth int) (types.AttributeValue, error) {
+	if depth > d.limits.MaxDepth {
+		return nil, fmt.Errorf("%w: nesting depth exceeds %d", ErrPayloadTooLarge, d.limits.MaxDepth)
+	}
 	tag, err := decodeTag(r)
 	if err != nil {
 		return nil, err
@@ -188,5 +310,5 @@ func (d *Deserializer) deserialize(r io.Reader) (types.AttributeValue, error) {
 	if err != nil {
 		return nil, err
 	}
-	return deserializeFunc(r)
+	return deserializeFunc(r, depth)
 }
@@ -0,0 +1,68 @@
+package serde
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxNumberExponent bounds the decimal exponent of numbers, far beyond DynamoDB's range of 1E-130 to 1E+126, so a
+// malformed exponent can not expand into a huge canonical form.
+const maxNumberExponent = 10000
+
+// canonicalNumber returns the canonical form of a DynamoDB number, so equal numbers serialize equally: plain decimal
+// notation without an exponent, a plus sign, leading zeros or trailing fractional zeros, as in 1.5 for +001.50 or
+// 100 for 1E2, and 0 for any zero. The digits are rearranged as text, never converted to a binary
+// fraction, so every digit of numbers of any precision is kept.
+func canonicalNumber(value string) (string, error) {
+	s := value
+	negative := false
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		negative = s[0] == '-'
+		s = s[1:]
+	}
+
+	mantissa, exponent := s, 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mantissa = s[:i]
+		exp, err := strconv.Atoi(s[i+1:])
+		if err != nil || exp < -maxNumberExponent || exp > maxNumberExponent {
+			return "", fmt.Errorf("invalid number %q: malformed exponent", value)
+		}
+		exponent = exp
+	}
+
+	intPart, fracPart, _ := strings.Cut(mantissa, ".")
+	digits := intPart + fracPart
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return "", fmt.Errorf("invalid number %q", value)
+	}
+
+	// The number is 0.digits × 10^point
+	point := len(intPart) + exponent
+	trimmed := strings.TrimLeft(digits, "0")
+	point -= len(digits) - len(trimmed)
+	digits = strings.TrimRight(trimmed, "0")
+	if digits == "" {
+		return "0", nil
+	}
+
+	var b strings.Builder
+	if negative {
+		b.WriteByte('-')
+	}
+	switch {
+	case point <= 0:
+		b.WriteString("0.")
+		b.WriteString(strings.Repeat("0", -point))
+		b.WriteString(digits)
+	case point >= len(digits):
+		b.WriteString(digits)
+		b.WriteString(strings.Repeat("0", point-len(digits)))
+	default:
+		b.WriteString(digits[:point])
+		b.WriteByte('.')
+		b.WriteString(digits[point:])
+	}
+	return b.String(), nil
+}
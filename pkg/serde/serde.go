@@ -2,12 +2,43 @@ package serde
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 )
 
 const reserved = "\x00"
 
+// Format versions of a serialized payload. Version 0 payloads have no header and start directly with the
+// reserved null byte of their first tag; later versions start with a non-zero version byte instead.
+const (
+	FormatVersion0 byte = 0
+	FormatVersion1 byte = 1
+	// FormatVersion2 payloads hold a compression ID followed by a compressed version 1 payload.
+	FormatVersion2 byte = 2
+
+	// CurrentFormatVersion is the version written by Serializer.Serialize.
+	CurrentFormatVersion = FormatVersion1
+)
+
+// ErrUnsupportedVersion is returned when a payload's format version is not known to this library.
+var ErrUnsupportedVersion = errors.New("unsupported serialization format version")
+
+// FormatVersion returns the format version of a serialized payload.
+func FormatVersion(data []byte) (byte, error) {
+	if len(data) == 0 {
+		return 0, fmt.Errorf("empty serialized data")
+	}
+	switch data[0] {
+	case reserved[0]:
+		return FormatVersion0, nil
+	case FormatVersion1, FormatVersion2:
+		return data[0], nil
+	default:
+		return 0, fmt.Errorf("%w: %d", ErrUnsupportedVersion, data[0])
+	}
+}
+
 type Tag byte
 
 const (
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"fmt"
 	"sort"
-	"strconv"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
@@ -15,7 +14,24 @@ func NewSerializer() *Serializer {
 	return &Serializer{}
 }
 
-func (s *Serializer) SerializeAttribute(attribute types.AttributeValue) ([]byte, error) {
+// Serialize encodes an attribute value prefixed with the current format version.
+func (s *Serializer) Serialize(attribute types.AttributeValue) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := s.SerializeTo(&buf, attribute); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SerializeAttribute encodes an attribute value without a format version header.
+func (s *Serializer) SerializeAttribute(attribute types.AttributeValue) (serialized []byte, err error) {
+	// Nested members and invalid numbers are reported through panics while encoding
+	defer func() {
+		if r := recover(); r != nil {
+			serialized, err = nil, fmt.Errorf("failed to serialize attribute: %v", r)
+		}
+	}()
+
 	switch v := attribute.(type) {
 	case *types.AttributeValueMemberB:
 		return s.serializeBinary(v.Value), nil
@@ -157,12 +173,13 @@ func (s *Serializer) transformBinaryValue(value interface{}) []byte {
 }
 
 func (s *Serializer) transformNumberValue(value string) []byte {
-	// Remove trailing zeros from the number
-	num, err := strconv.ParseFloat(value, 64)
+	// Remove trailing zeros from the number. DynamoDB numbers carry up to 38 significant digits, which a
+	// float64 cannot hold, so normalize the decimal text itself to keep every digit.
+	canonical, err := canonicalNumber(value)
 	if err != nil {
 		panic(err)
 	}
-	return []byte(strconv.FormatFloat(num, 'f', -1, 64))
+	return []byte(canonical)
 }
 
 type keyValue struct {
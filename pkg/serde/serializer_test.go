@@ -2,6 +2,7 @@ package serde
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
@@ -112,3 +113,54 @@ func TestSerializer_SerializeAttribute(t *testing.T) {
 		})
 	}
 }
+
+func TestSerializer_Numbers(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected string
+	}{
+		{value: "1.2340", expected: "1.234"},
+		{value: "12345678901234567890123456789012345678", expected: "12345678901234567890123456789012345678"},
+		{value: "0.00000000000000000000000000000000000001", expected: "0.00000000000000000000000000000000000001"},
+		{value: "-1E3", expected: "-1000"},
+		{value: "98765432109876543210.123456789012345678", expected: "98765432109876543210.123456789012345678"},
+		{value: "-0.10000000000000000000000000000000000000", expected: "-0.1"},
+		{value: "+001.50", expected: "1.5"},
+		{value: ".5", expected: "0.5"},
+		{value: "5.", expected: "5"},
+		{value: "1.2345e-2", expected: "0.012345"},
+		{value: "1.2345E+2", expected: "123.45"},
+		{value: "1E-130", expected: "0." + strings.Repeat("0", 129) + "1"},
+		{value: "9.9999999999999999999999999999999999999E+125", expected: "99999999999999999999999999999999999999" + strings.Repeat("0", 88)},
+		{value: "-0.000", expected: "0"},
+	}
+
+	serializer := NewSerializer()
+	deserializer := NewDeserializer()
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			serialized, err := serializer.SerializeAttribute(&types.AttributeValueMemberN{Value: tc.value})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			result, err := deserializer.DeserializeAttribute(serialized)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if n := result.(*types.AttributeValueMemberN).Value; n != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, n)
+			}
+		})
+	}
+
+	invalid := &types.AttributeValueMemberL{Value: []types.AttributeValue{&types.AttributeValueMemberN{Value: "abc"}}}
+	if _, err := serializer.SerializeAttribute(invalid); err == nil {
+		t.Errorf("Expected error for invalid number")
+	}
+	for _, value := range []string{"", "-", ".", "Inf", "1e", "1e+", "e5", "1.2.3", "1_000", "0x10", " 1", "1e100000"} {
+		if _, err := serializer.SerializeAttribute(&types.AttributeValueMemberN{Value: value}); err == nil {
+			t.Errorf("Expected error for invalid number %q", value)
+		}
+	}
+}
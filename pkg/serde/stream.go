@@ -0,0 +1,183 @@
+package serde
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/compression"
+)
+
+// SerializeCompressed encodes an attribute value as a version 1 payload compressed with the compressor
+// registered under id. The compressor must still be registered when the payload is deserialized.
+func (s *Serializer) SerializeCompressed(attribute types.AttributeValue, id compression.ID) ([]byte, error) {
+	if id == compression.None {
+		return s.Serialize(attribute)
+	}
+	compressor, err := compression.Lookup(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Write([]byte{FormatVersion2, byte(id)})
+	w, err := compressor.NewWriter(&buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compressor: %w", err)
+	}
+	if err := s.SerializeTo(w, attribute); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress attribute: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// SerializeTo writes an attribute value prefixed with the current format version to w. Binary and string values
+// are written straight from the attribute without being copied into an intermediate buffer.
+func (s *Serializer) SerializeTo(w io.Writer, attribute types.AttributeValue) error {
+	bw := bufio.NewWriter(w)
+	if err := bw.WriteByte(CurrentFormatVersion); err != nil {
+		return err
+	}
+	if err := s.writeAttribute(bw, attribute); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func (s *Serializer) writeAttribute(w *bufio.Writer, attribute types.AttributeValue) error {
+	switch v := attribute.(type) {
+	case *types.AttributeValueMemberB:
+		return s.writeValue(w, tagBinary, v.Value)
+	case *types.AttributeValueMemberS:
+		return s.writeValue(w, tagString, []byte(v.Value))
+	case *types.AttributeValueMemberL:
+		if err := s.writeHeader(w, tagList, len(v.Value)); err != nil {
+			return err
+		}
+		for _, member := range v.Value {
+			if err := s.writeAttribute(w, member); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *types.AttributeValueMemberM:
+		if err := s.writeHeader(w, tagMap, len(v.Value)); err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(v.Value))
+		for key := range v.Value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := s.writeValue(w, tagString, []byte(key)); err != nil {
+				return err
+			}
+			if err := s.writeAttribute(w, v.Value[key]); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		// Remaining types are small or need their members sorted, so they are encoded in memory
+		serialized, err := s.SerializeAttribute(attribute)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(serialized)
+		return err
+	}
+}
+
+func (s *Serializer) writeHeader(w *bufio.Writer, tag Tag, length int) error {
+	if _, err := w.Write([]byte{reserved[0], byte(tag)}); err != nil {
+		return err
+	}
+	_, err := w.Write(encodeLength(length))
+	return err
+}
+
+func (s *Serializer) writeValue(w *bufio.Writer, tag Tag, value []byte) error {
+	if err := s.writeHeader(w, tag, len(value)); err != nil {
+		return err
+	}
+	_, err := w.Write(value)
+	return err
+}
+
+// DeserializeFrom decodes a single attribute value from r, negotiating the format version from its first byte.
+// Readers that do not report their remaining length are only bounded by the deserializer's limits.
+func (d *Deserializer) DeserializeFrom(r io.Reader) (types.AttributeValue, error) {
+	version, err := decodeByte(r)
+	if err == io.EOF {
+		return nil, fmt.Errorf("empty serialized data")
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch version {
+	case FormatVersion0:
+		// Legacy payloads have no header, so the byte just read is the first tag's reserved byte
+		return d.deserialize(io.MultiReader(bytes.NewReader([]byte{version}), r), 0)
+	case FormatVersion1:
+		return d.deserialize(r, 0)
+	case FormatVersion2:
+		return d.deserializeCompressed(r)
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+}
+
+// deserializeCompressed decompresses a version 2 payload, whose content must be a version 1 payload of at most
+// MaxDecompressedSize bytes.
+func (d *Deserializer) deserializeCompressed(r io.Reader) (types.AttributeValue, error) {
+	id, err := decodeByte(r)
+	if err != nil {
+		return nil, err
+	}
+	compressor, err := compression.Lookup(compression.ID(id))
+	if err != nil {
+		return nil, err
+	}
+	cr, err := compressor.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress attribute: %w", err)
+	}
+	defer cr.Close()
+	limited := &sizeLimitedReader{r: cr, remaining: d.limits.MaxDecompressedSize}
+
+	version, err := decodeByte(limited)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress attribute: %w", err)
+	}
+	if version != FormatVersion1 {
+		return nil, fmt.Errorf("%w: %d inside compressed payload", ErrUnsupportedVersion, version)
+	}
+	return d.deserialize(limited, 0)
+}
+
+// sizeLimitedReader reads at most remaining bytes from r. Like io.LimitReader, but reads beyond the limit fail with
+// ErrPayloadTooLarge instead of io.EOF, since the deserializer only reads further when the payload continues.
+type sizeLimitedReader struct {
+	r         io.Reader
+	remaining int
+}
+
+func (l *sizeLimitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		return 0, fmt.Errorf("%w: decompressed size exceeds limit", ErrPayloadTooLarge)
+	}
+	if len(p) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= n
+	return n, err
+}
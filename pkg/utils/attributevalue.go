@@ -0,0 +1,18 @@
+package utils
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/cloudopsy/dynamodb-encryption-go/pkg/serde"
+)
+
+// AttributeValueToBytes encodes any DynamoDB attribute value, including sets, nested lists and maps, and NULL,
+// in serde's lossless tagged format.
+func AttributeValueToBytes(attribute types.AttributeValue) ([]byte, error) {
+	return serde.NewSerializer().Serialize(attribute)
+}
+
+// BytesToAttributeValue decodes an attribute value encoded by AttributeValueToBytes.
+func BytesToAttributeValue(data []byte) (types.AttributeValue, error) {
+	return serde.NewDeserializer().DeserializeAttribute(data)
+}
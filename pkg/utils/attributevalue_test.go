@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestAttributeValueRoundTrip(t *testing.T) {
+	attributes := []types.AttributeValue{
+		&types.AttributeValueMemberB{Value: []byte{1, 2, 3}},
+		&types.AttributeValueMemberN{Value: "12345678901234567890123456789012345678"},
+		&types.AttributeValueMemberS{Value: "hello"},
+		&types.AttributeValueMemberBOOL{Value: true},
+		&types.AttributeValueMemberNULL{Value: true},
+		&types.AttributeValueMemberBS{Value: [][]byte{{1}, {2}}},
+		&types.AttributeValueMemberNS{Value: []string{"1", "2.5"}},
+		&types.AttributeValueMemberSS{Value: []string{"a", "b"}},
+		&types.AttributeValueMemberL{Value: []types.AttributeValue{
+			&types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"nested": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+					&types.AttributeValueMemberNULL{Value: true},
+				}},
+			}},
+		}},
+	}
+
+	for _, attribute := range attributes {
+		data, err := AttributeValueToBytes(attribute)
+		if err != nil {
+			t.Fatalf("failed to encode %T: %v", attribute, err)
+		}
+		result, err := BytesToAttributeValue(data)
+		if err != nil {
+			t.Fatalf("failed to decode %T: %v", attribute, err)
+		}
+		if !reflect.DeepEqual(result, attribute) {
+			t.Errorf("Expected %#v, got %#v", attribute, result)
+		}
+	}
+}
@@ -5,6 +5,15 @@ import (
 	"encoding/hex"
 )
 
+// Zero overwrites b with zeros, so plaintext and key bytes do not linger in memory, and in core dumps or swap, once
+// they are no longer needed. It is best effort: copies made by the runtime, such as those left behind when a buffer
+// grows, or held in immutable strings, are out of its reach.
+func Zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
 // HashString takes an input string and returns its SHA256 hash as a hex-encoded string.
 func HashString(input string) string {
 	hasher := sha256.New()